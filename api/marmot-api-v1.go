@@ -118,14 +118,27 @@ type ApplicationLoadBalancerLabelSelector struct {
 
 // ApplicationLoadBalancerListener defines model for ApplicationLoadBalancerListener.
 type ApplicationLoadBalancerListener struct {
-	BackendPort            int                                  `json:"backendPort" yaml:"backendPort"`
-	BackendSelector        ApplicationLoadBalancerLabelSelector `json:"backendSelector" yaml:"backendSelector"`
-	HealthCheck            *ApplicationLoadBalancerHealthCheck  `json:"healthCheck,omitempty" yaml:"healthCheck,omitempty"`
-	LoadBalancingAlgorithm string                               `json:"loadBalancingAlgorithm" yaml:"loadBalancingAlgorithm"`
-	Name                   string                               `json:"name" yaml:"name"`
-	Protocol               string                               `json:"protocol" yaml:"protocol"`
-	SessionPersistence     *ApplicationLoadBalancerPersistence  `json:"sessionPersistence,omitempty" yaml:"sessionPersistence,omitempty"`
-	VipPort                int                                  `json:"vipPort" yaml:"vipPort"`
+	BackendPort     int                                  `json:"backendPort" yaml:"backendPort"`
+	BackendSelector ApplicationLoadBalancerLabelSelector `json:"backendSelector" yaml:"backendSelector"`
+
+	// CertificateIds IDs of the TLS certificates of an HTTPS listener. The certificate is selected by SNI,
+	// and the first one is used when no certificate matches.
+	CertificateIds         *[]string                           `json:"certificateIds,omitempty" yaml:"certificateIds,omitempty"`
+	HealthCheck            *ApplicationLoadBalancerHealthCheck `json:"healthCheck,omitempty" yaml:"healthCheck,omitempty"`
+	LoadBalancingAlgorithm string                              `json:"loadBalancingAlgorithm" yaml:"loadBalancingAlgorithm"`
+	Name                   string                              `json:"name" yaml:"name"`
+
+	// Protocol HTTP, HTTPS, TCP or UDP. HTTPS terminates TLS with certificateIds.
+	Protocol string `json:"protocol" yaml:"protocol"`
+
+	// RedirectToHttps Redirect requests of an HTTP listener to the HTTPS listener of the load balancer.
+	RedirectToHttps *bool `json:"redirectToHttps,omitempty" yaml:"redirectToHttps,omitempty"`
+
+	// Rules Routing rules evaluated in order. Requests that match no rule go to backendSelector.
+	// HTTP and HTTPS listeners match the Host header and the path, TCP listeners match the SNI of TLS connections.
+	Rules              *[]ApplicationLoadBalancerRule      `json:"rules,omitempty" yaml:"rules,omitempty"`
+	SessionPersistence *ApplicationLoadBalancerPersistence `json:"sessionPersistence,omitempty" yaml:"sessionPersistence,omitempty"`
+	VipPort            int                                 `json:"vipPort" yaml:"vipPort"`
 }
 
 // ApplicationLoadBalancerPersistence defines model for ApplicationLoadBalancerPersistence.
@@ -134,6 +147,20 @@ type ApplicationLoadBalancerPersistence struct {
 	Enabled    bool    `json:"enabled" yaml:"enabled"`
 }
 
+// ApplicationLoadBalancerRule defines model for ApplicationLoadBalancerRule.
+type ApplicationLoadBalancerRule struct {
+	// BackendPort Port of the backends. Defaults to the backendPort of the listener.
+	BackendPort     *int                                 `json:"backendPort,omitempty" yaml:"backendPort,omitempty"`
+	BackendSelector ApplicationLoadBalancerLabelSelector `json:"backendSelector" yaml:"backendSelector"`
+
+	// Hosts Host names to match. A leading "*." matches any subdomain.
+	Hosts *[]string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	Name  string    `json:"name" yaml:"name"`
+
+	// PathPrefixes Path prefixes to match. Only for HTTP and HTTPS listeners.
+	PathPrefixes *[]string `json:"pathPrefixes,omitempty" yaml:"pathPrefixes,omitempty"`
+}
+
 // ApplicationLoadBalancerSpec defines model for ApplicationLoadBalancerSpec.
 type ApplicationLoadBalancerSpec struct {
 	// BindPublicIpAddress Public bind IP address. CIDR form (e.g. 10.10.0.70/24) is also accepted.
@@ -151,6 +178,29 @@ type ApplicationLoadBalancerSpec struct {
 	Routes *[]Route `json:"routes,omitempty" yaml:"routes,omitempty"`
 }
 
+// AuditRecord A mutating API request and its result.
+type AuditRecord struct {
+	// ApiKeyId The id of the API key (or login session) used for the request.
+	ApiKeyId    *string `json:"apiKeyId,omitempty" yaml:"apiKeyId,omitempty"`
+	Id          string  `json:"id" yaml:"id"`
+	Method      string  `json:"method" yaml:"method"`
+	OperationId string  `json:"operationId" yaml:"operationId"`
+	Path        string  `json:"path" yaml:"path"`
+
+	// Resource The resource kind checked by RBAC (e.g. Server).
+	Resource   *string `json:"resource,omitempty" yaml:"resource,omitempty"`
+	ResourceId *string `json:"resourceId,omitempty" yaml:"resourceId,omitempty"`
+
+	// Result success, failure (the request was rejected or failed) or denied (authentication or authorization failed).
+	Result     string    `json:"result" yaml:"result"`
+	SourceIp   *string   `json:"sourceIp,omitempty" yaml:"sourceIp,omitempty"`
+	StatusCode int       `json:"statusCode" yaml:"statusCode"`
+	Timestamp  time.Time `json:"timestamp" yaml:"timestamp"`
+
+	// UserId The authenticated user. Empty when authentication failed.
+	UserId *string `json:"userId,omitempty" yaml:"userId,omitempty"`
+}
+
 // Auth defines model for Auth.
 type Auth struct {
 	PublicKey    *string   `json:"publicKey,omitempty" yaml:"publicKey,omitempty"`
@@ -187,13 +237,32 @@ type AuthMe struct {
 	UserId             string   `json:"userId" yaml:"userId"`
 }
 
+// AuthOidcConfig Settings that a client uses for the OIDC authorization code flow with PKCE.
+type AuthOidcConfig struct {
+	ClientId string   `json:"clientId" yaml:"clientId"`
+	Issuer   string   `json:"issuer" yaml:"issuer"`
+	Scopes   []string `json:"scopes" yaml:"scopes"`
+}
+
+// AuthOidcLoginRequest defines model for AuthOidcLoginRequest.
+type AuthOidcLoginRequest struct {
+	// IdToken ID token issued by the OIDC identity provider.
+	IdToken string `json:"idToken" yaml:"idToken"`
+}
+
 // AuthzCheckRequest defines model for AuthzCheckRequest.
 type AuthzCheckRequest struct {
-	Action     string                  `json:"action" yaml:"action"`
-	Context    *map[string]interface{} `json:"context,omitempty" yaml:"context,omitempty"`
-	Resource   string                  `json:"resource" yaml:"resource"`
-	ResourceId *string                 `json:"resourceId,omitempty" yaml:"resourceId,omitempty"`
-	UserId     *string                 `json:"userId,omitempty" yaml:"userId,omitempty"`
+	Action  string                  `json:"action" yaml:"action"`
+	Context *map[string]interface{} `json:"context,omitempty" yaml:"context,omitempty"`
+
+	// Labels Labels of the resource, used by permissions with a label selector.
+	Labels *map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Project Check the action inside this project, including the roles bound in the project.
+	Project    *string `json:"project,omitempty" yaml:"project,omitempty"`
+	Resource   string  `json:"resource" yaml:"resource"`
+	ResourceId *string `json:"resourceId,omitempty" yaml:"resourceId,omitempty"`
+	UserId     *string `json:"userId,omitempty" yaml:"userId,omitempty"`
 }
 
 // AuthzCheckResponse defines model for AuthzCheckResponse.
@@ -203,6 +272,56 @@ type AuthzCheckResponse struct {
 	Reason       *string   `json:"reason,omitempty" yaml:"reason,omitempty"`
 }
 
+// Certificate A TLS certificate for HTTPS listeners of application load balancers.
+// The private key is stored in etcd and is never returned by the API.
+type Certificate struct {
+	// Certificate PEM encoded certificate followed by the intermediate certificates
+	Certificate string `json:"certificate" yaml:"certificate"`
+
+	// DnsNames Subject alternative names of the certificate
+	DnsNames *[]string `json:"dnsNames,omitempty" yaml:"dnsNames,omitempty"`
+
+	// Fingerprint SHA-256 fingerprint of the certificate
+	Fingerprint *string    `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	Id          *string    `json:"id,omitempty" yaml:"id,omitempty"`
+	Name        string     `json:"name" yaml:"name"`
+	NotAfter    *time.Time `json:"notAfter,omitempty" yaml:"notAfter,omitempty"`
+
+	// PrivateKey PEM encoded private key. Required on create and replace, omitted in responses.
+	PrivateKey *string `json:"privateKey,omitempty" yaml:"privateKey,omitempty"`
+
+	// Project The project the certificate belongs to. Certificates without a project belong to the default project. It cannot be changed after registration.
+	Project *string `json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// DnsRecord A record of the internal DNS. The name is host.network or a fully qualified name.
+// A PTR record can be given an IP address as the name.
+type DnsRecord struct {
+	Id   *string `json:"id,omitempty" yaml:"id,omitempty"`
+	Name string  `json:"name" yaml:"name"`
+
+	// Port Port of the SRV record
+	Port *int `json:"port,omitempty" yaml:"port,omitempty"`
+
+	// Priority Priority of the SRV record
+	Priority *int `json:"priority,omitempty" yaml:"priority,omitempty"`
+
+	// Source custom for records created through the API, server for records of servers
+	Source *string `json:"source,omitempty" yaml:"source,omitempty"`
+
+	// Ttl Time to live in seconds. The default is 300
+	Ttl *int `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+
+	// Type A, AAAA, CNAME, PTR or SRV
+	Type string `json:"type" yaml:"type"`
+
+	// Value IP address for A and AAAA, target name for CNAME, PTR and SRV
+	Value string `json:"value" yaml:"value"`
+
+	// Weight Weight of the SRV record
+	Weight *int `json:"weight,omitempty" yaml:"weight,omitempty"`
+}
+
 // Error defines model for Error.
 type Error struct {
 	Code    int32  `json:"code" yaml:"code"`
@@ -246,11 +365,35 @@ type HostAllocation struct {
 
 // HostCapacity defines model for HostCapacity.
 type HostCapacity struct {
-	CpuCores          *int      `json:"cpuCores,omitempty" yaml:"cpuCores,omitempty"`
-	DiskCapacityGB    *int      `json:"diskCapacityGB,omitempty" yaml:"diskCapacityGB,omitempty"`
-	DiskCount         *int      `json:"diskCount,omitempty" yaml:"diskCount,omitempty"`
-	MemoryMB          *int      `json:"memoryMB,omitempty" yaml:"memoryMB,omitempty"`
-	NetworkInterfaces *[]string `json:"networkInterfaces,omitempty" yaml:"networkInterfaces,omitempty"`
+	CpuCores              *int      `json:"cpuCores,omitempty" yaml:"cpuCores,omitempty"`
+	DataVolumeGroupFreeGB *int      `json:"dataVolumeGroupFreeGB,omitempty" yaml:"dataVolumeGroupFreeGB,omitempty"`
+	DiskCapacityGB        *int      `json:"diskCapacityGB,omitempty" yaml:"diskCapacityGB,omitempty"`
+	DiskCount             *int      `json:"diskCount,omitempty" yaml:"diskCount,omitempty"`
+	MemoryMB              *int      `json:"memoryMB,omitempty" yaml:"memoryMB,omitempty"`
+	NetworkInterfaces     *[]string `json:"networkInterfaces,omitempty" yaml:"networkInterfaces,omitempty"`
+	OsVolumeGroupFreeGB   *int      `json:"osVolumeGroupFreeGB,omitempty" yaml:"osVolumeGroupFreeGB,omitempty"`
+	VolumeStoreFreeGB     *int      `json:"volumeStoreFreeGB,omitempty" yaml:"volumeStoreFreeGB,omitempty"`
+}
+
+// HostNumaNode defines model for HostNumaNode.
+type HostNumaNode struct {
+	Cpus             *[]int `json:"cpus,omitempty" yaml:"cpus,omitempty"`
+	Hugepages1GFree  *int   `json:"hugepages1GFree,omitempty" yaml:"hugepages1GFree,omitempty"`
+	Hugepages1GTotal *int   `json:"hugepages1GTotal,omitempty" yaml:"hugepages1GTotal,omitempty"`
+	Hugepages2MFree  *int   `json:"hugepages2MFree,omitempty" yaml:"hugepages2MFree,omitempty"`
+	Hugepages2MTotal *int   `json:"hugepages2MTotal,omitempty" yaml:"hugepages2MTotal,omitempty"`
+	Id               *int   `json:"id,omitempty" yaml:"id,omitempty"`
+	MemoryMB         *int   `json:"memoryMB,omitempty" yaml:"memoryMB,omitempty"`
+}
+
+// HostPerformance Pinned CPU pool and NUMA topology used to place servers with spec.performance.
+type HostPerformance struct {
+	// AllocatedPinnedCpus Pinned CPUs assigned to servers on the node.
+	AllocatedPinnedCpus *[]int          `json:"allocatedPinnedCpus,omitempty" yaml:"allocatedPinnedCpus,omitempty"`
+	NumaNodes           *[]HostNumaNode `json:"numaNodes,omitempty" yaml:"numaNodes,omitempty"`
+
+	// PinnedCpus Host CPUs reserved for dedicated vCPUs (pinned_cpus in marmotd.json).
+	PinnedCpus *[]int `json:"pinnedCpus,omitempty" yaml:"pinnedCpus,omitempty"`
 }
 
 // HostStatus defines model for HostStatus.
@@ -262,8 +405,17 @@ type HostStatus struct {
 	InitiatorId       *string         `json:"initiatorId,omitempty" yaml:"initiatorId,omitempty"`
 	IpAddress         *string         `json:"ipAddress,omitempty" yaml:"ipAddress,omitempty"`
 	IscsiServer       *bool           `json:"iscsiServer,omitempty" yaml:"iscsiServer,omitempty"`
-	LastUpdated       *time.Time      `json:"lastUpdated,omitempty" yaml:"lastUpdated,omitempty"`
-	NodeName          *string         `json:"nodeName,omitempty" yaml:"nodeName,omitempty"`
+
+	// Labels Node labels used by server placement nodeSelector.
+	Labels      *map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	LastUpdated *time.Time         `json:"lastUpdated,omitempty" yaml:"lastUpdated,omitempty"`
+
+	// Maintenance Maintenance state of a node set by cordon or drain.
+	Maintenance *NodeMaintenance `json:"maintenance,omitempty" yaml:"maintenance,omitempty"`
+	NodeName    *string          `json:"nodeName,omitempty" yaml:"nodeName,omitempty"`
+
+	// Performance Pinned CPU pool and NUMA topology used to place servers with spec.performance.
+	Performance *HostPerformance `json:"performance,omitempty" yaml:"performance,omitempty"`
 }
 
 // IPAddress defines model for IPAddress.
@@ -300,6 +452,9 @@ type Image struct {
 
 // ImageSpec defines model for ImageSpec.
 type ImageSpec struct {
+	// Firmware Firmware the image requires. One of bios, uefi or uefi-secure.
+	// When omitted, the image boots with any firmware.
+	Firmware      *string `json:"firmware,omitempty" yaml:"firmware,omitempty"`
 	Kind          *string `json:"kind,omitempty" yaml:"kind,omitempty"`
 	LogicalVolume *string `json:"logicalVolume,omitempty" yaml:"logicalVolume,omitempty"`
 	LvPath        *string `json:"lvPath,omitempty" yaml:"lvPath,omitempty"`
@@ -327,7 +482,13 @@ type JobSpec struct {
 	FinishTime  *time.Time `json:"finishTime,omitempty" yaml:"finishTime,omitempty"`
 	MaxTime     *time.Time `json:"maxTime,omitempty" yaml:"maxTime,omitempty"`
 	RequestTime *time.Time `json:"requestTime,omitempty" yaml:"requestTime,omitempty"`
-	StartTime   *time.Time `json:"startTime,omitempty" yaml:"startTime,omitempty"`
+
+	// ResourceId Id of the resource the job works on.
+	ResourceId *string `json:"resourceId,omitempty" yaml:"resourceId,omitempty"`
+
+	// ResourceKind Kind of the resource the job works on, e.g. Image.
+	ResourceKind *string    `json:"resourceKind,omitempty" yaml:"resourceKind,omitempty"`
+	StartTime    *time.Time `json:"startTime,omitempty" yaml:"startTime,omitempty"`
 }
 
 // KubernetesEngine defines model for KubernetesEngine.
@@ -378,7 +539,17 @@ type Metadata struct {
 	Labels   *map[string]interface{} `json:"labels,omitempty" yaml:"labels,omitempty"`
 	Name     string                  `json:"name" yaml:"name"`
 	NodeName *string                 `json:"nodeName,omitempty" yaml:"nodeName,omitempty"`
-	Uuid     *string                 `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+
+	// Owner The id of the user who created the resource. Resource usage is counted against the quotas of this user.
+	Owner *string `json:"owner,omitempty" yaml:"owner,omitempty"`
+
+	// Project The project the resource belongs to. Resources without a project belong to the default project.
+	// The project is set on creation and cannot be changed.
+	Project *string `json:"project,omitempty" yaml:"project,omitempty"`
+
+	// ResourceVersion etcd mod revision of the resource. Updates carrying a stale value are rejected with 409 Conflict.
+	ResourceVersion *string `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+	Uuid            *string `json:"uuid,omitempty" yaml:"uuid,omitempty"`
 }
 
 // Nameservers defines model for Nameservers.
@@ -446,6 +617,23 @@ type NetworkLoadBalancerSpec struct {
 	RemoteCIDR string `json:"remoteCIDR" yaml:"remoteCIDR"`
 }
 
+// NodeMaintenance Maintenance state of a node set by cordon or drain.
+type NodeMaintenance struct {
+	// DrainAction How running servers are moved away by drain, migrate (default) or stop.
+	DrainAction    *string    `json:"drainAction,omitempty" yaml:"drainAction,omitempty"`
+	DrainStartedAt *time.Time `json:"drainStartedAt,omitempty" yaml:"drainStartedAt,omitempty"`
+	LastUpdated    *time.Time `json:"lastUpdated,omitempty" yaml:"lastUpdated,omitempty"`
+	Message        *string    `json:"message,omitempty" yaml:"message,omitempty"`
+	NodeName       *string    `json:"nodeName,omitempty" yaml:"nodeName,omitempty"`
+	Reason         *string    `json:"reason,omitempty" yaml:"reason,omitempty"`
+
+	// State CORDONED, DRAINING or DRAINED.
+	State *string `json:"state,omitempty" yaml:"state,omitempty"`
+
+	// Unschedulable New servers and migrations are not placed on the node.
+	Unschedulable *bool `json:"unschedulable,omitempty" yaml:"unschedulable,omitempty"`
+}
+
 // PasswordChangeRequest defines model for PasswordChangeRequest.
 type PasswordChangeRequest struct {
 	CurrentPassword *string `json:"currentPassword,omitempty" yaml:"currentPassword,omitempty"`
@@ -454,8 +642,10 @@ type PasswordChangeRequest struct {
 
 // Permission defines model for Permission.
 type Permission struct {
-	Resource string   `json:"resource" yaml:"resource"`
-	Verbs    []string `json:"verbs" yaml:"verbs"`
+	// LabelSelector Restricts a permission to the resources that have all of the labels.
+	LabelSelector *RoleLabelSelector `json:"labelSelector,omitempty" yaml:"labelSelector,omitempty"`
+	Resource      string             `json:"resource" yaml:"resource"`
+	Verbs         []string           `json:"verbs" yaml:"verbs"`
 }
 
 // Pong defines model for Pong.
@@ -464,6 +654,69 @@ type Pong struct {
 	Ping string `json:"ping" yaml:"ping"`
 }
 
+// Project A group of resources. The roles of the members apply only to the resources in the project.
+type Project struct {
+	ApiVersion string      `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string      `json:"kind" yaml:"kind"`
+	Metadata   Metadata    `json:"metadata" yaml:"metadata"`
+	Spec       ProjectSpec `json:"spec" yaml:"spec"`
+}
+
+// ProjectMember A role binding of a user inside a project.
+type ProjectMember struct {
+	Roles  []string `json:"roles" yaml:"roles"`
+	UserId string   `json:"userId" yaml:"userId"`
+}
+
+// ProjectSpec defines model for ProjectSpec.
+type ProjectSpec struct {
+	Description *string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Members     *[]ProjectMember `json:"members,omitempty" yaml:"members,omitempty"`
+}
+
+// Quota Upper limits of the resources created by each of the users.
+// A limit that is not set is unlimited. When several quotas apply to a user, all of them must be satisfied.
+type Quota struct {
+	Id     *string        `json:"id,omitempty" yaml:"id,omitempty"`
+	Limits QuotaResources `json:"limits" yaml:"limits"`
+	Name   string         `json:"name" yaml:"name"`
+
+	// Projects The names of the projects the quota applies to. Usage is counted over the resources in each project.
+	Projects *[]string `json:"projects,omitempty" yaml:"projects,omitempty"`
+
+	// Users The ids of the users the quota applies to
+	Users *[]string `json:"users,omitempty" yaml:"users,omitempty"`
+}
+
+// QuotaResources defines model for QuotaResources.
+type QuotaResources struct {
+	// MemoryMB Memory of the servers in MB
+	MemoryMB *int `json:"memoryMB,omitempty" yaml:"memoryMB,omitempty"`
+
+	// Networks Number of virtual networks
+	Networks *int `json:"networks,omitempty" yaml:"networks,omitempty"`
+
+	// PublicIps Number of public IP addresses bound by gateways, VPN gateways and load balancers
+	PublicIps *int `json:"publicIps,omitempty" yaml:"publicIps,omitempty"`
+
+	// Servers Number of servers
+	Servers *int `json:"servers,omitempty" yaml:"servers,omitempty"`
+
+	// Vcpus Number of vCPUs of the servers
+	Vcpus *int `json:"vcpus,omitempty" yaml:"vcpus,omitempty"`
+
+	// VolumeGB Total size of the volumes in GB, including the boot volumes of the servers
+	VolumeGB *int `json:"volumeGB,omitempty" yaml:"volumeGB,omitempty"`
+}
+
+// QuotaUsage defines model for QuotaUsage.
+type QuotaUsage struct {
+	// Quotas The quotas applied to the user
+	Quotas []Quota        `json:"quotas" yaml:"quotas"`
+	Used   QuotaResources `json:"used" yaml:"used"`
+	UserId string         `json:"userId" yaml:"userId"`
+}
+
 // ReplyMessage defines model for ReplyMessage.
 type ReplyMessage struct {
 	// Message Example: ok
@@ -472,10 +725,11 @@ type ReplyMessage struct {
 
 // Role defines model for Role.
 type Role struct {
-	ApiVersion string   `json:"apiVersion" yaml:"apiVersion"`
-	Kind       string   `json:"kind" yaml:"kind"`
-	Metadata   Metadata `json:"metadata" yaml:"metadata"`
-	Spec       RoleSpec `json:"spec" yaml:"spec"`
+	ApiVersion string      `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string      `json:"kind" yaml:"kind"`
+	Metadata   Metadata    `json:"metadata" yaml:"metadata"`
+	Spec       RoleSpec    `json:"spec" yaml:"spec"`
+	Status     *RoleStatus `json:"status,omitempty" yaml:"status,omitempty"`
 }
 
 // RoleAssignmentRequest defines model for RoleAssignmentRequest.
@@ -483,6 +737,11 @@ type RoleAssignmentRequest struct {
 	RoleName string `json:"roleName" yaml:"roleName"`
 }
 
+// RoleLabelSelector Restricts a permission to the resources that have all of the labels.
+type RoleLabelSelector struct {
+	MatchLabels map[string]string `json:"matchLabels" yaml:"matchLabels"`
+}
+
 // RoleNames defines model for RoleNames.
 type RoleNames = []string
 
@@ -492,6 +751,12 @@ type RoleSpec struct {
 	Permissions []Permission `json:"permissions" yaml:"permissions"`
 }
 
+// RoleStatus defines model for RoleStatus.
+type RoleStatus struct {
+	// Builtin Built-in roles cannot be changed or deleted.
+	Builtin bool `json:"builtin" yaml:"builtin"`
+}
+
 // Roles defines model for Roles.
 type Roles = []Role
 
@@ -540,20 +805,264 @@ type ServerAnsiblePull struct {
 	Url *string `json:"url,omitempty" yaml:"url,omitempty"`
 }
 
+// ServerAffinity defines model for ServerAffinity.
+type ServerAffinity struct {
+	Preferred *[]ServerWeightedAffinityTerm `json:"preferred,omitempty" yaml:"preferred,omitempty"`
+	Required  *[]ServerAffinityTerm         `json:"required,omitempty" yaml:"required,omitempty"`
+}
+
+// ServerAffinityTerm defines model for ServerAffinityTerm.
+type ServerAffinityTerm struct {
+	// MatchLabels Labels of other servers (metadata.labels) this term matches.
+	MatchLabels map[string]string `json:"matchLabels" yaml:"matchLabels"`
+}
+
+// ServerCloneRequest defines model for ServerCloneRequest.
+type ServerCloneRequest struct {
+	// Comment Comment of the new server.
+	Comment *string `json:"comment,omitempty" yaml:"comment,omitempty"`
+
+	// Labels Labels of the new server. Without labels the labels of the source server are copied,
+	// so the new server also matches the backend selectors of load balancers.
+	// An empty object creates the new server without labels.
+	Labels *map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+
+	// Mode Clone mode of the volumes. One of full (default) or cow. See VolumeSource.
+	Mode *string `json:"mode,omitempty" yaml:"mode,omitempty"`
+
+	// Name Name of the new server. It is also used as the hostname of the guest.
+	Name string `json:"name" yaml:"name"`
+
+	// SnapshotId The id of a server snapshot of the source server. The volumes are cloned as they were when the snapshot was taken.
+	SnapshotId *string `json:"snapshotId,omitempty" yaml:"snapshotId,omitempty"`
+}
+
+// ServerCloudInit User-supplied cloud-init documents. They are merged with the user, password and SSH key
+// sections generated by marmot. Network settings are managed by marmot and cannot be changed here.
+type ServerCloudInit struct {
+	// UserData Raw user-data. A cloud-config ("#cloud-config"), a shell script ("#!") or a MIME multipart document.
+	// Go template expressions such as {{ .ServerName }} and {{ .Vars.key }} are expanded before the ISO is built.
+	UserData *string `json:"userData,omitempty" yaml:"userData,omitempty"`
+
+	// Variables Variables referenced from userData and vendorData as {{ .Vars.key }}.
+	Variables *map[string]string `json:"variables,omitempty" yaml:"variables,omitempty"`
+
+	// VendorData Extra vendor-data in the same formats as userData. Settings in user-data take precedence.
+	VendorData *string `json:"vendorData,omitempty" yaml:"vendorData,omitempty"`
+}
+
+// ServerConsoleToken defines model for ServerConsoleToken.
+type ServerConsoleToken struct {
+	// ExpiresAt New connections are refused after this time. Connections already opened are kept.
+	ExpiresAt time.Time `json:"expiresAt" yaml:"expiresAt"`
+
+	// NodeName The node of the server. Connect to marmotd on this node.
+	NodeName *string `json:"nodeName,omitempty" yaml:"nodeName,omitempty"`
+
+	// Path The path of the WebSocket endpoint including the token.
+	Path string `json:"path" yaml:"path"`
+
+	// Protocol The protocol of the graphical console. One of spice or vnc.
+	Protocol string `json:"protocol" yaml:"protocol"`
+
+	// Token The token to pass as the token query parameter.
+	Token string `json:"token" yaml:"token"`
+}
+
+// ServerGuestInfo Information reported by the QEMU guest agent running in the server.
+type ServerGuestInfo struct {
+	Hostname      *string                 `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	Interfaces    *[]ServerGuestInterface `json:"interfaces,omitempty" yaml:"interfaces,omitempty"`
+	KernelRelease *string                 `json:"kernelRelease,omitempty" yaml:"kernelRelease,omitempty"`
+	LastUpdated   *time.Time              `json:"lastUpdated,omitempty" yaml:"lastUpdated,omitempty"`
+	OsName        *string                 `json:"osName,omitempty" yaml:"osName,omitempty"`
+	OsVersion     *string                 `json:"osVersion,omitempty" yaml:"osVersion,omitempty"`
+}
+
+// ServerGuestInterface defines model for ServerGuestInterface.
+type ServerGuestInterface struct {
+	// IpAddresses Addresses in CIDR notation.
+	IpAddresses *[]string `json:"ipAddresses,omitempty" yaml:"ipAddresses,omitempty"`
+	MacAddress  *string   `json:"macAddress,omitempty" yaml:"macAddress,omitempty"`
+
+	// Name Interface name in the guest, e.g. enp1s0.
+	Name *string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// ServerMigration defines model for ServerMigration.
+type ServerMigration struct {
+	ApiVersion *string             `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Kind       *string             `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Metadata   Metadata            `json:"metadata" yaml:"metadata"`
+	Spec       ServerMigrationSpec `json:"spec" yaml:"spec"`
+	Status     *Status             `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// ServerMigrationSpec defines model for ServerMigrationSpec.
+type ServerMigrationSpec struct {
+	// ServerId The id of the migrated server. Set by marmotd.
+	ServerId *string `json:"serverId,omitempty" yaml:"serverId,omitempty"`
+
+	// SourceNode Node the server runs on when the migration starts. Set by marmotd.
+	SourceNode *string `json:"sourceNode,omitempty" yaml:"sourceNode,omitempty"`
+
+	// TargetNode Destination node. When omitted, the scheduler picks a node.
+	TargetNode *string `json:"targetNode,omitempty" yaml:"targetNode,omitempty"`
+
+	// Volumes Local volumes copied by block migration. Set by the source node controller.
+	Volumes *[]ServerMigrationVolume `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+}
+
+// ServerMigrationVolume defines model for ServerMigrationVolume.
+type ServerMigrationVolume struct {
+	// CapacityBytes Virtual size of the disk. The destination disk is created with this size.
+	CapacityBytes *int64 `json:"capacityBytes,omitempty" yaml:"capacityBytes,omitempty"`
+
+	// Kind os or data.
+	Kind          *string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	LogicalVolume *string `json:"logicalVolume,omitempty" yaml:"logicalVolume,omitempty"`
+
+	// Path Path of the qcow2 file or the LVM block device.
+	Path *string `json:"path,omitempty" yaml:"path,omitempty"`
+
+	// Type Volume backend type, qcow2 or lvm.
+	Type        *string `json:"type,omitempty" yaml:"type,omitempty"`
+	VolumeGroup *string `json:"volumeGroup,omitempty" yaml:"volumeGroup,omitempty"`
+	VolumeId    string  `json:"volumeId" yaml:"volumeId"`
+}
+
+// ServerPasswordRequest defines model for ServerPasswordRequest.
+type ServerPasswordRequest struct {
+	// Password The new password. It is stored only as a SHA-512 crypt hash until it is applied.
+	Password string `json:"password" yaml:"password"`
+
+	// User The user in the guest whose password is set.
+	User string `json:"user" yaml:"user"`
+}
+
+// ServerPerformance Performance settings for latency-sensitive servers. They are applied when the domain is defined
+// and cannot be changed after the server is created.
+type ServerPerformance struct {
+	// DedicatedCpu Pin each vCPU to a dedicated host CPU taken from the pinned CPU pool of the node (pinned_cpus in marmotd.json).
+	DedicatedCpu *bool `json:"dedicatedCpu,omitempty" yaml:"dedicatedCpu,omitempty"`
+
+	// Hugepages Back the memory with hugepages of this size, 2M or 1G. The memory size must be a multiple of the page size.
+	Hugepages *string `json:"hugepages,omitempty" yaml:"hugepages,omitempty"`
+
+	// IsolateEmulatorThreads Pin the QEMU emulator threads to one more dedicated host CPU. When false, they run on the shared host CPUs.
+	// Requires dedicatedCpu.
+	IsolateEmulatorThreads *bool `json:"isolateEmulatorThreads,omitempty" yaml:"isolateEmulatorThreads,omitempty"`
+
+	// Numa Place the pinned CPUs and the memory on a single host NUMA node (strict memory policy). Requires dedicatedCpu.
+	Numa *bool `json:"numa,omitempty" yaml:"numa,omitempty"`
+}
+
+// ServerPerformanceStatus Host resources assigned to the server on its node.
+type ServerPerformanceStatus struct {
+	// EmulatorCpus Host CPUs dedicated to the emulator threads.
+	EmulatorCpus *[]int `json:"emulatorCpus,omitempty" yaml:"emulatorCpus,omitempty"`
+
+	// NumaNode Host NUMA node holding the pinned CPUs and the memory.
+	NumaNode *int `json:"numaNode,omitempty" yaml:"numaNode,omitempty"`
+
+	// VcpuPins Host CPU pinned to each vCPU, in vCPU order.
+	VcpuPins *[]int `json:"vcpuPins,omitempty" yaml:"vcpuPins,omitempty"`
+}
+
+// ServerPlacement Placement rules evaluated by the scheduler. The topology domain is the hypervisor node.
+type ServerPlacement struct {
+	Affinity     *ServerAffinity `json:"affinity,omitempty" yaml:"affinity,omitempty"`
+	AntiAffinity *ServerAffinity `json:"antiAffinity,omitempty" yaml:"antiAffinity,omitempty"`
+
+	// NodeSelector Labels the hypervisor node must have (see node_labels in marmotd.json).
+	NodeSelector *map[string]string `json:"nodeSelector,omitempty" yaml:"nodeSelector,omitempty"`
+}
+
+// ServerSnapshot defines model for ServerSnapshot.
+type ServerSnapshot struct {
+	ApiVersion *string            `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	Kind       *string            `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Metadata   Metadata           `json:"metadata" yaml:"metadata"`
+	Spec       ServerSnapshotSpec `json:"spec" yaml:"spec"`
+	Status     *Status            `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+// ServerSnapshotSpec defines model for ServerSnapshotSpec.
+type ServerSnapshotSpec struct {
+	// Consistency Domain handling while taking the snapshot. One of none (default), quiesce, stop.
+	Consistency *string `json:"consistency,omitempty" yaml:"consistency,omitempty"`
+
+	// IncludeDataVolumes Also snapshot the data volumes attached to the server. Defaults to true.
+	IncludeDataVolumes *bool `json:"includeDataVolumes,omitempty" yaml:"includeDataVolumes,omitempty"`
+
+	// ServerId The id of the server the snapshot belongs to. Set by marmotd.
+	ServerId *string `json:"serverId,omitempty" yaml:"serverId,omitempty"`
+
+	// Volumes Volumes captured by the snapshot. Set by the server controller.
+	Volumes *[]ServerSnapshotVolume `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+}
+
+// ServerSnapshotVolume defines model for ServerSnapshotVolume.
+type ServerSnapshotVolume struct {
+	// Kind os or data.
+	Kind *string `json:"kind,omitempty" yaml:"kind,omitempty"`
+
+	// LogicalVolume Origin logical volume of an LVM snapshot.
+	LogicalVolume *string `json:"logicalVolume,omitempty" yaml:"logicalVolume,omitempty"`
+
+	// Path Path of the qcow2 file.
+	Path *string `json:"path,omitempty" yaml:"path,omitempty"`
+
+	// Size Volume size in GB at the time of the snapshot.
+	Size *int `json:"size,omitempty" yaml:"size,omitempty"`
+
+	// SnapshotName qcow2 internal snapshot tag or LVM snapshot logical volume name.
+	SnapshotName *string `json:"snapshotName,omitempty" yaml:"snapshotName,omitempty"`
+
+	// Type Volume backend type, qcow2 or lvm.
+	Type        *string `json:"type,omitempty" yaml:"type,omitempty"`
+	VolumeGroup *string `json:"volumeGroup,omitempty" yaml:"volumeGroup,omitempty"`
+	VolumeId    string  `json:"volumeId" yaml:"volumeId"`
+}
+
 // ServerSpec defines model for ServerSpec.
 type ServerSpec struct {
-	Ansible          *ServerAnsible      `json:"ansible,omitempty" yaml:"ansible,omitempty"`
-	Auth             *Auth               `json:"auth,omitempty" yaml:"auth,omitempty"`
-	BootVolume       *Volume             `json:"bootVolume,omitempty" yaml:"bootVolume,omitempty"`
-	Cpu              *int                `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+	Ansible    *ServerAnsible `json:"ansible,omitempty" yaml:"ansible,omitempty"`
+	Auth       *Auth          `json:"auth,omitempty" yaml:"auth,omitempty"`
+	BootVolume *Volume        `json:"bootVolume,omitempty" yaml:"bootVolume,omitempty"`
+
+	// CloudInit User-supplied cloud-init documents. They are merged with the user, password and SSH key
+	// sections generated by marmot. Network settings are managed by marmot and cannot be changed here.
+	CloudInit *ServerCloudInit `json:"cloudInit,omitempty" yaml:"cloudInit,omitempty"`
+	Cpu       *int             `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+
+	// Firmware Firmware of the server. One of bios, uefi or uefi-secure (UEFI with Secure Boot).
+	// When omitted, the firmware required by the image is used, or bios if the image does not require one.
+	Firmware         *string             `json:"firmware,omitempty" yaml:"firmware,omitempty"`
 	Memory           *int                `json:"memory,omitempty" yaml:"memory,omitempty"`
 	MmImage          *string             `json:"mmImage,omitempty" yaml:"mmImage,omitempty"`
 	NetworkInterface *[]NetworkInterface `json:"networkInterface,omitempty" yaml:"networkInterface,omitempty"`
 	OsLv             *string             `json:"osLv,omitempty" yaml:"osLv,omitempty"`
 	// Deprecated: this property has been marked as deprecated upstream, but no `x-deprecated-reason` was set
-	OsVariant *string   `json:"osVariant,omitempty" yaml:"osVariant,omitempty"`
-	OsVg      *string   `json:"osVg,omitempty" yaml:"osVg,omitempty"`
-	Storage   *[]Volume `json:"storage,omitempty" yaml:"storage,omitempty"`
+	OsVariant *string `json:"osVariant,omitempty" yaml:"osVariant,omitempty"`
+	OsVg      *string `json:"osVg,omitempty" yaml:"osVg,omitempty"`
+
+	// Performance Performance settings for latency-sensitive servers. They are applied when the domain is defined
+	// and cannot be changed after the server is created.
+	Performance *ServerPerformance `json:"performance,omitempty" yaml:"performance,omitempty"`
+
+	// Placement Placement rules evaluated by the scheduler. The topology domain is the hypervisor node.
+	Placement *ServerPlacement `json:"placement,omitempty" yaml:"placement,omitempty"`
+	Storage   *[]Volume        `json:"storage,omitempty" yaml:"storage,omitempty"`
+
+	// Tpm Attach a TPM 2.0 device emulated by swtpm. The TPM state is kept alongside the boot volume.
+	Tpm *bool `json:"tpm,omitempty" yaml:"tpm,omitempty"`
+}
+
+// ServerWeightedAffinityTerm defines model for ServerWeightedAffinityTerm.
+type ServerWeightedAffinityTerm struct {
+	MatchLabels map[string]string `json:"matchLabels" yaml:"matchLabels"`
+	Weight      int               `json:"weight" yaml:"weight"`
 }
 
 // Servers defines model for Servers.
@@ -566,7 +1075,10 @@ type Status struct {
 
 	// AttachProtocol Attach protocol used by backend, e.g. rbd for ceph.
 	AttachProtocol *string `json:"attachProtocol,omitempty" yaml:"attachProtocol,omitempty"`
-	Console        *string `json:"console,omitempty" yaml:"console,omitempty"`
+
+	// AttachedServerId The id of the server the volume is attached to. Empty when the volume is not in use.
+	AttachedServerId *string `json:"attachedServerId,omitempty" yaml:"attachedServerId,omitempty"`
+	Console          *string `json:"console,omitempty" yaml:"console,omitempty"`
 
 	// ControlPlaneHostIpAddress マルモtdホスト側veth(hostVeth)に割り当てた、コントロールプレーンnetnsへの経路確立用IPアドレス。
 	ControlPlaneHostIpAddress *string `json:"controlPlaneHostIpAddress,omitempty" yaml:"controlPlaneHostIpAddress,omitempty"`
@@ -580,10 +1092,19 @@ type Status struct {
 	EtcdClientPort *int `json:"etcdClientPort,omitempty" yaml:"etcdClientPort,omitempty"`
 
 	// EtcdPeerPort クラスタ専用etcd(KubernetesEngine)のピアポート番号。
-	EtcdPeerPort        *int       `json:"etcdPeerPort,omitempty" yaml:"etcdPeerPort,omitempty"`
+	EtcdPeerPort *int `json:"etcdPeerPort,omitempty" yaml:"etcdPeerPort,omitempty"`
+
+	// GuestInfo Information reported by the QEMU guest agent running in the server.
+	GuestInfo *ServerGuestInfo `json:"guestInfo,omitempty" yaml:"guestInfo,omitempty"`
+
+	// JobId The id of the job that tracks the long running operation on the resource.
+	JobId               *string    `json:"jobId,omitempty" yaml:"jobId,omitempty"`
 	LastUpdateTimeStamp *time.Time `json:"lastUpdateTimeStamp,omitempty" yaml:"lastUpdateTimeStamp,omitempty"`
 	Message             *string    `json:"message,omitempty" yaml:"message,omitempty"`
 
+	// Performance Host resources assigned to the server on its node.
+	Performance *ServerPerformanceStatus `json:"performance,omitempty" yaml:"performance,omitempty"`
+
 	// Provider Backend provider identifier, e.g. ceph.
 	Provider *string `json:"provider,omitempty" yaml:"provider,omitempty"`
 
@@ -592,13 +1113,19 @@ type Status struct {
 
 	// ResolvedKubernetesVersion mke.jsonの指定から解決したKubernetesのパッチバージョン。
 	ResolvedKubernetesVersion *string `json:"resolvedKubernetesVersion,omitempty" yaml:"resolvedKubernetesVersion,omitempty"`
-	Status                    *string `json:"status,omitempty" yaml:"status,omitempty"`
-	StatusCode                int     `json:"statusCode" yaml:"statusCode"`
+
+	// Size Size in GB of the volume backing store, recorded when the volume is expanded.
+	Size       *int    `json:"size,omitempty" yaml:"size,omitempty"`
+	Status     *string `json:"status,omitempty" yaml:"status,omitempty"`
+	StatusCode int     `json:"statusCode" yaml:"statusCode"`
 }
 
 // Success defines model for Success.
 type Success struct {
-	Id      string  `json:"id" yaml:"id"`
+	Id string `json:"id" yaml:"id"`
+
+	// JobId The id of the job that tracks the accepted operation, if any.
+	JobId   *string `json:"jobId,omitempty" yaml:"jobId,omitempty"`
 	Message *string `json:"message,omitempty" yaml:"message,omitempty"`
 }
 
@@ -613,9 +1140,12 @@ type User struct {
 
 // UserSpec defines model for UserSpec.
 type UserSpec struct {
-	Comment            *string   `json:"comment,omitempty" yaml:"comment,omitempty"`
-	Email              *string   `json:"email,omitempty" yaml:"email,omitempty"`
-	Enabled            bool      `json:"enabled" yaml:"enabled"`
+	Comment *string `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Email   *string `json:"email,omitempty" yaml:"email,omitempty"`
+	Enabled bool    `json:"enabled" yaml:"enabled"`
+
+	// IdentityProvider oidc for users signed in through the OIDC identity provider. Their roles follow the group claims of the ID token.
+	IdentityProvider   *string   `json:"identityProvider,omitempty" yaml:"identityProvider,omitempty"`
 	MustChangePassword *bool     `json:"mustChangePassword,omitempty" yaml:"mustChangePassword,omitempty"`
 	PasswordHash       *string   `json:"passwordHash,omitempty" yaml:"passwordHash,omitempty"`
 	Roles              *[]string `json:"roles,omitempty" yaml:"roles,omitempty"`
@@ -696,6 +1226,9 @@ type VolSpec struct {
 	Persistent     *bool   `json:"persistent,omitempty" yaml:"persistent,omitempty"`
 	Size           *int    `json:"size,omitempty" yaml:"size,omitempty"`
 
+	// Source Source of a cloned volume.
+	Source *VolumeSource `json:"source,omitempty" yaml:"source,omitempty"`
+
 	// StorageClass Storage classification key. Used for ceph pool selection.
 	StorageClass *string `json:"storageClass,omitempty" yaml:"storageClass,omitempty"`
 
@@ -714,6 +1247,18 @@ type Volume struct {
 	Status     *Status  `json:"status,omitempty" yaml:"status,omitempty"`
 }
 
+// VolumeSource Source of a cloned volume.
+type VolumeSource struct {
+	// Mode Clone mode. One of full (default) or cow. cow is supported for LVM data and Ceph volumes cloned from a volume.
+	Mode *string `json:"mode,omitempty" yaml:"mode,omitempty"`
+
+	// SnapshotId The id of a server snapshot that captured the volume. The volume is cloned as it was when the snapshot was taken.
+	SnapshotId *string `json:"snapshotId,omitempty" yaml:"snapshotId,omitempty"`
+
+	// VolumeId The id of the volume to clone.
+	VolumeId string `json:"volumeId" yaml:"volumeId"`
+}
+
 // VpnGateway defines model for VpnGateway.
 type VpnGateway struct {
 	ApiVersion string         `json:"apiVersion" yaml:"apiVersion"`
@@ -735,6 +1280,156 @@ type VpnGatewaySpec struct {
 	Routes *[]Route `json:"routes,omitempty" yaml:"routes,omitempty"`
 }
 
+// WatchEvent A change of a resource streamed by a list endpoint with watch=true.
+type WatchEvent struct {
+	// Object The resource after the change, its last state for DELETED, or an Error for ERROR.
+	Object *map[string]interface{} `json:"object,omitempty" yaml:"object,omitempty"`
+
+	// ResourceVersion Resource version (etcd revision) of the change. Resume the watch from here.
+	ResourceVersion *string `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+
+	// Type ADDED, MODIFIED, DELETED or ERROR.
+	Type string `json:"type" yaml:"type"`
+}
+
+// ProjectFilter defines model for ProjectFilter.
+type ProjectFilter = string
+
+// ResourceVersion defines model for ResourceVersion.
+type ResourceVersion = string
+
+// Watch defines model for Watch.
+type Watch = bool
+
+// ApiGetJobLogByIdParams defines parameters for ApiGetJobLogById.
+type ApiGetJobLogByIdParams struct {
+	// Lines Return only the last lines of the log.
+	Lines *int `form:"lines,omitempty" json:"lines,omitempty" yaml:"lines,omitempty"`
+}
+
+// ApiGetLoadBalancersParams defines parameters for ApiGetLoadBalancers.
+type ApiGetLoadBalancersParams struct {
+	// Watch Stream changes as server-sent events (text/event-stream) instead of returning the list.
+	// Each event carries a WatchEvent. Without resourceVersion the current objects are sent as ADDED events first.
+	Watch *Watch `form:"watch,omitempty" json:"watch,omitempty" yaml:"watch,omitempty"`
+
+	// ResourceVersion With watch=true, send only the changes made after this resource version (etcd revision).
+	// A version that is no longer available is reported by an ERROR event with code 410.
+	ResourceVersion *ResourceVersion `form:"resourceVersion,omitempty" json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+
+	// Project Return only the resources in this project.
+	Project *ProjectFilter `form:"project,omitempty" json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// ApiGetCertificatesParams defines parameters for ApiGetCertificates.
+type ApiGetCertificatesParams struct {
+	// Project Return only the resources in this project.
+	Project *ProjectFilter `form:"project,omitempty" json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// ApiGetAuditRecordsParams defines parameters for ApiGetAuditRecords.
+type ApiGetAuditRecordsParams struct {
+	// UserId Return only the requests made by this user.
+	UserId *string `form:"userId,omitempty" json:"userId,omitempty" yaml:"userId,omitempty"`
+
+	// OperationId Return only the requests of this API operation (e.g. apiDeleteServerById).
+	OperationId *string `form:"operationId,omitempty" json:"operationId,omitempty" yaml:"operationId,omitempty"`
+
+	// Resource Return only the requests for this resource kind (e.g. Server).
+	Resource *string `form:"resource,omitempty" json:"resource,omitempty" yaml:"resource,omitempty"`
+
+	// ResourceId Return only the requests for this resource id.
+	ResourceId *string `form:"resourceId,omitempty" json:"resourceId,omitempty" yaml:"resourceId,omitempty"`
+
+	// Result Return only the requests with this result (success, failure or denied).
+	Result *string `form:"result,omitempty" json:"result,omitempty" yaml:"result,omitempty"`
+
+	// Since Return only the requests made at or after this time.
+	Since *time.Time `form:"since,omitempty" json:"since,omitempty" yaml:"since,omitempty"`
+
+	// Until Return only the requests made before this time.
+	Until *time.Time `form:"until,omitempty" json:"until,omitempty" yaml:"until,omitempty"`
+
+	// Limit Maximum number of records to return (default 100, at most 1000).
+	Limit *int `form:"limit,omitempty" json:"limit,omitempty" yaml:"limit,omitempty"`
+}
+
+// ApiGetImagesParams defines parameters for ApiGetImages.
+type ApiGetImagesParams struct {
+	// Watch Stream changes as server-sent events (text/event-stream) instead of returning the list.
+	// Each event carries a WatchEvent. Without resourceVersion the current objects are sent as ADDED events first.
+	Watch *Watch `form:"watch,omitempty" json:"watch,omitempty" yaml:"watch,omitempty"`
+
+	// ResourceVersion With watch=true, send only the changes made after this resource version (etcd revision).
+	// A version that is no longer available is reported by an ERROR event with code 410.
+	ResourceVersion *ResourceVersion `form:"resourceVersion,omitempty" json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+
+	// Project Return only the resources in this project.
+	Project *ProjectFilter `form:"project,omitempty" json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// ApiGetNetworksParams defines parameters for ApiGetNetworks.
+type ApiGetNetworksParams struct {
+	// Watch Stream changes as server-sent events (text/event-stream) instead of returning the list.
+	// Each event carries a WatchEvent. Without resourceVersion the current objects are sent as ADDED events first.
+	Watch *Watch `form:"watch,omitempty" json:"watch,omitempty" yaml:"watch,omitempty"`
+
+	// ResourceVersion With watch=true, send only the changes made after this resource version (etcd revision).
+	// A version that is no longer available is reported by an ERROR event with code 410.
+	ResourceVersion *ResourceVersion `form:"resourceVersion,omitempty" json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+
+	// Project Return only the resources in this project.
+	Project *ProjectFilter `form:"project,omitempty" json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// ApiGetNetworkLoadBalancersParams defines parameters for ApiGetNetworkLoadBalancers.
+type ApiGetNetworkLoadBalancersParams struct {
+	// Watch Stream changes as server-sent events (text/event-stream) instead of returning the list.
+	// Each event carries a WatchEvent. Without resourceVersion the current objects are sent as ADDED events first.
+	Watch *Watch `form:"watch,omitempty" json:"watch,omitempty" yaml:"watch,omitempty"`
+
+	// ResourceVersion With watch=true, send only the changes made after this resource version (etcd revision).
+	// A version that is no longer available is reported by an ERROR event with code 410.
+	ResourceVersion *ResourceVersion `form:"resourceVersion,omitempty" json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+
+	// Project Return only the resources in this project.
+	Project *ProjectFilter `form:"project,omitempty" json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// ApiGetServersParams defines parameters for ApiGetServers.
+type ApiGetServersParams struct {
+	// Watch Stream changes as server-sent events (text/event-stream) instead of returning the list.
+	// Each event carries a WatchEvent. Without resourceVersion the current objects are sent as ADDED events first.
+	Watch *Watch `form:"watch,omitempty" json:"watch,omitempty" yaml:"watch,omitempty"`
+
+	// ResourceVersion With watch=true, send only the changes made after this resource version (etcd revision).
+	// A version that is no longer available is reported by an ERROR event with code 410.
+	ResourceVersion *ResourceVersion `form:"resourceVersion,omitempty" json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+
+	// Project Return only the resources in this project.
+	Project *ProjectFilter `form:"project,omitempty" json:"project,omitempty" yaml:"project,omitempty"`
+}
+
+// ApiGraphicalConsoleServerByIdParams defines parameters for ApiGraphicalConsoleServerById.
+type ApiGraphicalConsoleServerByIdParams struct {
+	// Token The console token
+	Token string `form:"token" json:"token" yaml:"token"`
+}
+
+// ApiListVolumesParams defines parameters for ApiListVolumes.
+type ApiListVolumesParams struct {
+	// Watch Stream changes as server-sent events (text/event-stream) instead of returning the list.
+	// Each event carries a WatchEvent. Without resourceVersion the current objects are sent as ADDED events first.
+	Watch *Watch `form:"watch,omitempty" json:"watch,omitempty" yaml:"watch,omitempty"`
+
+	// ResourceVersion With watch=true, send only the changes made after this resource version (etcd revision).
+	// A version that is no longer available is reported by an ERROR event with code 410.
+	ResourceVersion *ResourceVersion `form:"resourceVersion,omitempty" json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+
+	// Project Return only the resources in this project.
+	Project *ProjectFilter `form:"project,omitempty" json:"project,omitempty" yaml:"project,omitempty"`
+}
+
 // ApiCreateLoadBalancerJSONRequestBody defines body for ApiCreateLoadBalancer for application/json ContentType.
 type ApiCreateLoadBalancerJSONRequestBody = ApplicationLoadBalancer
 
@@ -744,9 +1439,21 @@ type ApiUpdateLoadBalancerByIdJSONRequestBody = ApplicationLoadBalancer
 // ApiAuthLoginJSONRequestBody defines body for ApiAuthLogin for application/json ContentType.
 type ApiAuthLoginJSONRequestBody = AuthLoginRequest
 
+// ApiAuthOidcLoginJSONRequestBody defines body for ApiAuthOidcLogin for application/json ContentType.
+type ApiAuthOidcLoginJSONRequestBody = AuthOidcLoginRequest
+
 // ApiAuthzCheckJSONRequestBody defines body for ApiAuthzCheck for application/json ContentType.
 type ApiAuthzCheckJSONRequestBody = AuthzCheckRequest
 
+// ApiCreateCertificateJSONRequestBody defines body for ApiCreateCertificate for application/json ContentType.
+type ApiCreateCertificateJSONRequestBody = Certificate
+
+// ApiUpdateCertificateByIdJSONRequestBody defines body for ApiUpdateCertificateById for application/json ContentType.
+type ApiUpdateCertificateByIdJSONRequestBody = Certificate
+
+// ApiCreateDnsRecordJSONRequestBody defines body for ApiCreateDnsRecord for application/json ContentType.
+type ApiCreateDnsRecordJSONRequestBody = DnsRecord
+
 // ApiCreateGatewayJSONRequestBody defines body for ApiCreateGateway for application/json ContentType.
 type ApiCreateGatewayJSONRequestBody = Gateway
 
@@ -762,6 +1469,12 @@ type ApiUpdateImageByIdJSONRequestBody = Image
 // ApiCreateKubernetesEngineJSONRequestBody defines body for ApiCreateKubernetesEngine for application/json ContentType.
 type ApiCreateKubernetesEngineJSONRequestBody = KubernetesEngine
 
+// ApiCordonNodeJSONRequestBody defines body for ApiCordonNode for application/json ContentType.
+type ApiCordonNodeJSONRequestBody = NodeMaintenance
+
+// ApiDrainNodeJSONRequestBody defines body for ApiDrainNode for application/json ContentType.
+type ApiDrainNodeJSONRequestBody = NodeMaintenance
+
 // ApiCreateNetworkJSONRequestBody defines body for ApiCreateNetwork for application/json ContentType.
 type ApiCreateNetworkJSONRequestBody = VirtualNetwork
 
@@ -774,12 +1487,42 @@ type ApiUpdateNetworkLoadBalancerByIdJSONRequestBody = NetworkLoadBalancer
 // ApiUpdateNetworkByIdJSONRequestBody defines body for ApiUpdateNetworkById for application/json ContentType.
 type ApiUpdateNetworkByIdJSONRequestBody = VirtualNetwork
 
+// ApiCreateQuotaJSONRequestBody defines body for ApiCreateQuota for application/json ContentType.
+type ApiCreateQuotaJSONRequestBody = Quota
+
+// ApiUpdateQuotaByIdJSONRequestBody defines body for ApiUpdateQuotaById for application/json ContentType.
+type ApiUpdateQuotaByIdJSONRequestBody = Quota
+
+// ApiCreateRoleJSONRequestBody defines body for ApiCreateRole for application/json ContentType.
+type ApiCreateRoleJSONRequestBody = Role
+
+// ApiUpdateRoleByNameJSONRequestBody defines body for ApiUpdateRoleByName for application/json ContentType.
+type ApiUpdateRoleByNameJSONRequestBody = Role
+
+// ApiCreateProjectJSONRequestBody defines body for ApiCreateProject for application/json ContentType.
+type ApiCreateProjectJSONRequestBody = Project
+
+// ApiUpdateProjectByNameJSONRequestBody defines body for ApiUpdateProjectByName for application/json ContentType.
+type ApiUpdateProjectByNameJSONRequestBody = Project
+
 // ApiCreateServerJSONRequestBody defines body for ApiCreateServer for application/json ContentType.
 type ApiCreateServerJSONRequestBody = Server
 
 // ApiMakeImageEntryFromRunningVMByIdJSONRequestBody defines body for ApiMakeImageEntryFromRunningVMById for application/json ContentType.
 type ApiMakeImageEntryFromRunningVMByIdJSONRequestBody = Image
 
+// ApiCloneServerJSONRequestBody defines body for ApiCloneServer for application/json ContentType.
+type ApiCloneServerJSONRequestBody = ServerCloneRequest
+
+// ApiMigrateServerJSONRequestBody defines body for ApiMigrateServer for application/json ContentType.
+type ApiMigrateServerJSONRequestBody = ServerMigration
+
+// ApiSetServerPasswordJSONRequestBody defines body for ApiSetServerPassword for application/json ContentType.
+type ApiSetServerPasswordJSONRequestBody = ServerPasswordRequest
+
+// ApiCreateServerSnapshotJSONRequestBody defines body for ApiCreateServerSnapshot for application/json ContentType.
+type ApiCreateServerSnapshotJSONRequestBody = ServerSnapshot
+
 // ApiUpdateServerByIdJSONRequestBody defines body for ApiUpdateServerById for application/json ContentType.
 type ApiUpdateServerByIdJSONRequestBody = Server
 
@@ -814,7 +1557,7 @@ type ApiUpdateVpnGatewayByIdJSONRequestBody = VpnGateway
 type ServerInterface interface {
 	// ApiGetLoadBalancers List ApplicationLoadBalancers
 	// (GET /application-load-balancer)
-	ApiGetLoadBalancers(ctx echo.Context) error
+	ApiGetLoadBalancers(ctx echo.Context, params ApiGetLoadBalancersParams) error
 	// ApiCreateLoadBalancer Create ApplicationLoadBalancer
 	// (POST /application-load-balancer)
 	ApiCreateLoadBalancer(ctx echo.Context) error
@@ -827,6 +1570,9 @@ type ServerInterface interface {
 	// ApiUpdateLoadBalancerById Update ApplicationLoadBalancer
 	// (PUT /application-load-balancer/{id})
 	ApiUpdateLoadBalancerById(ctx echo.Context, id string) error
+	// ApiGetAuditRecords List audit records
+	// (GET /audit)
+	ApiGetAuditRecords(ctx echo.Context, params ApiGetAuditRecordsParams) error
 	// ApiAuthLogin Login and issue access token
 	// (POST /auth/login)
 	ApiAuthLogin(ctx echo.Context) error
@@ -836,9 +1582,42 @@ type ServerInterface interface {
 	// ApiAuthMe Get current authenticated user
 	// (GET /auth/me)
 	ApiAuthMe(ctx echo.Context) error
+	// ApiAuthOidcConfig Get the OIDC identity provider settings for login
+	// (GET /auth/oidc)
+	ApiAuthOidcConfig(ctx echo.Context) error
+	// ApiAuthOidcLogin Login with an ID token from the OIDC identity provider and issue access token
+	// (POST /auth/oidc/login)
+	ApiAuthOidcLogin(ctx echo.Context) error
 	// ApiAuthzCheck Check authorization for an action
 	// (POST /authz/check)
 	ApiAuthzCheck(ctx echo.Context) error
+	// ApiGetCertificates List TLS certificates
+	// (GET /certificate)
+	ApiGetCertificates(ctx echo.Context, params ApiGetCertificatesParams) error
+	// ApiCreateCertificate Register a TLS certificate
+	// (POST /certificate)
+	ApiCreateCertificate(ctx echo.Context) error
+	// ApiDeleteCertificateById Delete a TLS certificate
+	// (DELETE /certificate/{id})
+	ApiDeleteCertificateById(ctx echo.Context, id string) error
+	// ApiGetCertificateById Info for a specific TLS certificate
+	// (GET /certificate/{id})
+	ApiGetCertificateById(ctx echo.Context, id string) error
+	// ApiUpdateCertificateById Replace a TLS certificate
+	// (PUT /certificate/{id})
+	ApiUpdateCertificateById(ctx echo.Context, id string) error
+	// ApiGetDnsRecords List custom DNS records
+	// (GET /dns-record)
+	ApiGetDnsRecords(ctx echo.Context) error
+	// ApiCreateDnsRecord Create a custom DNS record
+	// (POST /dns-record)
+	ApiCreateDnsRecord(ctx echo.Context) error
+	// ApiDeleteDnsRecordById Delete a custom DNS record
+	// (DELETE /dns-record/{id})
+	ApiDeleteDnsRecordById(ctx echo.Context, id string) error
+	// ApiGetDnsRecordById Info for a specific custom DNS record
+	// (GET /dns-record/{id})
+	ApiGetDnsRecordById(ctx echo.Context, id string) error
 	// ApiGetGateways List Gateways
 	// (GET /gateway)
 	ApiGetGateways(ctx echo.Context) error
@@ -859,7 +1638,7 @@ type ServerInterface interface {
 	ApiGetGatewayCertById(ctx echo.Context, id string) error
 	// ApiGetImages List Images
 	// (GET /image)
-	ApiGetImages(ctx echo.Context) error
+	ApiGetImages(ctx echo.Context, params ApiGetImagesParams) error
 	// ApiCreateImage Create Image
 	// (POST /image)
 	ApiCreateImage(ctx echo.Context) error
@@ -878,6 +1657,22 @@ type ServerInterface interface {
 	// ApiGetIpAddressesByNetwork Get assigned IP addresses for a specific IP network
 	// (GET /ipnetwork/{id}/addresses)
 	ApiGetIpAddressesByNetwork(ctx echo.Context, id string) error
+	// ApiGetJobs List Jobs
+	// (GET /job)
+	ApiGetJobs(ctx echo.Context) error
+
+	// ApiGetJobById Info for a specific job
+	// (GET /job/{id})
+	ApiGetJobById(ctx echo.Context, id string) error
+
+	// ApiCancelJobById Cancel Job
+	// (POST /job/{id}/cancel)
+	ApiCancelJobById(ctx echo.Context, id string) error
+
+	// ApiGetJobLogById Get Job Log
+	// (GET /job/{id}/log)
+	ApiGetJobLogById(ctx echo.Context, id string, params ApiGetJobLogByIdParams) error
+
 	// ApiGetKubernetesEngines List KubernetesEngines
 	// (GET /kubernetes-engine)
 	ApiGetKubernetesEngines(ctx echo.Context) error
@@ -896,18 +1691,27 @@ type ServerInterface interface {
 	// ApiGetMarmotCluster Get Marmot Cluster Status
 	// (GET /marmot/cluster)
 	ApiGetMarmotCluster(ctx echo.Context) error
+	// ApiCordonNode Cordon a node
+	// (POST /marmot/node/{nodeName}/cordon)
+	ApiCordonNode(ctx echo.Context, nodeName string) error
+	// ApiDrainNode Drain a node
+	// (POST /marmot/node/{nodeName}/drain)
+	ApiDrainNode(ctx echo.Context, nodeName string) error
+	// ApiUncordonNode Uncordon a node
+	// (POST /marmot/node/{nodeName}/uncordon)
+	ApiUncordonNode(ctx echo.Context, nodeName string) error
 	// ApiGetMarmotStatus Get Marmot Host Status
 	// (GET /marmot/status)
 	ApiGetMarmotStatus(ctx echo.Context) error
 	// ApiGetNetworks Get Network Information
 	// (GET /network)
-	ApiGetNetworks(ctx echo.Context) error
+	ApiGetNetworks(ctx echo.Context, params ApiGetNetworksParams) error
 	// ApiCreateNetwork Create Virtual Network
 	// (POST /network)
 	ApiCreateNetwork(ctx echo.Context) error
 	// ApiGetNetworkLoadBalancers List NetworkLoadBalancers
 	// (GET /network-load-balancer)
-	ApiGetNetworkLoadBalancers(ctx echo.Context) error
+	ApiGetNetworkLoadBalancers(ctx echo.Context, params ApiGetNetworkLoadBalancersParams) error
 	// ApiCreateNetworkLoadBalancer Create NetworkLoadBalancer
 	// (POST /network-load-balancer)
 	ApiCreateNetworkLoadBalancer(ctx echo.Context) error
@@ -935,15 +1739,54 @@ type ServerInterface interface {
 	// ApiReplyPing Alive
 	// (GET /ping)
 	ApiReplyPing(ctx echo.Context) error
+	// ApiGetProjects List projects
+	// (GET /projects)
+	ApiGetProjects(ctx echo.Context) error
+	// ApiCreateProject Create a project
+	// (POST /projects)
+	ApiCreateProject(ctx echo.Context) error
+	// ApiDeleteProjectByName Delete a project
+	// (DELETE /projects/{projectName})
+	ApiDeleteProjectByName(ctx echo.Context, projectName string) error
+	// ApiGetProjectByName Info for a specific project
+	// (GET /projects/{projectName})
+	ApiGetProjectByName(ctx echo.Context, projectName string) error
+	// ApiUpdateProjectByName Replace the description and members of a project
+	// (PUT /projects/{projectName})
+	ApiUpdateProjectByName(ctx echo.Context, projectName string) error
+	// ApiGetQuotas List quotas
+	// (GET /quota)
+	ApiGetQuotas(ctx echo.Context) error
+	// ApiCreateQuota Create a quota
+	// (POST /quota)
+	ApiCreateQuota(ctx echo.Context) error
+	// ApiDeleteQuotaById Delete a quota
+	// (DELETE /quota/{quotaId})
+	ApiDeleteQuotaById(ctx echo.Context, quotaId string) error
+	// ApiGetQuotaById Info for a specific quota
+	// (GET /quota/{quotaId})
+	ApiGetQuotaById(ctx echo.Context, quotaId string) error
+	// ApiUpdateQuotaById Replace a quota
+	// (PUT /quota/{quotaId})
+	ApiUpdateQuotaById(ctx echo.Context, quotaId string) error
 	// ApiListRoles List available roles
 	// (GET /roles)
 	ApiListRoles(ctx echo.Context) error
 	// ApiGetRoleByName Get a role
 	// (GET /roles/{roleName})
 	ApiGetRoleByName(ctx echo.Context, roleName string) error
+	// ApiCreateRole Create a custom role
+	// (POST /roles)
+	ApiCreateRole(ctx echo.Context) error
+	// ApiUpdateRoleByName Replace the permissions of a custom role
+	// (PUT /roles/{roleName})
+	ApiUpdateRoleByName(ctx echo.Context, roleName string) error
+	// ApiDeleteRoleByName Delete a custom role
+	// (DELETE /roles/{roleName})
+	ApiDeleteRoleByName(ctx echo.Context, roleName string) error
 	// ApiGetServers Get Server Information
 	// (GET /server)
-	ApiGetServers(ctx echo.Context) error
+	ApiGetServers(ctx echo.Context, params ApiGetServersParams) error
 	// ApiCreateServer Create Virtual Server
 	// (POST /server)
 	ApiCreateServer(ctx echo.Context) error
@@ -959,15 +1802,54 @@ type ServerInterface interface {
 	// ApiUpdateServerById Update Server Information by Id
 	// (PUT /server/{id})
 	ApiUpdateServerById(ctx echo.Context, id string) error
+	// ApiCloneServer Clone Server
+	// (POST /server/{id}/clone)
+	ApiCloneServer(ctx echo.Context, id string) error
 	// ApiConsoleServerById Connect to Server Console by Id
 	// (GET /server/{id}/console)
 	ApiConsoleServerById(ctx echo.Context, id string) error
+	// ApiGraphicalConsoleServerById Connect to Server Graphical Console by Id
+	// (GET /server/{id}/console/graphical)
+	ApiGraphicalConsoleServerById(ctx echo.Context, id string, params ApiGraphicalConsoleServerByIdParams) error
+	// ApiCreateServerConsoleToken Create Server Graphical Console Token
+	// (POST /server/{id}/console/token)
+	ApiCreateServerConsoleToken(ctx echo.Context, id string) error
+	// ApiGetServerMigration Show Server Migration
+	// (GET /server/{id}/migrate)
+	ApiGetServerMigration(ctx echo.Context, id string) error
+	// ApiMigrateServer Migrate Server
+	// (POST /server/{id}/migrate)
+	ApiMigrateServer(ctx echo.Context, id string) error
+	// ApiSetServerPassword Set Server User Password
+	// (POST /server/{id}/password)
+	ApiSetServerPassword(ctx echo.Context, id string) error
+	// ApiListServerSnapshots List Server Snapshots
+	// (GET /server/{id}/snapshot)
+	ApiListServerSnapshots(ctx echo.Context, id string) error
+	// ApiCreateServerSnapshot Create Server Snapshot
+	// (POST /server/{id}/snapshot)
+	ApiCreateServerSnapshot(ctx echo.Context, id string) error
+	// ApiDeleteServerSnapshotById Delete Server Snapshot
+	// (DELETE /server/{id}/snapshot/{snapshotId})
+	ApiDeleteServerSnapshotById(ctx echo.Context, id string, snapshotId string) error
+	// ApiGetServerSnapshotById Info for a specific server snapshot
+	// (GET /server/{id}/snapshot/{snapshotId})
+	ApiGetServerSnapshotById(ctx echo.Context, id string, snapshotId string) error
+	// ApiRevertServerSnapshotById Revert Server to Snapshot
+	// (POST /server/{id}/snapshot/{snapshotId}/revert)
+	ApiRevertServerSnapshotById(ctx echo.Context, id string, snapshotId string) error
 	// ApiStartServerById Start Server by Id
 	// (POST /server/{id}/start)
 	ApiStartServerById(ctx echo.Context, id string) error
 	// ApiStopServerById Stop Server by Id
 	// (POST /server/{id}/stop)
 	ApiStopServerById(ctx echo.Context, id string) error
+	// ApiDetachServerVolume Detach Volume from Server
+	// (DELETE /server/{id}/volumes/{volumeId})
+	ApiDetachServerVolume(ctx echo.Context, id string, volumeId string) error
+	// ApiAttachServerVolume Attach Volume to Server
+	// (POST /server/{id}/volumes/{volumeId})
+	ApiAttachServerVolume(ctx echo.Context, id string, volumeId string) error
 	// ApiListUsers List users
 	// (GET /users)
 	ApiListUsers(ctx echo.Context) error
@@ -998,6 +1880,9 @@ type ServerInterface interface {
 	// ApiChangeUserPassword Change or reset user password
 	// (POST /users/{userId}/password)
 	ApiChangeUserPassword(ctx echo.Context, userId string) error
+	// ApiGetUserQuotaUsage Resource usage and quotas of a user
+	// (GET /users/{userId}/quota)
+	ApiGetUserQuotaUsage(ctx echo.Context, userId string) error
 	// ApiListUserRoles List roles assigned to a user
 	// (GET /users/{userId}/roles)
 	ApiListUserRoles(ctx echo.Context, userId string) error
@@ -1015,7 +1900,7 @@ type ServerInterface interface {
 	ApiGetVersion(ctx echo.Context) error
 	// ApiListVolumes List Volumes
 	// (GET /volume)
-	ApiListVolumes(ctx echo.Context) error
+	ApiListVolumes(ctx echo.Context, params ApiListVolumesParams) error
 	// ApiCreateVolume Create Volume
 	// (POST /volume)
 	ApiCreateVolume(ctx echo.Context) error
@@ -1057,17 +1942,40 @@ type ServerInterfaceWrapper struct {
 func (w *ServerInterfaceWrapper) ApiGetLoadBalancers(ctx echo.Context) error {
 	var err error
 
-	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetLoadBalancers(ctx)
-	return err
-}
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiGetLoadBalancersParams
+	// ------------- Optional query parameter "watch" -------------
 
-// ApiCreateLoadBalancer converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiCreateLoadBalancer(ctx echo.Context) error {
-	var err error
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "watch", ctx.QueryParams(), &params.Watch, runtime.BindQueryParameterOptions{Type: "boolean", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter watch: %s", err))
+	}
 
-	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiCreateLoadBalancer(ctx)
+	// ------------- Optional query parameter "resourceVersion" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "resourceVersion", ctx.QueryParams(), &params.ResourceVersion, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter resourceVersion: %s", err))
+	}
+
+	// ------------- Optional query parameter "project" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "project", ctx.QueryParams(), &params.Project, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter project: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetLoadBalancers(ctx, params)
+	return err
+}
+
+// ApiCreateLoadBalancer converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateLoadBalancer(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiCreateLoadBalancer(ctx)
 	return err
 }
 
@@ -1119,6 +2027,73 @@ func (w *ServerInterfaceWrapper) ApiUpdateLoadBalancerById(ctx echo.Context) err
 	return err
 }
 
+// ApiGetAuditRecords converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetAuditRecords(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiGetAuditRecordsParams
+	// ------------- Optional query parameter "userId" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "userId", ctx.QueryParams(), &params.UserId, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter userId: %s", err))
+	}
+
+	// ------------- Optional query parameter "operationId" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "operationId", ctx.QueryParams(), &params.OperationId, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter operationId: %s", err))
+	}
+
+	// ------------- Optional query parameter "resource" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "resource", ctx.QueryParams(), &params.Resource, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter resource: %s", err))
+	}
+
+	// ------------- Optional query parameter "resourceId" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "resourceId", ctx.QueryParams(), &params.ResourceId, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter resourceId: %s", err))
+	}
+
+	// ------------- Optional query parameter "result" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "result", ctx.QueryParams(), &params.Result, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter result: %s", err))
+	}
+
+	// ------------- Optional query parameter "since" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "since", ctx.QueryParams(), &params.Since, runtime.BindQueryParameterOptions{Type: "string", Format: "date-time"})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter since: %s", err))
+	}
+
+	// ------------- Optional query parameter "until" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "until", ctx.QueryParams(), &params.Until, runtime.BindQueryParameterOptions{Type: "string", Format: "date-time"})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter until: %s", err))
+	}
+
+	// ------------- Optional query parameter "limit" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "limit", ctx.QueryParams(), &params.Limit, runtime.BindQueryParameterOptions{Type: "integer", Format: "int"})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter limit: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetAuditRecords(ctx, params)
+	return err
+}
+
 // ApiAuthLogin converts echo context to params.
 func (w *ServerInterfaceWrapper) ApiAuthLogin(ctx echo.Context) error {
 	var err error
@@ -1146,6 +2121,24 @@ func (w *ServerInterfaceWrapper) ApiAuthMe(ctx echo.Context) error {
 	return err
 }
 
+// ApiAuthOidcConfig converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiAuthOidcConfig(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiAuthOidcConfig(ctx)
+	return err
+}
+
+// ApiAuthOidcLogin converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiAuthOidcLogin(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiAuthOidcLogin(ctx)
+	return err
+}
+
 // ApiAuthzCheck converts echo context to params.
 func (w *ServerInterfaceWrapper) ApiAuthzCheck(ctx echo.Context) error {
 	var err error
@@ -1155,6 +2148,131 @@ func (w *ServerInterfaceWrapper) ApiAuthzCheck(ctx echo.Context) error {
 	return err
 }
 
+// ApiGetCertificates converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetCertificates(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiGetCertificatesParams
+	// ------------- Optional query parameter "project" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "project", ctx.QueryParams(), &params.Project, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter project: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetCertificates(ctx, params)
+	return err
+}
+
+// ApiCreateCertificate converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateCertificate(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiCreateCertificate(ctx)
+	return err
+}
+
+// ApiDeleteCertificateById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDeleteCertificateById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiDeleteCertificateById(ctx, id)
+	return err
+}
+
+// ApiGetCertificateById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetCertificateById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetCertificateById(ctx, id)
+	return err
+}
+
+// ApiUpdateCertificateById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiUpdateCertificateById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiUpdateCertificateById(ctx, id)
+	return err
+}
+
+// ApiGetDnsRecords converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetDnsRecords(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetDnsRecords(ctx)
+	return err
+}
+
+// ApiCreateDnsRecord converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateDnsRecord(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiCreateDnsRecord(ctx)
+	return err
+}
+
+// ApiDeleteDnsRecordById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDeleteDnsRecordById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiDeleteDnsRecordById(ctx, id)
+	return err
+}
+
+// ApiGetDnsRecordById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetDnsRecordById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetDnsRecordById(ctx, id)
+	return err
+}
+
 // ApiGetGateways converts echo context to params.
 func (w *ServerInterfaceWrapper) ApiGetGateways(ctx echo.Context) error {
 	var err error
@@ -1241,8 +2359,31 @@ func (w *ServerInterfaceWrapper) ApiGetGatewayCertById(ctx echo.Context) error {
 func (w *ServerInterfaceWrapper) ApiGetImages(ctx echo.Context) error {
 	var err error
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiGetImagesParams
+	// ------------- Optional query parameter "watch" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "watch", ctx.QueryParams(), &params.Watch, runtime.BindQueryParameterOptions{Type: "boolean", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter watch: %s", err))
+	}
+
+	// ------------- Optional query parameter "resourceVersion" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "resourceVersion", ctx.QueryParams(), &params.ResourceVersion, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter resourceVersion: %s", err))
+	}
+
+	// ------------- Optional query parameter "project" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "project", ctx.QueryParams(), &params.Project, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter project: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetImages(ctx)
+	err = w.Handler.ApiGetImages(ctx, params)
 	return err
 }
 
@@ -1328,6 +2469,72 @@ func (w *ServerInterfaceWrapper) ApiGetIpAddressesByNetwork(ctx echo.Context) er
 	return err
 }
 
+// ApiGetJobs converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetJobs(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetJobs(ctx)
+	return err
+}
+
+// ApiGetJobById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetJobById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetJobById(ctx, id)
+	return err
+}
+
+// ApiCancelJobById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCancelJobById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiCancelJobById(ctx, id)
+	return err
+}
+
+// ApiGetJobLogById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetJobLogById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiGetJobLogByIdParams
+	// ------------- Optional query parameter "lines" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "lines", ctx.QueryParams(), &params.Lines, runtime.BindQueryParameterOptions{Type: "integer", Format: "int"})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter lines: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetJobLogById(ctx, id, params)
+	return err
+}
+
 // ApiGetKubernetesEngines converts echo context to params.
 func (w *ServerInterfaceWrapper) ApiGetKubernetesEngines(ctx echo.Context) error {
 	var err error
@@ -1358,98 +2565,568 @@ func (w *ServerInterfaceWrapper) ApiDeleteKubernetesEngineById(ctx echo.Context)
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiDeleteKubernetesEngineById(ctx, id)
+	err = w.Handler.ApiDeleteKubernetesEngineById(ctx, id)
+	return err
+}
+
+// ApiGetKubernetesEngineById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetKubernetesEngineById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetKubernetesEngineById(ctx, id)
+	return err
+}
+
+// ApiGetKubernetesEngineKubeconfigById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetKubernetesEngineKubeconfigById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetKubernetesEngineKubeconfigById(ctx, id)
+	return err
+}
+
+// ApiGetMarmotCluster converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetMarmotCluster(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetMarmotCluster(ctx)
+	return err
+}
+
+// ApiCordonNode converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCordonNode(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "nodeName" -------------
+	var nodeName string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "nodeName", ctx.Param("nodeName"), &nodeName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter nodeName: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiCordonNode(ctx, nodeName)
+	return err
+}
+
+// ApiDrainNode converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDrainNode(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "nodeName" -------------
+	var nodeName string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "nodeName", ctx.Param("nodeName"), &nodeName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter nodeName: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiDrainNode(ctx, nodeName)
+	return err
+}
+
+// ApiUncordonNode converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiUncordonNode(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "nodeName" -------------
+	var nodeName string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "nodeName", ctx.Param("nodeName"), &nodeName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter nodeName: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiUncordonNode(ctx, nodeName)
+	return err
+}
+
+// ApiGetMarmotStatus converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetMarmotStatus(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetMarmotStatus(ctx)
+	return err
+}
+
+// ApiGetNetworks converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetNetworks(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiGetNetworksParams
+	// ------------- Optional query parameter "watch" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "watch", ctx.QueryParams(), &params.Watch, runtime.BindQueryParameterOptions{Type: "boolean", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter watch: %s", err))
+	}
+
+	// ------------- Optional query parameter "resourceVersion" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "resourceVersion", ctx.QueryParams(), &params.ResourceVersion, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter resourceVersion: %s", err))
+	}
+
+	// ------------- Optional query parameter "project" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "project", ctx.QueryParams(), &params.Project, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter project: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetNetworks(ctx, params)
+	return err
+}
+
+// ApiCreateNetwork converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateNetwork(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiCreateNetwork(ctx)
+	return err
+}
+
+// ApiGetNetworkLoadBalancers converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetNetworkLoadBalancers(ctx echo.Context) error {
+	var err error
+
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiGetNetworkLoadBalancersParams
+	// ------------- Optional query parameter "watch" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "watch", ctx.QueryParams(), &params.Watch, runtime.BindQueryParameterOptions{Type: "boolean", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter watch: %s", err))
+	}
+
+	// ------------- Optional query parameter "resourceVersion" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "resourceVersion", ctx.QueryParams(), &params.ResourceVersion, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter resourceVersion: %s", err))
+	}
+
+	// ------------- Optional query parameter "project" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "project", ctx.QueryParams(), &params.Project, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter project: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetNetworkLoadBalancers(ctx, params)
+	return err
+}
+
+// ApiCreateNetworkLoadBalancer converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateNetworkLoadBalancer(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiCreateNetworkLoadBalancer(ctx)
+	return err
+}
+
+// ApiDeleteNetworkLoadBalancerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDeleteNetworkLoadBalancerById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiDeleteNetworkLoadBalancerById(ctx, id)
+	return err
+}
+
+// ApiGetNetworkLoadBalancerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetNetworkLoadBalancerById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetNetworkLoadBalancerById(ctx, id)
+	return err
+}
+
+// ApiUpdateNetworkLoadBalancerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiUpdateNetworkLoadBalancerById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiUpdateNetworkLoadBalancerById(ctx, id)
+	return err
+}
+
+// ApiDeleteNetworkById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDeleteNetworkById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiDeleteNetworkById(ctx, id)
+	return err
+}
+
+// ApiGetNetworkById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetNetworkById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetNetworkById(ctx, id)
+	return err
+}
+
+// ApiUpdateNetworkById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiUpdateNetworkById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiUpdateNetworkById(ctx, id)
+	return err
+}
+
+// ApiGetNetworkIpNetworks converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetNetworkIpNetworks(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetNetworkIpNetworks(ctx, id)
+	return err
+}
+
+// ApiReplyPing converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiReplyPing(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiReplyPing(ctx)
+	return err
+}
+
+// ApiGetProjects converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetProjects(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetProjects(ctx)
+	return err
+}
+
+// ApiCreateProject converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateProject(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiCreateProject(ctx)
+	return err
+}
+
+// ApiDeleteProjectByName converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDeleteProjectByName(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "projectName" -------------
+	var projectName string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "projectName", ctx.Param("projectName"), &projectName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter projectName: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiDeleteProjectByName(ctx, projectName)
+	return err
+}
+
+// ApiGetProjectByName converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetProjectByName(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "projectName" -------------
+	var projectName string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "projectName", ctx.Param("projectName"), &projectName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter projectName: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetProjectByName(ctx, projectName)
+	return err
+}
+
+// ApiUpdateProjectByName converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiUpdateProjectByName(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "projectName" -------------
+	var projectName string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "projectName", ctx.Param("projectName"), &projectName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter projectName: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiUpdateProjectByName(ctx, projectName)
+	return err
+}
+
+// ApiGetQuotas converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetQuotas(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetQuotas(ctx)
+	return err
+}
+
+// ApiCreateQuota converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateQuota(ctx echo.Context) error {
+	var err error
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiCreateQuota(ctx)
+	return err
+}
+
+// ApiDeleteQuotaById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDeleteQuotaById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "quotaId" -------------
+	var quotaId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "quotaId", ctx.Param("quotaId"), &quotaId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter quotaId: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiDeleteQuotaById(ctx, quotaId)
+	return err
+}
+
+// ApiGetQuotaById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetQuotaById(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "quotaId" -------------
+	var quotaId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "quotaId", ctx.Param("quotaId"), &quotaId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter quotaId: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetQuotaById(ctx, quotaId)
 	return err
 }
 
-// ApiGetKubernetesEngineById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetKubernetesEngineById(ctx echo.Context) error {
+// ApiUpdateQuotaById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiUpdateQuotaById(ctx echo.Context) error {
 	var err error
-	// ------------- Path parameter "id" -------------
-	var id string
+	// ------------- Path parameter "quotaId" -------------
+	var quotaId string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	err = runtime.BindStyledParameterWithOptions("simple", "quotaId", ctx.Param("quotaId"), &quotaId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter quotaId: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetKubernetesEngineById(ctx, id)
+	err = w.Handler.ApiUpdateQuotaById(ctx, quotaId)
 	return err
 }
 
-// ApiGetKubernetesEngineKubeconfigById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetKubernetesEngineKubeconfigById(ctx echo.Context) error {
+// ApiListRoles converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiListRoles(ctx echo.Context) error {
 	var err error
-	// ------------- Path parameter "id" -------------
-	var id string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiListRoles(ctx)
+	return err
+}
+
+// ApiGetRoleByName converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetRoleByName(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "roleName" -------------
+	var roleName string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "roleName", ctx.Param("roleName"), &roleName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter roleName: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetKubernetesEngineKubeconfigById(ctx, id)
+	err = w.Handler.ApiGetRoleByName(ctx, roleName)
 	return err
 }
 
-// ApiGetMarmotCluster converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetMarmotCluster(ctx echo.Context) error {
+// ApiCreateRole converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateRole(ctx echo.Context) error {
 	var err error
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetMarmotCluster(ctx)
+	err = w.Handler.ApiCreateRole(ctx)
 	return err
 }
 
-// ApiGetMarmotStatus converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetMarmotStatus(ctx echo.Context) error {
+// ApiUpdateRoleByName converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiUpdateRoleByName(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "roleName" -------------
+	var roleName string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "roleName", ctx.Param("roleName"), &roleName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter roleName: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetMarmotStatus(ctx)
+	err = w.Handler.ApiUpdateRoleByName(ctx, roleName)
 	return err
 }
 
-// ApiGetNetworks converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetNetworks(ctx echo.Context) error {
+// ApiDeleteRoleByName converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDeleteRoleByName(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "roleName" -------------
+	var roleName string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "roleName", ctx.Param("roleName"), &roleName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter roleName: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetNetworks(ctx)
+	err = w.Handler.ApiDeleteRoleByName(ctx, roleName)
 	return err
 }
 
-// ApiCreateNetwork converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiCreateNetwork(ctx echo.Context) error {
+// ApiGetServers converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetServers(ctx echo.Context) error {
 	var err error
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiGetServersParams
+	// ------------- Optional query parameter "watch" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "watch", ctx.QueryParams(), &params.Watch, runtime.BindQueryParameterOptions{Type: "boolean", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter watch: %s", err))
+	}
+
+	// ------------- Optional query parameter "resourceVersion" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "resourceVersion", ctx.QueryParams(), &params.ResourceVersion, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter resourceVersion: %s", err))
+	}
+
+	// ------------- Optional query parameter "project" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "project", ctx.QueryParams(), &params.Project, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter project: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiCreateNetwork(ctx)
+	err = w.Handler.ApiGetServers(ctx, params)
 	return err
 }
 
-// ApiGetNetworkLoadBalancers converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetNetworkLoadBalancers(ctx echo.Context) error {
+// ApiCreateServer converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateServer(ctx echo.Context) error {
 	var err error
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetNetworkLoadBalancers(ctx)
+	err = w.Handler.ApiCreateServer(ctx)
 	return err
 }
 
-// ApiCreateNetworkLoadBalancer converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiCreateNetworkLoadBalancer(ctx echo.Context) error {
+// ApiDeleteServerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDeleteServerById(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiCreateNetworkLoadBalancer(ctx)
+	err = w.Handler.ApiDeleteServerById(ctx, id)
 	return err
 }
 
-// ApiDeleteNetworkLoadBalancerById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiDeleteNetworkLoadBalancerById(ctx echo.Context) error {
+// ApiGetServerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetServerById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1460,12 +3137,12 @@ func (w *ServerInterfaceWrapper) ApiDeleteNetworkLoadBalancerById(ctx echo.Conte
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiDeleteNetworkLoadBalancerById(ctx, id)
+	err = w.Handler.ApiGetServerById(ctx, id)
 	return err
 }
 
-// ApiGetNetworkLoadBalancerById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetNetworkLoadBalancerById(ctx echo.Context) error {
+// ApiMakeImageEntryFromRunningVMById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiMakeImageEntryFromRunningVMById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1476,12 +3153,12 @@ func (w *ServerInterfaceWrapper) ApiGetNetworkLoadBalancerById(ctx echo.Context)
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetNetworkLoadBalancerById(ctx, id)
+	err = w.Handler.ApiMakeImageEntryFromRunningVMById(ctx, id)
 	return err
 }
 
-// ApiUpdateNetworkLoadBalancerById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiUpdateNetworkLoadBalancerById(ctx echo.Context) error {
+// ApiUpdateServerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiUpdateServerById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1492,28 +3169,28 @@ func (w *ServerInterfaceWrapper) ApiUpdateNetworkLoadBalancerById(ctx echo.Conte
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiUpdateNetworkLoadBalancerById(ctx, id)
+	err = w.Handler.ApiUpdateServerById(ctx, id)
 	return err
 }
 
-// ApiDeleteNetworkById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiDeleteNetworkById(ctx echo.Context) error {
+// ApiCloneServer converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCloneServer(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true})
 	if err != nil {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiDeleteNetworkById(ctx, id)
+	err = w.Handler.ApiCloneServer(ctx, id)
 	return err
 }
 
-// ApiGetNetworkById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetNetworkById(ctx echo.Context) error {
+// ApiConsoleServerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiConsoleServerById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1524,12 +3201,12 @@ func (w *ServerInterfaceWrapper) ApiGetNetworkById(ctx echo.Context) error {
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetNetworkById(ctx, id)
+	err = w.Handler.ApiConsoleServerById(ctx, id)
 	return err
 }
 
-// ApiUpdateNetworkById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiUpdateNetworkById(ctx echo.Context) error {
+// ApiGraphicalConsoleServerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGraphicalConsoleServerById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1539,13 +3216,22 @@ func (w *ServerInterfaceWrapper) ApiUpdateNetworkById(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiGraphicalConsoleServerByIdParams
+	// ------------- Required query parameter "token" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, true, "token", ctx.QueryParams(), &params.Token, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter token: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiUpdateNetworkById(ctx, id)
+	err = w.Handler.ApiGraphicalConsoleServerById(ctx, id, params)
 	return err
 }
 
-// ApiGetNetworkIpNetworks converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetNetworkIpNetworks(ctx echo.Context) error {
+// ApiCreateServerConsoleToken converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateServerConsoleToken(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1556,64 +3242,92 @@ func (w *ServerInterfaceWrapper) ApiGetNetworkIpNetworks(ctx echo.Context) error
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetNetworkIpNetworks(ctx, id)
+	err = w.Handler.ApiCreateServerConsoleToken(ctx, id)
 	return err
 }
 
-// ApiReplyPing converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiReplyPing(ctx echo.Context) error {
+// ApiGetServerMigration converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetServerMigration(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiReplyPing(ctx)
+	err = w.Handler.ApiGetServerMigration(ctx, id)
 	return err
 }
 
-// ApiListRoles converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiListRoles(ctx echo.Context) error {
+// ApiMigrateServer converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiMigrateServer(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiListRoles(ctx)
+	err = w.Handler.ApiMigrateServer(ctx, id)
 	return err
 }
 
-// ApiGetRoleByName converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetRoleByName(ctx echo.Context) error {
+// ApiSetServerPassword converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiSetServerPassword(ctx echo.Context) error {
 	var err error
-	// ------------- Path parameter "roleName" -------------
-	var roleName string
+	// ------------- Path parameter "id" -------------
+	var id string
 
-	err = runtime.BindStyledParameterWithOptions("simple", "roleName", ctx.Param("roleName"), &roleName, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter roleName: %s", err))
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetRoleByName(ctx, roleName)
+	err = w.Handler.ApiSetServerPassword(ctx, id)
 	return err
 }
 
-// ApiGetServers converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetServers(ctx echo.Context) error {
+// ApiListServerSnapshots converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiListServerSnapshots(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetServers(ctx)
+	err = w.Handler.ApiListServerSnapshots(ctx, id)
 	return err
 }
 
-// ApiCreateServer converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiCreateServer(ctx echo.Context) error {
+// ApiCreateServerSnapshot converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiCreateServerSnapshot(ctx echo.Context) error {
 	var err error
+	// ------------- Path parameter "id" -------------
+	var id string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "id", ctx.Param("id"), &id, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
+	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiCreateServer(ctx)
+	err = w.Handler.ApiCreateServerSnapshot(ctx, id)
 	return err
 }
 
-// ApiDeleteServerById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiDeleteServerById(ctx echo.Context) error {
+// ApiDeleteServerSnapshotById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDeleteServerSnapshotById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1623,13 +3337,21 @@ func (w *ServerInterfaceWrapper) ApiDeleteServerById(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	// ------------- Path parameter "snapshotId" -------------
+	var snapshotId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "snapshotId", ctx.Param("snapshotId"), &snapshotId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter snapshotId: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiDeleteServerById(ctx, id)
+	err = w.Handler.ApiDeleteServerSnapshotById(ctx, id, snapshotId)
 	return err
 }
 
-// ApiGetServerById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiGetServerById(ctx echo.Context) error {
+// ApiGetServerSnapshotById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetServerSnapshotById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1639,13 +3361,21 @@ func (w *ServerInterfaceWrapper) ApiGetServerById(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	// ------------- Path parameter "snapshotId" -------------
+	var snapshotId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "snapshotId", ctx.Param("snapshotId"), &snapshotId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter snapshotId: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiGetServerById(ctx, id)
+	err = w.Handler.ApiGetServerSnapshotById(ctx, id, snapshotId)
 	return err
 }
 
-// ApiMakeImageEntryFromRunningVMById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiMakeImageEntryFromRunningVMById(ctx echo.Context) error {
+// ApiRevertServerSnapshotById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiRevertServerSnapshotById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1655,13 +3385,21 @@ func (w *ServerInterfaceWrapper) ApiMakeImageEntryFromRunningVMById(ctx echo.Con
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	// ------------- Path parameter "snapshotId" -------------
+	var snapshotId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "snapshotId", ctx.Param("snapshotId"), &snapshotId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter snapshotId: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiMakeImageEntryFromRunningVMById(ctx, id)
+	err = w.Handler.ApiRevertServerSnapshotById(ctx, id, snapshotId)
 	return err
 }
 
-// ApiUpdateServerById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiUpdateServerById(ctx echo.Context) error {
+// ApiStartServerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiStartServerById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1672,12 +3410,12 @@ func (w *ServerInterfaceWrapper) ApiUpdateServerById(ctx echo.Context) error {
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiUpdateServerById(ctx, id)
+	err = w.Handler.ApiStartServerById(ctx, id)
 	return err
 }
 
-// ApiConsoleServerById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiConsoleServerById(ctx echo.Context) error {
+// ApiStopServerById converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiStopServerById(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1688,12 +3426,12 @@ func (w *ServerInterfaceWrapper) ApiConsoleServerById(ctx echo.Context) error {
 	}
 
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiConsoleServerById(ctx, id)
+	err = w.Handler.ApiStopServerById(ctx, id)
 	return err
 }
 
-// ApiStartServerById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiStartServerById(ctx echo.Context) error {
+// ApiDetachServerVolume converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiDetachServerVolume(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1703,13 +3441,21 @@ func (w *ServerInterfaceWrapper) ApiStartServerById(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	// ------------- Path parameter "volumeId" -------------
+	var volumeId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "volumeId", ctx.Param("volumeId"), &volumeId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter volumeId: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiStartServerById(ctx, id)
+	err = w.Handler.ApiDetachServerVolume(ctx, id, volumeId)
 	return err
 }
 
-// ApiStopServerById converts echo context to params.
-func (w *ServerInterfaceWrapper) ApiStopServerById(ctx echo.Context) error {
+// ApiAttachServerVolume converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiAttachServerVolume(ctx echo.Context) error {
 	var err error
 	// ------------- Path parameter "id" -------------
 	var id string
@@ -1719,8 +3465,16 @@ func (w *ServerInterfaceWrapper) ApiStopServerById(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter id: %s", err))
 	}
 
+	// ------------- Path parameter "volumeId" -------------
+	var volumeId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "volumeId", ctx.Param("volumeId"), &volumeId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter volumeId: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiStopServerById(ctx, id)
+	err = w.Handler.ApiAttachServerVolume(ctx, id, volumeId)
 	return err
 }
 
@@ -1878,6 +3632,22 @@ func (w *ServerInterfaceWrapper) ApiChangeUserPassword(ctx echo.Context) error {
 	return err
 }
 
+// ApiGetUserQuotaUsage converts echo context to params.
+func (w *ServerInterfaceWrapper) ApiGetUserQuotaUsage(ctx echo.Context) error {
+	var err error
+	// ------------- Path parameter "userId" -------------
+	var userId string
+
+	err = runtime.BindStyledParameterWithOptions("simple", "userId", ctx.Param("userId"), &userId, runtime.BindStyledParameterOptions{ParamLocation: runtime.ParamLocationPath, Explode: false, Required: true, Type: "string", Format: "", ValueIsUnescaped: ctx.Request().URL.RawPath == ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter userId: %s", err))
+	}
+
+	// Invoke the callback with all the unmarshaled arguments
+	err = w.Handler.ApiGetUserQuotaUsage(ctx, userId)
+	return err
+}
+
 // ApiListUserRoles converts echo context to params.
 func (w *ServerInterfaceWrapper) ApiListUserRoles(ctx echo.Context) error {
 	var err error
@@ -1963,8 +3733,31 @@ func (w *ServerInterfaceWrapper) ApiGetVersion(ctx echo.Context) error {
 func (w *ServerInterfaceWrapper) ApiListVolumes(ctx echo.Context) error {
 	var err error
 
+	// Parameter object where we will unmarshal all parameters from the context
+	var params ApiListVolumesParams
+	// ------------- Optional query parameter "watch" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "watch", ctx.QueryParams(), &params.Watch, runtime.BindQueryParameterOptions{Type: "boolean", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter watch: %s", err))
+	}
+
+	// ------------- Optional query parameter "resourceVersion" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "resourceVersion", ctx.QueryParams(), &params.ResourceVersion, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter resourceVersion: %s", err))
+	}
+
+	// ------------- Optional query parameter "project" -------------
+
+	err = runtime.BindQueryParameterWithOptions("form", true, false, "project", ctx.QueryParams(), &params.Project, runtime.BindQueryParameterOptions{Type: "string", Format: ""})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid format for parameter project: %s", err))
+	}
+
 	// Invoke the callback with all the unmarshaled arguments
-	err = w.Handler.ApiListVolumes(ctx)
+	err = w.Handler.ApiListVolumes(ctx, params)
 	return err
 }
 
@@ -2157,6 +3950,8 @@ func RegisterHandlersWithOptions(router EchoRouter, si ServerInterface, options
 	router.POST(options.BaseURL+"/auth/login", wrapper.ApiAuthLogin, options.OperationMiddlewares["apiAuthLogin"]...)
 	router.POST(options.BaseURL+"/auth/logout", wrapper.ApiAuthLogout, options.OperationMiddlewares["apiAuthLogout"]...)
 	router.GET(options.BaseURL+"/auth/me", wrapper.ApiAuthMe, options.OperationMiddlewares["apiAuthMe"]...)
+	router.GET(options.BaseURL+"/auth/oidc", wrapper.ApiAuthOidcConfig, options.OperationMiddlewares["apiAuthOidcConfig"]...)
+	router.POST(options.BaseURL+"/auth/oidc/login", wrapper.ApiAuthOidcLogin, options.OperationMiddlewares["apiAuthOidcLogin"]...)
 	router.GET(options.BaseURL+"/users", wrapper.ApiListUsers, options.OperationMiddlewares["apiListUsers"]...)
 	router.POST(options.BaseURL+"/users", wrapper.ApiCreateUser, options.OperationMiddlewares["apiCreateUser"]...)
 	router.DELETE(options.BaseURL+"/users/:userId", wrapper.ApiDeleteUserById, options.OperationMiddlewares["apiDeleteUserById"]...)
@@ -2173,7 +3968,22 @@ func RegisterHandlersWithOptions(router EchoRouter, si ServerInterface, options
 	router.DELETE(options.BaseURL+"/users/:userId/apikeys/:apiKeyId", wrapper.ApiDeleteUserApiKey, options.OperationMiddlewares["apiDeleteUserApiKey"]...)
 	router.GET(options.BaseURL+"/roles", wrapper.ApiListRoles, options.OperationMiddlewares["apiListRoles"]...)
 	router.GET(options.BaseURL+"/roles/:roleName", wrapper.ApiGetRoleByName, options.OperationMiddlewares["apiGetRoleByName"]...)
+	router.POST(options.BaseURL+"/roles", wrapper.ApiCreateRole, options.OperationMiddlewares["apiCreateRole"]...)
+	router.PUT(options.BaseURL+"/roles/:roleName", wrapper.ApiUpdateRoleByName, options.OperationMiddlewares["apiUpdateRoleByName"]...)
+	router.DELETE(options.BaseURL+"/roles/:roleName", wrapper.ApiDeleteRoleByName, options.OperationMiddlewares["apiDeleteRoleByName"]...)
 	router.POST(options.BaseURL+"/authz/check", wrapper.ApiAuthzCheck, options.OperationMiddlewares["apiAuthzCheck"]...)
+	router.GET(options.BaseURL+"/audit", wrapper.ApiGetAuditRecords, options.OperationMiddlewares["apiGetAuditRecords"]...)
+	router.GET(options.BaseURL+"/users/:userId/quota", wrapper.ApiGetUserQuotaUsage, options.OperationMiddlewares["apiGetUserQuotaUsage"]...)
+	router.GET(options.BaseURL+"/quota", wrapper.ApiGetQuotas, options.OperationMiddlewares["apiGetQuotas"]...)
+	router.POST(options.BaseURL+"/quota", wrapper.ApiCreateQuota, options.OperationMiddlewares["apiCreateQuota"]...)
+	router.DELETE(options.BaseURL+"/quota/:quotaId", wrapper.ApiDeleteQuotaById, options.OperationMiddlewares["apiDeleteQuotaById"]...)
+	router.GET(options.BaseURL+"/quota/:quotaId", wrapper.ApiGetQuotaById, options.OperationMiddlewares["apiGetQuotaById"]...)
+	router.PUT(options.BaseURL+"/quota/:quotaId", wrapper.ApiUpdateQuotaById, options.OperationMiddlewares["apiUpdateQuotaById"]...)
+	router.GET(options.BaseURL+"/projects", wrapper.ApiGetProjects, options.OperationMiddlewares["apiGetProjects"]...)
+	router.POST(options.BaseURL+"/projects", wrapper.ApiCreateProject, options.OperationMiddlewares["apiCreateProject"]...)
+	router.DELETE(options.BaseURL+"/projects/:projectName", wrapper.ApiDeleteProjectByName, options.OperationMiddlewares["apiDeleteProjectByName"]...)
+	router.GET(options.BaseURL+"/projects/:projectName", wrapper.ApiGetProjectByName, options.OperationMiddlewares["apiGetProjectByName"]...)
+	router.PUT(options.BaseURL+"/projects/:projectName", wrapper.ApiUpdateProjectByName, options.OperationMiddlewares["apiUpdateProjectByName"]...)
 	router.GET(options.BaseURL+"/version", wrapper.ApiGetVersion, options.OperationMiddlewares["apiGetVersion"]...)
 	router.GET(options.BaseURL+"/ping", wrapper.ApiReplyPing, options.OperationMiddlewares["apiReplyPing"]...)
 	router.GET(options.BaseURL+"/volume", wrapper.ApiListVolumes, options.OperationMiddlewares["apiListVolumes"]...)
@@ -2188,7 +3998,20 @@ func RegisterHandlersWithOptions(router EchoRouter, si ServerInterface, options
 	router.POST(options.BaseURL+"/server/:id", wrapper.ApiMakeImageEntryFromRunningVMById, options.OperationMiddlewares["apiMakeImageEntryFromRunningVMById"]...)
 	router.PUT(options.BaseURL+"/server/:id", wrapper.ApiUpdateServerById, options.OperationMiddlewares["apiUpdateServerById"]...)
 	router.POST(options.BaseURL+"/server/:id/stop", wrapper.ApiStopServerById, options.OperationMiddlewares["apiStopServerById"]...)
+	router.DELETE(options.BaseURL+"/server/:id/volumes/:volumeId", wrapper.ApiDetachServerVolume, options.OperationMiddlewares["apiDetachServerVolume"]...)
+	router.POST(options.BaseURL+"/server/:id/volumes/:volumeId", wrapper.ApiAttachServerVolume, options.OperationMiddlewares["apiAttachServerVolume"]...)
+	router.POST(options.BaseURL+"/server/:id/clone", wrapper.ApiCloneServer, options.OperationMiddlewares["apiCloneServer"]...)
 	router.GET(options.BaseURL+"/server/:id/console", wrapper.ApiConsoleServerById, options.OperationMiddlewares["apiConsoleServerById"]...)
+	router.GET(options.BaseURL+"/server/:id/console/graphical", wrapper.ApiGraphicalConsoleServerById, options.OperationMiddlewares["apiGraphicalConsoleServerById"]...)
+	router.POST(options.BaseURL+"/server/:id/console/token", wrapper.ApiCreateServerConsoleToken, options.OperationMiddlewares["apiCreateServerConsoleToken"]...)
+	router.GET(options.BaseURL+"/server/:id/migrate", wrapper.ApiGetServerMigration, options.OperationMiddlewares["apiGetServerMigration"]...)
+	router.POST(options.BaseURL+"/server/:id/migrate", wrapper.ApiMigrateServer, options.OperationMiddlewares["apiMigrateServer"]...)
+	router.POST(options.BaseURL+"/server/:id/password", wrapper.ApiSetServerPassword, options.OperationMiddlewares["apiSetServerPassword"]...)
+	router.GET(options.BaseURL+"/server/:id/snapshot", wrapper.ApiListServerSnapshots, options.OperationMiddlewares["apiListServerSnapshots"]...)
+	router.POST(options.BaseURL+"/server/:id/snapshot", wrapper.ApiCreateServerSnapshot, options.OperationMiddlewares["apiCreateServerSnapshot"]...)
+	router.GET(options.BaseURL+"/server/:id/snapshot/:snapshotId", wrapper.ApiGetServerSnapshotById, options.OperationMiddlewares["apiGetServerSnapshotById"]...)
+	router.DELETE(options.BaseURL+"/server/:id/snapshot/:snapshotId", wrapper.ApiDeleteServerSnapshotById, options.OperationMiddlewares["apiDeleteServerSnapshotById"]...)
+	router.POST(options.BaseURL+"/server/:id/snapshot/:snapshotId/revert", wrapper.ApiRevertServerSnapshotById, options.OperationMiddlewares["apiRevertServerSnapshotById"]...)
 	router.POST(options.BaseURL+"/server/:id/start", wrapper.ApiStartServerById, options.OperationMiddlewares["apiStartServerById"]...)
 	router.GET(options.BaseURL+"/network", wrapper.ApiGetNetworks, options.OperationMiddlewares["apiGetNetworks"]...)
 	router.POST(options.BaseURL+"/network", wrapper.ApiCreateNetwork, options.OperationMiddlewares["apiCreateNetwork"]...)
@@ -2212,6 +4035,11 @@ func RegisterHandlersWithOptions(router EchoRouter, si ServerInterface, options
 	router.DELETE(options.BaseURL+"/application-load-balancer/:id", wrapper.ApiDeleteLoadBalancerById, options.OperationMiddlewares["apiDeleteLoadBalancerById"]...)
 	router.GET(options.BaseURL+"/application-load-balancer/:id", wrapper.ApiGetLoadBalancerById, options.OperationMiddlewares["apiGetLoadBalancerById"]...)
 	router.PUT(options.BaseURL+"/application-load-balancer/:id", wrapper.ApiUpdateLoadBalancerById, options.OperationMiddlewares["apiUpdateLoadBalancerById"]...)
+	router.GET(options.BaseURL+"/certificate", wrapper.ApiGetCertificates, options.OperationMiddlewares["apiGetCertificates"]...)
+	router.POST(options.BaseURL+"/certificate", wrapper.ApiCreateCertificate, options.OperationMiddlewares["apiCreateCertificate"]...)
+	router.DELETE(options.BaseURL+"/certificate/:id", wrapper.ApiDeleteCertificateById, options.OperationMiddlewares["apiDeleteCertificateById"]...)
+	router.GET(options.BaseURL+"/certificate/:id", wrapper.ApiGetCertificateById, options.OperationMiddlewares["apiGetCertificateById"]...)
+	router.PUT(options.BaseURL+"/certificate/:id", wrapper.ApiUpdateCertificateById, options.OperationMiddlewares["apiUpdateCertificateById"]...)
 	router.GET(options.BaseURL+"/network-load-balancer", wrapper.ApiGetNetworkLoadBalancers, options.OperationMiddlewares["apiGetNetworkLoadBalancers"]...)
 	router.POST(options.BaseURL+"/network-load-balancer", wrapper.ApiCreateNetworkLoadBalancer, options.OperationMiddlewares["apiCreateNetworkLoadBalancer"]...)
 	router.DELETE(options.BaseURL+"/network-load-balancer/:id", wrapper.ApiDeleteNetworkLoadBalancerById, options.OperationMiddlewares["apiDeleteNetworkLoadBalancerById"]...)
@@ -2227,6 +4055,17 @@ func RegisterHandlersWithOptions(router EchoRouter, si ServerInterface, options
 	router.GET(options.BaseURL+"/network/:id/ipnetworks", wrapper.ApiGetNetworkIpNetworks, options.OperationMiddlewares["apiGetNetworkIpNetworks"]...)
 	router.GET(options.BaseURL+"/marmot/status", wrapper.ApiGetMarmotStatus, options.OperationMiddlewares["apiGetMarmotStatus"]...)
 	router.GET(options.BaseURL+"/marmot/cluster", wrapper.ApiGetMarmotCluster, options.OperationMiddlewares["apiGetMarmotCluster"]...)
+	router.POST(options.BaseURL+"/marmot/node/:nodeName/cordon", wrapper.ApiCordonNode, options.OperationMiddlewares["apiCordonNode"]...)
+	router.POST(options.BaseURL+"/marmot/node/:nodeName/uncordon", wrapper.ApiUncordonNode, options.OperationMiddlewares["apiUncordonNode"]...)
+	router.POST(options.BaseURL+"/marmot/node/:nodeName/drain", wrapper.ApiDrainNode, options.OperationMiddlewares["apiDrainNode"]...)
+	router.GET(options.BaseURL+"/job", wrapper.ApiGetJobs, options.OperationMiddlewares["apiGetJobs"]...)
+	router.GET(options.BaseURL+"/job/:id", wrapper.ApiGetJobById, options.OperationMiddlewares["apiGetJobById"]...)
+	router.POST(options.BaseURL+"/job/:id/cancel", wrapper.ApiCancelJobById, options.OperationMiddlewares["apiCancelJobById"]...)
+	router.GET(options.BaseURL+"/job/:id/log", wrapper.ApiGetJobLogById, options.OperationMiddlewares["apiGetJobLogById"]...)
+	router.GET(options.BaseURL+"/dns-record", wrapper.ApiGetDnsRecords, options.OperationMiddlewares["apiGetDnsRecords"]...)
+	router.POST(options.BaseURL+"/dns-record", wrapper.ApiCreateDnsRecord, options.OperationMiddlewares["apiCreateDnsRecord"]...)
+	router.GET(options.BaseURL+"/dns-record/:id", wrapper.ApiGetDnsRecordById, options.OperationMiddlewares["apiGetDnsRecordById"]...)
+	router.DELETE(options.BaseURL+"/dns-record/:id", wrapper.ApiDeleteDnsRecordById, options.OperationMiddlewares["apiDeleteDnsRecordById"]...)
 	router.GET(options.BaseURL+"/kubernetes-engine", wrapper.ApiGetKubernetesEngines, options.OperationMiddlewares["apiGetKubernetesEngines"]...)
 	router.POST(options.BaseURL+"/kubernetes-engine", wrapper.ApiCreateKubernetesEngine, options.OperationMiddlewares["apiCreateKubernetesEngine"]...)
 	router.DELETE(options.BaseURL+"/kubernetes-engine/:id", wrapper.ApiDeleteKubernetesEngineById, options.OperationMiddlewares["apiDeleteKubernetesEngineById"]...)
@@ -0,0 +1,14 @@
+package api
+
+// ServerSnapshotID returns the snapshot identifier stored in metadata.id.
+func ServerSnapshotID(s ServerSnapshot) string {
+	return s.Metadata.Id
+}
+
+// SetServerSnapshotID stores the snapshot identifier into metadata.id.
+func SetServerSnapshotID(s *ServerSnapshot, id string) {
+	if s == nil {
+		return
+	}
+	s.Metadata.Id = id
+}
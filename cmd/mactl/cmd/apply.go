@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/client"
 	"go.yaml.in/yaml/v3"
 )
 
@@ -38,35 +40,35 @@ var applyCmd = &cobra.Command{
 
 			switch strings.ToLower(resourceName) {
 			case "server":
-				if err := applyServer(manifest); err != nil {
+				if err := applyWithConflictRetry(manifest, applyServer); err != nil {
 					return fmt.Errorf("manifest %d: %w", index+1, err)
 				}
 			case "image":
-				if err := applyImage(manifest); err != nil {
+				if err := applyWithConflictRetry(manifest, applyImage); err != nil {
 					return fmt.Errorf("manifest %d: %w", index+1, err)
 				}
 			case "volume":
-				if err := applyVolume(manifest); err != nil {
+				if err := applyWithConflictRetry(manifest, applyVolume); err != nil {
 					return fmt.Errorf("manifest %d: %w", index+1, err)
 				}
 			case "network":
-				if err := applyNetwork(manifest); err != nil {
+				if err := applyWithConflictRetry(manifest, applyNetwork); err != nil {
 					return fmt.Errorf("manifest %d: %w", index+1, err)
 				}
 			case "gateway":
-				if err := applyGateway(manifest); err != nil {
+				if err := applyWithConflictRetry(manifest, applyGateway); err != nil {
 					return fmt.Errorf("manifest %d: %w", index+1, err)
 				}
 			case "vpngateway":
-				if err := applyVpnGateway(manifest); err != nil {
+				if err := applyWithConflictRetry(manifest, applyVpnGateway); err != nil {
 					return fmt.Errorf("manifest %d: %w", index+1, err)
 				}
 			case "applicationloadbalancer":
-				if err := applyLoadBalancer(manifest); err != nil {
+				if err := applyWithConflictRetry(manifest, applyLoadBalancer); err != nil {
 					return fmt.Errorf("manifest %d: %w", index+1, err)
 				}
 			case "networkloadbalancer":
-				if err := applyNetworkLoadBalancer(manifest); err != nil {
+				if err := applyWithConflictRetry(manifest, applyNetworkLoadBalancer); err != nil {
 					return fmt.Errorf("manifest %d: %w", index+1, err)
 				}
 			default:
@@ -135,6 +137,7 @@ func applyServer(manifest map[string]interface{}) error {
 		if err := validateServerApplyForbiddenChanges(existingServer, *server); err != nil {
 			return err
 		}
+		setApplyResourceVersion(&server.Metadata, existingServer.Metadata.ResourceVersion)
 
 		// 更新
 		api.SetServerID(server, existingId)
@@ -208,6 +211,7 @@ func applyImage(manifest map[string]interface{}) error {
 	// イメージが既に存在するかチェック
 	exists := false
 	var existingId string
+	var existingVersion *string
 	list, _, err := m.GetImages()
 	if err == nil {
 		var images []api.Image
@@ -218,6 +222,7 @@ func applyImage(manifest map[string]interface{}) error {
 			if img.Metadata.Name == image.Metadata.Name {
 				exists = true
 				existingId = img.Metadata.Id
+				existingVersion = img.Metadata.ResourceVersion
 				break
 			}
 		}
@@ -227,6 +232,7 @@ func applyImage(manifest map[string]interface{}) error {
 	if exists {
 		// 更新
 		image.Metadata.Id = existingId
+		setApplyResourceVersion(&image.Metadata, existingVersion)
 		byteBody, _, err = m.UpdateImageById(existingId, *image)
 		if err != nil {
 			return fmt.Errorf("failed to update image: %w", err)
@@ -271,6 +277,7 @@ func applyVolume(manifest map[string]interface{}) error {
 	// ボリュームが既に存在するかチェック
 	exists := false
 	var existingId string
+	var existingVersion *string
 	list, _, err := m.ListVolumes()
 	if err == nil {
 		var volumes []api.Volume
@@ -281,6 +288,7 @@ func applyVolume(manifest map[string]interface{}) error {
 			if vol.Metadata.Name == volume.Metadata.Name {
 				exists = true
 				existingId = api.VolumeID(vol)
+				existingVersion = vol.Metadata.ResourceVersion
 				break
 			}
 		}
@@ -290,6 +298,7 @@ func applyVolume(manifest map[string]interface{}) error {
 	if exists {
 		// 更新
 		api.SetVolumeID(volume, existingId)
+		setApplyResourceVersion(&volume.Metadata, existingVersion)
 		byteBody, _, err = m.UpdateVolumeById(existingId, *volume)
 		if err != nil {
 			return fmt.Errorf("failed to update volume: %w", err)
@@ -331,6 +340,7 @@ func applyNetwork(manifest map[string]interface{}) error {
 	// ネットワークが既に存在するかチェック
 	exists := false
 	var existingId string
+	var existingVersion *string
 	list, _, err := m.GetVirtualNetworks()
 	if err == nil {
 		var networks []api.VirtualNetwork
@@ -341,6 +351,7 @@ func applyNetwork(manifest map[string]interface{}) error {
 			if net.Metadata.Name == network.Metadata.Name {
 				exists = true
 				existingId = api.VirtualNetworkID(net)
+				existingVersion = net.Metadata.ResourceVersion
 				break
 			}
 		}
@@ -350,6 +361,7 @@ func applyNetwork(manifest map[string]interface{}) error {
 	if exists {
 		// 更新
 		api.SetVirtualNetworkID(network, existingId)
+		setApplyResourceVersion(&network.Metadata, existingVersion)
 		byteBody, _, err = m.UpdateVirtualNetworkById(existingId, *network)
 		if err != nil {
 			return fmt.Errorf("failed to update network: %w", err)
@@ -573,6 +585,7 @@ func applyGateway(manifest map[string]interface{}) error {
 
 	exists := false
 	var existingID string
+	var existingVersion *string
 	list, _, err := m.GetGateways()
 	if err == nil {
 		var gateways []api.Gateway
@@ -586,6 +599,7 @@ func applyGateway(manifest map[string]interface{}) error {
 			if strings.TrimSpace(g.Spec.InternalVirtualNetwork) == strings.TrimSpace(gateway.Spec.InternalVirtualNetwork) {
 				exists = true
 				existingID = api.GatewayID(g)
+				existingVersion = g.Metadata.ResourceVersion
 				break
 			}
 		}
@@ -594,6 +608,7 @@ func applyGateway(manifest map[string]interface{}) error {
 	var byteBody []byte
 	if exists {
 		api.SetGatewayID(gateway, existingID)
+		setApplyResourceVersion(&gateway.Metadata, existingVersion)
 		byteBody, _, err = m.UpdateGatewayById(existingID, *gateway)
 		if err != nil {
 			return fmt.Errorf("failed to update gateway: %w", err)
@@ -634,6 +649,7 @@ func applyVpnGateway(manifest map[string]interface{}) error {
 
 	exists := false
 	var existingID string
+	var existingVersion *string
 	list, _, err := m.GetVpnGateways()
 	if err == nil {
 		var items []api.VpnGateway
@@ -647,6 +663,7 @@ func applyVpnGateway(manifest map[string]interface{}) error {
 			if strings.TrimSpace(g.Spec.InternalVirtualNetwork) == strings.TrimSpace(vpnGateway.Spec.InternalVirtualNetwork) {
 				exists = true
 				existingID = api.VpnGatewayID(g)
+				existingVersion = g.Metadata.ResourceVersion
 				break
 			}
 		}
@@ -655,6 +672,7 @@ func applyVpnGateway(manifest map[string]interface{}) error {
 	var byteBody []byte
 	if exists {
 		api.SetVpnGatewayID(vpnGateway, existingID)
+		setApplyResourceVersion(&vpnGateway.Metadata, existingVersion)
 		byteBody, _, err = m.UpdateVpnGatewayById(existingID, *vpnGateway)
 		if err != nil {
 			return fmt.Errorf("failed to update vpn gateway: %w", err)
@@ -695,6 +713,7 @@ func applyLoadBalancer(manifest map[string]interface{}) error {
 
 	exists := false
 	var existingID string
+	var existingVersion *string
 	list, _, err := m.GetLoadBalancers()
 	if err == nil {
 		var items []api.ApplicationLoadBalancer
@@ -708,6 +727,7 @@ func applyLoadBalancer(manifest map[string]interface{}) error {
 			if strings.TrimSpace(item.Spec.InternalVirtualNetwork) == strings.TrimSpace(lb.Spec.InternalVirtualNetwork) {
 				exists = true
 				existingID = api.LoadBalancerID(item)
+				existingVersion = item.Metadata.ResourceVersion
 				break
 			}
 		}
@@ -716,6 +736,7 @@ func applyLoadBalancer(manifest map[string]interface{}) error {
 	var byteBody []byte
 	if exists {
 		api.SetLoadBalancerID(lb, existingID)
+		setApplyResourceVersion(&lb.Metadata, existingVersion)
 		byteBody, _, err = m.UpdateLoadBalancerById(existingID, *lb)
 		if err != nil {
 			return fmt.Errorf("failed to update application load balancer: %w", err)
@@ -756,6 +777,7 @@ func applyNetworkLoadBalancer(manifest map[string]interface{}) error {
 
 	exists := false
 	var existingID string
+	var existingVersion *string
 	list, _, err := m.GetNetworkLoadBalancers()
 	if err == nil {
 		var items []api.NetworkLoadBalancer
@@ -769,6 +791,7 @@ func applyNetworkLoadBalancer(manifest map[string]interface{}) error {
 			if strings.TrimSpace(item.Spec.InternalVirtualNetwork) == strings.TrimSpace(nlb.Spec.InternalVirtualNetwork) {
 				exists = true
 				existingID = api.NetworkLoadBalancerID(item)
+				existingVersion = item.Metadata.ResourceVersion
 				break
 			}
 		}
@@ -777,6 +800,7 @@ func applyNetworkLoadBalancer(manifest map[string]interface{}) error {
 	var byteBody []byte
 	if exists {
 		api.SetNetworkLoadBalancerID(nlb, existingID)
+		setApplyResourceVersion(&nlb.Metadata, existingVersion)
 		byteBody, _, err = m.UpdateNetworkLoadBalancerById(existingID, *nlb)
 		if err != nil {
 			return fmt.Errorf("failed to update network load balancer: %w", err)
@@ -791,6 +815,64 @@ func applyNetworkLoadBalancer(manifest map[string]interface{}) error {
 	return processApplyResponse(byteBody, exists)
 }
 
+// 更新が他の操作と競合した場合に適用を試みる回数
+const applyConflictRetries = 3
+
+// applyWithConflictRetry は更新が他の操作と競合 (409 Conflict) した場合に、
+// 最新の状態を取得し直して適用を再試行する
+// マニフェストで metadata.resourceVersion が指定されている場合は再試行せずにエラーを返す
+func applyWithConflictRetry(manifest map[string]interface{}, apply func(map[string]interface{}) error) error {
+	pinnedVersion := normalizeManifestResourceVersion(manifest)
+	for attempt := 1; ; attempt++ {
+		err := apply(manifest)
+		if err == nil || !client.IsConflict(err) {
+			return err
+		}
+		if pinnedVersion != "" {
+			return fmt.Errorf("%w: metadata.resourceVersion %s は最新ではありません。mactl get で最新の状態を確認してマニフェストを修正してください", err, pinnedVersion)
+		}
+		if attempt >= applyConflictRetries {
+			return fmt.Errorf("%w: 他の操作との競合が %d 回続いたため中止しました", err, attempt)
+		}
+		fmt.Fprintf(os.Stderr, "リソースが他の操作で更新されました。最新の状態を取得して再試行します。(%d/%d)\n", attempt, applyConflictRetries-1)
+	}
+}
+
+// normalizeManifestResourceVersion はマニフェストの metadata.resourceVersion を文字列に揃えて返す
+// YAML で引用符なしに書かれた場合は数値として読み込まれるため
+func normalizeManifestResourceVersion(manifest map[string]interface{}) string {
+	metadata, ok := manifest["metadata"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	var version string
+	switch v := metadata["resourceVersion"].(type) {
+	case string:
+		version = strings.TrimSpace(v)
+	case int:
+		version = strconv.Itoa(v)
+	case int64:
+		version = strconv.FormatInt(v, 10)
+	case uint64:
+		version = strconv.FormatUint(v, 10)
+	case float64:
+		version = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+	metadata["resourceVersion"] = version
+	return version
+}
+
+// setApplyResourceVersion は更新要求に既存リソースの resourceVersion を設定する
+// マニフェストで指定されている場合はその値を使う
+func setApplyResourceVersion(desired *api.Metadata, existing *string) {
+	if desired.ResourceVersion != nil && strings.TrimSpace(*desired.ResourceVersion) != "" {
+		return
+	}
+	desired.ResourceVersion = existing
+}
+
 func processApplyResponse(byteBody []byte, updated bool) error {
 	switch outputStyle {
 	case "text":
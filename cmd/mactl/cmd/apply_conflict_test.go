@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/client"
+)
+
+func conflictError() error {
+	return &client.StatusError{StatusCode: http.StatusConflict, Message: "conflict"}
+}
+
+func TestApplyWithConflictRetryRetriesOnConflict(t *testing.T) {
+	calls := 0
+	err := applyWithConflictRetry(map[string]interface{}{"metadata": map[string]interface{}{"name": "gw"}}, func(map[string]interface{}) error {
+		calls++
+		if calls < 2 {
+			return conflictError()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("applyWithConflictRetry() unexpected err: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("apply called %d times, want 2", calls)
+	}
+}
+
+func TestApplyWithConflictRetryGivesUp(t *testing.T) {
+	calls := 0
+	err := applyWithConflictRetry(map[string]interface{}{}, func(map[string]interface{}) error {
+		calls++
+		return conflictError()
+	})
+	if !client.IsConflict(err) {
+		t.Fatalf("applyWithConflictRetry() err = %v, want conflict", err)
+	}
+	if calls != applyConflictRetries {
+		t.Fatalf("apply called %d times, want %d", calls, applyConflictRetries)
+	}
+}
+
+func TestApplyWithConflictRetryDoesNotRetryPinnedVersion(t *testing.T) {
+	manifest := map[string]interface{}{"metadata": map[string]interface{}{"name": "gw", "resourceVersion": 42}}
+	calls := 0
+	err := applyWithConflictRetry(manifest, func(map[string]interface{}) error {
+		calls++
+		return conflictError()
+	})
+	if !client.IsConflict(err) {
+		t.Fatalf("applyWithConflictRetry() err = %v, want conflict", err)
+	}
+	if calls != 1 {
+		t.Fatalf("apply called %d times, want 1", calls)
+	}
+	if got := manifest["metadata"].(map[string]interface{})["resourceVersion"]; got != "42" {
+		t.Fatalf("resourceVersion = %#v, want \"42\"", got)
+	}
+}
+
+func TestApplyWithConflictRetryReturnsOtherErrors(t *testing.T) {
+	want := errors.New("boom")
+	calls := 0
+	err := applyWithConflictRetry(map[string]interface{}{}, func(map[string]interface{}) error {
+		calls++
+		return want
+	})
+	if !errors.Is(err, want) || calls != 1 {
+		t.Fatalf("applyWithConflictRetry() err = %v calls = %d", err, calls)
+	}
+}
+
+func TestSetApplyResourceVersion(t *testing.T) {
+	existing := "10"
+	var meta api.Metadata
+	setApplyResourceVersion(&meta, &existing)
+	if meta.ResourceVersion == nil || *meta.ResourceVersion != "10" {
+		t.Fatalf("resourceVersion = %v, want 10", meta.ResourceVersion)
+	}
+
+	pinned := "7"
+	meta = api.Metadata{ResourceVersion: &pinned}
+	setApplyResourceVersion(&meta, &existing)
+	if *meta.ResourceVersion != "7" {
+		t.Fatalf("resourceVersion = %s, want the manifest value 7", *meta.ResourceVersion)
+	}
+}
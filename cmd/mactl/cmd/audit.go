@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+var (
+	auditUser       string // 利用者IDで絞り込む
+	auditOperation  string // API の operationId で絞り込む
+	auditResource   string // リソースの種類で絞り込む
+	auditResourceID string // リソースIDで絞り込む
+	auditResult     string // 結果で絞り込む
+	auditSince      string // この時刻以降
+	auditUntil      string // この時刻より前
+	auditLimit      int    // 表示する件数
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of API requests",
+	Long: `Show the audit log of API requests that changed resources, newest first.
+Only users with the Administrator role can read the audit log.
+
+  mactl audit --operation apiDeleteServerById
+  mactl audit --resource Server --resource-id ab12c
+  mactl audit --user alice --since 24h
+  mactl audit --result denied --since 2026-10-01T00:00:00+09:00`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params, err := auditParamsFromFlags(time.Now())
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "検索条件が正しくありません。", err)
+			return err
+		}
+
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetAuditRecords(params)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "監査記録の取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var data []api.AuditRecord
+		if err := json.Unmarshal(byteBody, &data); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printAuditRecordList(os.Stdout, data)
+		return nil
+	},
+}
+
+// parseAuditTime は RFC3339 の時刻、または現在からさかのぼる期間 (例: 24h) を時刻に変換する
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("duration must not be negative: %s", value)
+		}
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time must be RFC3339 or a duration such as 24h: %s", value)
+	}
+	return t, nil
+}
+
+// auditParamsFromFlags はフラグから監査記録の検索条件を組み立てる
+func auditParamsFromFlags(now time.Time) (api.ApiGetAuditRecordsParams, error) {
+	var params api.ApiGetAuditRecordsParams
+	optional := func(value string) *string {
+		v := strings.TrimSpace(value)
+		if v == "" {
+			return nil
+		}
+		return &v
+	}
+	params.UserId = optional(auditUser)
+	params.OperationId = optional(auditOperation)
+	params.Resource = optional(auditResource)
+	params.ResourceId = optional(auditResourceID)
+	params.Result = optional(auditResult)
+	if v := strings.TrimSpace(auditSince); v != "" {
+		t, err := parseAuditTime(v, now)
+		if err != nil {
+			return params, err
+		}
+		params.Since = &t
+	}
+	if v := strings.TrimSpace(auditUntil); v != "" {
+		t, err := parseAuditTime(v, now)
+		if err != nil {
+			return params, err
+		}
+		params.Until = &t
+	}
+	if auditLimit > 0 {
+		limit := auditLimit
+		params.Limit = &limit
+	}
+	return params, nil
+}
+
+// 監査記録の一覧を表示する
+func printAuditRecordList(w io.Writer, records []api.AuditRecord) {
+	if len(records) == 0 {
+		_, _ = fmt.Fprintln(w, "監査記録が見つかりません。")
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "  %-19s  %-12s  %-32s  %-24s  %-8s  %-6s  %s\n", "TIME", "USER", "OPERATION", "RESOURCE", "RESULT", "STATUS", "SOURCE-IP")
+	for _, rec := range records {
+		// リソースの種類とIDを Server/ab12c の形で表示する
+		resource := strings.Trim(util.OrDefault(rec.Resource, "")+"/"+util.OrDefault(rec.ResourceId, ""), "/")
+		if resource == "" {
+			resource = "N/A"
+		}
+		_, _ = fmt.Fprintf(w, "  %-19s  %-12s  %-32s  %-24s  %-8s  %-6d  %s\n",
+			rec.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			stringVal(rec.UserId),
+			rec.OperationId,
+			resource,
+			rec.Result,
+			rec.StatusCode,
+			stringVal(rec.SourceIp),
+		)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.Flags().StringVar(&auditUser, "user", "", "Show only the requests made by this user ID")
+	auditCmd.Flags().StringVar(&auditOperation, "operation", "", "Show only the requests of this API operation (e.g. apiDeleteServerById)")
+	auditCmd.Flags().StringVar(&auditResource, "resource", "", "Show only the requests for this resource kind (e.g. Server)")
+	auditCmd.Flags().StringVar(&auditResourceID, "resource-id", "", "Show only the requests for this resource ID")
+	auditCmd.Flags().StringVar(&auditResult, "result", "", "Show only the requests with this result (success, failure or denied)")
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "Show the requests at or after this time (RFC3339 or a duration such as 24h)")
+	auditCmd.Flags().StringVar(&auditUntil, "until", "", "Show the requests before this time (RFC3339 or a duration such as 1h)")
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 0, "Maximum number of records to show (default 100, at most 1000)")
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestPrintAuditRecordList(t *testing.T) {
+	records := []api.AuditRecord{
+		{Id: "a1", Timestamp: time.Now(), UserId: util.StringPtr("alice"), OperationId: "apiDeleteServerById",
+			Resource: util.StringPtr("Server"), ResourceId: util.StringPtr("ab12c"), Result: "success", StatusCode: 200,
+			SourceIp: util.StringPtr("192.0.2.10")},
+		{Id: "a2", Timestamp: time.Now(), OperationId: "apiCreateServer", Resource: util.StringPtr("Server"), Result: "denied", StatusCode: 401},
+	}
+
+	var out bytes.Buffer
+	printAuditRecordList(&out, records)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printAuditRecordList() printed %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	for _, want := range []string{"alice", "apiDeleteServerById", "Server/ab12c", "success", "200", "192.0.2.10"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("first row %q does not contain %q", lines[1], want)
+		}
+	}
+	for _, want := range []string{"N/A", "Server ", "denied", "401"} {
+		if !strings.Contains(lines[2], want) {
+			t.Fatalf("second row %q does not contain %q", lines[2], want)
+		}
+	}
+
+	out.Reset()
+	printAuditRecordList(&out, nil)
+	if !strings.Contains(out.String(), "監査記録が見つかりません。") {
+		t.Fatalf("printAuditRecordList(nil) = %q", out.String())
+	}
+}
+
+func TestAuditParamsFromFlags(t *testing.T) {
+	t.Cleanup(func() {
+		auditUser, auditResult, auditSince, auditUntil, auditLimit = "", "", "", "", 0
+	})
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	auditUser, auditResult, auditSince, auditUntil, auditLimit = " alice ", "denied", "24h", "2026-10-18T09:00:00+09:00", 20
+	params, err := auditParamsFromFlags(now)
+	if err != nil {
+		t.Fatalf("auditParamsFromFlags() error = %v", err)
+	}
+	if util.OrDefault(params.UserId, "") != "alice" || util.OrDefault(params.Result, "") != "denied" || params.OperationId != nil {
+		t.Fatalf("params = %+v, want user and result only", params)
+	}
+	if !params.Since.Equal(now.Add(-24*time.Hour)) || !params.Until.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("period = %v - %v", params.Since, params.Until)
+	}
+	if params.Limit == nil || *params.Limit != 20 {
+		t.Fatalf("limit = %v, want 20", params.Limit)
+	}
+
+	auditSince = "yesterday"
+	if _, err := auditParamsFromFlags(now); err == nil {
+		t.Fatal("auditParamsFromFlags() accepted an invalid time")
+	}
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+)
+
+var (
+	certificateCertFile string // 証明書のPEMファイル
+	certificateKeyFile  string // 秘密鍵のPEMファイル
+	certificateName     string // 更新時の証明書名
+)
+
+var certificateCmd = &cobra.Command{
+	Use:     "certificate",
+	Aliases: []string{"cert"},
+	Short:   "TLS certificate management commands",
+	Long: `TLS certificate management commands.
+
+Certificates are referenced by the certificateIds of HTTPS listeners of
+application load balancers. The private key is stored in marmot and is never
+returned by the API.`,
+}
+
+var certificateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List certificates",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetCertificates()
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "証明書の取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var data []api.Certificate
+		if err := json.Unmarshal(byteBody, &data); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printCertificateList(os.Stdout, data)
+		return nil
+	},
+}
+
+var certificateCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Register a certificate and its private key",
+	Long: `Register a certificate and its private key.
+
+  mactl certificate create web --cert fullchain.pem --key privkey.pem`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cert, err := certificateFromFiles(args[0], certificateCertFile, certificateKeyFile)
+		if err != nil {
+			return err
+		}
+
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.CreateCertificate(cert)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "証明書の登録に失敗しました。", err)
+			return err
+		}
+		var created api.Certificate
+		if err := json.Unmarshal(byteBody, &created); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		fmt.Println("証明書を登録しました。ID:", stringVal(created.Id))
+		return nil
+	},
+}
+
+var certificateDetailCmd = &cobra.Command{
+	Use:     "detail [certificate-id]",
+	Aliases: []string{"get"},
+	Short:   "Show a certificate",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetCertificateById(args[0])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "証明書の取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var cert api.Certificate
+		if err := json.Unmarshal(byteBody, &cert); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printCertificateList(os.Stdout, []api.Certificate{cert})
+		return nil
+	},
+}
+
+var certificateUpdateCmd = &cobra.Command{
+	Use:   "update [certificate-id]",
+	Short: "Replace a certificate and its private key",
+	Long: `Replace a certificate and its private key.
+Load balancers that reference the certificate are reconfigured with the new one.
+
+  mactl certificate update ab12c --cert fullchain.pem --key privkey.pem`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cert, err := certificateFromFiles(certificateName, certificateCertFile, certificateKeyFile)
+		if err != nil {
+			return err
+		}
+
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		if _, _, err := m.UpdateCertificateById(args[0], cert); err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "証明書の更新に失敗しました。", err)
+			return err
+		}
+		fmt.Println("証明書を更新しました。ID:", args[0])
+		return nil
+	},
+}
+
+var certificateDeleteCmd = &cobra.Command{
+	Use:   "delete [certificate-id...]",
+	Short: "Delete certificates",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		var lastErr error
+		for _, id := range args {
+			if _, _, err := m.DeleteCertificateById(id); err != nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "証明書の削除に失敗しました。", "ID:", id, err)
+				lastErr = err
+				continue
+			}
+			fmt.Println("証明書を削除しました。ID:", id)
+		}
+		return lastErr
+	},
+}
+
+// certificateFromFiles は証明書と秘密鍵のファイルから登録する証明書を作る
+func certificateFromFiles(name, certFile, keyFile string) (api.Certificate, error) {
+	var cert api.Certificate
+	if certFile == "" || keyFile == "" {
+		return cert, errors.New("--cert and --key are required")
+	}
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return cert, err
+	}
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return cert, err
+	}
+	key := string(keyPEM)
+	cert.Name = strings.TrimSpace(name)
+	cert.Certificate = string(certPEM)
+	cert.PrivateKey = &key
+	return cert, nil
+}
+
+// 証明書の一覧を表示する
+func printCertificateList(w io.Writer, certs []api.Certificate) {
+	if len(certs) == 0 {
+		_, _ = fmt.Fprintln(w, "証明書が見つかりません。")
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "  %2s  %-6s  %-20s  %-20s  %s\n", "No", "ID", "NAME", "NOT-AFTER", "DNS-NAMES")
+	for i, cert := range certs {
+		notAfter := ""
+		if cert.NotAfter != nil {
+			notAfter = cert.NotAfter.UTC().Format("2006-01-02 15:04:05")
+		}
+		dnsNames := ""
+		if cert.DnsNames != nil {
+			dnsNames = strings.Join(*cert.DnsNames, ",")
+		}
+		_, _ = fmt.Fprintf(w, "  %2d  %-6s  %-20s  %-20s  %s\n",
+			i+1,
+			stringVal(cert.Id),
+			cert.Name,
+			notAfter,
+			dnsNames,
+		)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(certificateCmd)
+	certificateCmd.AddCommand(certificateListCmd)
+	certificateCmd.AddCommand(certificateCreateCmd)
+	certificateCmd.AddCommand(certificateDetailCmd)
+	certificateCmd.AddCommand(certificateUpdateCmd)
+	certificateCmd.AddCommand(certificateDeleteCmd)
+	for _, c := range []*cobra.Command{certificateCreateCmd, certificateUpdateCmd} {
+		c.Flags().StringVar(&certificateCertFile, "cert", "", "PEM file of the certificate (with the intermediate certificates)")
+		c.Flags().StringVar(&certificateKeyFile, "key", "", "PEM file of the private key")
+	}
+	certificateUpdateCmd.Flags().StringVarP(&certificateName, "name", "n", "", "New name of the certificate")
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestCertificateFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, []byte("CERT"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, []byte("KEY"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := certificateFromFiles(" web ", certFile, keyFile)
+	if err != nil {
+		t.Fatalf("certificateFromFiles() error = %v", err)
+	}
+	if cert.Name != "web" || cert.Certificate != "CERT" || cert.PrivateKey == nil || *cert.PrivateKey != "KEY" {
+		t.Fatalf("certificateFromFiles() = %+v", cert)
+	}
+	if _, err := certificateFromFiles("web", certFile, ""); err == nil {
+		t.Fatal("certificateFromFiles() expected error without --key")
+	}
+}
+
+func TestPrintCertificateList(t *testing.T) {
+	notAfter := time.Date(2027, 1, 2, 3, 4, 5, 0, time.UTC)
+	certs := []api.Certificate{{
+		Id:       util.StringPtr("ab12c"),
+		Name:     "web",
+		DnsNames: &[]string{"www.example.com", "example.com"},
+		NotAfter: &notAfter,
+	}}
+
+	var out bytes.Buffer
+	printCertificateList(&out, certs)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("printCertificateList() printed %d lines, want 2:\n%s", len(lines), out.String())
+	}
+	for _, want := range []string{"ab12c", "web", "2027-01-02 03:04:05", "www.example.com,example.com"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("row %q does not contain %q", lines[1], want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
@@ -11,6 +12,7 @@ import (
 	"github.com/takara9/marmot/api"
 	"github.com/takara9/marmot/pkg/client"
 	"github.com/takara9/marmot/pkg/config"
+	"go.yaml.in/yaml/v3"
 )
 
 // creationTime は Status から作成日時を返す。nil の場合はゼロ時刻を返す。
@@ -40,9 +42,40 @@ func getClientConfig() (*client.MarmotEndpoint, error) {
 		return nil, err
 	}
 	_ = loadTokenForEndpoint(m) // best-effort; token file may not exist yet
+	// --project は設定ファイルの既定のプロジェクトより優先する
+	if p := strings.TrimSpace(projectFlag); p != "" {
+		m.Project = p
+	}
 	return m, nil
 }
 
+// printResponseBody は API の応答の JSON を --output で指定された json か yaml で表示する。
+func printResponseBody(byteBody []byte) error {
+	switch outputStyle {
+	case "json":
+		fmt.Println(string(byteBody))
+		return nil
+
+	case "yaml":
+		var data interface{}
+		if err := json.Unmarshal(byteBody, &data); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		yamlBytes, err := yaml.Marshal(data)
+		if err != nil {
+			fmt.Println("Failed to Marshal", err)
+			return err
+		}
+		fmt.Println(string(yamlBytes))
+		return nil
+
+	default:
+		fmt.Println("output style must set text/json/yaml")
+		return fmt.Errorf("output style must set text/json/yaml")
+	}
+}
+
 // clearScreen はターミナル画面をクリアする。
 func clearScreen() {
 	fmt.Print("\033[H\033[2J")
@@ -25,7 +25,12 @@ var errConsoleDetach = errors.New("console detached")
 var consoleCmd = &cobra.Command{
 	Use:   "console SERVER-NAME",
 	Short: "Connect to a server console",
-	Args:  cobra.ExactArgs(1),
+	Long: `Connect to the serial console of a server. Press Ctrl+] to detach.
+
+With --graphical, relay the graphical console (SPICE) of the server to a local port
+through the WebSocket proxy of marmotd. Connect a viewer such as remote-viewer to the
+printed address. With --url, print the WebSocket URL for a browser client instead.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 
@@ -67,6 +72,9 @@ var consoleCmd = &cobra.Command{
 		if hostPort == "" {
 			return fmt.Errorf("API host is required")
 		}
+		if consoleGraphical || consolePrintURL {
+			return runGraphicalConsole(cmd, m, *server, hostPort)
+		}
 		consolePath := strings.TrimSpace(m.BasePath)
 		if consolePath == "" {
 			consolePath = "/api/v1"
@@ -287,4 +295,7 @@ func setConsoleAuthorizationHeader(req *http.Request, accessToken string) {
 
 func init() {
 	rootCmd.AddCommand(consoleCmd)
+	consoleCmd.Flags().BoolVar(&consoleGraphical, "graphical", false, "Relay the graphical console (SPICE) to a local port")
+	consoleCmd.Flags().StringVar(&consoleListenAddr, "listen", "127.0.0.1:0", "Local address to listen on with --graphical")
+	consoleCmd.Flags().BoolVar(&consolePrintURL, "url", false, "Print the WebSocket URL of the graphical console and exit")
 }
\ No newline at end of file
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/client"
+)
+
+var (
+	consoleGraphical  bool   // グラフィカルコンソールをローカルのポートに中継する
+	consoleListenAddr string // --graphical で待ち受けるローカルのアドレス
+	consolePrintURL   bool   // WebSocket の URL を表示して終了する
+)
+
+// runGraphicalConsole はグラフィカルコンソールの WebSocket をローカルのポートに中継する
+// トークンは短命なため、ローカルの接続ごとに発行する。SPICE はチャンネルごとに接続を開く
+func runGraphicalConsole(cmd *cobra.Command, m *client.MarmotEndpoint, server api.Server, hostPort string) error {
+	serverID := api.ServerID(server)
+
+	if consolePrintURL {
+		tok, err := createConsoleToken(m, serverID)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\n", m.GraphicalConsoleURL(hostPort, tok.Path))
+		fmt.Fprintf(cmd.ErrOrStderr(), "protocol: %s, expires at: %s\n", tok.Protocol, tok.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", consoleListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", consoleListenAddr, err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	// 接続前にトークンを発行できることを確認する
+	tok, err := createConsoleToken(m, serverID)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s://%s に接続してください。Ctrl+C で終了します。\n", tok.Protocol, ln.Addr().String())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		<-sigCh
+		_ = ln.Close()
+	}()
+
+	for {
+		local, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			if err := relayLocalGraphicsConnection(m, serverID, hostPort, local); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "graphical console:", err)
+			}
+		}()
+	}
+}
+
+func createConsoleToken(m *client.MarmotEndpoint, serverID string) (api.ServerConsoleToken, error) {
+	body, _, err := m.CreateServerConsoleToken(serverID)
+	if err != nil {
+		return api.ServerConsoleToken{}, fmt.Errorf("failed to create console token: %w", err)
+	}
+	var tok api.ServerConsoleToken
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return api.ServerConsoleToken{}, fmt.Errorf("failed to parse console token: %w", err)
+	}
+	return tok, nil
+}
+
+func relayLocalGraphicsConnection(m *client.MarmotEndpoint, serverID, hostPort string, local net.Conn) error {
+	defer func() {
+		_ = local.Close()
+	}()
+
+	tok, err := createConsoleToken(m, serverID)
+	if err != nil {
+		return err
+	}
+	ws, err := m.DialGraphicalConsole(hostPort, tok.Path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = ws.Close()
+	}()
+
+	copyErr := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(ws, local)
+		copyErr <- err
+	}()
+	go func() {
+		_, err := io.Copy(local, ws)
+		copyErr <- err
+	}()
+
+	err = <-copyErr
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
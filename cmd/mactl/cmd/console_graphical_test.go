@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/takara9/marmot/pkg/client"
+	"golang.org/x/net/websocket"
+)
+
+var _ = Describe("graphical console relay", func() {
+	It("issues a token per local connection and relays it to the websocket", func() {
+		tokens := 0
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/server/ab12c/console/token":
+				tokens++
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				_, _ = fmt.Fprintf(w, `{"token":"t%d","expiresAt":"2099-01-01T00:00:00Z","protocol":"spice","path":"/api/v1/server/ab12c/console/graphical?token=t%d"}`, tokens, tokens)
+			case "/api/v1/server/ab12c/console/graphical":
+				websocket.Server{
+					Handshake: func(*websocket.Config, *http.Request) error { return nil },
+					Handler: func(ws *websocket.Conn) {
+						ws.PayloadType = websocket.BinaryFrame
+						_, _ = io.Copy(ws, ws)
+					},
+				}.ServeHTTP(w, r)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		defer ts.Close()
+
+		u, err := url.Parse(ts.URL)
+		Expect(err).NotTo(HaveOccurred())
+		m := &client.MarmotEndpoint{Scheme: u.Scheme, HostPort: u.Host, BasePath: "/api/v1", Client: ts.Client()}
+
+		local, remote := net.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- relayLocalGraphicsConnection(m, "ab12c", u.Host, remote)
+		}()
+
+		_, err = local.Write([]byte("REDQ"))
+		Expect(err).NotTo(HaveOccurred())
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(local, buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(buf)).To(Equal("REDQ"))
+
+		Expect(local.Close()).To(Succeed())
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(tokens).To(Equal(1))
+	})
+})
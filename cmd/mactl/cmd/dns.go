@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+)
+
+var (
+	dnsRecordTTL      int // 作成するレコードのTTL
+	dnsRecordPort     int // SRVレコードのポート番号
+	dnsRecordPriority int // SRVレコードの優先度
+	dnsRecordWeight   int // SRVレコードの重み
+)
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Internal DNS record management commands",
+	Long: `Internal DNS record management commands.
+
+The internal DNS answers A/AAAA records of servers, PTR records from the IPAM
+data and SRV records of load balancer listeners automatically. Custom records
+(A, AAAA, CNAME, PTR, SRV) can be added with these commands.`,
+}
+
+var dnsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List custom DNS records",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetDnsRecords()
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "DNSレコードの取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var data []api.DnsRecord
+		if err := json.Unmarshal(byteBody, &data); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printDnsRecordList(os.Stdout, data)
+		return nil
+	},
+}
+
+var dnsCreateCmd = &cobra.Command{
+	Use:   "create [name] [type] [value]",
+	Short: "Create a custom DNS record",
+	Long: `Create a custom DNS record.
+
+  mactl dns create www.net1 CNAME vm1.net1
+  mactl dns create 192.168.100.10 PTR vm1.net1
+  mactl dns create _web._tcp.net1 SRV vm1.net1 --port 8080`,
+	Args: cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		record := api.DnsRecord{
+			Name:  args[0],
+			Type:  strings.ToUpper(args[1]),
+			Value: args[2],
+		}
+		if cmd.Flags().Changed("ttl") {
+			record.Ttl = &dnsRecordTTL
+		}
+		if cmd.Flags().Changed("port") {
+			record.Port = &dnsRecordPort
+		}
+		if cmd.Flags().Changed("priority") {
+			record.Priority = &dnsRecordPriority
+		}
+		if cmd.Flags().Changed("weight") {
+			record.Weight = &dnsRecordWeight
+		}
+
+		byteBody, _, err := m.CreateDnsRecord(record)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "DNSレコードの作成に失敗しました。", err)
+			return err
+		}
+		var created api.DnsRecord
+		if err := json.Unmarshal(byteBody, &created); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		fmt.Println("DNSレコードを作成しました。ID:", stringVal(created.Id))
+		return nil
+	},
+}
+
+var dnsDetailCmd = &cobra.Command{
+	Use:     "detail [record-id]",
+	Aliases: []string{"get"},
+	Short:   "Show a custom DNS record",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetDnsRecordById(args[0])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "DNSレコードの取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var record api.DnsRecord
+		if err := json.Unmarshal(byteBody, &record); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printDnsRecordList(os.Stdout, []api.DnsRecord{record})
+		return nil
+	},
+}
+
+var dnsDeleteCmd = &cobra.Command{
+	Use:   "delete [record-id...]",
+	Short: "Delete custom DNS records",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		var lastErr error
+		for _, id := range args {
+			if _, _, err := m.DeleteDnsRecordById(id); err != nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "DNSレコードの削除に失敗しました。", "ID:", id, err)
+				lastErr = err
+				continue
+			}
+			fmt.Println("DNSレコードを削除しました。ID:", id)
+		}
+		return lastErr
+	},
+}
+
+// DNSレコードの一覧を表示する
+func printDnsRecordList(w io.Writer, records []api.DnsRecord) {
+	if len(records) == 0 {
+		_, _ = fmt.Fprintln(w, "DNSレコードが見つかりません。")
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "  %2s  %-6s  %-32s  %-6s  %-6s  %s\n", "No", "ID", "NAME", "TYPE", "TTL", "VALUE")
+	for i, rec := range records {
+		value := rec.Value
+		if rec.Type == "SRV" {
+			value = fmt.Sprintf("%s %s %s %s",
+				intValue(&rec, func(r *api.DnsRecord) *int { return r.Priority }, ""),
+				intValue(&rec, func(r *api.DnsRecord) *int { return r.Weight }, ""),
+				intValue(&rec, func(r *api.DnsRecord) *int { return r.Port }, ""),
+				rec.Value)
+		}
+		_, _ = fmt.Fprintf(w, "  %2d  %-6s  %-32s  %-6s  %-6s  %s\n",
+			i+1,
+			stringVal(rec.Id),
+			rec.Name,
+			rec.Type,
+			intValue(&rec, func(r *api.DnsRecord) *int { return r.Ttl }, ""),
+			value,
+		)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(dnsCmd)
+	dnsCmd.AddCommand(dnsListCmd)
+	dnsCmd.AddCommand(dnsCreateCmd)
+	dnsCmd.AddCommand(dnsDetailCmd)
+	dnsCmd.AddCommand(dnsDeleteCmd)
+	dnsCreateCmd.Flags().IntVar(&dnsRecordTTL, "ttl", 0, "TTL of the record in seconds (default 300)")
+	dnsCreateCmd.Flags().IntVar(&dnsRecordPort, "port", 0, "Port number of the SRV record")
+	dnsCreateCmd.Flags().IntVar(&dnsRecordPriority, "priority", 0, "Priority of the SRV record")
+	dnsCreateCmd.Flags().IntVar(&dnsRecordWeight, "weight", 0, "Weight of the SRV record")
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestPrintDnsRecordList(t *testing.T) {
+	records := []api.DnsRecord{
+		{Id: util.StringPtr("ab12c"), Name: "www.net1", Type: "CNAME", Value: "vm1.net1", Ttl: util.IntPtrInt(300)},
+		{Id: util.StringPtr("cd34e"), Name: "_web._tcp.net1", Type: "SRV", Value: "vm1.net1", Ttl: util.IntPtrInt(60),
+			Priority: util.IntPtrInt(10), Weight: util.IntPtrInt(5), Port: util.IntPtrInt(8080)},
+	}
+
+	var out bytes.Buffer
+	printDnsRecordList(&out, records)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printDnsRecordList() printed %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	for _, want := range []string{"ab12c", "www.net1", "CNAME", "300", "vm1.net1"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("first row %q does not contain %q", lines[1], want)
+		}
+	}
+	if !strings.Contains(lines[2], "10 5 8080 vm1.net1") {
+		t.Fatalf("second row = %q, want SRV value", lines[2])
+	}
+}
+
+func TestPrintDnsRecordListEmpty(t *testing.T) {
+	var out bytes.Buffer
+	printDnsRecordList(&out, nil)
+	if !strings.Contains(out.String(), "DNSレコードが見つかりません。") {
+		t.Fatalf("printDnsRecordList(nil) = %q", out.String())
+	}
+}
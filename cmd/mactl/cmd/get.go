@@ -200,7 +200,7 @@ func getLoadBalancerResources(name string) error {
 		}
 	}
 
-	return runList(listFn)
+	return runWatchList("/application-load-balancer", watchEventMatcher(name, false), listFn)
 }
 
 func getNetworkLoadBalancerResources(name string) error {
@@ -274,7 +274,7 @@ func getNetworkLoadBalancerResources(name string) error {
 		}
 	}
 
-	return runList(listFn)
+	return runWatchList("/network-load-balancer", watchEventMatcher(name, false), listFn)
 }
 
 func getKubernetesEngineResources(name string) error {
@@ -384,7 +384,7 @@ func getServerResources(name string) error {
 		return outputServers(servers)
 	}
 
-	return runList(listFn)
+	return runWatchList("/server", watchEventMatcher(name, !getServerShowAll), listFn)
 }
 
 func filterVisibleServers(servers []api.Server, showAll bool) []api.Server {
@@ -455,7 +455,7 @@ func getImageResources(name string) error {
 		return outputImages(images, clusterNodeCount)
 	}
 
-	return runList(listFn)
+	return runWatchList("/image", watchEventMatcher(name, false), listFn)
 }
 
 func getVolumeResources(name string) error {
@@ -496,7 +496,7 @@ func getVolumeResources(name string) error {
 		return outputVolumes(volumes)
 	}
 
-	return runList(listFn)
+	return runWatchList("/volume", volumeWatchEventMatcher(name, getServerShowAll), listFn)
 }
 
 func getNetworkResources(name string) error {
@@ -534,7 +534,7 @@ func getNetworkResources(name string) error {
 		return outputNetworks(networks)
 	}
 
-	return runList(listFn)
+	return runWatchList("/network", watchEventMatcher(name, false), listFn)
 }
 
 func getGatewayResources(name string) error {
@@ -60,6 +60,9 @@ var imageCreateCmd = &cobra.Command{
 			}
 			serveMap := data.(map[string]any)
 			fmt.Printf("イメージの作成要求が受け入れられました。ID: %v\n", serveMap["id"])
+			if jobId := extractResponseJobID(byteBody); jobId != "" {
+				fmt.Printf("進捗は mactl job detail %s で確認できます。\n", jobId)
+			}
 			return nil
 
 		case "json":
@@ -146,6 +146,7 @@ type imageArchiveMeta struct {
 	Name      string `json:"name"`
 	OsName    string `json:"osName,omitempty"`
 	OsVersion string `json:"osVersion,omitempty"`
+	Firmware  string `json:"firmware,omitempty"`
 }
 
 func writeImageArchive(outPath string, image api.Image, qcow2Bytes []byte) error {
@@ -188,6 +189,9 @@ func writeImageArchive(outPath string, image api.Image, qcow2Bytes []byte) error
 	if image.Spec.OsVersion != nil {
 		meta.OsVersion = strings.TrimSpace(*image.Spec.OsVersion)
 	}
+	if image.Spec.Firmware != nil {
+		meta.Firmware = strings.TrimSpace(*image.Spec.Firmware)
+	}
 	metaBytes, err := json.Marshal(meta)
 	if err != nil {
 		return fmt.Errorf("failed to marshal image metadata: %w", err)
@@ -20,7 +20,7 @@ var imageListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all images",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runList(func() error {
+		return runWatchList("/image", watchEventMatcher("", false), func() error {
 			m, err := getClientConfig()
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
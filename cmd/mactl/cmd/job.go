@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+)
+
+var jobLogLines int // ジョブログの末尾から表示する行数
+
+var jobCmd = &cobra.Command{
+	Use:   "job",
+	Short: "Job management commands",
+	Long: `Job management commands.
+
+Long running operations such as image download and image creation from a
+server return a job ID. The job shows the progress of the operation and can
+be cancelled while it is pending or running.`,
+}
+
+var jobListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List jobs",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runList(func() error {
+			m, err := getClientConfig()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+				os.Exit(1)
+			}
+
+			byteBody, _, err := m.GetJobs()
+			if err != nil {
+				println("エラー応答が返されました。", "err", err)
+				return nil
+			}
+			if outputStyle != "text" {
+				return printResponseBody(byteBody)
+			}
+
+			var data []api.Job
+			if err := json.Unmarshal(byteBody, &data); err != nil {
+				println("Failed to Unmarshal", err)
+				return err
+			}
+			printJobList(os.Stdout, data)
+			return nil
+		})
+	},
+}
+
+var jobDetailCmd = &cobra.Command{
+	Use:     "detail [job-id]",
+	Aliases: []string{"get"},
+	Short:   "Show a job",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetJobById(args[0])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ジョブの取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var job api.Job
+		if err := json.Unmarshal(byteBody, &job); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printJobDetail(os.Stdout, job)
+		return nil
+	},
+}
+
+var jobCancelCmd = &cobra.Command{
+	Use:   "cancel [job-id...]",
+	Short: "Cancel pending or running jobs",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		var lastErr error
+		for _, id := range args {
+			if _, _, err := m.CancelJobById(id); err != nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ジョブのキャンセルに失敗しました。", "ID:", id, err)
+				lastErr = err
+				continue
+			}
+			fmt.Println("ジョブのキャンセルを受け付けました。ID:", id)
+		}
+		return lastErr
+	},
+}
+
+var jobLogCmd = &cobra.Command{
+	Use:   "log [job-id]",
+	Short: "Show the log of a job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetJobLogById(args[0], jobLogLines)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ジョブログの取得に失敗しました。", err)
+			return err
+		}
+		_, err = os.Stdout.Write(byteBody)
+		return err
+	},
+}
+
+// ジョブ一覧を登録時間の順に表示する
+func printJobList(w io.Writer, jobs []api.Job) {
+	if len(jobs) == 0 {
+		_, _ = fmt.Fprintln(w, "ジョブが見つかりません。")
+		return
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobRequestTime(jobs[i]).Before(jobRequestTime(jobs[j]))
+	})
+
+	_, _ = fmt.Fprintf(w, "  %2s  %-6s  %-18s  %-10s  %-8s  %-12s  %-25s  %s\n", "No", "JOB-ID", "JOB-NAME", "STATUS", "RESOURCE", "NODE-NAME", "REQUESTED-AT", "MESSAGE")
+	for i, job := range jobs {
+		name, nodeName := "N/A", "N/A"
+		if job.Metadata != nil {
+			name = job.Metadata.Name
+			nodeName = stringVal(job.Metadata.NodeName)
+		}
+		_, _ = fmt.Fprintf(w, "  %2d  %-6s  %-18s  %-10s  %-8s  %-12s  %-25s  %s\n",
+			i+1,
+			formatID(job.Id),
+			name,
+			jobStatusName(job.Status),
+			stringValue(job.Spec, func(s *api.JobSpec) *string { return s.ResourceId }),
+			nodeName,
+			timeValue(job.Spec, func(s *api.JobSpec) *time.Time { return s.RequestTime }),
+			jobMessage(job.Status),
+		)
+	}
+}
+
+// ジョブの詳細を表示する
+func printJobDetail(w io.Writer, job api.Job) {
+	name, nodeName := "N/A", "N/A"
+	if job.Metadata != nil {
+		name = job.Metadata.Name
+		nodeName = stringVal(job.Metadata.NodeName)
+	}
+	_, _ = fmt.Fprintf(w, "Job ID:        %s\n", formatID(job.Id))
+	_, _ = fmt.Fprintf(w, "Name:          %s\n", name)
+	_, _ = fmt.Fprintf(w, "Status:        %s\n", jobStatusName(job.Status))
+	_, _ = fmt.Fprintf(w, "Node:          %s\n", nodeName)
+	_, _ = fmt.Fprintf(w, "Resource Kind: %s\n", stringValue(job.Spec, func(s *api.JobSpec) *string { return s.ResourceKind }))
+	_, _ = fmt.Fprintf(w, "Resource ID:   %s\n", stringValue(job.Spec, func(s *api.JobSpec) *string { return s.ResourceId }))
+	_, _ = fmt.Fprintf(w, "Requested At:  %s\n", timeValue(job.Spec, func(s *api.JobSpec) *time.Time { return s.RequestTime }))
+	_, _ = fmt.Fprintf(w, "Started At:    %s\n", timeValue(job.Spec, func(s *api.JobSpec) *time.Time { return s.StartTime }))
+	_, _ = fmt.Fprintf(w, "Finished At:   %s\n", timeValue(job.Spec, func(s *api.JobSpec) *time.Time { return s.FinishTime }))
+	_, _ = fmt.Fprintf(w, "Message:       %s\n", jobMessage(job.Status))
+}
+
+func jobRequestTime(job api.Job) time.Time {
+	if job.Spec != nil && job.Spec.RequestTime != nil {
+		return *job.Spec.RequestTime
+	}
+	return time.Time{}
+}
+
+func jobStatusName(status *api.Status) string {
+	if status == nil {
+		return "N/A"
+	}
+	if name, ok := db.JobStatus[status.StatusCode]; ok {
+		return name
+	}
+	return "N/A"
+}
+
+func jobMessage(status *api.Status) string {
+	if status == nil || status.Message == nil {
+		return ""
+	}
+	return *status.Message
+}
+
+// 応答に含まれるジョブIDを取り出す
+func extractResponseJobID(body []byte) string {
+	var data map[string]any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return ""
+	}
+	return normalizeResponseID(data["jobId"])
+}
+
+func init() {
+	rootCmd.AddCommand(jobCmd)
+	jobCmd.AddCommand(jobListCmd)
+	jobCmd.AddCommand(jobDetailCmd)
+	jobCmd.AddCommand(jobCancelCmd)
+	jobCmd.AddCommand(jobLogCmd)
+	jobLogCmd.Flags().IntVarP(&jobLogLines, "lines", "n", 0, "Show only the last N lines of the log")
+}
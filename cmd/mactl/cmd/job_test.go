@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestPrintJobListSortsByRequestTime(t *testing.T) {
+	now := time.Now()
+	jobs := []api.Job{
+		{
+			Id:       "bbbbb",
+			Metadata: &api.Metadata{Name: "image-from-server"},
+			Spec:     &api.JobSpec{RequestTime: util.TimePtr(now)},
+			Status:   &api.Status{StatusCode: db.JOB_RUNNING},
+		},
+		{
+			Id:       "aaaaa",
+			Metadata: &api.Metadata{Name: "image-download", NodeName: util.StringPtr("hv1")},
+			Spec:     &api.JobSpec{RequestTime: util.TimePtr(now.Add(-time.Minute)), ResourceId: util.StringPtr("img01")},
+			Status:   &api.Status{StatusCode: db.JOB_CANCELED, Message: util.StringPtr("canceled by request")},
+		},
+	}
+
+	var out bytes.Buffer
+	printJobList(&out, jobs)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("printJobList() printed %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	for _, want := range []string{"aaaaa", "image-download", "CANCELED", "img01", "hv1", "canceled by request"} {
+		if !strings.Contains(lines[1], want) {
+			t.Fatalf("first row %q does not contain %q", lines[1], want)
+		}
+	}
+	if !strings.Contains(lines[2], "bbbbb") || !strings.Contains(lines[2], "RUNNING") {
+		t.Fatalf("second row = %q, want bbbbb RUNNING", lines[2])
+	}
+}
+
+func TestPrintJobListEmpty(t *testing.T) {
+	var out bytes.Buffer
+	printJobList(&out, nil)
+	if !strings.Contains(out.String(), "ジョブが見つかりません。") {
+		t.Fatalf("printJobList(nil) = %q", out.String())
+	}
+}
+
+func TestExtractResponseJobID(t *testing.T) {
+	if got := extractResponseJobID([]byte(`{"id":"img01","jobId":"j0001"}`)); got != "j0001" {
+		t.Fatalf("extractResponseJobID() = %q, want j0001", got)
+	}
+	if got := extractResponseJobID([]byte(`{"id":"img01"}`)); got != "" {
+		t.Fatalf("extractResponseJobID() without jobId = %q, want empty", got)
+	}
+}
@@ -1,25 +1,74 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/exec"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/client"
 	"golang.org/x/term"
 )
 
+var (
+	loginOIDC        bool
+	loginOIDCTimeout time.Duration
+)
+
 var loginCmd = &cobra.Command{
-	Use:   "login USER-ID",
+	Use:   "login [USER-ID]",
 	Short: "Login to marmotd",
-	Long:  `Authenticate with marmotd using userId and password.`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Authenticate with marmotd using userId and password.
+
+With --oidc, mactl signs in through the OIDC identity provider configured in
+marmotd (authorization code flow with PKCE). mactl opens the login page in a
+browser and receives the result on a loopback address. The marmot roles of the
+user follow the group claims of the identity provider.
+
+  mactl login admin
+  mactl login --oidc`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		userID := strings.TrimSpace(args[0])
-		if userID == "" {
+		if loginOIDC {
+			if len(args) > 0 {
+				return fmt.Errorf("USER-ID cannot be given with --oidc")
+			}
+			m, err := getClientConfig()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+				return err
+			}
+			oidcConfig, err := m.AuthOidcConfig()
+			if err != nil {
+				return fmt.Errorf("failed to get OIDC settings from marmotd: %w", err)
+			}
+			ctx, cancel := context.WithTimeout(cmd.Context(), loginOIDCTimeout)
+			defer cancel()
+			idToken, err := client.OIDCAuthorize(ctx, *oidcConfig, openLoginURL)
+			if err != nil {
+				return fmt.Errorf("login failed: %w", err)
+			}
+			loginResp, err := m.AuthOidcLogin(idToken)
+			if err != nil {
+				return fmt.Errorf("login failed: %w", err)
+			}
+			userID := ""
+			if loginResp.User != nil {
+				userID = loginResp.User.Metadata.Id
+			}
+			return completeLogin(userID, loginResp)
+		}
+
+		if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
 			return fmt.Errorf("userId is required")
 		}
+		userID := strings.TrimSpace(args[0])
 
 		fmt.Print("Password: ")
 		pwd, err := term.ReadPassword(int(os.Stdin.Fd()))
@@ -35,61 +84,87 @@ var loginCmd = &cobra.Command{
 			return err
 		}
 
-		previousToken, prevErr := loadAccessToken()
-		if prevErr != nil {
-			fmt.Fprintln(os.Stderr, "Warning: Failed to load existing token:", prevErr)
-			previousToken = ""
-		}
-
 		loginResp, err := m.AuthLogin(userID, password)
 		if err != nil {
 			return fmt.Errorf("login failed: %w", err)
 		}
+		return completeLogin(userID, loginResp)
+	},
+}
 
-		if loginResp == nil || strings.TrimSpace(loginResp.AccessToken) == "" {
-			return fmt.Errorf("no access token returned")
-		}
+// completeLogin は前のセッションをログアウトし、新しいアクセストークンを保存して結果を表示する
+func completeLogin(userID string, loginResp *api.AuthLoginResponse) error {
+	previousToken, prevErr := loadAccessToken()
+	if prevErr != nil {
+		fmt.Fprintln(os.Stderr, "Warning: Failed to load existing token:", prevErr)
+		previousToken = ""
+	}
 
-		previousToken = strings.TrimSpace(previousToken)
-		if previousToken != "" && previousToken != strings.TrimSpace(loginResp.AccessToken) {
-			oldSessionClient, oldErr := getClientConfig()
-			if oldErr != nil {
-				fmt.Fprintln(os.Stderr, "Warning: Failed to initialize client for previous session logout:", oldErr)
-			} else {
-				oldSessionClient.SetAccessToken(previousToken)
-				if err := oldSessionClient.AuthLogout(); err != nil {
-					fmt.Fprintln(os.Stderr, "Warning: Failed to logout previous session:", err)
-				}
+	if loginResp == nil || strings.TrimSpace(loginResp.AccessToken) == "" {
+		return fmt.Errorf("no access token returned")
+	}
+
+	previousToken = strings.TrimSpace(previousToken)
+	if previousToken != "" && previousToken != strings.TrimSpace(loginResp.AccessToken) {
+		oldSessionClient, oldErr := getClientConfig()
+		if oldErr != nil {
+			fmt.Fprintln(os.Stderr, "Warning: Failed to initialize client for previous session logout:", oldErr)
+		} else {
+			oldSessionClient.SetAccessToken(previousToken)
+			if err := oldSessionClient.AuthLogout(); err != nil {
+				fmt.Fprintln(os.Stderr, "Warning: Failed to logout previous session:", err)
 			}
 		}
+	}
 
-		if err := saveAccessToken(loginResp.AccessToken); err != nil {
-			return fmt.Errorf("failed to save token: %w", err)
-		}
+	if err := saveAccessToken(loginResp.AccessToken); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
 
-		switch outputStyle {
-		case "text":
-			fmt.Printf("Successfully logged in as %s\n", userID)
-			if loginResp.MustChangePassword != nil && *loginResp.MustChangePassword {
-				fmt.Println("⚠️  You must change your password before using other commands.")
-				fmt.Println("   Run: mactl passwd")
-			}
-		case "json":
-			output := map[string]interface{}{
-				"success":            true,
-				"userId":             userID,
-				"mustChangePassword": loginResp.MustChangePassword,
-			}
-			if loginResp.ExpiresIn != nil {
-				output["expiresIn"] = *loginResp.ExpiresIn
-			}
-			jsonBytes, _ := json.MarshalIndent(output, "", "  ")
-			fmt.Println(string(jsonBytes))
+	switch outputStyle {
+	case "text":
+		fmt.Printf("Successfully logged in as %s\n", userID)
+		if loginResp.MustChangePassword != nil && *loginResp.MustChangePassword {
+			fmt.Println("⚠️  You must change your password before using other commands.")
+			fmt.Println("   Run: mactl passwd")
 		}
-		return nil
-	},
+	case "json":
+		output := map[string]interface{}{
+			"success":            true,
+			"userId":             userID,
+			"mustChangePassword": loginResp.MustChangePassword,
+		}
+		if loginResp.ExpiresIn != nil {
+			output["expiresIn"] = *loginResp.ExpiresIn
+		}
+		jsonBytes, _ := json.MarshalIndent(output, "", "  ")
+		fmt.Println(string(jsonBytes))
+	}
+	return nil
+}
+
+// openLoginURL は ID プロバイダーのログイン画面の URL を表示し、ブラウザで開く。
+// ブラウザを開けない場合は表示した URL を利用者が開く
+func openLoginURL(loginURL string) error {
+	fmt.Fprintln(os.Stderr, "Open the following URL in a browser to login:")
+	fmt.Fprintln(os.Stderr, loginURL)
+	var opener *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		opener = exec.Command("open", loginURL)
+	case "windows":
+		opener = exec.Command("rundll32", "url.dll,FileProtocolHandler", loginURL)
+	default:
+		opener = exec.Command("xdg-open", loginURL)
+	}
+	if err := opener.Start(); err == nil {
+		go func() { _ = opener.Wait() }()
+	}
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(loginCmd)
+	loginCmd.Flags().BoolVar(&loginOIDC, "oidc", false, "Login through the OIDC identity provider configured in marmotd")
+	loginCmd.Flags().DurationVar(&loginOIDCTimeout, "oidc-timeout", 5*time.Minute, "Time to wait for the login in the browser")
 }
@@ -274,8 +274,9 @@ func ApplyServerDefaults(server *api.Server) {
 
 // ApplyVolumeDefaults は volume.spec の既定値を補完する。
 // spec.type が未指定なら qcow2、spec.kind が未指定なら data を設定する。
+// spec.source を持つ複製の場合は、marmotd が複製元に合わせるため補完しない。
 func ApplyVolumeDefaults(volume *api.Volume) {
-	if volume == nil {
+	if volume == nil || volume.Spec.Source != nil {
 		return
 	}
 
@@ -84,15 +84,16 @@ func printHostCluster(statuses []api.HostStatus) {
 		return stringVal(statuses[i].NodeName) < stringVal(statuses[j].NodeName)
 	})
 
-	fmt.Printf("%-16s %-10s %-15s %7s %10s %6s %7s %7s %8s %8s %8s %s\n",
-		"NODE", "HOSTID", "IP", "CAP_CPU", "CAP_MEM(MB)", "TOTAL", "RUNNING", "STOPPED", "VCPU", "MEM(MB)", "VNETS", "AGE")
+	fmt.Printf("%-16s %-10s %-15s %-9s %7s %10s %6s %7s %7s %8s %8s %8s %s\n",
+		"NODE", "HOSTID", "IP", "STATUS", "CAP_CPU", "CAP_MEM(MB)", "TOTAL", "RUNNING", "STOPPED", "VCPU", "MEM(MB)", "VNETS", "AGE")
 	for _, s := range statuses {
 		a := s.Allocation
 		c := s.Capacity
-		fmt.Printf("%-16s %-10s %-15s %7d %10d %6d %7d %7d %8d %8d %8d %s\n",
+		fmt.Printf("%-16s %-10s %-15s %-9s %7d %10d %6d %7d %7d %8d %8d %8d %s\n",
 			stringVal(s.NodeName),
 			stringVal(s.HostId),
 			stringVal(s.IpAddress),
+			nodeScheduleStatus(s),
 			capacityIntVal(c, func(x *api.HostCapacity) *int { return x.CpuCores }),
 			capacityIntVal(c, func(x *api.HostCapacity) *int { return x.MemoryMB }),
 			intVal(a, func(x *api.HostAllocation) *int { return x.TotalVMs }),
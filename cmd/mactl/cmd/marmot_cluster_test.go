@@ -32,4 +32,25 @@ func TestPrintHostClusterUsesAgeColumn(t *testing.T) {
 	if !strings.Contains(out, "1d") {
 		t.Fatalf("stdout = %q, want creation age text", out)
 	}
-}
\ No newline at end of file
+}
+
+func TestPrintHostClusterShowsMaintenanceState(t *testing.T) {
+	ready := "hv1"
+	draining := "hv2"
+	state := "DRAINING"
+	unschedulable := true
+
+	out := captureStdoutForIDTest(t, func() {
+		printHostCluster([]api.HostStatus{
+			{NodeName: &ready},
+			{NodeName: &draining, Maintenance: &api.NodeMaintenance{Unschedulable: &unschedulable, State: &state}},
+		})
+	})
+
+	if !strings.Contains(out, "STATUS") {
+		t.Fatalf("stdout = %q, want STATUS header", out)
+	}
+	if !strings.Contains(out, "Ready") || !strings.Contains(out, "DRAINING") {
+		t.Fatalf("stdout = %q, want Ready and DRAINING states", out)
+	}
+}
@@ -19,7 +19,7 @@ var networkListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all networks",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runList(func() error {
+		return runWatchList("/network", watchEventMatcher("", false), func() error {
 			m, err := getClientConfig()
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+)
+
+var (
+	nodeMaintenanceReason string // cordon/drain の理由
+	nodeDrainAction       string // ドレイン時の稼働中サーバーの扱い
+)
+
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Node maintenance commands",
+}
+
+var nodeCordonCmd = &cobra.Command{
+	Use:   "cordon [node-name]",
+	Short: "Mark a node unschedulable",
+	Long: `Mark a node unschedulable.
+
+New servers and migrations are not placed on a cordoned node, and the node is
+excluded from scheduler leader and iSCSI target election. Servers already on
+the node keep running.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.CordonNode(args[0], nodeMaintenanceRequest())
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ノードの cordon に失敗しました。", err)
+			return err
+		}
+		if outputStyle == "text" {
+			fmt.Println("ノードをスケジュール対象外にしました。ノード:", args[0])
+			return nil
+		}
+		return printResponseBody(byteBody)
+	},
+}
+
+var nodeUncordonCmd = &cobra.Command{
+	Use:   "uncordon [node-name]",
+	Short: "Mark a node schedulable",
+	Long:  `Mark a node schedulable again. A drain in progress is cancelled.`,
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		if _, _, err := m.UncordonNode(args[0]); err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ノードの uncordon に失敗しました。", err)
+			return err
+		}
+		fmt.Println("ノードをスケジュール対象に戻しました。ノード:", args[0])
+		return nil
+	},
+}
+
+var nodeDrainCmd = &cobra.Command{
+	Use:   "drain [node-name]",
+	Short: "Cordon a node and move its servers away",
+	Long: `Cordon a node and move its running servers away.
+
+--action selects how running servers are handled:
+  migrate  live-migrate to other nodes, stop the ones that cannot be migrated (default)
+  stop     stop the servers
+
+The drain runs in the background. "mactl cluster" shows the node as DRAINED
+when no running server remains on it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		req := nodeMaintenanceRequest()
+		action := strings.TrimSpace(nodeDrainAction)
+		req.DrainAction = &action
+		byteBody, _, err := m.DrainNode(args[0], req)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ノードのドレインに失敗しました。", err)
+			return err
+		}
+		if outputStyle == "text" {
+			var rec api.NodeMaintenance
+			if err := json.Unmarshal(byteBody, &rec); err != nil {
+				fmt.Println("Failed to Unmarshal", err)
+				return err
+			}
+			fmt.Println("ノードのドレインを受け付けました。ノード:", args[0], "方法:", stringVal(rec.DrainAction))
+			return nil
+		}
+		return printResponseBody(byteBody)
+	},
+}
+
+func nodeMaintenanceRequest() api.NodeMaintenance {
+	var req api.NodeMaintenance
+	if reason := strings.TrimSpace(nodeMaintenanceReason); reason != "" {
+		req.Reason = &reason
+	}
+	return req
+}
+
+// nodeScheduleStatus はクラスタ一覧に表示するノードのスケジュール状態を返す
+func nodeScheduleStatus(s api.HostStatus) string {
+	if s.Maintenance == nil || s.Maintenance.Unschedulable == nil || !*s.Maintenance.Unschedulable {
+		return "Ready"
+	}
+	if s.Maintenance.State == nil || *s.Maintenance.State == "" {
+		return "CORDONED"
+	}
+	return *s.Maintenance.State
+}
+
+func init() {
+	rootCmd.AddCommand(nodeCmd)
+	nodeCmd.AddCommand(nodeCordonCmd)
+	nodeCmd.AddCommand(nodeUncordonCmd)
+	nodeCmd.AddCommand(nodeDrainCmd)
+	nodeCordonCmd.Flags().StringVar(&nodeMaintenanceReason, "reason", "", "Reason for the maintenance")
+	nodeDrainCmd.Flags().StringVar(&nodeMaintenanceReason, "reason", "", "Reason for the maintenance")
+	nodeDrainCmd.Flags().StringVar(&nodeDrainAction, "action", "migrate", "How running servers are handled: migrate or stop")
+}
@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/config"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "Project commands",
+	Long: `Project commands.
+
+Servers, volumes, virtual networks, images and load balancers belong to a
+project. Resources created without a project belong to the "default" project.
+Roles given to a project member apply only to the resources of that project.
+
+The --project flag and the default project set by 'mactl project use' select
+the project that list commands show and create commands use.`,
+}
+
+var projectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List projects",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetProjects()
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "プロジェクトの取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var data []api.Project
+		if err := json.Unmarshal(byteBody, &data); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printProjectList(os.Stdout, data, m.Project)
+		return nil
+	},
+}
+
+var projectCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a project",
+	Long: `Create a project. Members are given as USER-ID=ROLE[,ROLE...].
+
+  mactl project create team-a --description "Team A" --member alice=operator --member bob=viewer`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		project := api.Project{Metadata: api.Metadata{Name: args[0]}}
+		if err := applyProjectFlags(cmd, &project); err != nil {
+			return err
+		}
+
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		if _, _, err := m.CreateProject(project); err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "プロジェクトの作成に失敗しました。", err)
+			return err
+		}
+		fmt.Println("プロジェクトを作成しました。NAME:", args[0])
+		return nil
+	},
+}
+
+var projectDetailCmd = &cobra.Command{
+	Use:     "detail [name]",
+	Aliases: []string{"get"},
+	Short:   "Show a project and its members",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetProjectByName(args[0])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "プロジェクトの取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var project api.Project
+		if err := json.Unmarshal(byteBody, &project); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printProjectDetail(os.Stdout, project)
+		return nil
+	},
+}
+
+var projectUpdateCmd = &cobra.Command{
+	Use:   "update [name]",
+	Short: "Update a project",
+	Long: `Update a project. --member replaces the roles of the given member and
+--remove-member removes a member; other members are kept.
+
+  mactl project update team-a --member carol=operator --remove-member bob`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetProjectByName(args[0])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "プロジェクトの取得に失敗しました。", err)
+			return err
+		}
+		var project api.Project
+		if err := json.Unmarshal(byteBody, &project); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		if err := applyProjectFlags(cmd, &project); err != nil {
+			return err
+		}
+
+		if _, _, err := m.UpdateProjectByName(args[0], project); err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "プロジェクトの更新に失敗しました。", err)
+			return err
+		}
+		fmt.Println("プロジェクトを更新しました。NAME:", args[0])
+		return nil
+	},
+}
+
+var projectDeleteCmd = &cobra.Command{
+	Use:   "delete [name...]",
+	Short: "Delete projects that have no resources",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		var lastErr error
+		for _, name := range args {
+			if _, _, err := m.DeleteProjectByName(name); err != nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "プロジェクトの削除に失敗しました。", "NAME:", name, err)
+				lastErr = err
+				continue
+			}
+			fmt.Println("プロジェクトを削除しました。NAME:", name)
+		}
+		return lastErr
+	},
+}
+
+var projectUseCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Set the default project in $HOME/.marmot",
+	Long: `Set the default project used by list and create commands.
+Without a name, the default project is cleared and list commands show the
+resources of all projects the user can see.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfgPath := config.MarmotConfigPath()
+		if apiConfigFilename != "" {
+			cfgPath = apiConfigFilename
+		}
+		cfg, err := config.ReadMarmotConfig(cfgPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("設定ファイルが見つかりません。'mactl ep add <URL>' でエンドポイントを追加してください")
+			}
+			return fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+		}
+
+		cfg.Project = ""
+		if len(args) > 0 {
+			cfg.Project = strings.TrimSpace(args[0])
+		}
+		if err := config.WriteMarmotConfig(cfgPath, cfg); err != nil {
+			return fmt.Errorf("設定ファイルの書き込みに失敗しました: %w", err)
+		}
+
+		if cfg.Project == "" {
+			fmt.Println("既定のプロジェクトを解除しました")
+		} else {
+			fmt.Println("既定のプロジェクトを設定しました:", cfg.Project)
+		}
+		return nil
+	},
+}
+
+// parseProjectMember は USER-ID=ROLE[,ROLE...] 形式のメンバーを解析する
+func parseProjectMember(value string) (api.ProjectMember, error) {
+	userID, roles, ok := strings.Cut(value, "=")
+	userID = strings.TrimSpace(userID)
+	if !ok || userID == "" {
+		return api.ProjectMember{}, fmt.Errorf("--member must be USER-ID=ROLE[,ROLE...]: %q", value)
+	}
+	member := api.ProjectMember{UserId: userID, Roles: []string{}}
+	for _, role := range strings.Split(roles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			member.Roles = append(member.Roles, role)
+		}
+	}
+	if len(member.Roles) == 0 {
+		return api.ProjectMember{}, fmt.Errorf("--member %q has no roles", value)
+	}
+	return member, nil
+}
+
+// applyProjectFlags は指定されたフラグだけをプロジェクトに反映する。
+// --member は同じ利用者のロールを置き換え、--remove-member は利用者をメンバーから外す
+func applyProjectFlags(cmd *cobra.Command, project *api.Project) error {
+	flags := cmd.Flags()
+	if flags.Changed("description") {
+		description, err := flags.GetString("description")
+		if err != nil {
+			return err
+		}
+		project.Spec.Description = util.StringPtr(description)
+	}
+
+	members := util.OrDefault(project.Spec.Members, []api.ProjectMember{})
+	values, err := flags.GetStringArray("member")
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		member, err := parseProjectMember(value)
+		if err != nil {
+			return err
+		}
+		replaced := false
+		for i := range members {
+			if members[i].UserId == member.UserId {
+				members[i] = member
+				replaced = true
+			}
+		}
+		if !replaced {
+			members = append(members, member)
+		}
+	}
+	if flags.Lookup("remove-member") != nil {
+		removed, err := flags.GetStringSlice("remove-member")
+		if err != nil {
+			return err
+		}
+		for _, userID := range removed {
+			kept := members[:0]
+			for _, m := range members {
+				if m.UserId != strings.TrimSpace(userID) {
+					kept = append(kept, m)
+				}
+			}
+			members = kept
+		}
+	}
+	project.Spec.Members = &members
+	return nil
+}
+
+// プロジェクトの一覧を表示する。既定のプロジェクトには * を付ける
+func printProjectList(w io.Writer, projects []api.Project, current string) {
+	if len(projects) == 0 {
+		_, _ = fmt.Fprintln(w, "プロジェクトが見つかりません。")
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "  %2s  %1s %-24s  %7s  %s\n", "No", "", "NAME", "MEMBERS", "DESCRIPTION")
+	for i, p := range projects {
+		mark := ""
+		if p.Metadata.Name == strings.TrimSpace(current) {
+			mark = "*"
+		}
+		_, _ = fmt.Fprintf(w, "  %2d  %1s %-24s  %7d  %s\n",
+			i+1,
+			mark,
+			p.Metadata.Name,
+			len(util.OrDefault(p.Spec.Members, nil)),
+			util.OrDefault(p.Spec.Description, ""),
+		)
+	}
+}
+
+// プロジェクトの詳細とメンバーを表示する
+func printProjectDetail(w io.Writer, project api.Project) {
+	_, _ = fmt.Fprintf(w, "Name:        %s\n", project.Metadata.Name)
+	_, _ = fmt.Fprintf(w, "Description: %s\n", stringVal(project.Spec.Description))
+	_, _ = fmt.Fprintf(w, "Owner:       %s\n", stringVal(project.Metadata.Owner))
+	members := util.OrDefault(project.Spec.Members, nil)
+	if len(members) == 0 {
+		_, _ = fmt.Fprintln(w, "Members:     (none)")
+		return
+	}
+	_, _ = fmt.Fprintln(w, "Members:")
+	_, _ = fmt.Fprintf(w, "  %-24s  %s\n", "USER-ID", "ROLES")
+	for _, m := range members {
+		_, _ = fmt.Fprintf(w, "  %-24s  %s\n", m.UserId, strings.Join(m.Roles, ","))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(projectCmd)
+	projectCmd.AddCommand(projectListCmd)
+	projectCmd.AddCommand(projectCreateCmd)
+	projectCmd.AddCommand(projectDetailCmd)
+	projectCmd.AddCommand(projectUpdateCmd)
+	projectCmd.AddCommand(projectDeleteCmd)
+	projectCmd.AddCommand(projectUseCmd)
+	for _, c := range []*cobra.Command{projectCreateCmd, projectUpdateCmd} {
+		c.Flags().String("description", "", "Description of the project")
+		c.Flags().StringArray("member", nil, "Project member as USER-ID=ROLE[,ROLE...] (repeatable)")
+	}
+	projectUpdateCmd.Flags().StringSlice("remove-member", nil, "User ID to remove from the members (repeatable)")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestApplyProjectFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().String("description", "", "")
+	cmd.Flags().StringArray("member", nil, "")
+	cmd.Flags().StringSlice("remove-member", nil, "")
+	if err := cmd.Flags().Parse([]string{"--member", "alice=viewer,operator", "--member", "carol=viewer", "--remove-member", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	members := []api.ProjectMember{
+		{UserId: "alice", Roles: []string{"viewer"}},
+		{UserId: "bob", Roles: []string{"operator"}},
+	}
+	project := api.Project{Spec: api.ProjectSpec{Description: util.StringPtr("Team A"), Members: &members}}
+	if err := applyProjectFlags(cmd, &project); err != nil {
+		t.Fatalf("applyProjectFlags() error = %v", err)
+	}
+	if util.OrDefault(project.Spec.Description, "") != "Team A" {
+		t.Fatalf("description = %v, want unchanged", project.Spec.Description)
+	}
+	got := util.OrDefault(project.Spec.Members, nil)
+	if len(got) != 2 || got[0].UserId != "alice" || strings.Join(got[0].Roles, ",") != "viewer,operator" || got[1].UserId != "carol" {
+		t.Fatalf("members = %+v, want alice (viewer,operator) and carol", got)
+	}
+}
+
+func TestParseProjectMemberRejectsMissingRoles(t *testing.T) {
+	for _, value := range []string{"alice", "alice=", "=viewer", "alice= , "} {
+		if _, err := parseProjectMember(value); err == nil {
+			t.Fatalf("parseProjectMember(%q) expected error", value)
+		}
+	}
+}
+
+func TestPrintProjectListMarksDefaultProject(t *testing.T) {
+	var buf bytes.Buffer
+	members := []api.ProjectMember{{UserId: "alice", Roles: []string{"viewer"}}}
+	printProjectList(&buf, []api.Project{
+		{Metadata: api.Metadata{Name: "default"}},
+		{Metadata: api.Metadata{Name: "team-a"}, Spec: api.ProjectSpec{Description: util.StringPtr("Team A"), Members: &members}},
+	}, "team-a")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	if strings.Contains(lines[1], "*") || !strings.Contains(lines[2], "* team-a") || !strings.Contains(lines[2], "Team A") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,388 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+var quotaCmd = &cobra.Command{
+	Use:   "quota",
+	Short: "Resource quota commands",
+	Long: `Resource quota commands.
+
+Without a subcommand, shows the resource usage of the current user and the
+limits of the quotas applied to the user.
+
+Quotas limit the vCPUs, memory, volume size, servers, virtual networks and
+public IP addresses (gateways, VPN gateways and load balancers) created by
+each of the users listed in the quota, and the total created in each of the
+projects listed in the quota.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runQuotaUsage(cmd, "")
+	},
+}
+
+var quotaUsageCmd = &cobra.Command{
+	Use:   "usage [user-id]",
+	Short: "Show the resource usage and quotas of a user",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		userID := ""
+		if len(args) > 0 {
+			userID = args[0]
+		}
+		return runQuotaUsage(cmd, userID)
+	},
+}
+
+var quotaListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List quotas",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetQuotas()
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "クォータの取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var data []api.Quota
+		if err := json.Unmarshal(byteBody, &data); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printQuotaList(os.Stdout, data)
+		return nil
+	},
+}
+
+var quotaCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a quota",
+	Long: `Create a quota. Resources without a limit flag are not limited.
+
+  mactl quota create team-a --user alice --user bob --vcpus 16 --memory 32768 --servers 8`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		quota := api.Quota{Name: args[0]}
+		if err := applyQuotaFlags(cmd, &quota); err != nil {
+			return err
+		}
+
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.CreateQuota(quota)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "クォータの作成に失敗しました。", err)
+			return err
+		}
+		var created api.Quota
+		if err := json.Unmarshal(byteBody, &created); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		fmt.Println("クォータを作成しました。ID:", stringVal(created.Id))
+		return nil
+	},
+}
+
+var quotaUpdateCmd = &cobra.Command{
+	Use:   "update [quota-id]",
+	Short: "Update a quota",
+	Long: `Update a quota. Only the given flags are changed; a limit of -1 removes the limit.
+
+  mactl quota update ab12c --vcpus 32 --public-ips -1`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetQuotaById(args[0])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "クォータの取得に失敗しました。", err)
+			return err
+		}
+		var quota api.Quota
+		if err := json.Unmarshal(byteBody, &quota); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		if err := applyQuotaFlags(cmd, &quota); err != nil {
+			return err
+		}
+
+		if _, _, err := m.UpdateQuotaById(args[0], quota); err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "クォータの更新に失敗しました。", err)
+			return err
+		}
+		fmt.Println("クォータを更新しました。ID:", args[0])
+		return nil
+	},
+}
+
+var quotaDetailCmd = &cobra.Command{
+	Use:     "detail [quota-id]",
+	Aliases: []string{"get"},
+	Short:   "Show a quota",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.GetQuotaById(args[0])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "クォータの取得に失敗しました。", err)
+			return err
+		}
+		if outputStyle != "text" {
+			return printResponseBody(byteBody)
+		}
+
+		var quota api.Quota
+		if err := json.Unmarshal(byteBody, &quota); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		printQuotaList(os.Stdout, []api.Quota{quota})
+		return nil
+	},
+}
+
+var quotaDeleteCmd = &cobra.Command{
+	Use:   "delete [quota-id...]",
+	Short: "Delete quotas",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		var lastErr error
+		for _, id := range args {
+			if _, _, err := m.DeleteQuotaById(id); err != nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "クォータの削除に失敗しました。", "ID:", id, err)
+				lastErr = err
+				continue
+			}
+			fmt.Println("クォータを削除しました。ID:", id)
+		}
+		return lastErr
+	},
+}
+
+// runQuotaUsage は利用者の使用量とクォータを表示する。利用者を省略した場合はログイン中の利用者
+func runQuotaUsage(cmd *cobra.Command, userID string) error {
+	m, err := getClientConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+		os.Exit(1)
+	}
+
+	if userID == "" {
+		me, err := m.AuthMe()
+		if err != nil {
+			return fmt.Errorf("failed to get current user: %w", err)
+		}
+		userID = me.UserId
+	}
+
+	byteBody, _, err := m.GetUserQuotaUsage(userID)
+	if err != nil {
+		_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "使用量の取得に失敗しました。", err)
+		return err
+	}
+	if outputStyle != "text" {
+		return printResponseBody(byteBody)
+	}
+
+	var usage api.QuotaUsage
+	if err := json.Unmarshal(byteBody, &usage); err != nil {
+		fmt.Println("Failed to Unmarshal", err)
+		return err
+	}
+	printQuotaUsage(os.Stdout, usage)
+	return nil
+}
+
+// quotaLimitFlags はクォータの上限のフラグ名と設定先
+func quotaLimitFlags(limits *api.QuotaResources) []struct {
+	name  string
+	limit **int
+} {
+	return []struct {
+		name  string
+		limit **int
+	}{
+		{"vcpus", &limits.Vcpus},
+		{"memory", &limits.MemoryMB},
+		{"volume-gb", &limits.VolumeGB},
+		{"servers", &limits.Servers},
+		{"networks", &limits.Networks},
+		{"public-ips", &limits.PublicIps},
+	}
+}
+
+// applyQuotaFlags は指定されたフラグだけをクォータに反映する。上限の -1 は上限を外す
+func applyQuotaFlags(cmd *cobra.Command, quota *api.Quota) error {
+	flags := cmd.Flags()
+	for _, f := range quotaLimitFlags(&quota.Limits) {
+		if !flags.Changed(f.name) {
+			continue
+		}
+		value, err := flags.GetInt(f.name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case value == -1:
+			*f.limit = nil
+		case value < 0:
+			return fmt.Errorf("--%s must be 0 or more, or -1 to remove the limit", f.name)
+		default:
+			*f.limit = util.IntPtrInt(value)
+		}
+	}
+	if flags.Changed("user") {
+		users, err := flags.GetStringSlice("user")
+		if err != nil {
+			return err
+		}
+		quota.Users = &users
+	}
+	if flags.Changed("for-project") {
+		projects, err := flags.GetStringSlice("for-project")
+		if err != nil {
+			return err
+		}
+		quota.Projects = &projects
+	}
+	return nil
+}
+
+func quotaLimitText(limit *int) string {
+	if limit == nil {
+		return "-"
+	}
+	return strconv.Itoa(*limit)
+}
+
+// クォータの一覧を表示する
+func printQuotaList(w io.Writer, quotas []api.Quota) {
+	if len(quotas) == 0 {
+		_, _ = fmt.Fprintln(w, "クォータが見つかりません。")
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "  %2s  %-6s  %-16s  %6s  %10s  %10s  %7s  %8s  %10s  %-16s  %s\n",
+		"No", "ID", "NAME", "VCPUS", "MEMORY(MB)", "VOLUME(GB)", "SERVERS", "NETWORKS", "PUBLIC-IPS", "PROJECTS", "USERS")
+	for i, q := range quotas {
+		users := ""
+		if q.Users != nil {
+			users = strings.Join(*q.Users, ",")
+		}
+		projects := ""
+		if q.Projects != nil {
+			projects = strings.Join(*q.Projects, ",")
+		}
+		_, _ = fmt.Fprintf(w, "  %2d  %-6s  %-16s  %6s  %10s  %10s  %7s  %8s  %10s  %-16s  %s\n",
+			i+1,
+			stringVal(q.Id),
+			q.Name,
+			quotaLimitText(q.Limits.Vcpus),
+			quotaLimitText(q.Limits.MemoryMB),
+			quotaLimitText(q.Limits.VolumeGB),
+			quotaLimitText(q.Limits.Servers),
+			quotaLimitText(q.Limits.Networks),
+			quotaLimitText(q.Limits.PublicIps),
+			projects,
+			users,
+		)
+	}
+}
+
+// 利用者の使用量と、適用されるクォータのうち最も小さい上限を表示する
+func printQuotaUsage(w io.Writer, usage api.QuotaUsage) {
+	names := make([]string, 0, len(usage.Quotas))
+	for _, q := range usage.Quotas {
+		names = append(names, q.Name)
+	}
+	_, _ = fmt.Fprintf(w, "User: %s\n", usage.UserId)
+	if len(names) == 0 {
+		_, _ = fmt.Fprintln(w, "Quotas: (none)")
+	} else {
+		_, _ = fmt.Fprintf(w, "Quotas: %s\n", strings.Join(names, ", "))
+	}
+
+	rows := []struct {
+		name   string
+		used   *int
+		limits func(api.QuotaResources) *int
+	}{
+		{"vCPU", usage.Used.Vcpus, func(r api.QuotaResources) *int { return r.Vcpus }},
+		{"Memory(MB)", usage.Used.MemoryMB, func(r api.QuotaResources) *int { return r.MemoryMB }},
+		{"Volume(GB)", usage.Used.VolumeGB, func(r api.QuotaResources) *int { return r.VolumeGB }},
+		{"Servers", usage.Used.Servers, func(r api.QuotaResources) *int { return r.Servers }},
+		{"Networks", usage.Used.Networks, func(r api.QuotaResources) *int { return r.Networks }},
+		{"Public IPs", usage.Used.PublicIps, func(r api.QuotaResources) *int { return r.PublicIps }},
+	}
+	_, _ = fmt.Fprintf(w, "  %-12s  %8s  %8s\n", "RESOURCE", "USED", "LIMIT")
+	for _, row := range rows {
+		var limit *int
+		for _, q := range usage.Quotas {
+			if l := row.limits(q.Limits); l != nil && (limit == nil || *l < *limit) {
+				limit = l
+			}
+		}
+		_, _ = fmt.Fprintf(w, "  %-12s  %8d  %8s\n", row.name, util.OrDefault(row.used, 0), quotaLimitText(limit))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(quotaCmd)
+	quotaCmd.AddCommand(quotaUsageCmd)
+	quotaCmd.AddCommand(quotaListCmd)
+	quotaCmd.AddCommand(quotaCreateCmd)
+	quotaCmd.AddCommand(quotaDetailCmd)
+	quotaCmd.AddCommand(quotaUpdateCmd)
+	quotaCmd.AddCommand(quotaDeleteCmd)
+	for _, c := range []*cobra.Command{quotaCreateCmd, quotaUpdateCmd} {
+		c.Flags().Int("vcpus", 0, "Maximum number of vCPUs")
+		c.Flags().Int("memory", 0, "Maximum memory in MB")
+		c.Flags().Int("volume-gb", 0, "Maximum total volume size in GB")
+		c.Flags().Int("servers", 0, "Maximum number of servers")
+		c.Flags().Int("networks", 0, "Maximum number of virtual networks")
+		c.Flags().Int("public-ips", 0, "Maximum number of public IP addresses")
+		c.Flags().StringSlice("user", nil, "User ID the quota applies to (repeatable)")
+		c.Flags().StringSlice("for-project", nil, "Project the quota applies to (repeatable)")
+	}
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestApplyQuotaFlags(t *testing.T) {
+	cmd := &cobra.Command{}
+	cmd.Flags().Int("vcpus", 0, "")
+	cmd.Flags().Int("memory", 0, "")
+	cmd.Flags().Int("volume-gb", 0, "")
+	cmd.Flags().Int("servers", 0, "")
+	cmd.Flags().Int("networks", 0, "")
+	cmd.Flags().Int("public-ips", 0, "")
+	cmd.Flags().StringSlice("user", nil, "")
+	if err := cmd.Flags().Parse([]string{"--vcpus", "16", "--public-ips", "-1", "--user", "alice", "--user", "bob"}); err != nil {
+		t.Fatal(err)
+	}
+
+	quota := api.Quota{Name: "team-a", Limits: api.QuotaResources{
+		MemoryMB:  util.IntPtrInt(4096),
+		PublicIps: util.IntPtrInt(2),
+	}}
+	if err := applyQuotaFlags(cmd, &quota); err != nil {
+		t.Fatalf("applyQuotaFlags() error = %v", err)
+	}
+	if util.OrDefault(quota.Limits.Vcpus, 0) != 16 || util.OrDefault(quota.Limits.MemoryMB, 0) != 4096 {
+		t.Fatalf("limits = %+v, want vcpus 16 and the unchanged memory", quota.Limits)
+	}
+	if quota.Limits.PublicIps != nil || quota.Limits.Servers != nil {
+		t.Fatalf("limits = %+v, want public IPs removed and servers unset", quota.Limits)
+	}
+	if quota.Users == nil || strings.Join(*quota.Users, ",") != "alice,bob" {
+		t.Fatalf("users = %v, want alice,bob", quota.Users)
+	}
+
+	if err := cmd.Flags().Set("servers", "-2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyQuotaFlags(cmd, &quota); err == nil {
+		t.Fatal("applyQuotaFlags() expected error for --servers -2")
+	}
+}
+
+func TestPrintQuotaUsage(t *testing.T) {
+	usage := api.QuotaUsage{
+		UserId: "alice",
+		Used:   api.QuotaResources{Vcpus: util.IntPtrInt(6), Servers: util.IntPtrInt(3)},
+		Quotas: []api.Quota{
+			{Name: "team-a", Limits: api.QuotaResources{Vcpus: util.IntPtrInt(16), Servers: util.IntPtrInt(4)}},
+			{Name: "trial", Limits: api.QuotaResources{Vcpus: util.IntPtrInt(8)}},
+		},
+	}
+
+	var out bytes.Buffer
+	printQuotaUsage(&out, usage)
+	text := out.String()
+	for _, want := range []string{"User: alice", "Quotas: team-a, trial"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("output does not contain %q:\n%s", want, text)
+		}
+	}
+	rows := map[string][]string{
+		"vCPU":     {"6", "8"},
+		"Servers":  {"3", "4"},
+		"Networks": {"0", "-"},
+	}
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		if want, ok := rows[fields[0]]; ok {
+			if fields[1] != want[0] || fields[2] != want[1] {
+				t.Fatalf("row %q = %v, want used %s limit %s", fields[0], fields[1:], want[0], want[1])
+			}
+			delete(rows, fields[0])
+		}
+	}
+	if len(rows) != 0 {
+		t.Fatalf("rows %v are not printed:\n%s", rows, text)
+	}
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/config"
+)
+
+var roleFilename string
+
+var roleCreateCmd = &cobra.Command{
+	Use:   "create -f FILE.yaml",
+	Short: "Create a custom role (admin only)",
+	Long: `Create a custom role from a YAML or JSON file.
+
+  apiVersion: v1
+  kind: Role
+  metadata:
+    name: dev-operator
+  spec:
+    description: Operate servers labeled env=dev
+    permissions:
+      - resource: Server
+        verbs: [read]
+      - resource: Server
+        verbs: [create, update, delete]
+        labelSelector:
+          matchLabels:
+            env: dev
+
+A permission with labelSelector applies only to the resources that have all of
+the labels.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		role, err := readRoleFile(roleFilename)
+		if err != nil {
+			return err
+		}
+
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			return err
+		}
+
+		created, err := m.CreateRole(role)
+		if err != nil {
+			return fmt.Errorf("failed to create role: %w", err)
+		}
+		if outputStyle != "text" {
+			return printRole(created)
+		}
+		fmt.Printf("Role '%s' created successfully\n", created.Metadata.Name)
+		return nil
+	},
+}
+
+var roleUpdateCmd = &cobra.Command{
+	Use:   "update -f FILE.yaml",
+	Short: "Replace the permissions of a custom role (admin only)",
+	Long: `Replace the description and permissions of the custom role named by
+metadata.name in the file. Built-in roles cannot be changed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		role, err := readRoleFile(roleFilename)
+		if err != nil {
+			return err
+		}
+
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			return err
+		}
+
+		updated, err := m.UpdateRoleByName(role.Metadata.Name, role)
+		if err != nil {
+			return fmt.Errorf("failed to update role: %w", err)
+		}
+		if outputStyle != "text" {
+			return printRole(updated)
+		}
+		fmt.Printf("Role '%s' updated successfully\n", updated.Metadata.Name)
+		return nil
+	},
+}
+
+// readRoleFile はロールの定義を YAML または JSON のファイルから読み込む
+func readRoleFile(filename string) (api.Role, error) {
+	var role api.Role
+	if err := config.ReadYamlConfig(filename, &role); err != nil {
+		return api.Role{}, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+	if kind := strings.TrimSpace(role.Kind); kind != "" && kind != "Role" {
+		return api.Role{}, fmt.Errorf("kind must be Role: %s", kind)
+	}
+	if strings.TrimSpace(role.Metadata.Name) == "" {
+		return api.Role{}, fmt.Errorf("metadata.name is required in %s", filename)
+	}
+	return role, nil
+}
+
+func init() {
+	roleCmd.AddCommand(roleCreateCmd)
+	roleCmd.AddCommand(roleUpdateCmd)
+	for _, c := range []*cobra.Command{roleCreateCmd, roleUpdateCmd} {
+		c.Flags().StringVarP(&roleFilename, "configfile", "f", "", "YAML or JSON file of the role")
+		if err := c.MarkFlagRequired("configfile"); err != nil {
+			panic(err)
+		}
+	}
+}
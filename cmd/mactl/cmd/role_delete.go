@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var roleDeleteCmd = &cobra.Command{
+	Use:   "delete ROLE-NAME...",
+	Short: "Delete custom roles (admin only)",
+	Long:  `Delete custom roles. A role assigned to a user or a project member cannot be deleted.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			return err
+		}
+
+		var lastErr error
+		for _, name := range args {
+			if err := m.DeleteRoleByName(name); err != nil {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "failed to delete role '%s': %v\n", name, err)
+				lastErr = err
+				continue
+			}
+			fmt.Printf("Role '%s' deleted successfully\n", name)
+		}
+		return lastErr
+	},
+}
+
+func init() {
+	roleCmd.AddCommand(roleDeleteCmd)
+}
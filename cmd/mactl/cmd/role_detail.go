@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+var roleDetailCmd = &cobra.Command{
+	Use:     "detail ROLE-NAME",
+	Aliases: []string{"get"},
+	Short:   "Show the permissions of a role",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			return err
+		}
+
+		role, err := m.GetRoleByName(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get role: %w", err)
+		}
+		if outputStyle != "text" {
+			return printRole(role)
+		}
+		printRoleDetail(os.Stdout, *role)
+		return nil
+	},
+}
+
+// printRole はロールを -o で指定した形式で表示する
+func printRole(role *api.Role) error {
+	byteBody, err := json.Marshal(role)
+	if err != nil {
+		return err
+	}
+	return printResponseBody(byteBody)
+}
+
+// roleTypeText は組み込みロールかカスタムロールかを返す
+func roleTypeText(role api.Role) string {
+	if role.Status != nil && !role.Status.Builtin {
+		return "custom"
+	}
+	return "built-in"
+}
+
+// labelSelectorText はラベルの条件を key=value,... の形式で返す。条件がなければ "*"
+func labelSelectorText(selector *api.RoleLabelSelector) string {
+	if selector == nil || len(selector.MatchLabels) == 0 {
+		return "*"
+	}
+	pairs := make([]string, 0, len(selector.MatchLabels))
+	for k, v := range selector.MatchLabels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ロールの説明と権限を表示する
+func printRoleDetail(w io.Writer, role api.Role) {
+	_, _ = fmt.Fprintf(w, "Name:        %s\n", role.Metadata.Name)
+	_, _ = fmt.Fprintf(w, "Type:        %s\n", roleTypeText(role))
+	_, _ = fmt.Fprintf(w, "Description: %s\n", util.OrDefault(role.Spec.Description, ""))
+	_, _ = fmt.Fprintln(w, "Permissions:")
+	_, _ = fmt.Fprintf(w, "  %-24s  %-28s  %s\n", "RESOURCE", "VERBS", "LABEL-SELECTOR")
+	for _, perm := range role.Spec.Permissions {
+		_, _ = fmt.Fprintf(w, "  %-24s  %-28s  %s\n", perm.Resource, strings.Join(perm.Verbs, ","), labelSelectorText(perm.LabelSelector))
+	}
+}
+
+func init() {
+	roleCmd.AddCommand(roleDetailCmd)
+}
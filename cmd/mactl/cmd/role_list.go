@@ -12,7 +12,7 @@ import (
 var roleListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all available roles",
-	Long:  `Display all built-in roles defined in marmotd.`,
+	Long:  `Display the built-in roles and the custom roles defined in marmotd.`,
 	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		m, err := getClientConfig()
@@ -39,14 +39,14 @@ var roleListCmd = &cobra.Command{
 			return nil
 		}
 
-		fmt.Printf("%-32s  %s\n", "ROLE-NAME", "DESCRIPTION")
+		fmt.Printf("%-32s  %-8s  %s\n", "ROLE-NAME", "TYPE", "DESCRIPTION")
 		for _, r := range roles {
 			name := strings.TrimSpace(r.Metadata.Name)
 			desc := ""
 			if r.Spec.Description != nil {
 				desc = strings.TrimSpace(*r.Spec.Description)
 			}
-			fmt.Printf("%-32s  %s\n", name, desc)
+			fmt.Printf("%-32s  %-8s  %s\n", name, roleTypeText(r), desc)
 		}
 		return nil
 	},
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestReadRoleFileAcceptsYAML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "role.yaml")
+	content := `apiVersion: v1
+kind: Role
+metadata:
+  name: dev-operator
+spec:
+  description: Operate servers labeled env=dev
+  permissions:
+    - resource: Server
+      verbs: [read]
+    - resource: Server
+      verbs: [delete]
+      labelSelector:
+        matchLabels:
+          env: dev
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	role, err := readRoleFile(path)
+	if err != nil {
+		t.Fatalf("readRoleFile() error = %v", err)
+	}
+	if role.Metadata.Name != "dev-operator" || len(role.Spec.Permissions) != 2 {
+		t.Fatalf("role = %+v, want dev-operator with 2 permissions", role)
+	}
+	if selector := role.Spec.Permissions[1].LabelSelector; selector == nil || selector.MatchLabels["env"] != "dev" {
+		t.Fatalf("labelSelector = %+v, want env=dev", selector)
+	}
+}
+
+func TestReadRoleFileRejectsOtherKinds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	if err := os.WriteFile(path, []byte("kind: Server\nmetadata:\n  name: web\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readRoleFile(path); err == nil || !strings.Contains(err.Error(), "kind must be Role") {
+		t.Fatalf("readRoleFile() error = %v, want kind error", err)
+	}
+}
+
+func TestPrintRoleDetail(t *testing.T) {
+	var buf bytes.Buffer
+	printRoleDetail(&buf, api.Role{
+		Metadata: api.Metadata{Name: "dev-operator"},
+		Spec: api.RoleSpec{
+			Description: util.StringPtr("dev servers"),
+			Permissions: []api.Permission{
+				{Resource: "Server", Verbs: []string{"read"}},
+				{Resource: "Server", Verbs: []string{"update", "delete"}, LabelSelector: &api.RoleLabelSelector{MatchLabels: map[string]string{"team": "a", "env": "dev"}}},
+			},
+		},
+		Status: &api.RoleStatus{Builtin: false},
+	})
+
+	out := buf.String()
+	for _, want := range []string{"Type:        custom", "update,delete", "env=dev,team=a"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
@@ -14,6 +14,7 @@ var outputStyle string
 var watchMode bool
 var watchInterval int
 var labelSelector string
+var projectFlag string
 
 //var m *client.MarmotEndpoint
 
@@ -72,4 +73,5 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&outputStyle, "output", "o", "text", "Text style output")
 	rootCmd.PersistentFlags().BoolVarP(&watchMode, "watch", "w", false, "変化があった時に表示を更新する")
 	rootCmd.PersistentFlags().IntVar(&watchInterval, "watch-interval", 2, "Watchモードの更新間隔（秒）")
+	rootCmd.PersistentFlags().StringVar(&projectFlag, "project", "", "対象のプロジェクト (default is the project in $HOME/.marmot)")
 }
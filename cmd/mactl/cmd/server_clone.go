@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+)
+
+var (
+	cloneMode       string   // ボリュームの複製方法
+	cloneSnapshotId string   // 複製元とするサーバースナップショット
+	cloneComment    string   // 複製先サーバーのコメント
+	cloneLabels     []string // 複製先サーバーのラベル (KEY=VALUE)
+	cloneNoLabels   bool     // 複製元のラベルを引き継がない
+)
+
+var serverCloneCmd = &cobra.Command{
+	Use:   "clone server-id new-server-name",
+	Short: "Clone a server into a new server",
+	Long: `Clone a server into a new server and start it.
+
+The boot volume and the data volumes are cloned with the volume clone of
+each backend. The new server gets new MAC and IP addresses, uses its name
+as the hostname and gets a new machine-id and cloud-init instance-id.
+
+Full copies of qcow2 and LVM volumes need the source server to be stopped.
+Use --snapshot to clone a running server from a server snapshot, or
+--mode cow for copy-on-write clones of LVM data and Ceph volumes.
+
+The labels and cloud-init settings of the source server are copied, so the
+new server joins the load balancers whose backendSelector matches them.
+Use --label to replace the labels or --no-labels to create it without labels.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		req := api.ServerCloneRequest{Name: args[1]}
+		if mode := strings.TrimSpace(cloneMode); mode != "" {
+			req.Mode = &mode
+		}
+		if snapshotId := strings.TrimSpace(cloneSnapshotId); snapshotId != "" {
+			req.SnapshotId = &snapshotId
+		}
+		if comment := strings.TrimSpace(cloneComment); comment != "" {
+			req.Comment = &comment
+		}
+		if req.Labels, err = serverCloneLabels(cloneLabels, cloneNoLabels); err != nil {
+			return err
+		}
+		byteBody, _, err := m.CloneServer(args[0], req)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "サーバーの複製に失敗しました。", err)
+			return err
+		}
+
+		switch outputStyle {
+		case "text":
+			var resp api.Success
+			if err := json.Unmarshal(byteBody, &resp); err != nil {
+				fmt.Println("Failed to Unmarshal", err)
+				return err
+			}
+			fmt.Println("サーバーの複製を受け付けました。ID:", resp.Id)
+			return nil
+		default:
+			return printResponseBody(byteBody)
+		}
+	},
+}
+
+// serverCloneLabels は --label と --no-labels から複製先のラベルを返す。どちらも無ければ複製元を引き継ぐ
+func serverCloneLabels(pairs []string, noLabels bool) (*map[string]string, error) {
+	if noLabels && len(pairs) > 0 {
+		return nil, fmt.Errorf("--label and --no-labels cannot be used together")
+	}
+	if noLabels {
+		return &map[string]string{}, nil
+	}
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q: must be KEY=VALUE", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return &labels, nil
+}
+
+func init() {
+	serverCmd.AddCommand(serverCloneCmd)
+	serverCloneCmd.Flags().StringVar(&cloneMode, "mode", "", "Volume clone mode, full or cow (default full)")
+	serverCloneCmd.Flags().StringVar(&cloneSnapshotId, "snapshot", "", "Clone the volumes from this server snapshot")
+	serverCloneCmd.Flags().StringVar(&cloneComment, "comment", "", "Comment of the new server")
+	serverCloneCmd.Flags().StringArrayVar(&cloneLabels, "label", nil, "Label of the new server as KEY=VALUE, replacing the labels of the source (repeatable)")
+	serverCloneCmd.Flags().BoolVar(&cloneNoLabels, "no-labels", false, "Create the new server without the labels of the source")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import "testing"
+
+func TestServerCloneLabels(t *testing.T) {
+	labels, err := serverCloneLabels(nil, false)
+	if err != nil || labels != nil {
+		t.Fatalf("serverCloneLabels() = %v, %v, want nil to copy the labels of the source", labels, err)
+	}
+
+	labels, err = serverCloneLabels([]string{"role=canary", " tier = web "}, false)
+	if err != nil {
+		t.Fatalf("serverCloneLabels() error = %v", err)
+	}
+	if len(*labels) != 2 || (*labels)["role"] != "canary" || (*labels)["tier"] != "web" {
+		t.Fatalf("labels = %v, want role=canary and tier=web", *labels)
+	}
+
+	labels, err = serverCloneLabels(nil, true)
+	if err != nil || labels == nil || len(*labels) != 0 {
+		t.Fatalf("serverCloneLabels() with --no-labels = %v, %v, want empty labels", labels, err)
+	}
+
+	for _, pairs := range [][]string{{"role"}, {"=web"}} {
+		if _, err := serverCloneLabels(pairs, false); err == nil {
+			t.Fatalf("serverCloneLabels(%q) error = nil, want error", pairs)
+		}
+	}
+	if _, err := serverCloneLabels([]string{"role=web"}, true); err == nil {
+		t.Fatal("serverCloneLabels() error = nil, want error for --label with --no-labels")
+	}
+}
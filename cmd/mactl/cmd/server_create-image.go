@@ -36,6 +36,9 @@ var serverCreateImageCmd = &cobra.Command{
 				return err
 			}
 			fmt.Println("サーバーのイメージ作成が受け付けられました。ID:", data.(map[string]interface{})["id"])
+			if jobId := extractResponseJobID(byteBody); jobId != "" {
+				fmt.Printf("進捗は mactl job detail %s で確認できます。\n", jobId)
+			}
 			return nil
 
 		case "json":
@@ -105,8 +105,16 @@ func printServerDetails(server api.Server) {
 	fmt.Printf("  OS Level:      %s\n", stringValue(&server.Spec, func(s *api.ServerSpec) *string { return s.OsLv }))
 	fmt.Printf("  CPU:           %s\n", intValue(&server.Spec, func(s *api.ServerSpec) *int { return s.Cpu }, " vCPU"))
 	fmt.Printf("  Memory:        %s\n", intValue(&server.Spec, func(s *api.ServerSpec) *int { return s.Memory }, " MB"))
+	fmt.Printf("  Firmware:      %s\n", stringValue(&server.Spec, func(s *api.ServerSpec) *string { return s.Firmware }))
+	fmt.Printf("  TPM:           %s\n", boolValue(server.Spec.Tpm))
 	fmt.Println()
 
+	if server.Spec.Performance != nil {
+		fmt.Println("Performance")
+		printServerPerformance(server.Spec.Performance, server.Status)
+		fmt.Println()
+	}
+
 	fmt.Println("Boot Volume")
 	printVolumeSummary(&server.Spec, func(s *api.ServerSpec) *api.Volume { return s.BootVolume })
 	fmt.Println()
@@ -119,6 +127,37 @@ func printServerDetails(server api.Server) {
 	printNetworkInterfaces(&server.Spec, func(s *api.ServerSpec) *[]api.NetworkInterface { return s.NetworkInterface })
 }
 
+// 専有 CPU、NUMA、hugepages の指定と、ノードで割り当てられた物理 CPU を表示する
+func printServerPerformance(perf *api.ServerPerformance, status *api.Status) {
+	fmt.Printf("  Dedicated CPU: %s\n", boolValue(perf.DedicatedCpu))
+	fmt.Printf("  NUMA:          %s\n", boolValue(perf.Numa))
+	fmt.Printf("  Hugepages:     %s\n", stringValue(perf, func(p *api.ServerPerformance) *string { return p.Hugepages }))
+	fmt.Printf("  Isolate Emu:   %s\n", boolValue(perf.IsolateEmulatorThreads))
+	var assigned *api.ServerPerformanceStatus
+	if status != nil {
+		assigned = status.Performance
+	}
+	fmt.Printf("  vCPU Pins:     %s\n", cpuListValue(assigned, func(p *api.ServerPerformanceStatus) *[]int { return p.VcpuPins }))
+	fmt.Printf("  Emulator CPUs: %s\n", cpuListValue(assigned, func(p *api.ServerPerformanceStatus) *[]int { return p.EmulatorCpus }))
+	fmt.Printf("  NUMA Node:     %s\n", intValue(assigned, func(p *api.ServerPerformanceStatus) *int { return p.NumaNode }, ""))
+}
+
+func cpuListValue[T any](obj *T, selector func(*T) *[]int) string {
+	if obj == nil {
+		return "N/A"
+	}
+	value := selector(obj)
+	if value == nil || len(*value) == 0 {
+		return "N/A"
+	}
+	// vCPU の順に並べるため、範囲にまとめずに表示する
+	cpus := make([]string, len(*value))
+	for i, c := range *value {
+		cpus[i] = fmt.Sprintf("%d", c)
+	}
+	return strings.Join(cpus, ",")
+}
+
 func formatID(id string) string {
 	if id == "" {
 		return "N/A"
@@ -17,7 +17,7 @@ var serverListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all servers",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runList(func() error {
+		return runWatchList("/server", watchEventMatcher("", false), func() error {
 			m, err := getClientConfig()
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
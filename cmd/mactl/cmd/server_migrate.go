@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+)
+
+var (
+	migrateTargetNode string // 移行先ノード名
+	migrateShowStatus bool   // マイグレーションの状態を表示する
+)
+
+var serverMigrateCmd = &cobra.Command{
+	Use:   "migrate [server-id]",
+	Short: "Live-migrate a running server to another node",
+	Long: `Live-migrate a running server to another node.
+
+When --target is omitted the scheduler picks a node with enough free CPU,
+memory and storage. Local qcow2 and LVM volumes are copied to the target
+node while the server keeps running; iSCSI and Ceph volumes are reattached.
+
+Use --status to show the state of the latest migration of the server.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		var byteBody []byte
+		if migrateShowStatus {
+			byteBody, _, err = m.GetServerMigration(args[0])
+			if err != nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "マイグレーションの取得に失敗しました。", err)
+				return err
+			}
+		} else {
+			var req api.ServerMigration
+			if target := strings.TrimSpace(migrateTargetNode); target != "" {
+				req.Spec.TargetNode = &target
+			}
+			byteBody, _, err = m.MigrateServer(args[0], req)
+			if err != nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "マイグレーションの開始に失敗しました。", err)
+				return err
+			}
+		}
+
+		switch outputStyle {
+		case "text":
+			var mig api.ServerMigration
+			if err := json.Unmarshal(byteBody, &mig); err != nil {
+				fmt.Println("Failed to Unmarshal", err)
+				return err
+			}
+			target, status := "", ""
+			if mig.Spec.TargetNode != nil {
+				target = *mig.Spec.TargetNode
+			}
+			if mig.Status != nil && mig.Status.Status != nil {
+				status = *mig.Status.Status
+			}
+			if !migrateShowStatus {
+				fmt.Println("マイグレーションを受け付けました。ID:", mig.Metadata.Id, "移行先:", target)
+				return nil
+			}
+			fmt.Println("ID:", mig.Metadata.Id, "移行先:", target, "状態:", status)
+			if mig.Status != nil && mig.Status.Message != nil && *mig.Status.Message != "" {
+				fmt.Println("メッセージ:", *mig.Status.Message)
+			}
+			return nil
+		default:
+			return printResponseBody(byteBody)
+		}
+	},
+}
+
+func init() {
+	serverCmd.AddCommand(serverMigrateCmd)
+	serverMigrateCmd.Flags().StringVar(&migrateTargetNode, "target", "", "Target node name (chosen by the scheduler when omitted)")
+	serverMigrateCmd.Flags().BoolVar(&migrateShowStatus, "status", false, "Show the latest migration of the server")
+}
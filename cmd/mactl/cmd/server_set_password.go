@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"golang.org/x/term"
+)
+
+var (
+	setPasswordUser     string // パスワードを設定するゲストのユーザー
+	setPasswordPassword string // 新しいパスワード。未指定の場合は入力を求める
+)
+
+var serverSetPasswordCmd = &cobra.Command{
+	Use:   "set-password server-id",
+	Short: "Set the password of a user in a running server",
+	Long: `Set the password of a user in a running server through the QEMU guest agent.
+
+The guest must run qemu-guest-agent. The password is sent to marmotd, which
+keeps only its SHA-512 crypt hash until the server controller applies it.
+When --password is omitted, the password is read from the terminal.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		user := strings.TrimSpace(setPasswordUser)
+		if user == "" {
+			return fmt.Errorf("--user is required")
+		}
+		password := setPasswordPassword
+		if password == "" {
+			fmt.Print("New password: ")
+			pwd, err := term.ReadPassword(int(os.Stdin.Fd()))
+			if err != nil {
+				return fmt.Errorf("failed to read password: %w", err)
+			}
+			fmt.Println()
+			fmt.Print("Confirm password: ")
+			confirm, err := term.ReadPassword(int(os.Stdin.Fd()))
+			if err != nil {
+				return fmt.Errorf("failed to read password: %w", err)
+			}
+			fmt.Println()
+			if string(pwd) != string(confirm) {
+				return fmt.Errorf("passwords do not match")
+			}
+			password = string(pwd)
+		}
+		if password == "" {
+			return fmt.Errorf("password cannot be empty")
+		}
+
+		byteBody, _, err := m.SetServerPassword(args[0], api.ServerPasswordRequest{User: user, Password: password})
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "パスワードの設定に失敗しました。", err)
+			return err
+		}
+
+		switch outputStyle {
+		case "text":
+			var resp api.Success
+			if err := json.Unmarshal(byteBody, &resp); err != nil {
+				fmt.Println("Failed to Unmarshal", err)
+				return err
+			}
+			fmt.Println("パスワードの設定を受け付けました。ID:", resp.Id)
+			return nil
+		default:
+			return printResponseBody(byteBody)
+		}
+	},
+}
+
+func init() {
+	serverCmd.AddCommand(serverSetPasswordCmd)
+	serverSetPasswordCmd.Flags().StringVarP(&setPasswordUser, "user", "u", "", "User in the guest whose password is set")
+	serverSetPasswordCmd.Flags().StringVar(&setPasswordPassword, "password", "", "New password (read from the terminal when omitted)")
+}
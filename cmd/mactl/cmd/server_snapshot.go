@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"go.yaml.in/yaml/v3"
+)
+
+var (
+	snapshotConsistency string // スナップショット取得時のドメインの扱い
+	snapshotOsOnly      bool   // ブートボリュームだけを対象にする
+)
+
+var serverSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Server snapshot management commands",
+}
+
+var serverSnapshotCreateCmd = &cobra.Command{
+	Use:   "create [server-id] [snapshot-name]",
+	Short: "Take a snapshot of a server",
+	Long: `Take a snapshot of the boot volume and data volumes of a server.
+
+--consistency selects how the domain is handled while the snapshot is taken:
+  none     crash-consistent snapshot, the domain keeps running (default)
+  quiesce  the guest filesystems are frozen by the guest agent and the domain is paused
+  stop     the domain is shut down and started again afterwards; the snapshot
+           stays creating until the guest has shut down, and the domain is
+           forced off after server_shutdown_timeout_seconds
+
+qcow2 volumes of a running server can only be snapshotted with --consistency=stop.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		var snap api.ServerSnapshot
+		snap.Metadata.Name = args[1]
+		snap.Spec.Consistency = &snapshotConsistency
+		includeData := !snapshotOsOnly
+		snap.Spec.IncludeDataVolumes = &includeData
+
+		byteBody, _, err := m.CreateServerSnapshot(args[0], snap)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "スナップショットの作成に失敗しました。", err)
+			return err
+		}
+
+		switch outputStyle {
+		case "text":
+			id, err := extractResponseID(byteBody)
+			if err != nil {
+				return err
+			}
+			fmt.Println("スナップショットの作成を受け付けました。ID:", id)
+			return nil
+		default:
+			return printServerSnapshotBody(byteBody)
+		}
+	},
+}
+
+// printServerSnapshotBody は json/yaml 形式で応答を表示する
+func printServerSnapshotBody(byteBody []byte) error {
+	switch outputStyle {
+	case "json":
+		fmt.Println(string(byteBody))
+		return nil
+
+	case "yaml":
+		var data interface{}
+		if err := json.Unmarshal(byteBody, &data); err != nil {
+			fmt.Println("Failed to Unmarshal", err)
+			return err
+		}
+		yamlBytes, err := yaml.Marshal(data)
+		if err != nil {
+			fmt.Println("Failed to Marshal", err)
+			return err
+		}
+		fmt.Println(string(yamlBytes))
+		return nil
+
+	default:
+		fmt.Println("output style must set text/json/yaml")
+		return fmt.Errorf("output style must set text/json/yaml")
+	}
+}
+
+func init() {
+	serverCmd.AddCommand(serverSnapshotCmd)
+	serverSnapshotCmd.AddCommand(serverSnapshotCreateCmd)
+	serverSnapshotCreateCmd.Flags().StringVar(&snapshotConsistency, "consistency", "none", "Domain handling while taking the snapshot: none, quiesce or stop")
+	serverSnapshotCreateCmd.Flags().BoolVar(&snapshotOsOnly, "os-only", false, "Snapshot only the boot volume")
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var serverSnapshotDeleteCmd = &cobra.Command{
+	Use:   "delete [server-id] [snapshot-id...]",
+	Short: "Delete one or more snapshots of a server",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		for _, snapshotId := range args[1:] {
+			byteBody, _, err := m.DeleteServerSnapshotById(args[0], snapshotId)
+			if err != nil {
+				_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "スナップショットの削除に失敗しました。", snapshotId, err)
+				continue
+			}
+			if outputStyle == "text" {
+				fmt.Println("スナップショットの削除を受け付けました。ID:", snapshotId)
+				continue
+			}
+			if err := printServerSnapshotBody(byteBody); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	serverSnapshotCmd.AddCommand(serverSnapshotDeleteCmd)
+}
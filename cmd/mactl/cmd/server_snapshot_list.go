@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+)
+
+var serverSnapshotListCmd = &cobra.Command{
+	Use:   "list [server-id]",
+	Short: "List snapshots of a server",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runList(func() error {
+			m, err := getClientConfig()
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+				os.Exit(1)
+			}
+
+			byteBody, _, err := m.GetServerSnapshots(args[0])
+			if err != nil {
+				println("エラー応答が返されました。", "err", err)
+				return nil
+			}
+
+			if outputStyle != "text" {
+				return printServerSnapshotBody(byteBody)
+			}
+
+			var data []api.ServerSnapshot
+			if err := json.Unmarshal(byteBody, &data); err != nil {
+				println("Failed to Unmarshal", err)
+				return err
+			}
+			if len(data) == 0 {
+				fmt.Println("スナップショットが見つかりません。")
+				return nil
+			}
+			sort.Slice(data, func(i, j int) bool {
+				return creationTime(data[i].Status).Before(creationTime(data[j].Status))
+			})
+
+			fmt.Printf("  %2s  %-8s  %-16s  %-10s  %-11s  %-7s  %-25s  %s\n", "No", "SNAP-ID", "SNAPSHOT-NAME", "STATUS", "CONSISTENCY", "VOLUMES", "CREATED-AT", "MESSAGE")
+			for i, snap := range data {
+				status := "N/A"
+				message := ""
+				if snap.Status != nil {
+					status = db.SnapshotStatus[snap.Status.StatusCode]
+					if snap.Status.Message != nil {
+						message = *snap.Status.Message
+					}
+				}
+				volumes := 0
+				if snap.Spec.Volumes != nil {
+					volumes = len(*snap.Spec.Volumes)
+				}
+				fmt.Printf("  %2d  %-8s  %-16s  %-10s  %-11s  %-7d  %-25s  %s\n",
+					i+1,
+					formatID(snap.Metadata.Id),
+					snap.Metadata.Name,
+					status,
+					stringValue(&snap.Spec, func(s *api.ServerSnapshotSpec) *string { return s.Consistency }),
+					volumes,
+					timeValue(snap.Status, func(s *api.Status) *time.Time { return s.CreationTimeStamp }),
+					message,
+				)
+			}
+			return nil
+		})
+	},
+}
+
+func init() {
+	serverSnapshotCmd.AddCommand(serverSnapshotListCmd)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var serverSnapshotRevertCmd = &cobra.Command{
+	Use:   "revert [server-id] [snapshot-id]",
+	Short: "Roll a server back to a snapshot",
+	Long: `Roll the volumes of a server back to a snapshot.
+The server is shut down during the revert and started again if it was running.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.RevertServerSnapshotById(args[0], args[1])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ロールバックに失敗しました。", err)
+			return err
+		}
+
+		if outputStyle == "text" {
+			fmt.Println("スナップショットへのロールバックを受け付けました。ID:", args[1])
+			return nil
+		}
+		return printServerSnapshotBody(byteBody)
+	},
+}
+
+func init() {
+	serverSnapshotCmd.AddCommand(serverSnapshotRevertCmd)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var serverAttachVolumeCmd = &cobra.Command{
+	Use:   "attach-volume [server-id] [volume-id]",
+	Short: "Attach a data volume to a server",
+	Long: `Attach a data volume to a server.
+The volume is hot plugged into a running server and added to the definition of a stopped server.
+A volume can be attached to only one server at a time.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.AttachServerVolume(args[0], args[1])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ボリュームのアタッチに失敗しました。", err)
+			return err
+		}
+
+		if outputStyle == "text" {
+			fmt.Println("ボリュームのアタッチを受け付けました。ID:", args[1])
+			return nil
+		}
+		return printResponseBody(byteBody)
+	},
+}
+
+var serverDetachVolumeCmd = &cobra.Command{
+	Use:   "detach-volume [server-id] [volume-id]",
+	Short: "Detach a data volume from a server",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.DetachServerVolume(args[0], args[1])
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ボリュームのデタッチに失敗しました。", err)
+			return err
+		}
+
+		if outputStyle == "text" {
+			fmt.Println("ボリュームのデタッチを受け付けました。ID:", args[1])
+			return nil
+		}
+		return printResponseBody(byteBody)
+	},
+}
+
+func init() {
+	serverCmd.AddCommand(serverAttachVolumeCmd)
+	serverCmd.AddCommand(serverDetachVolumeCmd)
+}
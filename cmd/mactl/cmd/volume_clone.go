@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+)
+
+var (
+	volumeCloneName     string // 複製先のボリューム名
+	volumeCloneSnapshot string // 複製元のサーバースナップショットID
+	volumeCloneMode     string // full または cow
+	volumeCloneSize     int    // 複製先のサイズ(GB)
+)
+
+var volumeCloneCmd = &cobra.Command{
+	Use:   "clone [source volume id]",
+	Short: "Create a volume as a clone of another volume",
+	Long: `Create a volume as a clone of another volume.
+With --snapshot the volume is cloned as it was when the server snapshot was taken.
+--mode full (default) copies all data. --mode cow creates a copy-on-write clone
+that shares unchanged data with the source (LVM data and Ceph volumes only).
+Clones of qcow2 and LVM volumes are created on the node of the source volume.
+The copy runs as a job; follow it with "mactl job detail" and stop it with "mactl job cancel".`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volume, err := volumeCloneSpec(args[0], volumeCloneName, volumeCloneSnapshot, volumeCloneMode, volumeCloneSize)
+		if err != nil {
+			return err
+		}
+
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.CreateVolume(volume)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ボリュームの複製に失敗しました。", err)
+			return err
+		}
+
+		if outputStyle == "text" {
+			var created api.Volume
+			if err := json.Unmarshal(byteBody, &created); err != nil {
+				return err
+			}
+			jobID := ""
+			if created.Status != nil && created.Status.JobId != nil {
+				jobID = *created.Status.JobId
+			}
+			fmt.Printf("ボリュームの複製を受け付けました。ID: %s ジョブID: %s\n", api.VolumeID(created), jobID)
+			return nil
+		}
+		return printResponseBody(byteBody)
+	},
+}
+
+// volumeCloneSpec はフラグから複製するボリュームの定義を作る
+// タイプと種別は marmotd が複製元に合わせる
+func volumeCloneSpec(sourceID, name, snapshotID, mode string, size int) (api.Volume, error) {
+	var volume api.Volume
+	if strings.TrimSpace(sourceID) == "" {
+		return volume, errors.New("source volume id is required")
+	}
+	if size < 0 {
+		return volume, errors.New("--size must be a positive number of GB")
+	}
+	source := api.VolumeSource{VolumeId: strings.TrimSpace(sourceID)}
+	if snapshotID != "" {
+		source.SnapshotId = &snapshotID
+	}
+	switch mode {
+	case "":
+	case "full", "cow":
+		source.Mode = &mode
+	default:
+		return volume, fmt.Errorf("--mode must be full or cow: %s", mode)
+	}
+	volume.Metadata.Name = name
+	volume.Spec.Source = &source
+	if size > 0 {
+		volume.Spec.Size = &size
+	}
+	return volume, nil
+}
+
+func init() {
+	volumeCmd.AddCommand(volumeCloneCmd)
+	volumeCloneCmd.Flags().StringVarP(&volumeCloneName, "name", "n", "", "Name of the new volume")
+	volumeCloneCmd.Flags().StringVarP(&volumeCloneSnapshot, "snapshot", "s", "", "Server snapshot id to clone the volume from")
+	volumeCloneCmd.Flags().StringVarP(&volumeCloneMode, "mode", "m", "", "Clone mode (full, cow)")
+	volumeCloneCmd.Flags().IntVar(&volumeCloneSize, "size", 0, "Size of the new volume in GB (defaults to the source size)")
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import "testing"
+
+func TestVolumeCloneSpec(t *testing.T) {
+	volume, err := volumeCloneSpec("11111", "db-test", "", "", 0)
+	if err != nil {
+		t.Fatalf("volumeCloneSpec() error = %v", err)
+	}
+	if volume.Spec.Source == nil || volume.Spec.Source.VolumeId != "11111" {
+		t.Fatalf("spec.source = %+v, want volume 11111", volume.Spec.Source)
+	}
+	if volume.Spec.Source.Mode != nil || volume.Spec.Source.SnapshotId != nil || volume.Spec.Size != nil {
+		t.Fatalf("volume = %+v, want mode, snapshot and size left to marmotd", volume)
+	}
+	if volume.Spec.Type != nil || volume.Spec.Kind != nil {
+		t.Fatalf("spec.type and spec.kind must follow the source: %+v", volume.Spec)
+	}
+	if volume.Metadata.Name != "db-test" {
+		t.Fatalf("metadata.name = %q, want db-test", volume.Metadata.Name)
+	}
+
+	volume, err = volumeCloneSpec("11111", "", "22222", "cow", 40)
+	if err != nil {
+		t.Fatalf("volumeCloneSpec() error = %v", err)
+	}
+	if *volume.Spec.Source.SnapshotId != "22222" || *volume.Spec.Source.Mode != "cow" || *volume.Spec.Size != 40 {
+		t.Fatalf("volume = %+v, want snapshot 22222, cow and 40GB", volume.Spec)
+	}
+
+	if _, err := volumeCloneSpec("", "", "", "", 0); err == nil {
+		t.Fatal("volumeCloneSpec() error = nil, want error without a source")
+	}
+	if _, err := volumeCloneSpec("11111", "", "", "linked", 0); err == nil {
+		t.Fatal("volumeCloneSpec() error = nil, want error for an unknown mode")
+	}
+	if _, err := volumeCloneSpec("11111", "", "", "", -1); err == nil {
+		t.Fatal("volumeCloneSpec() error = nil, want error for a negative size")
+	}
+}
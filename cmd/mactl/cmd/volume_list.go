@@ -16,7 +16,7 @@ var volumeListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all volumes",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runList(func() error {
+		return runWatchList("/volume", watchEventMatcher("", false), func() error {
 			m, err := getClientConfig()
 			if err != nil {
 				fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/takara9/marmot/api"
+)
+
+var (
+	volumeUpdateName string // 新しいボリューム名
+	volumeUpdateSize int    // 拡張後のサイズ(GB)
+)
+
+var volumeUpdateCmd = &cobra.Command{
+	Use:   "update [volume id]",
+	Short: "Update a volume",
+	Long: `Update the name or size of a volume.
+Raising --size expands a qcow2, LVM data or Ceph volume. A running server that
+uses the volume sees the new size without a reboot. Volumes cannot be shrunk.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		volume, err := volumeUpdateSpec(volumeUpdateName, volumeUpdateSize)
+		if err != nil {
+			return err
+		}
+
+		m, err := getClientConfig()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Failed to get API client config:", err)
+			os.Exit(1)
+		}
+
+		byteBody, _, err := m.UpdateVolumeById(args[0], volume)
+		if err != nil {
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(), "ボリュームの更新に失敗しました。", err)
+			return err
+		}
+
+		if outputStyle == "text" {
+			if volume.Spec.Size != nil {
+				fmt.Printf("ボリュームの拡張を受け付けました。ID: %s サイズ: %dGB\n", args[0], *volume.Spec.Size)
+			} else {
+				fmt.Println("ボリュームを更新しました。ID:", args[0])
+			}
+			return nil
+		}
+		return printResponseBody(byteBody)
+	},
+}
+
+// volumeUpdateSpec はフラグから更新するボリュームの定義を作る
+func volumeUpdateSpec(name string, size int) (api.Volume, error) {
+	var volume api.Volume
+	if name == "" && size == 0 {
+		return volume, errors.New("--name or --size is required")
+	}
+	if size < 0 {
+		return volume, errors.New("--size must be a positive number of GB")
+	}
+	volume.Metadata.Name = name
+	if size > 0 {
+		volume.Spec.Size = &size
+	}
+	return volume, nil
+}
+
+func init() {
+	volumeCmd.AddCommand(volumeUpdateCmd)
+	volumeUpdateCmd.Flags().StringVarP(&volumeUpdateName, "name", "n", "", "New name of the volume")
+	volumeUpdateCmd.Flags().IntVarP(&volumeUpdateSize, "size", "s", 0, "New size of the volume in GB")
+}
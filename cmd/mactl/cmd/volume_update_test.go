@@ -0,0 +1,31 @@
+package cmd
+
+import "testing"
+
+func TestVolumeUpdateSpec(t *testing.T) {
+	volume, err := volumeUpdateSpec("", 40)
+	if err != nil {
+		t.Fatalf("volumeUpdateSpec() error = %v", err)
+	}
+	if volume.Spec.Size == nil || *volume.Spec.Size != 40 {
+		t.Fatalf("spec.size = %v, want 40", volume.Spec.Size)
+	}
+	if volume.Metadata.Name != "" {
+		t.Fatalf("metadata.name = %q, want empty", volume.Metadata.Name)
+	}
+
+	volume, err = volumeUpdateSpec("data01", 0)
+	if err != nil {
+		t.Fatalf("volumeUpdateSpec() error = %v", err)
+	}
+	if volume.Spec.Size != nil || volume.Metadata.Name != "data01" {
+		t.Fatalf("volume = %+v, want only the name", volume)
+	}
+
+	if _, err := volumeUpdateSpec("", 0); err == nil {
+		t.Fatal("volumeUpdateSpec() error = nil, want error without flags")
+	}
+	if _, err := volumeUpdateSpec("", -1); err == nil {
+		t.Fatal("volumeUpdateSpec() error = nil, want error for a negative size")
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/client"
+	"go.yaml.in/yaml/v3"
+)
+
+// 続けて届いた変更をまとめて表示し直すための待ち時間
+const watchRedrawDelay = 200 * time.Millisecond
+
+// runWatchList はリスト表示関数 fn を実行する。-w フラグが指定されている場合は
+// API の watch で変更を待ち、変更があるたびに表示を更新する。Ctrl+C で終了する。
+// text 出力では一覧を表示し直し、json/yaml 出力では match に一致する変更を1件ずつ出力する。
+// サーバーが watch に対応していない場合は runList と同じく一定間隔で取得し直す。
+func runWatchList(path string, match func(api.WatchEvent) bool, fn func() error) error {
+	if !watchMode {
+		return fn()
+	}
+
+	m, err := getClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get client config: %w", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if outputStyle != "text" {
+		err := m.WatchResources(ctx, path, "", func(ev api.WatchEvent) error {
+			if !match(ev) {
+				return nil
+			}
+			return printWatchEvent(os.Stdout, ev)
+		})
+		if errors.Is(err, client.ErrWatchNotSupported) {
+			stop()
+			return runList(fn)
+		}
+		return err
+	}
+
+	changed := make(chan struct{}, 1)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- m.WatchResources(ctx, path, "", func(api.WatchEvent) error {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+			return nil
+		})
+	}()
+
+	clearScreen()
+	if err := fn(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watchErr:
+			if errors.Is(err, client.ErrWatchNotSupported) {
+				stop()
+				return runList(fn)
+			}
+			return err
+		case <-changed:
+			time.Sleep(watchRedrawDelay)
+			select {
+			case <-changed:
+			default:
+			}
+			clearScreen()
+			if err := fn(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// printWatchEvent は変更のイベントを json は1行、yaml は1ドキュメントで出力する
+func printWatchEvent(w io.Writer, ev api.WatchEvent) error {
+	switch outputStyle {
+	case "json":
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "yaml":
+		data, err := yaml.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("failed to marshal watch event to YAML: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", data)
+		return err
+	default:
+		return fmt.Errorf("output style must be text/json/yaml")
+	}
+}
+
+// watchEventMatcher は NAME、ラベルセレクター、managedBy の有無でイベントを絞り込む
+func watchEventMatcher(name string, hideManaged bool) func(api.WatchEvent) bool {
+	return func(ev api.WatchEvent) bool {
+		var obj struct {
+			Metadata api.Metadata `json:"metadata"`
+		}
+		if err := client.DecodeWatchObject(ev, &obj); err != nil {
+			return false
+		}
+		if name != "" && obj.Metadata.Name != name {
+			return false
+		}
+		if hideManaged && hasManagedByLabel(obj.Metadata.Labels) {
+			return false
+		}
+		return labelSelector == "" || MatchesLabel(convertLabels(obj.Metadata.Labels), labelSelector)
+	}
+}
+
+// volumeWatchEventMatcher は watchEventMatcher に加えて、-a がない場合は data kind 以外のボリュームを除く
+func volumeWatchEventMatcher(name string, showAll bool) func(api.WatchEvent) bool {
+	match := watchEventMatcher(name, false)
+	return func(ev api.WatchEvent) bool {
+		if !match(ev) {
+			return false
+		}
+		if showAll {
+			return true
+		}
+		var vol api.Volume
+		if err := client.DecodeWatchObject(ev, &vol); err != nil {
+			return false
+		}
+		return len(filterDataKindVolumes([]api.Volume{vol}, false)) == 1
+	}
+}
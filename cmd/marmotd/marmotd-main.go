@@ -72,6 +72,19 @@ func main() {
 			slog.Warn("Failed to flush logger on shutdown", "err", err)
 		}
 	}()
+	auditShutdown, err := marmotd.SetupAuditLog(cfg)
+	if err != nil {
+		slog.Warn("Failed to initialize audit log sinks; audit records are stored only in etcd", "err", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if err := auditShutdown(ctx); err != nil {
+			slog.Warn("Failed to close audit log sinks on shutdown", "err", err)
+		}
+	}()
+
+	marmotd.SetupOIDC(cfg)
 
 	if err := marmotd.EnsureGatewayRuntimeAssets(); err != nil {
 		slog.Error("Failed to initialize gateway runtime assets", "err", err)
@@ -92,6 +105,11 @@ func main() {
 		"image_download_timeout_seconds", cfg.ImageDownloadTimeoutSeconds,
 		"image_resize_timeout_seconds", cfg.ImageResizeTimeoutSeconds,
 		"image_delete_timeout_seconds", cfg.ImageDeleteTimeoutSeconds,
+		"server_shutdown_timeout_seconds", cfg.ServerShutdownTimeoutSeconds,
+		"scheduler_cpu_overcommit_ratio", cfg.SchedulerCPUOvercommitRatio,
+		"scheduler_memory_overcommit_ratio", cfg.SchedulerMemoryOvercommitRatio,
+		"migration_uri_template", cfg.MigrationURITemplate,
+		"node_labels", cfg.NodeLabels,
 		"loki_push_url", cfg.LokiPushURL)
 
 	// Setup host-bridge for libvirt
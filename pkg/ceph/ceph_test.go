@@ -139,6 +139,36 @@ var _ = Describe("Ceph", func() {
 			Expect(runner.commands).To(ContainElement(expectedRBDCommand("create", "marmot-ssd/vol-abcde", "--size", "20G")))
 		})
 
+		It("issues an rbd resize command", func() {
+			err := client.ResizeVolume(context.Background(), "marmot-ssd", "vol-abcde", 40)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runner.commands).To(ContainElement(expectedRBDCommand("resize", "marmot-ssd/vol-abcde", "--size", "40G")))
+		})
+
+		It("issues rbd snapshot and clone commands", func() {
+			dest := ceph.VolumeRequest{Pool: "marmot-hdd", Image: "vol-fghij", SizeGB: 40}
+			err := client.CloneVolume(context.Background(), "marmot-ssd", "vol-abcde", "clone-fghij", dest)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runner.commands).To(Equal([]string{
+				expectedRBDCommand("snap", "create", "marmot-ssd/vol-abcde@clone-fghij"),
+				expectedRBDCommand("snap", "protect", "marmot-ssd/vol-abcde@clone-fghij"),
+				expectedRBDCommand("clone", "marmot-ssd/vol-abcde@clone-fghij", "marmot-hdd/vol-fghij"),
+			}))
+		})
+
+		It("flattens a clone and removes the protected snapshot", func() {
+			Expect(client.FlattenVolume(context.Background(), "marmot-hdd", "vol-fghij")).To(Succeed())
+			Expect(client.RemoveSnapshot(context.Background(), "marmot-ssd", "vol-abcde", "clone-fghij")).To(Succeed())
+
+			Expect(runner.commands).To(Equal([]string{
+				expectedRBDCommand("flatten", "marmot-hdd/vol-fghij"),
+				expectedRBDCommand("snap", "unprotect", "marmot-ssd/vol-abcde@clone-fghij"),
+				expectedRBDCommand("snap", "rm", "marmot-ssd/vol-abcde@clone-fghij"),
+			}))
+		})
+
 		It("parses rbd info JSON", func() {
 			command := expectedRBDCommand("info", "marmot-ssd/vol-abcde", "--format", "json")
 			runner.outputs[command] = []byte(`{"name":"vol-abcde","size":21474836480,"pool":"marmot-ssd"}`)
@@ -177,10 +207,12 @@ var _ = Describe("Ceph", func() {
 			fake := &ceph.FakeClient{}
 
 			Expect(fake.CreateVolume(context.Background(), ceph.VolumeRequest{Pool: "marmot-ssd", Image: "vol-abcde", SizeGB: 1})).To(Succeed())
+			Expect(fake.ResizeVolume(context.Background(), "marmot-ssd", "vol-abcde", 2)).To(Succeed())
 			_, err := fake.StatVolume(context.Background(), "marmot-ssd", "vol-abcde")
 			Expect(err).NotTo(HaveOccurred())
 
 			Expect(fake.Created).To(HaveLen(1))
+			Expect(fake.Resized).To(Equal([]string{"marmot-ssd/vol-abcde:2G"}))
 			Expect(fake.Stated).To(Equal([]string{"marmot-ssd/vol-abcde"}))
 		})
 	})
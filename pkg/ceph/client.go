@@ -28,6 +28,10 @@ var defaultPoolByClass = map[string]string{
 type VolumeClient interface {
 	CreateVolume(ctx context.Context, req VolumeRequest) error
 	DeleteVolume(ctx context.Context, pool, image string) error
+	ResizeVolume(ctx context.Context, pool, image string, sizeGB int) error
+	CloneVolume(ctx context.Context, pool, image, snapshot string, dest VolumeRequest) error
+	FlattenVolume(ctx context.Context, pool, image string) error
+	RemoveSnapshot(ctx context.Context, pool, image, snapshot string) error
 	StatVolume(ctx context.Context, pool, image string) (VolumeInfo, error)
 	ListVolumes(ctx context.Context, pool string) ([]string, error)
 }
@@ -1,17 +1,28 @@
 package ceph
 
-import "context"
+import (
+	"context"
+	"fmt"
+)
 
 type FakeClient struct {
-	CreateVolumeFunc func(ctx context.Context, req VolumeRequest) error
-	DeleteVolumeFunc func(ctx context.Context, pool, image string) error
-	StatVolumeFunc   func(ctx context.Context, pool, image string) (VolumeInfo, error)
-	ListVolumesFunc  func(ctx context.Context, pool string) ([]string, error)
+	CreateVolumeFunc   func(ctx context.Context, req VolumeRequest) error
+	DeleteVolumeFunc   func(ctx context.Context, pool, image string) error
+	ResizeVolumeFunc   func(ctx context.Context, pool, image string, sizeGB int) error
+	CloneVolumeFunc    func(ctx context.Context, pool, image, snapshot string, dest VolumeRequest) error
+	FlattenVolumeFunc  func(ctx context.Context, pool, image string) error
+	RemoveSnapshotFunc func(ctx context.Context, pool, image, snapshot string) error
+	StatVolumeFunc     func(ctx context.Context, pool, image string) (VolumeInfo, error)
+	ListVolumesFunc    func(ctx context.Context, pool string) ([]string, error)
 
-	Created []VolumeRequest
-	Deleted []string
-	Listed  []string
-	Stated  []string
+	Created          []VolumeRequest
+	Deleted          []string
+	Resized          []string
+	Cloned           []string
+	Flattened        []string
+	RemovedSnapshots []string
+	Listed           []string
+	Stated           []string
 }
 
 func (f *FakeClient) CreateVolume(ctx context.Context, req VolumeRequest) error {
@@ -30,6 +41,38 @@ func (f *FakeClient) DeleteVolume(ctx context.Context, pool, image string) error
 	return nil
 }
 
+func (f *FakeClient) ResizeVolume(ctx context.Context, pool, image string, sizeGB int) error {
+	f.Resized = append(f.Resized, fmt.Sprintf("%s/%s:%dG", pool, image, sizeGB))
+	if f.ResizeVolumeFunc != nil {
+		return f.ResizeVolumeFunc(ctx, pool, image, sizeGB)
+	}
+	return nil
+}
+
+func (f *FakeClient) CloneVolume(ctx context.Context, pool, image, snapshot string, dest VolumeRequest) error {
+	f.Cloned = append(f.Cloned, fmt.Sprintf("%s/%s@%s:%s", pool, image, snapshot, dest.ProviderVolumeID()))
+	if f.CloneVolumeFunc != nil {
+		return f.CloneVolumeFunc(ctx, pool, image, snapshot, dest)
+	}
+	return nil
+}
+
+func (f *FakeClient) FlattenVolume(ctx context.Context, pool, image string) error {
+	f.Flattened = append(f.Flattened, pool+"/"+image)
+	if f.FlattenVolumeFunc != nil {
+		return f.FlattenVolumeFunc(ctx, pool, image)
+	}
+	return nil
+}
+
+func (f *FakeClient) RemoveSnapshot(ctx context.Context, pool, image, snapshot string) error {
+	f.RemovedSnapshots = append(f.RemovedSnapshots, fmt.Sprintf("%s/%s@%s", pool, image, snapshot))
+	if f.RemoveSnapshotFunc != nil {
+		return f.RemoveSnapshotFunc(ctx, pool, image, snapshot)
+	}
+	return nil
+}
+
 func (f *FakeClient) StatVolume(ctx context.Context, pool, image string) (VolumeInfo, error) {
 	f.Stated = append(f.Stated, pool+"/"+image)
 	if f.StatVolumeFunc != nil {
@@ -60,6 +60,75 @@ func (c *Client) DeleteVolume(ctx context.Context, pool, image string) error {
 	return err
 }
 
+// ResizeVolume はイメージを sizeGB へ拡張する。縮小は rbd 側で拒否される
+func (c *Client) ResizeVolume(ctx context.Context, pool, image string, sizeGB int) error {
+	if strings.TrimSpace(pool) == "" {
+		return fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(image) == "" {
+		return fmt.Errorf("image is required")
+	}
+	if sizeGB < 1 {
+		return fmt.Errorf("size must be at least 1GB")
+	}
+	_, err := c.runCommand(ctx, "rbd", "resize", fmt.Sprintf("%s/%s", strings.TrimSpace(pool), strings.TrimSpace(image)), "--size", fmt.Sprintf("%dG", sizeGB))
+	return err
+}
+
+// CloneVolume はイメージのスナップショットを作成して保護し、コピーオンライトのクローンを dest に作成する
+// クローンが残っている間はスナップショットを削除できない
+func (c *Client) CloneVolume(ctx context.Context, pool, image, snapshot string, dest VolumeRequest) error {
+	if strings.TrimSpace(pool) == "" || strings.TrimSpace(dest.Pool) == "" {
+		return fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(image) == "" || strings.TrimSpace(dest.Image) == "" {
+		return fmt.Errorf("image is required")
+	}
+	if strings.TrimSpace(snapshot) == "" {
+		return fmt.Errorf("snapshot is required")
+	}
+	snap := fmt.Sprintf("%s/%s@%s", strings.TrimSpace(pool), strings.TrimSpace(image), strings.TrimSpace(snapshot))
+	if _, err := c.runCommand(ctx, "rbd", "snap", "create", snap); err != nil {
+		return err
+	}
+	if _, err := c.runCommand(ctx, "rbd", "snap", "protect", snap); err != nil {
+		return err
+	}
+	_, err := c.runCommand(ctx, "rbd", "clone", snap, dest.ProviderVolumeID())
+	return err
+}
+
+// FlattenVolume はクローンに親イメージのデータをコピーして、親との関係を切り離す
+func (c *Client) FlattenVolume(ctx context.Context, pool, image string) error {
+	if strings.TrimSpace(pool) == "" {
+		return fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(image) == "" {
+		return fmt.Errorf("image is required")
+	}
+	_, err := c.runCommand(ctx, "rbd", "flatten", fmt.Sprintf("%s/%s", strings.TrimSpace(pool), strings.TrimSpace(image)))
+	return err
+}
+
+// RemoveSnapshot は CloneVolume で作成したスナップショットの保護を解除して削除する
+func (c *Client) RemoveSnapshot(ctx context.Context, pool, image, snapshot string) error {
+	if strings.TrimSpace(pool) == "" {
+		return fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(image) == "" {
+		return fmt.Errorf("image is required")
+	}
+	if strings.TrimSpace(snapshot) == "" {
+		return fmt.Errorf("snapshot is required")
+	}
+	snap := fmt.Sprintf("%s/%s@%s", strings.TrimSpace(pool), strings.TrimSpace(image), strings.TrimSpace(snapshot))
+	if _, err := c.runCommand(ctx, "rbd", "snap", "unprotect", snap); err != nil {
+		return err
+	}
+	_, err := c.runCommand(ctx, "rbd", "snap", "rm", snap)
+	return err
+}
+
 func (c *Client) StatVolume(ctx context.Context, pool, image string) (VolumeInfo, error) {
 	if strings.TrimSpace(pool) == "" {
 		return VolumeInfo{}, fmt.Errorf("pool is required")
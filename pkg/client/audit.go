@@ -0,0 +1,50 @@
+package client
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/takara9/marmot/api"
+)
+
+// 監査記録の検索
+func (m *MarmotEndpoint) GetAuditRecords(params api.ApiGetAuditRecordsParams) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/audit")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := url.Values{}
+	setQuery := func(key string, value *string) {
+		if value != nil && *value != "" {
+			query.Set(key, *value)
+		}
+	}
+	setQuery("userId", params.UserId)
+	setQuery("operationId", params.OperationId)
+	setQuery("resource", params.Resource)
+	setQuery("resourceId", params.ResourceId)
+	setQuery("result", params.Result)
+	if params.Since != nil {
+		query.Set("since", params.Since.Format(time.RFC3339))
+	}
+	if params.Until != nil {
+		query.Set("until", params.Until.Format(time.RFC3339))
+	}
+	if params.Limit != nil {
+		query.Set("limit", strconv.Itoa(*params.Limit))
+	}
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+	slog.Debug("GetAuditRecords", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestGetAuditRecords(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2026, 10, 2, 9, 30, 0, 0, time.FixedZone("JST", 9*60*60))
+	limit := 20
+	records := `[{"id":"a0001","userId":"alice","operationId":"apiDeleteServerById","result":"success"}]`
+
+	runClientCases(t, []clientCase{
+		{
+			name: "sends only the given filters",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				return withoutURL(ep.GetAuditRecords(api.ApiGetAuditRecordsParams{
+					UserId:      util.StringPtr("alice"),
+					OperationId: util.StringPtr("apiDeleteServerById"),
+					Resource:    util.StringPtr(""),
+					Since:       &since,
+					Limit:       &limit,
+				}))
+			},
+			method:   http.MethodGet,
+			path:     "/api/v1/audit",
+			query:    url.Values{"userId": {"alice"}, "operationId": {"apiDeleteServerById"}, "since": {"2026-10-01T00:00:00Z"}, "limit": {"20"}},
+			respBody: records,
+		},
+		{
+			name: "formats the time range in RFC 3339",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				return withoutURL(ep.GetAuditRecords(api.ApiGetAuditRecordsParams{
+					ResourceId: util.StringPtr("ab12c"),
+					Result:     util.StringPtr("denied"),
+					Until:      &until,
+				}))
+			},
+			method:   http.MethodGet,
+			path:     "/api/v1/audit",
+			query:    url.Values{"resourceId": {"ab12c"}, "result": {"denied"}, "until": {"2026-10-02T09:30:00+09:00"}},
+			respBody: `[]`,
+		},
+		{
+			name: "sends no query without filters",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				return withoutURL(ep.GetAuditRecords(api.ApiGetAuditRecordsParams{}))
+			},
+			method:   http.MethodGet,
+			path:     "/api/v1/audit",
+			query:    url.Values{},
+			respBody: records,
+		},
+		{
+			name: "maps a denied request to its status",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				return withoutURL(ep.GetAuditRecords(api.ApiGetAuditRecordsParams{}))
+			},
+			method:     http.MethodGet,
+			path:       "/api/v1/audit",
+			status:     http.StatusForbidden,
+			respBody:   `{"code":403,"message":"permission denied"}`,
+			wantErr:    "permission denied",
+			wantStatus: http.StatusForbidden,
+		},
+	})
+}
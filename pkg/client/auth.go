@@ -177,6 +177,39 @@ func (m *MarmotEndpoint) GetRoleByName(roleName string) (*api.Role, error) {
 	return decodeJSONBody[api.Role](body)
 }
 
+func (m *MarmotEndpoint) CreateRole(role api.Role) (*api.Role, error) {
+	req, err := m.newJSONRequest(http.MethodPost, role, "roles")
+	if err != nil {
+		return nil, err
+	}
+	_, body, _, _, err := m.doJSONRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONBody[api.Role](body)
+}
+
+func (m *MarmotEndpoint) UpdateRoleByName(roleName string, role api.Role) (*api.Role, error) {
+	req, err := m.newJSONRequest(http.MethodPut, role, "roles", roleName)
+	if err != nil {
+		return nil, err
+	}
+	_, body, _, _, err := m.doJSONRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONBody[api.Role](body)
+}
+
+func (m *MarmotEndpoint) DeleteRoleByName(roleName string) error {
+	req, err := m.newJSONRequest(http.MethodDelete, nil, "roles", roleName)
+	if err != nil {
+		return err
+	}
+	_, _, _, _, err = m.doJSONRequest(req)
+	return err
+}
+
 func (m *MarmotEndpoint) ListUsers() (api.Users, error) {
 	req, err := m.newJSONRequest(http.MethodGet, nil, "users")
 	if err != nil {
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/takara9/marmot/api"
+)
+
+// 証明書の一覧取得
+func (m *MarmotEndpoint) GetCertificates() ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/certificate")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetCertificates", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", m.withProject(reqURL), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// 証明書の登録
+func (m *MarmotEndpoint) CreateCertificate(cert api.Certificate) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/certificate")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("CreateCertificate", "reqURL", reqURL)
+
+	meta := api.Metadata{Project: cert.Project}
+	m.assignProject(&meta)
+	cert.Project = meta.Project
+	byteJSON, err := json.Marshal(cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// 証明書の詳細取得
+func (m *MarmotEndpoint) GetCertificateById(id string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/certificate", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetCertificateById", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// 証明書と秘密鍵の置き換え
+func (m *MarmotEndpoint) UpdateCertificateById(id string, cert api.Certificate) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/certificate", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("UpdateCertificateById", "reqURL", reqURL)
+
+	byteJSON, err := json.Marshal(cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("PUT", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// 証明書の削除
+func (m *MarmotEndpoint) DeleteCertificateById(id string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/certificate", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("DeleteCertificateById", "reqURL", reqURL)
+
+	req, err := http.NewRequest("DELETE", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
@@ -0,0 +1,91 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+)
+
+func TestCertificateEndpoints(t *testing.T) {
+	key := "KEY"
+	teamA := "team-a"
+	cert := api.Certificate{Name: "web", Certificate: "CERT", PrivateKey: &key}
+
+	runClientCases(t, []clientCase{
+		{
+			name:     "lists certificates",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetCertificates()) },
+			method:   http.MethodGet,
+			path:     "/api/v1/certificate",
+			respBody: `[{"id":"ab12c","name":"web"}]`,
+		},
+		{
+			name:     "creates a certificate",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.CreateCertificate(cert)) },
+			method:   http.MethodPost,
+			path:     "/api/v1/certificate",
+			wantReq:  cert,
+			status:   http.StatusCreated,
+			respBody: `{"id":"ab12c","name":"web","certificate":"CERT"}`,
+		},
+		{
+			name: "lists certificates in the default project",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				ep.Project = "team-a"
+				return withoutURL(ep.GetCertificates())
+			},
+			method:   http.MethodGet,
+			path:     "/api/v1/certificate",
+			query:    url.Values{"project": {"team-a"}},
+			respBody: `[{"id":"ab12c","name":"web","project":"team-a"}]`,
+		},
+		{
+			name: "registers a certificate in the default project",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				ep.Project = "team-a"
+				return withoutURL(ep.CreateCertificate(cert))
+			},
+			method:   http.MethodPost,
+			path:     "/api/v1/certificate",
+			wantReq:  api.Certificate{Name: "web", Certificate: "CERT", PrivateKey: &key, Project: &teamA},
+			status:   http.StatusCreated,
+			respBody: `{"id":"ab12c","name":"web","project":"team-a"}`,
+		},
+		{
+			name:     "gets a certificate",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetCertificateById("ab12c")) },
+			method:   http.MethodGet,
+			path:     "/api/v1/certificate/ab12c",
+			respBody: `{"id":"ab12c","name":"web","certificate":"CERT"}`,
+		},
+		{
+			name: "replaces the certificate and key",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				return withoutURL(ep.UpdateCertificateById("ab12c", api.Certificate{Certificate: "CERT", PrivateKey: &key}))
+			},
+			method:   http.MethodPut,
+			path:     "/api/v1/certificate/ab12c",
+			wantReq:  api.Certificate{Certificate: "CERT", PrivateKey: &key},
+			respBody: `{"id":"ab12c","name":"web","certificate":"CERT"}`,
+		},
+		{
+			name:     "deletes a certificate",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.DeleteCertificateById("ab12c")) },
+			method:   http.MethodDelete,
+			path:     "/api/v1/certificate/ab12c",
+			respBody: `{"id":"ab12c","name":"web"}`,
+		},
+		{
+			name:       "maps a certificate in use to a conflict",
+			call:       func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.DeleteCertificateById("ab12c")) },
+			method:     http.MethodDelete,
+			path:       "/api/v1/certificate/ab12c",
+			status:     http.StatusConflict,
+			respBody:   `{"code":1,"message":"certificate is in use: used by application load balancer lb-web"}`,
+			wantErr:    "certificate is in use: used by application load balancer lb-web",
+			wantStatus: http.StatusConflict,
+		},
+	})
+}
@@ -3,12 +3,16 @@ package client
 import (
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
 )
 
 type apiErrorBody struct {
@@ -16,6 +20,23 @@ type apiErrorBody struct {
 	Message string `json:"message"`
 }
 
+// StatusError は API がエラーのステータスコードを返した場合のエラー
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
+// IsConflict は更新が他のリクエストと競合した (409 Conflict) エラーかどうかを返す
+// metadata.resourceVersion が古い場合に返される
+func IsConflict(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusConflict
+}
+
 type MarmotClient struct {
 	NodeName string
 }
@@ -32,9 +53,28 @@ type MarmotEndpoint struct {
 	BasePath              string // Base path for the API, e.g., "/api/v1".
 	InsecureSkipTLSVerify bool
 	AccessToken           string
+	Project               string       // Default project for list and create requests. Empty means all visible projects.
 	Client                *http.Client // Specialized client.
 }
 
+// withProject は既定のプロジェクトを一覧の要求の project パラメーターに加える
+func (m *MarmotEndpoint) withProject(reqURL string) string {
+	project := strings.TrimSpace(m.Project)
+	if project == "" {
+		return reqURL
+	}
+	return reqURL + "?" + url.Values{"project": []string{project}}.Encode()
+}
+
+// assignProject は作成するリソースに既定のプロジェクトを設定する。指定済みのプロジェクトは変更しない
+func (m *MarmotEndpoint) assignProject(meta *api.Metadata) {
+	project := strings.TrimSpace(m.Project)
+	if project == "" || strings.TrimSpace(util.OrDefault(meta.Project, "")) != "" {
+		return
+	}
+	meta.Project = &project
+}
+
 func NewMarmotdEp(schame string, address string, basePath string, timeout int, insecureSkipTLSVerify bool) (*MarmotEndpoint, error) {
 	tr := http.DefaultTransport.(*http.Transport).Clone()
 	tr.DisableCompression = true
@@ -110,13 +150,13 @@ func (m *MarmotEndpoint) httpRequest2(req *http.Request) ([]byte, *url.URL, erro
 		var apiErr apiErrorBody
 		if err := json.Unmarshal(byteJSON, &apiErr); err == nil {
 			if strings.TrimSpace(apiErr.Message) != "" {
-				return nil, nil, fmt.Errorf("%s", strings.TrimSpace(apiErr.Message))
+				return nil, nil, &StatusError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(apiErr.Message)}
 			}
 		}
 		if len(msg) > 0 {
-			return nil, nil, fmt.Errorf("%s", msg)
+			return nil, nil, &StatusError{StatusCode: resp.StatusCode, Message: msg}
 		}
-		return nil, nil, fmt.Errorf("http status code = %d", resp.StatusCode)
+		return nil, nil, &StatusError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("http status code = %d", resp.StatusCode)}
 	}
 
 	jobURL, err := resp.Location()
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestUpdateSendsResourceVersionAndMapsErrors(t *testing.T) {
+	gateway := api.Gateway{Metadata: api.Metadata{Name: "gw1", ResourceVersion: util.StringPtr("42")}}
+	update := func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.UpdateGatewayById("ab12c", gateway)) }
+
+	runClientCases(t, []clientCase{
+		{
+			name:     "sends metadata.resourceVersion",
+			call:     update,
+			method:   http.MethodPut,
+			path:     "/api/v1/gateway/ab12c",
+			wantReq:  gateway,
+			respBody: `{"metadata":{"id":"ab12c","name":"gw1","resourceVersion":"43"}}`,
+		},
+		{
+			name:       "maps a stale resourceVersion to a conflict",
+			call:       update,
+			method:     http.MethodPut,
+			path:       "/api/v1/gateway/ab12c",
+			wantReq:    gateway,
+			status:     http.StatusConflict,
+			respBody:   `{"code":1,"message":"the resource has been modified by another request"}`,
+			wantErr:    "the resource has been modified by another request",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "keeps plain-text error bodies",
+			call:       update,
+			method:     http.MethodPut,
+			path:       "/api/v1/gateway/ab12c",
+			wantReq:    gateway,
+			status:     http.StatusBadRequest,
+			respBody:   "invalid gateway\n",
+			wantErr:    "invalid gateway",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "reports the status code of empty error bodies",
+			call:       update,
+			method:     http.MethodPut,
+			path:       "/api/v1/gateway/ab12c",
+			wantReq:    gateway,
+			status:     http.StatusInternalServerError,
+			wantErr:    "http status code = 500",
+			wantStatus: http.StatusInternalServerError,
+		},
+	})
+}
+
+func TestIsConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "conflict", err: &StatusError{StatusCode: http.StatusConflict, Message: "conflict"}, want: true},
+		{name: "wrapped conflict", err: fmt.Errorf("update: %w", &StatusError{StatusCode: http.StatusConflict}), want: true},
+		{name: "other status", err: &StatusError{StatusCode: http.StatusInternalServerError}, want: false},
+		{name: "plain error", err: errors.New("plain error"), want: false},
+		{name: "nil", err: nil, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsConflict(tt.err); got != tt.want {
+				t.Fatalf("IsConflict(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/takara9/marmot/api"
+)
+
+// 内部DNSのカスタムレコードの一覧取得
+func (m *MarmotEndpoint) GetDnsRecords() ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/dns-record")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetDnsRecords", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// 内部DNSのカスタムレコードの登録
+func (m *MarmotEndpoint) CreateDnsRecord(record api.DnsRecord) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/dns-record")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("CreateDnsRecord", "reqURL", reqURL)
+
+	byteJSON, err := json.Marshal(record)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// 内部DNSのカスタムレコードの詳細取得
+func (m *MarmotEndpoint) GetDnsRecordById(id string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/dns-record", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetDnsRecordById", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// 内部DNSのカスタムレコードの削除
+func (m *MarmotEndpoint) DeleteDnsRecordById(id string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/dns-record", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("DeleteDnsRecordById", "reqURL", reqURL)
+
+	req, err := http.NewRequest("DELETE", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+)
+
+func TestDnsRecordEndpoints(t *testing.T) {
+	record := api.DnsRecord{Name: "www.net1", Type: "CNAME", Value: "vm1.net1"}
+
+	runClientCases(t, []clientCase{
+		{
+			name:     "lists records",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetDnsRecords()) },
+			method:   http.MethodGet,
+			path:     "/api/v1/dns-record",
+			respBody: `[{"id":"ab12c","name":"www.net1","type":"CNAME","value":"vm1.net1"}]`,
+		},
+		{
+			name:     "creates a record",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.CreateDnsRecord(record)) },
+			method:   http.MethodPost,
+			path:     "/api/v1/dns-record",
+			wantReq:  record,
+			status:   http.StatusCreated,
+			respBody: `{"id":"ab12c","name":"www.net1","type":"CNAME","value":"vm1.net1"}`,
+		},
+		{
+			name: "maps validation errors to their message",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				return withoutURL(ep.CreateDnsRecord(api.DnsRecord{Name: "www.net1", Type: "MX"}))
+			},
+			method:     http.MethodPost,
+			path:       "/api/v1/dns-record",
+			wantReq:    api.DnsRecord{Name: "www.net1", Type: "MX"},
+			status:     http.StatusBadRequest,
+			respBody:   `{"code":1,"message":"unsupported record type: MX"}`,
+			wantErr:    "unsupported record type: MX",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "gets a record",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetDnsRecordById("ab12c")) },
+			method:   http.MethodGet,
+			path:     "/api/v1/dns-record/ab12c",
+			respBody: `{"id":"ab12c","name":"www.net1","type":"CNAME","value":"vm1.net1"}`,
+		},
+		{
+			name:       "maps a missing record to not found",
+			call:       func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.DeleteDnsRecordById("ab12c")) },
+			method:     http.MethodDelete,
+			path:       "/api/v1/dns-record/ab12c",
+			status:     http.StatusNotFound,
+			respBody:   `{"code":1,"message":"IDが存在しません"}`,
+			wantErr:    "IDが存在しません",
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+// newTestEndpoint は handler で応答するテスト用のサーバーに接続するエンドポイントを返す
+func newTestEndpoint(t *testing.T, handler http.HandlerFunc) *MarmotEndpoint {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	parsedURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parse server url: %v", err)
+	}
+	return &MarmotEndpoint{
+		Scheme:   parsedURL.Scheme,
+		HostPort: parsedURL.Host,
+		BasePath: "/api/v1",
+		Client:   server.Client(),
+	}
+}
+
+// clientCase はクライアントの API 呼び出し1回分のテストケース
+// テスト用のサーバーは受け取ったリクエストを確認し、status と respBody で応答する
+type clientCase struct {
+	name string
+	call func(ep *MarmotEndpoint) ([]byte, error)
+
+	// 期待するリクエスト
+	method  string
+	path    string
+	query   url.Values  // nil ならクエリを確認しない
+	header  http.Header // 指定したヘッダーだけを確認する
+	wantReq any         // JSON として比較するリクエストボディ。nil ならボディが空であること
+
+	// テスト用のサーバーの応答
+	status      int    // 0 なら 200
+	contentType string // 空なら application/json
+	respBody    string
+
+	// 期待する結果
+	want       string // call の戻り値。空なら respBody
+	wantErr    string // エラーのメッセージ。空ならエラーにならないこと
+	wantStatus int    // 0 でなければ、エラーがこのステータスコードの StatusError であること
+}
+
+// runClientCases は cases をサブテストとして実行する
+func runClientCases(t *testing.T, cases []clientCase) {
+	t.Helper()
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ep := newTestEndpoint(t, func(w http.ResponseWriter, r *http.Request) {
+				if !tc.checkRequest(t, r) {
+					http.Error(w, "unexpected request", http.StatusTeapot)
+					return
+				}
+				contentType := tc.contentType
+				if contentType == "" {
+					contentType = "application/json"
+				}
+				w.Header().Set("Content-Type", contentType)
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+				}
+				_, _ = io.WriteString(w, tc.respBody)
+			})
+
+			got, err := tc.call(ep)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				var statusErr *StatusError
+				if tc.wantStatus != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tc.wantStatus) {
+					t.Fatalf("error = %#v, want a StatusError with status %d", err, tc.wantStatus)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := tc.want
+			if want == "" {
+				want = tc.respBody
+			}
+			if string(got) != want {
+				t.Fatalf("result = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// checkRequest は r が期待するリクエストかどうかを確認する。ハンドラーから呼ぶため Fatal は使わない
+func (tc clientCase) checkRequest(t *testing.T, r *http.Request) bool {
+	t.Helper()
+
+	ok := true
+	if r.Method != tc.method || r.URL.Path != tc.path {
+		t.Errorf("request = %s %s, want %s %s", r.Method, r.URL.Path, tc.method, tc.path)
+		ok = false
+	}
+	if tc.query != nil && !reflect.DeepEqual(r.URL.Query(), tc.query) {
+		t.Errorf("query = %v, want %v", r.URL.Query(), tc.query)
+		ok = false
+	}
+	for name, values := range tc.header {
+		if got := r.Header.Values(name); !reflect.DeepEqual(got, values) {
+			t.Errorf("header %s = %q, want %q", name, got, values)
+			ok = false
+		}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Errorf("read request body: %v", err)
+		return false
+	}
+	if tc.wantReq == nil {
+		if len(body) != 0 {
+			t.Errorf("request body = %s, want empty", body)
+			ok = false
+		}
+		return ok
+	}
+	var got, want any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Errorf("decode request body %s: %v", body, err)
+		return false
+	}
+	wantJSON, err := json.Marshal(tc.wantReq)
+	if err != nil {
+		t.Errorf("encode wantReq: %v", err)
+		return false
+	}
+	if err := json.Unmarshal(wantJSON, &want); err != nil {
+		t.Errorf("decode wantReq: %v", err)
+		return false
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("request body = %s, want %s", body, wantJSON)
+		ok = false
+	}
+	return ok
+}
+
+// withoutURL は API 呼び出しの戻り値からジョブの URL を除き、clientCase.call で使える形にする
+func withoutURL(body []byte, _ *url.URL, err error) ([]byte, error) {
+	return body, err
+}
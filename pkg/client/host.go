@@ -1,8 +1,12 @@
 package client
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
 	"net/url"
+
+	"github.com/takara9/marmot/api"
 )
 
 // ホストのステータスを取得
@@ -32,3 +36,36 @@ func (m *MarmotEndpoint) GetMarmotCluster() ([]byte, *url.URL, error) {
 	}
 	return m.httpRequest2(req)
 }
+
+// ノードを cordon してスケジュール対象外にする
+func (m *MarmotEndpoint) CordonNode(nodeName string, spec api.NodeMaintenance) ([]byte, *url.URL, error) {
+	return m.postNodeMaintenance(nodeName, "cordon", spec)
+}
+
+// ノードをスケジュール対象に戻す
+func (m *MarmotEndpoint) UncordonNode(nodeName string) ([]byte, *url.URL, error) {
+	return m.postNodeMaintenance(nodeName, "uncordon", api.NodeMaintenance{})
+}
+
+// ノードをドレインする
+func (m *MarmotEndpoint) DrainNode(nodeName string, spec api.NodeMaintenance) ([]byte, *url.URL, error) {
+	return m.postNodeMaintenance(nodeName, "drain", spec)
+}
+
+func (m *MarmotEndpoint) postNodeMaintenance(nodeName, op string, spec api.NodeMaintenance) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/marmot/node/"+nodeName+"/"+op)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byteJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
@@ -22,6 +22,7 @@ func (m *MarmotEndpoint) CreateImage(spec api.Image) ([]byte, *url.URL, error) {
 	}
 	slog.Debug("CreateImage", "reqURL", reqURL, "spec", spec)
 
+	m.assignProject(&spec.Metadata)
 	byteJSON, _ := json.Marshal(spec)
 	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
 	if err != nil {
@@ -53,7 +54,7 @@ func (m *MarmotEndpoint) GetImages() ([]byte, *url.URL, error) {
 	}
 	slog.Debug("GetImages", "reqURL", reqURL)
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequest("GET", m.withProject(reqURL), nil)
 	if err != nil {
 		return nil, nil, err
 	}
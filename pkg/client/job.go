@@ -0,0 +1,72 @@
+package client
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ジョブの一覧取得
+func (m *MarmotEndpoint) GetJobs() ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/job")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetJobs", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// ジョブの詳細取得
+func (m *MarmotEndpoint) GetJobById(id string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/job", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetJobById", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// ジョブのキャンセル
+func (m *MarmotEndpoint) CancelJobById(id string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/job", id, "cancel")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("CancelJobById", "reqURL", reqURL)
+
+	req, err := http.NewRequest("POST", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// ジョブログの取得、lines が正の値の場合は末尾の lines 行だけを取得する
+func (m *MarmotEndpoint) GetJobLogById(id string, lines int) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/job", id, "log")
+	if err != nil {
+		return nil, nil, err
+	}
+	if lines > 0 {
+		reqURL += "?" + url.Values{"lines": []string{strconv.Itoa(lines)}}.Encode()
+	}
+	slog.Debug("GetJobLogById", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", "text/plain")
+	return m.httpRequest2(req)
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestJobEndpoints(t *testing.T) {
+	runClientCases(t, []clientCase{
+		{
+			name:     "lists jobs",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetJobs()) },
+			method:   http.MethodGet,
+			path:     "/api/v1/job",
+			respBody: `[{"id":"ab12c","jobId":"ab12c"}]`,
+		},
+		{
+			name:     "gets a job",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetJobById("ab12c")) },
+			method:   http.MethodGet,
+			path:     "/api/v1/job/ab12c",
+			respBody: `{"id":"ab12c","jobId":"ab12c"}`,
+		},
+		{
+			name:     "cancels a job",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.CancelJobById("ab12c")) },
+			method:   http.MethodPost,
+			path:     "/api/v1/job/ab12c/cancel",
+			respBody: `{"id":"ab12c","jobId":"ab12c"}`,
+		},
+		{
+			name:     "maps cancel errors to their message",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.CancelJobById("ab12c")) },
+			method:   http.MethodPost,
+			path:     "/api/v1/job/ab12c/cancel",
+			status:   http.StatusConflict,
+			respBody: `{"code":1,"message":"ジョブは終了しています"}`,
+			wantErr:  "ジョブは終了しています",
+		},
+		{
+			name:        "gets the tail of a job log",
+			call:        func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetJobLogById("ab12c", 20)) },
+			method:      http.MethodGet,
+			path:        "/api/v1/job/ab12c/log",
+			query:       url.Values{"lines": {"20"}},
+			header:      http.Header{"Accept": {"text/plain"}},
+			contentType: "text/plain; charset=utf-8",
+			respBody:    "line1\nline2\n",
+		},
+		{
+			name:        "gets the whole job log",
+			call:        func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetJobLogById("ab12c", 0)) },
+			method:      http.MethodGet,
+			path:        "/api/v1/job/ab12c/log",
+			query:       url.Values{},
+			contentType: "text/plain; charset=utf-8",
+			respBody:    "line1\n",
+		},
+		{
+			name:     "maps plain-text errors to the body",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetJobLogById("zz99z", 0)) },
+			method:   http.MethodGet,
+			path:     "/api/v1/job/zz99z/log",
+			status:   http.StatusNotFound,
+			respBody: "job not found\n",
+			wantErr:  "job not found",
+		},
+	})
+}
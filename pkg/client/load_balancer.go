@@ -16,6 +16,7 @@ func (m *MarmotEndpoint) CreateLoadBalancer(spec api.ApplicationLoadBalancer) ([
 	if err != nil {
 		return nil, nil, err
 	}
+	m.assignProject(&spec.Metadata)
 	body, err := json.Marshal(spec)
 	if err != nil {
 		return nil, nil, err
@@ -33,7 +34,7 @@ func (m *MarmotEndpoint) GetLoadBalancers() ([]byte, *url.URL, error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequest("GET", m.withProject(reqURL), nil)
 	if err != nil {
 		return nil, nil, err
 	}
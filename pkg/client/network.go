@@ -19,6 +19,7 @@ func (m *MarmotEndpoint) CreateVirtualNetwork(spec api.VirtualNetwork) ([]byte,
 	}
 	slog.Debug("CreateVirtualNetwork", "reqURL", reqURL)
 
+	m.assignProject(&spec.Metadata)
 	byteJSON, err := json.Marshal(spec)
 	if err != nil {
 		return nil, nil, err
@@ -98,7 +99,7 @@ func (m *MarmotEndpoint) GetVirtualNetworks() ([]byte, *url.URL, error) {
 	}
 	slog.Debug("GetVirtualNetworks", "reqURL", reqURL)
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequest("GET", m.withProject(reqURL), nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,230 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+)
+
+/*
+OIDC の ID プロバイダーから認可コードフローと PKCE で ID トークンを取得する
+
+	ループバックアドレスで認可コードを受け取り、トークンエンドポイントで ID トークンと交換する。
+	取得した ID トークンは AuthOidcLogin で marmotd のログインセッションと交換する
+*/
+
+// oidcCallbackPath は認可コードを受け取るループバックの URL のパス
+const oidcCallbackPath = "/callback"
+
+// AuthOidcConfig は marmotd に設定された ID プロバイダーの情報を取得する
+func (m *MarmotEndpoint) AuthOidcConfig() (*api.AuthOidcConfig, error) {
+	req, err := m.newJSONRequest(http.MethodGet, nil, "auth", "oidc")
+	if err != nil {
+		return nil, err
+	}
+	_, body, _, _, err := m.doJSONRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSONBody[api.AuthOidcConfig](body)
+}
+
+// AuthOidcLogin は ID トークンを marmotd のログインセッションのアクセストークンと交換する
+func (m *MarmotEndpoint) AuthOidcLogin(idToken string) (*api.AuthLoginResponse, error) {
+	req, err := m.newJSONRequest(http.MethodPost, api.AuthOidcLoginRequest{IdToken: strings.TrimSpace(idToken)}, "auth", "oidc", "login")
+	if err != nil {
+		return nil, err
+	}
+	_, body, _, _, err := m.doJSONRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := decodeJSONBody[api.AuthLoginResponse](body)
+	if err != nil {
+		return nil, err
+	}
+	m.SetAccessToken(resp.AccessToken)
+	return resp, nil
+}
+
+// OIDCAuthorize は認可コードフローと PKCE で ID トークンを取得する。
+// openURL には利用者がブラウザで開く認可の URL を渡す。ctx の期限まで認可コードを待つ
+func OIDCAuthorize(ctx context.Context, cfg api.AuthOidcConfig, openURL func(string) error) (string, error) {
+	var discovery struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+	}
+	if err := oidcGetJSON(ctx, strings.TrimRight(cfg.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return "", fmt.Errorf("failed to get OIDC discovery document: %w", err)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" {
+		return "", errors.New("OIDC discovery document has no authorization_endpoint or token_endpoint")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to listen for the OIDC callback: %w", err)
+	}
+	defer listener.Close()
+	redirectURI := "http://" + listener.Addr().String() + oidcCallbackPath
+
+	verifier := oidcRandomString(32)
+	challenge := sha256.Sum256([]byte(verifier))
+	state := oidcRandomString(16)
+	nonce := oidcRandomString(16)
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization_endpoint: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", cfg.ClientId)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("scope", strings.Join(cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+
+	type callbackResult struct {
+		code string
+		err  error
+	}
+	results := make(chan callbackResult, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc(oidcCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		var result callbackResult
+		switch {
+		case params.Get("state") != state:
+			result.err = errors.New("OIDC callback state does not match")
+		case params.Get("error") != "":
+			result.err = fmt.Errorf("OIDC authorization failed: %s %s", params.Get("error"), params.Get("error_description"))
+		case params.Get("code") == "":
+			result.err = errors.New("OIDC callback has no authorization code")
+		default:
+			result.code = params.Get("code")
+		}
+		if result.err != nil {
+			http.Error(w, result.err.Error(), http.StatusBadRequest)
+		} else {
+			_, _ = io.WriteString(w, "Login succeeded. You can close this window and return to mactl.\n")
+		}
+		select {
+		case results <- result:
+		default:
+		}
+	})
+	server := &http.Server{Handler: mux}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	if err := openURL(authURL.String()); err != nil {
+		return "", err
+	}
+
+	var code string
+	select {
+	case result := <-results:
+		if result.err != nil {
+			return "", result.err
+		}
+		code = result.code
+	case <-ctx.Done():
+		return "", fmt.Errorf("timed out waiting for the OIDC login: %w", ctx.Err())
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("client_id", cfg.ClientId)
+	form.Set("code_verifier", verifier)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange the OIDC authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+	var token struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid OIDC token response (http status code = %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to exchange the OIDC authorization code: %s %s", token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("OIDC token response has no id_token")
+	}
+	if got := idTokenNonce(token.IDToken); got != nonce {
+		return "", errors.New("OIDC ID token nonce does not match")
+	}
+	return token.IDToken, nil
+}
+
+func oidcGetJSON(ctx context.Context, reqURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: http status code = %d", reqURL, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// idTokenNonce は ID トークンの nonce を返す。署名は marmotd が検証する
+func idTokenNonce(idToken string) string {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Nonce string `json:"nonce"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.Nonce
+}
+
+func oidcRandomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
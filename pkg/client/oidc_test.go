@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/oidctest"
+)
+
+// browserGet はブラウザの代わりに認可の URL を開き、ループバックへのリダイレクトをたどる
+func browserGet(t *testing.T) func(string) error {
+	return func(authURL string) error {
+		go func() {
+			resp, err := http.Get(authURL)
+			if err != nil {
+				t.Errorf("failed to open authorization URL: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+		return nil
+	}
+}
+
+func TestOIDCAuthorizeWithPKCE(t *testing.T) {
+	idp := oidctest.NewProvider("mactl")
+	defer idp.Close()
+	idp.SetClaims(map[string]interface{}{"preferred_username": "alice", "groups": []string{"developers"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	cfg := api.AuthOidcConfig{Issuer: idp.Issuer, ClientId: "mactl", Scopes: []string{"openid", "profile", "groups"}}
+	idToken, err := OIDCAuthorize(ctx, cfg, browserGet(t))
+	if err != nil {
+		t.Fatalf("OIDCAuthorize failed: %v", err)
+	}
+	if strings.Count(idToken, ".") != 2 || idTokenNonce(idToken) == "" {
+		t.Fatalf("unexpected ID token: %q", idToken)
+	}
+}
+
+func TestOIDCAuthorizeRejectsUnknownClient(t *testing.T) {
+	idp := oidctest.NewProvider("mactl")
+	defer idp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	cfg := api.AuthOidcConfig{Issuer: idp.Issuer, ClientId: "other", Scopes: []string{"openid"}}
+	if _, err := OIDCAuthorize(ctx, cfg, browserGet(t)); err == nil {
+		t.Fatal("OIDCAuthorize succeeded for a client the IdP does not know")
+	}
+}
+
+func TestOIDCEndpoints(t *testing.T) {
+	// accessToken はログインの後にエンドポイントが保持するアクセストークンを返す
+	accessToken := func(ep *MarmotEndpoint) ([]byte, error) {
+		if _, err := ep.AuthOidcLogin(" a.b.c "); err != nil {
+			return nil, err
+		}
+		return []byte(ep.AccessToken), nil
+	}
+
+	runClientCases(t, []clientCase{
+		{
+			name:     "gets the identity provider",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return decoded(ep.AuthOidcConfig()) },
+			method:   http.MethodGet,
+			path:     "/api/v1/auth/oidc",
+			respBody: `{"clientId":"mactl","issuer":"https://idp.example.com","scopes":["openid","profile"]}`,
+		},
+		{
+			name:     "maps a server without OIDC to its message",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return decoded(ep.AuthOidcConfig()) },
+			method:   http.MethodGet,
+			path:     "/api/v1/auth/oidc",
+			status:   http.StatusNotFound,
+			respBody: `{"code":404,"message":"OIDC login is not configured"}`,
+			wantErr:  "OIDC login is not configured",
+		},
+		{
+			name:     "exchanges the ID token and stores the access token",
+			call:     accessToken,
+			method:   http.MethodPost,
+			path:     "/api/v1/auth/oidc/login",
+			wantReq:  api.AuthOidcLoginRequest{IdToken: "a.b.c"},
+			respBody: `{"accessToken":"session-token","tokenType":"Bearer"}`,
+			want:     "session-token",
+		},
+		{
+			name:     "maps a rejected ID token to its message",
+			call:     accessToken,
+			method:   http.MethodPost,
+			path:     "/api/v1/auth/oidc/login",
+			wantReq:  api.AuthOidcLoginRequest{IdToken: "a.b.c"},
+			status:   http.StatusUnauthorized,
+			respBody: `{"code":401,"message":"invalid ID token"}`,
+			wantErr:  "invalid ID token",
+		},
+	})
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/takara9/marmot/api"
+)
+
+// プロジェクトの一覧取得
+func (m *MarmotEndpoint) GetProjects() ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/projects")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetProjects", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// プロジェクトの登録
+func (m *MarmotEndpoint) CreateProject(project api.Project) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/projects")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("CreateProject", "reqURL", reqURL)
+
+	byteJSON, err := json.Marshal(project)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// プロジェクトの詳細取得
+func (m *MarmotEndpoint) GetProjectByName(name string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/projects", name)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetProjectByName", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// プロジェクトの更新
+func (m *MarmotEndpoint) UpdateProjectByName(name string, project api.Project) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/projects", name)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("UpdateProjectByName", "reqURL", reqURL)
+
+	byteJSON, err := json.Marshal(project)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("PUT", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// プロジェクトの削除
+func (m *MarmotEndpoint) DeleteProjectByName(name string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/projects", name)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("DeleteProjectByName", "reqURL", reqURL)
+
+	req, err := http.NewRequest("DELETE", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestProjectEndpoints(t *testing.T) {
+	members := []api.ProjectMember{{UserId: "alice", Roles: []string{"operator"}}}
+	project := api.Project{Metadata: api.Metadata{Name: "team-a"}, Spec: api.ProjectSpec{Members: &members}}
+
+	runClientCases(t, []clientCase{
+		{
+			name:     "lists projects",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetProjects()) },
+			method:   http.MethodGet,
+			path:     "/api/v1/projects",
+			respBody: `[{"metadata":{"id":"team-a","name":"team-a"},"spec":{}}]`,
+		},
+		{
+			name:     "creates a project",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.CreateProject(project)) },
+			method:   http.MethodPost,
+			path:     "/api/v1/projects",
+			wantReq:  project,
+			status:   http.StatusCreated,
+			respBody: `{"metadata":{"id":"team-a","name":"team-a"},"spec":{"members":[]}}`,
+		},
+		{
+			name:     "gets a project",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetProjectByName("team-a")) },
+			method:   http.MethodGet,
+			path:     "/api/v1/projects/team-a",
+			respBody: `{"metadata":{"id":"team-a","name":"team-a"},"spec":{}}`,
+		},
+		{
+			name:     "updates a project",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.UpdateProjectByName("team-a", project)) },
+			method:   http.MethodPut,
+			path:     "/api/v1/projects/team-a",
+			wantReq:  project,
+			respBody: `{"metadata":{"id":"team-a","name":"team-a"},"spec":{"members":[]}}`,
+		},
+		{
+			name:       "maps a project with resources to a conflict",
+			call:       func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.DeleteProjectByName("team-a")) },
+			method:     http.MethodDelete,
+			path:       "/api/v1/projects/team-a",
+			status:     http.StatusConflict,
+			respBody:   `{"code":1,"message":"project \"team-a\" still has 2 resources"}`,
+			wantErr:    `project "team-a" still has 2 resources`,
+			wantStatus: http.StatusConflict,
+		},
+	})
+}
+
+func TestDefaultProject(t *testing.T) {
+	runClientCases(t, []clientCase{
+		{
+			name: "lists resources in the default project",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				ep.Project = "team-a"
+				return withoutURL(ep.GetServers())
+			},
+			method:   http.MethodGet,
+			path:     "/api/v1/server",
+			query:    url.Values{"project": {"team-a"}},
+			respBody: `[]`,
+		},
+		{
+			name:     "lists all visible projects without a default",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetServers()) },
+			method:   http.MethodGet,
+			path:     "/api/v1/server",
+			query:    url.Values{},
+			respBody: `[]`,
+		},
+		{
+			name: "creates resources in the default project",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				ep.Project = "team-a"
+				return withoutURL(ep.CreateVolume(api.Volume{}))
+			},
+			method:   http.MethodPost,
+			path:     "/api/v1/volume",
+			wantReq:  api.Volume{Metadata: api.Metadata{Project: util.StringPtr("team-a")}},
+			status:   http.StatusCreated,
+			respBody: `{"id":"v1"}`,
+		},
+		{
+			name: "keeps an explicit project",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				ep.Project = "team-a"
+				return withoutURL(ep.CreateVolume(api.Volume{Metadata: api.Metadata{Project: util.StringPtr("team-b")}}))
+			},
+			method:   http.MethodPost,
+			path:     "/api/v1/volume",
+			wantReq:  api.Volume{Metadata: api.Metadata{Project: util.StringPtr("team-b")}},
+			status:   http.StatusCreated,
+			respBody: `{"id":"v1"}`,
+		},
+	})
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/takara9/marmot/api"
+)
+
+// クォータの一覧取得
+func (m *MarmotEndpoint) GetQuotas() ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/quota")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetQuotas", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// クォータの登録
+func (m *MarmotEndpoint) CreateQuota(quota api.Quota) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/quota")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("CreateQuota", "reqURL", reqURL)
+
+	byteJSON, err := json.Marshal(quota)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// クォータの詳細取得
+func (m *MarmotEndpoint) GetQuotaById(id string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/quota", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetQuotaById", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// クォータの置き換え
+func (m *MarmotEndpoint) UpdateQuotaById(id string, quota api.Quota) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/quota", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("UpdateQuotaById", "reqURL", reqURL)
+
+	byteJSON, err := json.Marshal(quota)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("PUT", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// クォータの削除
+func (m *MarmotEndpoint) DeleteQuotaById(id string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/quota", id)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("DeleteQuotaById", "reqURL", reqURL)
+
+	req, err := http.NewRequest("DELETE", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// 利用者のリソースの使用量と適用されるクォータの取得
+func (m *MarmotEndpoint) GetUserQuotaUsage(userID string) ([]byte, *url.URL, error) {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/users", userID, "quota")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("GetUserQuotaUsage", "reqURL", reqURL)
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestQuotaEndpoints(t *testing.T) {
+	users := []string{"alice"}
+	quota := api.Quota{Name: "team-a", Users: &users, Limits: api.QuotaResources{Vcpus: util.IntPtrInt(8)}}
+
+	runClientCases(t, []clientCase{
+		{
+			name:     "lists quotas",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetQuotas()) },
+			method:   http.MethodGet,
+			path:     "/api/v1/quota",
+			respBody: `[{"id":"ab12c","name":"team-a","limits":{"vcpus":8}}]`,
+		},
+		{
+			name:     "creates a quota",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.CreateQuota(quota)) },
+			method:   http.MethodPost,
+			path:     "/api/v1/quota",
+			wantReq:  quota,
+			status:   http.StatusCreated,
+			respBody: `{"id":"ab12c","name":"team-a","limits":{"vcpus":8}}`,
+		},
+		{
+			name:     "gets a quota",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetQuotaById("ab12c")) },
+			method:   http.MethodGet,
+			path:     "/api/v1/quota/ab12c",
+			respBody: `{"id":"ab12c","name":"team-a","limits":{"vcpus":8}}`,
+		},
+		{
+			name:     "replaces a quota",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.UpdateQuotaById("ab12c", quota)) },
+			method:   http.MethodPut,
+			path:     "/api/v1/quota/ab12c",
+			wantReq:  quota,
+			respBody: `{"id":"ab12c","name":"team-a","limits":{"vcpus":8}}`,
+		},
+		{
+			name:     "deletes a quota",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.DeleteQuotaById("ab12c")) },
+			method:   http.MethodDelete,
+			path:     "/api/v1/quota/ab12c",
+			respBody: `{"id":"ab12c","message":"quota deleted"}`,
+		},
+		{
+			name:     "gets the usage of a user",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.GetUserQuotaUsage("alice")) },
+			method:   http.MethodGet,
+			path:     "/api/v1/users/alice/quota",
+			respBody: `{"userId":"alice","used":{"servers":1},"quotas":[]}`,
+		},
+		{
+			name:       "maps a duplicated quota to a conflict",
+			call:       func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.CreateQuota(quota)) },
+			method:     http.MethodPost,
+			path:       "/api/v1/quota",
+			wantReq:    quota,
+			status:     http.StatusConflict,
+			respBody:   `{"code":1,"message":"quota \"team-a\" already exists"}`,
+			wantErr:    `quota "team-a" already exists`,
+			wantStatus: http.StatusConflict,
+		},
+	})
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+)
+
+// decoded は API 呼び出しで復号した値を JSON に戻し、clientCase.call で使える形にする
+func decoded[T any](value T, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+func TestRoleEndpoints(t *testing.T) {
+	role := api.Role{
+		Metadata: api.Metadata{Name: "dev-operator"},
+		Spec: api.RoleSpec{Permissions: []api.Permission{
+			{Resource: "Server", Verbs: []string{"delete"}, LabelSelector: &api.RoleLabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+		}},
+	}
+	roleBody := `{"apiVersion":"","kind":"","metadata":{"id":"","name":"dev-operator"},"spec":{"permissions":[{"labelSelector":{"matchLabels":{"env":"dev"}},"resource":"Server","verbs":["delete"]}]},"status":{"builtin":false}}`
+
+	runClientCases(t, []clientCase{
+		{
+			name:     "lists roles",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return decoded(ep.ListRoles()) },
+			method:   http.MethodGet,
+			path:     "/api/v1/roles",
+			respBody: "[" + roleBody + "]",
+		},
+		{
+			name:     "gets a role",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return decoded(ep.GetRoleByName("dev-operator")) },
+			method:   http.MethodGet,
+			path:     "/api/v1/roles/dev-operator",
+			respBody: roleBody,
+		},
+		{
+			name:     "creates a role with label selectors",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return decoded(ep.CreateRole(role)) },
+			method:   http.MethodPost,
+			path:     "/api/v1/roles",
+			wantReq:  role,
+			status:   http.StatusCreated,
+			respBody: roleBody,
+		},
+		{
+			name:     "updates a role",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return decoded(ep.UpdateRoleByName("dev-operator", role)) },
+			method:   http.MethodPut,
+			path:     "/api/v1/roles/dev-operator",
+			wantReq:  role,
+			respBody: roleBody,
+		},
+		{
+			name:   "deletes a role",
+			call:   func(ep *MarmotEndpoint) ([]byte, error) { return nil, ep.DeleteRoleByName("dev-operator") },
+			method: http.MethodDelete,
+			path:   "/api/v1/roles/dev-operator",
+			status: http.StatusNoContent,
+		},
+		{
+			name:     "returns the server message of a role in use",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return nil, ep.DeleteRoleByName("dev-operator") },
+			method:   http.MethodDelete,
+			path:     "/api/v1/roles/dev-operator",
+			status:   http.StatusConflict,
+			respBody: `{"code":409,"message":"role is in use"}`,
+			wantErr:  "role is in use",
+		},
+		{
+			name:     "rejects a response that is not a role",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return decoded(ep.GetRoleByName("dev-operator")) },
+			method:   http.MethodGet,
+			path:     "/api/v1/roles/dev-operator",
+			respBody: `[]`,
+			wantErr:  "json: cannot unmarshal array into Go value of type api.Role",
+		},
+	})
+}
@@ -19,6 +19,7 @@ func (m *MarmotEndpoint) CreateServer(spec api.Server) ([]byte, *url.URL, error)
 	}
 	slog.Debug("CreateServer", "reqURL", reqURL)
 
+	m.assignProject(&spec.Metadata)
 	byteJSON, err := json.Marshal(spec)
 	if err != nil {
 		return nil, nil, err
@@ -98,7 +99,7 @@ func (m *MarmotEndpoint) GetServers() ([]byte, *url.URL, error) {
 	}
 	slog.Debug("GetServers", "reqURL", reqURL)
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequest("GET", m.withProject(reqURL), nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestCloneServer(t *testing.T) {
+	fromSnapshot := api.ServerCloneRequest{
+		Name:       "web2",
+		Comment:    util.StringPtr("copy of web"),
+		Mode:       util.StringPtr("cow"),
+		SnapshotId: util.StringPtr("s0001"),
+	}
+
+	runClientCases(t, []clientCase{
+		{
+			name: "clones a server",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				return withoutURL(ep.CloneServer("ab12c", api.ServerCloneRequest{Name: "web2"}))
+			},
+			method:   http.MethodPost,
+			path:     "/api/v1/server/ab12c/clone",
+			wantReq:  api.ServerCloneRequest{Name: "web2"},
+			status:   http.StatusAccepted,
+			respBody: `{"id":"de34f","message":"Accepted the request to clone the server"}`,
+		},
+		{
+			name:     "clones a server from a snapshot",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.CloneServer("ab12c", fromSnapshot)) },
+			method:   http.MethodPost,
+			path:     "/api/v1/server/ab12c/clone",
+			wantReq:  fromSnapshot,
+			status:   http.StatusAccepted,
+			respBody: `{"id":"de34f","message":"Accepted the request to clone the server"}`,
+		},
+		{
+			name: "maps a source that is not ready to a conflict",
+			call: func(ep *MarmotEndpoint) ([]byte, error) {
+				return withoutURL(ep.CloneServer("ab12c", api.ServerCloneRequest{Name: "web2"}))
+			},
+			method:     http.MethodPost,
+			path:       "/api/v1/server/ab12c/clone",
+			wantReq:    api.ServerCloneRequest{Name: "web2"},
+			status:     http.StatusConflict,
+			respBody:   `{"code":1,"message":"clone source server is not RUNNING or STOPPED"}`,
+			wantErr:    "clone source server is not RUNNING or STOPPED",
+			wantStatus: http.StatusConflict,
+		},
+	})
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+// グラフィカルコンソールのトークン発行
+func (m *MarmotEndpoint) CreateServerConsoleToken(id string) ([]byte, *url.URL, error) {
+	slog.Debug("===", "CreateServerConsoleToken is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/console/token")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("POST", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// GraphicalConsoleURL はトークン発行の応答の path から、hostPort の marmotd の WebSocket の URL を返す
+func (m *MarmotEndpoint) GraphicalConsoleURL(hostPort, path string) string {
+	scheme := "ws"
+	if strings.EqualFold(strings.TrimSpace(m.Scheme), "https") {
+		scheme = "wss"
+	}
+	return scheme + "://" + strings.TrimSpace(hostPort) + path
+}
+
+// DialGraphicalConsole はグラフィカルコンソールの WebSocket に接続する
+// トークンで認証するため Authorization ヘッダーは付けない
+func (m *MarmotEndpoint) DialGraphicalConsole(hostPort, path string) (*websocket.Conn, error) {
+	origin := m.Scheme + "://" + strings.TrimSpace(hostPort)
+	cfg, err := websocket.NewConfig(m.GraphicalConsoleURL(hostPort, path), origin)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Protocol = []string{"binary"}
+	if strings.EqualFold(strings.TrimSpace(m.Scheme), "https") {
+		cfg.TlsConfig = &tls.Config{InsecureSkipVerify: m.InsecureSkipTLSVerify}
+	}
+	ws, err := websocket.DialConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the graphical console: %w", err)
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestCreateServerConsoleToken(t *testing.T) {
+	create := func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.CreateServerConsoleToken("ab12c")) }
+
+	runClientCases(t, []clientCase{
+		{
+			name:     "issues a console token",
+			call:     create,
+			method:   http.MethodPost,
+			path:     "/api/v1/server/ab12c/console/token",
+			status:   http.StatusCreated,
+			respBody: `{"token":"tkn","expiresAt":"2026-01-01T00:00:00Z","protocol":"spice","path":"/api/v1/server/ab12c/console/graphical?token=tkn"}`,
+		},
+		{
+			name:       "maps a stopped server to a conflict",
+			call:       create,
+			method:     http.MethodPost,
+			path:       "/api/v1/server/ab12c/console/token",
+			status:     http.StatusConflict,
+			respBody:   `{"code":1,"message":"server is not RUNNING"}`,
+			wantErr:    "server is not RUNNING",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "maps an unknown server to not found",
+			call:       create,
+			method:     http.MethodPost,
+			path:       "/api/v1/server/ab12c/console/token",
+			status:     http.StatusNotFound,
+			respBody:   `{"code":1,"message":"not found"}`,
+			wantErr:    "not found",
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
+
+func TestDialGraphicalConsoleRelaysBinaryFrames(t *testing.T) {
+	ep := newTestEndpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/api/v1/server/ab12c/console/graphical" || r.URL.Query().Get("token") != "tkn" {
+			t.Errorf("unexpected request: %s", r.URL.String())
+			http.NotFound(w, r)
+			return
+		}
+		websocket.Server{
+			Handshake: func(cfg *websocket.Config, _ *http.Request) error {
+				if len(cfg.Protocol) != 1 || cfg.Protocol[0] != "binary" {
+					return fmt.Errorf("unexpected protocols %v", cfg.Protocol)
+				}
+				return nil
+			},
+			Handler: func(ws *websocket.Conn) {
+				ws.PayloadType = websocket.BinaryFrame
+				_, _ = io.Copy(ws, ws)
+			},
+		}.ServeHTTP(w, r)
+	})
+
+	if got := ep.GraphicalConsoleURL(ep.HostPort, "/p?token=x"); !strings.HasPrefix(got, "ws://"+ep.HostPort+"/p") {
+		t.Fatalf("GraphicalConsoleURL() = %q", got)
+	}
+	ws, err := ep.DialGraphicalConsole(ep.HostPort, "/api/v1/server/ab12c/console/graphical?token="+url.QueryEscape("tkn"))
+	if err != nil {
+		t.Fatalf("DialGraphicalConsole failed: %v", err)
+	}
+	defer ws.Close()
+
+	if _, err := ws.Write([]byte{0x52, 0x46, 0x42}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(ws, buf); err != nil || string(buf) != "RFB" {
+		t.Fatalf("read = %q, %v", buf, err)
+	}
+}
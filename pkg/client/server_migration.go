@@ -0,0 +1,46 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/takara9/marmot/api"
+)
+
+// サーバーのライブマイグレーション
+func (m *MarmotEndpoint) MigrateServer(id string, spec api.ServerMigration) ([]byte, *url.URL, error) {
+	slog.Debug("===", "MigrateServer is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/migrate")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byteJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// サーバーの最新のマイグレーション
+func (m *MarmotEndpoint) GetServerMigration(id string) ([]byte, *url.URL, error) {
+	slog.Debug("===", "GetServerMigration is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/migrate")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
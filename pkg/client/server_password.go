@@ -0,0 +1,31 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/takara9/marmot/api"
+)
+
+// サーバーのユーザーのパスワード設定
+func (m *MarmotEndpoint) SetServerPassword(id string, spec api.ServerPasswordRequest) ([]byte, *url.URL, error) {
+	slog.Debug("===", "SetServerPassword is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/password")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	byteJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+)
+
+func TestSetServerPassword(t *testing.T) {
+	spec := api.ServerPasswordRequest{User: "ubuntu", Password: "secret"}
+
+	runClientCases(t, []clientCase{
+		{
+			name:     "sets the password of a user",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.SetServerPassword("ab12c", spec)) },
+			method:   http.MethodPost,
+			path:     "/api/v1/server/ab12c/password",
+			wantReq:  spec,
+			status:   http.StatusAccepted,
+			respBody: `{"id":"ab12c","message":"Accepted the request to set the password"}`,
+		},
+		{
+			name:       "maps a stopped server to a conflict",
+			call:       func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.SetServerPassword("ab12c", spec)) },
+			method:     http.MethodPost,
+			path:       "/api/v1/server/ab12c/password",
+			wantReq:    spec,
+			status:     http.StatusConflict,
+			respBody:   `{"code":1,"message":"server is not RUNNING"}`,
+			wantErr:    "server is not RUNNING",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "maps an unknown server to not found",
+			call:       func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.SetServerPassword("zz99z", spec)) },
+			method:     http.MethodPost,
+			path:       "/api/v1/server/zz99z/password",
+			wantReq:    spec,
+			status:     http.StatusNotFound,
+			respBody:   `{"code":1,"message":"server zz99z not found"}`,
+			wantErr:    "server zz99z not found",
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/takara9/marmot/api"
+)
+
+// サーバーのスナップショット作成
+func (m *MarmotEndpoint) CreateServerSnapshot(id string, spec api.ServerSnapshot) ([]byte, *url.URL, error) {
+	slog.Debug("===", "CreateServerSnapshot is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/snapshot")
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Debug("CreateServerSnapshot", "reqURL", reqURL)
+
+	byteJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// サーバーのスナップショット一覧
+func (m *MarmotEndpoint) GetServerSnapshots(id string) ([]byte, *url.URL, error) {
+	slog.Debug("===", "GetServerSnapshots is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/snapshot")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// サーバーのスナップショット詳細
+func (m *MarmotEndpoint) GetServerSnapshotById(id, snapshotId string) ([]byte, *url.URL, error) {
+	slog.Debug("===", "GetServerSnapshotById is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/snapshot/"+snapshotId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// サーバーのスナップショット削除
+func (m *MarmotEndpoint) DeleteServerSnapshotById(id, snapshotId string) ([]byte, *url.URL, error) {
+	slog.Debug("===", "DeleteServerSnapshotById is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/snapshot/"+snapshotId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("DELETE", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// サーバーをスナップショットへロールバック
+func (m *MarmotEndpoint) RevertServerSnapshotById(id, snapshotId string) ([]byte, *url.URL, error) {
+	slog.Debug("===", "RevertServerSnapshotById is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/snapshot/"+snapshotId+"/revert")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("POST", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return m.httpRequest2(req)
+}
@@ -0,0 +1,37 @@
+package client
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// サーバーへのボリュームのアタッチ
+func (m *MarmotEndpoint) AttachServerVolume(id, volumeId string) ([]byte, *url.URL, error) {
+	slog.Debug("===", "AttachServerVolume is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/volumes/"+volumeId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("POST", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
+
+// サーバーからのボリュームのデタッチ
+func (m *MarmotEndpoint) DetachServerVolume(id, volumeId string) ([]byte, *url.URL, error) {
+	slog.Debug("===", "DetachServerVolume is called", "===")
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, "/server/"+id+"/volumes/"+volumeId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest("DELETE", reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.httpRequest2(req)
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestServerVolumeEndpoints(t *testing.T) {
+	runClientCases(t, []clientCase{
+		{
+			name:     "attaches a volume",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.AttachServerVolume("ab12c", "de34f")) },
+			method:   http.MethodPost,
+			path:     "/api/v1/server/ab12c/volumes/de34f",
+			status:   http.StatusAccepted,
+			respBody: `{"id":"ab12c","message":"Volume attach request accepted"}`,
+		},
+		{
+			name:       "maps attach conflicts to their message",
+			call:       func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.AttachServerVolume("ab12c", "de34f")) },
+			method:     http.MethodPost,
+			path:       "/api/v1/server/ab12c/volumes/de34f",
+			status:     http.StatusConflict,
+			respBody:   `{"code":1,"message":"volume is already attached to another server"}`,
+			wantErr:    "volume is already attached to another server",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:     "detaches a volume",
+			call:     func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.DetachServerVolume("ab12c", "de34f")) },
+			method:   http.MethodDelete,
+			path:     "/api/v1/server/ab12c/volumes/de34f",
+			status:   http.StatusAccepted,
+			respBody: `{"id":"ab12c","message":"Volume detach request accepted"}`,
+		},
+		{
+			name:       "maps detach errors to their message",
+			call:       func(ep *MarmotEndpoint) ([]byte, error) { return withoutURL(ep.DetachServerVolume("ab12c", "de34f")) },
+			method:     http.MethodDelete,
+			path:       "/api/v1/server/ab12c/volumes/de34f",
+			status:     http.StatusNotFound,
+			respBody:   `{"code":1,"message":"volume is not attached to the server"}`,
+			wantErr:    "volume is not attached to the server",
+			wantStatus: http.StatusNotFound,
+		},
+	})
+}
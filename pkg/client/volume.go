@@ -18,6 +18,7 @@ func (m *MarmotEndpoint) CreateVolume(spec api.Volume) ([]byte, *url.URL, error)
 	}
 	slog.Debug("CreateVolume", "reqURL", reqURL, "spec", spec)
 
+	m.assignProject(&spec.Metadata)
 	byteJSON, _ := json.Marshal(spec)
 	req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(byteJSON))
 	if err != nil {
@@ -49,7 +50,7 @@ func (m *MarmotEndpoint) ListVolumes() ([]byte, *url.URL, error) {
 	}
 	slog.Debug("ListVolumes", "reqURL", reqURL)
 
-	req, err := http.NewRequest("GET", reqURL, nil)
+	req, err := http.NewRequest("GET", m.withProject(reqURL), nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+)
+
+// サーバーが watch=true に対応しておらず、一覧を返した
+var ErrWatchNotSupported = errors.New("watch is not supported by the server")
+
+// イベント1件の最大サイズ
+const maxWatchEventSize = 16 * 1024 * 1024
+
+// WatchResources は一覧取得の API を watch=true で呼び出し、変更を受け取るたびに fn を呼び出す
+// path は "/server" のような一覧の API パス
+// m.Project を設定した場合はそのプロジェクトのリソースだけを受け取る
+// resourceVersion が空の場合は現在のリソースを ADDED で受け取ってから、その後の変更を受け取る
+// fn がエラーを返すか、ctx が終了するか、サーバーが接続を閉じると戻る。ERROR イベントはエラーとして返す
+func (m *MarmotEndpoint) WatchResources(ctx context.Context, path string, resourceVersion string, fn func(api.WatchEvent) error) error {
+	reqURL, err := url.JoinPath(m.Scheme+"://"+m.HostPort, m.BasePath, path)
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("watch", "true")
+	if strings.TrimSpace(resourceVersion) != "" {
+		query.Set("resourceVersion", strings.TrimSpace(resourceVersion))
+	}
+	if project := strings.TrimSpace(m.Project); project != "" {
+		query.Set("project", project)
+	}
+	reqURL += "?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "MarmotdClient/1.0")
+	req.Header.Set("Accept", "text/event-stream")
+	if token := strings.TrimSpace(m.AccessToken); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	// 長時間の接続になるため、クライアントのタイムアウトを外す
+	client := *m.Client
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var apiErr apiErrorBody
+		if err := json.Unmarshal(body, &apiErr); err == nil && strings.TrimSpace(apiErr.Message) != "" {
+			return fmt.Errorf("%s", strings.TrimSpace(apiErr.Message))
+		}
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return fmt.Errorf("%s", msg)
+		}
+		return fmt.Errorf("http status code = %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return ErrWatchNotSupported
+	}
+
+	err = readWatchEvents(resp.Body, fn)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// readWatchEvents は Server-Sent Events を読み、data ごとに fn を呼び出す
+func readWatchEvents(r io.Reader, fn func(api.WatchEvent) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxWatchEventSize)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			// コメント行（ハートビート）と event/id は data の内容と重複するため読み捨てる
+			if value, ok := strings.CutPrefix(line, "data:"); ok {
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(strings.TrimPrefix(value, " "))
+			}
+			continue
+		}
+		if data.Len() == 0 {
+			continue
+		}
+		var ev api.WatchEvent
+		if err := json.Unmarshal([]byte(data.String()), &ev); err != nil {
+			return fmt.Errorf("failed to parse watch event: %w", err)
+		}
+		data.Reset()
+		if ev.Type == "ERROR" {
+			return watchEventError(ev)
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func watchEventError(ev api.WatchEvent) error {
+	if ev.Object != nil {
+		if msg, ok := (*ev.Object)["message"].(string); ok && strings.TrimSpace(msg) != "" {
+			return fmt.Errorf("watch failed: %s", strings.TrimSpace(msg))
+		}
+	}
+	return errors.New("watch failed")
+}
+
+// DecodeWatchObject はイベントのリソースを out の型に変換する
+func DecodeWatchObject(ev api.WatchEvent, out interface{}) error {
+	if ev.Object == nil {
+		return errors.New("watch event has no object")
+	}
+	data, err := json.Marshal(*ev.Object)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
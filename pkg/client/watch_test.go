@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+)
+
+// watchNames は WatchResources で受け取ったイベントを "種類:resourceVersion:名前" の並びで返す
+func watchNames(path, resourceVersion string) func(ep *MarmotEndpoint) ([]byte, error) {
+	return func(ep *MarmotEndpoint) ([]byte, error) {
+		var got []string
+		err := ep.WatchResources(context.Background(), path, resourceVersion, func(ev api.WatchEvent) error {
+			var obj struct {
+				Metadata api.Metadata `json:"metadata"`
+			}
+			if err := DecodeWatchObject(ev, &obj); err != nil {
+				return err
+			}
+			got = append(got, ev.Type+":"+*ev.ResourceVersion+":"+obj.Metadata.Name)
+			return nil
+		})
+		return []byte(strings.Join(got, ",")), err
+	}
+}
+
+func TestWatchResources(t *testing.T) {
+	eventStream := http.Header{"Accept": {"text/event-stream"}}
+
+	runClientCases(t, []clientCase{
+		{
+			name:        "reads server-sent events after resourceVersion",
+			call:        watchNames("/server", "41"),
+			method:      http.MethodGet,
+			path:        "/api/v1/server",
+			query:       url.Values{"watch": {"true"}, "resourceVersion": {"41"}},
+			header:      eventStream,
+			contentType: "text/event-stream",
+			respBody: ": heartbeat\n\n" +
+				"event: ADDED\nid: 42\ndata: {\"type\":\"ADDED\",\"resourceVersion\":\"42\",\"object\":{\"metadata\":{\"name\":\"vm1\"}}}\n\n" +
+				"event: MODIFIED\nid: 43\ndata: {\"type\":\"MODIFIED\",\"resourceVersion\":\"43\",\"object\":{\"metadata\":{\"name\":\"vm1\"}}}\n\n",
+			want: "ADDED:42:vm1,MODIFIED:43:vm1",
+		},
+		{
+			name:        "returns ERROR events as errors",
+			call:        watchNames("/volume", ""),
+			method:      http.MethodGet,
+			path:        "/api/v1/volume",
+			query:       url.Values{"watch": {"true"}},
+			header:      eventStream,
+			contentType: "text/event-stream",
+			respBody:    "event: ERROR\ndata: {\"type\":\"ERROR\",\"object\":{\"code\":410,\"message\":\"resource version is too old\"}}\n\n",
+			wantErr:     "watch failed: resource version is too old",
+		},
+		{
+			name:     "detects servers without watch support",
+			call:     watchNames("/network", ""),
+			method:   http.MethodGet,
+			path:     "/api/v1/network",
+			query:    url.Values{"watch": {"true"}},
+			respBody: "[]\n",
+			wantErr:  ErrWatchNotSupported.Error(),
+		},
+		{
+			name:     "maps API errors to their message",
+			call:     watchNames("/image", ""),
+			method:   http.MethodGet,
+			path:     "/api/v1/image",
+			query:    url.Values{"watch": {"true"}},
+			status:   http.StatusForbidden,
+			respBody: `{"code":403,"message":"permission denied"}`,
+			wantErr:  "permission denied",
+		},
+	})
+}
@@ -13,6 +13,7 @@ import (
 func GetClientConfig2(apiConfigFilename string) (*client.MarmotEndpoint, error) {
 	var rawURL string
 	var insecureSkipTLSVerify bool
+	var project string
 
 	if len(apiConfigFilename) > 0 {
 		// ファイルパスとして .marmot フォーマットで読み込む
@@ -27,6 +28,7 @@ func GetClientConfig2(apiConfigFilename string) (*client.MarmotEndpoint, error)
 			return nil, err
 		}
 		insecureSkipTLSVerify = cfg.InsecureSkipTLSVerify
+		project = cfg.Project
 	} else {
 		// $HOME/.marmot を保証し読み込む
 		if err := EnsureMarmotConfig(); err != nil {
@@ -45,6 +47,7 @@ func GetClientConfig2(apiConfigFilename string) (*client.MarmotEndpoint, error)
 			return nil, aErr
 		}
 		insecureSkipTLSVerify = cfg.InsecureSkipTLSVerify
+		project = cfg.Project
 	}
 
 	if len(rawURL) == 0 {
@@ -57,11 +60,16 @@ func GetClientConfig2(apiConfigFilename string) (*client.MarmotEndpoint, error)
 		return nil, err
 	}
 
-	return client.NewMarmotdEp(
+	ep, err := client.NewMarmotdEp(
 		u.Scheme,
 		u.Host,
 		"/api/v1",
 		60,
 		insecureSkipTLSVerify,
 	)
+	if err != nil {
+		return nil, err
+	}
+	ep.Project = project
+	return ep, nil
 }
@@ -37,6 +37,8 @@ type MarmotConfig struct {
 	Endpoints        []string `yaml:"endpoints"`
 	InsecureSkipTLSVerify bool `yaml:"insecure-skip-tls-verify"`
 	EndpointComments []string `yaml:"endpointComments,omitempty"`
+	// Project は一覧と作成に使う既定のプロジェクト。空の場合は参照できるすべてのプロジェクト
+	Project string `yaml:"project,omitempty"`
 }
 
 // MarmotConfigPath は $HOME/.marmot のパスを返す。
@@ -36,12 +36,17 @@ var (
 	readApplicationLoadBalancerDesiredConfigHash = readApplicationLoadBalancerDesiredConfigHashCommand
 )
 
-const applicationLoadBalancerAgentStatePath = "/var/lib/marmot/lb-agent/state.json"
+const (
+	applicationLoadBalancerAgentStatePath = "/var/lib/marmot/lb-agent/state.json"
+	// HTTPS リスナーの証明書を置くロードバランサーのサーバー上のディレクトリ
+	applicationLoadBalancerCertificateDir = "/etc/haproxy/certs/marmot"
+)
 
 type applicationLoadBalancerPlaybookData struct {
 	TargetIP                string
 	DesiredConfigSourcePath string
 	DesiredConfigPath       string
+	CertificateSourceDir    string
 }
 
 type applicationLoadBalancerBackendServer struct {
@@ -49,14 +54,40 @@ type applicationLoadBalancerBackendServer struct {
 	IP   string
 }
 
+// applicationLoadBalancerCertificate は HAProxy に配布する証明書
+type applicationLoadBalancerCertificate struct {
+	ID          string
+	Fingerprint string
+	PEM         string // 証明書に秘密鍵を連結した HAProxy の crt ファイルの内容
+}
+
+// applicationLoadBalancerBackendKey はリスナーまたはルールのバックエンドを引くキーを返す
+func applicationLoadBalancerBackendKey(listenerName, ruleName string) string {
+	listenerName = strings.TrimSpace(listenerName)
+	ruleName = strings.TrimSpace(ruleName)
+	if ruleName == "" {
+		return listenerName
+	}
+	return listenerName + "/" + ruleName
+}
+
+func applicationLoadBalancerCertificatePath(certificateID string) string {
+	return filepath.Join(applicationLoadBalancerCertificateDir, sanitizeHAProxyToken(certificateID)+".pem")
+}
+
+// applicationLoadBalancerCertificateSourceDir は配布する証明書を置くコントローラー側のディレクトリを返す
+func applicationLoadBalancerCertificateSourceDir(desiredConfigPath string) string {
+	return strings.TrimSuffix(strings.TrimSpace(desiredConfigPath), ".cfg") + "-certs"
+}
+
 type applicationLoadBalancerAgentState struct {
 	LastAppliedHash string    `json:"lastAppliedHash"`
 	LastAppliedAt   time.Time `json:"lastAppliedAt"`
 	LastError       string    `json:"lastError,omitempty"`
 }
 
-func desiredApplicationLoadBalancerConfigHash(loadBalancer api.ApplicationLoadBalancer, listenerBackends map[string][]applicationLoadBalancerBackendServer) (string, error) {
-	haproxyCfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, listenerBackends)
+func desiredApplicationLoadBalancerConfigHash(loadBalancer api.ApplicationLoadBalancer, listenerBackends map[string][]applicationLoadBalancerBackendServer, certificates map[string]applicationLoadBalancerCertificate) (string, error) {
+	haproxyCfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, listenerBackends, certificates)
 	if err != nil {
 		return "", err
 	}
@@ -72,23 +103,56 @@ func applicationLoadBalancerDesiredConfigPath(loadBalancerID string) string {
 	return filepath.Join(applicationLoadBalancerDesiredDir, fmt.Sprintf("haproxy-desired-%s.cfg", id))
 }
 
-func writeApplicationLoadBalancerDesiredConfig(desiredConfigPath string, loadBalancer api.ApplicationLoadBalancer, listenerBackends map[string][]applicationLoadBalancerBackendServer) (string, error) {
+func writeApplicationLoadBalancerDesiredConfig(desiredConfigPath string, loadBalancer api.ApplicationLoadBalancer, listenerBackends map[string][]applicationLoadBalancerBackendServer, certificates map[string]applicationLoadBalancerCertificate) (string, error) {
 	if strings.TrimSpace(desiredConfigPath) == "" {
 		return "", fmt.Errorf("desired config path is empty")
 	}
-	haproxyCfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, listenerBackends)
+	haproxyCfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, listenerBackends, certificates)
 	if err != nil {
 		return "", err
 	}
 	if err := os.MkdirAll(filepath.Dir(desiredConfigPath), 0o755); err != nil {
 		return "", err
 	}
+	if err := writeApplicationLoadBalancerCertificates(applicationLoadBalancerCertificateSourceDir(desiredConfigPath), certificates); err != nil {
+		return "", err
+	}
 	if err := os.WriteFile(desiredConfigPath, []byte(haproxyCfg), 0o644); err != nil {
 		return "", err
 	}
 	return fileSHA256Hex(desiredConfigPath)
 }
 
+// writeApplicationLoadBalancerCertificates は配布する証明書をディレクトリに書き出し、使われなくなった証明書を消す
+func writeApplicationLoadBalancerCertificates(dir string, certificates map[string]applicationLoadBalancerCertificate) error {
+	if len(certificates) == 0 {
+		return os.RemoveAll(dir)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	keep := make(map[string]struct{}, len(certificates))
+	for id, cert := range certificates {
+		name := filepath.Base(applicationLoadBalancerCertificatePath(id))
+		keep[name] = struct{}{}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(cert.PEM), 0o600); err != nil {
+			return err
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if _, ok := keep[entry.Name()]; !ok {
+			if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func fileSHA256Hex(path string) (string, error) {
 	content, err := os.ReadFile(strings.TrimSpace(path))
 	if err != nil {
@@ -98,7 +162,7 @@ func fileSHA256Hex(path string) (string, error) {
 	return fmt.Sprintf("%x", sum), nil
 }
 
-func renderApplicationLoadBalancerPlaybook(playbookPath string, targetIP string, desiredConfigSourcePath string, desiredConfigPath string) error {
+func renderApplicationLoadBalancerPlaybook(playbookPath string, targetIP string, desiredConfigSourcePath string, desiredConfigPath string, certificateSourceDir string) error {
 	if strings.TrimSpace(playbookPath) == "" {
 		return fmt.Errorf("playbook path is empty")
 	}
@@ -131,6 +195,7 @@ func renderApplicationLoadBalancerPlaybook(playbookPath string, targetIP string,
 		TargetIP:                strings.TrimSpace(targetIP),
 		DesiredConfigSourcePath: strings.TrimSpace(desiredConfigSourcePath),
 		DesiredConfigPath:       strings.TrimSpace(desiredConfigPath),
+		CertificateSourceDir:    strings.TrimSpace(certificateSourceDir),
 	}
 	if err := tmpl.Execute(&buf, data); err != nil {
 		return err
@@ -256,7 +321,7 @@ func readApplicationLoadBalancerDesiredConfigHashCommand(targetAddress, privateK
 	return strings.TrimSpace(fields[0]), nil
 }
 
-func buildApplicationLoadBalancerHAProxyConfig(loadBalancer api.ApplicationLoadBalancer, listenerBackends map[string][]applicationLoadBalancerBackendServer) (string, error) {
+func buildApplicationLoadBalancerHAProxyConfig(loadBalancer api.ApplicationLoadBalancer, listenerBackends map[string][]applicationLoadBalancerBackendServer, certificates map[string]applicationLoadBalancerCertificate) (string, error) {
 	id := strings.TrimSpace(api.LoadBalancerID(loadBalancer))
 	if id == "" {
 		id = strings.TrimSpace(loadBalancer.Metadata.Name)
@@ -274,10 +339,11 @@ func buildApplicationLoadBalancerHAProxyConfig(loadBalancer api.ApplicationLoadB
 	b.WriteString("  timeout client 60s\n")
 	b.WriteString("  timeout server 60s\n\n")
 
+	backendNames := make(map[string]struct{})
 	for index, listener := range loadBalancer.Spec.Listeners {
 		protocol := strings.ToUpper(strings.TrimSpace(listener.Protocol))
 		mode := "tcp"
-		if protocol == "HTTP" {
+		if protocol == "HTTP" || protocol == "HTTPS" {
 			mode = "http"
 		}
 		algorithm := strings.ToLower(strings.TrimSpace(listener.LoadBalancingAlgorithm))
@@ -290,67 +356,175 @@ func buildApplicationLoadBalancerHAProxyConfig(loadBalancer api.ApplicationLoadB
 		}
 		frontendName := sanitizeHAProxyToken(fmt.Sprintf("%s-fe-%s", id, name))
 		backendName := sanitizeHAProxyToken(fmt.Sprintf("%s-be-%s", id, name))
+		backendNames[backendName] = struct{}{}
+		var rules []api.ApplicationLoadBalancerRule
+		if listener.Rules != nil {
+			rules = *listener.Rules
+		}
 
 		bindAddress, _, err := normalizePublicBindAddress(loadBalancer.Spec.BindPublicIpAddress)
 		if err != nil {
 			return "", fmt.Errorf("invalid bindPublicIpAddress: %w", err)
 		}
 		b.WriteString("frontend " + frontendName + "\n")
-		_, _ = fmt.Fprintf(&b, "  bind %s:%d\n", bindAddress, listener.VipPort)
-		b.WriteString("  mode " + mode + "\n")
-		b.WriteString("  default_backend " + backendName + "\n\n")
-
-		b.WriteString("backend " + backendName + "\n")
-		b.WriteString("  mode " + mode + "\n")
-		b.WriteString("  balance " + algorithm + "\n")
-		if mode == "http" {
-			b.WriteString("  option forwardfor if-none\n")
-			b.WriteString("  http-request set-header X-Real-IP %[src]\n")
-		}
-		if listener.HealthCheck != nil && listener.HealthCheck.Enabled && mode == "http" {
-			path := ""
-			if listener.HealthCheck.Path != nil {
-				path = strings.TrimSpace(*listener.HealthCheck.Path)
+		bind := fmt.Sprintf("  bind %s:%d", bindAddress, listener.VipPort)
+		if protocol == "HTTPS" {
+			// 最初の証明書が SNI に一致する証明書が無い場合の既定になる
+			if listener.CertificateIds == nil || len(*listener.CertificateIds) == 0 {
+				return "", fmt.Errorf("listener %s: HTTPS listener has no certificate", name)
 			}
-			if path == "" {
-				path = "/healthz"
+			bind += " ssl"
+			for _, certificateID := range *listener.CertificateIds {
+				cert, ok := certificates[strings.TrimSpace(certificateID)]
+				if !ok {
+					return "", fmt.Errorf("listener %s: certificate %s is not resolved", name, certificateID)
+				}
+				// 証明書の更新で設定のハッシュが変わり、HAProxy が読み込み直すようにする
+				_, _ = fmt.Fprintf(&b, "  # certificate %s sha256:%s\n", cert.ID, cert.Fingerprint)
+				bind += " crt " + applicationLoadBalancerCertificatePath(cert.ID)
 			}
-			b.WriteString("  option httpchk GET " + path + "\n")
 		}
-		if listener.SessionPersistence != nil && listener.SessionPersistence.Enabled && mode == "http" {
-			cookieName := ""
-			if listener.SessionPersistence.CookieName != nil {
-				cookieName = strings.TrimSpace(*listener.SessionPersistence.CookieName)
+		b.WriteString(bind + "\n")
+		b.WriteString("  mode " + mode + "\n")
+		if listener.RedirectToHttps != nil && *listener.RedirectToHttps {
+			b.WriteString(applicationLoadBalancerHTTPSRedirect(loadBalancer.Spec) + "\n")
+		}
+		if protocol == "HTTPS" {
+			b.WriteString("  http-request set-header X-Forwarded-Proto https\n")
+		}
+		if mode == "tcp" && len(rules) > 0 {
+			// TLS の ClientHello を待って SNI で振り分ける
+			b.WriteString("  tcp-request inspect-delay 5s\n")
+			b.WriteString("  tcp-request content accept if { req.ssl_hello_type 1 }\n")
+		}
+		ruleBackendNames := make([]string, len(rules))
+		for ruleIndex, rule := range rules {
+			ruleBackendNames[ruleIndex] = sanitizeHAProxyToken(fmt.Sprintf("%s-rule-%s-%s", id, name, rule.Name))
+			if _, exists := backendNames[ruleBackendNames[ruleIndex]]; exists {
+				return "", fmt.Errorf("listener %s: rule %s conflicts with the backend %s", name, rule.Name, ruleBackendNames[ruleIndex])
 			}
-			if cookieName == "" {
-				cookieName = sanitizeCookieName(loadBalancer.Metadata.Name)
+			backendNames[ruleBackendNames[ruleIndex]] = struct{}{}
+			b.WriteString(applicationLoadBalancerRuleACLs(rule, mode, ruleBackendNames[ruleIndex]))
+		}
+		b.WriteString("  default_backend " + backendName + "\n\n")
+
+		writeApplicationLoadBalancerBackend(&b, loadBalancer, listener, mode, algorithm, backendName, listener.BackendPort, listenerBackends[applicationLoadBalancerBackendKey(listener.Name, "")])
+		for ruleIndex, rule := range rules {
+			port := listener.BackendPort
+			if rule.BackendPort != nil {
+				port = *rule.BackendPort
 			}
-			b.WriteString("  cookie " + cookieName + " insert indirect nocache\n")
+			writeApplicationLoadBalancerBackend(&b, loadBalancer, listener, mode, algorithm, ruleBackendNames[ruleIndex], port, listenerBackends[applicationLoadBalancerBackendKey(listener.Name, rule.Name)])
 		}
-		backends := listenerBackends[strings.TrimSpace(listener.Name)]
-		if len(backends) == 0 {
-			_, _ = fmt.Fprintf(&b, "  server-template srv 20 _placeholder_:%d check disabled\n\n", listener.BackendPort)
-			continue
+	}
+
+	return b.String(), nil
+}
+
+// applicationLoadBalancerHTTPSRedirect は HTTP のリクエストを同じロードバランサーの HTTPS リスナーへ転送する設定を返す
+func applicationLoadBalancerHTTPSRedirect(spec api.ApplicationLoadBalancerSpec) string {
+	port := 443
+	for _, listener := range spec.Listeners {
+		if strings.ToUpper(strings.TrimSpace(listener.Protocol)) == "HTTPS" {
+			port = listener.VipPort
+			break
 		}
-		for backendIndex, backend := range backends {
-			backendIP := strings.TrimSpace(backend.IP)
-			if backendIP == "" {
+	}
+	if port == 443 {
+		return "  http-request redirect scheme https code 301"
+	}
+	return fmt.Sprintf("  http-request redirect location https://%%[req.hdr(host),field(1,:)]:%d%%[capture.req.uri] code 301", port)
+}
+
+// applicationLoadBalancerRuleACLs はルールの条件と振り分け先を返す
+// ホスト名は http モードでは Host ヘッダー、tcp モードでは TLS の SNI と照合する
+func applicationLoadBalancerRuleACLs(rule api.ApplicationLoadBalancerRule, mode string, backendName string) string {
+	token := sanitizeHAProxyToken(rule.Name)
+	fetch := "req.hdr(host),field(1,:),lower"
+	if mode == "tcp" {
+		fetch = "req.ssl_sni,lower"
+	}
+
+	var b strings.Builder
+	var conditions []string
+	if rule.Hosts != nil && len(*rule.Hosts) > 0 {
+		var exact, suffixes []string
+		for _, host := range *rule.Hosts {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if strings.HasPrefix(host, "*.") {
+				suffixes = append(suffixes, strings.TrimPrefix(host, "*"))
 				continue
 			}
-			serverName := sanitizeHAProxyToken(fmt.Sprintf("srv-%d-%s", backendIndex+1, backend.Name))
-			if serverName == "" {
-				serverName = fmt.Sprintf("srv-%d", backendIndex+1)
-			}
-			line := fmt.Sprintf("  server %s %s:%d check", serverName, backendIP, listener.BackendPort)
-			if listener.SessionPersistence != nil && listener.SessionPersistence.Enabled && mode == "http" {
-				line += fmt.Sprintf(" cookie %s", serverName)
-			}
-			b.WriteString(line + "\n")
+			exact = append(exact, host)
+		}
+		if len(exact) > 0 {
+			_, _ = fmt.Fprintf(&b, "  acl %s-host %s -m str %s\n", token, fetch, strings.Join(exact, " "))
 		}
-		b.WriteString("\n")
+		if len(suffixes) > 0 {
+			_, _ = fmt.Fprintf(&b, "  acl %s-host %s -m end %s\n", token, fetch, strings.Join(suffixes, " "))
+		}
+		conditions = append(conditions, token+"-host")
+	}
+	if mode == "http" && rule.PathPrefixes != nil && len(*rule.PathPrefixes) > 0 {
+		_, _ = fmt.Fprintf(&b, "  acl %s-path path -m beg %s\n", token, strings.Join(*rule.PathPrefixes, " "))
+		conditions = append(conditions, token+"-path")
+	}
+	if len(conditions) == 0 {
+		return ""
 	}
+	_, _ = fmt.Fprintf(&b, "  use_backend %s if %s\n", backendName, strings.Join(conditions, " "))
+	return b.String()
+}
 
-	return b.String(), nil
+// writeApplicationLoadBalancerBackend はリスナーまたはルールのバックエンドを書き出す
+func writeApplicationLoadBalancerBackend(b *strings.Builder, loadBalancer api.ApplicationLoadBalancer, listener api.ApplicationLoadBalancerListener, mode, algorithm, backendName string, backendPort int, backends []applicationLoadBalancerBackendServer) {
+	b.WriteString("backend " + backendName + "\n")
+	b.WriteString("  mode " + mode + "\n")
+	b.WriteString("  balance " + algorithm + "\n")
+	if mode == "http" {
+		b.WriteString("  option forwardfor if-none\n")
+		b.WriteString("  http-request set-header X-Real-IP %[src]\n")
+	}
+	if listener.HealthCheck != nil && listener.HealthCheck.Enabled && mode == "http" {
+		path := ""
+		if listener.HealthCheck.Path != nil {
+			path = strings.TrimSpace(*listener.HealthCheck.Path)
+		}
+		if path == "" {
+			path = "/healthz"
+		}
+		b.WriteString("  option httpchk GET " + path + "\n")
+	}
+	if listener.SessionPersistence != nil && listener.SessionPersistence.Enabled && mode == "http" {
+		cookieName := ""
+		if listener.SessionPersistence.CookieName != nil {
+			cookieName = strings.TrimSpace(*listener.SessionPersistence.CookieName)
+		}
+		if cookieName == "" {
+			cookieName = sanitizeCookieName(loadBalancer.Metadata.Name)
+		}
+		b.WriteString("  cookie " + cookieName + " insert indirect nocache\n")
+	}
+	if len(backends) == 0 {
+		_, _ = fmt.Fprintf(b, "  server-template srv 20 _placeholder_:%d check disabled\n\n", backendPort)
+		return
+	}
+	for backendIndex, backend := range backends {
+		backendIP := strings.TrimSpace(backend.IP)
+		if backendIP == "" {
+			continue
+		}
+		serverName := sanitizeHAProxyToken(fmt.Sprintf("srv-%d-%s", backendIndex+1, backend.Name))
+		if serverName == "" {
+			serverName = fmt.Sprintf("srv-%d", backendIndex+1)
+		}
+		line := fmt.Sprintf("  server %s %s:%d check", serverName, backendIP, backendPort)
+		if listener.SessionPersistence != nil && listener.SessionPersistence.Enabled && mode == "http" {
+			line += fmt.Sprintf(" cookie %s", serverName)
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n")
 }
 
 func sanitizeHAProxyToken(input string) string {
@@ -2,6 +2,8 @@ package controller
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -40,7 +42,7 @@ func TestBuildApplicationLoadBalancerHAProxyConfig_WithResolvedBackends(t *testi
 		},
 	}
 
-	cfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, backends)
+	cfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, backends, nil)
 	if err != nil {
 		t.Fatalf("buildApplicationLoadBalancerHAProxyConfig() failed: %v", err)
 	}
@@ -84,7 +86,7 @@ func TestBuildApplicationLoadBalancerHAProxyConfig_TCPListenerDoesNotInjectHTTPH
 
 	cfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, map[string][]applicationLoadBalancerBackendServer{
 		"db-tcp": {{Name: "db-a", IP: "172.16.20.11"}},
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("buildApplicationLoadBalancerHAProxyConfig() failed: %v", err)
 	}
@@ -122,7 +124,7 @@ func TestBuildApplicationLoadBalancerHAProxyConfig_NormalizesCIDRBindAddress(t *
 
 	cfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, map[string][]applicationLoadBalancerBackendServer{
 		"db-tcp": {{Name: "db-a", IP: "172.16.20.11"}},
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("buildApplicationLoadBalancerHAProxyConfig() failed: %v", err)
 	}
@@ -157,13 +159,13 @@ func TestDesiredApplicationLoadBalancerConfigHash_ChangesWhenBackendsChange(t *t
 
 	hashA, err := desiredApplicationLoadBalancerConfigHash(loadBalancer, map[string][]applicationLoadBalancerBackendServer{
 		"web-http": {{Name: "web-a", IP: "172.16.10.11"}},
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("desiredApplicationLoadBalancerConfigHash() failed: %v", err)
 	}
 	hashB, err := desiredApplicationLoadBalancerConfigHash(loadBalancer, map[string][]applicationLoadBalancerBackendServer{
 		"web-http": {{Name: "web-b", IP: "172.16.10.12"}},
-	})
+	}, nil)
 	if err != nil {
 		t.Fatalf("desiredApplicationLoadBalancerConfigHash() failed: %v", err)
 	}
@@ -240,3 +242,178 @@ func TestIsRetryableApplicationLoadBalancerPendingError(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildApplicationLoadBalancerHAProxyConfig_HTTPSWithRedirectAndRules(t *testing.T) {
+	redirect := true
+	loadBalancer := api.ApplicationLoadBalancer{
+		ApiVersion: "v1",
+		Kind:       "ApplicationLoadBalancer",
+		Metadata: api.Metadata{
+			Name: "lb-web",
+			Id:   "lb123",
+		},
+		Spec: api.ApplicationLoadBalancerSpec{
+			BindPublicIpAddress:    "192.168.1.120",
+			InternalVirtualNetwork: "web-servers",
+			Listeners: []api.ApplicationLoadBalancerListener{
+				{
+					Name:            "web-http",
+					Protocol:        "HTTP",
+					VipPort:         80,
+					BackendPort:     8080,
+					RedirectToHttps: &redirect,
+					BackendSelector: api.ApplicationLoadBalancerLabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				},
+				{
+					Name:           "web-https",
+					Protocol:       "HTTPS",
+					VipPort:        443,
+					BackendPort:    8080,
+					CertificateIds: &[]string{"c1", "c2"},
+					BackendSelector: api.ApplicationLoadBalancerLabelSelector{
+						MatchLabels: map[string]string{"app": "web"},
+					},
+					Rules: &[]api.ApplicationLoadBalancerRule{{
+						Name:            "api",
+						Hosts:           &[]string{"api.example.com", "*.api.example.com"},
+						PathPrefixes:    &[]string{"/v1/"},
+						BackendPort:     func() *int { p := 9090; return &p }(),
+						BackendSelector: api.ApplicationLoadBalancerLabelSelector{MatchLabels: map[string]string{"app": "api"}},
+					}},
+				},
+			},
+		},
+	}
+	certificates := map[string]applicationLoadBalancerCertificate{
+		"c1": {ID: "c1", Fingerprint: "aaaa", PEM: "pem1"},
+		"c2": {ID: "c2", Fingerprint: "bbbb", PEM: "pem2"},
+	}
+	backends := map[string][]applicationLoadBalancerBackendServer{
+		"web-http":      {{Name: "web-a", IP: "172.16.10.11"}},
+		"web-https":     {{Name: "web-a", IP: "172.16.10.11"}},
+		"web-https/api": {{Name: "api-a", IP: "172.16.10.21"}},
+	}
+
+	cfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, backends, certificates)
+	if err != nil {
+		t.Fatalf("buildApplicationLoadBalancerHAProxyConfig() failed: %v", err)
+	}
+	for _, want := range []string{
+		"  http-request redirect scheme https code 301\n",
+		"  # certificate c1 sha256:aaaa\n",
+		"  bind 192.168.1.120:443 ssl crt /etc/haproxy/certs/marmot/c1.pem crt /etc/haproxy/certs/marmot/c2.pem\n",
+		"  http-request set-header X-Forwarded-Proto https\n",
+		"  acl api-host req.hdr(host),field(1,:),lower -m str api.example.com\n",
+		"  acl api-host req.hdr(host),field(1,:),lower -m end .api.example.com\n",
+		"  acl api-path path -m beg /v1/\n",
+		"  use_backend lb123-rule-web-https-api if api-host api-path\n",
+		"backend lb123-rule-web-https-api\n",
+		"  server srv-1-api-a 172.16.10.21:9090 check\n",
+	} {
+		if !strings.Contains(cfg, want) {
+			t.Fatalf("generated cfg does not contain %q: %s", want, cfg)
+		}
+	}
+
+	delete(certificates, "c2")
+	if _, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, backends, certificates); err == nil {
+		t.Fatalf("buildApplicationLoadBalancerHAProxyConfig() expected error for an unresolved certificate")
+	}
+}
+
+func TestBuildApplicationLoadBalancerHAProxyConfig_RedirectToNonStandardPort(t *testing.T) {
+	redirect := true
+	spec := api.ApplicationLoadBalancerSpec{
+		Listeners: []api.ApplicationLoadBalancerListener{
+			{Name: "web-http", Protocol: "HTTP", VipPort: 80, RedirectToHttps: &redirect},
+			{Name: "web-https", Protocol: "HTTPS", VipPort: 8443},
+		},
+	}
+	want := "  http-request redirect location https://%[req.hdr(host),field(1,:)]:8443%[capture.req.uri] code 301"
+	if got := applicationLoadBalancerHTTPSRedirect(spec); got != want {
+		t.Fatalf("applicationLoadBalancerHTTPSRedirect() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildApplicationLoadBalancerHAProxyConfig_TCPRulesUseSNI(t *testing.T) {
+	loadBalancer := api.ApplicationLoadBalancer{
+		ApiVersion: "v1",
+		Kind:       "ApplicationLoadBalancer",
+		Metadata: api.Metadata{
+			Name: "lb-tls",
+			Id:   "lb-tls-1",
+		},
+		Spec: api.ApplicationLoadBalancerSpec{
+			BindPublicIpAddress:    "192.168.1.130",
+			InternalVirtualNetwork: "web-servers",
+			Listeners: []api.ApplicationLoadBalancerListener{{
+				Name:            "tls",
+				Protocol:        "TCP",
+				VipPort:         443,
+				BackendPort:     443,
+				BackendSelector: api.ApplicationLoadBalancerLabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Rules: &[]api.ApplicationLoadBalancerRule{{
+					Name:            "mail",
+					Hosts:           &[]string{"mail.example.com"},
+					BackendSelector: api.ApplicationLoadBalancerLabelSelector{MatchLabels: map[string]string{"app": "mail"}},
+				}},
+			}},
+		},
+	}
+
+	cfg, err := buildApplicationLoadBalancerHAProxyConfig(loadBalancer, map[string][]applicationLoadBalancerBackendServer{
+		"tls":      {{Name: "web-a", IP: "172.16.20.11"}},
+		"tls/mail": {{Name: "mail-a", IP: "172.16.20.21"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("buildApplicationLoadBalancerHAProxyConfig() failed: %v", err)
+	}
+	for _, want := range []string{
+		"  tcp-request inspect-delay 5s\n",
+		"  tcp-request content accept if { req.ssl_hello_type 1 }\n",
+		"  acl mail-host req.ssl_sni,lower -m str mail.example.com\n",
+		"  use_backend lb-tls-1-rule-tls-mail if mail-host\n",
+		"  server srv-1-mail-a 172.16.20.21:443 check\n",
+	} {
+		if !strings.Contains(cfg, want) {
+			t.Fatalf("generated cfg does not contain %q: %s", want, cfg)
+		}
+	}
+	if strings.Contains(cfg, " ssl crt ") {
+		t.Fatalf("generated cfg must not terminate TLS for TCP listeners: %s", cfg)
+	}
+}
+
+func TestWriteApplicationLoadBalancerCertificates(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "haproxy-desired-lb123-certs")
+
+	if err := writeApplicationLoadBalancerCertificates(dir, map[string]applicationLoadBalancerCertificate{
+		"c1": {ID: "c1", PEM: "pem1"},
+		"c2": {ID: "c2", PEM: "pem2"},
+	}); err != nil {
+		t.Fatalf("writeApplicationLoadBalancerCertificates() failed: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "c1.pem"))
+	if err != nil {
+		t.Fatalf("certificate file is not written: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Fatalf("certificate file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	if err := writeApplicationLoadBalancerCertificates(dir, map[string]applicationLoadBalancerCertificate{
+		"c2": {ID: "c2", PEM: "pem2"},
+	}); err != nil {
+		t.Fatalf("writeApplicationLoadBalancerCertificates() failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "c1.pem")); !os.IsNotExist(err) {
+		t.Fatalf("stale certificate file must be removed: %v", err)
+	}
+
+	if err := writeApplicationLoadBalancerCertificates(dir, nil); err != nil {
+		t.Fatalf("writeApplicationLoadBalancerCertificates() failed: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("certificate directory must be removed when no certificate is used: %v", err)
+	}
+}
@@ -47,81 +47,79 @@ func StartApplicationLoadBalancerController(node string, etcdUrl string) (*contr
 	c.stopChan = make(chan struct{})
 	c.doneChan = make(chan struct{})
 
-	ticker := time.NewTicker(applicationLoadBalancerControllerInterval)
-	go func() {
-		defer ticker.Stop()
-		defer close(c.doneChan)
-		for {
-			select {
-			case <-ticker.C:
-				c.applicationLoadBalancerControllerLoop()
-			case <-c.stopChan:
-				slog.Debug("ロードバランサーコントローラー停止")
-				return
-			}
-		}
-	}()
+	// 変更の監視と定期実行の開始
+	keyedWatchLoop{
+		name:      "ロードバランサーコントローラー",
+		resync:    applicationLoadBalancerControllerInterval,
+		primary:   db.LoadBalancerPrefix,
+		related:   []string{db.ServerPrefix},
+		reconcile: c.reconcileApplicationLoadBalancerById,
+	}.run(c.db, c.stopChan, c.doneChan)
 
 	return &c, nil
 }
 
-func (c *controller) applicationLoadBalancerControllerLoop() {
-	slog.Debug("ロードバランサーコントローラーの制御ループ実行", "CONTROLLER", time.Now().Format("2006-01-02 15:04:05"))
-
-	items, err := c.db.GetLoadBalancers()
+// reconcileApplicationLoadBalancerById は ID のロードバランサーを読み直して調整する。削除済みなら何もしない
+func (c *controller) reconcileApplicationLoadBalancerById(id string) reconcileResult {
+	item, err := c.db.GetLoadBalancerById(id)
 	if err != nil {
-		slog.Error("GetLoadBalancers() failed", "err", err)
-		return
+		if errors.Is(err, db.ErrNotFound) {
+			return reconcileResult{}
+		}
+		slog.Error("GetLoadBalancerById() failed", "id", id, "err", err)
+		return reconcileResult{retry: true}
 	}
+	return c.reconcileApplicationLoadBalancer(item)
+}
 
-	for _, item := range items {
-		id := api.LoadBalancerID(item)
-		if ok, assignedNode, reason := evaluateNodeAssignment(&item.Metadata, c.marmot.NodeName); !ok {
-			slog.Debug("別ノード割当のロードバランサーをスキップ", "id", id, "name", item.Metadata.Name, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
-			continue
-		}
+func (c *controller) reconcileApplicationLoadBalancer(item api.ApplicationLoadBalancer) reconcileResult {
+	id := api.LoadBalancerID(item)
+	if ok, assignedNode, reason := evaluateNodeAssignment(&item.Metadata, c.marmot.NodeName); !ok {
+		slog.Debug("別ノード割当のロードバランサーをスキップ", "id", id, "name", item.Metadata.Name, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
+		return reconcileResult{}
+	}
 
-		if item.Status == nil || item.Status.StatusCode != db.LOAD_BALANCER_DELETING {
-			missingServer, err := c.isApplicationLoadBalancerManagedServerMissing(item)
-			if err != nil {
-				slog.Warn("failed to validate load balancer managed server", "id", id, "err", err)
-				continue
-			}
-			if missingServer {
-				c.deleteApplicationLoadBalancerForMissingServer(item)
-				continue
-			}
+	if item.Status == nil || item.Status.StatusCode != db.LOAD_BALANCER_DELETING {
+		missingServer, err := c.isApplicationLoadBalancerManagedServerMissing(item)
+		if err != nil {
+			slog.Warn("failed to validate load balancer managed server", "id", id, "err", err)
+			return reconcileResult{retry: true}
 		}
-
-		if item.Status == nil {
-			_ = c.db.UpdateLoadBalancerStatusWithMessage(id, db.LOAD_BALANCER_PENDING, "")
-			continue
+		if missingServer {
+			c.deleteApplicationLoadBalancerForMissingServer(item)
+			return reconcileResult{}
 		}
+	}
 
-		if item.Status.DeletionTimeStamp != nil && time.Since(*item.Status.DeletionTimeStamp) > c.deletionDelay {
-			if item.Status.StatusCode != db.LOAD_BALANCER_DELETING {
-				_ = c.db.UpdateLoadBalancerStatusWithMessage(id, db.LOAD_BALANCER_DELETING, "")
-				continue
-			}
-		}
+	if item.Status == nil {
+		_ = c.db.UpdateLoadBalancerStatusWithMessage(id, db.LOAD_BALANCER_PENDING, "")
+		return reconcileResult{}
+	}
 
-		switch item.Status.StatusCode {
-		case db.LOAD_BALANCER_PENDING:
-			c.reconcileApplicationLoadBalancerPending(item)
-		case db.LOAD_BALANCER_PROVISIONING:
-			c.reconcileApplicationLoadBalancerProvisioning(item)
-		case db.LOAD_BALANCER_CONFIGURING:
-			c.reconcileApplicationLoadBalancerConfiguring(item)
-		case db.LOAD_BALANCER_ACTIVE, db.LOAD_BALANCER_DEGRADED:
-			c.reconcileApplicationLoadBalancerActive(item)
-		case db.LOAD_BALANCER_DELETING:
-			c.reconcileApplicationLoadBalancerDeleting(item)
-		case db.LOAD_BALANCER_FAILED:
-			slog.Debug("ロードバランサー状態を監視", "id", id, "statusCode", item.Status.StatusCode)
-		default:
-			slog.Warn("不明なロードバランサー状態", "id", id, "statusCode", item.Status.StatusCode)
+	if item.Status.DeletionTimeStamp != nil && time.Since(*item.Status.DeletionTimeStamp) > c.deletionDelay {
+		if item.Status.StatusCode != db.LOAD_BALANCER_DELETING {
+			_ = c.db.UpdateLoadBalancerStatusWithMessage(id, db.LOAD_BALANCER_DELETING, "")
+			return reconcileResult{}
 		}
 	}
+
+	switch item.Status.StatusCode {
+	case db.LOAD_BALANCER_PENDING:
+		c.reconcileApplicationLoadBalancerPending(item)
+	case db.LOAD_BALANCER_PROVISIONING:
+		c.reconcileApplicationLoadBalancerProvisioning(item)
+	case db.LOAD_BALANCER_CONFIGURING:
+		c.reconcileApplicationLoadBalancerConfiguring(item)
+	case db.LOAD_BALANCER_ACTIVE, db.LOAD_BALANCER_DEGRADED:
+		c.reconcileApplicationLoadBalancerActive(item)
+	case db.LOAD_BALANCER_DELETING:
+		c.reconcileApplicationLoadBalancerDeleting(item)
+	case db.LOAD_BALANCER_FAILED:
+		slog.Debug("ロードバランサー状態を監視", "id", id, "statusCode", item.Status.StatusCode)
+	default:
+		slog.Warn("不明なロードバランサー状態", "id", id, "statusCode", item.Status.StatusCode)
+	}
+	return reconcileResult{}
 }
 
 func (c *controller) isApplicationLoadBalancerManagedServerMissing(loadBalancer api.ApplicationLoadBalancer) (bool, error) {
@@ -248,16 +246,25 @@ func (c *controller) reconcileApplicationLoadBalancerConfiguring(loadBalancer ap
 		_ = c.db.UpdateLoadBalancerStatusWithMessage(loadBalancerID, db.LOAD_BALANCER_DEGRADED, msg)
 		return
 	}
+	certificates, err := c.resolveApplicationLoadBalancerCertificates(loadBalancer)
+	if err != nil {
+		c.handleApplicationLoadBalancerConfigFailure(loadBalancerID, err)
+		return
+	}
 
 	playbookPath := filepath.Join(applicationLoadBalancerPlaybookDir, fmt.Sprintf("load-balancer-%s.yaml", loadBalancerID))
 	desiredConfigPath := applicationLoadBalancerDesiredConfigPath(loadBalancerID)
-	configHash, err := writeApplicationLoadBalancerDesiredConfig(desiredConfigPath, loadBalancer, listenerBackends)
+	configHash, err := writeApplicationLoadBalancerDesiredConfig(desiredConfigPath, loadBalancer, listenerBackends, certificates)
 	if err != nil {
 		c.handleApplicationLoadBalancerConfigFailure(loadBalancerID, err)
 		return
 	}
 	if applicationLoadBalancerStagedConfigHash(loadBalancer) != configHash {
-		if err := renderApplicationLoadBalancerPlaybook(playbookPath, targetIP, desiredConfigPath, desiredConfigPath); err != nil {
+		certificateSourceDir := ""
+		if len(certificates) > 0 {
+			certificateSourceDir = applicationLoadBalancerCertificateSourceDir(desiredConfigPath)
+		}
+		if err := renderApplicationLoadBalancerPlaybook(playbookPath, targetIP, desiredConfigPath, desiredConfigPath, certificateSourceDir); err != nil {
 			c.handleApplicationLoadBalancerConfigFailure(loadBalancerID, err)
 			return
 		}
@@ -320,7 +327,13 @@ func (c *controller) reconcileApplicationLoadBalancerActive(loadBalancer api.App
 		return
 	}
 
-	desiredHash, err := desiredApplicationLoadBalancerConfigHash(loadBalancer, listenerBackends)
+	certificates, err := c.resolveApplicationLoadBalancerCertificates(loadBalancer)
+	if err != nil {
+		_ = c.db.UpdateLoadBalancerStatusWithMessage(loadBalancerID, db.LOAD_BALANCER_DEGRADED, err.Error())
+		return
+	}
+
+	desiredHash, err := desiredApplicationLoadBalancerConfigHash(loadBalancer, listenerBackends, certificates)
 	if err != nil {
 		_ = c.db.UpdateLoadBalancerStatusWithMessage(loadBalancerID, db.LOAD_BALANCER_FAILED, err.Error())
 		return
@@ -366,34 +379,71 @@ func (c *controller) resolveApplicationLoadBalancerListenerBackends(loadBalancer
 		if listenerName == "" {
 			continue
 		}
-		items := make([]applicationLoadBalancerBackendServer, 0)
-		for _, server := range servers {
-			if server.Status == nil || server.Status.StatusCode != db.SERVER_RUNNING {
-				continue
-			}
-			if !serverMatchesApplicationLoadBalancerSelector(server, listener.BackendSelector.MatchLabels) {
-				continue
-			}
-			ip := serverAddressInNetwork(server, internalNetwork)
-			if ip == "" {
+		result[listenerName] = applicationLoadBalancerSelectBackends(servers, listener.BackendSelector.MatchLabels, internalNetwork)
+		if listener.Rules == nil {
+			continue
+		}
+		for _, rule := range *listener.Rules {
+			result[applicationLoadBalancerBackendKey(listenerName, rule.Name)] = applicationLoadBalancerSelectBackends(servers, rule.BackendSelector.MatchLabels, internalNetwork)
+		}
+	}
+	return result, nil
+}
+
+// applicationLoadBalancerSelectBackends はラベルが一致する稼働中のサーバーを、内部ネットワークのアドレスとともに名前の順に返す
+func applicationLoadBalancerSelectBackends(servers []api.Server, matchLabels map[string]string, internalNetwork string) []applicationLoadBalancerBackendServer {
+	items := make([]applicationLoadBalancerBackendServer, 0)
+	for _, server := range servers {
+		if server.Status == nil || server.Status.StatusCode != db.SERVER_RUNNING {
+			continue
+		}
+		if !serverMatchesApplicationLoadBalancerSelector(server, matchLabels) {
+			continue
+		}
+		ip := serverAddressInNetwork(server, internalNetwork)
+		if ip == "" {
+			continue
+		}
+		name := strings.TrimSpace(server.Metadata.Name)
+		if name == "" {
+			name = strings.TrimSpace(api.ServerID(server))
+		}
+		if name == "" {
+			continue
+		}
+		items = append(items, applicationLoadBalancerBackendServer{Name: name, IP: ip})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Name == items[j].Name {
+			return items[i].IP < items[j].IP
+		}
+		return items[i].Name < items[j].Name
+	})
+	return items
+}
+
+// resolveApplicationLoadBalancerCertificates は HTTPS リスナーが参照する証明書を秘密鍵とともに読み出す
+func (c *controller) resolveApplicationLoadBalancerCertificates(loadBalancer api.ApplicationLoadBalancer) (map[string]applicationLoadBalancerCertificate, error) {
+	result := make(map[string]applicationLoadBalancerCertificate)
+	for _, listener := range loadBalancer.Spec.Listeners {
+		if listener.CertificateIds == nil {
+			continue
+		}
+		for _, id := range *listener.CertificateIds {
+			id = strings.TrimSpace(id)
+			if _, ok := result[id]; ok || id == "" {
 				continue
 			}
-			name := strings.TrimSpace(server.Metadata.Name)
-			if name == "" {
-				name = strings.TrimSpace(api.ServerID(server))
+			cert, err := c.db.GetCertificateById(id)
+			if err != nil {
+				return nil, fmt.Errorf("listener %s: certificate %s: %w", listener.Name, id, err)
 			}
-			if name == "" {
-				continue
+			result[id] = applicationLoadBalancerCertificate{
+				ID:          id,
+				Fingerprint: util.OrDefault(cert.Fingerprint, ""),
+				PEM:         strings.TrimSpace(cert.Certificate) + "\n" + strings.TrimSpace(util.OrDefault(cert.PrivateKey, "")) + "\n",
 			}
-			items = append(items, applicationLoadBalancerBackendServer{Name: name, IP: ip})
 		}
-		sort.SliceStable(items, func(i, j int) bool {
-			if items[i].Name == items[j].Name {
-				return items[i].IP < items[j].IP
-			}
-			return items[i].Name < items[j].Name
-		})
-		result[listenerName] = items
 	}
 	return result, nil
 }
@@ -445,6 +495,15 @@ func applicationLoadBalancerBackendAvailabilityMessage(loadBalancer api.Applicat
 		if len(listenerBackends[name]) == 0 {
 			missing = append(missing, name)
 		}
+		if listener.Rules == nil {
+			continue
+		}
+		for _, rule := range *listener.Rules {
+			key := applicationLoadBalancerBackendKey(name, rule.Name)
+			if len(listenerBackends[key]) == 0 {
+				missing = append(missing, key)
+			}
+		}
 	}
 	if len(missing) == 0 {
 		return ""
@@ -491,6 +550,9 @@ func (c *controller) cleanupApplicationLoadBalancerDesiredConfig(loadBalancerID
 	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
 		slog.Warn("failed to remove load balancer desired config file", "id", loadBalancerID, "path", path, "err", err)
 	}
+	if err := os.RemoveAll(applicationLoadBalancerCertificateSourceDir(path)); err != nil {
+		slog.Warn("failed to remove load balancer certificates", "id", loadBalancerID, "err", err)
+	}
 }
 
 func (c *controller) ensureApplicationLoadBalancerManagedServerLabel(loadBalancerID string, serverID string) error {
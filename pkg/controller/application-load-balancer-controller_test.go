@@ -79,7 +79,7 @@ func TestLoadBalancerControllerStateTransitions(t *testing.T) {
 	if err != nil {
 		t.Fatalf("resolveApplicationLoadBalancerListenerBackends() failed: %v", err)
 	}
-	desiredHash, err = desiredApplicationLoadBalancerConfigHash(afterProvisioning, listenerBackends)
+	desiredHash, err = desiredApplicationLoadBalancerConfigHash(afterProvisioning, listenerBackends, nil)
 	if err != nil {
 		t.Fatalf("desiredApplicationLoadBalancerConfigHash() failed: %v", err)
 	}
@@ -327,7 +327,7 @@ func TestLoadBalancerControllerWaitsForNewerAgentApplyResult(t *testing.T) {
 	if err != nil {
 		t.Fatalf("resolveApplicationLoadBalancerListenerBackends() failed: %v", err)
 	}
-	desiredHash, err = desiredApplicationLoadBalancerConfigHash(configuring, listenerBackends)
+	desiredHash, err = desiredApplicationLoadBalancerConfigHash(configuring, listenerBackends, nil)
 	if err != nil {
 		t.Fatalf("desiredApplicationLoadBalancerConfigHash() failed: %v", err)
 	}
@@ -455,7 +455,7 @@ func TestLoadBalancerControllerRecoversAfterConsecutiveAgentReadSuccesses(t *tes
 	if err != nil {
 		t.Fatalf("resolveApplicationLoadBalancerListenerBackends() failed: %v", err)
 	}
-	desiredHash, err = desiredApplicationLoadBalancerConfigHash(configuring, listenerBackends)
+	desiredHash, err = desiredApplicationLoadBalancerConfigHash(configuring, listenerBackends, nil)
 	if err != nil {
 		t.Fatalf("desiredApplicationLoadBalancerConfigHash() failed: %v", err)
 	}
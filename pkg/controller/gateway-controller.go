@@ -42,20 +42,14 @@ func StartGatewayController(node string, etcdUrl string) (*controller, error) {
 	c.stopChan = make(chan struct{})
 	c.doneChan = make(chan struct{})
 
-	ticker := time.NewTicker(GATEWAY_CONTROLLER_INTERVAL)
-	go func() {
-		defer ticker.Stop()
-		defer close(c.doneChan)
-		for {
-			select {
-			case <-ticker.C:
-				c.gatewayControllerLoop()
-			case <-c.stopChan:
-				slog.Debug("ゲートウェイコントローラー停止")
-				return
-			}
-		}
-	}()
+	// 変更の監視と定期実行の開始
+	keyedWatchLoop{
+		name:      "ゲートウェイコントローラー",
+		resync:    GATEWAY_CONTROLLER_INTERVAL,
+		primary:   db.GatewayPrefix,
+		related:   []string{db.ServerPrefix},
+		reconcile: c.reconcileGatewayById,
+	}.run(c.db, c.stopChan, c.doneChan)
 
 	return &c, nil
 }
@@ -70,56 +64,74 @@ func (c *controller) gatewayControllerLoop() {
 	}
 
 	for _, gateway := range gateways {
-		gatewayID := api.GatewayID(gateway)
-		if ok, assignedNode, reason := evaluateNodeAssignment(&gateway.Metadata, c.marmot.NodeName); !ok {
-			slog.Debug("別ノード割当のゲートウェイをスキップ", "gatewayId", gatewayID, "gatewayName", gateway.Metadata.Name, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
-			continue
-		}
+		c.reconcileGateway(gateway)
+	}
+}
 
-		if gateway.Status == nil || gateway.Status.StatusCode != db.GATEWAY_DELETING {
-			missingInternalServer, err := c.isGatewayInternalServerMissing(gateway)
-			if err != nil {
-				slog.Warn("failed to validate gateway internal server", "gatewayId", gatewayID, "err", err)
-				continue
-			}
-			if missingInternalServer {
-				c.deleteGatewayForMissingInternalServer(gateway)
-				continue
-			}
+// reconcileGatewayById は ID のゲートウェイを読み直して調整する。削除済みなら何もしない
+func (c *controller) reconcileGatewayById(id string) reconcileResult {
+	gateway, err := c.db.GetGatewayById(id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return reconcileResult{}
 		}
+		slog.Error("GetGatewayById() failed", "id", id, "err", err)
+		return reconcileResult{retry: true}
+	}
+	return c.reconcileGateway(gateway)
+}
 
-		if gateway.Status == nil {
-			if err := c.db.UpdateGatewayStatusWithMessage(gatewayID, db.GATEWAY_PENDING, ""); err != nil {
-				slog.Warn("UpdateGatewayStatusWithMessage() failed", "gatewayId", gatewayID, "err", err)
-			}
-			continue
+func (c *controller) reconcileGateway(gateway api.Gateway) reconcileResult {
+	gatewayID := api.GatewayID(gateway)
+	if ok, assignedNode, reason := evaluateNodeAssignment(&gateway.Metadata, c.marmot.NodeName); !ok {
+		slog.Debug("別ノード割当のゲートウェイをスキップ", "gatewayId", gatewayID, "gatewayName", gateway.Metadata.Name, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
+		return reconcileResult{}
+	}
+
+	if gateway.Status == nil || gateway.Status.StatusCode != db.GATEWAY_DELETING {
+		missingInternalServer, err := c.isGatewayInternalServerMissing(gateway)
+		if err != nil {
+			slog.Warn("failed to validate gateway internal server", "gatewayId", gatewayID, "err", err)
+			return reconcileResult{retry: true}
 		}
+		if missingInternalServer {
+			c.deleteGatewayForMissingInternalServer(gateway)
+			return reconcileResult{}
+		}
+	}
 
-		if gateway.Status.DeletionTimeStamp != nil && time.Since(*gateway.Status.DeletionTimeStamp) > c.deletionDelay {
-			if gateway.Status.StatusCode != db.GATEWAY_DELETING {
-				if err := c.db.UpdateGatewayStatusWithMessage(gatewayID, db.GATEWAY_DELETING, ""); err != nil {
-					slog.Warn("UpdateGatewayStatusWithMessage() failed", "gatewayId", gatewayID, "err", err)
-				}
-			}
+	if gateway.Status == nil {
+		if err := c.db.UpdateGatewayStatusWithMessage(gatewayID, db.GATEWAY_PENDING, ""); err != nil {
+			slog.Warn("UpdateGatewayStatusWithMessage() failed", "gatewayId", gatewayID, "err", err)
 		}
+		return reconcileResult{}
+	}
 
-		switch gateway.Status.StatusCode {
-		case db.GATEWAY_PENDING:
-			c.reconcileGatewayPending(gateway)
-		case db.GATEWAY_PROVISIONING:
-			c.reconcileGatewayProvisioning(gateway)
-		case db.GATEWAY_CONFIGURING:
-			c.reconcileGatewayConfiguring(gateway)
-		case db.GATEWAY_ACTIVE:
-			c.reconcileGatewayActive(gateway)
-		case db.GATEWAY_DELETING:
-			c.reconcileGatewayDeleting(gateway)
-		case db.GATEWAY_FAILED:
-			slog.Debug("FAILED 状態のゲートウェイを検出", "gatewayId", gatewayID)
-		default:
-			slog.Warn("不明なゲートウェイ状態", "gatewayId", gatewayID, "statusCode", gateway.Status.StatusCode)
+	if gateway.Status.DeletionTimeStamp != nil && time.Since(*gateway.Status.DeletionTimeStamp) > c.deletionDelay {
+		if gateway.Status.StatusCode != db.GATEWAY_DELETING {
+			if err := c.db.UpdateGatewayStatusWithMessage(gatewayID, db.GATEWAY_DELETING, ""); err != nil {
+				slog.Warn("UpdateGatewayStatusWithMessage() failed", "gatewayId", gatewayID, "err", err)
+			}
 		}
 	}
+
+	switch gateway.Status.StatusCode {
+	case db.GATEWAY_PENDING:
+		c.reconcileGatewayPending(gateway)
+	case db.GATEWAY_PROVISIONING:
+		c.reconcileGatewayProvisioning(gateway)
+	case db.GATEWAY_CONFIGURING:
+		c.reconcileGatewayConfiguring(gateway)
+	case db.GATEWAY_ACTIVE:
+		c.reconcileGatewayActive(gateway)
+	case db.GATEWAY_DELETING:
+		c.reconcileGatewayDeleting(gateway)
+	case db.GATEWAY_FAILED:
+		slog.Debug("FAILED 状態のゲートウェイを検出", "gatewayId", gatewayID)
+	default:
+		slog.Warn("不明なゲートウェイ状態", "gatewayId", gatewayID, "statusCode", gateway.Status.StatusCode)
+	}
+	return reconcileResult{}
 }
 
 func (c *controller) isGatewayInternalServerMissing(gateway api.Gateway) (bool, error) {
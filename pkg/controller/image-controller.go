@@ -2,10 +2,10 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
-	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -44,153 +44,160 @@ func StartImageController(node string, etcdUrl string, deletionDelaySeconds int)
 	c.stopChan = make(chan struct{})
 	c.doneChan = make(chan struct{})
 
-	// 定期実行の開始
-	ticker := time.NewTicker(IMAGE_CONTROLLER_INTERVAL)
-	go func() {
-		defer ticker.Stop()
-		defer close(c.doneChan)
-		for {
-			select {
-			case <-ticker.C:
-				c.imageControllerLoop()
-			case <-c.stopChan:
-				slog.Debug("イメージコントローラー停止")
-				return
-			}
-		}
-	}()
+	// 変更の監視と定期実行の開始
+	keyedWatchLoop{
+		name:      "イメージコントローラー",
+		resync:    IMAGE_CONTROLLER_INTERVAL,
+		primary:   db.ImagePrefix,
+		reconcile: c.reconcileImageById,
+	}.run(c.db, c.stopChan, c.doneChan)
 	return &c, nil
 }
 
-// コントローラーの制御ループ
-func (c *controller) imageControllerLoop() {
-	slog.Debug("イメージコントローラーの制御ループ実行", "CONTROLLER", time.Now().Format(time.DateTime))
-
-	imgaes, err := c.marmot.GetImagesManage()
+// reconcileImageById は ID のイメージを読み直して調整する。削除済みなら何もしない
+func (c *controller) reconcileImageById(id string) reconcileResult {
+	image, err := c.db.GetImage(id)
 	if err != nil {
-		slog.Error("failed to get images", "err", err)
-		return
+		if errors.Is(err, db.ErrNotFound) {
+			return reconcileResult{}
+		}
+		slog.Error("GetImage() failed", "id", id, "err", err)
+		return reconcileResult{retry: true}
 	}
+	return c.reconcileImage(image)
+}
 
-	for _, image := range imgaes {
-		if ok, assignedNode, reason := evaluateNodeAssignment(&image.Metadata, c.marmot.NodeName); !ok {
-			objectName := image.Metadata.Name
-			slog.Debug("別ノード割当のイメージをスキップ", "imageId", image.Metadata.Id, "imageName", objectName, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
-			continue
-		}
+func (c *controller) reconcileImage(image api.Image) reconcileResult {
+	if ok, assignedNode, reason := evaluateNodeAssignment(&image.Metadata, c.marmot.NodeName); !ok {
+		objectName := image.Metadata.Name
+		slog.Debug("別ノード割当のイメージをスキップ", "imageId", image.Metadata.Id, "imageName", objectName, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
+		return reconcileResult{}
+	}
 
-		// 削除タイムスタンプの処理
-		// 削除のタイムスタンプが一定時間以上経過しているかをチェックして、削除処理を実行する
-		if image.Status != nil && image.Status.DeletionTimeStamp != nil {
-			deletionTime := *image.Status.DeletionTimeStamp
-			if time.Since(deletionTime) > c.deletionDelay {
-				slog.Debug("削除のタイムスタンプが一定時間以上経過しているイメージ検出", "IMAGE", image.Metadata.Name)
-				if dbErr := c.marmot.Db.UpdateImageStatus(image.Metadata.Id, db.IMAGE_DELETING); dbErr != nil {
-					slog.Error("UpdateImageStatus() failed", "imageId", image.Metadata.Id, "err", dbErr)
-				}
+	// 削除タイムスタンプの処理
+	// 削除のタイムスタンプが一定時間以上経過しているかをチェックして、削除処理を実行する
+	if image.Status != nil && image.Status.DeletionTimeStamp != nil {
+		deletionTime := *image.Status.DeletionTimeStamp
+		if time.Since(deletionTime) > c.deletionDelay {
+			slog.Debug("削除のタイムスタンプが一定時間以上経過しているイメージ検出", "IMAGE", image.Metadata.Name)
+			if dbErr := c.marmot.Db.UpdateImageStatus(image.Metadata.Id, db.IMAGE_DELETING); dbErr != nil {
+				slog.Error("UpdateImageStatus() failed", "imageId", image.Metadata.Id, "err", dbErr)
 			}
 		}
+	}
 
-		//jsonBytes, err := json.MarshalIndent(image, "", "    ")
-		//if err != nil {
-		//	slog.Error("failed to marshal image", "err", err)
-		//	continue
-		//}
-		//fmt.Println("details", string(jsonBytes))
-		slog.Debug("イメージの状態を確認", "image", image.Metadata.Name, "state", db.ImageStatus[image.Status.StatusCode])
-
-		// イメージの状態に応じた処理
-		switch image.Status.StatusCode {
-		case db.IMAGE_PENDING:
-			slog.Debug("イメージの作成処理を実行", "image", image.Metadata.Name)
-			if err := c.ensureFollowerImagesWaiting(image); err != nil {
-				slog.Error("フォロワー用イメージエントリーの作成に失敗", "headImageId", image.Metadata.Id, "err", err)
-			}
-			source := ""
-			if image.Metadata.Labels != nil {
-				source = db.GetImageSource(*image.Metadata.Labels)
-			}
+	//jsonBytes, err := json.MarshalIndent(image, "", "    ")
+	//if err != nil {
+	//	slog.Error("failed to marshal image", "err", err)
+	//	return reconcileResult{}
+	//}
+	//fmt.Println("details", string(jsonBytes))
+	slog.Debug("イメージの状態を確認", "image", image.Metadata.Name, "state", db.ImageStatus[image.Status.StatusCode])
+
+	// イメージの状態に応じた処理
+	switch image.Status.StatusCode {
+	case db.IMAGE_PENDING:
+		slog.Debug("イメージの作成処理を実行", "image", image.Metadata.Name)
+		if err := c.ensureFollowerImagesWaiting(image); err != nil {
+			slog.Error("フォロワー用イメージエントリーの作成に失敗", "headImageId", image.Metadata.Id, "err", err)
+		}
+		source := ""
+		if image.Metadata.Labels != nil {
+			source = db.GetImageSource(*image.Metadata.Labels)
+		}
 
-			// bootVolume 由来のイメージは、インポート済み扱いにせず必ずVMコピー処理へ進める。
-			if source != "bootVolume" && image.Spec.SourceUrl == nil && strings.TrimSpace(util.OrDefault(image.Spec.Qcow2Path, "")) != "" {
-				slog.Debug("インポート済みQCOW2イメージを利用可能に遷移", "image", image.Metadata.Name, "imageId", image.Metadata.Id)
-				if dbErr := c.marmot.Db.UpdateImageStatusMessage(image.Metadata.Id, db.IMAGE_AVAILABLE, ""); dbErr != nil {
-					slog.Error("UpdateImageStatusMessage() failed", "imageId", image.Metadata.Id, "err", dbErr)
-				}
-				continue
+		// bootVolume 由来のイメージは、インポート済み扱いにせず必ずVMコピー処理へ進める。
+		if source != "bootVolume" && image.Spec.SourceUrl == nil && strings.TrimSpace(util.OrDefault(image.Spec.Qcow2Path, "")) != "" {
+			slog.Debug("インポート済みQCOW2イメージを利用可能に遷移", "image", image.Metadata.Name, "imageId", image.Metadata.Id)
+			if dbErr := c.marmot.Db.UpdateImageStatusMessage(image.Metadata.Id, db.IMAGE_AVAILABLE, ""); dbErr != nil {
+				slog.Error("UpdateImageStatusMessage() failed", "imageId", image.Metadata.Id, "err", dbErr)
 			}
-			if dbErr := c.marmot.Db.UpdateImageStatus(image.Metadata.Id, db.IMAGE_CREATING); dbErr != nil {
-				slog.Error("UpdateImageStatus() failed", "imageId", image.Metadata.Id, "err", dbErr)
-				continue
-			}
-			// ラベルの存在をチェック
-			if image.Metadata.Labels != nil {
-				if source == "bootVolume" {
-					slog.Debug("実行中VMからイメージの作成", "image", image.Metadata.Name, "source", "bootVolume")
-					serverId := db.GetImageServerID(*image.Metadata.Labels)
-					go func(image api.Image, serverId string) {
-						timeout := marmotd.CurrentConfig().ImageCreateFromVMTimeout()
-						ctx, cancel := context.WithTimeout(context.Background(), timeout)
-						defer cancel()
-						if _, err := c.marmot.MakeImageEntryFromRunningVMWithContext(ctx, serverId, image.Metadata.Name, image); err != nil {
-							slog.Error("実行中VMからのイメージ作成に失敗", "imageId", image.Metadata.Id, "serverId", serverId, "timeout", timeout, "err", err)
-						}
-					}(image, serverId)
-				} else {
-					slog.Debug("ダウンロードしてイメージの作成", "image", image.Metadata.Name, "source", "url")
-					go func(image api.Image) {
-						timeout := marmotd.CurrentConfig().ImageCreateFromURLTimeout()
-						ctx, cancel := context.WithTimeout(context.Background(), timeout)
-						defer cancel()
-						if _, err := c.marmot.CreateNewImageManageWithContext(ctx, image.Metadata.Id); err != nil {
-							slog.Error("URLからのイメージ作成に失敗", "imageId", image.Metadata.Id, "timeout", timeout, "err", err)
-						}
-					}(image)
-				}
+			return reconcileResult{}
+		}
+		if dbErr := c.marmot.Db.UpdateImageStatus(image.Metadata.Id, db.IMAGE_CREATING); dbErr != nil {
+			slog.Error("UpdateImageStatus() failed", "imageId", image.Metadata.Id, "err", dbErr)
+			return reconcileResult{}
+		}
+		// ラベルの存在をチェック
+		if image.Metadata.Labels != nil {
+			if source == "bootVolume" {
+				slog.Debug("実行中VMからイメージの作成", "image", image.Metadata.Name, "source", "bootVolume")
+				serverId := db.GetImageServerID(*image.Metadata.Labels)
+				go func(image api.Image, serverId string) {
+					timeout := marmotd.CurrentConfig().ImageCreateFromVMTimeout()
+					ctx, cancel := context.WithTimeout(context.Background(), timeout)
+					defer cancel()
+					err := c.marmot.RunWithJob(ctx, imageJobId(image), func(ctx context.Context) error {
+						_, err := c.marmot.MakeImageEntryFromRunningVMWithContext(ctx, serverId, image.Metadata.Name, image)
+						return err
+					})
+					if err != nil {
+						slog.Error("実行中VMからのイメージ作成に失敗", "imageId", image.Metadata.Id, "serverId", serverId, "timeout", timeout, "err", err)
+					}
+				}(image, serverId)
 			} else {
 				slog.Debug("ダウンロードしてイメージの作成", "image", image.Metadata.Name, "source", "url")
 				go func(image api.Image) {
 					timeout := marmotd.CurrentConfig().ImageCreateFromURLTimeout()
 					ctx, cancel := context.WithTimeout(context.Background(), timeout)
 					defer cancel()
-					if _, err := c.marmot.CreateNewImageManageWithContext(ctx, image.Metadata.Id); err != nil {
+					err := c.marmot.RunWithJob(ctx, imageJobId(image), func(ctx context.Context) error {
+						_, err := c.marmot.CreateNewImageManageWithContext(ctx, image.Metadata.Id)
+						return err
+					})
+					if err != nil {
 						slog.Error("URLからのイメージ作成に失敗", "imageId", image.Metadata.Id, "timeout", timeout, "err", err)
 					}
 				}(image)
 			}
-		case db.IMAGE_CREATING:
-			slog.Debug("イメージの作成処理を継続", "image", image.Metadata.Name)
-			// ここにイメージの作成処理の継続を実装
-		case db.IMAGE_WAITING:
-			slog.Debug("イメージはヘッドノード完了待ち", "image", image.Metadata.Name)
-			if err := c.startFollowerSync(image); err != nil {
-				slog.Error("フォロワーイメージの同期開始に失敗", "imageId", image.Metadata.Id, "err", err)
-			}
-		case db.IMAGE_CREATION_FAILED:
-			slog.Debug("イメージの作成に失敗", "image", image.Metadata.Name)
-			// ここにイメージの作成失敗時の処理を実装
-		case db.IMAGE_AVAILABLE:
-			slog.Debug("イメージは利用可能", "image", image.Metadata.Name)
-			if err := marmotd.CheckImageBackingStore(image); err != nil {
-				slog.Warn("AVAILABLE イメージの実体が見つからないため DELETED に更新", "imageId", image.Metadata.Id, "err", err)
-				if dbErr := c.marmot.Db.UpdateImageStatusMessage(image.Metadata.Id, db.IMAGE_DELETED, err.Error()); dbErr != nil {
-					slog.Error("UpdateImageStatusMessage() failed", "imageId", image.Metadata.Id, "err", dbErr)
+		} else {
+			slog.Debug("ダウンロードしてイメージの作成", "image", image.Metadata.Name, "source", "url")
+			go func(image api.Image) {
+				timeout := marmotd.CurrentConfig().ImageCreateFromURLTimeout()
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+				err := c.marmot.RunWithJob(ctx, imageJobId(image), func(ctx context.Context) error {
+					_, err := c.marmot.CreateNewImageManageWithContext(ctx, image.Metadata.Id)
+					return err
+				})
+				if err != nil {
+					slog.Error("URLからのイメージ作成に失敗", "imageId", image.Metadata.Id, "timeout", timeout, "err", err)
 				}
-				continue
-			}
-			if err := c.reconcileFollowerImageSpec(image); err != nil {
-				slog.Warn("フォロワーIMAGEのspec同期に失敗", "imageId", image.Metadata.Id, "err", err)
-			}
-		case db.IMAGE_DELETING:
-			slog.Debug("イメージの削除処理を実行", "image", image.Metadata.Name)
-			err := c.marmot.DeleteImageManage(image.Metadata.Id)
-			if err != nil {
-				slog.Error("DeleteImageById()", "err", err)
+			}(image)
+		}
+	case db.IMAGE_CREATING:
+		slog.Debug("イメージの作成処理を継続", "image", image.Metadata.Name)
+		// ここにイメージの作成処理の継続を実装
+	case db.IMAGE_WAITING:
+		slog.Debug("イメージはヘッドノード完了待ち", "image", image.Metadata.Name)
+		if err := c.startFollowerSync(image); err != nil {
+			slog.Error("フォロワーイメージの同期開始に失敗", "imageId", image.Metadata.Id, "err", err)
+		}
+	case db.IMAGE_CREATION_FAILED:
+		slog.Debug("イメージの作成に失敗", "image", image.Metadata.Name)
+		// ここにイメージの作成失敗時の処理を実装
+	case db.IMAGE_AVAILABLE:
+		slog.Debug("イメージは利用可能", "image", image.Metadata.Name)
+		if err := marmotd.CheckImageBackingStore(image); err != nil {
+			slog.Warn("AVAILABLE イメージの実体が見つからないため DELETED に更新", "imageId", image.Metadata.Id, "err", err)
+			if dbErr := c.marmot.Db.UpdateImageStatusMessage(image.Metadata.Id, db.IMAGE_DELETED, err.Error()); dbErr != nil {
+				slog.Error("UpdateImageStatusMessage() failed", "imageId", image.Metadata.Id, "err", dbErr)
 			}
-		default:
-			slog.Debug("イメージは安定状態", "image", image.Metadata.Name, "state", *image.Status.Status)
+			return reconcileResult{}
+		}
+		if err := c.reconcileFollowerImageSpec(image); err != nil {
+			slog.Warn("フォロワーIMAGEのspec同期に失敗", "imageId", image.Metadata.Id, "err", err)
 		}
+	case db.IMAGE_DELETING:
+		slog.Debug("イメージの削除処理を実行", "image", image.Metadata.Name)
+		err := c.marmot.DeleteImageManage(image.Metadata.Id)
+		if err != nil {
+			slog.Error("DeleteImageById()", "err", err)
+		}
+	default:
+		slog.Debug("イメージは安定状態", "image", image.Metadata.Name, "state", *image.Status.Status)
 	}
+	return reconcileResult{}
 }
 
 func (c *controller) ensureFollowerImagesWaiting(headImage api.Image) error {
@@ -443,21 +450,16 @@ func setIntPtrFromHead(dst **int, src *int) bool {
 	return true
 }
 
-func buildHeadImageDownloadURL(headIP, imageID string) string {
-	port := "8750"
-	apiListenAddr := strings.TrimSpace(marmotd.CurrentConfig().APIListenAddr)
-	if host, p, err := net.SplitHostPort(apiListenAddr); err == nil {
-		if strings.TrimSpace(p) != "" {
-			port = p
-		}
-		_ = host
-	} else if strings.HasPrefix(apiListenAddr, ":") {
-		if p := strings.TrimPrefix(apiListenAddr, ":"); strings.TrimSpace(p) != "" {
-			port = p
-		}
+// イメージ作成を追跡するジョブのID
+func imageJobId(image api.Image) string {
+	if image.Status == nil || image.Status.JobId == nil {
+		return ""
 	}
+	return *image.Status.JobId
+}
 
-	return fmt.Sprintf("http://%s:%s/api/v1/image/%s/qcow2", headIP, port, imageID)
+func buildHeadImageDownloadURL(headIP, imageID string) string {
+	return fmt.Sprintf("%s/image/%s/qcow2", marmotd.NodeAPIBaseURL(headIP), imageID)
 }
 
 func downloadImageFromHeadWithContext(ctx context.Context, sourceURL, destPath string) error {
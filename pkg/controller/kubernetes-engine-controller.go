@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -73,21 +74,14 @@ func StartKubernetesEngineController(node string, etcdUrl string, mkeConfigPath
 	c.stopChan = make(chan struct{})
 	c.doneChan = make(chan struct{})
 
-	// 定期実行の開始（10秒間隔）
-	ticker := time.NewTicker(KUBERNETES_ENGINE_CONTROLLER_INTERVAL)
-	go func() {
-		defer ticker.Stop()
-		defer close(c.doneChan)
-		for {
-			select {
-			case <-ticker.C:
-				c.kubernetesEngineControllerLoop()
-			case <-c.stopChan:
-				slog.Debug("KubernetesEngineコントローラー停止")
-				return
-			}
-		}
-	}()
+	// 変更の監視と定期実行（10秒間隔）の開始
+	keyedWatchLoop{
+		name:      "KubernetesEngineコントローラー",
+		resync:    KUBERNETES_ENGINE_CONTROLLER_INTERVAL,
+		primary:   db.KubernetesEnginePrefix,
+		related:   []string{db.ServerPrefix},
+		reconcile: c.reconcileKubernetesEngineById,
+	}.run(c.db, c.stopChan, c.doneChan)
 	return &c, nil
 }
 
@@ -106,53 +100,54 @@ func (c *kubernetesEngineController) Stop() {
 	}
 }
 
-// kubernetesEngineControllerLoop は /marmot/kubernetes-engine 配下を毎tickポーリングし、
-// 新規作成イベントの検知と状態遷移の駆動を行う（etcd watch APIは未使用、既存コントローラーと
-// 同様のポーリング方式）。
-func (c *kubernetesEngineController) kubernetesEngineControllerLoop() {
-	slog.Debug("KubernetesEngineコントローラーの制御ループ実行", "CONTROLLER", time.Now().Format("2006-01-02 15:04:05"))
-
-	items, err := c.db.GetKubernetesEngines()
+// reconcileKubernetesEngineById は ID のKubernetesEngineを読み直して調整する。削除済みなら何もしない
+func (c *kubernetesEngineController) reconcileKubernetesEngineById(id string) reconcileResult {
+	item, err := c.db.GetKubernetesEngineById(id)
 	if err != nil {
-		slog.Error("GetKubernetesEngines() failed", "err", err)
-		return
+		if errors.Is(err, db.ErrNotFound) {
+			return reconcileResult{}
+		}
+		slog.Error("GetKubernetesEngineById() failed", "id", id, "err", err)
+		return reconcileResult{retry: true}
 	}
+	return c.reconcileKubernetesEngine(item)
+}
 
-	for _, item := range items {
-		id := api.KubernetesEngineID(item)
+func (c *kubernetesEngineController) reconcileKubernetesEngine(item api.KubernetesEngine) reconcileResult {
+	id := api.KubernetesEngineID(item)
 
-		if item.Status == nil {
-			// 生成直後で状態未設定のレコードを検知した場合の雛形（通常は Create 時に PENDING が設定される）。
-			slog.Debug("KubernetesEngineの生成を検知しました", "id", id, "name", item.Metadata.Name)
-			_ = c.db.UpdateKubernetesEngineStatusWithMessage(id, db.KUBERNETES_ENGINE_PENDING, "")
-			continue
-		}
+	if item.Status == nil {
+		// 生成直後で状態未設定のレコードを検知した場合の雛形（通常は Create 時に PENDING が設定される）。
+		slog.Debug("KubernetesEngineの生成を検知しました", "id", id, "name", item.Metadata.Name)
+		_ = c.db.UpdateKubernetesEngineStatusWithMessage(id, db.KUBERNETES_ENGINE_PENDING, "")
+		return reconcileResult{}
+	}
 
-		if item.Status.DeletionTimeStamp != nil {
-			if isKubernetesEngineInGracePeriod(item) {
-				continue
-			}
-			if item.Status.StatusCode != db.KUBERNETES_ENGINE_DELETING {
-				_ = c.db.UpdateKubernetesEngineStatusWithMessage(id, db.KUBERNETES_ENGINE_DELETING, "")
-				continue
-			}
+	if item.Status.DeletionTimeStamp != nil {
+		if isKubernetesEngineInGracePeriod(item) {
+			return reconcileResult{}
 		}
-
-		switch item.Status.StatusCode {
-		case db.KUBERNETES_ENGINE_PENDING:
-			c.reconcileKubernetesEnginePending(item)
-		case db.KUBERNETES_ENGINE_PROVISIONING:
-			c.reconcileKubernetesEngineProvisioning(item)
-		case db.KUBERNETES_ENGINE_RUNNING:
-			c.reconcileKubernetesEngineRunning(item)
-		case db.KUBERNETES_ENGINE_DELETING:
-			c.reconcileKubernetesEngineDeleting(item)
-		case db.KUBERNETES_ENGINE_FAILED:
-			slog.Debug("FAILED 状態のKubernetesEngineを検出", "id", id)
-		default:
-			slog.Warn("不明なKubernetesEngine状態", "id", id, "statusCode", item.Status.StatusCode)
+		if item.Status.StatusCode != db.KUBERNETES_ENGINE_DELETING {
+			_ = c.db.UpdateKubernetesEngineStatusWithMessage(id, db.KUBERNETES_ENGINE_DELETING, "")
+			return reconcileResult{}
 		}
 	}
+
+	switch item.Status.StatusCode {
+	case db.KUBERNETES_ENGINE_PENDING:
+		c.reconcileKubernetesEnginePending(item)
+	case db.KUBERNETES_ENGINE_PROVISIONING:
+		c.reconcileKubernetesEngineProvisioning(item)
+	case db.KUBERNETES_ENGINE_RUNNING:
+		c.reconcileKubernetesEngineRunning(item)
+	case db.KUBERNETES_ENGINE_DELETING:
+		c.reconcileKubernetesEngineDeleting(item)
+	case db.KUBERNETES_ENGINE_FAILED:
+		slog.Debug("FAILED 状態のKubernetesEngineを検出", "id", id)
+	default:
+		slog.Warn("不明なKubernetesEngine状態", "id", id, "statusCode", item.Status.StatusCode)
+	}
+	return reconcileResult{}
 }
 
 // reconcileKubernetesEnginePending はクラスタ専用ネットワークを作成した上で PROVISIONING に進める。
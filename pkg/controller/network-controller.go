@@ -61,26 +61,23 @@ func StartNetController(node string, etcdUrl string, deletionDelaySeconds int) (
 		return nil, err
 	}
 
-	// 定期実行の開始
-	ticker := time.NewTicker(NETWORK_CONTROLLER_INTERVAL)
-	go func() {
-		defer ticker.Stop()
-		defer close(c.doneChan)
-		for {
-			select {
-			case <-ticker.C:
-				c.networkControllerLoop(networkFabric)
-			case <-c.stopChan:
-				slog.Debug("ネットワークコントローラー停止")
-				return
-			}
-		}
-	}()
+	// 変更の監視と定期実行の開始
+	keyedWatchLoop{
+		name:    "ネットワークコントローラー",
+		resync:  NETWORK_CONTROLLER_INTERVAL,
+		primary: db.NetworkPrefix,
+		related: []string{db.ServerPrefix},
+		prepare: c.prepareNetworkControllerLoop,
+		reconcile: func(id string) reconcileResult {
+			return c.reconcileVirtualNetworkById(id, networkFabric)
+		},
+	}.run(c.db, c.stopChan, c.doneChan)
 	return &c, nil
 }
 
-// コントローラーの制御ループ
-func (c *controller) networkControllerLoop(fabric networkfabric.NetworkFabric) {
+// prepareNetworkControllerLoop は仮想ネットワークごとの調整の前に、ネットワークをまたがる処理を行う
+// 既存の仮想ネットワークの登録と、クラスターのメンバー変更・VXLAN ハブの引き継ぎを反映する
+func (c *controller) prepareNetworkControllerLoop() {
 	slog.Debug("ネットワークコントローラーの制御ループ実行", "CONTROLLER", time.Now().Format("2006-01-02 15:04:05"))
 
 	// 既存の仮想ネットワークを取得して、データベースに登録する
@@ -124,179 +121,186 @@ func (c *controller) networkControllerLoop(fabric networkfabric.NetworkFabric) {
 			if err := c.reconcileVxlanHubFailovers(vnets, statuses); err != nil {
 				slog.Warn("failed to reconcile vxlan hub failovers", "err", err)
 			}
-			refreshed, refreshErr := c.marmot.GetVirtualNetwork()
-			if refreshErr != nil {
-				slog.Warn("failed to refresh virtual networks after hub failover reconciliation", "err", refreshErr)
-			} else {
-				vnets = refreshed
-			}
 		}
 	}
+}
 
-	for _, vnet := range vnets {
-		vnetID := api.VirtualNetworkID(vnet)
-		if ok, assignedNode, reason := evaluateNodeAssignment(&vnet.Metadata, c.marmot.NodeName); !ok {
-			objectName := vnet.Metadata.Name
-			slog.Debug("別ノード割当の仮想ネットワークをスキップ", "networkId", vnetID, "networkName", objectName, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
-			continue
+// reconcileVirtualNetworkById は ID の仮想ネットワークを読み直して調整する。削除済みなら何もしない
+func (c *controller) reconcileVirtualNetworkById(id string, fabric networkfabric.NetworkFabric) reconcileResult {
+	vnet, err := c.db.GetVirtualNetworkById(id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return reconcileResult{}
 		}
+		slog.Error("GetVirtualNetworkById() failed", "id", id, "err", err)
+		return reconcileResult{retry: true}
+	}
+	return c.reconcileVirtualNetwork(vnet, fabric)
+}
 
-		role := networkSyncRole(&vnet.Metadata)
-
-		// 削除タイムスタンプが設定されて一定時間経過した仮想ネットワークを削除処理へ進める。
-		// 依存リソースが残っている場合は DEL-PENDING で待機し、依存解消後に DELETING へ遷移する。
-		// ERROR 状態でも削除要求を優先し、削除フローへ進める。
-		if vnet.Status != nil && vnet.Status.DeletionTimeStamp != nil {
-			if err := c.distributeDeleteIntentToSameNameNetworks(vnet); err != nil {
-				slog.Error("同名ネットワークへの削除意図配布に失敗", "networkId", vnetID, "err", err)
-			}
-			deletionTime := *vnet.Status.DeletionTimeStamp
-			if time.Since(deletionTime) > c.deletionDelay {
-				deps, depErr := c.collectDeleteBlockingDependencies(vnet)
-				if depErr != nil {
-					slog.Warn("依存リソース判定に失敗したためネットワーク削除を保留", "networkId", vnetID, "err", depErr)
-				} else if deps.hasAny() {
-					c.marmot.Db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_DEL_PENDING, deps.statusMessage())
-					vnet.Status.StatusCode = db.NETWORK_DEL_PENDING
-					slog.Debug("依存リソースが残っているためネットワーク削除を待機", "networkId", vnetID, "dependents", deps.statusMessage())
-				} else {
-					slog.Debug("削除のタイムスタンプが一定時間以上経過し依存リソースも存在しないため削除を開始", "networkId", vnetID)
-					c.marmot.Db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_DELETING)
-					vnet.Status.StatusCode = db.NETWORK_DELETING
-				}
+func (c *controller) reconcileVirtualNetwork(vnet api.VirtualNetwork, fabric networkfabric.NetworkFabric) reconcileResult {
+	vnetID := api.VirtualNetworkID(vnet)
+	if ok, assignedNode, reason := evaluateNodeAssignment(&vnet.Metadata, c.marmot.NodeName); !ok {
+		objectName := vnet.Metadata.Name
+		slog.Debug("別ノード割当の仮想ネットワークをスキップ", "networkId", vnetID, "networkName", objectName, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
+		return reconcileResult{}
+	}
+
+	role := networkSyncRole(&vnet.Metadata)
+
+	// 削除タイムスタンプが設定されて一定時間経過した仮想ネットワークを削除処理へ進める。
+	// 依存リソースが残っている場合は DEL-PENDING で待機し、依存解消後に DELETING へ遷移する。
+	// ERROR 状態でも削除要求を優先し、削除フローへ進める。
+	if vnet.Status != nil && vnet.Status.DeletionTimeStamp != nil {
+		if err := c.distributeDeleteIntentToSameNameNetworks(vnet); err != nil {
+			slog.Error("同名ネットワークへの削除意図配布に失敗", "networkId", vnetID, "err", err)
+		}
+		deletionTime := *vnet.Status.DeletionTimeStamp
+		if time.Since(deletionTime) > c.deletionDelay {
+			deps, depErr := c.collectDeleteBlockingDependencies(vnet)
+			if depErr != nil {
+				slog.Warn("依存リソース判定に失敗したためネットワーク削除を保留", "networkId", vnetID, "err", depErr)
+			} else if deps.hasAny() {
+				c.marmot.Db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_DEL_PENDING, deps.statusMessage())
+				vnet.Status.StatusCode = db.NETWORK_DEL_PENDING
+				slog.Debug("依存リソースが残っているためネットワーク削除を待機", "networkId", vnetID, "dependents", deps.statusMessage())
+			} else {
+				slog.Debug("削除のタイムスタンプが一定時間以上経過し依存リソースも存在しないため削除を開始", "networkId", vnetID)
+				c.marmot.Db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_DELETING)
+				vnet.Status.StatusCode = db.NETWORK_DELETING
 			}
 		}
-		//fmt.Println("======================================================")
-		//fmt.Println("仮想ネットワーク: ", "ID=", vnet.Id)
-		//if strings.TrimSpace(vnet.Metadata.Name) != "" {
-		//	fmt.Println("ネットワーク 名前=", vnet.Metadata.Name)
-		//}
-		//byte, err := json.MarshalIndent(vnet, "", "  ")
-		//if err != nil {
-		//	slog.Error("failed to marshal virtual network", "err", err)
-		//} else {
-		//	fmt.Println("仮想ネットワークのJSON情報", "json", string(byte))
-		//}
-		//fmt.Println("======================================================")
-
-		if vnet.Status != nil && vnet.Status.Status != nil {
-			switch vnet.Status.StatusCode {
-			case db.NETWORK_PENDING:
-				if role == "follower" {
-					c.db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_WAITING)
-					continue
-				}
-				slog.Debug("待ち状態の仮想ネットワークを処理", "networkId", vnetID)
-				if err := c.ensureFollowerNetworksWaiting(vnet); err != nil {
-					slog.Error("フォロワー用ネットワークエントリーの作成に失敗", "headNetworkId", vnetID, "err", err)
+	}
+	//fmt.Println("======================================================")
+	//fmt.Println("仮想ネットワーク: ", "ID=", vnet.Id)
+	//if strings.TrimSpace(vnet.Metadata.Name) != "" {
+	//	fmt.Println("ネットワーク 名前=", vnet.Metadata.Name)
+	//}
+	//byte, err := json.MarshalIndent(vnet, "", "  ")
+	//if err != nil {
+	//	slog.Error("failed to marshal virtual network", "err", err)
+	//} else {
+	//	fmt.Println("仮想ネットワークのJSON情報", "json", string(byte))
+	//}
+	//fmt.Println("======================================================")
+
+	if vnet.Status != nil && vnet.Status.Status != nil {
+		switch vnet.Status.StatusCode {
+		case db.NETWORK_PENDING:
+			if role == "follower" {
+				c.db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_WAITING)
+				return reconcileResult{}
+			}
+			slog.Debug("待ち状態の仮想ネットワークを処理", "networkId", vnetID)
+			if err := c.ensureFollowerNetworksWaiting(vnet); err != nil {
+				slog.Error("フォロワー用ネットワークエントリーの作成に失敗", "headNetworkId", vnetID, "err", err)
+			}
+			if err := c.reconcileHeadProvisioningNetwork(vnet, fabric); err != nil {
+				slog.Error("head network provisioning failed", "networkId", vnetID, "err", err)
+				c.db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_ERROR, err.Error())
+				return reconcileResult{}
+			}
+
+		case db.NETWORK_PROVISIONING:
+			slog.Debug("プロビジョニング中の仮想ネットワークを処理", "networkId", vnetID)
+			if role == "follower" {
+				if err := c.reconcileFollowerWaitingNetwork(vnet, fabric); err != nil {
+					slog.Error("フォロワーネットワークのプロビジョニング継続に失敗", "networkId", vnetID, "err", err)
+					c.db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_ERROR)
 				}
+			} else {
 				if err := c.reconcileHeadProvisioningNetwork(vnet, fabric); err != nil {
-					slog.Error("head network provisioning failed", "networkId", vnetID, "err", err)
+					slog.Error("head network provisioning resume failed", "networkId", vnetID, "err", err)
 					c.db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_ERROR, err.Error())
-					continue
-				}
-
-			case db.NETWORK_PROVISIONING:
-				slog.Debug("プロビジョニング中の仮想ネットワークを処理", "networkId", vnetID)
-				if role == "follower" {
-					if err := c.reconcileFollowerWaitingNetwork(vnet, fabric); err != nil {
-						slog.Error("フォロワーネットワークのプロビジョニング継続に失敗", "networkId", vnetID, "err", err)
-						c.db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_ERROR)
-					}
-				} else {
-					if err := c.reconcileHeadProvisioningNetwork(vnet, fabric); err != nil {
-						slog.Error("head network provisioning resume failed", "networkId", vnetID, "err", err)
-						c.db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_ERROR, err.Error())
-					}
 				}
+			}
 
-			case db.NETWORK_DELETING:
-				slog.Debug("削除中の仮想ネットワークを処理", "networkId", vnetID)
-				if role == "follower" {
-					// フォロワー: libvirt destroy/undefine と fabric cleanup
-					if err := c.ensureVirtualNetworkAbsent(vnet); err != nil {
-						slog.Error("failed to delete virtual network on follower node", "err", err, "networkId", vnetID, "controllerNode", c.marmot.NodeName)
-						c.db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_ERROR, "fabric:detach-failed:"+err.Error())
-						continue
-					}
-					// fabric cleanup（ブリッジ削除）
-					if err := fabric.DeleteBridge(&vnet); err != nil {
-						slog.Warn("failed to delete bridge on follower, continuing", "networkId", vnetID, "err", err)
-						// ブリッジ削除失敗は WARNING レベル、DB 削除は続行
-					}
-					if err := c.db.DeleteVirtualNetworkById(vnetID); err != nil {
-						slog.Error("failed to delete follower network object from DB", "err", err, "networkId", vnetID)
-						c.db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_ERROR)
-					}
-					continue
+		case db.NETWORK_DELETING:
+			slog.Debug("削除中の仮想ネットワークを処理", "networkId", vnetID)
+			if role == "follower" {
+				// フォロワー: libvirt destroy/undefine と fabric cleanup
+				if err := c.ensureVirtualNetworkAbsent(vnet); err != nil {
+					slog.Error("failed to delete virtual network on follower node", "err", err, "networkId", vnetID, "controllerNode", c.marmot.NodeName)
+					c.db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_ERROR, "fabric:detach-failed:"+err.Error())
+					return reconcileResult{}
 				}
-				// ヘッド: libvirt destroy/undefine → fabric cleanup → DB 削除
-				if err := c.marmot.DeleteVirtualNetwork(vnetID); err != nil {
-					slog.Error("DeleteVirtualNetwork()", "err", err)
-					c.db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_ERROR, "libvirt:delete-failed:"+err.Error())
-					continue
-				}
-				// fabric cleanup
+				// fabric cleanup（ブリッジ削除）
 				if err := fabric.DeleteBridge(&vnet); err != nil {
-					slog.Warn("failed to delete bridge on head, continuing", "networkId", vnetID, "err", err)
+					slog.Warn("failed to delete bridge on follower, continuing", "networkId", vnetID, "err", err)
+					// ブリッジ削除失敗は WARNING レベル、DB 削除は続行
 				}
-				slog.Debug("仮想ネットワークの削除成功", "networkId", vnetID)
-			case db.NETWORK_DEL_PENDING:
-				slog.Debug("依存リソースの削除待ち状態の仮想ネットワークを処理", "networkId", vnetID)
-			case db.NETWORK_ERROR:
-				slog.Debug("エラー状態の仮想ネットワークを処理", "networkId", vnetID)
-				// ERROR 状態は保持する。削除要求（DeletionTimeStamp）が入った場合のみ削除意図を伝播する。
-				if role != "follower" && vnet.Status != nil && vnet.Status.DeletionTimeStamp != nil {
-					if err := c.distributeDeleteIntentToFollowerNetworks(vnet); err != nil {
-						slog.Error("failed to distribute delete intent to follower networks", "headNetworkId", vnetID, "err", err)
-					}
-					continue
+				if err := c.db.DeleteVirtualNetworkById(vnetID); err != nil {
+					slog.Error("failed to delete follower network object from DB", "err", err, "networkId", vnetID)
+					c.db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_ERROR)
 				}
-
-				if role == "follower" {
-					if err := c.recoverFollowerErrorNetwork(vnet, fabric); err != nil {
-						slog.Warn("failed to auto-recover follower network from error", "networkId", vnetID, "err", err)
-						continue
-					}
-				} else {
-					if err := c.recoverHeadErrorNetwork(vnet, fabric); err != nil {
-						slog.Warn("failed to auto-recover head network from error", "networkId", vnetID, "err", err)
-						continue
-					}
+				return reconcileResult{}
+			}
+			// ヘッド: libvirt destroy/undefine → fabric cleanup → DB 削除
+			if err := c.marmot.DeleteVirtualNetwork(vnetID); err != nil {
+				slog.Error("DeleteVirtualNetwork()", "err", err)
+				c.db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_ERROR, "libvirt:delete-failed:"+err.Error())
+				return reconcileResult{}
+			}
+			// fabric cleanup
+			if err := fabric.DeleteBridge(&vnet); err != nil {
+				slog.Warn("failed to delete bridge on head, continuing", "networkId", vnetID, "err", err)
+			}
+			slog.Debug("仮想ネットワークの削除成功", "networkId", vnetID)
+		case db.NETWORK_DEL_PENDING:
+			slog.Debug("依存リソースの削除待ち状態の仮想ネットワークを処理", "networkId", vnetID)
+		case db.NETWORK_ERROR:
+			slog.Debug("エラー状態の仮想ネットワークを処理", "networkId", vnetID)
+			// ERROR 状態は保持する。削除要求（DeletionTimeStamp）が入った場合のみ削除意図を伝播する。
+			if role != "follower" && vnet.Status != nil && vnet.Status.DeletionTimeStamp != nil {
+				if err := c.distributeDeleteIntentToFollowerNetworks(vnet); err != nil {
+					slog.Error("failed to distribute delete intent to follower networks", "headNetworkId", vnetID, "err", err)
 				}
+				return reconcileResult{}
+			}
 
-				slog.Debug("network recovered from error", "networkId", vnetID, "networkName", vnet.Metadata.Name, "role", role)
-
-			case db.NETWORK_ACTIVE:
-				slog.Debug("利用可能な仮想ネットワークを処理", "networkId", vnetID)
-				if role == "follower" {
-					if err := c.reconcileFollowerActiveNetwork(vnet, fabric); err != nil {
-						slog.Error("failed to reconcile follower network", "err", err, "networkId", vnetID, "controllerNode", c.marmot.NodeName)
-						c.db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_ERROR)
-					}
-				} else {
-					if err := c.ensureOverlayMeshForNetwork(fabric, vnet); err != nil {
-						slog.Error("failed to reconcile head overlay mesh", "err", err, "networkId", vnetID, "controllerNode", c.marmot.NodeName)
-						c.db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_ERROR, "fabric:overlay-failed:"+err.Error())
-					}
+			if role == "follower" {
+				if err := c.recoverFollowerErrorNetwork(vnet, fabric); err != nil {
+					slog.Warn("failed to auto-recover follower network from error", "networkId", vnetID, "err", err)
+					return reconcileResult{}
 				}
-
-			case db.NETWORK_WAITING:
-				if role != "follower" {
-					continue
+			} else {
+				if err := c.recoverHeadErrorNetwork(vnet, fabric); err != nil {
+					slog.Warn("failed to auto-recover head network from error", "networkId", vnetID, "err", err)
+					return reconcileResult{}
 				}
-				slog.Debug("フォロワーネットワークはヘッドノード完了待ち", "networkId", vnetID)
-				if err := c.reconcileFollowerWaitingNetwork(vnet, fabric); err != nil {
-					slog.Error("フォロワーネットワークの同期開始に失敗", "networkId", vnetID, "err", err)
+			}
+
+			slog.Debug("network recovered from error", "networkId", vnetID, "networkName", vnet.Metadata.Name, "role", role)
+
+		case db.NETWORK_ACTIVE:
+			slog.Debug("利用可能な仮想ネットワークを処理", "networkId", vnetID)
+			if role == "follower" {
+				if err := c.reconcileFollowerActiveNetwork(vnet, fabric); err != nil {
+					slog.Error("failed to reconcile follower network", "err", err, "networkId", vnetID, "controllerNode", c.marmot.NodeName)
 					c.db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_ERROR)
 				}
+			} else {
+				if err := c.ensureOverlayMeshForNetwork(fabric, vnet); err != nil {
+					slog.Error("failed to reconcile head overlay mesh", "err", err, "networkId", vnetID, "controllerNode", c.marmot.NodeName)
+					c.db.UpdateVirtualNetworkStatusWithMessage(vnetID, db.NETWORK_ERROR, "fabric:overlay-failed:"+err.Error())
+				}
+			}
 
-			default:
-				slog.Warn("不明なステータスの仮想ネットワークをスキップ", "networkId", vnetID, "status", *vnet.Status.Status)
+		case db.NETWORK_WAITING:
+			if role != "follower" {
+				return reconcileResult{}
+			}
+			slog.Debug("フォロワーネットワークはヘッドノード完了待ち", "networkId", vnetID)
+			if err := c.reconcileFollowerWaitingNetwork(vnet, fabric); err != nil {
+				slog.Error("フォロワーネットワークの同期開始に失敗", "networkId", vnetID, "err", err)
+				c.db.UpdateVirtualNetworkStatus(vnetID, db.NETWORK_ERROR)
 			}
+
+		default:
+			slog.Warn("不明なステータスの仮想ネットワークをスキップ", "networkId", vnetID, "status", *vnet.Status.Status)
 		}
 	}
-	// ワークキューから処理を取り出して、処理を実行する
+	return reconcileResult{}
 }
 
 func (c *controller) reconcileHeadProvisioningNetwork(vnet api.VirtualNetwork, fabric networkfabric.NetworkFabric) error {
@@ -44,20 +44,14 @@ func StartNetworkLoadBalancerController(node string, etcdUrl string) (*controlle
 	c.stopChan = make(chan struct{})
 	c.doneChan = make(chan struct{})
 
-	ticker := time.NewTicker(networkLoadBalancerControllerInterval)
-	go func() {
-		defer ticker.Stop()
-		defer close(c.doneChan)
-		for {
-			select {
-			case <-ticker.C:
-				c.networkLoadBalancerControllerLoop()
-			case <-c.stopChan:
-				slog.Debug("ネットワークロードバランサーコントローラー停止")
-				return
-			}
-		}
-	}()
+	// 変更の監視と定期実行の開始
+	keyedWatchLoop{
+		name:      "ネットワークロードバランサーコントローラー",
+		resync:    networkLoadBalancerControllerInterval,
+		primary:   db.NetworkLoadBalancerPrefix,
+		related:   []string{db.ServerPrefix},
+		reconcile: c.reconcileNetworkLoadBalancerById,
+	}.run(c.db, c.stopChan, c.doneChan)
 
 	return &c, nil
 }
@@ -75,63 +69,67 @@ func networkLoadBalancerControllerSettingsFromEnv() {
 	}
 }
 
-func (c *controller) networkLoadBalancerControllerLoop() {
-	slog.Debug("ネットワークロードバランサーコントローラーの制御ループ実行", "CONTROLLER", time.Now().Format("2006-01-02 15:04:05"))
-
-	items, err := c.db.GetNetworkLoadBalancers()
+// reconcileNetworkLoadBalancerById は ID のネットワークロードバランサーを読み直して調整する。削除済みなら何もしない
+func (c *controller) reconcileNetworkLoadBalancerById(id string) reconcileResult {
+	item, err := c.db.GetNetworkLoadBalancerById(id)
 	if err != nil {
-		slog.Error("GetNetworkLoadBalancers() failed", "err", err)
-		return
+		if errors.Is(err, db.ErrNotFound) {
+			return reconcileResult{}
+		}
+		slog.Error("GetNetworkLoadBalancerById() failed", "id", id, "err", err)
+		return reconcileResult{retry: true}
 	}
+	return c.reconcileNetworkLoadBalancer(item)
+}
 
-	for _, item := range items {
-		id := api.NetworkLoadBalancerID(item)
-		if ok, assignedNode, reason := evaluateNodeAssignment(&item.Metadata, c.marmot.NodeName); !ok {
-			slog.Debug("別ノード割当のネットワークロードバランサーをスキップ", "id", id, "name", item.Metadata.Name, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
-			continue
-		}
+func (c *controller) reconcileNetworkLoadBalancer(item api.NetworkLoadBalancer) reconcileResult {
+	id := api.NetworkLoadBalancerID(item)
+	if ok, assignedNode, reason := evaluateNodeAssignment(&item.Metadata, c.marmot.NodeName); !ok {
+		slog.Debug("別ノード割当のネットワークロードバランサーをスキップ", "id", id, "name", item.Metadata.Name, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
+		return reconcileResult{}
+	}
 
-		if item.Status == nil || item.Status.StatusCode != db.NETWORK_LOAD_BALANCER_DELETING {
-			missingServer, err := c.isNetworkLoadBalancerManagedServerMissing(item)
-			if err != nil {
-				slog.Warn("failed to validate network load balancer managed server", "id", id, "err", err)
-				continue
-			}
-			if missingServer {
-				c.deleteNetworkLoadBalancerForMissingServer(item)
-				continue
-			}
+	if item.Status == nil || item.Status.StatusCode != db.NETWORK_LOAD_BALANCER_DELETING {
+		missingServer, err := c.isNetworkLoadBalancerManagedServerMissing(item)
+		if err != nil {
+			slog.Warn("failed to validate network load balancer managed server", "id", id, "err", err)
+			return reconcileResult{retry: true}
 		}
-
-		if item.Status == nil {
-			_ = c.db.UpdateNetworkLoadBalancerStatusWithMessage(id, db.NETWORK_LOAD_BALANCER_PENDING, "")
-			continue
+		if missingServer {
+			c.deleteNetworkLoadBalancerForMissingServer(item)
+			return reconcileResult{}
 		}
+	}
 
-		if item.Status.DeletionTimeStamp != nil && time.Since(*item.Status.DeletionTimeStamp) > c.deletionDelay {
-			if item.Status.StatusCode != db.NETWORK_LOAD_BALANCER_DELETING {
-				_ = c.db.UpdateNetworkLoadBalancerStatusWithMessage(id, db.NETWORK_LOAD_BALANCER_DELETING, "")
-				continue
-			}
-		}
+	if item.Status == nil {
+		_ = c.db.UpdateNetworkLoadBalancerStatusWithMessage(id, db.NETWORK_LOAD_BALANCER_PENDING, "")
+		return reconcileResult{}
+	}
 
-		switch item.Status.StatusCode {
-		case db.NETWORK_LOAD_BALANCER_PENDING:
-			c.reconcileNetworkLoadBalancerPending(item)
-		case db.NETWORK_LOAD_BALANCER_PROVISIONING:
-			c.reconcileNetworkLoadBalancerProvisioning(item)
-		case db.NETWORK_LOAD_BALANCER_CONFIGURING:
-			c.reconcileNetworkLoadBalancerConfiguring(item)
-		case db.NETWORK_LOAD_BALANCER_ACTIVE:
-			c.reconcileNetworkLoadBalancerActive(item)
-		case db.NETWORK_LOAD_BALANCER_DELETING:
-			c.reconcileNetworkLoadBalancerDeleting(item)
-		case db.NETWORK_LOAD_BALANCER_FAILED:
-			slog.Debug("ネットワークロードバランサー状態を監視", "id", id, "statusCode", item.Status.StatusCode)
-		default:
-			slog.Warn("不明なネットワークロードバランサー状態", "id", id, "statusCode", item.Status.StatusCode)
+	if item.Status.DeletionTimeStamp != nil && time.Since(*item.Status.DeletionTimeStamp) > c.deletionDelay {
+		if item.Status.StatusCode != db.NETWORK_LOAD_BALANCER_DELETING {
+			_ = c.db.UpdateNetworkLoadBalancerStatusWithMessage(id, db.NETWORK_LOAD_BALANCER_DELETING, "")
+			return reconcileResult{}
 		}
 	}
+
+	switch item.Status.StatusCode {
+	case db.NETWORK_LOAD_BALANCER_PENDING:
+		c.reconcileNetworkLoadBalancerPending(item)
+	case db.NETWORK_LOAD_BALANCER_PROVISIONING:
+		c.reconcileNetworkLoadBalancerProvisioning(item)
+	case db.NETWORK_LOAD_BALANCER_CONFIGURING:
+		c.reconcileNetworkLoadBalancerConfiguring(item)
+	case db.NETWORK_LOAD_BALANCER_ACTIVE:
+		c.reconcileNetworkLoadBalancerActive(item)
+	case db.NETWORK_LOAD_BALANCER_DELETING:
+		c.reconcileNetworkLoadBalancerDeleting(item)
+	case db.NETWORK_LOAD_BALANCER_FAILED:
+		slog.Debug("ネットワークロードバランサー状態を監視", "id", id, "statusCode", item.Status.StatusCode)
+	default:
+		slog.Warn("不明なネットワークロードバランサー状態", "id", id, "statusCode", item.Status.StatusCode)
+	}
+	return reconcileResult{}
 }
 
 func (c *controller) isNetworkLoadBalancerManagedServerMissing(loadBalancer api.NetworkLoadBalancer) (bool, error) {
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+)
+
+// ノードのドレインの制御ループ
+// ドレイン中の自ノードで稼働しているサーバーを他ノードへ移行、または停止する
+func (c *controller) nodeDrainControllerLoop() {
+	node := c.marmot.NodeName
+	rec, err := c.marmot.Db.GetNodeMaintenance(node)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			slog.Error("GetNodeMaintenance()", "node", node, "err", err)
+		}
+		return
+	}
+	if rec.State == nil || *rec.State != db.NODE_DRAINING {
+		return
+	}
+	action := db.DRAIN_ACTION_MIGRATE
+	if rec.DrainAction != nil && strings.TrimSpace(*rec.DrainAction) != "" {
+		action = strings.TrimSpace(*rec.DrainAction)
+	}
+
+	servers, err := c.marmot.Db.GetServers()
+	if err != nil {
+		slog.Error("GetServers()", "err", err)
+		return
+	}
+
+	remaining := 0
+	for _, server := range servers {
+		if server.Status == nil || server.Metadata.NodeName == nil || strings.TrimSpace(*server.Metadata.NodeName) != node {
+			continue
+		}
+		id := api.ServerID(server)
+		switch server.Status.StatusCode {
+		case db.SERVER_RUNNING:
+			remaining++
+			if action == db.DRAIN_ACTION_MIGRATE && !c.drainMigrationFailed(id, rec) {
+				_, err := c.marmot.StartServerMigration(server, api.ServerMigration{})
+				if err == nil {
+					slog.Info("ドレインのためサーバーの移行を開始", "serverId", id, "node", node)
+					continue
+				}
+				slog.Warn("ドレインでサーバーを移行できないため停止する", "serverId", id, "node", node, "err", err)
+			}
+			msg := fmt.Sprintf("ノード %s のドレインにより停止", node)
+			if dbErr := c.marmot.Db.UpdateServerStatus(id, db.SERVER_STOPPING, msg); dbErr != nil {
+				slog.Error("UpdateServerStatus() failed", "serverId", id, "err", dbErr)
+			}
+		case db.SERVER_MIGRATING, db.SERVER_STOPPING, db.SERVER_STARTING, db.SERVER_PROVISIONING:
+			remaining++
+		}
+	}
+
+	state, msg := db.NODE_DRAINING, fmt.Sprintf("%d 台のサーバーを移行または停止中", remaining)
+	if remaining == 0 {
+		state, msg = db.NODE_DRAINED, ""
+	}
+	if rec.Message != nil && *rec.Message == msg && *rec.State == state {
+		return
+	}
+	if err := c.marmot.Db.UpdateNodeMaintenanceState(node, state, msg); err != nil && !errors.Is(err, db.ErrNotFound) {
+		slog.Error("UpdateNodeMaintenanceState() failed", "node", node, "err", err)
+	}
+}
+
+// drainMigrationFailed はドレイン開始後のマイグレーションが失敗したサーバーかを返す
+// 失敗したサーバーは再試行せずに停止する
+func (c *controller) drainMigrationFailed(serverId string, rec api.NodeMaintenance) bool {
+	mig, err := c.marmot.Db.GetServerMigrationByServerId(serverId)
+	if err != nil || mig.Status == nil || mig.Status.StatusCode != db.MIGRATION_FAILED {
+		return false
+	}
+	if rec.DrainStartedAt == nil || mig.Status.CreationTimeStamp == nil {
+		return true
+	}
+	return mig.Status.CreationTimeStamp.After(*rec.DrainStartedAt)
+}
@@ -1,8 +1,8 @@
 package controller
 
 import (
+	"errors"
 	"log/slog"
-	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -37,20 +37,15 @@ func StartSchedulerController(node string, etcdUrl string) (*schedulerController
 		return nil, err
 	}
 
-	ticker := time.NewTicker(SCHEDULER_CONTROLLER_INTERVAL)
-	go func() {
-		defer ticker.Stop()
-		defer close(c.doneChan)
-		for {
-			select {
-			case <-ticker.C:
-				c.schedulerControllerLoop()
-			case <-c.stopChan:
-				slog.Debug("スケジューラーコントローラー停止")
-				return
-			}
-		}
-	}()
+	// サーバーの追加、ノードの稼働状況や cordon の変更を監視してスケジューリングする
+	// サーバーはIDごとに、ノードの状況が変わった場合は全サーバーを調整し直す
+	keyedWatchLoop{
+		name:      "スケジューラーコントローラー",
+		resync:    SCHEDULER_CONTROLLER_INTERVAL,
+		primary:   db.ServerPrefix,
+		related:   []string{db.HostStatusPrefix, db.NodeMaintenancePrefix},
+		reconcile: c.reconcileServerScheduling,
+	}.run(c.marmot.Db, c.stopChan, c.doneChan)
 	return &c, nil
 }
 
@@ -69,77 +64,134 @@ func (c *schedulerController) Stop() {
 	}
 }
 
-// schedulerControllerLoop はリーダーノードで未割り当ての PENDING サーバーにノードを割り当てる。
-func (c *schedulerController) schedulerControllerLoop() {
-	slog.Debug("スケジューラーコントローラーの制御ループ実行", "node", c.marmot.NodeName, "time", time.Now().Format("2006-01-02 15:04:05"))
+// reconcileServerScheduling は ID のサーバーが未割り当ての PENDING なら、リーダーノードでノードを割り当てる。
+func (c *schedulerController) reconcileServerScheduling(id string) reconcileResult {
+	server, err := c.marmot.Db.GetServerById(id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return reconcileResult{}
+		}
+		slog.Error("GetServerById() failed", "serverId", id, "err", err)
+		return reconcileResult{retry: true}
+	}
+	if server.Status == nil || server.Status.StatusCode != db.SERVER_PENDING {
+		return reconcileResult{}
+	}
+
+	statuses, _, nodeResources, ok := c.schedulingState()
+	if !ok {
+		return reconcileResult{}
+	}
+	return c.scheduleServer(server, statuses, nodeResources)
+}
 
+// schedulingState はリーダーノードの場合に、スケジューリングに使うホストの状態、全サーバー、ノードごとの資源状況を返す。
+// リーダーでない場合や、スケジューリングできない場合は ok が false になる。
+func (c *schedulerController) schedulingState() (statuses []api.HostStatus, servers []api.Server, nodeResources map[string]*marmotd.NodeResources, ok bool) {
 	// 全ホストステータスを取得してアクティブなホスト一覧を構築
 	statuses, err := c.marmot.Db.GetAllHostStatus()
 	if err != nil {
 		slog.Error("GetAllHostStatus() failed", "err", err)
-		return
+		return nil, nil, nil, false
 	}
 
 	// リーダーでなければスケジューリングをスキップ
 	if !marmotd.IsSchedulerLeader(c.marmot.NodeName, statuses) {
 		slog.Debug("スケジューラーリーダーではないためスキップ", "node", c.marmot.NodeName)
-		return
+		return nil, nil, nil, false
 	}
 	slog.Debug("スケジューラーリーダーとして動作中", "node", c.marmot.NodeName)
 
-	// 全サーバーを取得して未割り当て PENDING サーバーを検出
-	servers, err := c.marmot.Db.GetServers()
+	// 全サーバーを取得して配置済みの資源を集計する
+	servers, err = c.marmot.Db.GetServers()
 	if err != nil {
 		slog.Error("GetServers() failed", "err", err)
-		return
+		return nil, nil, nil, false
 	}
 
 	activeNodes := activeNodeNames(statuses)
 	if len(activeNodes) == 0 {
 		slog.Warn("スケジューリング対象のアクティブノードが見つかりません")
-		return
+		return nil, nil, nil, false
 	}
 
 	nodeLoads := buildNodeLoads(activeNodes, servers)
+	return statuses, servers, buildNodeResources(statuses, nodeLoads, servers), true
+}
 
-	for _, server := range servers {
-		// PENDING 状態でない場合はスキップ
-		if server.Status == nil || server.Status.StatusCode != db.SERVER_PENDING {
-			continue
-		}
+// scheduleServer は未割り当ての PENDING サーバーにノードを割り当て、nodeResources に割当を反映する。
+func (c *schedulerController) scheduleServer(server api.Server, statuses []api.HostStatus, nodeResources map[string]*marmotd.NodeResources) reconcileResult {
+	// PENDING 状態でない場合はスキップ
+	if server.Status == nil || server.Status.StatusCode != db.SERVER_PENDING {
+		return reconcileResult{}
+	}
 
-		assignedNode := ""
-		if server.Metadata.NodeName != nil {
-			assignedNode = strings.TrimSpace(*server.Metadata.NodeName)
-		}
+	assignedNode := ""
+	if server.Metadata.NodeName != nil {
+		assignedNode = strings.TrimSpace(*server.Metadata.NodeName)
+	}
 
-		// metadata.nodeName が指定済みの場合は存在チェックのみ行い、未知ノードなら ERROR に更新する。
-		if assignedNode != "" {
-			if !clusterHasNode(statuses, assignedNode) {
-				msg := "metadata.nodeName=" + assignedNode + " はクラスタ内に存在しません"
-				slog.Warn("存在しない nodeName が指定されたサーバーを ERROR に更新", "serverId", api.ServerID(server), "nodeName", assignedNode)
-					if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(server), db.SERVER_ERROR, msg); dbErr != nil {
-						slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(server), "err", dbErr)
-					}
+	// metadata.nodeName が指定済みの場合は存在チェックのみ行い、未知ノードなら ERROR に更新する。
+	if assignedNode != "" {
+		if !clusterHasNode(statuses, assignedNode) {
+			msg := "metadata.nodeName=" + assignedNode + " はクラスタ内に存在しません"
+			slog.Warn("存在しない nodeName が指定されたサーバーを ERROR に更新", "serverId", api.ServerID(server), "nodeName", assignedNode)
+			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(server), db.SERVER_ERROR, msg); dbErr != nil {
+				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(server), "err", dbErr)
+				return reconcileResult{retry: true}
 			}
-			continue
 		}
+		return reconcileResult{}
+	}
 
-		// 同一ループ内で均等化するため、ローカルの負荷カウントを使って選定する。
-		targetNode, err := selectLeastLoadedNode(activeNodes, nodeLoads)
-		if err != nil {
-			slog.Error("selectLeastLoadedNode() failed", "err", err, "serverId", api.ServerID(server))
-			continue
+	// 作成済みストレージでノードが決まるサーバーは、その結果に従う。
+	// 解決に失敗した場合はサーバーコントローラーが ERROR に更新する。
+	req := marmotd.ServerResourceRequestOf(server)
+	storageNode, err := c.marmot.ResolveAndAssignServerNodeByStorage(api.ServerID(server))
+	if err != nil {
+		slog.Warn("ResolveAndAssignServerNodeByStorage() failed", "err", err, "serverId", api.ServerID(server))
+		return reconcileResult{retry: true}
+	}
+	if storageNode != "" {
+		if nr, ok := nodeResources[storageNode]; ok {
+			server.Metadata.NodeName = &storageNode
+			nr.Reserve(req)
+			nr.TotalVMs++
+			nr.Servers = append(nr.Servers, server)
 		}
+		slog.Debug("ストレージの配置によりノードを割り当てました", "serverId", api.ServerID(server), "targetNode", storageNode)
+		return reconcileResult{}
+	}
 
-		// ノードを割り当て
-		if err := c.marmot.Db.AssignNodeToServer(api.ServerID(server), targetNode); err != nil {
-			slog.Warn("AssignNodeToServer() failed", "err", err, "serverId", api.ServerID(server), "targetNode", targetNode)
-			continue
+	// 同一ループ内の割当も反映した空き資源と配置ルールから配置先を選定する。
+	targetNode, err := selectNodeForServer(nodeResources, server, req)
+	if err != nil {
+		if errors.Is(err, marmotd.ErrInsufficientCapacity) || errors.Is(err, marmotd.ErrPlacementUnsatisfiable) {
+			// 配置できるノードが無い場合は PENDING のまま理由をメッセージに残し、ノードの状況の変化か定期実行で再評価する。
+			msg := err.Error()
+			if server.Status.Message == nil || *server.Status.Message != msg {
+				slog.Warn("サーバーを配置できるノードがありません", "serverId", api.ServerID(server), "reason", msg)
+				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(server), db.SERVER_PENDING, msg); dbErr != nil {
+					slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(server), "err", dbErr)
+				}
+			}
+			return reconcileResult{}
 		}
-		nodeLoads[targetNode]++
-		slog.Debug("サーバーにノードを割り当てました", "serverId", api.ServerID(server), "targetNode", targetNode)
+		slog.Error("selectNodeForServer() failed", "err", err, "serverId", api.ServerID(server))
+		return reconcileResult{retry: true}
+	}
+
+	// ノードを割り当て
+	if err := c.marmot.Db.AssignNodeToServer(api.ServerID(server), targetNode); err != nil {
+		slog.Warn("AssignNodeToServer() failed", "err", err, "serverId", api.ServerID(server), "targetNode", targetNode)
+		return reconcileResult{retry: true}
 	}
+	server.Metadata.NodeName = &targetNode
+	nodeResources[targetNode].Reserve(req)
+	nodeResources[targetNode].TotalVMs++
+	nodeResources[targetNode].Servers = append(nodeResources[targetNode].Servers, server)
+	slog.Debug("サーバーにノードを割り当てました", "serverId", api.ServerID(server), "targetNode", targetNode)
+	return reconcileResult{}
 }
 
 func activeNodeNames(statuses []api.HostStatus) []string {
@@ -187,22 +239,49 @@ func buildNodeLoads(activeNodes []string, servers []api.Server) map[string]int {
 	return loads
 }
 
-func selectLeastLoadedNode(activeNodes []string, nodeLoads map[string]int) (string, error) {
-	if len(activeNodes) == 0 {
-		return "", marmotd.ErrNoActiveHosts
+// buildNodeResources はアクティブノードごとの資源状況と配置済みサーバーを構築する。
+// HostAllocation は RUNNING のサーバーのみを計上するため、割当済みで起動前のサーバーの要求資源を加算する。
+func buildNodeResources(statuses []api.HostStatus, nodeLoads map[string]int, servers []api.Server) map[string]*marmotd.NodeResources {
+	cfg := marmotd.CurrentConfig()
+	resources := make(map[string]*marmotd.NodeResources, len(nodeLoads))
+	for _, st := range statuses {
+		if st.NodeName == nil {
+			continue
+		}
+		nodeName := strings.TrimSpace(*st.NodeName)
+		load, active := nodeLoads[nodeName]
+		if !active {
+			continue
+		}
+		nr := marmotd.NewNodeResources(st, cfg.SchedulerCPUOvercommitRatio, cfg.SchedulerMemoryOvercommitRatio)
+		nr.NodeName = nodeName
+		nr.TotalVMs = load
+		resources[nodeName] = &nr
 	}
 
-	candidates := append([]string(nil), activeNodes...)
-	sort.Slice(candidates, func(i, j int) bool {
-		loadI := nodeLoads[candidates[i]]
-		loadJ := nodeLoads[candidates[j]]
-		if loadI != loadJ {
-			return loadI < loadJ
+	for _, server := range servers {
+		if server.Status == nil || server.Metadata.NodeName == nil {
+			continue
 		}
-		return candidates[i] < candidates[j]
-	})
+		nr, ok := resources[strings.TrimSpace(*server.Metadata.NodeName)]
+		if !ok || server.Status.StatusCode == db.SERVER_DELETING {
+			continue
+		}
+		nr.Servers = append(nr.Servers, server)
+		switch server.Status.StatusCode {
+		case db.SERVER_PENDING, db.SERVER_PROVISIONING, db.SERVER_STARTING:
+			nr.Reserve(marmotd.ServerResourceRequestOf(server))
+		}
+	}
+	return resources
+}
 
-	return candidates[0], nil
+func selectNodeForServer(resources map[string]*marmotd.NodeResources, server api.Server, req marmotd.ServerResourceRequest) (string, error) {
+	nodes := make([]marmotd.NodeResources, 0, len(resources))
+	for _, nr := range resources {
+		nodes = append(nodes, *nr)
+	}
+	return marmotd.SelectNodeForServer(nodes, server, req)
 }
 
 func clusterHasNode(statuses []api.HostStatus, nodeName string) bool {
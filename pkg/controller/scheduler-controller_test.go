@@ -39,20 +39,53 @@ func TestClusterHasNode(t *testing.T) {
 	}
 }
 
-func TestSelectLeastLoadedNode(t *testing.T) {
-	activeNodes := []string{"marmot1", "marmot2", "marmot3"}
-	loads := map[string]int{
-		"marmot1": 2,
-		"marmot2": 0,
-		"marmot3": 0,
+func TestSelectNodeForServer(t *testing.T) {
+	// 資源情報の無いノードは VM 数の少ない順に選ばれる
+	resources := map[string]*marmotd.NodeResources{}
+	for name, load := range map[string]int{"marmot1": 2, "marmot2": 0, "marmot3": 0} {
+		nr := marmotd.NewNodeResources(api.HostStatus{NodeName: util.StringPtr(name)}, 1, 1)
+		nr.TotalVMs = load
+		resources[name] = &nr
 	}
 
-	node, err := selectLeastLoadedNode(activeNodes, loads)
+	node, err := selectNodeForServer(resources, api.Server{}, marmotd.ServerResourceRequest{CpuCores: 2, MemoryMB: 2048})
 	if err != nil {
-		t.Fatalf("selectLeastLoadedNode returned error: %v", err)
+		t.Fatalf("selectNodeForServer returned error: %v", err)
 	}
 	if node != "marmot2" {
-		t.Fatalf("selectLeastLoadedNode() = %q, want %q", node, "marmot2")
+		t.Fatalf("selectNodeForServer() = %q, want %q", node, "marmot2")
+	}
+}
+
+func TestBuildNodeResources(t *testing.T) {
+	statuses := []api.HostStatus{
+		{
+			NodeName:   util.StringPtr("marmot1"),
+			Capacity:   &api.HostCapacity{CpuCores: util.IntPtrInt(4), MemoryMB: util.IntPtrInt(8192)},
+			Allocation: &api.HostAllocation{AllocatedCpuCores: util.IntPtrInt(2), AllocatedMemoryMB: util.IntPtrInt(2048)},
+		},
+		{NodeName: util.StringPtr("stale")},
+	}
+	servers := []api.Server{
+		{Metadata: api.Metadata{Name: "running", NodeName: util.StringPtr("marmot1")}, Spec: api.ServerSpec{Cpu: util.IntPtrInt(2), Memory: util.IntPtrInt(2048)}, Status: &api.Status{StatusCode: db.SERVER_RUNNING}},
+		{Metadata: api.Metadata{Name: "provisioning", NodeName: util.StringPtr("marmot1")}, Spec: api.ServerSpec{Cpu: util.IntPtrInt(1), Memory: util.IntPtrInt(1024)}, Status: &api.Status{StatusCode: db.SERVER_PROVISIONING}},
+		{Metadata: api.Metadata{Name: "unassigned"}, Status: &api.Status{StatusCode: db.SERVER_PENDING}},
+	}
+
+	resources := buildNodeResources(statuses, map[string]int{"marmot1": 2}, servers)
+	if len(resources) != 1 {
+		t.Fatalf("buildNodeResources() returned %d nodes, want 1", len(resources))
+	}
+	nr := resources["marmot1"]
+	if nr == nil {
+		t.Fatal("buildNodeResources() has no entry for marmot1")
+	}
+	// RUNNING は HostAllocation に計上済み、PROVISIONING の要求資源のみ加算される
+	if nr.AllocatedCpuCores != 3 || nr.AllocatedMemoryMB != 3072 {
+		t.Fatalf("allocated = (%d, %d), want (3, 3072)", nr.AllocatedCpuCores, nr.AllocatedMemoryMB)
+	}
+	if nr.TotalVMs != 2 {
+		t.Fatalf("TotalVMs = %d, want 2", nr.TotalVMs)
 	}
 }
 
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -16,8 +17,15 @@ import (
 
 const (
 	SERVER_CONTROLLER_INTERVAL = 5 * time.Second
+	SERVER_RESYNC_INTERVAL     = 30 * time.Second // watch を取りこぼした場合に備えて全サーバーを調整し直す間隔
 )
 
+// スナップショット、マイグレーション、ドレインの制御ループを実行するためにワークキューへ投入するキー
+const serverProgressKey = "#progress"
+
+// ボリュームの反映に失敗した時のステータスメッセージ。成功した時に消すために接頭辞として使う
+const volumeSyncErrorMessage = "ボリュームの反映に失敗"
+
 type controller struct {
 	db                         *db.Database
 	Lock                       sync.Mutex
@@ -27,6 +35,7 @@ type controller struct {
 	stopChan                   chan struct{}
 	doneChan                   chan struct{}
 	stopOnce                   sync.Once
+	volumeClones               sync.Map // 複製を実行中のボリュームのID
 }
 
 // VMコントローラーの開始
@@ -52,19 +61,55 @@ func StartVmController(node string, etcdUrl string, deletionDelaySeconds int) (*
 	c.stopChan = make(chan struct{})
 	c.doneChan = make(chan struct{})
 
-	// 定期実行の開始
+	// サーバーの変更を watch してサーバー単位で調整する
+	// スナップショット、マイグレーション、ドレインは進行状況を確認するため一定間隔で実行する
+	q := newWorkQueue()
+	servers := c.db.NewInformer(db.ServerPrefix, SERVER_RESYNC_INTERVAL)
+	servers.AddHandler(func(ev db.InformerEvent) {
+		if id := serverIdFromKey(ev.Key); id != "" && ev.Type != db.EVENT_DELETE {
+			q.Add(id)
+		}
+	})
+	go servers.Run(c.stopChan)
+	// cordon や drain の変更は全サーバーを調整し直す
+	watchPrefixes(c.db, c.stopChan, func(db.InformerEvent) { q.Add(controllerLoopKey) }, db.NodeMaintenancePrefix)
 	ticker := time.NewTicker(SERVER_CONTROLLER_INTERVAL)
 	go func() {
 		defer ticker.Stop()
-		defer close(c.doneChan)
 		for {
 			select {
 			case <-ticker.C:
-				c.serverControllerLoop()
+				q.Add(serverProgressKey)
 			case <-c.stopChan:
+				q.ShutDown()
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(c.doneChan)
+		for {
+			key, ok := q.Get()
+			if !ok {
 				slog.Debug("サーバーコントローラー停止")
 				return
 			}
+			switch key {
+			case serverProgressKey:
+				c.serverSnapshotControllerLoop()
+				result := c.serverMigrationControllerLoop()
+				c.nodeDrainControllerLoop()
+				result.requeue(q, key)
+			case controllerLoopKey:
+				for _, k := range servers.Keys() {
+					if id := serverIdFromKey(k); id != "" {
+						q.Add(id)
+					}
+				}
+			default:
+				c.reconcileServerById(key).requeue(q, key)
+			}
+			q.Done(key)
 		}
 	}()
 	return &c, nil
@@ -106,212 +151,319 @@ func (c *controller) serverControllerLoop() {
 	}
 
 	for _, spec := range serverSpec {
-		if spec.Status != nil && spec.Status.StatusCode == db.SERVER_PENDING {
-			assignedNode, assignErr := c.marmot.ResolveAndAssignServerNodeByStorage(api.ServerID(spec))
-			if assignErr != nil {
-				slog.Error("ResolveAndAssignServerNodeByStorage()", "serverId", api.ServerID(spec), "err", assignErr)
-				msg := fmt.Sprintf("サーバーのノード割当解決に失敗した。原因エラー: %v", assignErr)
-				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
-					slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
-				}
-				continue
-			}
-			if strings.TrimSpace(assignedNode) != "" {
-				spec.Metadata.NodeName = util.StringPtr(assignedNode)
+		c.reconcileServer(spec, statuses, clusterHasNodes)
+	}
+}
+
+// reconcileServerById は watch で変更を検知したサーバーを etcd から読み直して調整する
+func (c *controller) reconcileServerById(id string) reconcileResult {
+	statuses, err := c.marmot.Db.GetAllHostStatus()
+	if err != nil {
+		slog.Warn("GetAllHostStatus() failed", "err", err)
+		return reconcileResult{retry: true}
+	}
+	spec, err := c.marmot.Db.GetServerById(id)
+	if errors.Is(err, db.ErrNotFound) {
+		return reconcileResult{}
+	}
+	if err != nil {
+		return reconcileResult{retry: true}
+	}
+	return c.reconcileServer(spec, statuses, clusterHasAnyNode(statuses))
+}
+
+// serverIdFromKey は etcd のキーからサーバーIDを取り出す。サーバーのキーでなければ空文字
+func serverIdFromKey(key string) string {
+	return resourceIdFromKey(db.ServerPrefix, key)
+}
+
+// reconcileServer はサーバー1台の状態に応じた処理を実行する
+func (c *controller) reconcileServer(spec api.Server, statuses []api.HostStatus, clusterHasNodes bool) (result reconcileResult) {
+	if spec.Status != nil && spec.Status.StatusCode == db.SERVER_PENDING {
+		assignedNode, assignErr := c.marmot.ResolveAndAssignServerNodeByStorage(api.ServerID(spec))
+		if assignErr != nil {
+			slog.Error("ResolveAndAssignServerNodeByStorage()", "serverId", api.ServerID(spec), "err", assignErr)
+			msg := fmt.Sprintf("サーバーのノード割当解決に失敗した。原因エラー: %v", assignErr)
+			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
+				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
 			}
+			return result
 		}
+		if strings.TrimSpace(assignedNode) != "" {
+			spec.Metadata.NodeName = util.StringPtr(assignedNode)
+		}
+	}
 
-		// 削除のタイムスタンプが一定時間以上経過しているかをチェックして、削除処理を実行する
-		if spec.Status != nil && spec.Status.DeletionTimeStamp != nil {
-			deletionTime := *spec.Status.DeletionTimeStamp
-			if time.Since(deletionTime) > c.deletionDelay {
-				slog.Debug("削除のタイムスタンプが一定時間以上経過しているサーバー検出", "SERVER", api.ServerID(spec))
-				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_DELETING, ""); dbErr != nil {
-					slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
-				}
-				spec.Status.StatusCode = db.SERVER_DELETING
-				spec.Status.Status = util.StringPtr(db.ServerStatus[db.SERVER_DELETING])
+	// 削除のタイムスタンプが一定時間以上経過しているかをチェックして、削除処理を実行する
+	if spec.Status != nil && spec.Status.DeletionTimeStamp != nil {
+		deletionTime := *spec.Status.DeletionTimeStamp
+		if time.Since(deletionTime) > c.deletionDelay {
+			slog.Debug("削除のタイムスタンプが一定時間以上経過しているサーバー検出", "SERVER", api.ServerID(spec))
+			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_DELETING, ""); dbErr != nil {
+				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
 			}
+			spec.Status.StatusCode = db.SERVER_DELETING
+			spec.Status.Status = util.StringPtr(db.ServerStatus[db.SERVER_DELETING])
+		} else {
+			// 待機時間の経過後に削除処理を実行する
+			result.requeueAfter = c.deletionDelay - time.Since(deletionTime)
 		}
+	}
 
-		forceDeleteWithoutNode, bypassReason := shouldBypassNodeGateForDeletingServer(spec, statuses)
+	forceDeleteWithoutNode, bypassReason := shouldBypassNodeGateForDeletingServer(spec, statuses)
 
-		// サーバーは必ず nodeName 割当後に処理する。
-		if !forceDeleteWithoutNode && (spec.Metadata.NodeName == nil || strings.TrimSpace(*spec.Metadata.NodeName) == "") {
-			objectName := spec.Metadata.Name
-			slog.Debug("nodeName 未割当サーバーをスキップ", "serverId", api.ServerID(spec), "serverName", objectName, "controllerNode", c.marmot.NodeName, "reason", "assigned_node_missing")
-			continue
-		}
+	// サーバーは必ず nodeName 割当後に処理する。
+	if !forceDeleteWithoutNode && (spec.Metadata.NodeName == nil || strings.TrimSpace(*spec.Metadata.NodeName) == "") {
+		objectName := spec.Metadata.Name
+		slog.Debug("nodeName 未割当サーバーをスキップ", "serverId", api.ServerID(spec), "serverName", objectName, "controllerNode", c.marmot.NodeName, "reason", "assigned_node_missing")
+		return result
+	}
 
-		if !forceDeleteWithoutNode {
-			if ok, assignedNode, reason := evaluateNodeAssignment(&spec.Metadata, c.marmot.NodeName); !ok {
-				objectName := spec.Metadata.Name
-				slog.Debug("別ノード割当のサーバーをスキップ", "serverId", api.ServerID(spec), "serverName", objectName, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
-				continue
-			}
-		} else {
+	if !forceDeleteWithoutNode {
+		if ok, assignedNode, reason := evaluateNodeAssignment(&spec.Metadata, c.marmot.NodeName); !ok {
 			objectName := spec.Metadata.Name
-			slog.Warn("nodeName 判定をバイパスして削除を継続", "serverId", api.ServerID(spec), "serverName", objectName, "reason", bypassReason)
+			slog.Debug("別ノード割当のサーバーをスキップ", "serverId", api.ServerID(spec), "serverName", objectName, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
+			return result
 		}
+	} else {
+		objectName := spec.Metadata.Name
+		slog.Warn("nodeName 判定をバイパスして削除を継続", "serverId", api.ServerID(spec), "serverName", objectName, "reason", bypassReason)
+	}
 
-		// 取得したサーバースペック情報の表示とプロビジョニング中サーバーの検出
-		//jsonByte, err := json.MarshalIndent(spec, "", "  ")
-		//if err != nil {
-		//	slog.Error("json.MarshalIndent()", "err", err)
-		//	continue
-		//}
-		//fmt.Println(string(jsonByte))
-
-		// サーバーの状態に応じた処理を実行する
-		switch spec.Status.StatusCode {
-		case db.SERVER_PENDING:
-			slog.Debug("生成待ち状態のサーバー検出", "SERVER", api.ServerID(spec))
-			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_PROVISIONING, ""); dbErr != nil {
-				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
-				continue
-			}
-			if _, err := c.marmot.CreateServerManage(api.ServerID(spec)); err != nil {
-				slog.Error("CreateServerManage()", "err", err)
-				msg := fmt.Sprintf("サーバーのプロビジョニングに失敗した。原因エラー: %v", err)
-				if isRetryableServerProvisionError(err) {
-					retryMsg := fmt.Sprintf("サーバーのプロビジョニング待機中（依存関係の準備待ち）: %v", err)
-					slog.Warn("CreateServerManage() failed with retryable error; keep server pending", "server", api.ServerID(spec), "err", err)
-					if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_PENDING, retryMsg); dbErr != nil {
-						slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
-					}
-					continue
+	// 取得したサーバースペック情報の表示とプロビジョニング中サーバーの検出
+	//jsonByte, err := json.MarshalIndent(spec, "", "  ")
+	//if err != nil {
+	//	slog.Error("json.MarshalIndent()", "err", err)
+	//	continue
+	//}
+	//fmt.Println(string(jsonByte))
+
+	// サーバーの状態に応じた処理を実行する
+	switch spec.Status.StatusCode {
+	case db.SERVER_PENDING:
+		slog.Debug("生成待ち状態のサーバー検出", "SERVER", api.ServerID(spec))
+		if maintenance := nodeMaintenance(statuses, c.marmot.NodeName); maintenance != nil {
+			// cordon されたノードでは新しいサーバーを作成しない
+			msg := fmt.Sprintf("ノード %s は cordon されているため作成を保留中", c.marmot.NodeName)
+			if spec.Status.Message == nil || *spec.Status.Message != msg {
+				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_PENDING, msg); dbErr != nil {
+					slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
 				}
-				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
+			}
+			return result
+		}
+		if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_PROVISIONING, ""); dbErr != nil {
+			slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
+			return result
+		}
+		if _, err := c.marmot.CreateServerManage(api.ServerID(spec)); err != nil {
+			slog.Error("CreateServerManage()", "err", err)
+			msg := fmt.Sprintf("サーバーのプロビジョニングに失敗した。原因エラー: %v", err)
+			if isRetryableServerProvisionError(err) {
+				retryMsg := fmt.Sprintf("サーバーのプロビジョニング待機中（依存関係の準備待ち）: %v", err)
+				slog.Warn("CreateServerManage() failed with retryable error; keep server pending", "server", api.ServerID(spec), "err", err)
+				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_PENDING, retryMsg); dbErr != nil {
 					slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
 				}
-				continue
+				return reconcileResult{retry: true}
 			}
-			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_RUNNING, ""); dbErr != nil {
+			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
 				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
 			}
-		case db.SERVER_RUNNING:
-			slog.Debug("稼働中のサーバー検出", "SERVER", api.ServerID(spec))
-			if err := c.marmot.SyncServerResourcesManage(api.ServerID(spec)); err != nil {
-				slog.Error("SyncServerResourcesManage()", "serverId", api.ServerID(spec), "err", err)
-				msg := fmt.Sprintf("サーバー設定(CPU/Memory)の反映に失敗: %v", err)
-				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
-					slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
-				}
+			return result
+		}
+		if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_RUNNING, ""); dbErr != nil {
+			slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
+		}
+	case db.SERVER_RUNNING:
+		slog.Debug("稼働中のサーバー検出", "SERVER", api.ServerID(spec))
+		if err := c.marmot.SyncServerResourcesManage(api.ServerID(spec)); err != nil {
+			if errors.Is(err, marmotd.ErrServerShutdownInProgress) {
+				// 変更の反映のためにゲストOSの停止を待つ。ワーカーを待たせずに後で確認する
+				result.requeueAfter = SERVER_CONTROLLER_INTERVAL
+				return result
 			}
-		case db.SERVER_STOPPING:
-			slog.Debug("停止要求のサーバー検出", "SERVER", api.ServerID(spec))
-			if err := c.marmot.StopServerManage(api.ServerID(spec)); err != nil {
-				slog.Error("StopServerManage()", "err", err)
-				msg := fmt.Sprintf("サーバーの停止に失敗: %v", err)
-				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
-					slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
+			slog.Error("SyncServerResourcesManage()", "serverId", api.ServerID(spec), "err", err)
+			msg := fmt.Sprintf("サーバー設定(CPU/Memory)の反映に失敗: %v", err)
+			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
+				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
+			}
+			return result
+		}
+		if err := c.marmot.SyncServerGuestInfoManage(api.ServerID(spec)); err != nil {
+			slog.Error("SyncServerGuestInfoManage()", "serverId", api.ServerID(spec), "err", err)
+		}
+		if err := c.marmot.ApplyGuestPasswordManage(api.ServerID(spec)); err != nil {
+			slog.Error("ApplyGuestPasswordManage()", "serverId", api.ServerID(spec), "err", err)
+		}
+		return c.syncServerVolumes(spec)
+	case db.SERVER_STOPPING:
+		slog.Debug("停止要求のサーバー検出", "SERVER", api.ServerID(spec))
+		if err := c.marmot.StopServerManage(api.ServerID(spec)); err != nil {
+			if errors.Is(err, marmotd.ErrServerShutdownInProgress) {
+				// ゲストOSの停止を待つ。ワーカーを待たせずに後で確認する
+				result.requeueAfter = SERVER_CONTROLLER_INTERVAL
+				return result
+			}
+			slog.Error("StopServerManage()", "err", err)
+			msg := fmt.Sprintf("サーバーの停止に失敗: %v", err)
+			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
+				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
+			}
+		}
+	case db.SERVER_STOPPED:
+		slog.Debug("停止中のサーバー検出", "SERVER", api.ServerID(spec))
+		return c.syncServerVolumes(spec)
+	case db.SERVER_STARTING:
+		slog.Debug("起動要求のサーバー検出", "SERVER", api.ServerID(spec))
+		if maintenance := nodeMaintenance(statuses, c.marmot.NodeName); maintenance != nil && maintenance.State != nil && *maintenance.State != db.NODE_CORDONED {
+			// ドレイン中またはドレイン済みのノードではサーバーを起動しない
+			msg := fmt.Sprintf("ノード %s はドレインされているため起動できません", c.marmot.NodeName)
+			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_STOPPED, msg); dbErr != nil {
+				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
+			}
+			return result
+		}
+		if err := c.marmot.StartServerManage(api.ServerID(spec)); err != nil {
+			slog.Error("StartServerManage()", "err", err)
+			msg := fmt.Sprintf("サーバーの起動に失敗: %v", err)
+			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
+				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
+			}
+		}
+	case db.SERVER_MIGRATING:
+		// マイグレーションの制御ループが処理する
+		slog.Debug("マイグレーション中のサーバー検出", "SERVER", api.ServerID(spec))
+	case db.SERVER_ERROR:
+		slog.Debug("エラー状態のサーバー検出", "SERVER", api.ServerID(spec))
+	case db.SERVER_DELETING:
+		slog.Debug("削除中のサーバー検出", "SERVER", api.ServerID(spec))
+
+		if !clusterHasNodes {
+			if spec.Spec.BootVolume != nil && strings.TrimSpace(api.VolumeID(*spec.Spec.BootVolume)) != "" {
+				c.marmot.Db.SetVolumeDeletionTimestamp(api.VolumeID(*spec.Spec.BootVolume))
+			}
+			if spec.Spec.Storage != nil {
+				for _, vol := range *spec.Spec.Storage {
+					if vol.Spec.Persistent != nil && *vol.Spec.Persistent {
+						continue
+					}
+					if strings.TrimSpace(api.VolumeID(vol)) == "" {
+						continue
+					}
+					c.marmot.Db.SetVolumeDeletionTimestamp(api.VolumeID(vol))
 				}
 			}
-		case db.SERVER_STOPPED:
-			slog.Debug("停止中のサーバー検出", "SERVER", api.ServerID(spec))
-		case db.SERVER_STARTING:
-			slog.Debug("起動要求のサーバー検出", "SERVER", api.ServerID(spec))
-			if err := c.marmot.StartServerManage(api.ServerID(spec)); err != nil {
-				slog.Error("StartServerManage()", "err", err)
-				msg := fmt.Sprintf("サーバーの起動に失敗: %v", err)
+			if err := c.marmot.Db.DetachVolumesFromServer(api.ServerID(spec)); err != nil {
+				slog.Warn("DetachVolumesFromServer()", "serverId", api.ServerID(spec), "err", err)
+			}
+			slog.Warn("クラスタノード不在のため VM 実体削除をスキップし、サーバー定義削除を継続", "serverId", api.ServerID(spec))
+		} else {
+			// スナップショットの片付け（LVM スナップショットが残っていると元ボリュームを削除できない）
+			if err := c.marmot.DeleteServerSnapshotsByServerManage(api.ServerID(spec)); err != nil {
+				slog.Error("DeleteServerSnapshotsByServerManage()", "serverId", api.ServerID(spec), "err", err)
+			}
+			// 仮想マシンの削除処理の実行
+			if err := c.marmot.DeleteServerByIdManage(api.ServerID(spec)); err != nil {
+				slog.Error("DeleteServerById()", "err", err)
+				msg := fmt.Sprintf("サーバーの削除に失敗: %v", err)
 				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
 					slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
 				}
 			}
-		case db.SERVER_ERROR:
-			slog.Debug("エラー状態のサーバー検出", "SERVER", api.ServerID(spec))
-		case db.SERVER_DELETING:
-			slog.Debug("削除中のサーバー検出", "SERVER", api.ServerID(spec))
-
-			if !clusterHasNodes {
-				if spec.Spec.BootVolume != nil && strings.TrimSpace(api.VolumeID(*spec.Spec.BootVolume)) != "" {
-					c.marmot.Db.SetVolumeDeletionTimestamp(api.VolumeID(*spec.Spec.BootVolume))
-				}
-				if spec.Spec.Storage != nil {
-					for _, vol := range *spec.Spec.Storage {
-						if vol.Spec.Persistent != nil && *vol.Spec.Persistent {
-							continue
-						}
-						if strings.TrimSpace(api.VolumeID(vol)) == "" {
-							continue
-						}
-						c.marmot.Db.SetVolumeDeletionTimestamp(api.VolumeID(vol))
-					}
-				}
-				slog.Warn("クラスタノード不在のため VM 実体削除をスキップし、サーバー定義削除を継続", "serverId", api.ServerID(spec))
-			} else {
-				// 仮想マシンの削除処理の実行
-				if err := c.marmot.DeleteServerByIdManage(api.ServerID(spec)); err != nil {
-					slog.Error("DeleteServerById()", "err", err)
-					msg := fmt.Sprintf("サーバーの削除に失敗: %v", err)
-					if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
-						slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
-					}
+		}
+
+		// IPアドレス開放処理の実行
+		if spec.Spec.NetworkInterface != nil {
+			for _, nic := range *spec.Spec.NetworkInterface {
+				debugPrintln("NIC=========================================")
+				jsonbyte, err := json.MarshalIndent(nic, "", "  ")
+				if err != nil {
+					slog.Error("json.MarshalIndent()", "err", err)
+					continue
 				}
-			}
+				debugPrintln(string(jsonbyte))
+				debugPrintln("=========================================")
 
-			// IPアドレス開放処理の実行
-			if spec.Spec.NetworkInterface != nil {
-				for _, nic := range *spec.Spec.NetworkInterface {
-					debugPrintln("NIC=========================================")
-					jsonbyte, err := json.MarshalIndent(nic, "", "  ")
-					if err != nil {
-						slog.Error("json.MarshalIndent()", "err", err)
+				if nic.IpNetworkId != nil && nic.Address != nil {
+					if err := c.marmot.Db.ReleaseIP(nic.Networkid, *nic.IpNetworkId, *nic.Address); err != nil {
+						slog.Error("ReleaseIP()", "err", err)
 						continue
 					}
-					debugPrintln(string(jsonbyte))
-					debugPrintln("=========================================")
-
-					if nic.IpNetworkId != nil && nic.Address != nil {
-						if err := c.marmot.Db.ReleaseIP(nic.Networkid, *nic.IpNetworkId, *nic.Address); err != nil {
-							slog.Error("ReleaseIP()", "err", err)
-							continue
-						}
-					}
-
-					// 内部DNSからエントリーを削除する
-					if strings.TrimSpace(spec.Metadata.Name) != "" {
-						if err := c.marmot.Db.DeleteDnsEntryByName(spec.Metadata.Name, nic.Networkname); err != nil {
-							slog.Error("DeleteDnsEntryByName()", "err", err)
-							continue
-						}
-					}
 				}
-			}
 
-			// 作成失敗時など NIC 情報だけでは解放しきれない残留 IP を回収する。
-			if strings.TrimSpace(spec.Metadata.Name) != "" {
-				if err := c.marmot.Db.ReleaseIPsByHostID(spec.Metadata.Name); err != nil {
-					slog.Error("ReleaseIPsByHostID()", "err", err, "serverId", api.ServerID(spec), "serverName", spec.Metadata.Name)
+				// 内部DNSからエントリーを削除する
+				if strings.TrimSpace(spec.Metadata.Name) != "" {
+					if err := c.marmot.Db.DeleteDnsEntryByName(spec.Metadata.Name, nic.Networkname); err != nil {
+						slog.Error("DeleteDnsEntryByName()", "err", err)
+						continue
+					}
 				}
 			}
+		}
 
-			// データベースから削除する
-			if err := c.marmot.Db.DeleteServerById(api.ServerID(spec)); err != nil {
-				slog.Error("DeleteServerById()", "err", err)
-				msg := fmt.Sprintf("サーバーのデータベースからの削除に失敗: %v", err)
-				if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
-					slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
-				}
+		// 作成失敗時など NIC 情報だけでは解放しきれない残留 IP を回収する。
+		if strings.TrimSpace(spec.Metadata.Name) != "" {
+			if err := c.marmot.Db.ReleaseIPsByHostID(spec.Metadata.Name); err != nil {
+				slog.Error("ReleaseIPsByHostID()", "err", err, "serverId", api.ServerID(spec), "serverName", spec.Metadata.Name)
 			}
+		}
 
-		case db.SERVER_PROVISIONING:
-			slog.Debug("プロビジョニング中のサーバー検出", "SERVER", api.ServerID(spec))
-			msg := "サーバーのプロビジョニングが中断されました。marmot サービス再起動後も自動再開されないため、サーバーを再作成してください"
-			if spec.Status != nil && spec.Status.Message != nil && strings.TrimSpace(*spec.Status.Message) != "" {
-				msg = *spec.Status.Message
-			}
+		// データベースから削除する
+		if err := c.marmot.Db.DeleteServerById(api.ServerID(spec)); err != nil {
+			slog.Error("DeleteServerById()", "err", err)
+			msg := fmt.Sprintf("サーバーのデータベースからの削除に失敗: %v", err)
 			if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
 				slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
 			}
+		}
 
-		default:
-			slog.Warn("不明な状態のサーバー検出", "SERVER", api.ServerID(spec), "STATUS", *spec.Status.Status)
+	case db.SERVER_PROVISIONING:
+		slog.Debug("プロビジョニング中のサーバー検出", "SERVER", api.ServerID(spec))
+		msg := "サーバーのプロビジョニングが中断されました。marmot サービス再起動後も自動再開されないため、サーバーを再作成してください"
+		if spec.Status != nil && spec.Status.Message != nil && strings.TrimSpace(*spec.Status.Message) != "" {
+			msg = *spec.Status.Message
+		}
+		if dbErr := c.marmot.Db.UpdateServerStatus(api.ServerID(spec), db.SERVER_ERROR, msg); dbErr != nil {
+			slog.Error("UpdateServerStatus() failed", "serverId", api.ServerID(spec), "err", dbErr)
 		}
+
+	default:
+		slog.Warn("不明な状態のサーバー検出", "SERVER", api.ServerID(spec), "STATUS", *spec.Status.Status)
 	}
+	return result
+}
 
-	// ワークキューから処理を取り出して、処理を実行する
+// syncServerVolumes はアタッチ・デタッチされたボリュームをドメインへ反映する
+// 失敗した場合は理由をステータスのメッセージに残して再試行する
+func (c *controller) syncServerVolumes(spec api.Server) reconcileResult {
+	id := api.ServerID(spec)
+	if err := c.marmot.SyncServerVolumesManage(id); err != nil {
+		slog.Error("SyncServerVolumesManage()", "serverId", id, "err", err)
+		msg := fmt.Sprintf("%s: %v", volumeSyncErrorMessage, err)
+		if spec.Status.Message == nil || *spec.Status.Message != msg {
+			if dbErr := c.marmot.Db.UpdateServerStatus(id, spec.Status.StatusCode, msg); dbErr != nil {
+				slog.Error("UpdateServerStatus() failed", "serverId", id, "err", dbErr)
+			}
+		}
+		return reconcileResult{retry: true}
+	}
+	if spec.Status.Message != nil && strings.HasPrefix(*spec.Status.Message, volumeSyncErrorMessage) {
+		if dbErr := c.marmot.Db.UpdateServerStatus(id, spec.Status.StatusCode, ""); dbErr != nil {
+			slog.Error("UpdateServerStatus() failed", "serverId", id, "err", dbErr)
+		}
+	}
+	return reconcileResult{}
+}
 
+// nodeMaintenance は cordon または drain されたノードのメンテナンス状態を返す。対象外なら nil
+func nodeMaintenance(statuses []api.HostStatus, nodeName string) *api.NodeMaintenance {
+	for _, st := range statuses {
+		if st.NodeName != nil && strings.TrimSpace(*st.NodeName) == nodeName && db.IsNodeUnschedulable(st) {
+			return st.Maintenance
+		}
+	}
+	return nil
 }
 
 func clusterHasAnyNode(statuses []api.HostStatus) bool {
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/marmotd"
+)
+
+// バックグラウンドで実行中のマイグレーションの完了を確認する間隔
+const SERVER_MIGRATION_POLL_INTERVAL = 2 * time.Second
+
+// サーバーマイグレーションの制御ループ
+// 移行元ノードと移行先ノードのコントローラーが、それぞれの担当する段階を処理する
+// 実行中のマイグレーションがあれば、完了を確認するために再実行の間隔を返す
+func (c *controller) serverMigrationControllerLoop() (result reconcileResult) {
+	migrations, err := c.marmot.Db.GetServerMigrations()
+	if err != nil {
+		slog.Error("GetServerMigrations()", "err", err)
+		return result
+	}
+
+	for _, mig := range migrations {
+		if db.IsMigrationFinished(mig) || mig.Spec.ServerId == nil || mig.Spec.SourceNode == nil || mig.Spec.TargetNode == nil {
+			continue
+		}
+		id := *mig.Spec.ServerId
+		onSource := strings.TrimSpace(*mig.Spec.SourceNode) == c.marmot.NodeName
+		onTarget := strings.TrimSpace(*mig.Spec.TargetNode) == c.marmot.NodeName
+
+		switch mig.Status.StatusCode {
+		case db.MIGRATION_PENDING:
+			if !onSource {
+				continue
+			}
+			slog.Debug("受付済みのマイグレーション検出", "SERVER", id)
+			if err := c.marmot.InspectServerMigrationManage(id); err != nil {
+				slog.Error("InspectServerMigrationManage()", "serverId", id, "err", err)
+				c.failServerMigration(id, db.SERVER_RUNNING, fmt.Sprintf("移行対象ディスクの確認に失敗: %v", err))
+				continue
+			}
+			c.setServerMigrationStatus(id, db.MIGRATION_PREPARING, "")
+		case db.MIGRATION_PREPARING:
+			if !onTarget {
+				continue
+			}
+			slog.Debug("移行先の準備待ちのマイグレーション検出", "SERVER", id)
+			if err := c.marmot.PrepareServerMigrationTargetManage(id); err != nil {
+				slog.Error("PrepareServerMigrationTargetManage()", "serverId", id, "err", err)
+				c.failServerMigration(id, db.SERVER_RUNNING, fmt.Sprintf("移行先ノードの準備に失敗: %v", err))
+				continue
+			}
+			c.setServerMigrationStatus(id, db.MIGRATION_PREPARED, "")
+		case db.MIGRATION_PREPARED:
+			if !onSource {
+				continue
+			}
+			slog.Debug("マイグレーション開始", "SERVER", id, "targetNode", *mig.Spec.TargetNode)
+			if dbErr := c.marmot.Db.UpdateServerMigrationStatusWithMessage(id, db.MIGRATION_MIGRATING, ""); dbErr != nil {
+				slog.Error("UpdateServerMigrationStatusWithMessage() failed", "serverId", id, "err", dbErr)
+				continue
+			}
+			// ディスクのコピーが終わるまでワーカーを待たせないよう、バックグラウンドで実行して完了を確認する
+			c.marmot.StartServerMigrationManage(id)
+			result.requeueAfter = SERVER_MIGRATION_POLL_INTERVAL
+		case db.MIGRATION_MIGRATING:
+			if !onSource {
+				continue
+			}
+			migrated, err := c.marmot.PollServerMigrationManage(id)
+			switch {
+			case err == nil:
+				// 移行先への移動と記録の更新が完了した
+			case errors.Is(err, marmotd.ErrServerMigrationInProgress):
+				result.requeueAfter = SERVER_MIGRATION_POLL_INTERVAL
+			case errors.Is(err, marmotd.ErrServerMigrationNotRunning):
+				// このプロセスで開始していないのは、marmotd の再起動で中断されたもの
+				if c.serverDomainActive(id) {
+					c.rollbackServerMigration(id, "マイグレーションが中断されました")
+					continue
+				}
+				c.failServerMigration(id, db.SERVER_ERROR, "マイグレーションが中断されました。移行先ノードの仮想マシンを確認してください")
+			case migrated:
+				slog.Error("PollServerMigrationManage()", "serverId", id, "migrated", migrated, "err", err)
+				// ドメインは移行先で稼働しているため、移行先のディスクは片付けない
+				c.failServerMigration(id, db.SERVER_ERROR, fmt.Sprintf("移行先ノード %s で稼働中ですが記録の更新に失敗: %v", *mig.Spec.TargetNode, err))
+			default:
+				slog.Error("PollServerMigrationManage()", "serverId", id, "migrated", migrated, "err", err)
+				c.rollbackServerMigration(id, fmt.Sprintf("マイグレーションに失敗: %v", err))
+			}
+		case db.MIGRATION_ROLLINGBACK:
+			if !onTarget {
+				continue
+			}
+			slog.Debug("ロールバック中のマイグレーション検出", "SERVER", id)
+			msg := ""
+			if mig.Status.Message != nil {
+				msg = *mig.Status.Message
+			}
+			if err := c.marmot.CleanupServerMigrationTargetManage(id); err != nil {
+				slog.Error("CleanupServerMigrationTargetManage()", "serverId", id, "err", err)
+				msg = fmt.Sprintf("%s (移行先ノードの片付けに失敗: %v)", msg, err)
+			}
+			c.setServerMigrationStatus(id, db.MIGRATION_FAILED, msg)
+		}
+	}
+	return result
+}
+
+func (c *controller) setServerMigrationStatus(id string, status int, msg string) {
+	if dbErr := c.marmot.Db.UpdateServerMigrationStatusWithMessage(id, status, msg); dbErr != nil {
+		slog.Error("UpdateServerMigrationStatusWithMessage() failed", "serverId", id, "err", dbErr)
+	}
+}
+
+// failServerMigration はマイグレーションを FAILED にし、サーバーの状態を serverStatus に戻す
+func (c *controller) failServerMigration(id string, serverStatus int, msg string) {
+	c.setServerMigrationStatus(id, db.MIGRATION_FAILED, msg)
+	if dbErr := c.marmot.Db.UpdateServerStatus(id, serverStatus, msg); dbErr != nil {
+		slog.Error("UpdateServerStatus() failed", "serverId", id, "err", dbErr)
+	}
+}
+
+// rollbackServerMigration はサーバーを移行元で RUNNING に戻し、移行先ノードに準備したディスクの片付けを依頼する
+func (c *controller) rollbackServerMigration(id string, msg string) {
+	c.setServerMigrationStatus(id, db.MIGRATION_ROLLINGBACK, msg)
+	if dbErr := c.marmot.Db.UpdateServerStatus(id, db.SERVER_RUNNING, msg); dbErr != nil {
+		slog.Error("UpdateServerStatus() failed", "serverId", id, "err", dbErr)
+	}
+}
+
+// serverDomainActive はサーバーのドメインがこのノードで稼働しているかを返す
+func (c *controller) serverDomainActive(id string) bool {
+	server, err := c.marmot.Db.GetServerById(id)
+	if err != nil || server.Metadata.InstanceName == nil {
+		return false
+	}
+	active, err := c.marmot.Virt.IsDomainActive(*server.Metadata.InstanceName)
+	return err == nil && active
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/marmotd"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// consistency=stop の作成とロールバックで、ゲストOSの停止を待っている間のステータスメッセージ
+const snapshotShutdownWaitMessage = "サーバーの停止を待っています"
+
+// サーバースナップショットの制御ループ
+// サーバーと同じノードで動くコントローラーだけが処理する
+func (c *controller) serverSnapshotControllerLoop() {
+	snapshots, err := c.marmot.Db.GetServerSnapshots()
+	if err != nil {
+		slog.Error("GetServerSnapshots()", "err", err)
+		return
+	}
+
+	for _, snap := range snapshots {
+		id := api.ServerSnapshotID(snap)
+		if snap.Status == nil {
+			continue
+		}
+		if ok, assignedNode, reason := evaluateNodeAssignment(&snap.Metadata, c.marmot.NodeName); !ok {
+			slog.Debug("別ノード割当のスナップショットをスキップ", "snapshotId", id, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
+			continue
+		}
+
+		switch snap.Status.StatusCode {
+		case db.SNAPSHOT_PENDING:
+			slog.Debug("作成待ちのスナップショット検出", "SNAPSHOT", id)
+			if dbErr := c.marmot.Db.UpdateServerSnapshotStatusWithMessage(id, db.SNAPSHOT_CREATING, ""); dbErr != nil {
+				slog.Error("UpdateServerSnapshotStatusWithMessage() failed", "snapshotId", id, "err", dbErr)
+				continue
+			}
+			c.createServerSnapshot(snap)
+		case db.SNAPSHOT_CREATING:
+			if util.OrDefault(snap.Status.Message, "") == snapshotShutdownWaitMessage {
+				// サーバーの停止を待っているので、作成を続ける
+				c.createServerSnapshot(snap)
+				continue
+			}
+			// それ以外の作成処理は制御ループ内で完了するため、この状態で残っているのは中断されたもの
+			c.setServerSnapshotStatus(id, db.SNAPSHOT_ERROR, "スナップショットの作成が中断されました。削除して再作成してください")
+		case db.SNAPSHOT_REVERTING:
+			slog.Debug("ロールバック要求のスナップショット検出", "SNAPSHOT", id)
+			if err := c.marmot.RevertServerSnapshotManage(id); err != nil {
+				if errors.Is(err, marmotd.ErrServerShutdownInProgress) {
+					c.setServerSnapshotWaiting(snap, db.SNAPSHOT_REVERTING)
+					continue
+				}
+				slog.Error("RevertServerSnapshotManage()", "snapshotId", id, "err", err)
+				c.setServerSnapshotStatus(id, db.SNAPSHOT_ERROR, fmt.Sprintf("スナップショットへのロールバックに失敗: %v", err))
+				continue
+			}
+			c.setServerSnapshotStatus(id, db.SNAPSHOT_AVAILABLE, "")
+		case db.SNAPSHOT_DELETING:
+			slog.Debug("削除要求のスナップショット検出", "SNAPSHOT", id)
+			if err := c.marmot.DeleteServerSnapshotManage(id); err != nil {
+				if errors.Is(err, marmotd.ErrSnapshotDeferred) {
+					c.setServerSnapshotStatus(id, db.SNAPSHOT_DELETING, "qcow2 スナップショットはサーバー停止後に削除されます")
+					continue
+				}
+				slog.Error("DeleteServerSnapshotManage()", "snapshotId", id, "err", err)
+				c.setServerSnapshotStatus(id, db.SNAPSHOT_ERROR, fmt.Sprintf("スナップショットの削除に失敗: %v", err))
+				continue
+			}
+			if err := c.marmot.Db.DeleteServerSnapshotById(id); err != nil {
+				slog.Error("DeleteServerSnapshotById()", "snapshotId", id, "err", err)
+			}
+		}
+	}
+}
+
+// createServerSnapshot はスナップショットを取得して状態を更新する
+// サーバーの停止を待つ間は CREATING のままにして、次の周期で続ける
+func (c *controller) createServerSnapshot(snap api.ServerSnapshot) {
+	id := api.ServerSnapshotID(snap)
+	if err := c.marmot.CreateServerSnapshotManage(id); err != nil {
+		if errors.Is(err, marmotd.ErrServerShutdownInProgress) {
+			c.setServerSnapshotWaiting(snap, db.SNAPSHOT_CREATING)
+			return
+		}
+		slog.Error("CreateServerSnapshotManage()", "snapshotId", id, "err", err)
+		c.setServerSnapshotStatus(id, db.SNAPSHOT_ERROR, fmt.Sprintf("スナップショットの作成に失敗: %v", err))
+		return
+	}
+	c.setServerSnapshotStatus(id, db.SNAPSHOT_AVAILABLE, "")
+}
+
+// setServerSnapshotWaiting はサーバーの停止を待っていることを記録する。記録済みなら書き込まない
+func (c *controller) setServerSnapshotWaiting(snap api.ServerSnapshot, status int) {
+	if snap.Status.StatusCode == status && util.OrDefault(snap.Status.Message, "") == snapshotShutdownWaitMessage {
+		return
+	}
+	c.setServerSnapshotStatus(api.ServerSnapshotID(snap), status, snapshotShutdownWaitMessage)
+}
+
+func (c *controller) setServerSnapshotStatus(id string, status int, msg string) {
+	if dbErr := c.marmot.Db.UpdateServerSnapshotStatusWithMessage(id, status, msg); dbErr != nil {
+		slog.Error("UpdateServerSnapshotStatusWithMessage() failed", "snapshotId", id, "err", dbErr)
+	}
+}
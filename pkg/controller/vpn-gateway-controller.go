@@ -33,81 +33,79 @@ func StartVpnGatewayController(node string, etcdUrl string) (*controller, error)
 	c.stopChan = make(chan struct{})
 	c.doneChan = make(chan struct{})
 
-	ticker := time.NewTicker(VPN_GATEWAY_CONTROLLER_INTERVAL)
-	go func() {
-		defer ticker.Stop()
-		defer close(c.doneChan)
-		for {
-			select {
-			case <-ticker.C:
-				c.vpnGatewayControllerLoop()
-			case <-c.stopChan:
-				slog.Debug("VPNゲートウェイコントローラー停止")
-				return
-			}
-		}
-	}()
+	// 変更の監視と定期実行の開始
+	keyedWatchLoop{
+		name:      "VPNゲートウェイコントローラー",
+		resync:    VPN_GATEWAY_CONTROLLER_INTERVAL,
+		primary:   db.VpnGatewayPrefix,
+		related:   []string{db.ServerPrefix},
+		reconcile: c.reconcileVpnGatewayById,
+	}.run(c.db, c.stopChan, c.doneChan)
 
 	return &c, nil
 }
 
-func (c *controller) vpnGatewayControllerLoop() {
-	slog.Debug("VPNゲートウェイコントローラーの制御ループ実行", "CONTROLLER", time.Now().Format("2006-01-02 15:04:05"))
-
-	items, err := c.db.GetVpnGateways()
+// reconcileVpnGatewayById は ID のVPNゲートウェイを読み直して調整する。削除済みなら何もしない
+func (c *controller) reconcileVpnGatewayById(id string) reconcileResult {
+	item, err := c.db.GetVpnGatewayById(id)
 	if err != nil {
-		slog.Error("GetVpnGateways() failed", "err", err)
-		return
+		if errors.Is(err, db.ErrNotFound) {
+			return reconcileResult{}
+		}
+		slog.Error("GetVpnGatewayById() failed", "id", id, "err", err)
+		return reconcileResult{retry: true}
 	}
+	return c.reconcileVpnGateway(item)
+}
 
-	for _, item := range items {
-		id := api.VpnGatewayID(item)
-		if ok, assignedNode, reason := evaluateNodeAssignment(&item.Metadata, c.marmot.NodeName); !ok {
-			slog.Debug("別ノード割当のVPNゲートウェイをスキップ", "id", id, "name", item.Metadata.Name, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
-			continue
-		}
+func (c *controller) reconcileVpnGateway(item api.VpnGateway) reconcileResult {
+	id := api.VpnGatewayID(item)
+	if ok, assignedNode, reason := evaluateNodeAssignment(&item.Metadata, c.marmot.NodeName); !ok {
+		slog.Debug("別ノード割当のVPNゲートウェイをスキップ", "id", id, "name", item.Metadata.Name, "controllerNode", c.marmot.NodeName, "assignedNode", assignedNode, "reason", reason)
+		return reconcileResult{}
+	}
 
-		if item.Status == nil || item.Status.StatusCode != db.VPN_GATEWAY_DELETING {
-			missingServer, err := c.isVpnGatewayManagedServerMissing(item)
-			if err != nil {
-				slog.Warn("failed to validate vpn gateway managed server", "id", id, "err", err)
-				continue
-			}
-			if missingServer {
-				c.deleteVpnGatewayForMissingServer(item)
-				continue
-			}
+	if item.Status == nil || item.Status.StatusCode != db.VPN_GATEWAY_DELETING {
+		missingServer, err := c.isVpnGatewayManagedServerMissing(item)
+		if err != nil {
+			slog.Warn("failed to validate vpn gateway managed server", "id", id, "err", err)
+			return reconcileResult{retry: true}
 		}
-
-		if item.Status == nil {
-			_ = c.db.UpdateVpnGatewayStatusWithMessage(id, db.VPN_GATEWAY_PENDING, "")
-			continue
+		if missingServer {
+			c.deleteVpnGatewayForMissingServer(item)
+			return reconcileResult{}
 		}
+	}
 
-		if item.Status.DeletionTimeStamp != nil && time.Since(*item.Status.DeletionTimeStamp) > c.deletionDelay {
-			if item.Status.StatusCode != db.VPN_GATEWAY_DELETING {
-				_ = c.db.UpdateVpnGatewayStatusWithMessage(id, db.VPN_GATEWAY_DELETING, "")
-				continue
-			}
-		}
+	if item.Status == nil {
+		_ = c.db.UpdateVpnGatewayStatusWithMessage(id, db.VPN_GATEWAY_PENDING, "")
+		return reconcileResult{}
+	}
 
-		switch item.Status.StatusCode {
-		case db.VPN_GATEWAY_PENDING:
-			c.reconcileVpnGatewayPending(item)
-		case db.VPN_GATEWAY_PROVISIONING:
-			c.reconcileVpnGatewayProvisioning(item)
-		case db.VPN_GATEWAY_CONFIGURING:
-			c.reconcileVpnGatewayConfiguring(item)
-		case db.VPN_GATEWAY_ACTIVE:
-			c.reconcileVpnGatewayActive(item)
-		case db.VPN_GATEWAY_DELETING:
-			c.reconcileVpnGatewayDeleting(item)
-		case db.VPN_GATEWAY_FAILED:
-			slog.Debug("FAILED 状態のVPNゲートウェイを検出", "id", id)
-		default:
-			slog.Warn("不明なVPNゲートウェイ状態", "id", id, "statusCode", item.Status.StatusCode)
+	if item.Status.DeletionTimeStamp != nil && time.Since(*item.Status.DeletionTimeStamp) > c.deletionDelay {
+		if item.Status.StatusCode != db.VPN_GATEWAY_DELETING {
+			_ = c.db.UpdateVpnGatewayStatusWithMessage(id, db.VPN_GATEWAY_DELETING, "")
+			return reconcileResult{}
 		}
 	}
+
+	switch item.Status.StatusCode {
+	case db.VPN_GATEWAY_PENDING:
+		c.reconcileVpnGatewayPending(item)
+	case db.VPN_GATEWAY_PROVISIONING:
+		c.reconcileVpnGatewayProvisioning(item)
+	case db.VPN_GATEWAY_CONFIGURING:
+		c.reconcileVpnGatewayConfiguring(item)
+	case db.VPN_GATEWAY_ACTIVE:
+		c.reconcileVpnGatewayActive(item)
+	case db.VPN_GATEWAY_DELETING:
+		c.reconcileVpnGatewayDeleting(item)
+	case db.VPN_GATEWAY_FAILED:
+		slog.Debug("FAILED 状態のVPNゲートウェイを検出", "id", id)
+	default:
+		slog.Warn("不明なVPNゲートウェイ状態", "id", id, "statusCode", item.Status.StatusCode)
+	}
+	return reconcileResult{}
 }
 
 func (c *controller) isVpnGatewayManagedServerMissing(vpnGateway api.VpnGateway) (bool, error) {
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/takara9/marmot/pkg/db"
+)
+
+// 同じキーを連続して調整する場合の最小間隔
+// 調整自身の書き込みによる変更通知で、調整が空回りし続けないようにする
+const watchLoopMinInterval = 1 * time.Second
+
+// 全リソースを調整し直すためにワークキューへ投入するキー
+const controllerLoopKey = "*"
+
+// reconcileResult はキー単位の調整結果。ワークキューへの再投入に使う
+type reconcileResult struct {
+	retry        bool          // 一時的な失敗のため、待ち時間を延ばしながら再試行する
+	requeueAfter time.Duration // 指定時間の経過後にもう一度調整する
+}
+
+// requeue は調整結果に応じてキーをワークキューへ戻す
+func (r reconcileResult) requeue(q *workQueue, key string) {
+	if r.retry {
+		q.AddRateLimited(key)
+		return
+	}
+	q.Forget(key)
+	if r.requeueAfter > 0 {
+		q.AddAfter(key, r.requeueAfter)
+	}
+}
+
+// watchPrefixes はプレフィックスごとにインフォーマーを起動し、変更があれば onChange を呼び出す
+// インフォーマーのキャッシュは同じデータベースの一覧取得にも使われる
+func watchPrefixes(d *db.Database, stop <-chan struct{}, onChange func(db.InformerEvent), prefixes ...string) []*db.Informer {
+	informers := make([]*db.Informer, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		inf := d.NewInformer(prefix, 0)
+		inf.AddHandler(onChange)
+		go inf.Run(stop)
+		informers = append(informers, inf)
+	}
+	return informers
+}
+
+// keyedWatchLoop はリソースのIDをキーにしてワークキューで調整する制御ループ
+// キーごとに重複をまとめ、失敗したキーだけを待ち時間を延ばしながら再試行する
+type keyedWatchLoop struct {
+	name    string
+	resync  time.Duration // 取りこぼしに備えて全リソースを調整し直す間隔
+	primary string        // ID ごとに調整するリソースのプレフィックス
+	related []string      // 変更があれば primary の全リソースを調整し直すプレフィックス
+	// 全リソースを調整し直す前に1回だけ実行する、リソースをまたがる処理。nil なら何もしない
+	prepare func()
+	// reconcile はIDのリソースを etcd から読み直して調整する
+	reconcile func(id string) reconcileResult
+}
+
+// run は watch と定期実行を開始する。stop が閉じられると終了し、done を閉じる
+func (l keyedWatchLoop) run(d *db.Database, stop <-chan struct{}, done chan struct{}) {
+	q := newWorkQueue()
+	primary := d.NewInformer(l.primary, 0)
+	primary.AddHandler(func(ev db.InformerEvent) {
+		if id := resourceIdFromKey(l.primary, ev.Key); id != "" && ev.Type != db.EVENT_DELETE {
+			q.Add(id)
+		}
+	})
+	go primary.Run(stop)
+	watchPrefixes(d, stop, func(db.InformerEvent) { q.Add(controllerLoopKey) }, l.related...)
+	q.Add(controllerLoopKey)
+
+	go func() {
+		ticker := time.NewTicker(l.resync)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				q.Add(controllerLoopKey)
+			case <-stop:
+				q.ShutDown()
+				return
+			}
+		}
+	}()
+
+	go func() {
+		defer close(done)
+		for {
+			key, ok := q.Get()
+			if !ok {
+				slog.Debug(l.name + "停止")
+				return
+			}
+			if key == controllerLoopKey {
+				if l.prepare != nil {
+					l.prepare()
+				}
+				for _, k := range primary.Keys() {
+					if id := resourceIdFromKey(l.primary, k); id != "" {
+						q.Add(id)
+					}
+				}
+			} else {
+				l.reconcile(key).requeue(q, key)
+			}
+			q.DoneAfter(key, watchLoopMinInterval)
+		}
+	}()
+}
+
+// resourceIdFromKey は etcd のキーから prefix 直下のリソースIDを取り出す。リソースのキーでなければ空文字
+func resourceIdFromKey(prefix, key string) string {
+	id, ok := strings.CutPrefix(key, prefix+"/")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"sync"
+	"time"
+)
+
+// 再試行の待ち時間の初期値と上限
+const (
+	workQueueBaseDelay = 500 * time.Millisecond
+	workQueueMaxDelay  = 30 * time.Second
+)
+
+// workQueue はキー単位で重複を排除するワークキュー
+// 処理中のキーが再投入された場合は処理の完了後にもう一度取り出される
+// 同じキーが同時に複数のワーカーへ渡されることはない
+// AddRateLimited で再試行を待っているキーは、変更通知で再投入されても待ち時間が過ぎるまで取り出さない
+type workQueue struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	queue      []string
+	dirty      map[string]struct{}
+	processing map[string]struct{}
+	failures   map[string]int
+	notBefore  map[string]time.Time
+	waiting    map[string]*delayedKey
+	shutdown   bool
+}
+
+// delayedKey は AddAfter で投入を待っているキー
+type delayedKey struct {
+	at    time.Time
+	timer *time.Timer
+}
+
+func newWorkQueue() *workQueue {
+	q := &workQueue{
+		dirty:      map[string]struct{}{},
+		processing: map[string]struct{}{},
+		failures:   map[string]int{},
+		notBefore:  map[string]time.Time{},
+		waiting:    map[string]*delayedKey{},
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// Add はキーを投入する。既に待機中のキーは追加しない
+func (q *workQueue) Add(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.add(key)
+}
+
+func (q *workQueue) add(key string) {
+	if q.shutdown {
+		return
+	}
+	if at, ok := q.notBefore[key]; ok {
+		if wait := time.Until(at); wait > 0 {
+			q.addAfter(key, wait)
+			return
+		}
+	}
+	if _, ok := q.dirty[key]; ok {
+		return
+	}
+	q.dirty[key] = struct{}{}
+	if _, ok := q.processing[key]; ok {
+		return
+	}
+	q.queue = append(q.queue, key)
+	q.cond.Signal()
+}
+
+// AddAfter は指定時間の経過後にキーを投入する
+// 同じキーの待機中の投入がある場合は、早く投入される方を残す
+func (q *workQueue) AddAfter(key string, d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if d <= 0 {
+		q.add(key)
+		return
+	}
+	q.addAfter(key, d)
+}
+
+func (q *workQueue) addAfter(key string, d time.Duration) {
+	if q.shutdown {
+		return
+	}
+	at := time.Now().Add(d)
+	if w, ok := q.waiting[key]; ok {
+		if !w.at.After(at) {
+			return
+		}
+		w.timer.Stop()
+	}
+	w := &delayedKey{at: at}
+	w.timer = time.AfterFunc(d, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if q.waiting[key] != w {
+			return
+		}
+		delete(q.waiting, key)
+		q.add(key)
+	})
+	q.waiting[key] = w
+}
+
+// AddRateLimited は失敗回数に応じて指数的に延びる待ち時間の後にキーを投入する
+func (q *workQueue) AddRateLimited(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	d := backoffDelay(q.failures[key])
+	q.failures[key]++
+	q.notBefore[key] = time.Now().Add(d)
+	q.addAfter(key, d)
+}
+
+// Forget はキーの失敗回数をリセットする。処理に成功した時に呼び出す
+func (q *workQueue) Forget(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.failures, key)
+	delete(q.notBefore, key)
+}
+
+// Retries はキーの連続失敗回数を返す
+func (q *workQueue) Retries(key string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.failures[key]
+}
+
+// Get はキーを取り出す。キューが空の場合は投入されるまで待つ
+// ShutDown された場合は false を返す
+func (q *workQueue) Get() (string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.queue) == 0 && !q.shutdown {
+		q.cond.Wait()
+	}
+	if q.shutdown {
+		return "", false
+	}
+	key := q.queue[0]
+	q.queue = q.queue[1:]
+	delete(q.dirty, key)
+	q.processing[key] = struct{}{}
+	return key, true
+}
+
+// Done はキーの処理の完了を通知する。処理中に再投入されていればキューに戻す
+func (q *workQueue) Done(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.processing, key)
+	if _, ok := q.dirty[key]; !ok || q.shutdown {
+		return
+	}
+	// 処理中に再試行の待ち時間が設定された場合は、待ち時間が過ぎてから取り出す
+	if at, ok := q.notBefore[key]; ok && time.Now().Before(at) {
+		delete(q.dirty, key)
+		q.addAfter(key, time.Until(at))
+		return
+	}
+	q.queue = append(q.queue, key)
+	q.cond.Signal()
+}
+
+// DoneAfter は Done と同じく完了を通知するが、処理中に再投入されていれば d の経過後に取り出す
+// 処理自身の書き込みによる変更通知で、同じキーの処理が空回りし続けないようにする
+func (q *workQueue) DoneAfter(key string, d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.processing, key)
+	if _, ok := q.dirty[key]; !ok || q.shutdown {
+		return
+	}
+	delete(q.dirty, key)
+	if at, ok := q.notBefore[key]; ok && time.Until(at) > d {
+		d = time.Until(at)
+	}
+	q.addAfter(key, d)
+}
+
+// Len は待機中のキーの数を返す
+func (q *workQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queue)
+}
+
+// ShutDown はキューを停止し、Get で待機しているワーカーを解放する
+func (q *workQueue) ShutDown() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.shutdown = true
+	for key, w := range q.waiting {
+		w.timer.Stop()
+		delete(q.waiting, key)
+	}
+	q.cond.Broadcast()
+}
+
+func backoffDelay(failures int) time.Duration {
+	d := workQueueBaseDelay
+	for i := 0; i < failures; i++ {
+		d *= 2
+		if d >= workQueueMaxDelay {
+			return workQueueMaxDelay
+		}
+	}
+	return d
+}
@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/pkg/db"
+)
+
+func TestWorkQueue_DeduplicatesPendingKeys(t *testing.T) {
+	q := newWorkQueue()
+	q.Add("a")
+	q.Add("b")
+	q.Add("a")
+	if got := q.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+	key, ok := q.Get()
+	if !ok || key != "a" {
+		t.Fatalf("Get() = %q, %v, want a, true", key, ok)
+	}
+}
+
+func TestWorkQueue_RequeuesKeyAddedWhileProcessing(t *testing.T) {
+	q := newWorkQueue()
+	q.Add("a")
+	key, _ := q.Get()
+
+	// 処理中のキーは二重に取り出さない
+	q.Add("a")
+	if got := q.Len(); got != 0 {
+		t.Fatalf("Len() while processing = %d, want 0", got)
+	}
+
+	q.Done(key)
+	if got := q.Len(); got != 1 {
+		t.Fatalf("Len() after Done = %d, want 1", got)
+	}
+}
+
+func TestWorkQueue_DoneAfterDelaysKeyAddedWhileProcessing(t *testing.T) {
+	q := newWorkQueue()
+	q.Add("a")
+	q.Add("b")
+	key, _ := q.Get()
+
+	// 処理中の書き込みで再投入されたキーは、最小間隔が過ぎてから取り出す
+	q.Add("a")
+	q.DoneAfter(key, 50*time.Millisecond)
+	if got := q.Len(); got != 1 {
+		t.Fatalf("Len() after DoneAfter = %d, want 1 (only b)", got)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := q.Len(); got != 2 {
+		t.Fatalf("Len() after the interval = %d, want 2", got)
+	}
+
+	// 再投入されていなければ何もしない
+	key, _ = q.Get()
+	q.DoneAfter(key, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if got := q.Len(); got != 1 {
+		t.Fatalf("Len() after DoneAfter without re-add = %d, want 1", got)
+	}
+}
+
+func TestWorkQueue_AddAfterKeepsEarliest(t *testing.T) {
+	q := newWorkQueue()
+	q.AddAfter("a", time.Hour)
+	q.AddAfter("a", 10*time.Millisecond)
+	q.AddAfter("a", time.Hour)
+
+	done := make(chan string, 1)
+	go func() {
+		key, _ := q.Get()
+		done <- key
+	}()
+	select {
+	case key := <-done:
+		if key != "a" {
+			t.Fatalf("Get() = %q, want a", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("AddAfter() key was not added")
+	}
+}
+
+func TestWorkQueue_RateLimitedKeyWaitsForBackoff(t *testing.T) {
+	q := newWorkQueue()
+	q.AddRateLimited("a")
+	q.AddRateLimited("a")
+	if got := q.Retries("a"); got != 2 {
+		t.Fatalf("Retries() = %d, want 2", got)
+	}
+
+	// 再試行の待ち時間中は変更通知で投入されても取り出さない
+	q.Add("a")
+	if got := q.Len(); got != 0 {
+		t.Fatalf("Len() during backoff = %d, want 0", got)
+	}
+
+	q.Forget("a")
+	if got := q.Retries("a"); got != 0 {
+		t.Fatalf("Retries() after Forget = %d, want 0", got)
+	}
+	q.Add("a")
+	if got := q.Len(); got != 1 {
+		t.Fatalf("Len() after Forget = %d, want 1", got)
+	}
+}
+
+func TestWorkQueue_ShutDownReleasesGet(t *testing.T) {
+	q := newWorkQueue()
+	done := make(chan bool, 1)
+	go func() {
+		_, ok := q.Get()
+		done <- ok
+	}()
+	q.ShutDown()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("Get() after ShutDown returned ok")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Get() was not released by ShutDown")
+	}
+	q.Add("a")
+	if got := q.Len(); got != 0 {
+		t.Fatalf("Len() after ShutDown = %d, want 0", got)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	if got := backoffDelay(0); got != workQueueBaseDelay {
+		t.Fatalf("backoffDelay(0) = %v, want %v", got, workQueueBaseDelay)
+	}
+	if got := backoffDelay(2); got != 4*workQueueBaseDelay {
+		t.Fatalf("backoffDelay(2) = %v, want %v", got, 4*workQueueBaseDelay)
+	}
+	if got := backoffDelay(100); got != workQueueMaxDelay {
+		t.Fatalf("backoffDelay(100) = %v, want %v", got, workQueueMaxDelay)
+	}
+}
+
+func TestServerIdFromKey(t *testing.T) {
+	cases := map[string]string{
+		"/marmot/server/abc12":     "abc12",
+		"/marmot/server/":          "",
+		"/marmot/server/abc12/sub": "",
+		"/marmot/volume/abc12":     "",
+	}
+	for key, want := range cases {
+		if got := serverIdFromKey(key); got != want {
+			t.Errorf("serverIdFromKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestResourceIdFromKey(t *testing.T) {
+	cases := map[string]string{
+		"/marmot/network/net1":                  "net1",
+		"/marmot/network/net1/ip_network/ab12c": "",
+		"/marmot/network-load-balancer/nlb1":    "",
+		"/marmot/network/":                      "",
+	}
+	for key, want := range cases {
+		if got := resourceIdFromKey(db.NetworkPrefix, key); got != want {
+			t.Errorf("resourceIdFromKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
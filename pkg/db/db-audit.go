@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+	etcd "go.etcd.io/etcd/client/v3"
+)
+
+/*
+API の更新系リクエストの監査記録を保存する
+
+	/marmot/audit/<UnixNano 20桁>-<ID>  => api.AuditRecord
+
+キーが時刻の順に並ぶため、期間の検索と保持期間を過ぎた記録の削除をキーの範囲で行う
+*/
+
+const AuditPrefix = "/marmot/audit"
+
+const (
+	AUDIT_SUCCESS = "success" // 成功
+	AUDIT_FAILURE = "failure" // リクエストの誤りや処理の失敗
+	AUDIT_DENIED  = "denied"  // 認証または認可の失敗
+)
+
+// 監査記録を検索する際に一度に読み出す件数
+const auditScanBatch = 500
+
+// AuditFilter は監査記録の検索条件。空の項目は条件にしない
+type AuditFilter struct {
+	UserID      string
+	OperationID string
+	Resource    string
+	ResourceID  string
+	Result      string
+	Since       time.Time // この時刻以降
+	Until       time.Time // この時刻より前
+	Limit       int
+}
+
+// auditTimeKey は指定した時刻に記録された監査記録のキーの先頭部分を返す
+func auditTimeKey(t time.Time) string {
+	return fmt.Sprintf("%s/%020d", AuditPrefix, t.UnixNano())
+}
+
+// auditRecordMatches は監査記録が検索条件に一致するかを返す
+func auditRecordMatches(rec api.AuditRecord, filter AuditFilter) bool {
+	switch {
+	case filter.UserID != "" && util.OrDefault(rec.UserId, "") != filter.UserID:
+		return false
+	case filter.OperationID != "" && rec.OperationId != filter.OperationID:
+		return false
+	case filter.Resource != "" && util.OrDefault(rec.Resource, "") != filter.Resource:
+		return false
+	case filter.ResourceID != "" && util.OrDefault(rec.ResourceId, "") != filter.ResourceID:
+		return false
+	case filter.Result != "" && rec.Result != filter.Result:
+		return false
+	}
+	return true
+}
+
+// 監査記録を保存する。ID と時刻が無い場合は付与する
+func (d *Database) PutAuditRecord(rec api.AuditRecord) (api.AuditRecord, error) {
+	if rec.Timestamp.IsZero() {
+		rec.Timestamp = time.Now()
+	}
+	rec.Timestamp = rec.Timestamp.UTC()
+	if rec.Id == "" {
+		rec.Id = uuid.New().String()[:8]
+	}
+	key := auditTimeKey(rec.Timestamp) + "-" + rec.Id
+	if err := d.PutJSON(key, rec); err != nil {
+		slog.Error("failed to write audit record", "err", err, "key", key)
+		return api.AuditRecord{}, err
+	}
+	return rec, nil
+}
+
+// 監査記録を新しい順に検索する
+func (d *Database) GetAuditRecords(filter AuditFilter) ([]api.AuditRecord, error) {
+	start := AuditPrefix + "/"
+	if !filter.Since.IsZero() {
+		start = auditTimeKey(filter.Since)
+	}
+	end := etcd.GetPrefixRangeEnd(AuditPrefix + "/")
+	if !filter.Until.IsZero() {
+		end = auditTimeKey(filter.Until)
+	}
+
+	records := make([]api.AuditRecord, 0)
+	for start < end {
+		ctx, cancel := context.WithTimeout(d.Ctx, 5*time.Second)
+		resp, err := d.Cli.Get(ctx, start,
+			etcd.WithRange(end),
+			etcd.WithSort(etcd.SortByKey, etcd.SortDescend),
+			etcd.WithLimit(auditScanBatch))
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("etcd get audit records failed: %w", err)
+		}
+		for _, kv := range resp.Kvs {
+			var rec api.AuditRecord
+			if err := json.Unmarshal(kv.Value, &rec); err != nil {
+				slog.Warn("skipped malformed audit record", "err", err, "key", string(kv.Key))
+				continue
+			}
+			if !auditRecordMatches(rec, filter) {
+				continue
+			}
+			records = append(records, rec)
+			if filter.Limit > 0 && len(records) >= filter.Limit {
+				return records, nil
+			}
+		}
+		if !resp.More || len(resp.Kvs) == 0 {
+			break
+		}
+		// 次は読み出した最も古いキーより前を読む
+		end = string(resp.Kvs[len(resp.Kvs)-1].Key)
+	}
+	return records, nil
+}
+
+// 指定した時刻より前の監査記録を削除して、削除した件数を返す
+func (d *Database) DeleteAuditRecordsBefore(t time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(d.Ctx, 30*time.Second)
+	defer cancel()
+	resp, err := d.Cli.Delete(ctx, AuditPrefix+"/", etcd.WithRange(auditTimeKey(t)))
+	if err != nil {
+		return 0, fmt.Errorf("etcd delete audit records failed: %w", err)
+	}
+	return resp.Deleted, nil
+}
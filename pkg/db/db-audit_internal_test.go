@@ -0,0 +1,64 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestAuditTimeKeyOrder(t *testing.T) {
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		time.Date(2001, 9, 9, 1, 46, 39, 0, time.UTC),
+		base,
+		base.Add(time.Nanosecond),
+		base.Add(time.Hour),
+	}
+	for i := 1; i < len(times); i++ {
+		prev, next := auditTimeKey(times[i-1]), auditTimeKey(times[i])
+		if prev >= next {
+			t.Fatalf("auditTimeKey(%v) = %q is not before auditTimeKey(%v) = %q", times[i-1], prev, times[i], next)
+		}
+	}
+
+	// 同じ時刻の記録は、その時刻を開始とする範囲に含まれ、終了とする範囲に含まれない
+	key := auditTimeKey(base) + "-ab12cd34"
+	if key < auditTimeKey(base) || key >= auditTimeKey(base.Add(time.Nanosecond)) {
+		t.Fatalf("key %q is out of the range of its timestamp", key)
+	}
+}
+
+func TestAuditRecordMatches(t *testing.T) {
+	rec := api.AuditRecord{
+		UserId:      util.StringPtr("alice"),
+		OperationId: "apiDeleteServerById",
+		Resource:    util.StringPtr("Server"),
+		ResourceId:  util.StringPtr("ab12c"),
+		Result:      AUDIT_SUCCESS,
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   bool
+	}{
+		{"no filter", AuditFilter{}, true},
+		{"all matched", AuditFilter{UserID: "alice", OperationID: "apiDeleteServerById", Resource: "Server", ResourceID: "ab12c", Result: AUDIT_SUCCESS}, true},
+		{"other user", AuditFilter{UserID: "bob"}, false},
+		{"other operation", AuditFilter{OperationID: "apiCreateServer"}, false},
+		{"other resource", AuditFilter{Resource: "Volume"}, false},
+		{"other resource id", AuditFilter{ResourceID: "cd34e"}, false},
+		{"other result", AuditFilter{Result: AUDIT_DENIED}, false},
+	}
+	for _, tt := range tests {
+		if got := auditRecordMatches(rec, tt.filter); got != tt.want {
+			t.Errorf("%s: auditRecordMatches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if auditRecordMatches(api.AuditRecord{}, AuditFilter{UserID: "alice"}) {
+		t.Error("auditRecordMatches() = true for a record without user, want false")
+	}
+}
@@ -71,7 +71,9 @@ func builtinRoles() map[string]api.Role {
 			permission("VpnGateway", "create", "read", "update", "delete"),
 			permission("NetworkLoadBalancer", "create", "read", "update", "delete"),
 			permission("ApplicationLoadBalancer", "create", "read", "update", "delete"),
+			permission("Certificate", "create", "read", "update", "delete"),
 			permission("KubernetesEngine", "create", "read", "delete"),
+			permission("Job", "create", "read", "update", "delete"),
 			permission("User", "create", "read", "update", "delete"),
 		}),
 		"Network-Administrator": roleTemplate("Network-Administrator", "Network administration access", []api.Permission{
@@ -83,7 +85,9 @@ func builtinRoles() map[string]api.Role {
 			permission("VpnGateway", "create", "read", "update", "delete"),
 			permission("NetworkLoadBalancer", "create", "read", "update", "delete"),
 			permission("ApplicationLoadBalancer", "create", "read", "update", "delete"),
+			permission("Certificate", "create", "read", "update", "delete"),
 			permission("KubernetesEngine", "read"),
+			permission("Job", "read"),
 			permission("User", "read"),
 		}),
 		"Compute-Operator": roleTemplate("Compute-Operator", "Compute operations access", []api.Permission{
@@ -95,7 +99,9 @@ func builtinRoles() map[string]api.Role {
 			permission("VpnGateway", "read"),
 			permission("NetworkLoadBalancer", "read"),
 			permission("ApplicationLoadBalancer", "read"),
+			permission("Certificate", "read"),
 			permission("KubernetesEngine", "create", "read", "delete"),
+			permission("Job", "read", "update"),
 			permission("User", "read"),
 		}),
 		"Viewer": roleTemplate("Viewer", "Read-only access", []api.Permission{
@@ -107,7 +113,9 @@ func builtinRoles() map[string]api.Role {
 			permission("VpnGateway", "read"),
 			permission("NetworkLoadBalancer", "read"),
 			permission("ApplicationLoadBalancer", "read"),
+			permission("Certificate", "read"),
 			permission("KubernetesEngine", "read"),
+			permission("Job", "read"),
 			permission("User", "read"),
 		}),
 	}
@@ -129,6 +137,7 @@ func roleTemplate(name, description string, permissions []api.Permission) api.Ro
 			Description: util.StringPtr(description),
 			Permissions: permissions,
 		},
+		Status: &api.RoleStatus{Builtin: true},
 	}
 }
 
@@ -277,15 +286,11 @@ func userHasPermission(user api.User, resource, verb string, roles map[string]ap
 	return false
 }
 
+// roleAllows はロールで操作が許可されるかを返す。ラベルの条件のある権限は対象のリソースがないため評価しない
 func roleAllows(role api.Role, resource, verb string) bool {
 	for _, perm := range role.Spec.Permissions {
-		if !strings.EqualFold(strings.TrimSpace(perm.Resource), strings.TrimSpace(resource)) {
-			continue
-		}
-		for _, candidate := range perm.Verbs {
-			if strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(verb)) {
-				return true
-			}
+		if perm.LabelSelector == nil && permissionMatches(perm, resource, verb) {
+			return true
 		}
 	}
 	return false
@@ -373,10 +378,12 @@ func (d *Database) EnsureBootstrapAdmin() error {
 func (d *Database) GetUserById(userID string) (api.User, error) {
 	var user api.User
 	key := userKey(userID)
-	if _, err := d.GetJSON(key, &user); err != nil {
+	resp, err := d.GetJSON(key, &user)
+	if err != nil {
 		return api.User{}, err
 	}
 	normalizeUserIdentity(&user, userID)
+	setResourceVersion(&user.Metadata, resp.Kvs[0].ModRevision)
 	return user, nil
 }
 
@@ -402,6 +409,7 @@ func (d *Database) ListUsers() (api.Users, error) {
 			userID = userID[:idx]
 		}
 		normalizeUserIdentity(&user, userID)
+		setResourceVersion(&user.Metadata, kv.ModRevision)
 		users = append(users, user)
 	}
 	sort.Slice(users, func(i, j int) bool { return users[i].Metadata.Id < users[j].Metadata.Id })
@@ -410,6 +418,12 @@ func (d *Database) ListUsers() (api.Users, error) {
 
 // UpdateUser updates the stored user.
 func (d *Database) UpdateUser(userID string, input api.User) error {
+	return d.UpdateUserWithResourceVersion(userID, input, "")
+}
+
+// UpdateUserWithResourceVersion updates the stored user only when resourceVersion,
+// if given, matches the current one. Otherwise it returns ErrResourceVersionConflict.
+func (d *Database) UpdateUserWithResourceVersion(userID string, input api.User, resourceVersion string) error {
 	key := userKey(userID)
 	mutex, err := d.LockKey(key)
 	if err != nil {
@@ -422,9 +436,12 @@ func (d *Database) UpdateUser(userID string, input api.User) error {
 	if err != nil {
 		return err
 	}
-	_ = resp
+	if err := checkResourceVersion(resourceVersion, resp.Kvs[0].ModRevision); err != nil {
+		return err
+	}
 	util.PatchStruct(&current, input)
 	current.Metadata.Id = strings.TrimSpace(userID)
+	current.Metadata.ResourceVersion = nil
 	if strings.TrimSpace(current.Metadata.Name) == "" {
 		current.Metadata.Name = current.Metadata.Id
 	}
@@ -470,6 +487,9 @@ func (d *Database) SetUserPasswordHash(userID, passwordHash string, mustChangePa
 	if err != nil {
 		return err
 	}
+	if isOIDCUser(user) {
+		return fmt.Errorf("%w: the password of %q is managed by the identity provider", ErrExternalUser, userID)
+	}
 	if user.Status == nil {
 		user.Status = &api.UserStatus{}
 	}
@@ -596,9 +616,13 @@ func (d *Database) recordUserLogin(userID string) error {
 	return d.PutJSONCAS(key, resp.Kvs[0].ModRevision, user)
 }
 
-// ListRoles returns the built-in role catalog.
+// ListRoles returns the built-in roles followed by the custom roles.
 func (d *Database) ListRoles() (api.Roles, error) {
-	roles := make(api.Roles, 0, len(builtinRoleNames))
+	custom, err := d.GetCustomRoles()
+	if err != nil {
+		return nil, err
+	}
+	roles := make(api.Roles, 0, len(builtinRoleNames)+len(custom))
 	templates := builtinRoles()
 	for _, name := range builtinRoleNames {
 		role, ok := templates[name]
@@ -607,16 +631,23 @@ func (d *Database) ListRoles() (api.Roles, error) {
 		}
 		roles = append(roles, role)
 	}
-	return roles, nil
+	return append(roles, custom...), nil
 }
 
-// GetRoleByName returns a built-in role by name.
+// GetRoleByName returns a built-in or custom role by name.
 func (d *Database) GetRoleByName(roleName string) (api.Role, error) {
-	role, ok := builtinRoles()[strings.TrimSpace(roleName)]
-	if !ok {
-		return api.Role{}, ErrNotFound
+	if role, ok := builtinRoles()[strings.TrimSpace(roleName)]; ok {
+		normalizeRoleIdentity(&role, roleName)
+		return role, nil
+	}
+	var role api.Role
+	resp, err := d.GetJSON(roleKey(roleName), &role)
+	if err != nil {
+		return api.Role{}, err
 	}
 	normalizeRoleIdentity(&role, roleName)
+	setResourceVersion(&role.Metadata, resp.Kvs[0].ModRevision)
+	role.Status = &api.RoleStatus{Builtin: false}
 	return role, nil
 }
 
@@ -658,6 +689,9 @@ func (d *Database) AddUserRole(userID, roleName string) error {
 	if err != nil {
 		return err
 	}
+	if isOIDCUser(user) {
+		return fmt.Errorf("%w: the roles of %q follow the group claims", ErrExternalUser, userID)
+	}
 	if user.Spec.Roles == nil {
 		roles := []string{}
 		user.Spec.Roles = &roles
@@ -687,6 +721,9 @@ func (d *Database) DeleteUserRole(userID, roleName string) error {
 	if err != nil {
 		return err
 	}
+	if isOIDCUser(user) {
+		return fmt.Errorf("%w: the roles of %q follow the group claims", ErrExternalUser, userID)
+	}
 	if user.Spec.Roles == nil || len(*user.Spec.Roles) == 0 {
 		return nil
 	}
@@ -711,7 +748,11 @@ func (d *Database) Authorize(userID, resource, verb string) (bool, error) {
 	if !user.Spec.Enabled || isUserLocked(user) {
 		return false, nil
 	}
-	if userHasPermission(user, resource, verb, builtinRoles()) {
+	roles, err := d.roleCatalog()
+	if err != nil {
+		return false, err
+	}
+	if userHasPermission(user, resource, verb, roles) {
 		return true, nil
 	}
 	return false, nil
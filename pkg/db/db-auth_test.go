@@ -138,6 +138,81 @@ var _ = Describe("Auth", Ordered, func() {
 			Expect(assigned).NotTo(ContainElement("Viewer"))
 		})
 
+		It("manages custom roles with label selectors", func() {
+			created, err := d.CreateRole(api.Role{
+				Metadata: api.Metadata{Name: "dev-operator"},
+				Spec: api.RoleSpec{Permissions: []api.Permission{
+					{Resource: "Server", Verbs: []string{"read"}},
+					{Resource: "Server", Verbs: []string{"delete"}, LabelSelector: &api.RoleLabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+				}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(created.Status.Builtin).To(BeFalse())
+
+			_, err = d.CreateRole(api.Role{Metadata: api.Metadata{Name: "Viewer"}, Spec: created.Spec})
+			Expect(err).To(MatchError(db.ErrFound))
+
+			roles, err := d.ListRoles()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(roles[len(roles)-1].Metadata.Name).To(Equal("dev-operator"))
+
+			Expect(d.AddUserRole(userID, "dev-operator")).To(Succeed())
+			allowed, err := d.Authorize(userID, "Server", "read")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeTrue())
+			allowed, err = d.Authorize(userID, "Server", "delete")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeFalse())
+			allowed, err = d.AuthorizeResource(userID, "Server", "delete", api.Metadata{Labels: &map[string]interface{}{"env": "dev"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeTrue())
+
+			Expect(d.DeleteRoleByName("dev-operator")).To(MatchError(db.ErrRoleInUse))
+			Expect(d.DeleteRoleByName("Viewer")).To(MatchError(db.ErrBuiltinRole))
+
+			updated, err := d.UpdateRoleByName("dev-operator", api.Role{Spec: api.RoleSpec{Permissions: []api.Permission{
+				{Resource: "Volume", Verbs: []string{"read"}},
+			}}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.Spec.Permissions).To(HaveLen(1))
+			allowed, err = d.Authorize(userID, "Server", "read")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeFalse())
+
+			Expect(d.DeleteUserRole(userID, "dev-operator")).To(Succeed())
+			Expect(d.DeleteRoleByName("dev-operator")).To(Succeed())
+			_, err = d.GetRoleByName("dev-operator")
+			Expect(err).To(MatchError(db.ErrNotFound))
+		})
+
+		It("registers OIDC users and follows their group roles", func() {
+			user, err := d.SyncOIDCUser(db.ExternalUser{UserID: "oidc-alice", DisplayName: "Alice", Roles: []string{"Viewer", "Compute-Operator", "Viewer"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*user.Spec.IdentityProvider).To(Equal(db.IdentityProviderOIDC))
+			Expect(*user.Spec.Roles).To(Equal([]string{"Compute-Operator", "Viewer"}))
+			allowed, err := d.Authorize("oidc-alice", "Server", "create")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeTrue())
+
+			user, err = d.SyncOIDCUser(db.ExternalUser{UserID: "oidc-alice", DisplayName: "Alice", Roles: []string{"Viewer"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*user.Spec.Roles).To(Equal([]string{"Viewer"}))
+			allowed, err = d.Authorize("oidc-alice", "Server", "create")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(allowed).To(BeFalse())
+
+			Expect(d.AddUserRole("oidc-alice", "Administrator")).To(MatchError(db.ErrExternalUser))
+			Expect(d.SetUserPasswordHash("oidc-alice", "hash", nil)).To(MatchError(db.ErrExternalUser))
+
+			_, err = d.SyncOIDCUser(db.ExternalUser{UserID: userID, Roles: []string{"Administrator"}})
+			Expect(err).To(MatchError(db.ErrInvalidCredentials))
+
+			Expect(d.LockUserById("oidc-alice")).To(Succeed())
+			_, err = d.SyncOIDCUser(db.ExternalUser{UserID: "oidc-alice", Roles: []string{"Viewer"}})
+			Expect(err).To(MatchError(db.ErrUserLocked))
+			Expect(d.DeleteUserById("oidc-alice")).To(Succeed())
+		})
+
 		It("creates, resolves, and revokes API keys", func() {
 			fromIP := "192.0.2.10"
 			apiKey, token, err := d.CreateUserApiKey(userID, api.ApiKeyCreateRequest{
@@ -173,6 +248,26 @@ var _ = Describe("Auth", Ordered, func() {
 			Expect(err).To(HaveOccurred())
 		})
 
+		It("takes a console token only once", func() {
+			now := time.Now()
+			Expect(d.PutConsoleToken("console-token", db.ConsoleToken{
+				ServerId:  " s0001 ",
+				UserId:    "alice",
+				IssuedAt:  now,
+				ExpiresAt: now.Add(time.Minute),
+			})).To(Succeed())
+
+			rec, err := d.TakeConsoleToken("console-token")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rec.ServerId).To(Equal("s0001"))
+			Expect(rec.UserId).To(Equal("alice"))
+
+			_, err = d.TakeConsoleToken("console-token")
+			Expect(err).To(MatchError(db.ErrNotFound))
+			_, err = d.TakeConsoleToken(" ")
+			Expect(err).To(MatchError(db.ErrNotFound))
+		})
+
 		It("physically cleans up revoked API keys after threshold", func() {
 			targetUserID := userID
 			if targetUserID == "" {
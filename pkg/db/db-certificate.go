@@ -0,0 +1,227 @@
+package db
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+/*
+アプリケーションロードバランサーの HTTPS リスナーが使う TLS 証明書を秘密鍵とともに保存する
+
+	/marmot/certificate/<証明書ID>  => api.Certificate
+
+秘密鍵は API の応答に含めず、ロードバランサーコントローラーだけが読み出して HAProxy に配布する
+*/
+
+const CertificatePrefix = "/marmot/certificate"
+
+var (
+	ErrInvalidCertificate = errors.New("invalid certificate")
+	ErrCertificateInUse   = errors.New("certificate is in use")
+)
+
+// NormalizeCertificate は証明書と秘密鍵の組を検証して、証明書から読み取れる項目を補完する
+func NormalizeCertificate(spec api.Certificate) (api.Certificate, error) {
+	cert, err := util.DeepCopy(spec)
+	if err != nil {
+		return api.Certificate{}, err
+	}
+	cert.Name = strings.TrimSpace(cert.Name)
+	if cert.Name == "" {
+		return api.Certificate{}, fmt.Errorf("%w: name is required", ErrInvalidCertificate)
+	}
+	cert.Certificate = strings.TrimSpace(cert.Certificate) + "\n"
+	privateKey := strings.TrimSpace(util.OrDefault(cert.PrivateKey, ""))
+	if privateKey == "" {
+		return api.Certificate{}, fmt.Errorf("%w: privateKey is required", ErrInvalidCertificate)
+	}
+	privateKey += "\n"
+	cert.PrivateKey = &privateKey
+
+	pair, err := tls.X509KeyPair([]byte(cert.Certificate), []byte(privateKey))
+	if err != nil {
+		return api.Certificate{}, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return api.Certificate{}, fmt.Errorf("%w: %v", ErrInvalidCertificate, err)
+	}
+
+	dnsNames := append([]string{}, leaf.DNSNames...)
+	if len(dnsNames) == 0 && leaf.Subject.CommonName != "" {
+		dnsNames = append(dnsNames, leaf.Subject.CommonName)
+	}
+	notAfter := leaf.NotAfter.UTC()
+	sum := sha256.Sum256(leaf.Raw)
+	cert.DnsNames = &dnsNames
+	cert.NotAfter = &notAfter
+	cert.Fingerprint = util.StringPtr(fmt.Sprintf("%x", sum))
+	return cert, nil
+}
+
+// 証明書を登録する
+func (d *Database) CreateCertificate(spec api.Certificate) (api.Certificate, error) {
+	cert, err := NormalizeCertificate(spec)
+	if err != nil {
+		return api.Certificate{}, err
+	}
+
+	mutex, err := d.LockKey("/lock/certificate")
+	if err != nil {
+		return api.Certificate{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	current, err := d.GetCertificates()
+	if err != nil {
+		return api.Certificate{}, err
+	}
+	for _, c := range current {
+		if c.Name == cert.Name {
+			return api.Certificate{}, fmt.Errorf("%w: certificate %q already exists", ErrFound, cert.Name)
+		}
+	}
+
+	var key string
+	for {
+		id := uuid.New().String()[:5]
+		key = CertificatePrefix + "/" + id
+		if _, err := d.getRaw(key); err == ErrNotFound {
+			cert.Id = util.StringPtr(id)
+			break
+		} else if err != nil {
+			return api.Certificate{}, err
+		}
+	}
+	if err := d.PutJSON(key, cert); err != nil {
+		slog.Error("failed to write certificate", "err", err, "key", key)
+		return api.Certificate{}, err
+	}
+	return cert, nil
+}
+
+// 証明書の一覧を名前の順に取得する
+func (d *Database) GetCertificates() ([]api.Certificate, error) {
+	resp, err := d.GetByPrefix(CertificatePrefix + "/")
+	if err == ErrNotFound {
+		return []api.Certificate{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	certs := make([]api.Certificate, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var cert api.Certificate
+		if err := json.Unmarshal(kv.Value, &cert); err != nil {
+			slog.Warn("skipped malformed certificate", "err", err, "key", string(kv.Key))
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	sort.SliceStable(certs, func(i, j int) bool {
+		return certs[i].Name < certs[j].Name
+	})
+	return certs, nil
+}
+
+// IDで証明書を取得する
+func (d *Database) GetCertificateById(id string) (api.Certificate, error) {
+	var cert api.Certificate
+	if _, err := d.GetJSON(CertificatePrefix+"/"+id, &cert); err != nil {
+		return api.Certificate{}, err
+	}
+	return cert, nil
+}
+
+// 証明書と秘密鍵を置き換える。名前の指定が無い場合は元の名前を使う。プロジェクトは変更できない
+func (d *Database) UpdateCertificateById(id string, spec api.Certificate) (api.Certificate, error) {
+	mutex, err := d.LockKey("/lock/certificate")
+	if err != nil {
+		return api.Certificate{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	current, err := d.GetCertificateById(id)
+	if err != nil {
+		return api.Certificate{}, err
+	}
+	if strings.TrimSpace(spec.Name) == "" {
+		spec.Name = current.Name
+	}
+	currentProject := ProjectOf(api.Metadata{Project: current.Project})
+	if project := strings.TrimSpace(util.OrDefault(spec.Project, "")); project != "" && project != currentProject {
+		return api.Certificate{}, fmt.Errorf("%w: project cannot be changed from %q", ErrInvalidCertificate, currentProject)
+	}
+	spec.Project = current.Project
+	cert, err := NormalizeCertificate(spec)
+	if err != nil {
+		return api.Certificate{}, err
+	}
+	if cert.Name != current.Name {
+		certs, err := d.GetCertificates()
+		if err != nil {
+			return api.Certificate{}, err
+		}
+		for _, c := range certs {
+			if c.Name == cert.Name {
+				return api.Certificate{}, fmt.Errorf("%w: certificate %q already exists", ErrFound, cert.Name)
+			}
+		}
+	}
+	cert.Id = util.StringPtr(id)
+	if err := d.PutJSON(CertificatePrefix+"/"+id, cert); err != nil {
+		return api.Certificate{}, err
+	}
+	return cert, nil
+}
+
+// CertificateUsers は証明書を参照するアプリケーションロードバランサーの名前を返す
+func (d *Database) CertificateUsers(id string) ([]string, error) {
+	loadBalancers, err := d.GetLoadBalancers()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, lb := range loadBalancers {
+		for _, listener := range lb.Spec.Listeners {
+			if listener.CertificateIds != nil && slices.Contains(*listener.CertificateIds, id) {
+				names = append(names, lb.Metadata.Name)
+				break
+			}
+		}
+	}
+	return names, nil
+}
+
+// IDで証明書を削除する。ロードバランサーが参照している証明書は削除できない
+func (d *Database) DeleteCertificateById(id string) error {
+	mutex, err := d.LockKey("/lock/certificate")
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	if _, err := d.GetCertificateById(id); err != nil {
+		return err
+	}
+	users, err := d.CertificateUsers(id)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		return fmt.Errorf("%w: used by application load balancer %s", ErrCertificateInUse, strings.Join(users, ","))
+	}
+	return d.DeleteJSON(CertificatePrefix + "/" + id)
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// testCertificatePEM は自己署名の証明書と秘密鍵を PEM で返す
+func testCertificatePEM(t *testing.T, commonName string, dnsNames ...string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
+
+func TestNormalizeCertificate(t *testing.T) {
+	certPEM, keyPEM := testCertificatePEM(t, "www.example.com", "www.example.com", "example.com")
+
+	cert, err := NormalizeCertificate(api.Certificate{Name: " web ", Certificate: certPEM, PrivateKey: util.StringPtr(keyPEM)})
+	if err != nil {
+		t.Fatalf("NormalizeCertificate() error = %v", err)
+	}
+	if cert.Name != "web" {
+		t.Fatalf("name = %q, want web", cert.Name)
+	}
+	if cert.DnsNames == nil || len(*cert.DnsNames) != 2 || (*cert.DnsNames)[1] != "example.com" {
+		t.Fatalf("dnsNames = %v, want the subject alternative names", cert.DnsNames)
+	}
+	if cert.NotAfter == nil || cert.NotAfter.Before(time.Now()) {
+		t.Fatalf("notAfter = %v, want the expiry of the certificate", cert.NotAfter)
+	}
+	if cert.Fingerprint == nil || len(*cert.Fingerprint) != 64 {
+		t.Fatalf("fingerprint = %v, want a SHA-256 hex digest", cert.Fingerprint)
+	}
+
+	cnOnlyPEM, cnOnlyKey := testCertificatePEM(t, "api.example.com")
+	cert, err = NormalizeCertificate(api.Certificate{Name: "api", Certificate: cnOnlyPEM, PrivateKey: util.StringPtr(cnOnlyKey)})
+	if err != nil {
+		t.Fatalf("NormalizeCertificate() error = %v", err)
+	}
+	if len(*cert.DnsNames) != 1 || (*cert.DnsNames)[0] != "api.example.com" {
+		t.Fatalf("dnsNames = %v, want the common name", *cert.DnsNames)
+	}
+}
+
+func TestNormalizeCertificateRejects(t *testing.T) {
+	certPEM, keyPEM := testCertificatePEM(t, "www.example.com", "www.example.com")
+	_, otherKey := testCertificatePEM(t, "other.example.com", "other.example.com")
+
+	tests := []struct {
+		name string
+		cert api.Certificate
+	}{
+		{name: "without name", cert: api.Certificate{Certificate: certPEM, PrivateKey: util.StringPtr(keyPEM)}},
+		{name: "without private key", cert: api.Certificate{Name: "web", Certificate: certPEM}},
+		{name: "mismatched private key", cert: api.Certificate{Name: "web", Certificate: certPEM, PrivateKey: util.StringPtr(otherKey)}},
+		{name: "not PEM", cert: api.Certificate{Name: "web", Certificate: "not a certificate", PrivateKey: util.StringPtr(keyPEM)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NormalizeCertificate(tt.cert); !errors.Is(err, ErrInvalidCertificate) {
+				t.Fatalf("NormalizeCertificate() error = %v, want ErrInvalidCertificate", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	etcd "go.etcd.io/etcd/client/v3"
+)
+
+// ConsoleToken はグラフィカルコンソールに接続するための短命なトークン
+// トークン自体は保存せず、SHA-256 のハッシュをキーにする
+type ConsoleToken struct {
+	ServerId  string    `json:"serverId"`
+	UserId    string    `json:"userId"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func consoleTokenKey(token string) string {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	return ConsoleTokenPrefix + "/" + hex.EncodeToString(hash[:])
+}
+
+// PutConsoleToken はコンソールのトークンを登録する
+func (d *Database) PutConsoleToken(token string, rec ConsoleToken) error {
+	rec.ServerId = strings.TrimSpace(rec.ServerId)
+	return d.PutJSON(consoleTokenKey(token), rec)
+}
+
+// TakeConsoleToken はコンソールのトークンを削除して、削除前の内容を返す。登録されていない場合は ErrNotFound
+// トークンは 1 回だけ使える。取得と削除を 1 つの操作で行うため、同じトークンを同時に使っても 1 つだけが成功する
+// 有効期限は呼び出し側で確認する
+func (d *Database) TakeConsoleToken(token string) (ConsoleToken, error) {
+	if strings.TrimSpace(token) == "" {
+		return ConsoleToken{}, ErrNotFound
+	}
+	ctx, cancel := context.WithTimeout(d.Ctx, 5*time.Second)
+	defer cancel()
+
+	key := consoleTokenKey(token)
+	resp, err := d.Cli.Delete(ctx, key, etcd.WithPrevKV())
+	if err != nil {
+		return ConsoleToken{}, err
+	}
+	d.observeWrite(resp.Header, key)
+	if len(resp.PrevKvs) == 0 {
+		return ConsoleToken{}, ErrNotFound
+	}
+	var rec ConsoleToken
+	if err := json.Unmarshal(resp.PrevKvs[0].Value, &rec); err != nil {
+		return ConsoleToken{}, fmt.Errorf("json unmarshal failed: %w", err)
+	}
+	return rec, nil
+}
+
+// DeleteExpiredConsoleTokens は有効期限を過ぎたトークンを削除し、削除した数を返す
+func (d *Database) DeleteExpiredConsoleTokens(now time.Time) (int, error) {
+	resp, err := d.GetByPrefix(ConsoleTokenPrefix + "/")
+	if err == ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, kv := range resp.Kvs {
+		var rec ConsoleToken
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			slog.Warn("DeleteExpiredConsoleTokens() unmarshal failed", "err", err, "key", string(kv.Key))
+			continue
+		}
+		if now.Before(rec.ExpiresAt) {
+			continue
+		}
+		if err := d.DeleteJSON(string(kv.Key)); err != nil && err != ErrNotFound {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
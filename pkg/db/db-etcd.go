@@ -30,6 +30,11 @@ const (
 	VersionKey                = "/marmot/version"
 	JobPrefix                 = "/marmot/job"
 	InternalDNSPrefix         = "/marmot/dns"
+	SnapshotPrefix            = "/marmot/snapshot"
+	MigrationPrefix           = "/marmot/migration"
+	NodeMaintenancePrefix     = "/marmot/node-maintenance"
+	GuestPasswordPrefix       = "/marmot/guest-password"
+	ConsoleTokenPrefix        = "/marmot/console-token"
 	// エラーメッセージ
 	ErrAlreadyExists           = "Network with the same AddressMaskLen already exists"
 	ErrOverlapsExistingNetwork = "overlaps with an existing network"
@@ -88,6 +93,10 @@ type Database struct {
 	Ctx     context.Context
 	Session *concurrency.Session
 	Mutex   *concurrency.Mutex
+
+	// 一覧取得をローカルキャッシュから応答するインフォーマー
+	informerMu sync.RWMutex
+	informers  []*Informer
 }
 
 func NewDatabase(url string) (*Database, error) {
@@ -151,10 +160,11 @@ func (d *Database) PutJSON(key string, v interface{}) error {
 	if err != nil {
 		return fmt.Errorf("json marshal failed: %w", err)
 	}
-	_, err = d.Cli.Put(ctx, key, string(byteJSON))
+	resp, err := d.Cli.Put(ctx, key, string(byteJSON))
 	if err != nil {
 		return fmt.Errorf("etcd put failed: %w", err)
 	}
+	d.observeWrite(resp.Header, key)
 	return nil
 }
 
@@ -185,7 +195,15 @@ func (d *Database) GetJSON(key string, out interface{}) (*etcd.GetResponse, erro
 }
 
 // プレフィックス検索で取得
+// 同期済みのインフォーマーが監視しているプレフィックスはキャッシュから応答する
 func (d *Database) GetByPrefix(prefix string) (*etcd.GetResponse, error) {
+	if resp, ok := d.cachedByPrefix(prefix); ok {
+		if resp.Count == 0 {
+			return nil, ErrNotFound
+		}
+		return resp, nil
+	}
+
 	ctx, cancel := context.WithTimeout(d.Ctx, 5*time.Second)
 	defer cancel()
 	resp, err := d.Cli.Get(ctx, prefix, etcd.WithPrefix())
@@ -221,16 +239,18 @@ func (d *Database) PutJSONCAS(key string, expectedRev int64, v interface{}) erro
 	if !txnResp.Succeeded {
 		return ErrUpdateConflict
 	}
+	d.observeWrite(txnResp.Header, key)
 	return nil
 }
 
 func (d *Database) DeleteJSON(key string) error {
 	ctx, cancel := context.WithTimeout(d.Ctx, 5*time.Second)
 	defer cancel()
-	_, err := d.Cli.Delete(ctx, key)
+	resp, err := d.Cli.Delete(ctx, key)
 	if err != nil {
 		slog.Error("DeleteJSON() failed", "err", err, "key", key)
 		return err
 	}
+	d.observeWrite(resp.Header, key)
 	return nil
 }
@@ -183,6 +183,7 @@ func (d *Database) GetGateways() ([]api.Gateway, error) {
 			slog.Error("GetGateways() unmarshal failed", "err", err, "key", string(kv.Key))
 			continue
 		}
+		setResourceVersion(&gateway.Metadata, kv.ModRevision)
 		gateways = append(gateways, gateway)
 	}
 
@@ -193,17 +194,18 @@ func (d *Database) GetGateways() ([]api.Gateway, error) {
 func (d *Database) GetGatewayById(id string) (api.Gateway, error) {
 	key := GatewayPrefix + "/" + id
 	var gateway api.Gateway
-	_, err := d.GetJSON(key, &gateway)
+	resp, err := d.GetJSON(key, &gateway)
 	if err != nil {
 		return api.Gateway{}, err
 	}
+	setResourceVersion(&gateway.Metadata, resp.Kvs[0].ModRevision)
 	return gateway, nil
 }
 
 // UpdateGatewayById updates a gateway using optimistic locking.
 func (d *Database) UpdateGatewayById(id string, spec api.Gateway) error {
 	for {
-		err := d.updateGateway(id, spec)
+		err := d.updateGateway(id, spec, "")
 		if err == ErrUpdateConflict {
 			slog.Warn("UpdateGatewayById() retrying due to update conflict", "gatewayId", id)
 			continue
@@ -212,7 +214,19 @@ func (d *Database) UpdateGatewayById(id string, spec api.Gateway) error {
 	}
 }
 
-func (d *Database) updateGateway(id string, spec api.Gateway) error {
+// UpdateGatewayByIdWithResourceVersion は resourceVersion が指定された場合、現在のリソースバージョンと一致する時だけ更新する
+// 一致しない場合は ErrResourceVersionConflict を返す
+func (d *Database) UpdateGatewayByIdWithResourceVersion(id string, spec api.Gateway, resourceVersion string) error {
+	for {
+		err := d.updateGateway(id, spec, resourceVersion)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
+func (d *Database) updateGateway(id string, spec api.Gateway, resourceVersion string) error {
 	lockKey := "/lock/gateway/" + id
 	mutex, err := d.LockKey(lockKey)
 	if err != nil {
@@ -228,8 +242,15 @@ func (d *Database) updateGateway(id string, spec api.Gateway) error {
 	}
 
 	expected := resp.Kvs[0].ModRevision
+	if err := checkResourceVersion(resourceVersion, expected); err != nil {
+		return err
+	}
+	owner, project := rec.Metadata.Owner, rec.Metadata.Project
 	util.PatchStruct(&rec, spec)
+	// 所有者とプロジェクトはクォータの集計と認可に使うため作成後は変更しない
+	rec.Metadata.Owner, rec.Metadata.Project = owner, project
 	api.SetGatewayID(&rec, id)
+	rec.Metadata.ResourceVersion = nil
 
 	return d.PutJSONCAS(key, expected, &rec)
 }
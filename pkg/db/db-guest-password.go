@@ -0,0 +1,38 @@
+package db
+
+import (
+	"strings"
+	"time"
+)
+
+// GuestPasswordRequest はゲストのユーザーのパスワード設定の要求
+// パスワードは crypt(3) 形式のハッシュだけを保存し、サーバーの API の応答には含めない
+type GuestPasswordRequest struct {
+	ServerId        string    `json:"serverId"`
+	User            string    `json:"user"`
+	CryptedPassword string    `json:"cryptedPassword"`
+	RequestedAt     time.Time `json:"requestedAt"`
+}
+
+// PutGuestPasswordRequest はパスワード設定の要求を登録する。同じサーバーの未適用の要求は置き換える
+func (d *Database) PutGuestPasswordRequest(req GuestPasswordRequest) error {
+	req.ServerId = strings.TrimSpace(req.ServerId)
+	if req.RequestedAt.IsZero() {
+		req.RequestedAt = time.Now()
+	}
+	return d.PutJSON(GuestPasswordPrefix+"/"+req.ServerId, req)
+}
+
+// GetGuestPasswordRequest はサーバーの未適用のパスワード設定の要求を返す。無い場合は ErrNotFound
+func (d *Database) GetGuestPasswordRequest(serverId string) (GuestPasswordRequest, error) {
+	var req GuestPasswordRequest
+	if _, err := d.GetJSON(GuestPasswordPrefix+"/"+strings.TrimSpace(serverId), &req); err != nil {
+		return GuestPasswordRequest{}, err
+	}
+	return req, nil
+}
+
+// DeleteGuestPasswordRequest はパスワード設定の要求を削除する
+func (d *Database) DeleteGuestPasswordRequest(serverId string) error {
+	return d.DeleteJSON(GuestPasswordPrefix + "/" + strings.TrimSpace(serverId))
+}
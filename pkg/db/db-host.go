@@ -42,6 +42,8 @@ func (d *Database) PutHostStatus(status api.HostStatus) error {
 	}
 	now := time.Now()
 	status.CreationTimeStamp = resolveHostStatusCreationTimestamp(status, existing, now)
+	// メンテナンス状態は別キーで管理し、読み出し時に合成する
+	status.Maintenance = nil
 	key := HostStatusPrefix + "/" + *status.NodeName
 	return d.PutJSON(key, status)
 }
@@ -54,6 +56,12 @@ func (d *Database) GetHostStatus(nodeName string) (api.HostStatus, error) {
 	if err != nil {
 		return api.HostStatus{}, err
 	}
+	status.Maintenance = nil
+	if rec, err := d.GetNodeMaintenance(nodeName); err == nil {
+		status.Maintenance = &rec
+	} else if err != ErrNotFound {
+		return api.HostStatus{}, err
+	}
 	return status, nil
 }
 
@@ -71,12 +79,24 @@ func (d *Database) GetAllHostStatus() ([]api.HostStatus, error) {
 		return statuses, err
 	}
 
+	maintenance, err := d.getAllNodeMaintenance()
+	if err != nil {
+		slog.Error("getAllNodeMaintenance() failed", "err", err)
+		return statuses, err
+	}
+
 	for _, kv := range resp.Kvs {
 		var status api.HostStatus
 		if err := json.Unmarshal(kv.Value, &status); err != nil {
 			slog.Error("Unmarshal() failed", "err", err, "key", string(kv.Key))
 			continue
 		}
+		status.Maintenance = nil
+		if status.NodeName != nil {
+			if rec, ok := maintenance[*status.NodeName]; ok {
+				status.Maintenance = &rec
+			}
+		}
 		statuses = append(statuses, status)
 	}
 
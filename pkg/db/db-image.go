@@ -192,9 +192,11 @@ func (d *Database) MakeImageEntryFromSpec(imageSpec api.Image) (string, error) {
 		ApiVersion: apiVersion,
 		Kind:       kind,
 		Metadata: api.Metadata{
-			Name: strings.TrimSpace(imageSpec.Metadata.Name),
-			Id:   id,
-			Uuid: util.StringPtr(uuidString),
+			Name:    strings.TrimSpace(imageSpec.Metadata.Name),
+			Id:      id,
+			Uuid:    util.StringPtr(uuidString),
+			Owner:   imageSpec.Metadata.Owner,
+			Project: imageSpec.Metadata.Project,
 		},
 		Spec: spec,
 		Status: &api.Status{
@@ -208,6 +210,9 @@ func (d *Database) MakeImageEntryFromSpec(imageSpec api.Image) (string, error) {
 	if nodeName != "" {
 		img.Metadata.NodeName = util.StringPtr(nodeName)
 	}
+	if imageSpec.Status != nil && imageSpec.Status.JobId != nil {
+		img.Status.JobId = util.StringPtr(*imageSpec.Status.JobId)
+	}
 
 	key := ImagePrefix + "/" + id
 	if err := d.PutJSON(key, img); err != nil {
@@ -319,6 +324,11 @@ func (d *Database) MakeImportedImageEntry(name, nodeName, qcow2Path string) (api
 
 // ブートボリュームからイメージを作成する
 func (d *Database) MakeImageEntryFromRunningVM(serverId, name string) (api.Image, error) {
+	return d.MakeImageEntryFromRunningVMWithJob(serverId, name, "")
+}
+
+// ブートボリュームからイメージを作成する、jobId が指定された場合は進捗を追跡するジョブとして記録する
+func (d *Database) MakeImageEntryFromRunningVMWithJob(serverId, name, jobId string) (api.Image, error) {
 	slog.Debug("MakeImageEntryFromRunningVM() called", "name", name, "serverId", serverId)
 
 	//一意なIDを発行
@@ -436,6 +446,15 @@ func (d *Database) MakeImageEntryFromRunningVM(serverId, name string) (api.Image
 		slog.Error("MakeImageEntryFromRunningVM() unsupported volume type", "volumeId", api.VolumeID(*bootVol), "type", bootVol.Spec.Type)
 		return api.Image{}, fmt.Errorf("unsupported volume type for volume with id %v: %v", api.VolumeID(*bootVol), bootVol.Spec.Type)
 	}
+	if jobId != "" {
+		img.Status.JobId = util.StringPtr(jobId)
+	}
+	// イメージは元のサーバーと同じプロジェクトに属する
+	img.Metadata.Project = server.Metadata.Project
+	// UEFI で起動したサーバーのブートボリュームは UEFI でないと起動できない
+	if strings.HasPrefix(strings.TrimSpace(util.OrDefault(server.Spec.Firmware, "")), "uefi") {
+		img.Spec.Firmware = util.StringPtr("uefi")
+	}
 
 	key := ImagePrefix + "/" + id
 	if err := d.PutJSON(key, img); err != nil {
@@ -527,12 +546,13 @@ func (d *Database) GetImage(id string) (api.Image, error) {
 	slog.Debug("GetImage() called", "id", id)
 	key := ImagePrefix + "/" + id
 	var img api.Image
-	_, err := d.GetJSON(key, &img)
+	resp, err := d.GetJSON(key, &img)
 	if err != nil {
 		slog.Error("GetImage()", "err", err)
 		return api.Image{}, err
 	}
 	normalizeImageMetadataID(&img, id)
+	setResourceVersion(&img.Metadata, resp.Kvs[0].ModRevision)
 
 	return img, nil
 }
@@ -561,6 +581,7 @@ func (d *Database) GetImages() ([]api.Image, error) {
 			continue
 		}
 		normalizeImageMetadataID(&img, imageIDFromKey(string(kv.Key)))
+		setResourceVersion(&img.Metadata, kv.ModRevision)
 		images = append(images, img)
 	}
 
@@ -655,7 +676,7 @@ func (d *Database) updateImageStatusMessage(id string, status int, message strin
 // イメージのオブジェクトを部分更新
 func (d *Database) UpdateImage(id string, spec api.Image) error {
 	for {
-		err := d.updateImage(id, spec)
+		err := d.updateImage(id, spec, "")
 		if err == ErrUpdateConflict {
 			slog.Warn("UpdateImage() retrying due to update conflict", "imageId", id)
 			continue
@@ -677,8 +698,20 @@ func (d *Database) UpdateImage(id string, spec api.Image) error {
 	return nil
 }
 
+// UpdateImageWithResourceVersion は resourceVersion が指定された場合、現在のリソースバージョンと一致する時だけ更新する
+// 一致しない場合は ErrResourceVersionConflict を返す
+func (d *Database) UpdateImageWithResourceVersion(id string, spec api.Image, resourceVersion string) error {
+	for {
+		err := d.updateImage(id, spec, resourceVersion)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
 // 内部イメージを更新
-func (d *Database) updateImage(id string, spec api.Image) error {
+func (d *Database) updateImage(id string, spec api.Image, resourceVersion string) error {
 	lockKey := "/lock/image/" + id
 	mutex, err := d.LockKey(lockKey)
 	if err != nil {
@@ -695,10 +728,16 @@ func (d *Database) updateImage(id string, spec api.Image) error {
 		return err
 	}
 	expected := resp.Kvs[0].ModRevision
+	if err := checkResourceVersion(resourceVersion, expected); err != nil {
+		return err
+	}
 
 	rec.Metadata.Id = id
+	project := rec.Metadata.Project
 	// パッチ適用
 	util.PatchStruct(&rec, spec)
+	rec.Metadata.Project = project // プロジェクトは認可に使うため作成後は変更しない
+	rec.Metadata.ResourceVersion = nil
 
 	err = d.PutJSONCAS(key, expected, &rec)
 	if err != nil {
@@ -876,6 +915,12 @@ func (d *Database) MakeFollowerImageEntry(headImage api.Image, followerNodeName
 			followerSpec.OsVersion = util.StringPtr(osVersion)
 		}
 	}
+	if headImage.Spec.Firmware != nil {
+		firmware := strings.TrimSpace(*headImage.Spec.Firmware)
+		if firmware != "" {
+			followerSpec.Firmware = util.StringPtr(firmware)
+		}
+	}
 
 	follower := api.Image{
 		ApiVersion: "v1",
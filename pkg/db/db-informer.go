@@ -0,0 +1,346 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcd "go.etcd.io/etcd/client/v3"
+)
+
+// インフォーマーが通知するイベントの種類
+const (
+	EVENT_PUT    = 0 // キーの作成・更新
+	EVENT_DELETE = 1 // キーの削除
+	EVENT_RESYNC = 2 // 定期再同期（キャッシュ内の全キーを通知する）
+)
+
+// 監視が切断された時に再接続するまでの待ち時間
+const informerRetryInterval = 2 * time.Second
+
+// InformerEvent はインフォーマーがハンドラーに通知する変更
+type InformerEvent struct {
+	Type int
+	Key  string
+}
+
+// Informer は etcd のプレフィックスを watch してローカルキャッシュを維持し、変更をハンドラーへ通知する
+// Run で全件取得した後は watch のイベントだけでキャッシュを更新するため、一覧の取得で etcd を読みに行かない
+type Informer struct {
+	db       *Database
+	prefix   string
+	resync   time.Duration
+	mu       sync.RWMutex
+	cache    map[string]*mvccpb.KeyValue
+	revision int64
+	written  int64 // このクライアントがプレフィックス内に書き込んだ最新のリビジョン
+	synced   bool
+	handlers []func(InformerEvent)
+}
+
+// NewInformer はプレフィックスを監視するインフォーマーを作成してデータベースに登録する
+// 同期済みのインフォーマーと同じプレフィックスの GetByPrefix はキャッシュから応答する（下位のプレフィックスは etcd から読む）
+// resync が 0 より大きい場合は、その間隔でキャッシュ内の全キーを EVENT_RESYNC で通知する
+func (d *Database) NewInformer(prefix string, resync time.Duration) *Informer {
+	inf := &Informer{
+		db:     d,
+		prefix: prefix,
+		resync: resync,
+		cache:  map[string]*mvccpb.KeyValue{},
+	}
+	d.informerMu.Lock()
+	d.informers = append(d.informers, inf)
+	d.informerMu.Unlock()
+	return inf
+}
+
+// AddHandler は変更通知を受け取るハンドラーを追加する。Run の前に呼び出すこと
+func (i *Informer) AddHandler(h func(InformerEvent)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.handlers = append(i.handlers, h)
+}
+
+// stop は監視を終えたインフォーマーを未同期にして、データベースの登録から外す
+// 更新されなくなったキャッシュで GetByPrefix に応答し続けないようにする
+func (i *Informer) stop() {
+	i.mu.Lock()
+	i.synced = false
+	i.mu.Unlock()
+	i.db.removeInformer(i)
+}
+
+// removeInformer はインフォーマーをデータベースの登録から外す
+func (d *Database) removeInformer(inf *Informer) {
+	d.informerMu.Lock()
+	defer d.informerMu.Unlock()
+	for n, registered := range d.informers {
+		if registered == inf {
+			d.informers = append(d.informers[:n:n], d.informers[n+1:]...)
+			return
+		}
+	}
+}
+
+// HasSynced は初回の全件取得が完了しているかを返す
+func (i *Informer) HasSynced() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.synced
+}
+
+// Keys はキャッシュ内のキーを昇順で返す
+func (i *Informer) Keys() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	keys := make([]string, 0, len(i.cache))
+	for k := range i.cache {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get はキャッシュからキーの値を返す
+func (i *Informer) Get(key string) (*mvccpb.KeyValue, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	kv, ok := i.cache[key]
+	return kv, ok
+}
+
+// list はキャッシュから prefix に一致するキーを昇順で返す
+// 自分の書き込みがまだキャッシュに届いていない場合は fresh が false になる
+func (i *Informer) list(prefix string) (kvs []*mvccpb.KeyValue, rev int64, fresh bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	for k, kv := range i.cache {
+		if strings.HasPrefix(k, prefix) {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(a, b int) bool { return string(kvs[a].Key) < string(kvs[b].Key) })
+	return kvs, i.revision, i.revision >= i.written
+}
+
+// Run はキャッシュを同期し、stop が閉じられるまで watch を続ける
+// watch が切断された場合やリビジョンがコンパクションされた場合は全件を取り直す
+// 終了時にはデータベースの登録から外し、以降の GetByPrefix は etcd から応答する
+func (i *Informer) Run(stop <-chan struct{}) {
+	defer i.stop()
+	ctx, cancel := context.WithCancel(i.db.Ctx)
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	var resyncC <-chan time.Time
+	if i.resync > 0 {
+		ticker := time.NewTicker(i.resync)
+		defer ticker.Stop()
+		resyncC = ticker.C
+	}
+
+	for ctx.Err() == nil {
+		if err := i.relist(ctx); err != nil {
+			slog.Warn("informer relist failed", "prefix", i.prefix, "err", err)
+			if !sleepContext(ctx, informerRetryInterval) {
+				return
+			}
+			continue
+		}
+		err := i.watch(ctx, resyncC)
+		if ctx.Err() != nil {
+			return
+		}
+		// 再同期するまでは GetByPrefix を etcd から応答させる
+		i.mu.Lock()
+		i.synced = false
+		i.mu.Unlock()
+		slog.Warn("informer watch closed, relisting", "prefix", i.prefix, "err", err)
+		if !sleepContext(ctx, informerRetryInterval) {
+			return
+		}
+	}
+}
+
+// relist は全件を取得してキャッシュを置き換え、差分をハンドラーへ通知する
+func (i *Informer) relist(ctx context.Context) error {
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	resp, err := i.db.Cli.Get(reqCtx, i.prefix, etcd.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	fresh := make(map[string]*mvccpb.KeyValue, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		fresh[string(kv.Key)] = kv
+	}
+
+	i.mu.Lock()
+	var events []InformerEvent
+	for k, kv := range fresh {
+		if old, ok := i.cache[k]; !ok || old.ModRevision != kv.ModRevision {
+			events = append(events, InformerEvent{Type: EVENT_PUT, Key: k})
+		}
+	}
+	for k := range i.cache {
+		if _, ok := fresh[k]; !ok {
+			events = append(events, InformerEvent{Type: EVENT_DELETE, Key: k})
+		}
+	}
+	i.cache = fresh
+	i.revision = resp.Header.Revision
+	i.synced = true
+	handlers := i.handlers
+	i.mu.Unlock()
+
+	notify(handlers, events)
+	return nil
+}
+
+// watch は relist したリビジョンの次から変更を受け取りキャッシュへ反映する
+func (i *Informer) watch(ctx context.Context, resyncC <-chan time.Time) error {
+	i.mu.RLock()
+	rev := i.revision
+	i.mu.RUnlock()
+
+	watchCtx, cancel := context.WithCancel(etcd.WithRequireLeader(ctx))
+	defer cancel()
+	wch := i.db.Cli.Watch(watchCtx, i.prefix, etcd.WithPrefix(), etcd.WithRev(rev+1))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-resyncC:
+			i.notifyResync()
+		case wresp, ok := <-wch:
+			if !ok {
+				return errors.New("watch channel closed")
+			}
+			// コンパクションでリビジョンが失われた場合もエラーになり、全件を取り直す
+			if err := wresp.Err(); err != nil {
+				return err
+			}
+			i.apply(wresp.Header, wresp.Events)
+		}
+	}
+}
+
+// apply は watch のイベントをキャッシュへ反映して通知する
+func (i *Informer) apply(header etcdserverpb.ResponseHeader, evs []*etcd.Event) {
+	i.mu.Lock()
+	events := make([]InformerEvent, 0, len(evs))
+	for _, ev := range evs {
+		key := string(ev.Kv.Key)
+		switch ev.Type {
+		case mvccpb.PUT:
+			i.cache[key] = ev.Kv
+			events = append(events, InformerEvent{Type: EVENT_PUT, Key: key})
+		case mvccpb.DELETE:
+			delete(i.cache, key)
+			events = append(events, InformerEvent{Type: EVENT_DELETE, Key: key})
+		}
+	}
+	if header.Revision > i.revision {
+		i.revision = header.Revision
+	}
+	handlers := i.handlers
+	i.mu.Unlock()
+
+	notify(handlers, events)
+}
+
+func (i *Informer) notifyResync() {
+	keys := i.Keys()
+	events := make([]InformerEvent, 0, len(keys))
+	for _, k := range keys {
+		events = append(events, InformerEvent{Type: EVENT_RESYNC, Key: k})
+	}
+	i.mu.RLock()
+	handlers := i.handlers
+	i.mu.RUnlock()
+	notify(handlers, events)
+}
+
+func notify(handlers []func(InformerEvent), events []InformerEvent) {
+	for _, ev := range events {
+		for _, h := range handlers {
+			h(ev)
+		}
+	}
+}
+
+// cachedByPrefix は prefix と同じプレフィックス（末尾の / は問わない）を監視する同期済みインフォーマーがあればキャッシュから応答を組み立てる
+// IPAM のような下位プレフィックスの検索は割当の競合を避けるため常に etcd から読む
+// このクライアントの書き込みがまだ watch で届いていない間も etcd から読み、自分の更新を取りこぼさない
+func (d *Database) cachedByPrefix(prefix string) (*etcd.GetResponse, bool) {
+	d.informerMu.RLock()
+	defer d.informerMu.RUnlock()
+	for _, inf := range d.informers {
+		if (prefix != inf.prefix && prefix != inf.prefix+"/") || !inf.HasSynced() {
+			continue
+		}
+		kvs, rev, fresh := inf.list(prefix)
+		if !fresh {
+			return nil, false
+		}
+		return &etcd.GetResponse{
+			Header: &etcdserverpb.ResponseHeader{Revision: rev},
+			Kvs:    kvs,
+			Count:  int64(len(kvs)),
+		}, true
+	}
+	return nil, false
+}
+
+// observeWrite は書き込み結果のリビジョンを、そのキーを監視するインフォーマーに記録する
+// keys を省略した場合は複数のプレフィックスにまたがる書き込みとしてすべてのインフォーマーに記録する
+func (d *Database) observeWrite(header *etcdserverpb.ResponseHeader, keys ...string) {
+	if header == nil {
+		return
+	}
+	d.informerMu.RLock()
+	defer d.informerMu.RUnlock()
+	for _, inf := range d.informers {
+		if !inf.watches(keys) {
+			continue
+		}
+		inf.mu.Lock()
+		if header.Revision > inf.written {
+			inf.written = header.Revision
+		}
+		inf.mu.Unlock()
+	}
+}
+
+func (i *Informer) watches(keys []string) bool {
+	if len(keys) == 0 {
+		return true
+	}
+	for _, k := range keys {
+		if strings.HasPrefix(k, i.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
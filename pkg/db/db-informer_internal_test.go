@@ -0,0 +1,102 @@
+package db
+
+import (
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcd "go.etcd.io/etcd/client/v3"
+)
+
+func putEvent(key string, rev int64) *etcd.Event {
+	return &etcd.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte("{}"), ModRevision: rev}}
+}
+
+func newSyncedInformer(d *Database, prefix string) *Informer {
+	inf := d.NewInformer(prefix, 0)
+	inf.synced = true
+	return inf
+}
+
+func TestInformer_ApplyUpdatesCacheAndNotifies(t *testing.T) {
+	d := &Database{}
+	inf := newSyncedInformer(d, ServerPrefix)
+	var events []InformerEvent
+	inf.AddHandler(func(ev InformerEvent) { events = append(events, ev) })
+
+	inf.apply(etcdserverpb.ResponseHeader{Revision: 3}, []*etcd.Event{
+		putEvent(ServerPrefix+"/b", 2),
+		putEvent(ServerPrefix+"/a", 3),
+	})
+	inf.apply(etcdserverpb.ResponseHeader{Revision: 4}, []*etcd.Event{
+		{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte(ServerPrefix + "/b"), ModRevision: 4}},
+	})
+
+	if got := inf.Keys(); len(got) != 1 || got[0] != ServerPrefix+"/a" {
+		t.Fatalf("Keys() = %v, want [%s/a]", got, ServerPrefix)
+	}
+	if len(events) != 3 || events[2].Type != EVENT_DELETE {
+		t.Fatalf("events = %+v, want 2 puts and a delete", events)
+	}
+}
+
+func TestCachedByPrefix_MatchesOnlyInformerPrefix(t *testing.T) {
+	d := &Database{}
+	inf := newSyncedInformer(d, NetworkPrefix)
+	inf.apply(etcdserverpb.ResponseHeader{Revision: 2}, []*etcd.Event{
+		putEvent(NetworkPrefix+"/net1", 2),
+	})
+
+	for _, prefix := range []string{NetworkPrefix, NetworkPrefix + "/"} {
+		resp, ok := d.cachedByPrefix(prefix)
+		if !ok || resp.Count != 1 || resp.Header.Revision != 2 {
+			t.Fatalf("cachedByPrefix(%q) = %+v, %v, want one cached key", prefix, resp, ok)
+		}
+	}
+	// IPAM のような下位のプレフィックスは etcd から読む
+	if _, ok := d.cachedByPrefix(NetworkPrefix + "/net1/ip_network/"); ok {
+		t.Fatal("cachedByPrefix() served a sub-prefix from the cache")
+	}
+	if _, ok := d.cachedByPrefix(VolumePrefix); ok {
+		t.Fatal("cachedByPrefix() served a prefix without informer")
+	}
+}
+
+func TestCachedByPrefix_BypassesCacheUntilOwnWriteArrives(t *testing.T) {
+	d := &Database{}
+	inf := newSyncedInformer(d, ServerPrefix)
+	inf.apply(etcdserverpb.ResponseHeader{Revision: 5}, []*etcd.Event{putEvent(ServerPrefix+"/a", 5)})
+
+	d.observeWrite(&etcdserverpb.ResponseHeader{Revision: 7}, VolumePrefix+"/v1")
+	if _, ok := d.cachedByPrefix(ServerPrefix); !ok {
+		t.Fatal("cachedByPrefix() bypassed the cache for a write outside the prefix")
+	}
+
+	d.observeWrite(&etcdserverpb.ResponseHeader{Revision: 8}, ServerPrefix+"/a")
+	if _, ok := d.cachedByPrefix(ServerPrefix); ok {
+		t.Fatal("cachedByPrefix() served a cache older than our own write")
+	}
+
+	inf.apply(etcdserverpb.ResponseHeader{Revision: 8}, []*etcd.Event{putEvent(ServerPrefix+"/a", 8)})
+	if _, ok := d.cachedByPrefix(ServerPrefix); !ok {
+		t.Fatal("cachedByPrefix() did not serve the cache after the write arrived")
+	}
+}
+
+func TestInformer_StopUnregistersAndStopsServingCache(t *testing.T) {
+	d := &Database{}
+	stopped := newSyncedInformer(d, ServerPrefix)
+	other := newSyncedInformer(d, VolumePrefix)
+	stopped.apply(etcdserverpb.ResponseHeader{Revision: 2}, []*etcd.Event{putEvent(ServerPrefix+"/a", 2)})
+
+	stopped.stop()
+	if stopped.HasSynced() {
+		t.Fatal("HasSynced() = true after the informer stopped")
+	}
+	if _, ok := d.cachedByPrefix(ServerPrefix); ok {
+		t.Fatal("cachedByPrefix() served the cache of a stopped informer")
+	}
+	if len(d.informers) != 1 || d.informers[0] != other {
+		t.Fatalf("informers = %v, want only the running informer", d.informers)
+	}
+}
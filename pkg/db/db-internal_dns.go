@@ -1,13 +1,109 @@
 package db
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/miekg/dns"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
 )
 
+/*
+内部DNSのレコードは、ドメイン名のラベルを逆順に並べたキーの下に、型付きのレコードとして保存する
+
+	/marmot/dns/<network>/<host>/@/<レコードID>  => api.DnsRecord
+
+サーバーのレコードはサーバー作成時に登録され、IDは server-a または server-aaaa とする
+逆引き（PTR）とロードバランサーの SRV レコードは、保存せずに IPAM とロードバランサーの設定から生成する
+カスタムレコードは API から登録され、IDからレコードを引くインデックスを /marmot/dns-record/<ID> に持つ
+以前の形式の、名前のキーにIPアドレスの文字列だけを保存したエントリーも読み出せる
+*/
+
+const (
+	DnsRecordIndexPrefix = "/marmot/dns-record"
+
+	DNS_RECORD_A     = "A"
+	DNS_RECORD_AAAA  = "AAAA"
+	DNS_RECORD_CNAME = "CNAME"
+	DNS_RECORD_PTR   = "PTR"
+	DNS_RECORD_SRV   = "SRV"
+
+	DNS_RECORD_SOURCE_SERVER        = "server"
+	DNS_RECORD_SOURCE_CUSTOM        = "custom"
+	DNS_RECORD_SOURCE_LOAD_BALANCER = "load-balancer"
+
+	DefaultDnsRecordTTL = 300
+
+	// 名前のキーとレコードを区切るラベル、DNSのラベルには現れない
+	dnsRecordSeparator = "/@/"
+)
+
+var ErrInvalidDnsRecord = errors.New("invalid DNS record")
+
+// DnsNamePath はドメイン名のラベルを逆順に並べた /marmot/dns/ 形式のキーに変換する
+func DnsNamePath(domain string) string {
+	parts := DnsNameLabels(domain)
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return InternalDNSPrefix + "/" + strings.Join(parts, "/")
+}
+
+// DnsNameLabels はドメイン名を空のラベルを除いたラベルに分割する
+func DnsNameLabels(domain string) []string {
+	domain = strings.TrimSuffix(domain, ".")
+	parts := strings.Split(domain, ".")
+
+	filtered := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered
+}
+
+func dnsRecordKey(namePath, id string) string {
+	return namePath + dnsRecordSeparator + id
+}
+
+// サーバーのIPアドレスからレコードを作る、IPv4 と IPv6 で別のレコードにする
+func serverDnsRecord(hostname, subdomain string, ip net.IP) api.DnsRecord {
+	recordType := DNS_RECORD_A
+	id := "server-a"
+	if ip.To4() == nil {
+		recordType = DNS_RECORD_AAAA
+		id = "server-aaaa"
+	}
+	return api.DnsRecord{
+		Id:     util.StringPtr(id),
+		Name:   hostname + "." + subdomain,
+		Type:   recordType,
+		Value:  ip.String(),
+		Ttl:    util.IntPtrInt(DefaultDnsRecordTTL),
+		Source: util.StringPtr(DNS_RECORD_SOURCE_SERVER),
+	}
+}
+
 // ホスト名、サブドメイン、IPアドレスを登録する
+// IPv4 は A レコード、IPv6 は AAAA レコードとして登録する
 func (d *Database) PutDnsEntry(hostname, subdomain, ipAddress string) error {
 	slog.Debug("Putting DNS entry", "hostname", hostname, "ipAddress", ipAddress)
 
+	ip := net.ParseIP(strings.TrimSpace(ipAddress))
+	if ip == nil {
+		return fmt.Errorf("%w: invalid IP address %q", ErrInvalidDnsRecord, ipAddress)
+	}
+
 	lockKey := "/lock/dns/" + subdomain + "/" + hostname
 	mutex, err := d.LockKey(lockKey)
 	if err != nil {
@@ -16,27 +112,42 @@ func (d *Database) PutDnsEntry(hostname, subdomain, ipAddress string) error {
 	}
 	defer d.UnlockKey(mutex)
 
-	key := InternalDNSPrefix + "/" + subdomain + "/" + hostname
-	if err := d.PutJSON(key, ipAddress); err != nil {
-		slog.Error("failed to write image template data", "err", err, "key", key)
+	rec := serverDnsRecord(hostname, subdomain, ip)
+	key := dnsRecordKey(InternalDNSPrefix+"/"+subdomain+"/"+hostname, *rec.Id)
+	if err := d.PutJSON(key, rec); err != nil {
+		slog.Error("failed to write DNS record", "err", err, "key", key)
+		return err
+	}
+	// 以前の形式のエントリーは、型付きのレコードに置き換える
+	legacyKey := InternalDNSPrefix + "/" + subdomain + "/" + hostname
+	if err := d.DeleteJSON(legacyKey); err != nil {
+		slog.Error("failed to delete legacy DNS entry", "err", err, "key", legacyKey)
 		return err
 	}
 	return nil
 }
 
 // ホスト名とドメイン名からIPアドレスを取得する
+// A レコードがあれば A レコードを、無ければ AAAA レコードのアドレスを返す
 func (d *Database) GetDnsEntry(hostname, subdomain string) (string, error) {
 	slog.Debug("Getting DNS entry", "hostname", hostname, "subdomain", subdomain)
 
-	var ipAddress string
-	key := InternalDNSPrefix + "/" + subdomain + "/" + hostname
-	if _, err := d.GetJSON(key, &ipAddress); err != nil {
+	records, err := d.GetDnsRecordsByPath(InternalDNSPrefix + "/" + subdomain + "/" + hostname)
+	if err != nil {
 		return "", err
 	}
-	return ipAddress, nil
+	for _, recordType := range []string{DNS_RECORD_A, DNS_RECORD_AAAA} {
+		for _, rec := range records {
+			if rec.Type == recordType {
+				return rec.Value, nil
+			}
+		}
+	}
+	return "", ErrNotFound
 }
 
 // ホスト名とドメイン名で、エントリーを削除する。
+// サーバーのレコードだけを削除し、同じ名前のカスタムレコードは残す
 func (d *Database) DeleteDnsEntryByName(hostname, subdomain string) error {
 	slog.Debug("Deleting DNS entry by name", "hostname", hostname)
 	lockKey := "/lock/dns/" + subdomain + "/" + hostname
@@ -46,18 +157,416 @@ func (d *Database) DeleteDnsEntryByName(hostname, subdomain string) error {
 		return err
 	}
 	defer d.UnlockKey(mutex)
-	key := InternalDNSPrefix + "/" + subdomain + "/" + hostname
-	if err := d.DeleteJSON(key); err != nil {
-		slog.Error("failed to delete DNS entry", "err", err, "key", key)
-		return err
+
+	namePath := InternalDNSPrefix + "/" + subdomain + "/" + hostname
+	for _, key := range []string{namePath, dnsRecordKey(namePath, "server-a"), dnsRecordKey(namePath, "server-aaaa")} {
+		if err := d.DeleteJSON(key); err != nil {
+			slog.Error("failed to delete DNS entry", "err", err, "key", key)
+			return err
+		}
 	}
 	return nil
 }
 
-/*
-func (d *Database) UpdateDnsEntry(hostname string, ipAddress string) error {
-	slog.Debug("Updateting DNS entry", "hostname", hostname, "ipAddress", ipAddress)
-	// Here you would add the logic to put the DNS entry into etcd
+// 名前のキーに登録されたレコードを全て取得する
+// 以前の形式のエントリーは A または AAAA レコードとして返す
+func (d *Database) GetDnsRecordsByPath(namePath string) ([]api.DnsRecord, error) {
+	var records []api.DnsRecord
+
+	resp, err := d.getRaw(namePath)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	if err == nil {
+		if rec, ok := decodeLegacyDnsEntry(namePath, resp.Kvs[0].Value); ok {
+			records = append(records, rec)
+		}
+	}
+
+	resp, err = d.GetByPrefix(namePath + dnsRecordSeparator)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+	if err == nil {
+		for _, kv := range resp.Kvs {
+			var rec api.DnsRecord
+			if err := json.Unmarshal(kv.Value, &rec); err != nil {
+				slog.Warn("skipped malformed DNS record", "err", err, "key", string(kv.Key))
+				continue
+			}
+			records = append(records, rec)
+		}
+	}
+
+	if len(records) == 0 {
+		return nil, ErrNotFound
+	}
+	return records, nil
+}
+
+// 以前の形式のエントリー（IPアドレスの文字列）をレコードに変換する
+func decodeLegacyDnsEntry(namePath string, raw []byte) (api.DnsRecord, bool) {
+	var ipStr string
+	if err := json.Unmarshal(raw, &ipStr); err != nil {
+		return api.DnsRecord{}, false
+	}
+	ip := net.ParseIP(strings.TrimSpace(ipStr))
+	if ip == nil {
+		return api.DnsRecord{}, false
+	}
+	labels := strings.Split(strings.TrimPrefix(namePath, InternalDNSPrefix+"/"), "/")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	recordType := DNS_RECORD_A
+	if ip.To4() == nil {
+		recordType = DNS_RECORD_AAAA
+	}
+	return api.DnsRecord{
+		Name:   strings.Join(labels, "."),
+		Type:   recordType,
+		Value:  ip.String(),
+		Ttl:    util.IntPtrInt(DefaultDnsRecordTTL),
+		Source: util.StringPtr(DNS_RECORD_SOURCE_SERVER),
+	}, true
+}
+
+// カスタムレコードを登録する
+func (d *Database) CreateDnsRecord(spec api.DnsRecord) (api.DnsRecord, error) {
+	rec, err := NormalizeDnsRecord(spec)
+	if err != nil {
+		return api.DnsRecord{}, err
+	}
+	namePath := DnsNamePath(rec.Name)
+
+	lockKey := "/lock/dns/" + strings.TrimPrefix(namePath, InternalDNSPrefix+"/")
+	mutex, err := d.LockKey(lockKey)
+	if err != nil {
+		slog.Error("failed to lock", "err", err, "key", lockKey)
+		return api.DnsRecord{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	// CNAME は同じ名前の他のレコードと共存できない
+	existing, err := d.GetDnsRecordsByPath(namePath)
+	if err != nil && err != ErrNotFound {
+		return api.DnsRecord{}, err
+	}
+	for _, e := range existing {
+		if rec.Type == DNS_RECORD_CNAME || e.Type == DNS_RECORD_CNAME {
+			return api.DnsRecord{}, fmt.Errorf("%w: a CNAME record cannot coexist with other records of %s", ErrInvalidDnsRecord, rec.Name)
+		}
+	}
+
+	var indexKey string
+	for {
+		id := uuid.New().String()[:5]
+		indexKey = DnsRecordIndexPrefix + "/" + id
+		if _, err := d.getRaw(indexKey); err == ErrNotFound {
+			rec.Id = util.StringPtr(id)
+			break
+		} else if err != nil {
+			return api.DnsRecord{}, err
+		}
+	}
+
+	key := dnsRecordKey(namePath, *rec.Id)
+	if err := d.PutJSON(key, rec); err != nil {
+		slog.Error("failed to write DNS record", "err", err, "key", key)
+		return api.DnsRecord{}, err
+	}
+	if err := d.PutJSON(indexKey, rec); err != nil {
+		slog.Error("failed to write DNS record index", "err", err, "key", indexKey)
+		_ = d.DeleteJSON(key)
+		return api.DnsRecord{}, err
+	}
+	return rec, nil
+}
+
+// カスタムレコードの一覧を名前の順に取得する
+func (d *Database) GetDnsRecords() ([]api.DnsRecord, error) {
+	resp, err := d.GetByPrefix(DnsRecordIndexPrefix + "/")
+	if err == ErrNotFound {
+		return []api.DnsRecord{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	records := make([]api.DnsRecord, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var rec api.DnsRecord
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			slog.Warn("skipped malformed DNS record", "err", err, "key", string(kv.Key))
+			continue
+		}
+		records = append(records, rec)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].Type < records[j].Type
+	})
+	return records, nil
+}
+
+// IDでカスタムレコードを取得する
+func (d *Database) GetDnsRecordById(id string) (api.DnsRecord, error) {
+	var rec api.DnsRecord
+	if _, err := d.GetJSON(DnsRecordIndexPrefix+"/"+id, &rec); err != nil {
+		return api.DnsRecord{}, err
+	}
+	return rec, nil
+}
+
+// IDでカスタムレコードを削除する
+func (d *Database) DeleteDnsRecordById(id string) error {
+	rec, err := d.GetDnsRecordById(id)
+	if err != nil {
+		return err
+	}
+	namePath := DnsNamePath(rec.Name)
+
+	lockKey := "/lock/dns/" + strings.TrimPrefix(namePath, InternalDNSPrefix+"/")
+	mutex, err := d.LockKey(lockKey)
+	if err != nil {
+		slog.Error("failed to lock", "err", err, "key", lockKey)
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	if err := d.DeleteJSON(dnsRecordKey(namePath, id)); err != nil {
+		return err
+	}
+	return d.DeleteJSON(DnsRecordIndexPrefix + "/" + id)
+}
+
+// NormalizeDnsRecord はカスタムレコードを検証して、既定値を補完する
+// PTR レコードの名前にIPアドレスが指定された場合は、逆引きの名前に変換する
+func NormalizeDnsRecord(spec api.DnsRecord) (api.DnsRecord, error) {
+	rec := spec
+	rec.Id = nil
+	rec.Source = util.StringPtr(DNS_RECORD_SOURCE_CUSTOM)
+	rec.Type = strings.ToUpper(strings.TrimSpace(rec.Type))
+	rec.Name = strings.TrimSuffix(strings.TrimSpace(rec.Name), ".")
+	rec.Value = strings.TrimSpace(rec.Value)
+
+	if rec.Type == DNS_RECORD_PTR && net.ParseIP(rec.Name) != nil {
+		reverse, err := dns.ReverseAddr(rec.Name)
+		if err != nil {
+			return api.DnsRecord{}, fmt.Errorf("%w: %v", ErrInvalidDnsRecord, err)
+		}
+		rec.Name = strings.TrimSuffix(reverse, ".")
+	}
+	if err := validateDnsName(rec.Name); err != nil {
+		return api.DnsRecord{}, fmt.Errorf("%w: name: %v", ErrInvalidDnsRecord, err)
+	}
+
+	switch rec.Type {
+	case DNS_RECORD_A, DNS_RECORD_AAAA:
+		ip := net.ParseIP(rec.Value)
+		if ip == nil || (ip.To4() != nil) != (rec.Type == DNS_RECORD_A) {
+			return api.DnsRecord{}, fmt.Errorf("%w: value must be an IPv4 address for A and an IPv6 address for AAAA", ErrInvalidDnsRecord)
+		}
+		rec.Value = ip.String()
+	case DNS_RECORD_CNAME, DNS_RECORD_PTR, DNS_RECORD_SRV:
+		rec.Value = strings.TrimSuffix(rec.Value, ".")
+		if err := validateDnsName(rec.Value); err != nil {
+			return api.DnsRecord{}, fmt.Errorf("%w: value: %v", ErrInvalidDnsRecord, err)
+		}
+	default:
+		return api.DnsRecord{}, fmt.Errorf("%w: unsupported type %q", ErrInvalidDnsRecord, spec.Type)
+	}
+
+	if rec.Type == DNS_RECORD_SRV {
+		if rec.Port == nil || *rec.Port < 1 || *rec.Port > 65535 {
+			return api.DnsRecord{}, fmt.Errorf("%w: port must be between 1 and 65535 for SRV", ErrInvalidDnsRecord)
+		}
+		if rec.Priority == nil {
+			rec.Priority = util.IntPtrInt(0)
+		}
+		if rec.Weight == nil {
+			rec.Weight = util.IntPtrInt(0)
+		}
+		if *rec.Priority < 0 || *rec.Priority > 65535 || *rec.Weight < 0 || *rec.Weight > 65535 {
+			return api.DnsRecord{}, fmt.Errorf("%w: priority and weight must be between 0 and 65535 for SRV", ErrInvalidDnsRecord)
+		}
+	} else {
+		rec.Port, rec.Priority, rec.Weight = nil, nil, nil
+	}
+
+	if rec.Ttl == nil {
+		rec.Ttl = util.IntPtrInt(DefaultDnsRecordTTL)
+	}
+	if *rec.Ttl <= 0 {
+		return api.DnsRecord{}, fmt.Errorf("%w: ttl must be positive", ErrInvalidDnsRecord)
+	}
+	return rec, nil
+}
+
+func validateDnsName(name string) error {
+	if name == "" {
+		return fmt.Errorf("is empty")
+	}
+	if _, ok := dns.IsDomainName(name); !ok {
+		return fmt.Errorf("%q is not a domain name", name)
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || label == "@" || strings.Contains(label, "/") {
+			return fmt.Errorf("%q has an invalid label", name)
+		}
+	}
 	return nil
 }
-*/
+
+// IPAM の割り当てから、IPアドレスを持つサーバーの名前（host.network）を取得する
+// 逆引き（PTR）の応答に使う
+func (d *Database) GetHostnamesByIP(ipAddress string) ([]string, error) {
+	ip := net.ParseIP(strings.TrimSpace(ipAddress))
+	if ip == nil {
+		return nil, fmt.Errorf("%w: invalid IP address %q", ErrInvalidDnsRecord, ipAddress)
+	}
+
+	resp, err := d.GetByPrefix(NetworkPrefix + "/")
+	if err == ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	vnetNames := map[string]string{}
+	for _, kv := range resp.Kvs {
+		key := string(kv.Key)
+		if !strings.Contains(key, "/ip_network/") || !strings.Contains(key, "/ip_address/") {
+			continue
+		}
+		var rec api.IPAddress
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			continue
+		}
+		if allocated := net.ParseIP(rec.IpAddress); allocated == nil || !allocated.Equal(ip) {
+			continue
+		}
+		if rec.HostId == nil || strings.TrimSpace(*rec.HostId) == "" {
+			continue
+		}
+
+		vnetId, _, _ := strings.Cut(strings.TrimPrefix(key, NetworkPrefix+"/"), "/")
+		vnetName, ok := vnetNames[vnetId]
+		if !ok {
+			vnet, err := d.GetVirtualNetworkById(vnetId)
+			if err != nil {
+				slog.Warn("GetHostnamesByIP() virtual network not found", "err", err, "vnetId", vnetId)
+				continue
+			}
+			vnetName = vnet.Metadata.Name
+			vnetNames[vnetId] = vnetName
+		}
+		names = append(names, strings.TrimSpace(*rec.HostId)+"."+vnetName)
+	}
+	return names, nil
+}
+
+// ロードバランサーのレコードを生成する
+//
+//	<name>.<network>                  A/AAAA  bindPublicIpAddress
+//	_<listener>._<tcp|udp>.<name>.<network>  SRV     <name>.<network> の vipPort
+//
+// network は内部仮想ネットワークの名前
+func (d *Database) GetLoadBalancerDnsRecords(domain string) ([]api.DnsRecord, error) {
+	labels := DnsNameLabels(domain)
+	if len(labels) != 2 && len(labels) != 4 {
+		return nil, nil
+	}
+	lbName, network := labels[len(labels)-2], labels[len(labels)-1]
+
+	type listener struct {
+		name     string
+		protocol string
+		port     int
+	}
+	type loadBalancer struct {
+		bindAddress string
+		listeners   []listener
+	}
+	var matched []loadBalancer
+
+	albs, err := d.GetLoadBalancers()
+	if err != nil {
+		return nil, err
+	}
+	for _, alb := range albs {
+		if alb.Metadata.Name != lbName || strings.TrimSpace(alb.Spec.InternalVirtualNetwork) != network {
+			continue
+		}
+		lb := loadBalancer{bindAddress: alb.Spec.BindPublicIpAddress}
+		for _, l := range alb.Spec.Listeners {
+			// ALB の HTTP リスナーは TCP で待ち受ける
+			protocol := strings.ToLower(strings.TrimSpace(l.Protocol))
+			if protocol == "http" {
+				protocol = "tcp"
+			}
+			lb.listeners = append(lb.listeners, listener{name: l.Name, protocol: protocol, port: l.VipPort})
+		}
+		matched = append(matched, lb)
+	}
+	nlbs, err := d.GetNetworkLoadBalancers()
+	if err != nil {
+		return nil, err
+	}
+	for _, nlb := range nlbs {
+		if nlb.Metadata.Name != lbName || strings.TrimSpace(nlb.Spec.InternalVirtualNetwork) != network {
+			continue
+		}
+		lb := loadBalancer{bindAddress: nlb.Spec.BindPublicIpAddress}
+		for _, l := range nlb.Spec.Listeners {
+			lb.listeners = append(lb.listeners, listener{name: l.Name, protocol: strings.ToLower(strings.TrimSpace(l.Protocol)), port: l.VipPort})
+		}
+		matched = append(matched, lb)
+	}
+
+	name := strings.Join(labels, ".")
+	target := lbName + "." + network
+	var records []api.DnsRecord
+	for _, lb := range matched {
+		if len(labels) == 2 {
+			address, _, _ := strings.Cut(strings.TrimSpace(lb.bindAddress), "/")
+			ip := net.ParseIP(address)
+			if ip == nil {
+				continue
+			}
+			recordType := DNS_RECORD_A
+			if ip.To4() == nil {
+				recordType = DNS_RECORD_AAAA
+			}
+			records = append(records, api.DnsRecord{
+				Name:   name,
+				Type:   recordType,
+				Value:  ip.String(),
+				Ttl:    util.IntPtrInt(DefaultDnsRecordTTL),
+				Source: util.StringPtr(DNS_RECORD_SOURCE_LOAD_BALANCER),
+			})
+			continue
+		}
+
+		service := strings.TrimPrefix(labels[0], "_")
+		protocol := strings.TrimPrefix(labels[1], "_")
+		for _, l := range lb.listeners {
+			if !strings.EqualFold(l.name, service) || !strings.EqualFold(l.protocol, protocol) {
+				continue
+			}
+			records = append(records, api.DnsRecord{
+				Name:     name,
+				Type:     DNS_RECORD_SRV,
+				Value:    target,
+				Ttl:      util.IntPtrInt(DefaultDnsRecordTTL),
+				Priority: util.IntPtrInt(0),
+				Weight:   util.IntPtrInt(0),
+				Port:     util.IntPtrInt(l.port),
+				Source:   util.StringPtr(DNS_RECORD_SOURCE_LOAD_BALANCER),
+			})
+		}
+	}
+	return records, nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestDecodeLegacyDnsEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      []byte
+		wantOK   bool
+		wantType string
+		wantIP   string
+	}{
+		{name: "valid IPv4 JSON", raw: []byte(`"192.168.100.2"`), wantOK: true, wantType: DNS_RECORD_A, wantIP: "192.168.100.2"},
+		{name: "valid IPv6 JSON", raw: []byte(`"2001:db8::10"`), wantOK: true, wantType: DNS_RECORD_AAAA, wantIP: "2001:db8::10"},
+		{name: "invalid JSON", raw: []byte(`192.168.100.2`)},
+		{name: "invalid IP string", raw: []byte(`"not-an-ip"`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, ok := decodeLegacyDnsEntry(InternalDNSPrefix+"/test-net-1/vm1", tt.raw)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if rec.Name != "vm1.test-net-1" || rec.Type != tt.wantType || rec.Value != tt.wantIP {
+				t.Fatalf("unexpected record: %+v", rec)
+			}
+		})
+	}
+}
+
+func TestNormalizeDnsRecord(t *testing.T) {
+	rec, err := NormalizeDnsRecord(api.DnsRecord{Name: "www.net1.", Type: "cname", Value: "vm1.net1.", Port: util.IntPtrInt(80)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Name != "www.net1" || rec.Type != DNS_RECORD_CNAME || rec.Value != "vm1.net1" {
+		t.Fatalf("unexpected record: %+v", rec)
+	}
+	if rec.Port != nil || rec.Ttl == nil || *rec.Ttl != DefaultDnsRecordTTL || rec.Source == nil || *rec.Source != DNS_RECORD_SOURCE_CUSTOM {
+		t.Fatalf("unexpected defaults: %+v", rec)
+	}
+
+	// PTR の名前にIPアドレスを指定すると逆引きの名前になる
+	rec, err = NormalizeDnsRecord(api.DnsRecord{Name: "192.168.100.2", Type: "PTR", Value: "vm1.net1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Name != "2.100.168.192.in-addr.arpa" {
+		t.Fatalf("unexpected PTR name: %s", rec.Name)
+	}
+
+	rec, err = NormalizeDnsRecord(api.DnsRecord{Name: "_web._tcp.alb1.net1", Type: "SRV", Value: "alb1.net1", Port: util.IntPtrInt(443)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Priority == nil || *rec.Priority != 0 || rec.Weight == nil || *rec.Weight != 0 {
+		t.Fatalf("unexpected SRV defaults: %+v", rec)
+	}
+
+	invalid := []api.DnsRecord{
+		{Name: "", Type: "A", Value: "192.168.100.2"},
+		{Name: "vm1.net1", Type: "MX", Value: "mail.net1"},
+		{Name: "vm1.net1", Type: "A", Value: "2001:db8::10"},
+		{Name: "vm1.net1", Type: "AAAA", Value: "192.168.100.2"},
+		{Name: "vm1.@.net1", Type: "A", Value: "192.168.100.2"},
+		{Name: "_web._tcp.alb1.net1", Type: "SRV", Value: "alb1.net1"},
+		{Name: "vm1.net1", Type: "A", Value: "192.168.100.2", Ttl: util.IntPtrInt(0)},
+	}
+	for _, spec := range invalid {
+		if _, err := NormalizeDnsRecord(spec); !errors.Is(err, ErrInvalidDnsRecord) {
+			t.Fatalf("NormalizeDnsRecord(%+v) err = %v, want ErrInvalidDnsRecord", spec, err)
+		}
+	}
+}
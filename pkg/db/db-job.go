@@ -1,12 +1,18 @@
 package db
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/exec"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,7 +43,8 @@ var JobStatus = map[int]string{
 	4: "SUCCEEDED",
 }
 
-// 更新ロックを入れること
+// 終了済みのジョブはキャンセルできない
+var ErrJobFinished = errors.New("job already finished")
 
 // ジョブコントローラの生成
 func NewJobController(url, jobLogPath string) (*Job, error) {
@@ -64,8 +71,49 @@ func NewJobController(url, jobLogPath string) (*Job, error) {
 	}, nil
 }
 
+// 既存のデータベース接続を使うジョブ機能の生成
+// marmotd の API やコントローラーから、長時間の処理をジョブとして記録するために使う
+func NewJob(d *Database, jobLogPath string) *Job {
+	if len(jobLogPath) == 0 {
+		jobLogPath = JOB_PATH
+	}
+	return &Job{
+		Database:   d,
+		jobLogPath: jobLogPath,
+	}
+}
+
 // 新しいジョブの登録
 func (d *Job) EntryJob(name string, cmd ...string) (string, error) {
+	return d.entryJob(name, cmd, nil)
+}
+
+// リソースに対する長時間の処理を追跡するジョブの登録
+// コマンドは持たず、処理を実行するノードが StartJob で開始して Finish で終了させる
+func (d *Job) EntryResourceJob(name, nodeName, resourceKind string) (string, error) {
+	return d.entryJob(name, nil, func(job *api.Job) {
+		if len(nodeName) > 0 {
+			job.Metadata.NodeName = util.StringPtr(nodeName)
+		}
+		job.Spec.ResourceKind = util.StringPtr(resourceKind)
+	})
+}
+
+// ジョブの対象リソースのIDと、処理を実行するノードを設定する
+func (d *Job) SetJobResource(id, resourceId, nodeName string) error {
+	return d.updateJob(id, func(job *api.Job) error {
+		job.Spec.ResourceId = util.StringPtr(resourceId)
+		if len(nodeName) > 0 {
+			if job.Metadata == nil {
+				job.Metadata = &api.Metadata{}
+			}
+			job.Metadata.NodeName = util.StringPtr(nodeName)
+		}
+		return nil
+	})
+}
+
+func (d *Job) entryJob(name string, cmd []string, fill func(*api.Job)) (string, error) {
 	var job api.Job
 	var spec api.JobSpec
 	var metadata api.Metadata
@@ -107,6 +155,9 @@ func (d *Job) EntryJob(name string, cmd ...string) (string, error) {
 	job.Status.Status = util.StringPtr(JobStatus[job.Status.StatusCode])
 	job.Status.CreationTimeStamp = util.TimePtr(time.Now())
 	job.Status.LastUpdateTimeStamp = util.TimePtr(time.Now())
+	if fill != nil {
+		fill(&job)
+	}
 
 	// ジョブのIDの重複は発生しないので、排他制御しない
 	Key := JobPrefix + "/" + job.Id
@@ -128,35 +179,70 @@ func (d *Job) GetJob(id string) (api.Job, error) {
 }
 
 // ジョブ番号を指定してジョブをキャンセルする
+// 実行待ちのジョブはそのまま終了し、実行中のジョブは StartJob の監視を通して処理が中断される
+// キャンセル済みのジョブは何もしない、終了済みのジョブは ErrJobFinished を返す
 func (d *Job) CancelJob(id string) error {
-	// ロックする
-	lockName := JobPrefix + "/" + id
-	m, err := d.Database.LockKey(lockName)
-	if err != nil {
-		slog.Error("failed to acquire lock for canceling job", "err", err, "lockName", lockName)
-		return fmt.Errorf("failed to acquire lock for canceling job %s: %w", id, err)
+	err := d.updateJob(id, func(job *api.Job) error {
+		switch job.Status.StatusCode {
+		case JOB_SUCCEEDED, JOB_FAILED:
+			return ErrJobFinished
+		case JOB_PENDING:
+			job.Spec.FinishTime = util.TimePtr(time.Now())
+		}
+		job.Status.StatusCode = JOB_CANCELED
+		job.Status.Status = util.StringPtr(JobStatus[job.Status.StatusCode])
+		job.Status.Message = util.StringPtr("canceled by request")
+		return nil
+	})
+	if err != nil && err != ErrJobFinished {
+		slog.Error("CancelJob() failed to update job entry", "err", err, "id", id)
 	}
-	defer d.Database.UnlockKey(m)
+	return err
+}
 
-	// キーでデータを取得
-	job, err := d.GetJob(id)
-	if err != nil {
-		slog.Error("CancelJob() failed to get job entry", "err", err, "id", id)
-		return err
-	}
+// ジョブの更新、他の更新と競合した場合は読み直して再実行する
+// update が errSkipJobUpdate を返した場合は更新せずに nil を返す
+func (d *Job) updateJob(id string, update func(job *api.Job) error) error {
+	_, err := d.updateJobRev(id, update)
+	return err
+}
 
-	// 状態をキャンセルにして
-	job.Status.StatusCode = JOB_CANCELED
-	job.Status.Status = util.StringPtr(JobStatus[job.Status.StatusCode])
-	job.Status.LastUpdateTimeStamp = util.TimePtr(time.Now())
+var errSkipJobUpdate = errors.New("skip job update")
 
-	// データを保存する
+func (d *Job) updateJobRev(id string, update func(job *api.Job) error) (int64, error) {
 	key := JobPrefix + "/" + id
-	if err := d.Database.PutJSON(key, job); err != nil {
-		slog.Error("CancelJob() failed to update job entry", "err", err, "key", key)
-		return err
+	for {
+		var job api.Job
+		resp, err := d.Database.GetJSON(key, &job)
+		if err != nil {
+			return 0, err
+		}
+		if job.Spec == nil {
+			job.Spec = &api.JobSpec{}
+		}
+		if job.Status == nil {
+			job.Status = &api.Status{}
+		}
+		rev := resp.Kvs[0].ModRevision
+		if err := update(&job); err == errSkipJobUpdate {
+			return rev, nil
+		} else if err != nil {
+			return rev, err
+		}
+		job.Status.LastUpdateTimeStamp = util.TimePtr(time.Now())
+		err = d.Database.PutJSONCAS(key, rev, job)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		latest, err := d.Database.getRaw(key)
+		if err != nil {
+			return 0, err
+		}
+		return latest.Kvs[0].ModRevision, nil
 	}
-	return nil
 }
 
 // 古いものから順番にジョブのリストを取得する
@@ -353,9 +439,9 @@ func (d *Job) CleanupJob(t int) error {
 				slog.Error("failed to delete job entry", "err", err, "key", key)
 				return err
 			}
-			// ジョブログの削除
+			// ジョブログの削除、実行されずに終わったジョブにはログが無い
 			jobLogFile := fmt.Sprintf("%s/%s.log", d.jobLogPath, job.Id)
-			if err := os.Remove(jobLogFile); err != nil {
+			if err := os.Remove(jobLogFile); err != nil && !os.IsNotExist(err) {
 				slog.Error("failed to delete job log file", "err", err, "file", jobLogFile)
 				return err
 			}
@@ -365,3 +451,193 @@ func (d *Job) CleanupJob(t int) error {
 	return nil
 
 }
+
+// 実行中のジョブ
+// Context はジョブがキャンセルされると終了する
+type RunningJob struct {
+	job    *Job
+	id     string
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	log io.WriteCloser
+}
+
+// ジョブを実行中にして、キャンセルを監視する
+// キャンセル済みのジョブは、Context が終了した状態で返す
+func (d *Job) StartJob(ctx context.Context, id string) (*RunningJob, error) {
+	rev, err := d.updateJobRev(id, func(job *api.Job) error {
+		if job.Status.StatusCode != JOB_PENDING {
+			return errSkipJobUpdate
+		}
+		job.Status.StatusCode = JOB_RUNNING
+		job.Status.Status = util.StringPtr(JobStatus[job.Status.StatusCode])
+		job.Spec.StartTime = util.TimePtr(time.Now())
+		return nil
+	})
+	if err != nil {
+		slog.Error("StartJob() failed to update job entry", "err", err, "id", id)
+		return nil, err
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	r := &RunningJob{
+		job:    d,
+		id:     id,
+		ctx:    runCtx,
+		cancel: cancel,
+		log:    d.openJobLog(id),
+	}
+
+	job, err := d.GetJob(id)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if job.Status.StatusCode == JOB_CANCELED {
+		cancel()
+		return r, nil
+	}
+
+	// ジョブのキーを監視して、キャンセルされたら Context を終了する
+	key := JobPrefix + "/" + id
+	go func() {
+		for ev := range d.Database.WatchPrefix(runCtx, key, rev) {
+			if ev.Type == WATCH_ERROR {
+				slog.Warn("job watch failed", "err", ev.Err, "id", id)
+				return
+			}
+			if ev.Key != key {
+				continue
+			}
+			if ev.Type == WATCH_DELETED {
+				cancel()
+				return
+			}
+			var latest api.Job
+			if err := json.Unmarshal(ev.Value, &latest); err != nil {
+				continue
+			}
+			if latest.Status != nil && latest.Status.StatusCode == JOB_CANCELED {
+				slog.Info("job is canceled", "id", id)
+				cancel()
+				return
+			}
+		}
+	}()
+	return r, nil
+}
+
+// ジョブのID
+func (r *RunningJob) Id() string {
+	return r.id
+}
+
+// ジョブがキャンセルされると終了する Context
+func (r *RunningJob) Context() context.Context {
+	return r.ctx
+}
+
+// ジョブログに1行書き込む
+func (r *RunningJob) Logf(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.log == nil {
+		return
+	}
+	_, _ = fmt.Fprintf(r.log, "%s %s\n", time.Now().Format(time.RFC3339), fmt.Sprintf(format, args...))
+}
+
+// ジョブを終了する
+// err が nil なら正常終了、そうでなければ失敗とする。キャンセル済みのジョブはキャンセルのまま残す
+func (r *RunningJob) Finish(err error) {
+	defer r.cancel()
+	if err != nil {
+		r.Logf("failed: %v", err)
+	} else {
+		r.Logf("succeeded")
+	}
+
+	updateErr := r.job.updateJob(r.id, func(job *api.Job) error {
+		job.Spec.FinishTime = util.TimePtr(time.Now())
+		if err != nil {
+			job.Spec.ExitCode = util.IntPtrInt(1)
+		} else {
+			job.Spec.ExitCode = util.IntPtrInt(0)
+		}
+		if job.Status.StatusCode == JOB_CANCELED {
+			return nil
+		}
+		if err != nil {
+			job.Status.StatusCode = JOB_FAILED
+			job.Status.Message = util.StringPtr(err.Error())
+		} else {
+			job.Status.StatusCode = JOB_SUCCEEDED
+		}
+		job.Status.Status = util.StringPtr(JobStatus[job.Status.StatusCode])
+		return nil
+	})
+	if updateErr != nil {
+		slog.Error("failed to finish job", "err", updateErr, "id", r.id)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.log != nil {
+		_ = r.log.Close()
+		r.log = nil
+	}
+}
+
+// ジョブログを追記で開く、開けない場合はログを捨てる
+func (d *Job) openJobLog(id string) io.WriteCloser {
+	if err := os.MkdirAll(d.jobLogPath, 0750); err != nil {
+		slog.Warn("failed to create job log directory", "err", err, "path", d.jobLogPath)
+		return nil
+	}
+	jobLogFile := fmt.Sprintf("%s/%s.log", d.jobLogPath, id)
+	file, err := os.OpenFile(jobLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		slog.Warn("failed to open job log file", "err", err, "file", jobLogFile)
+		return nil
+	}
+	return file
+}
+
+// ジョブログの取得
+// lines が正の値の場合は末尾の lines 行だけを返す。ログが無い場合は os.ErrNotExist を返す
+func (d *Job) ReadJobLog(id string, lines int) ([]byte, error) {
+	jobLogFile := fmt.Sprintf("%s/%s.log", d.jobLogPath, id)
+	file, err := os.Open(jobLogFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return tailLines(file, lines)
+}
+
+// 末尾の n 行を返す、n が 0 以下なら全体を返す
+func tailLines(r io.Reader, n int) ([]byte, error) {
+	if n <= 0 {
+		return io.ReadAll(r)
+	}
+	ring := make([]string, 0, n)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(ring) == n {
+			ring = ring[1:]
+		}
+		ring = append(ring, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(ring) == 0 {
+		return []byte{}, nil
+	}
+	return []byte(strings.Join(ring, "\n") + "\n"), nil
+}
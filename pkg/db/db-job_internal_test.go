@@ -0,0 +1,49 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTailLines(t *testing.T) {
+	input := "l1\nl2\nl3\nl4\n"
+	cases := []struct {
+		n    int
+		want string
+	}{
+		{0, input},
+		{2, "l3\nl4\n"},
+		{10, input},
+	}
+	for _, c := range cases {
+		got, err := tailLines(strings.NewReader(input), c.n)
+		if err != nil {
+			t.Fatalf("tailLines(%d) failed: %v", c.n, err)
+		}
+		if string(got) != c.want {
+			t.Fatalf("tailLines(%d) = %q, want %q", c.n, got, c.want)
+		}
+	}
+}
+
+func TestReadJobLog(t *testing.T) {
+	dir := t.TempDir()
+	j := NewJob(nil, dir)
+	if _, err := j.ReadJobLog("abc12", 0); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("ReadJobLog() without log = %v, want ErrNotExist", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "abc12.log"), []byte("start\ndownload\ndone\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	got, err := j.ReadJobLog("abc12", 1)
+	if err != nil {
+		t.Fatalf("ReadJobLog() failed: %v", err)
+	}
+	if string(got) != "done\n" {
+		t.Fatalf("ReadJobLog() = %q, want last line", got)
+	}
+}
@@ -268,6 +268,7 @@ func (d *Database) GetLoadBalancers() ([]api.ApplicationLoadBalancer, error) {
 			slog.Error("GetLoadBalancers() unmarshal failed", "err", err, "key", string(kv.Key))
 			continue
 		}
+		setResourceVersion(&rec.Metadata, kv.ModRevision)
 		result = append(result, rec)
 	}
 	return result, nil
@@ -276,16 +277,17 @@ func (d *Database) GetLoadBalancers() ([]api.ApplicationLoadBalancer, error) {
 func (d *Database) GetLoadBalancerById(id string) (api.ApplicationLoadBalancer, error) {
 	key := LoadBalancerPrefix + "/" + id
 	var rec api.ApplicationLoadBalancer
-	_, err := d.GetJSON(key, &rec)
+	resp, err := d.GetJSON(key, &rec)
 	if err != nil {
 		return api.ApplicationLoadBalancer{}, err
 	}
+	setResourceVersion(&rec.Metadata, resp.Kvs[0].ModRevision)
 	return rec, nil
 }
 
 func (d *Database) UpdateLoadBalancerById(id string, spec api.ApplicationLoadBalancer) error {
 	for {
-		err := d.updateLoadBalancer(id, spec)
+		err := d.updateLoadBalancer(id, spec, "")
 		if err == ErrUpdateConflict {
 			continue
 		}
@@ -293,7 +295,19 @@ func (d *Database) UpdateLoadBalancerById(id string, spec api.ApplicationLoadBal
 	}
 }
 
-func (d *Database) updateLoadBalancer(id string, spec api.ApplicationLoadBalancer) error {
+// UpdateLoadBalancerByIdWithResourceVersion は resourceVersion が指定された場合、現在のリソースバージョンと一致する時だけ更新する
+// 一致しない場合は ErrResourceVersionConflict を返す
+func (d *Database) UpdateLoadBalancerByIdWithResourceVersion(id string, spec api.ApplicationLoadBalancer, resourceVersion string) error {
+	for {
+		err := d.updateLoadBalancer(id, spec, resourceVersion)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
+func (d *Database) updateLoadBalancer(id string, spec api.ApplicationLoadBalancer, resourceVersion string) error {
 	lockKey := "/lock/load-balancer/" + id
 	mutex, err := d.LockKey(lockKey)
 	if err != nil {
@@ -307,8 +321,15 @@ func (d *Database) updateLoadBalancer(id string, spec api.ApplicationLoadBalance
 	if err != nil {
 		return err
 	}
+	if err := checkResourceVersion(resourceVersion, resp.Kvs[0].ModRevision); err != nil {
+		return err
+	}
+	owner, project := rec.Metadata.Owner, rec.Metadata.Project
 	util.PatchStruct(&rec, spec)
+	// 所有者とプロジェクトはクォータの集計と認可に使うため作成後は変更しない
+	rec.Metadata.Owner, rec.Metadata.Project = owner, project
 	api.SetLoadBalancerID(&rec, id)
+	rec.Metadata.ResourceVersion = nil
 	return d.PutJSONCAS(key, resp.Kvs[0].ModRevision, &rec)
 }
 
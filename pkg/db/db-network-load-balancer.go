@@ -268,6 +268,7 @@ func (d *Database) GetNetworkLoadBalancers() ([]api.NetworkLoadBalancer, error)
 			slog.Error("GetNetworkLoadBalancers() unmarshal failed", "err", err, "key", string(kv.Key))
 			continue
 		}
+		setResourceVersion(&rec.Metadata, kv.ModRevision)
 		result = append(result, rec)
 	}
 	return result, nil
@@ -276,16 +277,17 @@ func (d *Database) GetNetworkLoadBalancers() ([]api.NetworkLoadBalancer, error)
 func (d *Database) GetNetworkLoadBalancerById(id string) (api.NetworkLoadBalancer, error) {
 	key := NetworkLoadBalancerPrefix + "/" + id
 	var rec api.NetworkLoadBalancer
-	_, err := d.GetJSON(key, &rec)
+	resp, err := d.GetJSON(key, &rec)
 	if err != nil {
 		return api.NetworkLoadBalancer{}, err
 	}
+	setResourceVersion(&rec.Metadata, resp.Kvs[0].ModRevision)
 	return rec, nil
 }
 
 func (d *Database) UpdateNetworkLoadBalancerById(id string, spec api.NetworkLoadBalancer) error {
 	for {
-		err := d.updateNetworkLoadBalancer(id, spec)
+		err := d.updateNetworkLoadBalancer(id, spec, "")
 		if err == ErrUpdateConflict {
 			continue
 		}
@@ -293,7 +295,19 @@ func (d *Database) UpdateNetworkLoadBalancerById(id string, spec api.NetworkLoad
 	}
 }
 
-func (d *Database) updateNetworkLoadBalancer(id string, spec api.NetworkLoadBalancer) error {
+// UpdateNetworkLoadBalancerByIdWithResourceVersion は resourceVersion が指定された場合、現在のリソースバージョンと一致する時だけ更新する
+// 一致しない場合は ErrResourceVersionConflict を返す
+func (d *Database) UpdateNetworkLoadBalancerByIdWithResourceVersion(id string, spec api.NetworkLoadBalancer, resourceVersion string) error {
+	for {
+		err := d.updateNetworkLoadBalancer(id, spec, resourceVersion)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
+func (d *Database) updateNetworkLoadBalancer(id string, spec api.NetworkLoadBalancer, resourceVersion string) error {
 	lockKey := "/lock/network-load-balancer/" + id
 	mutex, err := d.LockKey(lockKey)
 	if err != nil {
@@ -307,8 +321,15 @@ func (d *Database) updateNetworkLoadBalancer(id string, spec api.NetworkLoadBala
 	if err != nil {
 		return err
 	}
+	if err := checkResourceVersion(resourceVersion, resp.Kvs[0].ModRevision); err != nil {
+		return err
+	}
+	owner, project := rec.Metadata.Owner, rec.Metadata.Project
 	util.PatchStruct(&rec, spec)
+	// 所有者とプロジェクトはクォータの集計と認可に使うため作成後は変更しない
+	rec.Metadata.Owner, rec.Metadata.Project = owner, project
 	api.SetNetworkLoadBalancerID(&rec, id)
+	rec.Metadata.ResourceVersion = nil
 	return d.PutJSONCAS(key, resp.Kvs[0].ModRevision, &rec)
 }
 
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// ノードのメンテナンス状態
+const (
+	NODE_CORDONED = "CORDONED" // スケジュール対象外
+	NODE_DRAINING = "DRAINING" // スケジュール対象外、稼働中のサーバーを移行または停止中
+	NODE_DRAINED  = "DRAINED"  // スケジュール対象外、稼働中のサーバーなし
+)
+
+// ドレイン時の稼働中サーバーの扱い
+const (
+	DRAIN_ACTION_MIGRATE = "migrate"
+	DRAIN_ACTION_STOP    = "stop"
+)
+
+// IsNodeUnschedulable は HostStatus のノードが cordon または drain されているかを返す
+func IsNodeUnschedulable(status api.HostStatus) bool {
+	return status.Maintenance != nil && status.Maintenance.Unschedulable != nil && *status.Maintenance.Unschedulable
+}
+
+// CordonNode はノードをスケジュール対象外にする
+// ドレイン中またはドレイン済みのノードは状態を変えない
+func (d *Database) CordonNode(nodeName, reason string) (api.NodeMaintenance, error) {
+	return d.putNodeMaintenance(nodeName, func(rec *api.NodeMaintenance) {
+		if rec.State == nil || *rec.State == "" {
+			rec.State = util.StringPtr(NODE_CORDONED)
+		}
+		if reason = strings.TrimSpace(reason); reason != "" {
+			rec.Reason = util.StringPtr(reason)
+		}
+	})
+}
+
+// DrainNode はノードをスケジュール対象外にし、稼働中のサーバーの移行または停止を開始する
+func (d *Database) DrainNode(nodeName, action, reason string) (api.NodeMaintenance, error) {
+	action = strings.TrimSpace(action)
+	if action == "" {
+		action = DRAIN_ACTION_MIGRATE
+	}
+	if action != DRAIN_ACTION_MIGRATE && action != DRAIN_ACTION_STOP {
+		return api.NodeMaintenance{}, errors.New("drainAction must be migrate or stop")
+	}
+	return d.putNodeMaintenance(nodeName, func(rec *api.NodeMaintenance) {
+		rec.State = util.StringPtr(NODE_DRAINING)
+		rec.DrainAction = util.StringPtr(action)
+		rec.DrainStartedAt = util.TimePtr(time.Now())
+		rec.Message = util.StringPtr("")
+		if reason = strings.TrimSpace(reason); reason != "" {
+			rec.Reason = util.StringPtr(reason)
+		}
+	})
+}
+
+func (d *Database) putNodeMaintenance(nodeName string, update func(rec *api.NodeMaintenance)) (api.NodeMaintenance, error) {
+	nodeName = strings.TrimSpace(nodeName)
+	if nodeName == "" {
+		return api.NodeMaintenance{}, errors.New("node name is not set")
+	}
+
+	mutex, err := d.LockKey("/lock/node-maintenance/" + nodeName)
+	if err != nil {
+		return api.NodeMaintenance{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	key := NodeMaintenancePrefix + "/" + nodeName
+	var rec api.NodeMaintenance
+	if _, err := d.GetJSON(key, &rec); err != nil && err != ErrNotFound {
+		return api.NodeMaintenance{}, err
+	}
+	rec.NodeName = util.StringPtr(nodeName)
+	rec.Unschedulable = util.BoolPtr(true)
+	update(&rec)
+	rec.LastUpdated = util.TimePtr(time.Now())
+
+	if err := d.PutJSON(key, rec); err != nil {
+		slog.Error("putNodeMaintenance() PutJSON failed", "err", err, "key", key)
+		return api.NodeMaintenance{}, err
+	}
+	return rec, nil
+}
+
+// UncordonNode はノードをスケジュール対象に戻す。ドレイン中であれば中止する
+func (d *Database) UncordonNode(nodeName string) error {
+	nodeName = strings.TrimSpace(nodeName)
+	mutex, err := d.LockKey("/lock/node-maintenance/" + nodeName)
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+	return d.DeleteJSON(NodeMaintenancePrefix + "/" + nodeName)
+}
+
+// GetNodeMaintenance はノードのメンテナンス状態を返す。未設定の場合は ErrNotFound
+func (d *Database) GetNodeMaintenance(nodeName string) (api.NodeMaintenance, error) {
+	var rec api.NodeMaintenance
+	if _, err := d.GetJSON(NodeMaintenancePrefix+"/"+strings.TrimSpace(nodeName), &rec); err != nil {
+		return api.NodeMaintenance{}, err
+	}
+	return rec, nil
+}
+
+// getAllNodeMaintenance はノード名をキーにすべてのメンテナンス状態を返す
+func (d *Database) getAllNodeMaintenance() (map[string]api.NodeMaintenance, error) {
+	recs := map[string]api.NodeMaintenance{}
+	resp, err := d.GetByPrefix(NodeMaintenancePrefix + "/")
+	if err == ErrNotFound {
+		return recs, nil
+	}
+	if err != nil {
+		return recs, err
+	}
+	for _, kv := range resp.Kvs {
+		var rec api.NodeMaintenance
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			slog.Error("getAllNodeMaintenance() unmarshal failed", "err", err, "key", string(kv.Key))
+			continue
+		}
+		if rec.NodeName != nil {
+			recs[*rec.NodeName] = rec
+		}
+	}
+	return recs, nil
+}
+
+// UpdateNodeMaintenanceState はドレインの進行状態とメッセージを更新する
+// uncordon で削除されたノードは ErrNotFound を返し、状態を復活させない
+func (d *Database) UpdateNodeMaintenanceState(nodeName, state, message string) error {
+	nodeName = strings.TrimSpace(nodeName)
+	mutex, err := d.LockKey("/lock/node-maintenance/" + nodeName)
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	key := NodeMaintenancePrefix + "/" + nodeName
+	var rec api.NodeMaintenance
+	resp, err := d.GetJSON(key, &rec)
+	if err != nil {
+		return err
+	}
+	rec.State = util.StringPtr(state)
+	rec.Message = util.StringPtr(strings.TrimSpace(message))
+	rec.LastUpdated = util.TimePtr(time.Now())
+	return d.PutJSONCAS(key, resp.Kvs[0].ModRevision, rec)
+}
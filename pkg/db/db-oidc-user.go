@@ -0,0 +1,122 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+/*
+OIDC の ID プロバイダーで認証した利用者を保存する
+
+	利用者はローカルの利用者と同じ /marmot/user/<利用者ID> に spec.identityProvider: oidc で保存する。
+	パスワードは持たず、ロールは ID トークンの group の対応で認証のたびに置き換える。
+	ロック・無効化はローカルの利用者と同じく管理者が行う
+*/
+
+const IdentityProviderOIDC = "oidc"
+
+// ErrExternalUser は ID プロバイダーが管理するパスワードやロールを変更しようとしたことを表す
+var ErrExternalUser = errors.New("user is managed by the identity provider")
+
+// ExternalUser は外部の ID プロバイダーで認証した利用者
+type ExternalUser struct {
+	UserID      string
+	DisplayName string
+	Email       string
+	Roles       []string
+}
+
+func isOIDCUser(user api.User) bool {
+	return util.OrDefault(user.Spec.IdentityProvider, "") == IdentityProviderOIDC
+}
+
+// SyncOIDCUser は OIDC で認証した利用者を登録し、名前・メールアドレス・ロールを ID トークンに合わせる。
+// 同じ ID のローカルの利用者がいる場合は認証しない
+func (d *Database) SyncOIDCUser(ext ExternalUser) (api.User, error) {
+	userID := strings.TrimSpace(ext.UserID)
+	if userID == "" {
+		return api.User{}, fmt.Errorf("%w: the ID token has no user ID claim", ErrInvalidCredentials)
+	}
+	roles := make([]string, 0, len(ext.Roles))
+	for _, role := range ext.Roles {
+		if role = strings.TrimSpace(role); role != "" && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	name := strings.TrimSpace(ext.DisplayName)
+	if name == "" {
+		name = userID
+	}
+
+	key := userKey(userID)
+	mutex, err := d.LockKey(key)
+	if err != nil {
+		return api.User{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	var user api.User
+	resp, err := d.GetJSON(key, &user)
+	if err == ErrNotFound {
+		user = api.User{
+			ApiVersion: "v1",
+			Kind:       "User",
+			Metadata:   api.Metadata{Id: userID, Name: name},
+			Spec: api.UserSpec{
+				Enabled:          true,
+				IdentityProvider: util.StringPtr(IdentityProviderOIDC),
+				Roles:            &roles,
+			},
+		}
+		if email := strings.TrimSpace(ext.Email); email != "" {
+			user.Spec.Email = &email
+		}
+		normalizeUserIdentity(&user, userID)
+		if err := d.PutJSON(key, user); err != nil {
+			return api.User{}, err
+		}
+		return user, nil
+	} else if err != nil {
+		return api.User{}, err
+	}
+
+	normalizeUserIdentity(&user, userID)
+	if !isOIDCUser(user) {
+		return api.User{}, fmt.Errorf("%w: %q is a local user", ErrInvalidCredentials, userID)
+	}
+	if isUserLocked(user) {
+		return api.User{}, ErrUserLocked
+	}
+	if !user.Spec.Enabled {
+		return api.User{}, ErrUserDisabled
+	}
+
+	email := strings.TrimSpace(ext.Email)
+	if slices.Equal(*user.Spec.Roles, roles) && user.Metadata.Name == name && util.OrDefault(user.Spec.Email, "") == email {
+		setResourceVersion(&user.Metadata, resp.Kvs[0].ModRevision)
+		return user, nil
+	}
+	user.Metadata.Name = name
+	user.Metadata.ResourceVersion = nil
+	user.Spec.Roles = &roles
+	user.Spec.Email = nil
+	if email != "" {
+		user.Spec.Email = &email
+	}
+	if err := d.PutJSONCAS(key, resp.Kvs[0].ModRevision, user); err != nil {
+		return api.User{}, err
+	}
+	return user, nil
+}
+
+// RecordUserLogin は利用者の最終ログイン日時を記録する
+func (d *Database) RecordUserLogin(userID string) error {
+	return d.recordUserLogin(userID)
+}
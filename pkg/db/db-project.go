@@ -0,0 +1,299 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+/*
+リソースをまとめるプロジェクトを保存する
+
+	/marmot/project/<プロジェクト名>  => api.Project
+
+リソースは metadata.project でプロジェクトに属し、指定のないリソースは default プロジェクトに属する。
+利用者に割り当てたロールは全体に、プロジェクトのメンバーとして割り当てたロールはそのプロジェクトのリソースだけに効く
+*/
+
+const (
+	ProjectPrefix      = "/marmot/project"
+	DefaultProjectName = "default"
+)
+
+var (
+	ErrInvalidProject = errors.New("invalid project")
+	ErrProjectInUse   = errors.New("project is in use")
+)
+
+// プロジェクト名は DNS ラベルと同じ形式に限る
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+func projectKey(name string) string {
+	return ProjectPrefix + "/" + strings.TrimSpace(name)
+}
+
+// ProjectOf はリソースが属するプロジェクトの名前を返す。指定のないリソースは default プロジェクトに属する
+func ProjectOf(meta api.Metadata) string {
+	if project := strings.TrimSpace(util.OrDefault(meta.Project, "")); project != "" {
+		return project
+	}
+	return DefaultProjectName
+}
+
+// NormalizeProject はプロジェクトの名前とメンバーを整えて検証する
+func NormalizeProject(spec api.Project) (api.Project, error) {
+	project, err := util.DeepCopy(spec)
+	if err != nil {
+		return api.Project{}, err
+	}
+	name := strings.TrimSpace(project.Metadata.Name)
+	if name == "" {
+		name = strings.TrimSpace(project.Metadata.Id)
+	}
+	if len(name) > 63 || !projectNamePattern.MatchString(name) {
+		return api.Project{}, fmt.Errorf("%w: name %q must be a DNS label (lower case letters, digits and '-')", ErrInvalidProject, name)
+	}
+	project.ApiVersion = "v1"
+	project.Kind = "Project"
+	key := projectKey(name)
+	project.Metadata = api.Metadata{
+		Id:      name,
+		Name:    name,
+		Key:     &key,
+		Comment: project.Metadata.Comment,
+		Labels:  project.Metadata.Labels,
+		Owner:   project.Metadata.Owner,
+	}
+
+	members := make([]api.ProjectMember, 0)
+	if project.Spec.Members != nil {
+		index := map[string]int{}
+		for _, m := range *project.Spec.Members {
+			userID := strings.TrimSpace(m.UserId)
+			if userID == "" {
+				return api.Project{}, fmt.Errorf("%w: members[].userId is required", ErrInvalidProject)
+			}
+			i, ok := index[userID]
+			if !ok {
+				i = len(members)
+				index[userID] = i
+				members = append(members, api.ProjectMember{UserId: userID, Roles: []string{}})
+			}
+			for _, role := range m.Roles {
+				role = strings.TrimSpace(role)
+				if role != "" && !slices.Contains(members[i].Roles, role) {
+					members[i].Roles = append(members[i].Roles, role)
+				}
+			}
+		}
+	}
+	for _, m := range members {
+		if len(m.Roles) == 0 {
+			return api.Project{}, fmt.Errorf("%w: member %q has no roles", ErrInvalidProject, m.UserId)
+		}
+		sort.Strings(m.Roles)
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return members[i].UserId < members[j].UserId
+	})
+	project.Spec.Members = &members
+	return project, nil
+}
+
+// validateProjectMembers はメンバーの利用者とロールが存在するかを確認する
+func (d *Database) validateProjectMembers(project api.Project) error {
+	for _, m := range util.OrDefault(project.Spec.Members, nil) {
+		if _, err := d.GetUserById(m.UserId); err == ErrNotFound {
+			return fmt.Errorf("%w: user %q does not exist", ErrInvalidProject, m.UserId)
+		} else if err != nil {
+			return err
+		}
+		for _, role := range m.Roles {
+			if _, err := d.GetRoleByName(role); err == ErrNotFound {
+				return fmt.Errorf("%w: role %q does not exist", ErrInvalidProject, role)
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// プロジェクトを登録する
+func (d *Database) CreateProject(spec api.Project) (api.Project, error) {
+	project, err := NormalizeProject(spec)
+	if err != nil {
+		return api.Project{}, err
+	}
+	if err := d.validateProjectMembers(project); err != nil {
+		return api.Project{}, err
+	}
+
+	key := projectKey(project.Metadata.Name)
+	mutex, err := d.LockKey(key)
+	if err != nil {
+		return api.Project{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	if _, err := d.getRaw(key); err == nil {
+		return api.Project{}, fmt.Errorf("%w: project %q already exists", ErrFound, project.Metadata.Name)
+	} else if err != ErrNotFound {
+		return api.Project{}, err
+	}
+	if err := d.PutJSON(key, project); err != nil {
+		slog.Error("failed to write project", "err", err, "key", key)
+		return api.Project{}, err
+	}
+	return project, nil
+}
+
+// EnsureDefaultProject は default プロジェクトがなければ作成する。
+// プロジェクトの導入前に作成したリソースは default プロジェクトに属する
+func (d *Database) EnsureDefaultProject() error {
+	if _, err := d.GetProjectByName(DefaultProjectName); err == nil {
+		return nil
+	} else if err != ErrNotFound {
+		return err
+	}
+	_, err := d.CreateProject(api.Project{
+		Metadata: api.Metadata{Name: DefaultProjectName},
+		Spec:     api.ProjectSpec{Description: util.StringPtr("resources without a project")},
+	})
+	if errors.Is(err, ErrFound) {
+		return nil
+	}
+	return err
+}
+
+// プロジェクトの一覧を名前の順に取得する
+func (d *Database) GetProjects() ([]api.Project, error) {
+	resp, err := d.GetByPrefix(ProjectPrefix + "/")
+	if err == ErrNotFound {
+		return []api.Project{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	projects := make([]api.Project, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var project api.Project
+		if err := json.Unmarshal(kv.Value, &project); err != nil {
+			slog.Warn("skipped malformed project", "err", err, "key", string(kv.Key))
+			continue
+		}
+		setResourceVersion(&project.Metadata, kv.ModRevision)
+		projects = append(projects, project)
+	}
+	sort.SliceStable(projects, func(i, j int) bool {
+		return projects[i].Metadata.Name < projects[j].Metadata.Name
+	})
+	return projects, nil
+}
+
+// 名前でプロジェクトを取得する
+func (d *Database) GetProjectByName(name string) (api.Project, error) {
+	var project api.Project
+	resp, err := d.GetJSON(projectKey(name), &project)
+	if err != nil {
+		return api.Project{}, err
+	}
+	setResourceVersion(&project.Metadata, resp.Kvs[0].ModRevision)
+	return project, nil
+}
+
+// プロジェクトの説明とメンバーを置き換える。metadata.resourceVersion を指定した場合は一致するときだけ更新する
+func (d *Database) UpdateProjectByName(name string, spec api.Project) (api.Project, error) {
+	spec.Metadata.Name = name
+	spec.Metadata.Id = name
+	project, err := NormalizeProject(spec)
+	if err != nil {
+		return api.Project{}, err
+	}
+	if err := d.validateProjectMembers(project); err != nil {
+		return api.Project{}, err
+	}
+
+	key := projectKey(name)
+	mutex, err := d.LockKey(key)
+	if err != nil {
+		return api.Project{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	var current api.Project
+	resp, err := d.GetJSON(key, &current)
+	if err != nil {
+		return api.Project{}, err
+	}
+	if err := checkResourceVersion(util.OrDefault(spec.Metadata.ResourceVersion, ""), resp.Kvs[0].ModRevision); err != nil {
+		return api.Project{}, err
+	}
+	project.Metadata.Owner = current.Metadata.Owner
+	if err := d.PutJSONCAS(key, resp.Kvs[0].ModRevision, project); err != nil {
+		return api.Project{}, err
+	}
+	return project, nil
+}
+
+// 名前でプロジェクトを削除する。リソースが残っているかは呼び出し側で確認する
+func (d *Database) DeleteProjectByName(name string) error {
+	if strings.TrimSpace(name) == DefaultProjectName {
+		return fmt.Errorf("%w: the default project cannot be deleted", ErrProjectInUse)
+	}
+	key := projectKey(name)
+	mutex, err := d.LockKey(key)
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	if _, err := d.getRaw(key); err != nil {
+		return err
+	}
+	return d.DeleteJSON(key)
+}
+
+// projectMemberRoles はプロジェクトで利用者に割り当てたロールを返す
+func projectMemberRoles(project api.Project, userID string) []string {
+	for _, m := range util.OrDefault(project.Spec.Members, nil) {
+		if strings.TrimSpace(m.UserId) == strings.TrimSpace(userID) {
+			return m.Roles
+		}
+	}
+	return nil
+}
+
+// projectAllows はプロジェクトのメンバーとしてのロールで、ラベルを持つリソースへの操作が許可されるかを返す
+func projectAllows(project api.Project, userID, resource, verb string, labels *map[string]interface{}, roles map[string]api.Role) bool {
+	for _, roleName := range projectMemberRoles(project, userID) {
+		role, ok := roles[strings.TrimSpace(roleName)]
+		if ok && roleAllowsResource(role, resource, verb, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProjectsForMember は利用者がメンバーのプロジェクトを返す
+func (d *Database) GetProjectsForMember(userID string) ([]api.Project, error) {
+	projects, err := d.GetProjects()
+	if err != nil {
+		return nil, err
+	}
+	member := make([]api.Project, 0)
+	for _, p := range projects {
+		if len(projectMemberRoles(p, userID)) > 0 {
+			member = append(member, p)
+		}
+	}
+	return member, nil
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestNormalizeProject(t *testing.T) {
+	members := []api.ProjectMember{
+		{UserId: " bob ", Roles: []string{"Viewer"}},
+		{UserId: "alice", Roles: []string{"Compute-Operator", " "}},
+		{UserId: "bob", Roles: []string{"Compute-Operator", "Viewer"}},
+	}
+	project, err := NormalizeProject(api.Project{
+		Metadata: api.Metadata{Name: " team-a ", ResourceVersion: util.StringPtr("10")},
+		Spec:     api.ProjectSpec{Members: &members},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeProject() error = %v", err)
+	}
+	if project.Metadata.Id != "team-a" || project.Metadata.Name != "team-a" || project.Kind != "Project" {
+		t.Fatalf("metadata = %+v kind = %q, want team-a Project", project.Metadata, project.Kind)
+	}
+	if project.Metadata.ResourceVersion != nil {
+		t.Fatalf("resourceVersion = %v, want it not to be stored", *project.Metadata.ResourceVersion)
+	}
+	got := *project.Spec.Members
+	if len(got) != 2 || got[0].UserId != "alice" || got[1].UserId != "bob" {
+		t.Fatalf("members = %+v, want alice and bob", got)
+	}
+	if strings.Join(got[1].Roles, ",") != "Compute-Operator,Viewer" {
+		t.Fatalf("roles of bob = %v, want merged Compute-Operator,Viewer", got[1].Roles)
+	}
+	if members[0].UserId != " bob " {
+		t.Fatalf("NormalizeProject() modified the request: %+v", members)
+	}
+
+	invalid := []struct {
+		name    string
+		project api.Project
+		want    string
+	}{
+		{"empty name", api.Project{}, "must be a DNS label"},
+		{"upper case", api.Project{Metadata: api.Metadata{Name: "Team"}}, "must be a DNS label"},
+		{"no user", api.Project{Metadata: api.Metadata{Name: "p"}, Spec: api.ProjectSpec{Members: &[]api.ProjectMember{{Roles: []string{"Viewer"}}}}}, "userId is required"},
+		{"no roles", api.Project{Metadata: api.Metadata{Name: "p"}, Spec: api.ProjectSpec{Members: &[]api.ProjectMember{{UserId: "alice"}}}}, "has no roles"},
+	}
+	for _, tt := range invalid {
+		_, err := NormalizeProject(tt.project)
+		if !errors.Is(err, ErrInvalidProject) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: NormalizeProject() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestProjectOf(t *testing.T) {
+	if got := ProjectOf(api.Metadata{}); got != DefaultProjectName {
+		t.Fatalf("ProjectOf(no project) = %q, want %q", got, DefaultProjectName)
+	}
+	if got := ProjectOf(api.Metadata{Project: util.StringPtr(" team-a ")}); got != "team-a" {
+		t.Fatalf("ProjectOf(team-a) = %q, want team-a", got)
+	}
+}
+
+func TestProjectAllows(t *testing.T) {
+	project := api.Project{Spec: api.ProjectSpec{Members: &[]api.ProjectMember{
+		{UserId: "alice", Roles: []string{"Compute-Operator"}},
+		{UserId: "bob", Roles: []string{"Viewer"}},
+	}}}
+	roles := builtinRoles()
+	tests := []struct {
+		user, resource, verb string
+		want                 bool
+	}{
+		{"alice", "Server", "create", true},
+		{"alice", "Network", "create", false},
+		{"bob", "Server", "read", true},
+		{"bob", "Server", "delete", false},
+		{"carol", "Server", "read", false},
+	}
+	for _, tt := range tests {
+		if got := projectAllows(project, tt.user, tt.resource, tt.verb, nil, roles); got != tt.want {
+			t.Errorf("projectAllows(%s, %s, %s) = %v, want %v", tt.user, tt.resource, tt.verb, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+/*
+利用者が作成できるリソースの上限 (クォータ) を保存する
+
+	/marmot/quota/<クォータID>  => api.Quota
+
+使用量は利用者のクォータではリソースの metadata.owner から、プロジェクトのクォータでは metadata.project から集計する。
+上限の確認は API サーバーが作成・更新の前に行う
+*/
+
+const QuotaPrefix = "/marmot/quota"
+
+var (
+	ErrInvalidQuota  = errors.New("invalid quota")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// NormalizeQuota はクォータの名前と対象の利用者を整え、上限の値を検証する
+func NormalizeQuota(spec api.Quota) (api.Quota, error) {
+	quota, err := util.DeepCopy(spec)
+	if err != nil {
+		return api.Quota{}, err
+	}
+	quota.Name = strings.TrimSpace(quota.Name)
+	if quota.Name == "" {
+		return api.Quota{}, fmt.Errorf("%w: name is required", ErrInvalidQuota)
+	}
+
+	if quota.Users != nil {
+		users := uniqueTrimmed(*quota.Users)
+		quota.Users = &users
+	}
+	if quota.Projects != nil {
+		projects := uniqueTrimmed(*quota.Projects)
+		quota.Projects = &projects
+	}
+
+	limits := map[string]*int{
+		"vcpus":     quota.Limits.Vcpus,
+		"memoryMB":  quota.Limits.MemoryMB,
+		"volumeGB":  quota.Limits.VolumeGB,
+		"servers":   quota.Limits.Servers,
+		"networks":  quota.Limits.Networks,
+		"publicIps": quota.Limits.PublicIps,
+	}
+	for name, limit := range limits {
+		if limit != nil && *limit < 0 {
+			return api.Quota{}, fmt.Errorf("%w: limits.%s must not be negative", ErrInvalidQuota, name)
+		}
+	}
+	return quota, nil
+}
+
+// uniqueTrimmed は空白を除き、空と重複を取り除いた一覧を返す
+func uniqueTrimmed(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" && !slices.Contains(result, v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// quotaNameExists は同じ名前の別のクォータがあるかを返す
+func (d *Database) quotaNameExists(name, exceptID string) (bool, error) {
+	quotas, err := d.GetQuotas()
+	if err != nil {
+		return false, err
+	}
+	for _, q := range quotas {
+		if q.Name == name && util.OrDefault(q.Id, "") != exceptID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// クォータを登録する
+func (d *Database) CreateQuota(spec api.Quota) (api.Quota, error) {
+	quota, err := NormalizeQuota(spec)
+	if err != nil {
+		return api.Quota{}, err
+	}
+
+	mutex, err := d.LockKey("/lock/quota")
+	if err != nil {
+		return api.Quota{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	exists, err := d.quotaNameExists(quota.Name, "")
+	if err != nil {
+		return api.Quota{}, err
+	}
+	if exists {
+		return api.Quota{}, fmt.Errorf("%w: quota %q already exists", ErrFound, quota.Name)
+	}
+
+	var key string
+	for {
+		id := uuid.New().String()[:5]
+		key = QuotaPrefix + "/" + id
+		if _, err := d.getRaw(key); err == ErrNotFound {
+			quota.Id = util.StringPtr(id)
+			break
+		} else if err != nil {
+			return api.Quota{}, err
+		}
+	}
+	if err := d.PutJSON(key, quota); err != nil {
+		slog.Error("failed to write quota", "err", err, "key", key)
+		return api.Quota{}, err
+	}
+	return quota, nil
+}
+
+// クォータの一覧を名前の順に取得する
+func (d *Database) GetQuotas() ([]api.Quota, error) {
+	resp, err := d.GetByPrefix(QuotaPrefix + "/")
+	if err == ErrNotFound {
+		return []api.Quota{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	quotas := make([]api.Quota, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var quota api.Quota
+		if err := json.Unmarshal(kv.Value, &quota); err != nil {
+			slog.Warn("skipped malformed quota", "err", err, "key", string(kv.Key))
+			continue
+		}
+		quotas = append(quotas, quota)
+	}
+	sort.SliceStable(quotas, func(i, j int) bool {
+		return quotas[i].Name < quotas[j].Name
+	})
+	return quotas, nil
+}
+
+// GetQuotasForUser は利用者に適用されるクォータを返す
+func (d *Database) GetQuotasForUser(userID string) ([]api.Quota, error) {
+	quotas, err := d.GetQuotas()
+	if err != nil {
+		return nil, err
+	}
+	applied := make([]api.Quota, 0)
+	for _, q := range quotas {
+		if q.Users != nil && slices.Contains(*q.Users, userID) {
+			applied = append(applied, q)
+		}
+	}
+	return applied, nil
+}
+
+// GetQuotasForProject はプロジェクトに適用されるクォータを返す
+func (d *Database) GetQuotasForProject(project string) ([]api.Quota, error) {
+	quotas, err := d.GetQuotas()
+	if err != nil {
+		return nil, err
+	}
+	applied := make([]api.Quota, 0)
+	for _, q := range quotas {
+		if q.Projects != nil && slices.Contains(*q.Projects, project) {
+			applied = append(applied, q)
+		}
+	}
+	return applied, nil
+}
+
+// IDでクォータを取得する
+func (d *Database) GetQuotaById(id string) (api.Quota, error) {
+	var quota api.Quota
+	if _, err := d.GetJSON(QuotaPrefix+"/"+id, &quota); err != nil {
+		return api.Quota{}, err
+	}
+	return quota, nil
+}
+
+// クォータを置き換える
+func (d *Database) UpdateQuotaById(id string, spec api.Quota) (api.Quota, error) {
+	quota, err := NormalizeQuota(spec)
+	if err != nil {
+		return api.Quota{}, err
+	}
+
+	mutex, err := d.LockKey("/lock/quota")
+	if err != nil {
+		return api.Quota{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	if _, err := d.GetQuotaById(id); err != nil {
+		return api.Quota{}, err
+	}
+	exists, err := d.quotaNameExists(quota.Name, id)
+	if err != nil {
+		return api.Quota{}, err
+	}
+	if exists {
+		return api.Quota{}, fmt.Errorf("%w: quota %q already exists", ErrFound, quota.Name)
+	}
+	quota.Id = util.StringPtr(id)
+	if err := d.PutJSON(QuotaPrefix+"/"+id, quota); err != nil {
+		return api.Quota{}, err
+	}
+	return quota, nil
+}
+
+// IDでクォータを削除する
+func (d *Database) DeleteQuotaById(id string) error {
+	mutex, err := d.LockKey("/lock/quota")
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	if _, err := d.GetQuotaById(id); err != nil {
+		return err
+	}
+	return d.DeleteJSON(QuotaPrefix + "/" + id)
+}
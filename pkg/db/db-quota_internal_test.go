@@ -0,0 +1,51 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestNormalizeQuota(t *testing.T) {
+	users := []string{" alice ", "bob", "", "alice"}
+	projects := []string{"team-a", " team-a "}
+	quota, err := NormalizeQuota(api.Quota{
+		Name:     " team-a ",
+		Users:    &users,
+		Projects: &projects,
+		Limits:   api.QuotaResources{Vcpus: util.IntPtrInt(8), Servers: util.IntPtrInt(0)},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeQuota() error = %v", err)
+	}
+	if quota.Name != "team-a" {
+		t.Fatalf("name = %q, want team-a", quota.Name)
+	}
+	if quota.Users == nil || strings.Join(*quota.Users, ",") != "alice,bob" {
+		t.Fatalf("users = %v, want trimmed and deduplicated alice,bob", quota.Users)
+	}
+	if quota.Projects == nil || strings.Join(*quota.Projects, ",") != "team-a" {
+		t.Fatalf("projects = %v, want deduplicated team-a", quota.Projects)
+	}
+	if users[0] != " alice " {
+		t.Fatalf("NormalizeQuota() modified the request: %v", users)
+	}
+
+	invalid := []struct {
+		name  string
+		quota api.Quota
+		want  string
+	}{
+		{"empty name", api.Quota{Name: " "}, "name is required"},
+		{"negative limit", api.Quota{Name: "q", Limits: api.QuotaResources{MemoryMB: util.IntPtrInt(-1)}}, "limits.memoryMB must not be negative"},
+	}
+	for _, tt := range invalid {
+		_, err := NormalizeQuota(tt.quota)
+		if !errors.Is(err, ErrInvalidQuota) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: NormalizeQuota() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+var (
+	// 更新要求のリソースバージョンが現在のものと異なる、他の更新が先に行われている
+	ErrResourceVersionConflict = errors.New("the resource has been modified by another request, get the latest resourceVersion and try again")
+	// リソースバージョンの形式が不正
+	ErrInvalidResourceVersion = errors.New("invalid resourceVersion")
+)
+
+// etcd の ModRevision をリソースバージョンとしてメタデータに設定する
+func setResourceVersion(meta *api.Metadata, rev int64) {
+	meta.ResourceVersion = util.StringPtr(strconv.FormatInt(rev, 10))
+}
+
+// 更新要求のメタデータからリソースバージョンを取り出す
+// リソースバージョンは etcd に保存しないため、メタデータからは取り除く
+func TakeResourceVersion(meta *api.Metadata) string {
+	if meta == nil || meta.ResourceVersion == nil {
+		return ""
+	}
+	rv := strings.TrimSpace(*meta.ResourceVersion)
+	meta.ResourceVersion = nil
+	return rv
+}
+
+// 更新要求のリソースバージョンと現在の ModRevision を比較する
+// リソースバージョンが空の場合は比較しない（後勝ちで更新する）
+func checkResourceVersion(resourceVersion string, current int64) error {
+	if resourceVersion == "" {
+		return nil
+	}
+	rev, err := strconv.ParseInt(resourceVersion, 10, 64)
+	if err != nil || rev <= 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidResourceVersion, resourceVersion)
+	}
+	if rev != current {
+		return ErrResourceVersionConflict
+	}
+	return nil
+}
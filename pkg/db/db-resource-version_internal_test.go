@@ -0,0 +1,58 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestCheckResourceVersion(t *testing.T) {
+	cases := []struct {
+		rv      string
+		current int64
+		want    error
+	}{
+		{"", 10, nil},
+		{"10", 10, nil},
+		{"9", 10, ErrResourceVersionConflict},
+		{"11", 10, ErrResourceVersionConflict},
+		{"abc", 10, ErrInvalidResourceVersion},
+		{"0", 10, ErrInvalidResourceVersion},
+		{"-1", 10, ErrInvalidResourceVersion},
+	}
+	for _, c := range cases {
+		err := checkResourceVersion(c.rv, c.current)
+		if c.want == nil {
+			if err != nil {
+				t.Fatalf("checkResourceVersion(%q, %d) unexpected err: %v", c.rv, c.current, err)
+			}
+			continue
+		}
+		if !errors.Is(err, c.want) {
+			t.Fatalf("checkResourceVersion(%q, %d) = %v, want %v", c.rv, c.current, err, c.want)
+		}
+	}
+}
+
+func TestTakeResourceVersion(t *testing.T) {
+	meta := api.Metadata{ResourceVersion: util.StringPtr(" 42 ")}
+	if got := TakeResourceVersion(&meta); got != "42" {
+		t.Fatalf("TakeResourceVersion() = %q, want 42", got)
+	}
+	if meta.ResourceVersion != nil {
+		t.Fatalf("resourceVersion must be removed from metadata: %v", *meta.ResourceVersion)
+	}
+	if got := TakeResourceVersion(&meta); got != "" {
+		t.Fatalf("TakeResourceVersion() = %q, want empty", got)
+	}
+	if got := TakeResourceVersion(nil); got != "" {
+		t.Fatalf("TakeResourceVersion(nil) = %q, want empty", got)
+	}
+
+	setResourceVersion(&meta, 7)
+	if meta.ResourceVersion == nil || *meta.ResourceVersion != "7" {
+		t.Fatalf("setResourceVersion() = %v, want 7", meta.ResourceVersion)
+	}
+}
@@ -0,0 +1,412 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+/*
+利用者が定義するカスタムロールを保存する
+
+	/marmot/role/<ロール名>  => api.Role
+
+組み込みロールは保存せず builtinRoles() から返す。組み込みロールと同じ名前のカスタムロールは作成できない。
+権限の labelSelector を指定した場合、その権限はラベルがすべて一致するリソースだけに効く
+*/
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	ErrBuiltinRole = errors.New("built-in roles cannot be changed")
+	ErrRoleInUse   = errors.New("role is in use")
+)
+
+// 権限に指定できる操作
+var roleVerbs = []string{"create", "read", "update", "delete"}
+
+// ロール名は英数字で始まり英数字で終わる、英数字と '-' '_' '.' の並びに限る
+var roleNamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._-]*[A-Za-z0-9])?$`)
+
+// roleResourceTypes は権限に指定できるリソースの種類を返す。Administrator ロールが持つ種類と同じ
+func roleResourceTypes() []string {
+	admin := builtinRoles()[BootstrapAdminRoleName]
+	types := make([]string, 0, len(admin.Spec.Permissions))
+	for _, perm := range admin.Spec.Permissions {
+		types = append(types, perm.Resource)
+	}
+	return types
+}
+
+// isBuiltinRoleName は組み込みロールの名前かを返す。大文字と小文字は区別しない
+func isBuiltinRoleName(name string) bool {
+	for _, builtin := range builtinRoleNames {
+		if strings.EqualFold(builtin, strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeRole はカスタムロールの名前と権限を整えて検証する
+func NormalizeRole(spec api.Role) (api.Role, error) {
+	role, err := util.DeepCopy(spec)
+	if err != nil {
+		return api.Role{}, err
+	}
+	name := strings.TrimSpace(role.Metadata.Name)
+	if name == "" {
+		name = strings.TrimSpace(role.Metadata.Id)
+	}
+	if len(name) > 63 || !roleNamePattern.MatchString(name) {
+		return api.Role{}, fmt.Errorf("%w: name %q must be letters, digits, '-', '_' and '.'", ErrInvalidRole, name)
+	}
+	role.ApiVersion = "v1"
+	role.Kind = "Role"
+	key := roleKey(name)
+	role.Metadata = api.Metadata{
+		Id:      name,
+		Name:    name,
+		Key:     &key,
+		Comment: role.Metadata.Comment,
+		Labels:  role.Metadata.Labels,
+		Owner:   role.Metadata.Owner,
+	}
+	role.Status = nil
+
+	if len(role.Spec.Permissions) == 0 {
+		return api.Role{}, fmt.Errorf("%w: spec.permissions is required", ErrInvalidRole)
+	}
+	resourceTypes := roleResourceTypes()
+	for i := range role.Spec.Permissions {
+		perm := &role.Spec.Permissions[i]
+		field := fmt.Sprintf("spec.permissions[%d]", i)
+
+		index := slices.IndexFunc(resourceTypes, func(t string) bool {
+			return strings.EqualFold(t, strings.TrimSpace(perm.Resource))
+		})
+		if index < 0 {
+			return api.Role{}, fmt.Errorf("%w: %s.resource %q must be one of %s", ErrInvalidRole, field, perm.Resource, strings.Join(resourceTypes, ", "))
+		}
+		perm.Resource = resourceTypes[index]
+
+		verbs := make([]string, 0, len(perm.Verbs))
+		for _, verb := range perm.Verbs {
+			verb = strings.ToLower(strings.TrimSpace(verb))
+			if !slices.Contains(roleVerbs, verb) {
+				return api.Role{}, fmt.Errorf("%w: %s.verbs %q must be one of %s", ErrInvalidRole, field, verb, strings.Join(roleVerbs, ", "))
+			}
+			if !slices.Contains(verbs, verb) {
+				verbs = append(verbs, verb)
+			}
+		}
+		if len(verbs) == 0 {
+			return api.Role{}, fmt.Errorf("%w: %s.verbs is required", ErrInvalidRole, field)
+		}
+		perm.Verbs = verbs
+
+		if perm.LabelSelector != nil {
+			if len(perm.LabelSelector.MatchLabels) == 0 {
+				return api.Role{}, fmt.Errorf("%w: %s.labelSelector.matchLabels must not be empty", ErrInvalidRole, field)
+			}
+			matchLabels := make(map[string]string, len(perm.LabelSelector.MatchLabels))
+			for k, v := range perm.LabelSelector.MatchLabels {
+				if strings.TrimSpace(k) == "" {
+					return api.Role{}, fmt.Errorf("%w: %s.labelSelector.matchLabels has an empty key", ErrInvalidRole, field)
+				}
+				matchLabels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+			perm.LabelSelector.MatchLabels = matchLabels
+		}
+	}
+	return role, nil
+}
+
+// カスタムロールを登録する
+func (d *Database) CreateRole(spec api.Role) (api.Role, error) {
+	role, err := NormalizeRole(spec)
+	if err != nil {
+		return api.Role{}, err
+	}
+	if isBuiltinRoleName(role.Metadata.Name) {
+		return api.Role{}, fmt.Errorf("%w: role %q is a built-in role", ErrFound, role.Metadata.Name)
+	}
+
+	key := roleKey(role.Metadata.Name)
+	mutex, err := d.LockKey(key)
+	if err != nil {
+		return api.Role{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	if _, err := d.getRaw(key); err == nil {
+		return api.Role{}, fmt.Errorf("%w: role %q already exists", ErrFound, role.Metadata.Name)
+	} else if err != ErrNotFound {
+		return api.Role{}, err
+	}
+	if err := d.PutJSON(key, role); err != nil {
+		slog.Error("failed to write role", "err", err, "key", key)
+		return api.Role{}, err
+	}
+	role.Status = &api.RoleStatus{Builtin: false}
+	return role, nil
+}
+
+// カスタムロールの一覧を名前の順に取得する
+func (d *Database) GetCustomRoles() ([]api.Role, error) {
+	resp, err := d.GetByPrefix(AuthRolePrefix + "/")
+	if err == ErrNotFound {
+		return []api.Role{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	roles := make([]api.Role, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var role api.Role
+		if err := json.Unmarshal(kv.Value, &role); err != nil {
+			slog.Warn("skipped malformed role", "err", err, "key", string(kv.Key))
+			continue
+		}
+		normalizeRoleIdentity(&role, strings.TrimPrefix(string(kv.Key), AuthRolePrefix+"/"))
+		setResourceVersion(&role.Metadata, kv.ModRevision)
+		role.Status = &api.RoleStatus{Builtin: false}
+		roles = append(roles, role)
+	}
+	sort.SliceStable(roles, func(i, j int) bool {
+		return roles[i].Metadata.Name < roles[j].Metadata.Name
+	})
+	return roles, nil
+}
+
+// カスタムロールの権限と説明を置き換える。metadata.resourceVersion を指定した場合は一致するときだけ更新する
+func (d *Database) UpdateRoleByName(name string, spec api.Role) (api.Role, error) {
+	if isBuiltinRoleName(name) {
+		return api.Role{}, fmt.Errorf("%w: %s", ErrBuiltinRole, strings.TrimSpace(name))
+	}
+	spec.Metadata.Name = name
+	spec.Metadata.Id = name
+	role, err := NormalizeRole(spec)
+	if err != nil {
+		return api.Role{}, err
+	}
+
+	key := roleKey(name)
+	mutex, err := d.LockKey(key)
+	if err != nil {
+		return api.Role{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	var current api.Role
+	resp, err := d.GetJSON(key, &current)
+	if err != nil {
+		return api.Role{}, err
+	}
+	if err := checkResourceVersion(util.OrDefault(spec.Metadata.ResourceVersion, ""), resp.Kvs[0].ModRevision); err != nil {
+		return api.Role{}, err
+	}
+	role.Metadata.Owner = current.Metadata.Owner
+	if err := d.PutJSONCAS(key, resp.Kvs[0].ModRevision, role); err != nil {
+		return api.Role{}, err
+	}
+	role.Status = &api.RoleStatus{Builtin: false}
+	return role, nil
+}
+
+// カスタムロールを削除する。利用者やプロジェクトのメンバーに割り当てたロールは削除できない
+func (d *Database) DeleteRoleByName(name string) error {
+	name = strings.TrimSpace(name)
+	if isBuiltinRoleName(name) {
+		return fmt.Errorf("%w: %s", ErrBuiltinRole, name)
+	}
+	key := roleKey(name)
+	mutex, err := d.LockKey(key)
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	if _, err := d.getRaw(key); err != nil {
+		return err
+	}
+	users, err := d.ListUsers()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if slices.Contains(util.OrDefault(user.Spec.Roles, nil), name) {
+			return fmt.Errorf("%w: role %q is assigned to user %q", ErrRoleInUse, name, user.Metadata.Id)
+		}
+	}
+	projects, err := d.GetProjects()
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		for _, m := range util.OrDefault(p.Spec.Members, nil) {
+			if slices.Contains(m.Roles, name) {
+				return fmt.Errorf("%w: role %q is bound to %q in project %q", ErrRoleInUse, name, m.UserId, p.Metadata.Name)
+			}
+		}
+	}
+	return d.DeleteJSON(key)
+}
+
+// roleCatalog は組み込みロールとカスタムロールを名前で引けるように返す
+func (d *Database) roleCatalog() (map[string]api.Role, error) {
+	roles := builtinRoles()
+	custom, err := d.GetCustomRoles()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range custom {
+		if _, ok := roles[role.Metadata.Name]; !ok {
+			roles[role.Metadata.Name] = role
+		}
+	}
+	return roles, nil
+}
+
+// labelsMatch はリソースのラベルが権限のラベルの条件を満たすかを返す。条件のない権限はすべてのリソースに効く
+func labelsMatch(selector *api.RoleLabelSelector, labels *map[string]interface{}) bool {
+	if selector == nil {
+		return true
+	}
+	if labels == nil {
+		return false
+	}
+	for key, expected := range selector.MatchLabels {
+		value, ok := (*labels)[key]
+		if !ok || strings.TrimSpace(fmt.Sprint(value)) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+// permissionMatches はリソースの種類と操作が権限に含まれるかを返す。ラベルの条件は評価しない
+func permissionMatches(perm api.Permission, resource, verb string) bool {
+	if !strings.EqualFold(strings.TrimSpace(perm.Resource), strings.TrimSpace(resource)) {
+		return false
+	}
+	for _, candidate := range perm.Verbs {
+		if strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(verb)) {
+			return true
+		}
+	}
+	return false
+}
+
+// roleAllowsResource はロールでラベルを持つリソースへの操作が許可されるかを返す
+func roleAllowsResource(role api.Role, resource, verb string, labels *map[string]interface{}) bool {
+	for _, perm := range role.Spec.Permissions {
+		if permissionMatches(perm, resource, verb) && labelsMatch(perm.LabelSelector, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessGrant は利用者が操作できるリソースの範囲
+type AccessGrant struct {
+	Project     string            // 空の場合はすべてのプロジェクト
+	MatchLabels map[string]string // 空の場合はすべてのラベル
+}
+
+// Allows はリソースが範囲に含まれるかを返す
+func (g AccessGrant) Allows(meta api.Metadata) bool {
+	if g.Project != "" && ProjectOf(meta) != g.Project {
+		return false
+	}
+	if len(g.MatchLabels) == 0 {
+		return true
+	}
+	return labelsMatch(&api.RoleLabelSelector{MatchLabels: g.MatchLabels}, meta.Labels)
+}
+
+// roleGrants はロールで操作が許可されるリソースの範囲を返す
+func roleGrants(role api.Role, resource, verb, project string) []AccessGrant {
+	grants := make([]AccessGrant, 0)
+	for _, perm := range role.Spec.Permissions {
+		if !permissionMatches(perm, resource, verb) {
+			continue
+		}
+		grant := AccessGrant{Project: project}
+		if perm.LabelSelector != nil {
+			grant.MatchLabels = perm.LabelSelector.MatchLabels
+		}
+		grants = append(grants, grant)
+	}
+	return grants
+}
+
+// AuthorizeResource は利用者がリソースに操作できるかを返す。
+// 利用者に割り当てたロールに加えて、リソースのプロジェクトのメンバーとしてのロールと、権限のラベルの条件を評価する
+func (d *Database) AuthorizeResource(userID, resource, verb string, meta api.Metadata) (bool, error) {
+	user, err := d.GetUserById(userID)
+	if err != nil {
+		return false, err
+	}
+	if !user.Spec.Enabled || isUserLocked(user) {
+		return false, nil
+	}
+	roles, err := d.roleCatalog()
+	if err != nil {
+		return false, err
+	}
+	for _, roleName := range util.OrDefault(user.Spec.Roles, nil) {
+		role, ok := roles[strings.TrimSpace(roleName)]
+		if ok && roleAllowsResource(role, resource, verb, meta.Labels) {
+			return true, nil
+		}
+	}
+	p, err := d.GetProjectByName(ProjectOf(meta))
+	if err == ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return projectAllows(p, user.Metadata.Id, resource, verb, meta.Labels, roles), nil
+}
+
+// AccessGrants は利用者が操作できるリソースの範囲を返す。
+// 利用者に割り当てたロールの範囲はすべてのプロジェクトに、メンバーとしてのロールの範囲はそのプロジェクトに限る
+func (d *Database) AccessGrants(userID, resource, verb string) ([]AccessGrant, error) {
+	user, err := d.GetUserById(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Spec.Enabled || isUserLocked(user) {
+		return []AccessGrant{}, nil
+	}
+	roles, err := d.roleCatalog()
+	if err != nil {
+		return nil, err
+	}
+	grants := make([]AccessGrant, 0)
+	for _, roleName := range util.OrDefault(user.Spec.Roles, nil) {
+		if role, ok := roles[strings.TrimSpace(roleName)]; ok {
+			grants = append(grants, roleGrants(role, resource, verb, "")...)
+		}
+	}
+	projects, err := d.GetProjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects {
+		for _, roleName := range projectMemberRoles(p, user.Metadata.Id) {
+			if role, ok := roles[strings.TrimSpace(roleName)]; ok {
+				grants = append(grants, roleGrants(role, resource, verb, p.Metadata.Name)...)
+			}
+		}
+	}
+	return grants, nil
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestNormalizeRole(t *testing.T) {
+	role, err := NormalizeRole(api.Role{
+		Metadata: api.Metadata{Name: " dev-operator ", ResourceVersion: util.StringPtr("3")},
+		Spec: api.RoleSpec{Permissions: []api.Permission{
+			{Resource: "server", Verbs: []string{"Read", "update", "read"}, LabelSelector: &api.RoleLabelSelector{MatchLabels: map[string]string{" env ": " dev "}}},
+		}},
+		Status: &api.RoleStatus{Builtin: true},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeRole() error = %v", err)
+	}
+	if role.Metadata.Id != "dev-operator" || role.Kind != "Role" || role.Metadata.ResourceVersion != nil || role.Status != nil {
+		t.Fatalf("role = %+v, want dev-operator without resourceVersion and status", role)
+	}
+	perm := role.Spec.Permissions[0]
+	if perm.Resource != "Server" || strings.Join(perm.Verbs, ",") != "read,update" || perm.LabelSelector.MatchLabels["env"] != "dev" {
+		t.Fatalf("permission = %+v, want Server read,update env=dev", perm)
+	}
+
+	invalid := []struct {
+		name string
+		role api.Role
+		want string
+	}{
+		{"empty name", api.Role{Spec: api.RoleSpec{Permissions: []api.Permission{permission("Server", "read")}}}, "name"},
+		{"no permissions", api.Role{Metadata: api.Metadata{Name: "r"}}, "spec.permissions is required"},
+		{"unknown resource", api.Role{Metadata: api.Metadata{Name: "r"}, Spec: api.RoleSpec{Permissions: []api.Permission{permission("Disk", "read")}}}, "resource \"Disk\""},
+		{"unknown verb", api.Role{Metadata: api.Metadata{Name: "r"}, Spec: api.RoleSpec{Permissions: []api.Permission{permission("Server", "reboot")}}}, "verbs \"reboot\""},
+		{"no verbs", api.Role{Metadata: api.Metadata{Name: "r"}, Spec: api.RoleSpec{Permissions: []api.Permission{permission("Server")}}}, "verbs is required"},
+		{"empty selector", api.Role{Metadata: api.Metadata{Name: "r"}, Spec: api.RoleSpec{Permissions: []api.Permission{
+			{Resource: "Server", Verbs: []string{"read"}, LabelSelector: &api.RoleLabelSelector{}},
+		}}}, "matchLabels must not be empty"},
+	}
+	for _, tt := range invalid {
+		_, err := NormalizeRole(tt.role)
+		if !errors.Is(err, ErrInvalidRole) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: NormalizeRole() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestRoleLabelSelector(t *testing.T) {
+	role := roleTemplate("dev-operator", "", []api.Permission{
+		permission("Server", "read"),
+		{Resource: "Server", Verbs: []string{"delete"}, LabelSelector: &api.RoleLabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+	})
+	dev := &map[string]interface{}{"env": "dev", "team": "a"}
+	prod := &map[string]interface{}{"env": "prod"}
+
+	if !roleAllows(role, "Server", "read") {
+		t.Error("roleAllows(read) = false, want the permission without a selector to apply")
+	}
+	if roleAllows(role, "Server", "delete") {
+		t.Error("roleAllows(delete) = true, want the permission with a selector to need the resource labels")
+	}
+	if !roleAllowsResource(role, "Server", "delete", dev) {
+		t.Error("roleAllowsResource(delete, env=dev) = false, want true")
+	}
+	if roleAllowsResource(role, "Server", "delete", prod) || roleAllowsResource(role, "Server", "delete", nil) {
+		t.Error("roleAllowsResource(delete) = true for a resource without env=dev")
+	}
+
+	grants := roleGrants(role, "Server", "delete", "team-a")
+	if len(grants) != 1 || grants[0].Project != "team-a" || grants[0].MatchLabels["env"] != "dev" {
+		t.Fatalf("roleGrants() = %+v, want team-a env=dev", grants)
+	}
+	if !grants[0].Allows(api.Metadata{Project: util.StringPtr("team-a"), Labels: dev}) {
+		t.Error("Allows(team-a env=dev) = false, want true")
+	}
+	if grants[0].Allows(api.Metadata{Labels: dev}) || grants[0].Allows(api.Metadata{Project: util.StringPtr("team-a"), Labels: prod}) {
+		t.Error("Allows() = true outside the project or the labels")
+	}
+}
+
+func TestIsBuiltinRoleName(t *testing.T) {
+	if !isBuiltinRoleName(" viewer ") || isBuiltinRoleName("dev-operator") {
+		t.Fatal("isBuiltinRoleName() did not match the built-in roles case-insensitively")
+	}
+}
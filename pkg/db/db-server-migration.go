@@ -0,0 +1,327 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+	etcd "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	MIGRATION_PENDING     = 0 // 受付済み、移行元ノードがディスク情報を収集する
+	MIGRATION_PREPARING   = 1 // 移行先ノードがディスクや cloud-init ISO を準備する
+	MIGRATION_PREPARED    = 2 // 準備完了、移行元ノードがマイグレーションを開始する
+	MIGRATION_MIGRATING   = 3 // マイグレーション実行中
+	MIGRATION_COMPLETED   = 4 // 完了
+	MIGRATION_ROLLINGBACK = 5 // 失敗、移行先ノードが準備したディスクを片付ける
+	MIGRATION_FAILED      = 6 // 失敗
+)
+
+var MigrationStatus = map[int]string{
+	0: "PENDING",
+	1: "PREPARING",
+	2: "PREPARED",
+	3: "MIGRATING",
+	4: "COMPLETED",
+	5: "ROLLINGBACK",
+	6: "FAILED",
+}
+
+// ErrMigrationInProgress はサーバーのマイグレーションが既に進行中であることを示す
+var ErrMigrationInProgress = errors.New("migration already in progress")
+
+// IsMigrationFinished はマイグレーションが終了状態かを返す
+func IsMigrationFinished(m api.ServerMigration) bool {
+	return m.Status == nil || m.Status.StatusCode == MIGRATION_COMPLETED || m.Status.StatusCode == MIGRATION_FAILED
+}
+
+// CreateServerMigration はサーバーのマイグレーションを PENDING で登録する
+// マイグレーションはサーバー毎に1件で、終了済みの記録は上書きする
+func (d *Database) CreateServerMigration(spec api.ServerMigration) (api.ServerMigration, error) {
+	if spec.Spec.ServerId == nil || strings.TrimSpace(*spec.Spec.ServerId) == "" {
+		return api.ServerMigration{}, errors.New("server id is not set")
+	}
+	id := strings.TrimSpace(*spec.Spec.ServerId)
+
+	mutex, err := d.LockKey("/lock/migration/" + id)
+	if err != nil {
+		return api.ServerMigration{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	key := MigrationPrefix + "/" + id
+	var existing api.ServerMigration
+	if _, err := d.GetJSON(key, &existing); err == nil {
+		if !IsMigrationFinished(existing) {
+			return api.ServerMigration{}, ErrMigrationInProgress
+		}
+	} else if err != ErrNotFound {
+		return api.ServerMigration{}, err
+	}
+
+	migration, err := util.DeepCopy(spec)
+	if err != nil {
+		return api.ServerMigration{}, err
+	}
+	migration.Metadata.Id = id
+	migration.Metadata.Key = util.StringPtr(key)
+
+	now := time.Now()
+	migration.Status = &api.Status{
+		StatusCode:          MIGRATION_PENDING,
+		Status:              util.StringPtr(MigrationStatus[MIGRATION_PENDING]),
+		CreationTimeStamp:   util.TimePtr(now),
+		LastUpdateTimeStamp: util.TimePtr(now),
+	}
+
+	if err := d.PutJSON(key, migration); err != nil {
+		slog.Error("CreateServerMigration() PutJSON failed", "err", err, "key", key)
+		return api.ServerMigration{}, err
+	}
+	return migration, nil
+}
+
+// GetServerMigrations はすべてのマイグレーションを返す
+func (d *Database) GetServerMigrations() ([]api.ServerMigration, error) {
+	var migrations []api.ServerMigration
+	resp, err := d.GetByPrefix(MigrationPrefix + "/")
+	if err == ErrNotFound {
+		return migrations, nil
+	}
+	if err != nil {
+		return migrations, err
+	}
+
+	for _, kv := range resp.Kvs {
+		var migration api.ServerMigration
+		if err := json.Unmarshal(kv.Value, &migration); err != nil {
+			slog.Error("GetServerMigrations() unmarshal failed", "err", err, "key", string(kv.Key))
+			continue
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// GetServerMigrationByServerId はサーバーの最新のマイグレーションを返す
+func (d *Database) GetServerMigrationByServerId(serverId string) (api.ServerMigration, error) {
+	key := MigrationPrefix + "/" + serverId
+	var migration api.ServerMigration
+	if _, err := d.GetJSON(key, &migration); err != nil {
+		return api.ServerMigration{}, err
+	}
+	return migration, nil
+}
+
+// UpdateServerMigration はマイグレーションを楽観ロックで更新する
+func (d *Database) UpdateServerMigration(serverId string, spec api.ServerMigration) error {
+	for {
+		err := d.updateServerMigration(serverId, spec)
+		if err == ErrUpdateConflict {
+			slog.Warn("UpdateServerMigration() retrying due to update conflict", "serverId", serverId)
+			continue
+		}
+		return err
+	}
+}
+
+func (d *Database) updateServerMigration(serverId string, spec api.ServerMigration) error {
+	mutex, err := d.LockKey("/lock/migration/" + serverId)
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	key := MigrationPrefix + "/" + serverId
+	var rec api.ServerMigration
+	resp, err := d.GetJSON(key, &rec)
+	if err != nil {
+		return err
+	}
+
+	expected := resp.Kvs[0].ModRevision
+	util.PatchStruct(&rec, spec)
+	rec.Metadata.Id = serverId
+
+	return d.PutJSONCAS(key, expected, &rec)
+}
+
+// UpdateServerMigrationStatusWithMessage はマイグレーションのステータスとメッセージを更新する
+func (d *Database) UpdateServerMigrationStatusWithMessage(serverId string, status int, message string) error {
+	migration, err := d.GetServerMigrationByServerId(serverId)
+	if err != nil {
+		return err
+	}
+	if migration.Status == nil {
+		migration.Status = &api.Status{}
+	}
+	migration.Status.StatusCode = status
+	migration.Status.Status = util.StringPtr(MigrationStatus[status])
+	migration.Status.LastUpdateTimeStamp = util.TimePtr(time.Now())
+	// PatchStruct は nil を無視するため、空文字で古いメッセージを消す
+	migration.Status.Message = util.StringPtr(strings.TrimSpace(message))
+
+	return d.UpdateServerMigration(serverId, migration)
+}
+
+// CommitServerMigration はマイグレーション完了をひとつのトランザクションで記録する
+// サーバーの nodeName と RUNNING 状態、コピーしたボリュームの nodeName、
+// IPAM と内部DNSのエントリー、マイグレーションの COMPLETED をまとめて書き込む
+func (d *Database) CommitServerMigration(serverId string) error {
+	for {
+		err := d.commitServerMigration(serverId)
+		if err == ErrUpdateConflict {
+			slog.Warn("CommitServerMigration() retrying due to update conflict", "serverId", serverId)
+			continue
+		}
+		return err
+	}
+}
+
+func (d *Database) commitServerMigration(serverId string) error {
+	mutex, err := d.LockKey("/lock/server/" + serverId)
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	var cmps []etcd.Cmp
+	var ops []etcd.Op
+	put := func(key string, rev int64, v interface{}) error {
+		byteData, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("json marshal failed: %w", err)
+		}
+		if rev > 0 {
+			cmps = append(cmps, etcd.Compare(etcd.ModRevision(key), "=", rev))
+		}
+		ops = append(ops, etcd.OpPut(key, string(byteData)))
+		return nil
+	}
+
+	migrationKey := MigrationPrefix + "/" + serverId
+	var migration api.ServerMigration
+	resp, err := d.GetJSON(migrationKey, &migration)
+	if err != nil {
+		return err
+	}
+	migrationRev := resp.Kvs[0].ModRevision
+	if migration.Spec.TargetNode == nil || strings.TrimSpace(*migration.Spec.TargetNode) == "" {
+		return fmt.Errorf("migration of server %s has no target node", serverId)
+	}
+	targetNode := strings.TrimSpace(*migration.Spec.TargetNode)
+
+	serverKey := ServerPrefix + "/" + serverId
+	var server api.Server
+	resp, err = d.GetJSON(serverKey, &server)
+	if err != nil {
+		return err
+	}
+	serverRev := resp.Kvs[0].ModRevision
+
+	// コピーしたボリュームは移行先ノードの所有になる
+	copied := map[string]bool{}
+	if migration.Spec.Volumes != nil {
+		for _, v := range *migration.Spec.Volumes {
+			copied[v.VolumeId] = true
+		}
+	}
+	for id := range copied {
+		volumeKey := VolumePrefix + "/" + id
+		var vol api.Volume
+		resp, err := d.GetJSON(volumeKey, &vol)
+		if err != nil {
+			return fmt.Errorf("volume %s: %w", id, err)
+		}
+		vol.Metadata.NodeName = util.StringPtr(targetNode)
+		if err := put(volumeKey, resp.Kvs[0].ModRevision, vol); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	server.Metadata.NodeName = util.StringPtr(targetNode)
+	if server.Spec.BootVolume != nil && copied[api.VolumeID(*server.Spec.BootVolume)] {
+		server.Spec.BootVolume.Metadata.NodeName = util.StringPtr(targetNode)
+	}
+	if server.Spec.Storage != nil {
+		for i := range *server.Spec.Storage {
+			if copied[api.VolumeID((*server.Spec.Storage)[i])] {
+				(*server.Spec.Storage)[i].Metadata.NodeName = util.StringPtr(targetNode)
+			}
+		}
+	}
+	if server.Status == nil {
+		server.Status = &api.Status{}
+	}
+	server.Status.StatusCode = SERVER_RUNNING
+	server.Status.Status = util.StringPtr(ServerStatus[SERVER_RUNNING])
+	server.Status.Message = nil
+	server.Status.LastUpdateTimeStamp = util.TimePtr(now)
+	api.SetServerID(&server, serverId)
+	if err := put(serverKey, serverRev, server); err != nil {
+		return err
+	}
+
+	// IP アドレスはクラスタ全体で共通のため移行後も変わらない。所有者と DNS の登録を同じトランザクションで確定させる
+	if server.Spec.NetworkInterface != nil {
+		for _, nic := range *server.Spec.NetworkInterface {
+			if nic.Address == nil || strings.TrimSpace(*nic.Address) == "" {
+				continue
+			}
+			address := strings.TrimSpace(*nic.Address)
+			if nic.IpNetworkId != nil && strings.TrimSpace(nic.Networkid) != "" {
+				ipnet, err := d.GetIpNetworkById(nic.Networkid, *nic.IpNetworkId)
+				if err != nil {
+					return fmt.Errorf("ip network %s: %w", *nic.IpNetworkId, err)
+				}
+				rec := api.IPAddress{
+					HostId:    util.StringPtr(server.Metadata.Name),
+					IpAddress: address,
+					NetworkId: nic.IpNetworkId,
+				}
+				key := NetworkPrefix + "/" + nic.Networkid + "/ip_network/" + *nic.IpNetworkId + "/ip_address/" + *ipnet.AddressMaskLen + "/" + address
+				if err := put(key, 0, rec); err != nil {
+					return err
+				}
+			}
+			if ip := net.ParseIP(address); ip != nil {
+				rec := serverDnsRecord(server.Metadata.Name, nic.Networkname, ip)
+				if err := put(dnsRecordKey(InternalDNSPrefix+"/"+nic.Networkname+"/"+server.Metadata.Name, *rec.Id), 0, rec); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if migration.Status == nil {
+		migration.Status = &api.Status{}
+	}
+	migration.Status.StatusCode = MIGRATION_COMPLETED
+	migration.Status.Status = util.StringPtr(MigrationStatus[MIGRATION_COMPLETED])
+	migration.Status.Message = util.StringPtr("")
+	migration.Status.LastUpdateTimeStamp = util.TimePtr(now)
+	if err := put(migrationKey, migrationRev, migration); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(d.Ctx, 5*time.Second)
+	defer cancel()
+	txnResp, err := d.Cli.Txn(ctx).If(cmps...).Then(ops...).Commit()
+	if err != nil {
+		return fmt.Errorf("etcd txn failed: %w", err)
+	}
+	if !txnResp.Succeeded {
+		return ErrUpdateConflict
+	}
+	d.observeWrite(txnResp.Header)
+	return nil
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+const (
+	SNAPSHOT_PENDING   = 0
+	SNAPSHOT_CREATING  = 1
+	SNAPSHOT_AVAILABLE = 2
+	SNAPSHOT_REVERTING = 3
+	SNAPSHOT_DELETING  = 4
+	SNAPSHOT_ERROR     = 5
+)
+
+var SnapshotStatus = map[int]string{
+	0: "PENDING",
+	1: "CREATING",
+	2: "AVAILABLE",
+	3: "REVERTING",
+	4: "DELETING",
+	5: "ERROR",
+}
+
+// CreateServerSnapshot stores a server snapshot object in etcd with a generated ID and PENDING status.
+func (d *Database) CreateServerSnapshot(spec api.ServerSnapshot) (api.ServerSnapshot, error) {
+	mutex, err := d.LockKey("/lock/snapshot/create")
+	if err != nil {
+		return api.ServerSnapshot{}, err
+	}
+	defer d.UnlockKey(mutex)
+
+	snapshot, err := util.DeepCopy(spec)
+	if err != nil {
+		return api.ServerSnapshot{}, err
+	}
+
+	var key string
+	for {
+		snapshot.Metadata.Uuid = util.StringPtr(uuid.New().String())
+		id := (*snapshot.Metadata.Uuid)[:5]
+		api.SetServerSnapshotID(&snapshot, id)
+		key = SnapshotPrefix + "/" + id
+
+		var existing api.ServerSnapshot
+		_, getErr := d.GetJSON(key, &existing)
+		if getErr == ErrNotFound {
+			break
+		}
+		if getErr != nil {
+			return api.ServerSnapshot{}, getErr
+		}
+	}
+	snapshot.Metadata.Key = util.StringPtr(key)
+
+	now := time.Now()
+	snapshot.Status = &api.Status{
+		StatusCode:          SNAPSHOT_PENDING,
+		Status:              util.StringPtr(SnapshotStatus[SNAPSHOT_PENDING]),
+		CreationTimeStamp:   util.TimePtr(now),
+		LastUpdateTimeStamp: util.TimePtr(now),
+	}
+
+	if err := d.PutJSON(key, snapshot); err != nil {
+		slog.Error("CreateServerSnapshot() PutJSON failed", "err", err, "key", key)
+		return api.ServerSnapshot{}, err
+	}
+
+	return snapshot, nil
+}
+
+// GetServerSnapshots returns all server snapshots.
+func (d *Database) GetServerSnapshots() ([]api.ServerSnapshot, error) {
+	var snapshots []api.ServerSnapshot
+	resp, err := d.GetByPrefix(SnapshotPrefix)
+	if err == ErrNotFound {
+		return snapshots, nil
+	}
+	if err != nil {
+		return snapshots, err
+	}
+
+	for _, kv := range resp.Kvs {
+		var snapshot api.ServerSnapshot
+		if err := json.Unmarshal(kv.Value, &snapshot); err != nil {
+			slog.Error("GetServerSnapshots() unmarshal failed", "err", err, "key", string(kv.Key))
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// GetServerSnapshotsByServerId returns snapshots that belong to the server.
+func (d *Database) GetServerSnapshotsByServerId(serverId string) ([]api.ServerSnapshot, error) {
+	all, err := d.GetServerSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]api.ServerSnapshot, 0)
+	for _, snapshot := range all {
+		if snapshot.Spec.ServerId != nil && strings.TrimSpace(*snapshot.Spec.ServerId) == strings.TrimSpace(serverId) {
+			result = append(result, snapshot)
+		}
+	}
+	return result, nil
+}
+
+// GetServerSnapshotById returns one server snapshot by ID.
+func (d *Database) GetServerSnapshotById(id string) (api.ServerSnapshot, error) {
+	key := SnapshotPrefix + "/" + id
+	var snapshot api.ServerSnapshot
+	_, err := d.GetJSON(key, &snapshot)
+	if err != nil {
+		return api.ServerSnapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// UpdateServerSnapshotById updates a server snapshot using optimistic locking.
+func (d *Database) UpdateServerSnapshotById(id string, spec api.ServerSnapshot) error {
+	for {
+		err := d.updateServerSnapshot(id, spec)
+		if err == ErrUpdateConflict {
+			slog.Warn("UpdateServerSnapshotById() retrying due to update conflict", "snapshotId", id)
+			continue
+		}
+		return err
+	}
+}
+
+func (d *Database) updateServerSnapshot(id string, spec api.ServerSnapshot) error {
+	lockKey := "/lock/snapshot/" + id
+	mutex, err := d.LockKey(lockKey)
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	key := SnapshotPrefix + "/" + id
+	var rec api.ServerSnapshot
+	resp, err := d.GetJSON(key, &rec)
+	if err != nil {
+		return err
+	}
+
+	expected := resp.Kvs[0].ModRevision
+	util.PatchStruct(&rec, spec)
+	api.SetServerSnapshotID(&rec, id)
+
+	return d.PutJSONCAS(key, expected, &rec)
+}
+
+// DeleteServerSnapshotById deletes a server snapshot record by ID.
+func (d *Database) DeleteServerSnapshotById(id string) error {
+	lockKey := "/lock/snapshot/" + id
+	mutex, err := d.LockKey(lockKey)
+	if err != nil {
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	key := SnapshotPrefix + "/" + id
+	return d.DeleteJSON(key)
+}
+
+// UpdateServerSnapshotStatusWithMessage updates status and optional message for server snapshot objects.
+func (d *Database) UpdateServerSnapshotStatusWithMessage(id string, status int, message string) error {
+	snapshot, err := d.GetServerSnapshotById(id)
+	if err != nil {
+		return err
+	}
+	if snapshot.Status == nil {
+		snapshot.Status = &api.Status{}
+	}
+	snapshot.Status.StatusCode = status
+	snapshot.Status.Status = util.StringPtr(SnapshotStatus[status])
+	snapshot.Status.LastUpdateTimeStamp = util.TimePtr(time.Now())
+	// PatchStruct skips nil pointers, so use empty string to reliably clear stale messages.
+	snapshot.Status.Message = util.StringPtr(strings.TrimSpace(message))
+	if status == SNAPSHOT_DELETING {
+		snapshot.Status.DeletionTimeStamp = util.TimePtr(time.Now())
+	}
+
+	return d.UpdateServerSnapshotById(id, snapshot)
+}
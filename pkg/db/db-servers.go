@@ -21,6 +21,7 @@ const (
 	SERVER_DELETING     = 5 // 削除中
 	SERVER_STOPPING     = 6 // 停止処理中
 	SERVER_STARTING     = 7 // 起動処理中
+	SERVER_MIGRATING    = 8 // ライブマイグレーション中
 )
 
 var ServerStatus = map[int]string{
@@ -32,6 +33,7 @@ var ServerStatus = map[int]string{
 	5: "DELETING",
 	6: "STOPPING",
 	7: "STARTING",
+	8: "MIGRATING",
 }
 
 // サーバーを登録、サーバーを一意に識別するIDを自動生成、サーバーのステータスは、PENDINGで開始
@@ -97,12 +99,13 @@ func (d *Database) DeleteServerById(id string) error {
 func (d *Database) GetServerById(id string) (api.Server, error) {
 	key := ServerPrefix + "/" + id
 	var server api.Server
-	_, err := d.GetJSON(key, &server)
+	resp, err := d.GetJSON(key, &server)
 	if err != nil {
 		slog.Error("GetServerById()", "err", err, "key", key)
 		return api.Server{}, err
 	}
 	api.SetServerID(&server, id)
+	setResourceVersion(&server.Metadata, resp.Kvs[0].ModRevision)
 	return server, nil
 }
 
@@ -133,6 +136,7 @@ func (d *Database) GetServers() (api.Servers, error) {
 		if idx := strings.LastIndex(key, "/"); idx >= 0 && idx+1 < len(key) {
 			api.SetServerID(&server, key[idx+1:])
 		}
+		setResourceVersion(&server.Metadata, kv.ModRevision)
 		servers = append(servers, server)
 	}
 
@@ -142,7 +146,7 @@ func (d *Database) GetServers() (api.Servers, error) {
 // サーバーを更新
 func (d *Database) UpdateServer(id string, spec api.Server) error {
 	for {
-		err := d.updateServer(id, spec)
+		err := d.updateServer(id, spec, "")
 		if err == ErrUpdateConflict {
 			slog.Warn("UpdateServer() retrying due to update conflict", "serverId", id)
 			continue
@@ -164,8 +168,20 @@ func (d *Database) UpdateServer(id string, spec api.Server) error {
 	return nil
 }
 
+// UpdateServerWithResourceVersion は resourceVersion が指定された場合、現在のリソースバージョンと一致する時だけ更新する
+// 一致しない場合は ErrResourceVersionConflict を返す
+func (d *Database) UpdateServerWithResourceVersion(id string, spec api.Server, resourceVersion string) error {
+	for {
+		err := d.updateServer(id, spec, resourceVersion)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
 // サーバーを更新
-func (d *Database) updateServer(id string, spec api.Server) error {
+func (d *Database) updateServer(id string, spec api.Server, resourceVersion string) error {
 	lockKey := "/lock/server/" + id
 	mutex, err := d.LockKey(lockKey)
 	if err != nil {
@@ -182,11 +198,18 @@ func (d *Database) updateServer(id string, spec api.Server) error {
 		return err
 	}
 	expected := resp.Kvs[0].ModRevision
+	if err := checkResourceVersion(resourceVersion, expected); err != nil {
+		return err
+	}
 
 	api.SetServerID(&rec, id)
 	// パッチ適用
+	owner, project := rec.Metadata.Owner, rec.Metadata.Project
 	util.PatchStruct(&rec, spec)
+	// 所有者とプロジェクトはクォータの集計と認可に使うため作成後は変更しない
+	rec.Metadata.Owner, rec.Metadata.Project = owner, project
 	api.SetServerID(&rec, id)
+	rec.Metadata.ResourceVersion = nil
 
 	err = d.PutJSONCAS(key, expected, &rec)
 	if err != nil {
@@ -251,6 +274,80 @@ func (d *Database) updateServerStatus(id string, status int, message string) err
 	return nil
 }
 
+// UpdateServerGuestInfo はゲストエージェントから取得した情報をサーバーのステータスに記録する
+// ステータスコードとメッセージは変更しない
+func (d *Database) UpdateServerGuestInfo(id string, info *api.ServerGuestInfo) error {
+	for {
+		err := d.updateServerGuestInfo(id, info)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
+func (d *Database) updateServerGuestInfo(id string, info *api.ServerGuestInfo) error {
+	lockKey := "/lock/server/" + id
+	mutex, err := d.LockKey(lockKey)
+	if err != nil {
+		slog.Error("failed to lock", "err", err, "lockKey", lockKey)
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	var rec api.Server
+	key := ServerPrefix + "/" + id
+	resp, err := d.GetJSON(key, &rec)
+	if err != nil {
+		slog.Error("GetJSON() failed", "err", err, "key", key)
+		return err
+	}
+	if rec.Status == nil {
+		rec.Status = &api.Status{}
+	}
+	rec.Status.GuestInfo = info
+
+	api.SetServerID(&rec, id)
+	return d.PutJSONCAS(key, resp.Kvs[0].ModRevision, &rec)
+}
+
+// UpdateServerPerformance はサーバーのノードで割り当てたホスト CPU と NUMA ノードをステータスに記録する
+// ステータスコードとメッセージは変更しない
+func (d *Database) UpdateServerPerformance(id string, perf *api.ServerPerformanceStatus) error {
+	for {
+		err := d.updateServerPerformance(id, perf)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
+func (d *Database) updateServerPerformance(id string, perf *api.ServerPerformanceStatus) error {
+	lockKey := "/lock/server/" + id
+	mutex, err := d.LockKey(lockKey)
+	if err != nil {
+		slog.Error("failed to lock", "err", err, "lockKey", lockKey)
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	var rec api.Server
+	key := ServerPrefix + "/" + id
+	resp, err := d.GetJSON(key, &rec)
+	if err != nil {
+		slog.Error("GetJSON() failed", "err", err, "key", key)
+		return err
+	}
+	if rec.Status == nil {
+		rec.Status = &api.Status{}
+	}
+	rec.Status.Performance = perf
+
+	api.SetServerID(&rec, id)
+	return d.PutJSONCAS(key, resp.Kvs[0].ModRevision, &rec)
+}
+
 // AssignNodeToServer は NodeName 未設定のサーバーに対して nodeName を割り当てる。
 // 既に割り当て済みの場合は何もしない。
 // etcd の CAS を利用して競合を防ぐ。
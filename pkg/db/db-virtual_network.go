@@ -204,6 +204,7 @@ func (d *Database) GetVirtualNetworks() ([]api.VirtualNetwork, error) {
 			continue
 		}
 		normalizeVirtualNetworkID(&network, string(kv.Key))
+		setResourceVersion(&network.Metadata, kv.ModRevision)
 		networks = append(networks, network)
 	}
 
@@ -214,12 +215,13 @@ func (d *Database) GetVirtualNetworks() ([]api.VirtualNetwork, error) {
 func (d *Database) GetVirtualNetworkById(id string) (api.VirtualNetwork, error) {
 	key := NetworkPrefix + "/" + id
 	var network api.VirtualNetwork
-	_, err := d.GetJSON(key, &network)
+	resp, err := d.GetJSON(key, &network)
 	if err != nil {
 		slog.Error("GetVirtualNetworkById()", "err", err, "id", id, "key", key) // ここでエラーが発生する。なぜ？？ not found
 		return api.VirtualNetwork{}, err
 	}
 	normalizeVirtualNetworkID(&network, key)
+	setResourceVersion(&network.Metadata, resp.Kvs[0].ModRevision)
 	return network, nil
 }
 
@@ -312,7 +314,7 @@ func (d *Database) DeleteVirtualNetworkById(vnetId string) error {
 // 仮想ネットワークを更新
 func (d *Database) UpdateVirtualNetworkById(vnetId string, spec api.VirtualNetwork) error {
 	for {
-		err := d.updateVirtualNetwork(vnetId, spec)
+		err := d.updateVirtualNetwork(vnetId, spec, "")
 		if err == ErrUpdateConflict {
 			slog.Warn("UpdateVirtualNetwork() retrying due to update conflict", "networkId", vnetId)
 			continue
@@ -334,8 +336,20 @@ func (d *Database) UpdateVirtualNetworkById(vnetId string, spec api.VirtualNetwo
 	return nil
 }
 
+// UpdateVirtualNetworkWithResourceVersion は resourceVersion が指定された場合、現在のリソースバージョンと一致する時だけ更新する
+// 一致しない場合は ErrResourceVersionConflict を返す
+func (d *Database) UpdateVirtualNetworkWithResourceVersion(id string, spec api.VirtualNetwork, resourceVersion string) error {
+	for {
+		err := d.updateVirtualNetwork(id, spec, resourceVersion)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
 // 内部関数 仮想ネットワークを更新
-func (d *Database) updateVirtualNetwork(id string, spec api.VirtualNetwork) error {
+func (d *Database) updateVirtualNetwork(id string, spec api.VirtualNetwork, resourceVersion string) error {
 	lockKey := "/lock/virtualnetwork/" + id
 	mutex, err := d.LockKey(lockKey)
 	if err != nil {
@@ -352,11 +366,18 @@ func (d *Database) updateVirtualNetwork(id string, spec api.VirtualNetwork) erro
 		return err
 	}
 	expected := resp.Kvs[0].ModRevision
+	if err := checkResourceVersion(resourceVersion, expected); err != nil {
+		return err
+	}
 
 	normalizeVirtualNetworkID(&rec, key)
 	// パッチ適用
+	owner, project := rec.Metadata.Owner, rec.Metadata.Project
 	util.PatchStruct(&rec, spec)
+	// 所有者とプロジェクトはクォータの集計と認可に使うため作成後は変更しない
+	rec.Metadata.Owner, rec.Metadata.Project = owner, project
 	api.SetVirtualNetworkID(&rec, id)
+	rec.Metadata.ResourceVersion = nil
 
 	err = d.PutJSONCAS(key, expected, &rec)
 	if err != nil {
@@ -2,6 +2,7 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -21,8 +22,13 @@ const (
 	VOLUME_DELETING     = 4 // 削除中
 	VOLUME_UNAVAILABLE  = 5 // 実体欠損
 	//VOLUME_DELETED      = 6 // 削除済み
+	VOLUME_EXPANDING = 7 // 拡張中
+	VOLUME_CLONING   = 8 // 複製中
 )
 
+// ErrVolumeInUse はボリュームが他のサーバーにアタッチされていることを示す
+var ErrVolumeInUse = errors.New("volume is attached to another server")
+
 var VolStatus = map[int]string{
 	0: "PENDING",
 	1: "PROVISIONING",
@@ -31,6 +37,8 @@ var VolStatus = map[int]string{
 	4: "DELETING",
 	5: "UNAVAILABLE",
 	//6: "DELETED",
+	7: "EXPANDING",
+	8: "CLONING",
 }
 
 // 仮想マシンを生成する時にボリュームを生成して、アタッチする
@@ -203,7 +211,7 @@ func (d *Database) RollbackVolumeCreation(id string) {
 // / ボリュームを更新
 func (d *Database) UpdateVolume(id string, updateData api.Volume) error {
 	for {
-		err := d.updateVolume(id, updateData)
+		err := d.updateVolume(id, updateData, "")
 		if err == ErrUpdateConflict {
 			slog.Warn("UpdateVolume() retrying due to update conflict", "volumeId", id)
 			continue
@@ -225,8 +233,20 @@ func (d *Database) UpdateVolume(id string, updateData api.Volume) error {
 	return nil
 }
 
+// UpdateVolumeWithResourceVersion は resourceVersion が指定された場合、現在のリソースバージョンと一致する時だけ更新する
+// 一致しない場合は ErrResourceVersionConflict を返す
+func (d *Database) UpdateVolumeWithResourceVersion(id string, spec api.Volume, resourceVersion string) error {
+	for {
+		err := d.updateVolume(id, spec, resourceVersion)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
 // 内部関数 ボリュームを更新
-func (d *Database) updateVolume(id string, updateData api.Volume) error {
+func (d *Database) updateVolume(id string, updateData api.Volume, resourceVersion string) error {
 	lockKey := "/lock/volume/" + id
 	mutex, err := d.LockKey(lockKey)
 	if err != nil {
@@ -243,10 +263,17 @@ func (d *Database) updateVolume(id string, updateData api.Volume) error {
 		return err
 	}
 	expected := resp.Kvs[0].ModRevision
+	if err := checkResourceVersion(resourceVersion, expected); err != nil {
+		return err
+	}
 
 	api.SetVolumeID(&rec, id)
 	// パッチ適用
+	owner, project := rec.Metadata.Owner, rec.Metadata.Project
 	util.PatchStruct(&rec, updateData)
+	// 所有者とプロジェクトはクォータの集計と認可に使うため作成後は変更しない
+	rec.Metadata.Owner, rec.Metadata.Project = owner, project
+	rec.Metadata.ResourceVersion = nil
 
 	err = d.PutJSONCAS(key, expected, &rec)
 	if err != nil {
@@ -290,6 +317,7 @@ func (d *Database) GetVolumes() ([]api.Volume, error) {
 			slog.Error("Unmarshal() failed", "err", err, "key", string(kv.Key))
 			continue
 		}
+		setResourceVersion(&vol.Metadata, kv.ModRevision)
 		volumes = append(volumes, vol)
 	}
 
@@ -319,6 +347,7 @@ func (d *Database) ListVolumes(kind string) ([]api.Volume, error) {
 			continue
 		}
 		if vol.Spec.Kind != nil && *vol.Spec.Kind == kind {
+			setResourceVersion(&vol.Metadata, kv.ModRevision)
 			volumes = append(volumes, vol)
 		}
 	}
@@ -331,10 +360,12 @@ func (d *Database) GetVolumeById(id string) (api.Volume, error) {
 	var vol api.Volume
 	key := VolumePrefix + "/" + id
 	slog.Debug("volume data", "key", key, "id", id)
-	if _, err := d.GetJSON(key, &vol); err != nil {
+	resp, err := d.GetJSON(key, &vol)
+	if err != nil {
 		slog.Error("failed to get volume data", "err", err, "key", key, "id", id)
 		return vol, err
 	}
+	setResourceVersion(&vol.Metadata, resp.Kvs[0].ModRevision)
 	return vol, nil
 }
 
@@ -397,6 +428,148 @@ func (d *Database) UpdateVolumeStatusMessage(id string, status int, message stri
 	}
 }
 
+// ボリュームにアタッチ先のサーバーIDを記録する
+// 他のサーバーにアタッチされている場合は ErrVolumeInUse を返す
+func (d *Database) AttachVolumeToServer(id, serverId string) error {
+	for {
+		err := d.setVolumeAttachment(id, serverId, true)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
+// ボリュームのアタッチ先の記録を消す
+// 指定と異なるサーバーにアタッチされている場合は何もしない
+func (d *Database) DetachVolumeFromServer(id, serverId string) error {
+	for {
+		err := d.setVolumeAttachment(id, serverId, false)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
+// 内部関数 アタッチ先の記録を更新する
+// PatchStruct では nil に戻せないため、レコード全体を書き戻す
+func (d *Database) setVolumeAttachment(id, serverId string, attach bool) error {
+	lockKey := "/lock/volume/" + id
+	mutex, err := d.LockKey(lockKey)
+	if err != nil {
+		slog.Error("failed to lock", "err", err, "lockKey", lockKey)
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	var rec api.Volume
+	key := VolumePrefix + "/" + id
+	resp, err := d.GetJSON(key, &rec)
+	if err != nil {
+		return err
+	}
+	current := VolumeAttachedServerId(rec)
+	if attach {
+		if current == serverId {
+			return nil
+		}
+		if current != "" {
+			return fmt.Errorf("%w: %s", ErrVolumeInUse, current)
+		}
+		if rec.Status == nil {
+			rec.Status = &api.Status{}
+		}
+		rec.Status.AttachedServerId = util.StringPtr(serverId)
+	} else {
+		if current == "" || current != serverId {
+			return nil
+		}
+		rec.Status.AttachedServerId = nil
+	}
+	rec.Status.LastUpdateTimeStamp = util.TimePtr(time.Now())
+	rec.Metadata.ResourceVersion = nil
+
+	return d.PutJSONCAS(key, resp.Kvs[0].ModRevision, &rec)
+}
+
+// サーバーにアタッチされている全てのボリュームのアタッチ先の記録を消す
+// サーバーの削除時に使う
+func (d *Database) DetachVolumesFromServer(serverId string) error {
+	vols, err := d.GetVolumes()
+	if err != nil {
+		return err
+	}
+	for _, vol := range vols {
+		if VolumeAttachedServerId(vol) != serverId {
+			continue
+		}
+		if err := d.DetachVolumeFromServer(api.VolumeID(vol), serverId); err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// ボリュームがアタッチされているサーバーのIDを返す、未使用なら空文字
+func VolumeAttachedServerId(vol api.Volume) string {
+	if vol.Status == nil || vol.Status.AttachedServerId == nil {
+		return ""
+	}
+	return strings.TrimSpace(*vol.Status.AttachedServerId)
+}
+
+// ボリュームの拡張結果を記録して AVAILABLE に戻す
+// 失敗した場合は spec.size を拡張前のサイズへ戻し、理由をメッセージに残す
+func (d *Database) CompleteVolumeExpansion(id string, expandErr error) error {
+	for {
+		err := d.completeVolumeExpansion(id, expandErr)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
+// 内部関数 拡張結果を書き戻す
+// PatchStruct ではメッセージを消せないため、レコード全体を書き戻す
+func (d *Database) completeVolumeExpansion(id string, expandErr error) error {
+	lockKey := "/lock/volume/" + id
+	mutex, err := d.LockKey(lockKey)
+	if err != nil {
+		slog.Error("failed to lock", "err", err, "lockKey", lockKey)
+		return err
+	}
+	defer d.UnlockKey(mutex)
+
+	var rec api.Volume
+	key := VolumePrefix + "/" + id
+	resp, err := d.GetJSON(key, &rec)
+	if err != nil {
+		return err
+	}
+	if rec.Status == nil {
+		rec.Status = &api.Status{}
+	}
+	if expandErr != nil {
+		if rec.Status.Size != nil {
+			rec.Spec.Size = util.IntPtrInt(*rec.Status.Size)
+		}
+		rec.Status.Message = util.StringPtr(fmt.Sprintf("ボリュームの拡張に失敗: %v", expandErr))
+	} else {
+		if rec.Spec.Size != nil {
+			rec.Status.Size = util.IntPtrInt(*rec.Spec.Size)
+		}
+		rec.Status.Message = nil
+	}
+	rec.Status.StatusCode = VOLUME_AVAILABLE
+	rec.Status.Status = util.StringPtr(VolStatus[VOLUME_AVAILABLE])
+	rec.Status.LastUpdateTimeStamp = util.TimePtr(time.Now())
+	rec.Metadata.ResourceVersion = nil
+
+	return d.PutJSONCAS(key, resp.Kvs[0].ModRevision, &rec)
+}
+
 // 削除タイムスタンプのセット
 func (d *Database) SetVolumeDeletionTimestamp(id string) {
 	if len(id) == 0 {
@@ -419,13 +592,3 @@ func (d *Database) SetVolumeDeletionTimestamp(id string) {
 func createDataVolumefromVm() {
 
 }
-
-// アタッチデータボリューム ここではない、VMコントローラ側で実装するべきか？
-func attachDataVolume() {
-
-}
-
-// デタッチデータボリューム　ここではない、VMコントローラ側で実装するべきか？
-func detachDataVolume() {
-
-}
@@ -2,6 +2,7 @@ package db_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"time"
@@ -103,6 +104,64 @@ var _ = Describe("Volumes", Ordered, func() {
 				fmt.Println(string(jsonData))
 			})
 
+			It("ボリュームのアタッチ先の記録", func() {
+				id := api.VolumeID(*volSpec)
+				Expect(v.AttachVolumeToServer(id, "sv001")).To(Succeed())
+				// 同じサーバーへの再アタッチは成功する
+				Expect(v.AttachVolumeToServer(id, "sv001")).To(Succeed())
+				// 他のサーバーにはアタッチできない
+				err := v.AttachVolumeToServer(id, "sv002")
+				Expect(errors.Is(err, db.ErrVolumeInUse)).To(BeTrue())
+
+				vol, err := v.GetVolumeById(id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(db.VolumeAttachedServerId(vol)).To(Equal("sv001"))
+				Expect(vol.Status.StatusCode).To(Equal(db.VOLUME_AVAILABLE))
+			})
+
+			It("ボリュームのアタッチ先の解除", func() {
+				id := api.VolumeID(*volSpec)
+				// アタッチ先と異なるサーバーの指定は無視する
+				Expect(v.DetachVolumeFromServer(id, "sv002")).To(Succeed())
+				vol, err := v.GetVolumeById(id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(db.VolumeAttachedServerId(vol)).To(Equal("sv001"))
+
+				Expect(v.DetachVolumeFromServer(id, "sv001")).To(Succeed())
+				vol, err = v.GetVolumeById(id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(db.VolumeAttachedServerId(vol)).To(BeEmpty())
+				Expect(v.AttachVolumeToServer(id, "sv002")).To(Succeed())
+				Expect(v.DetachVolumeFromServer(id, "sv002")).To(Succeed())
+			})
+
+			It("ボリュームの拡張結果の記録", func() {
+				id := api.VolumeID(*volSpec)
+				// 1GB から 3GB への拡張が成功した場合
+				Expect(v.UpdateVolume(id, api.Volume{
+					Spec:   api.VolSpec{Size: util.IntPtrInt(3)},
+					Status: &api.Status{StatusCode: db.VOLUME_EXPANDING, Size: util.IntPtrInt(1)},
+				})).To(Succeed())
+				Expect(v.CompleteVolumeExpansion(id, nil)).To(Succeed())
+				vol, err := v.GetVolumeById(id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(vol.Status.StatusCode).To(Equal(db.VOLUME_AVAILABLE))
+				Expect(*vol.Status.Size).To(Equal(3))
+				Expect(vol.Status.Message).To(BeNil())
+
+				// 5GB への拡張が失敗した場合はサイズを戻す
+				Expect(v.UpdateVolume(id, api.Volume{
+					Spec:   api.VolSpec{Size: util.IntPtrInt(5)},
+					Status: &api.Status{StatusCode: db.VOLUME_EXPANDING},
+				})).To(Succeed())
+				Expect(v.CompleteVolumeExpansion(id, errors.New("insufficient free space"))).To(Succeed())
+				vol, err = v.GetVolumeById(id)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(vol.Status.StatusCode).To(Equal(db.VOLUME_AVAILABLE))
+				Expect(*vol.Spec.Size).To(Equal(3))
+				Expect(*vol.Status.Message).To(ContainSubstring("insufficient free space"))
+			})
+
 			It("ボリュームの作成 #2", func() {
 				vol := &api.Volume{
 					Metadata: api.Metadata{
@@ -174,6 +174,7 @@ func (d *Database) GetVpnGateways() ([]api.VpnGateway, error) {
 			slog.Error("GetVpnGateways() unmarshal failed", "err", err)
 			continue
 		}
+		setResourceVersion(&rec.Metadata, kv.ModRevision)
 		result = append(result, rec)
 	}
 	return result, nil
@@ -182,16 +183,17 @@ func (d *Database) GetVpnGateways() ([]api.VpnGateway, error) {
 func (d *Database) GetVpnGatewayById(id string) (api.VpnGateway, error) {
 	key := VpnGatewayPrefix + "/" + id
 	var rec api.VpnGateway
-	_, err := d.GetJSON(key, &rec)
+	resp, err := d.GetJSON(key, &rec)
 	if err != nil {
 		return api.VpnGateway{}, err
 	}
+	setResourceVersion(&rec.Metadata, resp.Kvs[0].ModRevision)
 	return rec, nil
 }
 
 func (d *Database) UpdateVpnGatewayById(id string, spec api.VpnGateway) error {
 	for {
-		err := d.updateVpnGateway(id, spec)
+		err := d.updateVpnGateway(id, spec, "")
 		if err == ErrUpdateConflict {
 			continue
 		}
@@ -199,7 +201,19 @@ func (d *Database) UpdateVpnGatewayById(id string, spec api.VpnGateway) error {
 	}
 }
 
-func (d *Database) updateVpnGateway(id string, spec api.VpnGateway) error {
+// UpdateVpnGatewayByIdWithResourceVersion は resourceVersion が指定された場合、現在のリソースバージョンと一致する時だけ更新する
+// 一致しない場合は ErrResourceVersionConflict を返す
+func (d *Database) UpdateVpnGatewayByIdWithResourceVersion(id string, spec api.VpnGateway, resourceVersion string) error {
+	for {
+		err := d.updateVpnGateway(id, spec, resourceVersion)
+		if err == ErrUpdateConflict {
+			continue
+		}
+		return err
+	}
+}
+
+func (d *Database) updateVpnGateway(id string, spec api.VpnGateway, resourceVersion string) error {
 	lockKey := "/lock/vpn-gateway/" + id
 	mutex, err := d.LockKey(lockKey)
 	if err != nil {
@@ -213,8 +227,15 @@ func (d *Database) updateVpnGateway(id string, spec api.VpnGateway) error {
 	if err != nil {
 		return err
 	}
+	if err := checkResourceVersion(resourceVersion, resp.Kvs[0].ModRevision); err != nil {
+		return err
+	}
+	owner, project := rec.Metadata.Owner, rec.Metadata.Project
 	util.PatchStruct(&rec, spec)
+	// 所有者とプロジェクトはクォータの集計と認可に使うため作成後は変更しない
+	rec.Metadata.Owner, rec.Metadata.Project = owner, project
 	api.SetVpnGatewayID(&rec, id)
+	rec.Metadata.ResourceVersion = nil
 	return d.PutJSONCAS(key, resp.Kvs[0].ModRevision, &rec)
 }
 
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	etcd "go.etcd.io/etcd/client/v3"
+)
+
+// WatchPrefix が送るイベントの種類（Kubernetes の watch と同じ名前）
+const (
+	WATCH_ADDED    = "ADDED"
+	WATCH_MODIFIED = "MODIFIED"
+	WATCH_DELETED  = "DELETED"
+	WATCH_ERROR    = "ERROR"
+)
+
+// 指定されたリソースバージョンがコンパクションで失われている
+var ErrResourceVersionExpired = errors.New("resource version is too old")
+
+// WatchEvent はプレフィックス配下のキーの変更
+type WatchEvent struct {
+	Type     string
+	Key      string
+	Value    []byte // DELETED の場合は削除前の値
+	Revision int64  // 変更のリビジョン。resourceVersion として返す
+	Err      error  // ERROR の場合の原因
+}
+
+// WatchPrefix は prefix 配下の変更を afterRevision の次のリビジョンから送る
+// afterRevision が 0 の場合は現在のキーを ADDED として送ってから、その後の変更を送る
+// ctx が終了すると、watch が失敗した場合は ERROR を送ってからチャネルを閉じる
+func (d *Database) WatchPrefix(ctx context.Context, prefix string, afterRevision int64) <-chan WatchEvent {
+	out := make(chan WatchEvent)
+	go func() {
+		defer close(out)
+		send := func(ev WatchEvent) bool {
+			select {
+			case out <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		if afterRevision <= 0 {
+			resp, err := d.Cli.Get(ctx, prefix, etcd.WithPrefix())
+			if err != nil {
+				send(WatchEvent{Type: WATCH_ERROR, Err: fmt.Errorf("etcd get prefix failed: %w", err)})
+				return
+			}
+			for _, kv := range resp.Kvs {
+				if !send(WatchEvent{Type: WATCH_ADDED, Key: string(kv.Key), Value: kv.Value, Revision: kv.ModRevision}) {
+					return
+				}
+			}
+			afterRevision = resp.Header.Revision
+		}
+
+		wch := d.Cli.Watch(etcd.WithRequireLeader(ctx), prefix, etcd.WithPrefix(), etcd.WithRev(afterRevision+1), etcd.WithPrevKV())
+		for wresp := range wch {
+			if err := wresp.Err(); err != nil {
+				if errors.Is(err, rpctypes.ErrCompacted) || wresp.CompactRevision != 0 {
+					err = fmt.Errorf("%w: compacted at %d", ErrResourceVersionExpired, wresp.CompactRevision)
+				}
+				send(WatchEvent{Type: WATCH_ERROR, Err: err})
+				return
+			}
+			for _, ev := range wresp.Events {
+				we := WatchEvent{Key: string(ev.Kv.Key), Value: ev.Kv.Value, Revision: ev.Kv.ModRevision}
+				switch {
+				case ev.Type == mvccpb.DELETE:
+					we.Type = WATCH_DELETED
+					we.Revision = wresp.Header.Revision
+					if ev.PrevKv != nil {
+						we.Value = ev.PrevKv.Value
+					}
+				case ev.IsCreate():
+					we.Type = WATCH_ADDED
+				default:
+					we.Type = WATCH_MODIFIED
+				}
+				if !send(we) {
+					return
+				}
+			}
+		}
+		if ctx.Err() == nil {
+			send(WatchEvent{Type: WATCH_ERROR, Err: errors.New("watch channel closed")})
+		}
+	}()
+	return out
+}
+
+// DecodeResource は prefix 配下のキーの値を一覧取得と同じ形のリソースに変換する
+// リソース本体ではないキー（IPアドレスの割当など）の場合は false を返す
+func DecodeResource(prefix, key string, value []byte) (interface{}, bool, error) {
+	id, ok := strings.CutPrefix(key, prefix+"/")
+	if !ok || id == "" {
+		return nil, false, nil
+	}
+	switch prefix {
+	case ServerPrefix:
+		if strings.Contains(id, "/") {
+			return nil, false, nil
+		}
+		var server api.Server
+		if err := json.Unmarshal(value, &server); err != nil {
+			return nil, false, err
+		}
+		api.SetServerID(&server, id)
+		server.NormalizeMMImageAlias()
+		return server, true, nil
+	case VolumePrefix:
+		var vol api.Volume
+		if err := json.Unmarshal(value, &vol); err != nil {
+			return nil, false, err
+		}
+		return vol, true, nil
+	case NetworkPrefix:
+		if strings.Contains(key, "/ip_network/") {
+			return nil, false, nil
+		}
+		var network api.VirtualNetwork
+		if err := json.Unmarshal(value, &network); err != nil {
+			return nil, false, err
+		}
+		normalizeVirtualNetworkID(&network, key)
+		return network, true, nil
+	case ImagePrefix:
+		var img api.Image
+		if err := json.Unmarshal(value, &img); err != nil {
+			return nil, false, err
+		}
+		normalizeImageMetadataID(&img, imageIDFromKey(key))
+		return img, true, nil
+	case LoadBalancerPrefix:
+		var rec api.ApplicationLoadBalancer
+		if err := json.Unmarshal(value, &rec); err != nil {
+			return nil, false, err
+		}
+		return rec, true, nil
+	case NetworkLoadBalancerPrefix:
+		var rec api.NetworkLoadBalancer
+		if err := json.Unmarshal(value, &rec); err != nil {
+			return nil, false, err
+		}
+		return rec, true, nil
+	}
+	return nil, false, fmt.Errorf("unsupported watch prefix %s", prefix)
+}
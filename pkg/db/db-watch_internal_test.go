@@ -0,0 +1,53 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/takara9/marmot/api"
+)
+
+func TestDecodeResource_SkipsNonResourceKeys(t *testing.T) {
+	cases := []struct {
+		prefix string
+		key    string
+	}{
+		{ServerPrefix, ServerPrefix},
+		{ServerPrefix, ServerPrefix + "/abc12/snapshot"},
+		{NetworkPrefix, NetworkPrefix + "/net01/ip_network/10.0.0.0"},
+		{VolumePrefix, GatewayPrefix + "/gw001"},
+	}
+	for _, c := range cases {
+		_, ok, err := DecodeResource(c.prefix, c.key, []byte("{}"))
+		if err != nil || ok {
+			t.Fatalf("DecodeResource(%s, %s) = ok:%v err:%v, want skipped", c.prefix, c.key, ok, err)
+		}
+	}
+}
+
+func TestDecodeResource_SetsIDFromKey(t *testing.T) {
+	obj, ok, err := DecodeResource(ServerPrefix, ServerPrefix+"/abc12", []byte(`{"metadata":{"name":"vm1"}}`))
+	if err != nil || !ok {
+		t.Fatalf("DecodeResource() = ok:%v err:%v, want decoded", ok, err)
+	}
+	server, isServer := obj.(api.Server)
+	if !isServer {
+		t.Fatalf("DecodeResource() returned %T, want api.Server", obj)
+	}
+	if api.ServerID(server) != "abc12" || server.Metadata.Name != "vm1" {
+		t.Fatalf("server = id:%q name:%q, want abc12/vm1", api.ServerID(server), server.Metadata.Name)
+	}
+
+	obj, ok, err = DecodeResource(NetworkPrefix, NetworkPrefix+"/net01", []byte(`{"metadata":{"name":"default"}}`))
+	if err != nil || !ok {
+		t.Fatalf("DecodeResource() = ok:%v err:%v, want decoded", ok, err)
+	}
+	if id := api.VirtualNetworkID(obj.(api.VirtualNetwork)); id != "net01" {
+		t.Fatalf("network id = %q, want net01", id)
+	}
+}
+
+func TestDecodeResource_RejectsUnsupportedPrefix(t *testing.T) {
+	if _, _, err := DecodeResource(JobPrefix, JobPrefix+"/job1", []byte("{}")); err == nil {
+		t.Fatal("DecodeResource() with job prefix succeeded, want error")
+	}
+}
@@ -2,8 +2,6 @@ package internaldns
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
 	"log/slog"
@@ -14,12 +12,11 @@ import (
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/takara9/marmot/api"
 	"github.com/takara9/marmot/pkg/db"
 	"github.com/takara9/marmot/pkg/marmotd"
 )
 
-var errInvalidDNSRecordIP = errors.New("invalid IP address in DNS record")
-
 type controller struct {
 	db       *db.Database
 	mu       sync.Mutex
@@ -122,52 +119,32 @@ func (c *controller) handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 	}
 
 	q := r.Question[0]
-	lookupKeys := DomainToMarmotPaths(q.Name)
-
-	// etcd から IP アドレスを取得
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-
-	foundInEtcd := false
-	for _, etcdKey := range lookupKeys {
-		resp, err := c.db.Cli.Get(ctx, etcdKey)
-		if err != nil {
-			slog.Error("検索の失敗 Failed to query etcd", "err", err)
-			dns.HandleFailed(w, r)
-			return
-		}
-
-		if len(resp.Kvs) == 0 {
-			continue
-		}
 
-		ip, ipStr, err := decodeDNSRecordIP(resp.Kvs[0].Value)
-		if err != nil {
-			slog.Error("Failed to decode DNS record", "err", err, "key", etcdKey)
-			dns.HandleFailed(w, r)
-			return
-		}
-
-		foundInEtcd = true
-
-		if ip != nil && (q.Qtype == dns.TypeA || q.Qtype == dns.TypeANY) {
-			log.Printf("Resolved from etcd: %s -> %s", q.Name[:len(q.Name)-1], ipStr)
-			m := new(dns.Msg)
-			m.SetReply(r)
-			m.Answer = append(m.Answer, &dns.A{
-				Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
-				A:   ip,
-			})
-			_ = w.WriteMsg(m)
-			return
-		}
+	// etcd からレコードを取得
+	records, err := c.lookupRecords(q.Name)
+	if err != nil {
+		slog.Error("検索の失敗 Failed to query etcd", "err", err)
+		dns.HandleFailed(w, r)
+		return
 	}
 
-	// marmot管理下のホストだがAレコード以外(AAAA等)の問い合わせはNODATAで即答し、
+	// marmot管理下の名前は、該当する型のレコードが無くても NODATA で即答し、
 	// 上位DNSへ再転送してforwardループになるのを防ぐ
-	if foundInEtcd {
+	if len(records) > 0 {
 		m := new(dns.Msg)
 		m.SetReply(r)
+		m.Authoritative = true
+		m.Answer = buildAnswer(q, records, func(name string) []api.DnsRecord {
+			recs, err := c.lookupRecords(name)
+			if err != nil {
+				slog.Error("Failed to query etcd", "err", err, "name", name)
+				return nil
+			}
+			return recs
+		})
+		for _, rr := range m.Answer {
+			log.Printf("Resolved from etcd: %s", rr.String())
+		}
 		_ = w.WriteMsg(m)
 		return
 	}
@@ -190,16 +167,145 @@ func (c *controller) handleRequest(w dns.ResponseWriter, r *dns.Msg) {
 	_ = w.WriteMsg(reply)
 }
 
-func decodeDNSRecordIP(raw []byte) (net.IP, string, error) {
-	var ipStr string
-	if err := json.Unmarshal(raw, &ipStr); err != nil {
-		return nil, "", err
+// lookupRecords は名前に対応するレコードを集める
+// 保存されたレコードに加えて、逆引きは IPAM から、ロードバランサーは設定からレコードを生成する
+func (c *controller) lookupRecords(name string) ([]api.DnsRecord, error) {
+	var records []api.DnsRecord
+	for _, etcdKey := range DomainToMarmotPaths(name) {
+		recs, err := c.db.GetDnsRecordsByPath(etcdKey)
+		if err == db.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, recs...)
+		break
 	}
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return nil, ipStr, errInvalidDNSRecordIP
+
+	if ip := reverseNameToIP(name); ip != nil {
+		hostnames, err := c.db.GetHostnamesByIP(ip.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, hostname := range hostnames {
+			records = append(records, api.DnsRecord{Name: name, Type: db.DNS_RECORD_PTR, Value: hostname})
+		}
+		return records, nil
 	}
-	return ip, ipStr, nil
+
+	lbRecords, err := c.db.GetLoadBalancerDnsRecords(name)
+	if err != nil {
+		return nil, err
+	}
+	return append(records, lbRecords...), nil
+}
+
+// CNAME を辿る最大の回数
+const maxCNAMEChain = 8
+
+// buildAnswer は問い合わせの型に一致するレコードから応答を作る
+// 問い合わせた名前が CNAME の場合は、marmot 管理下の名前である限り別名の先を辿る
+func buildAnswer(q dns.Question, records []api.DnsRecord, lookup func(name string) []api.DnsRecord) []dns.RR {
+	var answer []dns.RR
+	name := q.Name
+	for i := 0; i <= maxCNAMEChain; i++ {
+		var cname *api.DnsRecord
+		for j := range records {
+			rec := records[j]
+			if rec.Type == db.DNS_RECORD_CNAME && q.Qtype != dns.TypeCNAME && q.Qtype != dns.TypeANY {
+				cname = &rec
+				continue
+			}
+			if q.Qtype != dns.TypeANY && dns.StringToType[rec.Type] != q.Qtype {
+				continue
+			}
+			if rr := recordToRR(name, rec); rr != nil {
+				answer = append(answer, rr)
+			}
+		}
+		if cname == nil {
+			break
+		}
+		rr := recordToRR(name, *cname)
+		if rr == nil {
+			break
+		}
+		answer = append(answer, rr)
+		name = dns.Fqdn(cname.Value)
+		records = lookup(name)
+		if len(records) == 0 {
+			break
+		}
+	}
+	return answer
+}
+
+// recordToRR はレコードを応答のリソースレコードに変換する
+func recordToRR(name string, rec api.DnsRecord) dns.RR {
+	ttl := uint32(db.DefaultDnsRecordTTL)
+	if rec.Ttl != nil && *rec.Ttl > 0 {
+		ttl = uint32(*rec.Ttl)
+	}
+	hdr := dns.RR_Header{Name: name, Rrtype: dns.StringToType[rec.Type], Class: dns.ClassINET, Ttl: ttl}
+
+	switch rec.Type {
+	case db.DNS_RECORD_A:
+		ip := net.ParseIP(rec.Value).To4()
+		if ip == nil {
+			return nil
+		}
+		return &dns.A{Hdr: hdr, A: ip}
+	case db.DNS_RECORD_AAAA:
+		ip := net.ParseIP(rec.Value)
+		if ip == nil || ip.To4() != nil {
+			return nil
+		}
+		return &dns.AAAA{Hdr: hdr, AAAA: ip}
+	case db.DNS_RECORD_CNAME:
+		return &dns.CNAME{Hdr: hdr, Target: dns.Fqdn(rec.Value)}
+	case db.DNS_RECORD_PTR:
+		return &dns.PTR{Hdr: hdr, Ptr: dns.Fqdn(rec.Value)}
+	case db.DNS_RECORD_SRV:
+		return &dns.SRV{
+			Hdr:      hdr,
+			Priority: uint16(intValue(rec.Priority)),
+			Weight:   uint16(intValue(rec.Weight)),
+			Port:     uint16(intValue(rec.Port)),
+			Target:   dns.Fqdn(rec.Value),
+		}
+	}
+	return nil
+}
+
+func intValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// reverseNameToIP は逆引きの名前（in-addr.arpa / ip6.arpa）をIPアドレスに変換する
+// 逆引きの名前でない場合は nil を返す
+func reverseNameToIP(name string) net.IP {
+	labels := db.DnsNameLabels(strings.ToLower(name))
+	switch {
+	case len(labels) == 6 && labels[4] == "in-addr" && labels[5] == "arpa":
+		return net.ParseIP(labels[3] + "." + labels[2] + "." + labels[1] + "." + labels[0]).To4()
+	case len(labels) == 34 && labels[32] == "ip6" && labels[33] == "arpa":
+		var b strings.Builder
+		for i := 31; i >= 0; i-- {
+			if len(labels[i]) != 1 {
+				return nil
+			}
+			b.WriteString(labels[i])
+			if i%4 == 0 && i > 0 {
+				b.WriteString(":")
+			}
+		}
+		return net.ParseIP(b.String())
+	}
+	return nil
 }
 
 func parseAllowedUpstreamCIDRs(cidrs []string) ([]netip.Prefix, error) {
@@ -249,15 +355,7 @@ func shouldForwardUpstream(remoteAddr net.Addr, allowedCIDRs []netip.Prefix) boo
 
 // DomainToMarmotPath はドメイン名を /marmot/dns/ 形式のパスに変換します
 func DomainToMarmotPath(domain string) string {
-	parts := splitDomainLabels(domain)
-
-	// スライスの要素を逆順に入れ替え
-	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
-		parts[i], parts[j] = parts[j], parts[i]
-	}
-
-	// プレフィックス を先頭につけて結合
-	return db.InternalDNSPrefix + "/" + strings.Join(parts, "/")
+	return db.DnsNamePath(domain)
 }
 
 // DomainToMarmotPaths は問い合わせドメインから探索候補キーを返す。
@@ -265,7 +363,7 @@ func DomainToMarmotPath(domain string) string {
 // 2) host.network 形式へのフォールバックキー
 func DomainToMarmotPaths(domain string) []string {
 	full := DomainToMarmotPath(domain)
-	parts := splitDomainLabels(domain)
+	parts := db.DnsNameLabels(domain)
 
 	paths := []string{full}
 	if len(parts) >= 2 {
@@ -277,19 +375,3 @@ func DomainToMarmotPaths(domain string) []string {
 
 	return paths
 }
-
-func splitDomainLabels(domain string) []string {
-	domain = strings.TrimSuffix(domain, ".")
-	parts := strings.Split(domain, ".")
-
-	filtered := make([]string, 0, len(parts))
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p == "" {
-			continue
-		}
-		filtered = append(filtered, p)
-	}
-
-	return filtered
-}
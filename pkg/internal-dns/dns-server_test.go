@@ -1,10 +1,13 @@
 package internaldns
 
 import (
-	"errors"
 	"net"
 	"net/netip"
 	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
 )
 
 type stubAddr struct {
@@ -16,75 +19,111 @@ func (a stubAddr) Network() string { return a.network }
 
 func (a stubAddr) String() string { return a.addr }
 
-func TestDecodeDNSRecordIP(t *testing.T) {
+func TestBuildAnswer(t *testing.T) {
+	ttl := 60
+	records := []api.DnsRecord{
+		{Name: "vm1.net1", Type: db.DNS_RECORD_A, Value: "192.168.100.2", Ttl: &ttl},
+		{Name: "vm1.net1", Type: db.DNS_RECORD_AAAA, Value: "2001:db8::10"},
+	}
+	noLookup := func(string) []api.DnsRecord { return nil }
+
 	tests := []struct {
-		name    string
-		raw     []byte
-		wantIP  net.IP
-		wantStr string
-		wantErr error
+		name  string
+		qtype uint16
+		want  []string
 	}{
-		{
-			name:    "valid IPv4 JSON",
-			raw:     []byte(`"192.168.100.2"`),
-			wantIP:  net.ParseIP("192.168.100.2"),
-			wantStr: "192.168.100.2",
-		},
-		{
-			name:    "valid IPv6 JSON",
-			raw:     []byte(`"2001:db8::10"`),
-			wantIP:  net.ParseIP("2001:db8::10"),
-			wantStr: "2001:db8::10",
-		},
-		{
-			name:    "invalid JSON",
-			raw:     []byte(`192.168.100.2`),
-			wantErr: errors.New("json decode error"),
-		},
-		{
-			name:    "invalid IP string",
-			raw:     []byte(`"not-an-ip"`),
-			wantStr: "not-an-ip",
-			wantErr: errInvalidDNSRecordIP,
-		},
+		{name: "A", qtype: dns.TypeA, want: []string{"vm1.net1.\t60\tIN\tA\t192.168.100.2"}},
+		{name: "AAAA", qtype: dns.TypeAAAA, want: []string{"vm1.net1.\t300\tIN\tAAAA\t2001:db8::10"}},
+		{name: "ANY", qtype: dns.TypeANY, want: []string{"vm1.net1.\t60\tIN\tA\t192.168.100.2", "vm1.net1.\t300\tIN\tAAAA\t2001:db8::10"}},
+		{name: "NODATA", qtype: dns.TypeMX, want: nil},
 	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotIP, gotStr, err := decodeDNSRecordIP(tt.raw)
+			got := buildAnswer(dns.Question{Name: "vm1.net1.", Qtype: tt.qtype, Qclass: dns.ClassINET}, records, noLookup)
+			assertRRs(t, got, tt.want)
+		})
+	}
+}
 
-			if tt.wantErr == nil && err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
-			if tt.wantErr != nil {
-				if err == nil {
-					t.Fatalf("expected error, got nil")
-				}
-				if errors.Is(tt.wantErr, errInvalidDNSRecordIP) {
-					if !errors.Is(err, errInvalidDNSRecordIP) {
-						t.Fatalf("expected errInvalidDNSRecordIP, got: %v", err)
-					}
-				} else {
-					if errors.Is(err, errInvalidDNSRecordIP) {
-						t.Fatalf("unexpected errInvalidDNSRecordIP")
-					}
-				}
-			}
+func TestBuildAnswerFollowsCNAME(t *testing.T) {
+	records := []api.DnsRecord{{Name: "www.net1", Type: db.DNS_RECORD_CNAME, Value: "vm1.net1"}}
+	lookup := func(name string) []api.DnsRecord {
+		if name != "vm1.net1." {
+			t.Fatalf("unexpected lookup: %s", name)
+		}
+		return []api.DnsRecord{{Name: "vm1.net1", Type: db.DNS_RECORD_A, Value: "192.168.100.2"}}
+	}
 
-			if tt.wantStr != gotStr {
-				t.Fatalf("decoded string mismatch: want=%q got=%q", tt.wantStr, gotStr)
-			}
+	got := buildAnswer(dns.Question{Name: "www.net1.", Qtype: dns.TypeA, Qclass: dns.ClassINET}, records, lookup)
+	assertRRs(t, got, []string{
+		"www.net1.\t300\tIN\tCNAME\tvm1.net1.",
+		"vm1.net1.\t300\tIN\tA\t192.168.100.2",
+	})
+
+	// CNAME の問い合わせには別名の先を辿らない
+	got = buildAnswer(dns.Question{Name: "www.net1.", Qtype: dns.TypeCNAME, Qclass: dns.ClassINET}, records, lookup)
+	assertRRs(t, got, []string{"www.net1.\t300\tIN\tCNAME\tvm1.net1."})
+}
+
+func TestBuildAnswerStopsCNAMELoop(t *testing.T) {
+	loop := []api.DnsRecord{{Name: "a.net1", Type: db.DNS_RECORD_CNAME, Value: "a.net1"}}
+	got := buildAnswer(dns.Question{Name: "a.net1.", Qtype: dns.TypeA, Qclass: dns.ClassINET}, loop, func(string) []api.DnsRecord { return loop })
+	if len(got) != maxCNAMEChain+1 {
+		t.Fatalf("answer length = %d, want %d", len(got), maxCNAMEChain+1)
+	}
+}
+
+func TestBuildAnswerPTRAndSRV(t *testing.T) {
+	port, priority, weight := 443, 10, 5
+	noLookup := func(string) []api.DnsRecord { return nil }
+
+	got := buildAnswer(dns.Question{Name: "2.100.168.192.in-addr.arpa.", Qtype: dns.TypePTR, Qclass: dns.ClassINET},
+		[]api.DnsRecord{{Type: db.DNS_RECORD_PTR, Value: "vm1.net1"}}, noLookup)
+	assertRRs(t, got, []string{"2.100.168.192.in-addr.arpa.\t300\tIN\tPTR\tvm1.net1."})
 
-			if tt.wantIP == nil && gotIP != nil {
-				t.Fatalf("expected nil IP, got %v", gotIP)
+	got = buildAnswer(dns.Question{Name: "_web._tcp.alb1.net1.", Qtype: dns.TypeSRV, Qclass: dns.ClassINET},
+		[]api.DnsRecord{{Type: db.DNS_RECORD_SRV, Value: "alb1.net1", Port: &port, Priority: &priority, Weight: &weight}}, noLookup)
+	assertRRs(t, got, []string{"_web._tcp.alb1.net1.\t300\tIN\tSRV\t10 5 443 alb1.net1."})
+}
+
+func TestReverseNameToIP(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "2.100.168.192.in-addr.arpa.", want: "192.168.100.2"},
+		{name: "0.1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", want: "2001:db8::10"},
+		{name: "vm1.net1.", want: ""},
+		{name: "100.168.192.in-addr.arpa.", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reverseNameToIP(tt.name)
+			if tt.want == "" {
+				if got != nil {
+					t.Fatalf("expected nil, got %v", got)
+				}
+				return
 			}
-			if tt.wantIP != nil && !tt.wantIP.Equal(gotIP) {
-				t.Fatalf("IP mismatch: want=%v got=%v", tt.wantIP, gotIP)
+			if !net.ParseIP(tt.want).Equal(got) {
+				t.Fatalf("reverseNameToIP(%q) = %v, want %s", tt.name, got, tt.want)
 			}
 		})
 	}
 }
 
+func assertRRs(t *testing.T, got []dns.RR, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("answer = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Fatalf("answer[%d] = %q, want %q", i, got[i].String(), want[i])
+		}
+	}
+}
+
 func TestDomainToMarmotPath(t *testing.T) {
 	tests := []struct {
 		domain string
@@ -63,6 +63,18 @@ func RemoveLV(vgx string, lvx string) error {
 	return nil
 }
 
+// 論理ボリュームの拡張、サイズは拡張後の総量
+// 現在のサイズ以下の指定はエラーになる
+func ExtendLV(vgx string, lvx string, sizeInByte uint64) error {
+	slog.Debug("ExtendLV() called", "vgx", vgx, "lvx", lvx, "sizeInByte", sizeInByte)
+	output, err := exec.Command("lvextend", "-L", fmt.Sprintf("%db", sizeInByte), vgx+"/"+lvx).CombinedOutput()
+	if err != nil {
+		slog.Error("Failed to execute lvextend command", "err", err, "output", string(output))
+		return fmt.Errorf("failed to extend logical volume %s/%s: %v", vgx, lvx, err)
+	}
+	return nil
+}
+
 // スナップショットの作成、OSボリューム作成用
 func CreateSnapshot(vgx string, lvx string, svx string, sizeInByte uint64) error {
 	slog.Debug("CreateSnapshot() called", "vgx", vgx, "lvx", lvx, "svx", svx, "sizeInByte", sizeInByte)
@@ -82,6 +94,18 @@ func CreateSnapshot(vgx string, lvx string, svx string, sizeInByte uint64) error
 	return nil
 }
 
+// スナップショットを元の論理ボリュームへマージ（ロールバック）する
+// マージ後にスナップショット論理ボリュームは消滅する。元の論理ボリュームが使用中の場合は次回アクティブ化まで延期される。
+func MergeSnapshot(vgx string, svx string) error {
+	slog.Debug("MergeSnapshot() called", "vgx", vgx, "svx", svx)
+	output, err := exec.Command("lvconvert", "--merge", vgx+"/"+svx).CombinedOutput()
+	if err != nil {
+		slog.Error("Failed to execute lvconvert command", "err", err, "output", string(output))
+		return fmt.Errorf("failed to merge snapshot %s/%s: %v", vgx, svx, err)
+	}
+	return nil
+}
+
 // ボリュームグループの総量量と空きチェック
 func CheckVG(vgx string) (uint64, uint64, error) {
 	tlvm.Verbose = false
@@ -57,6 +57,11 @@ var _ = Describe("Lvm", Ordered, func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 
+			It("Extend Logical Volume", func() {
+				err := ExtendLV(vg, lv, sz*2)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
 			time.Sleep(time.Second * 30)
 
 			It("Remove Logical Volume", func() {
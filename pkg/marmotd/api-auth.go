@@ -3,6 +3,7 @@ package marmotd
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"strings"
@@ -28,8 +29,12 @@ func mapAuthDBError(ctx echo.Context, err error) error {
 		return apiErrorJSON(ctx, http.StatusNotFound, err.Error())
 	case errors.Is(err, db.ErrInvalidCredentials), errors.Is(err, db.ErrUserLocked), errors.Is(err, db.ErrUserDisabled):
 		return apiErrorJSON(ctx, http.StatusUnauthorized, err.Error())
-	case errors.Is(err, db.ErrUpdateConflict):
+	case errors.Is(err, db.ErrUpdateConflict), errors.Is(err, db.ErrResourceVersionConflict), errors.Is(err, db.ErrFound), errors.Is(err, db.ErrRoleInUse):
 		return apiErrorJSON(ctx, http.StatusConflict, err.Error())
+	case errors.Is(err, db.ErrInvalidResourceVersion), errors.Is(err, db.ErrInvalidRole), errors.Is(err, db.ErrExternalUser):
+		return apiErrorJSON(ctx, http.StatusBadRequest, err.Error())
+	case errors.Is(err, db.ErrBuiltinRole):
+		return apiErrorJSON(ctx, http.StatusForbidden, err.Error())
 	default:
 		return apiErrorJSON(ctx, http.StatusInternalServerError, err.Error())
 	}
@@ -96,6 +101,14 @@ func (s *Server) requireBearerAuth(ctx echo.Context) (api.User, api.ApiKey, stri
 	if !ok {
 		return api.User{}, api.ApiKey{}, "", apiErrorJSON(ctx, http.StatusUnauthorized, "missing or invalid Authorization header")
 	}
+	// OIDC の ID トークンは API キーを持たない
+	if provider := currentOIDCProvider(); provider != nil && looksLikeJWT(token) {
+		user, err := s.authenticateIDToken(ctx.Request().Context(), provider, token, false)
+		if err != nil {
+			return api.User{}, api.ApiKey{}, "", mapAuthDBError(ctx, err)
+		}
+		return user, api.ApiKey{}, token, nil
+	}
 	user, key, err := s.Ma.Db.AuthenticateApiKey(token)
 	if err != nil {
 		return api.User{}, api.ApiKey{}, "", mapAuthDBError(ctx, err)
@@ -113,17 +126,24 @@ func (s *Server) ApiAuthLogin(ctx echo.Context) error {
 	if userID == "" || password == "" {
 		return apiErrorJSON(ctx, http.StatusBadRequest, "userId and password are required")
 	}
+	// 失敗したログインも試みた利用者で監査記録に残す
+	ctx.Set(authUserContextKey, userID)
 
 	user, err := s.Ma.Db.AuthenticateUser(userID, password)
 	if err != nil {
 		return mapLoginError(ctx, err)
 	}
+	return s.issueLoginSession(ctx, user)
+}
+
+// issueLoginSession は認証した利用者のログインセッションの API キーを発行して応答する
+func (s *Server) issueLoginSession(ctx echo.Context, user api.User) error {
 	sessionType := db.ApiKeySessionTypeLogin
 	createReq := api.ApiKeyCreateRequest{Comment: util.StringPtr("login-session"), SessionType: &sessionType}
 	if fromIP := sourceIPFromContext(ctx); fromIP != "" {
 		createReq.FromIP = &fromIP
 	}
-	apiKey, rawToken, err := s.Ma.Db.CreateUserApiKey(userID, createReq)
+	apiKey, rawToken, err := s.Ma.Db.CreateUserApiKey(user.Metadata.Id, createReq)
 	if err != nil {
 		return mapAuthDBError(ctx, err)
 	}
@@ -149,15 +169,59 @@ func (s *Server) ApiAuthLogin(ctx echo.Context) error {
 	if idleExpiresIn > 0 {
 		resp.ExpiresIn = &idleExpiresIn
 	}
+	ctx.Set(authApiKeyContextKey, apiKey.Metadata.Id)
 	ctx.Response().Header().Set("X-Marmot-ApiKey-Id", apiKey.Metadata.Id)
 	return ctx.JSON(http.StatusOK, resp)
 }
 
+// ApiAuthOidcConfig returns the settings that mactl login --oidc uses for the authorization code flow.
+func (s *Server) ApiAuthOidcConfig(ctx echo.Context) error {
+	provider := currentOIDCProvider()
+	if provider == nil {
+		return apiErrorJSON(ctx, http.StatusNotFound, "OIDC login is not configured")
+	}
+	return ctx.JSON(http.StatusOK, api.AuthOidcConfig{
+		Issuer:   provider.cfg.Issuer,
+		ClientId: provider.cfg.ClientID,
+		Scopes:   provider.cfg.Scopes,
+	})
+}
+
+// ApiAuthOidcLogin exchanges an ID token from the OIDC identity provider for a login session.
+func (s *Server) ApiAuthOidcLogin(ctx echo.Context) error {
+	provider := currentOIDCProvider()
+	if provider == nil {
+		return apiErrorJSON(ctx, http.StatusNotFound, "OIDC login is not configured")
+	}
+	var req api.AuthOidcLoginRequest
+	if err := ctx.Bind(&req); err != nil {
+		return apiErrorJSON(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	if strings.TrimSpace(req.IdToken) == "" {
+		return apiErrorJSON(ctx, http.StatusBadRequest, "idToken is required")
+	}
+
+	user, err := s.authenticateIDToken(ctx.Request().Context(), provider, req.IdToken, true)
+	if err != nil {
+		slog.Info("OIDC login failed", "err", err)
+		return mapLoginError(ctx, err)
+	}
+	ctx.Set(authUserContextKey, user.Metadata.Id)
+	if err := s.Ma.Db.RecordUserLogin(user.Metadata.Id); err != nil {
+		slog.Warn("ApiAuthOidcLogin() login stamp update failed", "err", err, "userId", user.Metadata.Id)
+	}
+	return s.issueLoginSession(ctx, user)
+}
+
 func (s *Server) ApiAuthLogout(ctx echo.Context) error {
 	user, key, _, err := s.requireBearerAuth(ctx)
 	if err != nil {
 		return err
 	}
+	if key.Metadata.Id == "" {
+		// ID トークンは ID プロバイダーが失効させる
+		return ctx.NoContent(http.StatusNoContent)
+	}
 	if err := s.Ma.Db.DeleteUserApiKey(user.Metadata.Id, key.Metadata.Id); err != nil {
 		if errors.Is(err, db.ErrNotFound) {
 			return ctx.NoContent(http.StatusNoContent)
@@ -208,7 +272,15 @@ func (s *Server) ApiAuthzCheck(ctx echo.Context) error {
 	if strings.TrimSpace(checkUserID) != strings.TrimSpace(user.Metadata.Id) && !userHasAnyRole(user, []string{"Administrator"}) {
 		return apiErrorJSON(ctx, http.StatusForbidden, "forbidden")
 	}
-	allowed, err := s.Ma.Db.Authorize(checkUserID, resource, action)
+	var allowed bool
+	if target, ok, targetErr := s.authzCheckTarget(req); targetErr != nil {
+		return mapAuthDBError(ctx, targetErr)
+	} else if ok {
+		// 対象のリソースを指定した場合はプロジェクトのメンバーとしてのロールとラベルの条件も評価する
+		allowed, err = s.Ma.Db.AuthorizeResource(checkUserID, resource, action, target)
+	} else {
+		allowed, err = s.Ma.Db.Authorize(checkUserID, resource, action)
+	}
 	if err != nil {
 		return mapAuthDBError(ctx, err)
 	}
@@ -220,6 +292,45 @@ func (s *Server) ApiAuthzCheck(ctx echo.Context) error {
 	return ctx.JSON(http.StatusOK, resp)
 }
 
+// 認可の確認で resourceId から metadata を求めるリソースの種類
+var authzCheckResourcePrefixes = map[string]string{
+	"Server":                  db.ServerPrefix,
+	"Volume":                  db.VolumePrefix,
+	"Network":                 db.NetworkPrefix,
+	"ApplicationLoadBalancer": db.LoadBalancerPrefix,
+	"NetworkLoadBalancer":     db.NetworkLoadBalancerPrefix,
+	"Certificate":             db.CertificatePrefix,
+}
+
+// authzCheckTarget は認可の確認の対象のリソースの metadata を返す。
+// resourceId の指定があればリソースの metadata を使い、project と labels の指定で上書きする。
+// いずれも指定がない場合は false を返す
+func (s *Server) authzCheckTarget(req api.AuthzCheckRequest) (api.Metadata, bool, error) {
+	var meta api.Metadata
+	found := false
+	resourceID := strings.TrimSpace(util.OrDefault(req.ResourceId, ""))
+	if prefix, ok := authzCheckResourcePrefixes[strings.TrimSpace(req.Resource)]; ok && resourceID != "" {
+		var err error
+		if meta, err = s.lookupResourceMetadata(prefix, resourceID); err != nil {
+			return api.Metadata{}, false, err
+		}
+		found = true
+	}
+	if project := strings.TrimSpace(util.OrDefault(req.Project, "")); project != "" {
+		meta.Project = &project
+		found = true
+	}
+	if req.Labels != nil {
+		labels := make(map[string]interface{}, len(*req.Labels))
+		for k, v := range *req.Labels {
+			labels[k] = v
+		}
+		meta.Labels = &labels
+		found = true
+	}
+	return meta, found, nil
+}
+
 func (s *Server) ApiListRoles(ctx echo.Context) error {
 	if _, _, _, err := s.requireBearerAuth(ctx); err != nil {
 		return err
@@ -242,6 +353,47 @@ func (s *Server) ApiGetRoleByName(ctx echo.Context, roleName string) error {
 	return ctx.JSON(http.StatusOK, role)
 }
 
+func (s *Server) ApiCreateRole(ctx echo.Context) error {
+	if _, _, _, err := s.requireBearerAuth(ctx); err != nil {
+		return err
+	}
+	var input api.Role
+	if err := ctx.Bind(&input); err != nil {
+		return apiErrorJSON(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	input.Metadata.Owner = requestOwner(ctx)
+	role, err := s.Ma.Db.CreateRole(input)
+	if err != nil {
+		return mapAuthDBError(ctx, err)
+	}
+	return ctx.JSON(http.StatusCreated, role)
+}
+
+func (s *Server) ApiUpdateRoleByName(ctx echo.Context, roleName string) error {
+	if _, _, _, err := s.requireBearerAuth(ctx); err != nil {
+		return err
+	}
+	var input api.Role
+	if err := ctx.Bind(&input); err != nil {
+		return apiErrorJSON(ctx, http.StatusBadRequest, "invalid request body")
+	}
+	role, err := s.Ma.Db.UpdateRoleByName(roleName, input)
+	if err != nil {
+		return mapAuthDBError(ctx, err)
+	}
+	return ctx.JSON(http.StatusOK, role)
+}
+
+func (s *Server) ApiDeleteRoleByName(ctx echo.Context, roleName string) error {
+	if _, _, _, err := s.requireBearerAuth(ctx); err != nil {
+		return err
+	}
+	if err := s.Ma.Db.DeleteRoleByName(roleName); err != nil {
+		return mapAuthDBError(ctx, err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
 func (s *Server) ApiListUsers(ctx echo.Context) error {
 	if _, _, _, err := s.requireBearerAuth(ctx); err != nil {
 		return err
@@ -300,7 +452,8 @@ func (s *Server) ApiUpdateUserById(ctx echo.Context, userId string) error {
 	if err := ctx.Bind(&input); err != nil {
 		return apiErrorJSON(ctx, http.StatusBadRequest, "invalid request body")
 	}
-	if err := s.Ma.Db.UpdateUser(userId, input); err != nil {
+	resourceVersion := db.TakeResourceVersion(&input.Metadata)
+	if err := s.Ma.Db.UpdateUserWithResourceVersion(userId, input, resourceVersion); err != nil {
 		return mapAuthDBError(ctx, err)
 	}
 	user, err := s.Ma.Db.GetUserById(userId)
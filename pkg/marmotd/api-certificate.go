@@ -0,0 +1,105 @@
+package marmotd
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// publicCertificate は API の応答から秘密鍵を取り除く
+func publicCertificate(cert api.Certificate) api.Certificate {
+	cert.PrivateKey = nil
+	return cert
+}
+
+// certificateMetadata は証明書のプロジェクトでの認可と絞り込みに使う metadata を返す
+func certificateMetadata(cert api.Certificate) api.Metadata {
+	return api.Metadata{Id: util.OrDefault(cert.Id, ""), Name: cert.Name, Project: cert.Project}
+}
+
+func certificateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrInvalidCertificate):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrFound), errors.Is(err, db.ErrCertificateInUse):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// ApiGetCertificates lists TLS certificates without private keys.
+func (s *Server) ApiGetCertificates(ctx echo.Context, params api.ApiGetCertificatesParams) error {
+	certs, err := s.Ma.Db.GetCertificates()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	certs = filterByProject(certs, certificateMetadata, projectVisible(ctx, params.Project))
+	for i := range certs {
+		certs[i] = publicCertificate(certs[i])
+	}
+	return ctx.JSON(http.StatusOK, certs)
+}
+
+// ApiCreateCertificate registers a TLS certificate and its private key.
+func (s *Server) ApiCreateCertificate(ctx echo.Context) error {
+	var cert api.Certificate
+	if err := ctx.Bind(&cert); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+	meta := certificateMetadata(cert)
+	if err := s.assignProject(&meta); err != nil {
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	cert.Project = meta.Project
+
+	created, err := s.Ma.Db.CreateCertificate(cert)
+	if err != nil {
+		return ctx.JSON(certificateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusCreated, publicCertificate(created))
+}
+
+// ApiGetCertificateById returns one TLS certificate without its private key.
+func (s *Server) ApiGetCertificateById(ctx echo.Context, id string) error {
+	cert, err := s.Ma.Db.GetCertificateById(id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, publicCertificate(cert))
+}
+
+// ApiUpdateCertificateById replaces a TLS certificate and its private key.
+// Application load balancers using the certificate pick up the new one on their next reconcile.
+func (s *Server) ApiUpdateCertificateById(ctx echo.Context, id string) error {
+	var cert api.Certificate
+	if err := ctx.Bind(&cert); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+
+	updated, err := s.Ma.Db.UpdateCertificateById(id, cert)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(certificateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, publicCertificate(updated))
+}
+
+// ApiDeleteCertificateById deletes a TLS certificate that no load balancer uses.
+func (s *Server) ApiDeleteCertificateById(ctx echo.Context, id string) error {
+	if err := s.Ma.Db.DeleteCertificateById(id); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(certificateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, api.Success{Id: id, Message: util.StringPtr("certificate deleted")})
+}
@@ -216,6 +216,10 @@ func NewServerWithOptions(node string, etcdurl string, skipPreflight bool) *Serv
 		slog.Error("Failed to seed bootstrap admin user", "err", err)
 		os.Exit(1)
 	}
+	if err := marmotInstance.Db.EnsureDefaultProject(); err != nil {
+		slog.Error("Failed to create the default project", "err", err)
+		os.Exit(1)
+	}
 	s := &Server{
 		Ma: marmotInstance,
 	}
@@ -252,6 +256,8 @@ func (s *Server) startRevokedAPIKeyCleanupWorker() {
 		defer revokeTicker.Stop()
 		ticker := time.NewTicker(revokedAPIKeyCleanupInterval)
 		defer ticker.Stop()
+		auditTicker := time.NewTicker(auditRecordCleanupInterval)
+		defer auditTicker.Stop()
 
 		slog.Debug("Started API key maintenance worker", "revokeInterval", idleLoginSessionRevokeInterval.String(), "cleanupInterval", revokedAPIKeyCleanupInterval.String(), "deleteAfter", revokedAPIKeyPhysicalDeleteAfter.String())
 
@@ -277,6 +283,16 @@ func (s *Server) startRevokedAPIKeyCleanupWorker() {
 				if deleted > 0 {
 					slog.Info("Revoked API key cleanup completed", "deleted", deleted)
 				}
+			case <-auditTicker.C:
+				// 保持期間を過ぎた監査記録を削除する
+				deleted, err := s.Ma.Db.DeleteAuditRecordsBefore(time.Now().Add(-CurrentConfig().AuditRetention()))
+				if err != nil {
+					slog.Warn("Audit record cleanup failed", "err", err)
+					continue
+				}
+				if deleted > 0 {
+					slog.Info("Audit record cleanup completed", "deleted", deleted)
+				}
 			}
 		}
 	}()
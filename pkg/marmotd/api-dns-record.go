@@ -0,0 +1,60 @@
+package marmotd
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// ApiGetDnsRecords lists custom records of the internal DNS.
+func (s *Server) ApiGetDnsRecords(ctx echo.Context) error {
+	records, err := s.Ma.Db.GetDnsRecords()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, records)
+}
+
+// ApiCreateDnsRecord registers a custom record to the internal DNS.
+func (s *Server) ApiCreateDnsRecord(ctx echo.Context) error {
+	var record api.DnsRecord
+	if err := ctx.Bind(&record); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+
+	created, err := s.Ma.Db.CreateDnsRecord(record)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidDnsRecord) {
+			return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusCreated, created)
+}
+
+// ApiGetDnsRecordById returns one custom record by ID.
+func (s *Server) ApiGetDnsRecordById(ctx echo.Context, id string) error {
+	record, err := s.Ma.Db.GetDnsRecordById(id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, record)
+}
+
+// ApiDeleteDnsRecordById deletes one custom record by ID.
+func (s *Server) ApiDeleteDnsRecordById(ctx echo.Context, id string) error {
+	if err := s.Ma.Db.DeleteDnsRecordById(id); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, api.Success{Id: id, Message: util.StringPtr("DNS record deleted")})
+}
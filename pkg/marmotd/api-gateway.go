@@ -30,6 +30,16 @@ func (s *Server) ApiCreateGateway(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
 	}
 
+	if err := s.assignProject(&gateway.Metadata); err != nil {
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	gateway.Metadata.Owner = requestOwner(ctx)
+	release, err := s.reserveQuota(requestUserID(ctx), db.ProjectOf(gateway.Metadata), quotaAmount{PublicIps: 1})
+	if err != nil {
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	defer release()
+
 	created, err := s.Ma.Db.CreateGateway(gateway)
 	if err != nil {
 		if errors.Is(err, db.ErrFound) {
@@ -76,6 +86,7 @@ func (s *Server) ApiUpdateGatewayById(ctx echo.Context, id string) error {
 	if err := ctx.Bind(&req); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
 	}
+	resourceVersion := db.TakeResourceVersion(&req.Metadata)
 
 	if err := normalizeGatewaySpec(&req.Spec); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
@@ -89,8 +100,8 @@ func (s *Server) ApiUpdateGatewayById(ctx echo.Context, id string) error {
 	current.Spec.ServerPorts = req.Spec.ServerPorts
 	current.Spec.RemoteCIDR = req.Spec.RemoteCIDR
 	current.Spec.RemoteCIDRs = req.Spec.RemoteCIDRs
-	if err := s.Ma.Db.UpdateGatewayById(id, current); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	if err := s.Ma.Db.UpdateGatewayByIdWithResourceVersion(id, current, resourceVersion); err != nil {
+		return ctx.JSON(updateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
 	}
 
 	return ctx.JSON(http.StatusOK, api.Success{Id: id, Message: util.StringPtr("Accepted the request to update the gateway")})
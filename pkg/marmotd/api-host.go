@@ -1,12 +1,14 @@
 package marmotd
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
 	"github.com/takara9/marmot/pkg/util"
 )
 
@@ -66,3 +68,74 @@ func (s *Server) ApiGetMarmotCluster(ctx echo.Context) error {
 
 	return ctx.JSON(http.StatusOK, annotateIscsiServerStatuses(statuses))
 }
+
+// ノードの存在を確認し、存在しなければ 404 を返す
+func (s *Server) requireClusterNode(ctx echo.Context, nodeName string) (bool, error) {
+	if _, err := s.Ma.Db.GetHostStatus(nodeName); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return false, ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "ノードが存在しません"})
+		}
+		slog.Error("GetHostStatus() failed", "err", err, "nodeName", nodeName)
+		return false, ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return true, nil
+}
+
+// ノードを cordon してスケジュール対象外にするAPIハンドラー
+func (s *Server) ApiCordonNode(ctx echo.Context, nodeName string) error {
+	slog.Debug("===ApiCordonNode() is called===", "nodeName", nodeName)
+
+	var req api.NodeMaintenance
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+	if ok, err := s.requireClusterNode(ctx, nodeName); !ok {
+		return err
+	}
+
+	rec, err := s.Ma.Db.CordonNode(nodeName, util.OrDefault(req.Reason, ""))
+	if err != nil {
+		slog.Error("CordonNode() failed", "err", err, "nodeName", nodeName)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, rec)
+}
+
+// ノードをスケジュール対象に戻すAPIハンドラー
+func (s *Server) ApiUncordonNode(ctx echo.Context, nodeName string) error {
+	slog.Debug("===ApiUncordonNode() is called===", "nodeName", nodeName)
+
+	if ok, err := s.requireClusterNode(ctx, nodeName); !ok {
+		return err
+	}
+	if err := s.Ma.Db.UncordonNode(nodeName); err != nil {
+		slog.Error("UncordonNode() failed", "err", err, "nodeName", nodeName)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// ノードをドレインするAPIハンドラー
+// 稼働中のサーバーの移行または停止は、対象ノードのサーバーコントローラーが実施する
+func (s *Server) ApiDrainNode(ctx echo.Context, nodeName string) error {
+	slog.Debug("===ApiDrainNode() is called===", "nodeName", nodeName)
+
+	var req api.NodeMaintenance
+	if err := ctx.Bind(&req); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+	action := strings.TrimSpace(util.OrDefault(req.DrainAction, db.DRAIN_ACTION_MIGRATE))
+	if action != db.DRAIN_ACTION_MIGRATE && action != db.DRAIN_ACTION_STOP {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "drainAction は migrate または stop を指定してください"})
+	}
+	if ok, err := s.requireClusterNode(ctx, nodeName); !ok {
+		return err
+	}
+
+	rec, err := s.Ma.Db.DrainNode(nodeName, action, util.OrDefault(req.Reason, ""))
+	if err != nil {
+		slog.Error("DrainNode() failed", "err", err, "nodeName", nodeName)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusAccepted, rec)
+}
@@ -59,6 +59,7 @@ type archiveMetaJSON struct {
 	Name      string `json:"name"`
 	OsName    string `json:"osName,omitempty"`
 	OsVersion string `json:"osVersion,omitempty"`
+	Firmware  string `json:"firmware,omitempty"`
 }
 
 func extractFromTGZ(src io.Reader, destDir string, meta *archiveMetaJSON) (string, error) {
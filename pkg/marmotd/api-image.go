@@ -47,6 +47,15 @@ func (s *Server) ApiCreateImage(ctx echo.Context) error {
 		slog.Error("ApiCreateImage()", "err", err)
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
 	}
+	if err := validateImageFirmwareSpec(&imageSpec.Spec); err != nil {
+		slog.Error("ApiCreateImage()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+	if err := s.assignProject(&imageSpec.Metadata); err != nil {
+		slog.Error("ApiCreateImage()", "err", err)
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	imageSpec.Metadata.Owner = requestOwner(ctx)
 	assignNodeNameIfUnset(&imageSpec.Metadata, s.Ma.NodeName)
 	assignedNodeName := ""
 	if imageSpec.Metadata.NodeName != nil {
@@ -54,21 +63,41 @@ func (s *Server) ApiCreateImage(ctx echo.Context) error {
 	}
 	slog.Debug("Received post body", "imageSpec", imageSpec, "sourceUrl", *imageSpec.Spec.SourceUrl, "nodeName", assignedNodeName)
 
+	// ダウンロードは時間がかかるため、進捗とキャンセルをジョブで追跡する
+	jobs := s.Ma.Jobs()
+	jobId, err := jobs.EntryResourceJob("image-download", assignedNodeName, "Image")
+	if err != nil {
+		slog.Error("ApiCreateImage() EntryResourceJob failed", "err", err)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if imageSpec.Status == nil {
+		imageSpec.Status = &api.Status{}
+	}
+	imageSpec.Status.JobId = util.StringPtr(jobId)
+
 	id, err := s.Ma.Db.MakeImageEntryFromSpec(imageSpec)
 	if err != nil {
 		slog.Error("ApiCreateImage()", "err", err)
+		_ = jobs.CancelJob(jobId)
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
-	slog.Debug("ApiCreateImage()", "Image Id", id)
+	if err := jobs.SetJobResource(jobId, id, ""); err != nil {
+		slog.Warn("ApiCreateImage() SetJobResource failed", "err", err, "jobId", jobId, "imageId", id)
+	}
+	slog.Debug("ApiCreateImage()", "Image Id", id, "Job Id", jobId)
 
 	var resp api.Success
 	resp.Id = id
+	resp.JobId = util.StringPtr(jobId)
 	resp.Message = util.StringPtr("Image created successfully")
 	return ctx.JSON(http.StatusOK, resp)
 }
 
-func (s *Server) ApiGetImages(ctx echo.Context) error {
+func (s *Server) ApiGetImages(ctx echo.Context, params api.ApiGetImagesParams) error {
 	slog.Debug("===", "ApiGetImages() is called", "===")
+	if isWatchRequest(params.Watch) {
+		return s.watchResources(ctx, db.ImagePrefix, params.ResourceVersion, projectVisible(ctx, params.Project))
+	}
 	var imageSpec api.Image
 	if err := ctx.Bind(&imageSpec); err != nil {
 		slog.Error("ApiGetImages()", "err", err)
@@ -82,6 +111,7 @@ func (s *Server) ApiGetImages(ctx echo.Context) error {
 		slog.Error("ApiGetImages()", "err", err)
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
+	recs = filterByProject(recs, func(r api.Image) api.Metadata { return r.Metadata }, projectVisible(ctx, params.Project))
 
 	return ctx.JSON(http.StatusOK, recs)
 }
@@ -158,11 +188,16 @@ func (s *Server) ApiUpdateImageById(ctx echo.Context, id string) error {
 		slog.Error("ApiUpdateImageById()", "err", err)
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
 	}
+	if err := validateImageFirmwareSpec(&imageSpec.Spec); err != nil {
+		slog.Error("ApiUpdateImageById()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
 
-	err := s.Ma.UpdateImageManage(id, imageSpec)
+	resourceVersion := db.TakeResourceVersion(&imageSpec.Metadata)
+	err := s.Ma.UpdateImageManage(id, imageSpec, resourceVersion)
 	if err != nil {
 		slog.Error("ApiUpdateImageById()", "err", err)
-		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+		return ctx.JSON(updateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
 	}
 
 	var resp api.Success
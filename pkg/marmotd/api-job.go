@@ -0,0 +1,151 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// 他ノードから転送されたリクエストであることを示すヘッダー、再転送を防ぐ
+const jobLogForwardedHeader = "X-Marmot-Forwarded"
+
+var jobLogForwardClient = &http.Client{Timeout: 30 * time.Second}
+
+// ジョブの一覧を取得
+func (s *Server) ApiGetJobs(ctx echo.Context) error {
+	slog.Debug("===ApiGetJobs() is called===")
+	jobs, err := s.Ma.Jobs().GetAllJobs()
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		slog.Error("ApiGetJobs()", "err", err)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if jobs == nil {
+		jobs = []api.Job{}
+	}
+	return ctx.JSON(http.StatusOK, jobs)
+}
+
+// ジョブの詳細を取得
+func (s *Server) ApiGetJobById(ctx echo.Context, id string) error {
+	slog.Debug("===ApiGetJobById() is called===", "id", id)
+	job, err := s.Ma.Jobs().GetJob(id)
+	if err != nil {
+		slog.Error("ApiGetJobById()", "err", err, "id", id)
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, job)
+}
+
+// ジョブのキャンセル
+// 実行中のジョブは、実行しているノードが監視していて処理を中断する
+func (s *Server) ApiCancelJobById(ctx echo.Context, id string) error {
+	slog.Debug("===ApiCancelJobById() is called===", "id", id)
+	if err := s.Ma.Jobs().CancelJob(id); err != nil {
+		slog.Error("ApiCancelJobById()", "err", err, "id", id)
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		case errors.Is(err, db.ErrJobFinished):
+			return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "ジョブは終了しています"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, api.Success{Id: id, JobId: util.StringPtr(id), Message: util.StringPtr("Job canceled")})
+}
+
+// ジョブログの取得
+// ジョブログは実行したノードにあるため、他ノードのジョブはそのノードの API へ転送する
+func (s *Server) ApiGetJobLogById(ctx echo.Context, id string, params api.ApiGetJobLogByIdParams) error {
+	slog.Debug("===ApiGetJobLogById() is called===", "id", id)
+	job, err := s.Ma.Jobs().GetJob(id)
+	if err != nil {
+		slog.Error("ApiGetJobLogById()", "err", err, "id", id)
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	lines := 0
+	if params.Lines != nil {
+		if *params.Lines < 0 {
+			return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "lines must be zero or positive"})
+		}
+		lines = *params.Lines
+	}
+
+	nodeName := ""
+	if job.Metadata != nil && job.Metadata.NodeName != nil {
+		nodeName = strings.TrimSpace(*job.Metadata.NodeName)
+	}
+	if nodeName != "" && nodeName != s.Ma.NodeName && ctx.Request().Header.Get(jobLogForwardedHeader) == "" {
+		return s.forwardJobLog(ctx, nodeName, id, lines)
+	}
+
+	data, err := s.Ma.Jobs().ReadJobLog(id, lines)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// 開始前のジョブにはログが無い
+			return ctx.Blob(http.StatusOK, "text/plain; charset=utf-8", []byte{})
+		}
+		slog.Error("ApiGetJobLogById() ReadJobLog failed", "err", err, "id", id)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.Blob(http.StatusOK, "text/plain; charset=utf-8", data)
+}
+
+func (s *Server) forwardJobLog(ctx echo.Context, nodeName, id string, lines int) error {
+	status, err := s.Ma.Db.GetHostStatus(nodeName)
+	if err != nil || status.IpAddress == nil || strings.TrimSpace(*status.IpAddress) == "" {
+		slog.Error("forwardJobLog() node address is unknown", "err", err, "nodeName", nodeName)
+		return ctx.JSON(http.StatusBadGateway, api.Error{Code: 1, Message: fmt.Sprintf("ジョブを実行したノード %s のアドレスが不明です", nodeName)})
+	}
+
+	u, err := url.Parse(NodeAPIBaseURL(strings.TrimSpace(*status.IpAddress)) + "/job/" + url.PathEscape(id) + "/log")
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if lines > 0 {
+		u.RawQuery = url.Values{"lines": []string{strconv.Itoa(lines)}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx.Request().Context(), http.MethodGet, u.String(), nil)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	req.Header.Set(jobLogForwardedHeader, s.Ma.NodeName)
+	if auth := ctx.Request().Header.Get("Authorization"); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := jobLogForwardClient.Do(req)
+	if err != nil {
+		slog.Error("forwardJobLog() request failed", "err", err, "nodeName", nodeName, "url", u.String())
+		return ctx.JSON(http.StatusBadGateway, api.Error{Code: 1, Message: err.Error()})
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ctx.JSON(http.StatusBadGateway, api.Error{Code: 1, Message: err.Error()})
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	return ctx.Blob(resp.StatusCode, contentType, body)
+}
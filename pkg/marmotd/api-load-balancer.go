@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/miekg/dns"
 	"github.com/takara9/marmot/api"
 	"github.com/takara9/marmot/pkg/db"
 	"github.com/takara9/marmot/pkg/util"
@@ -23,6 +25,18 @@ func (s *Server) ApiCreateLoadBalancer(ctx echo.Context) error {
 	if err := normalizeLoadBalancerResource(&rec); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
 	}
+	if err := s.assignProject(&rec.Metadata); err != nil {
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	if err := s.checkLoadBalancerCertificates(rec.Spec, db.ProjectOf(rec.Metadata)); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+	rec.Metadata.Owner = requestOwner(ctx)
+	release, err := s.reserveQuota(requestUserID(ctx), db.ProjectOf(rec.Metadata), quotaAmount{PublicIps: 1})
+	if err != nil {
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	defer release()
 
 	created, err := s.Ma.Db.CreateLoadBalancer(rec)
 	if err != nil {
@@ -35,11 +49,15 @@ func (s *Server) ApiCreateLoadBalancer(ctx echo.Context) error {
 	return ctx.JSON(http.StatusCreated, created)
 }
 
-func (s *Server) ApiGetLoadBalancers(ctx echo.Context) error {
+func (s *Server) ApiGetLoadBalancers(ctx echo.Context, params api.ApiGetLoadBalancersParams) error {
+	if isWatchRequest(params.Watch) {
+		return s.watchResources(ctx, db.LoadBalancerPrefix, params.ResourceVersion, projectVisible(ctx, params.Project))
+	}
 	items, err := s.Ma.Db.GetLoadBalancers()
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
+	items = filterByProject(items, func(r api.ApplicationLoadBalancer) api.Metadata { return r.Metadata }, projectVisible(ctx, params.Project))
 	return ctx.JSON(http.StatusOK, items)
 }
 
@@ -67,17 +85,21 @@ func (s *Server) ApiUpdateLoadBalancerById(ctx echo.Context, id string) error {
 	if err := ctx.Bind(&req); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
 	}
+	resourceVersion := db.TakeResourceVersion(&req.Metadata)
 	if err := normalizeLoadBalancerSpec(&req.Spec); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
 	}
+	if err := s.checkLoadBalancerCertificates(req.Spec, db.ProjectOf(current.Metadata)); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
 	if changedImmutableLoadBalancerField(current, req) {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "immutable fields changed: spec.bindPublicIpAddress, spec.internalVirtualNetwork"})
 	}
 
 	current.Spec.Listeners = req.Spec.Listeners
 	current.Spec.RemoteCIDR = req.Spec.RemoteCIDR
-	if err := s.Ma.Db.UpdateLoadBalancerById(id, current); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	if err := s.Ma.Db.UpdateLoadBalancerByIdWithResourceVersion(id, current, resourceVersion); err != nil {
+		return ctx.JSON(updateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
 	}
 
 	return ctx.JSON(http.StatusOK, api.Success{Id: id, Message: util.StringPtr("Accepted the request to update the load balancer")})
@@ -167,9 +189,10 @@ func normalizeLoadBalancerSpec(spec *api.ApplicationLoadBalancerSpec) error {
 		seenNames[listener.Name] = struct{}{}
 
 		listener.Protocol = strings.ToUpper(strings.TrimSpace(listener.Protocol))
-		if listener.Protocol != "HTTP" && listener.Protocol != "TCP" && listener.Protocol != "UDP" {
-			return fmt.Errorf("spec.listeners[%d].protocol must be one of HTTP/TCP/UDP", index)
+		if listener.Protocol != "HTTP" && listener.Protocol != "HTTPS" && listener.Protocol != "TCP" && listener.Protocol != "UDP" {
+			return fmt.Errorf("spec.listeners[%d].protocol must be one of HTTP/HTTPS/TCP/UDP", index)
 		}
+		httpMode := listener.Protocol == "HTTP" || listener.Protocol == "HTTPS"
 		if listener.VipPort < 1 || listener.VipPort > 65535 {
 			return fmt.Errorf("spec.listeners[%d].vipPort must be between 1 and 65535", index)
 		}
@@ -201,8 +224,8 @@ func normalizeLoadBalancerSpec(spec *api.ApplicationLoadBalancerSpec) error {
 				trimmedPath := strings.TrimSpace(*listener.HealthCheck.Path)
 				listener.HealthCheck.Path = &trimmedPath
 			}
-			if listener.HealthCheck.Enabled && listener.Protocol != "HTTP" {
-				return fmt.Errorf("spec.listeners[%d].healthCheck can be enabled only for HTTP listeners", index)
+			if listener.HealthCheck.Enabled && !httpMode {
+				return fmt.Errorf("spec.listeners[%d].healthCheck can be enabled only for HTTP/HTTPS listeners", index)
 			}
 		}
 		if listener.SessionPersistence != nil {
@@ -210,11 +233,167 @@ func normalizeLoadBalancerSpec(spec *api.ApplicationLoadBalancerSpec) error {
 				trimmedCookieName := strings.TrimSpace(*listener.SessionPersistence.CookieName)
 				listener.SessionPersistence.CookieName = &trimmedCookieName
 			}
-			if listener.SessionPersistence.Enabled && listener.Protocol != "HTTP" {
-				return fmt.Errorf("spec.listeners[%d].sessionPersistence can be enabled only for HTTP listeners", index)
+			if listener.SessionPersistence.Enabled && !httpMode {
+				return fmt.Errorf("spec.listeners[%d].sessionPersistence can be enabled only for HTTP/HTTPS listeners", index)
 			}
 		}
+
+		if err := normalizeLoadBalancerListenerTLS(listener, index); err != nil {
+			return err
+		}
+		if err := normalizeLoadBalancerListenerRules(listener, index); err != nil {
+			return err
+		}
 	}
 
+	for index, listener := range spec.Listeners {
+		if listener.RedirectToHttps != nil && *listener.RedirectToHttps && httpsListener(*spec) == nil {
+			return fmt.Errorf("spec.listeners[%d].redirectToHttps requires an HTTPS listener", index)
+		}
+	}
+
+	return nil
+}
+
+// httpsListener はロードバランサーの最初の HTTPS リスナーを返す
+func httpsListener(spec api.ApplicationLoadBalancerSpec) *api.ApplicationLoadBalancerListener {
+	for index := range spec.Listeners {
+		if spec.Listeners[index].Protocol == "HTTPS" {
+			return &spec.Listeners[index]
+		}
+	}
+	return nil
+}
+
+// normalizeLoadBalancerListenerTLS は HTTPS リスナーの証明書と、HTTP リスナーのリダイレクトの指定を検証する
+func normalizeLoadBalancerListenerTLS(listener *api.ApplicationLoadBalancerListener, index int) error {
+	var ids []string
+	if listener.CertificateIds != nil {
+		for _, id := range *listener.CertificateIds {
+			id = strings.TrimSpace(id)
+			if id != "" && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	switch {
+	case listener.Protocol == "HTTPS" && len(ids) == 0:
+		return fmt.Errorf("spec.listeners[%d].certificateIds is required for HTTPS listeners", index)
+	case listener.Protocol != "HTTPS" && len(ids) > 0:
+		return fmt.Errorf("spec.listeners[%d].certificateIds can be set only for HTTPS listeners", index)
+	}
+	if len(ids) > 0 {
+		listener.CertificateIds = &ids
+	} else {
+		listener.CertificateIds = nil
+	}
+	if listener.RedirectToHttps != nil && *listener.RedirectToHttps && listener.Protocol != "HTTP" {
+		return fmt.Errorf("spec.listeners[%d].redirectToHttps can be enabled only for HTTP listeners", index)
+	}
+	return nil
+}
+
+// normalizeLoadBalancerListenerRules はリスナーのルーティングルールを検証する
+// HTTP/HTTPS はホスト名とパス、TCP は TLS の SNI によるホスト名で振り分ける
+func normalizeLoadBalancerListenerRules(listener *api.ApplicationLoadBalancerListener, index int) error {
+	if listener.Rules == nil || len(*listener.Rules) == 0 {
+		listener.Rules = nil
+		return nil
+	}
+	if listener.Protocol == "UDP" {
+		return fmt.Errorf("spec.listeners[%d].rules cannot be set for UDP listeners", index)
+	}
+	seen := make(map[string]struct{}, len(*listener.Rules))
+	for ruleIndex := range *listener.Rules {
+		rule := &(*listener.Rules)[ruleIndex]
+		field := fmt.Sprintf("spec.listeners[%d].rules[%d]", index, ruleIndex)
+		rule.Name = strings.TrimSpace(rule.Name)
+		if rule.Name == "" {
+			return fmt.Errorf("%s.name is required", field)
+		}
+		if _, exists := seen[rule.Name]; exists {
+			return fmt.Errorf("%s.name %q is duplicated", field, rule.Name)
+		}
+		seen[rule.Name] = struct{}{}
+
+		var hosts []string
+		if rule.Hosts != nil {
+			for _, host := range *rule.Hosts {
+				host = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(host), "."))
+				if host == "" {
+					continue
+				}
+				if _, ok := dns.IsDomainName(strings.TrimPrefix(host, "*.")); !ok || strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+					return fmt.Errorf("%s.hosts has an invalid host name %q", field, host)
+				}
+				hosts = append(hosts, host)
+			}
+		}
+		var paths []string
+		if rule.PathPrefixes != nil {
+			for _, path := range *rule.PathPrefixes {
+				path = strings.TrimSpace(path)
+				if path == "" {
+					continue
+				}
+				if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, " \t") {
+					return fmt.Errorf("%s.pathPrefixes must start with / and must not contain spaces: %q", field, path)
+				}
+				paths = append(paths, path)
+			}
+		}
+		if listener.Protocol == "TCP" {
+			if len(paths) > 0 {
+				return fmt.Errorf("%s.pathPrefixes can be set only for HTTP/HTTPS listeners", field)
+			}
+			if len(hosts) == 0 {
+				return fmt.Errorf("%s.hosts is required for TCP listeners", field)
+			}
+		}
+		if len(hosts) == 0 && len(paths) == 0 {
+			return fmt.Errorf("%s must have hosts or pathPrefixes", field)
+		}
+		rule.Hosts = nil
+		if len(hosts) > 0 {
+			rule.Hosts = &hosts
+		}
+		rule.PathPrefixes = nil
+		if len(paths) > 0 {
+			rule.PathPrefixes = &paths
+		}
+
+		if rule.BackendPort != nil && (*rule.BackendPort < 1 || *rule.BackendPort > 65535) {
+			return fmt.Errorf("%s.backendPort must be between 1 and 65535", field)
+		}
+		if len(rule.BackendSelector.MatchLabels) == 0 {
+			return fmt.Errorf("%s.backendSelector.matchLabels is required", field)
+		}
+		for key, value := range rule.BackendSelector.MatchLabels {
+			delete(rule.BackendSelector.MatchLabels, key)
+			rule.BackendSelector.MatchLabels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return nil
+}
+
+// checkLoadBalancerCertificates は HTTPS リスナーが参照する証明書が、ロードバランサーと同じプロジェクトに登録されているかを検査する
+func (s *Server) checkLoadBalancerCertificates(spec api.ApplicationLoadBalancerSpec, project string) error {
+	for index, listener := range spec.Listeners {
+		if listener.CertificateIds == nil {
+			continue
+		}
+		for _, id := range *listener.CertificateIds {
+			cert, err := s.Ma.Db.GetCertificateById(id)
+			if err != nil {
+				if errors.Is(err, db.ErrNotFound) {
+					return fmt.Errorf("spec.listeners[%d].certificateIds: certificate %s is not found", index, id)
+				}
+				return err
+			}
+			if certProject := db.ProjectOf(certificateMetadata(cert)); certProject != project {
+				return fmt.Errorf("spec.listeners[%d].certificateIds: certificate %s belongs to project %q, not %q", index, id, certProject, project)
+			}
+		}
+	}
 	return nil
 }
\ No newline at end of file
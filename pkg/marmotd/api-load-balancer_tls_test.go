@@ -0,0 +1,115 @@
+package marmotd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+)
+
+func newTLSLoadBalancerSpec() *api.ApplicationLoadBalancerSpec {
+	redirect := true
+	return &api.ApplicationLoadBalancerSpec{
+		BindPublicIpAddress:    "192.168.1.120",
+		InternalVirtualNetwork: "web-servers",
+		Listeners: []api.ApplicationLoadBalancerListener{
+			{
+				Name:            "web-http",
+				Protocol:        "http",
+				VipPort:         80,
+				BackendPort:     8080,
+				RedirectToHttps: &redirect,
+				BackendSelector: api.ApplicationLoadBalancerLabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+			{
+				Name:            "web-https",
+				Protocol:        "https",
+				VipPort:         443,
+				BackendPort:     8080,
+				CertificateIds:  &[]string{" c1 ", "c1", "c2"},
+				BackendSelector: api.ApplicationLoadBalancerLabelSelector{MatchLabels: map[string]string{"app": "web"}},
+				Rules: &[]api.ApplicationLoadBalancerRule{{
+					Name:            " api ",
+					Hosts:           &[]string{"API.example.com.", "*.api.example.com"},
+					PathPrefixes:    &[]string{" /v1/ "},
+					BackendSelector: api.ApplicationLoadBalancerLabelSelector{MatchLabels: map[string]string{" app ": " api "}},
+				}},
+			},
+		},
+	}
+}
+
+func TestNormalizeLoadBalancerSpec_HTTPSListener(t *testing.T) {
+	spec := newTLSLoadBalancerSpec()
+	if err := normalizeLoadBalancerSpec(spec); err != nil {
+		t.Fatalf("normalizeLoadBalancerSpec() failed: %v", err)
+	}
+
+	https := spec.Listeners[1]
+	if https.Protocol != "HTTPS" {
+		t.Fatalf("protocol = %q, want HTTPS", https.Protocol)
+	}
+	if len(*https.CertificateIds) != 2 || (*https.CertificateIds)[0] != "c1" {
+		t.Fatalf("certificateIds = %v, want [c1 c2]", *https.CertificateIds)
+	}
+	rule := (*https.Rules)[0]
+	if rule.Name != "api" {
+		t.Fatalf("rule name = %q, want api", rule.Name)
+	}
+	if (*rule.Hosts)[0] != "api.example.com" || (*rule.Hosts)[1] != "*.api.example.com" {
+		t.Fatalf("rule hosts = %v, want lower case names without the trailing dot", *rule.Hosts)
+	}
+	if (*rule.PathPrefixes)[0] != "/v1/" {
+		t.Fatalf("rule pathPrefixes = %v, want [/v1/]", *rule.PathPrefixes)
+	}
+	if rule.BackendSelector.MatchLabels["app"] != "api" {
+		t.Fatalf("rule matchLabels = %v, want trimmed labels", rule.BackendSelector.MatchLabels)
+	}
+}
+
+func TestNormalizeLoadBalancerSpec_RejectsInvalidTLSAndRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(spec *api.ApplicationLoadBalancerSpec)
+		want   string
+	}{
+		{"HTTPS without certificates", func(spec *api.ApplicationLoadBalancerSpec) { spec.Listeners[1].CertificateIds = nil }, "certificateIds is required"},
+		{"certificates on HTTP", func(spec *api.ApplicationLoadBalancerSpec) { spec.Listeners[0].CertificateIds = &[]string{"c1"} }, "only for HTTPS listeners"},
+		{"redirect without HTTPS listener", func(spec *api.ApplicationLoadBalancerSpec) { spec.Listeners = spec.Listeners[:1] }, "requires an HTTPS listener"},
+		{"redirect on HTTPS", func(spec *api.ApplicationLoadBalancerSpec) {
+			spec.Listeners[1].RedirectToHttps = spec.Listeners[0].RedirectToHttps
+		}, "only for HTTP listeners"},
+		{"invalid host", func(spec *api.ApplicationLoadBalancerSpec) {
+			(*spec.Listeners[1].Rules)[0].Hosts = &[]string{"a*.example.com"}
+		}, "invalid host name"},
+		{"relative path", func(spec *api.ApplicationLoadBalancerSpec) {
+			(*spec.Listeners[1].Rules)[0].PathPrefixes = &[]string{"v1"}
+		}, "must start with /"},
+		{"rule without conditions", func(spec *api.ApplicationLoadBalancerSpec) {
+			(*spec.Listeners[1].Rules)[0].Hosts = nil
+			(*spec.Listeners[1].Rules)[0].PathPrefixes = nil
+		}, "must have hosts or pathPrefixes"},
+		{"rule without selector", func(spec *api.ApplicationLoadBalancerSpec) {
+			(*spec.Listeners[1].Rules)[0].BackendSelector.MatchLabels = nil
+		}, "matchLabels is required"},
+		{"path rule on TCP", func(spec *api.ApplicationLoadBalancerSpec) {
+			spec.Listeners[1].Protocol = "TCP"
+			spec.Listeners[1].CertificateIds = nil
+		}, "only for HTTP/HTTPS listeners"},
+		{"rule on UDP", func(spec *api.ApplicationLoadBalancerSpec) {
+			spec.Listeners[0].RedirectToHttps = nil
+			spec.Listeners[1].Protocol = "UDP"
+			spec.Listeners[1].CertificateIds = nil
+		}, "cannot be set for UDP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTLSLoadBalancerSpec()
+			tt.mutate(spec)
+			err := normalizeLoadBalancerSpec(spec)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("normalizeLoadBalancerSpec() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -24,6 +24,16 @@ func (s *Server) ApiCreateNetworkLoadBalancer(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
 	}
 
+	if err := s.assignProject(&rec.Metadata); err != nil {
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	rec.Metadata.Owner = requestOwner(ctx)
+	release, err := s.reserveQuota(requestUserID(ctx), db.ProjectOf(rec.Metadata), quotaAmount{PublicIps: 1})
+	if err != nil {
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	defer release()
+
 	created, err := s.Ma.Db.CreateNetworkLoadBalancer(rec)
 	if err != nil {
 		if errors.Is(err, db.ErrFound) {
@@ -35,11 +45,15 @@ func (s *Server) ApiCreateNetworkLoadBalancer(ctx echo.Context) error {
 	return ctx.JSON(http.StatusCreated, created)
 }
 
-func (s *Server) ApiGetNetworkLoadBalancers(ctx echo.Context) error {
+func (s *Server) ApiGetNetworkLoadBalancers(ctx echo.Context, params api.ApiGetNetworkLoadBalancersParams) error {
+	if isWatchRequest(params.Watch) {
+		return s.watchResources(ctx, db.NetworkLoadBalancerPrefix, params.ResourceVersion, projectVisible(ctx, params.Project))
+	}
 	items, err := s.Ma.Db.GetNetworkLoadBalancers()
 	if err != nil {
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
+	items = filterByProject(items, func(r api.NetworkLoadBalancer) api.Metadata { return r.Metadata }, projectVisible(ctx, params.Project))
 	return ctx.JSON(http.StatusOK, items)
 }
 
@@ -67,6 +81,7 @@ func (s *Server) ApiUpdateNetworkLoadBalancerById(ctx echo.Context, id string) e
 	if err := ctx.Bind(&req); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
 	}
+	resourceVersion := db.TakeResourceVersion(&req.Metadata)
 	if err := normalizeNetworkLoadBalancerSpec(&req.Spec); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
 	}
@@ -76,8 +91,8 @@ func (s *Server) ApiUpdateNetworkLoadBalancerById(ctx echo.Context, id string) e
 
 	current.Spec.Listeners = req.Spec.Listeners
 	current.Spec.RemoteCIDR = req.Spec.RemoteCIDR
-	if err := s.Ma.Db.UpdateNetworkLoadBalancerById(id, current); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	if err := s.Ma.Db.UpdateNetworkLoadBalancerByIdWithResourceVersion(id, current, resourceVersion); err != nil {
+		return ctx.JSON(updateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
 	}
 
 	return ctx.JSON(http.StatusOK, api.Success{Id: id, Message: util.StringPtr("Accepted the request to update the network load balancer")})
@@ -48,6 +48,18 @@ func (s *Server) ApiCreateNetwork(ctx echo.Context) error {
 	}
 	debugPrintln("request body", "body", string(jsonbytes))
 
+	if err := s.assignProject(&spec.Metadata); err != nil {
+		slog.Error("failed to assign project", "err", err)
+		return echo.NewHTTPError(projectErrorStatus(err), err.Error())
+	}
+	spec.Metadata.Owner = requestOwner(ctx)
+	release, err := s.reserveQuota(requestUserID(ctx), db.ProjectOf(spec.Metadata), quotaAmount{Networks: 1})
+	if err != nil {
+		slog.Error("failed to check quota", "err", err)
+		return echo.NewHTTPError(quotaErrorStatus(err), err.Error())
+	}
+	defer release()
+
 	// 仮想ネットワークを作成する
 	network, err := s.Ma.Db.CreateVirtualNetwork(spec)
 	if err != nil {
@@ -60,12 +72,16 @@ func (s *Server) ApiCreateNetwork(ctx echo.Context) error {
 
 // 参照のみ
 // 仮想ネットワーク一覧を取得する
-func (s *Server) ApiGetNetworks(ctx echo.Context) error {
+func (s *Server) ApiGetNetworks(ctx echo.Context, params api.ApiGetNetworksParams) error {
+	if isWatchRequest(params.Watch) {
+		return s.watchResources(ctx, db.NetworkPrefix, params.ResourceVersion, projectVisible(ctx, params.Project))
+	}
 	networks, err := s.Ma.Db.GetVirtualNetworks()
 	if err != nil {
 		slog.Error("failed to get virtual networks", "err", err)
 		return echo.NewHTTPError(500, "failed to get virtual networks")
 	}
+	networks = filterByProject(networks, func(r api.VirtualNetwork) api.Metadata { return r.Metadata }, projectVisible(ctx, params.Project))
 
 	return ctx.JSON(http.StatusOK, networks)
 }
@@ -121,8 +137,12 @@ func (s *Server) ApiUpdateNetworkById(ctx echo.Context, id string) error {
 		return echo.NewHTTPError(400, "invalid request body")
 	}
 
-	if err := s.Ma.Db.UpdateVirtualNetworkById(id, spec); err != nil {
+	resourceVersion := db.TakeResourceVersion(&spec.Metadata)
+	if err := s.Ma.Db.UpdateVirtualNetworkWithResourceVersion(id, spec, resourceVersion); err != nil {
 		slog.Error("failed to update virtual network", "err", err, "networkId", id)
+		if status := updateErrorStatus(err); status != http.StatusInternalServerError {
+			return ctx.JSON(status, api.Error{Code: 1, Message: err.Error()})
+		}
 		return echo.NewHTTPError(500, "failed to update virtual network")
 	}
 
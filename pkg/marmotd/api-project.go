@@ -0,0 +1,154 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func projectErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrInvalidProject):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrProjectInUse), errors.Is(err, db.ErrFound), errors.Is(err, db.ErrResourceVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// canReadAllProjects はクラスタの参照権限ですべてのプロジェクトを参照できるかを返す
+func (s *Server) canReadAllProjects(ctx echo.Context) (bool, error) {
+	return s.Ma.Db.Authorize(requestUserID(ctx), "Cluster", "read")
+}
+
+// ApiGetProjects lists projects. Users without the Cluster read permission see only the projects they are a member of.
+func (s *Server) ApiGetProjects(ctx echo.Context) error {
+	all, err := s.canReadAllProjects(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	var projects []api.Project
+	if all {
+		projects, err = s.Ma.Db.GetProjects()
+	} else {
+		projects, err = s.Ma.Db.GetProjectsForMember(requestUserID(ctx))
+	}
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, projects)
+}
+
+// ApiCreateProject registers a project.
+func (s *Server) ApiCreateProject(ctx echo.Context) error {
+	var project api.Project
+	if err := ctx.Bind(&project); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+	project.Metadata.Owner = requestOwner(ctx)
+
+	created, err := s.Ma.Db.CreateProject(project)
+	if err != nil {
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusCreated, created)
+}
+
+// ApiGetProjectByName returns one project by name.
+func (s *Server) ApiGetProjectByName(ctx echo.Context, projectName string) error {
+	project, err := s.Ma.Db.GetProjectByName(projectName)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "プロジェクトが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	all, err := s.canReadAllProjects(ctx)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if !all && !isProjectMember(project, requestUserID(ctx)) {
+		return ctx.JSON(http.StatusForbidden, api.Error{Code: 1, Message: "forbidden"})
+	}
+	return ctx.JSON(http.StatusOK, project)
+}
+
+// ApiUpdateProjectByName replaces the description and members of a project.
+func (s *Server) ApiUpdateProjectByName(ctx echo.Context, projectName string) error {
+	var project api.Project
+	if err := ctx.Bind(&project); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+
+	updated, err := s.Ma.Db.UpdateProjectByName(projectName, project)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "プロジェクトが存在しません"})
+		}
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, updated)
+}
+
+// ApiDeleteProjectByName deletes a project that has no resources.
+func (s *Server) ApiDeleteProjectByName(ctx echo.Context, projectName string) error {
+	if projectName != db.DefaultProjectName {
+		count, err := s.projectResourceCount(projectName)
+		if err != nil {
+			return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+		}
+		if count > 0 {
+			err := fmt.Errorf("%w: %d resources still belong to project %q", db.ErrProjectInUse, count, projectName)
+			return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: err.Error()})
+		}
+	}
+	if err := s.Ma.Db.DeleteProjectByName(projectName); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "プロジェクトが存在しません"})
+		}
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, api.Success{Id: projectName, Message: util.StringPtr("project deleted")})
+}
+
+// isProjectMember は利用者がプロジェクトのメンバーかを返す
+func isProjectMember(project api.Project, userID string) bool {
+	for _, m := range util.OrDefault(project.Spec.Members, nil) {
+		if m.UserId == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// projectResourceCount はプロジェクトに属するリソースの数を返す
+func (s *Server) projectResourceCount(project string) (int, error) {
+	set, err := s.quotaResources()
+	if err != nil {
+		return 0, err
+	}
+	images, err := s.Ma.Db.GetImages()
+	if err != nil {
+		return 0, err
+	}
+	certs, err := s.Ma.Db.GetCertificates()
+	if err != nil {
+		return 0, err
+	}
+	inProject := func(meta api.Metadata) bool { return db.ProjectOf(meta) == project }
+	count := len(filterByProject(set.Servers, func(r api.Server) api.Metadata { return r.Metadata }, inProject)) +
+		len(filterByProject(set.Volumes, func(r api.Volume) api.Metadata { return r.Metadata }, inProject)) +
+		len(filterByProject(set.Networks, func(r api.VirtualNetwork) api.Metadata { return r.Metadata }, inProject)) +
+		len(filterByProject(set.ApplicationLoadBalancers, func(r api.ApplicationLoadBalancer) api.Metadata { return r.Metadata }, inProject)) +
+		len(filterByProject(set.NetworkLoadBalancers, func(r api.NetworkLoadBalancer) api.Metadata { return r.Metadata }, inProject)) +
+		len(filterByProject(images, func(r api.Image) api.Metadata { return r.Metadata }, inProject)) +
+		len(filterByProject(certs, certificateMetadata, inProject))
+	return count, nil
+}
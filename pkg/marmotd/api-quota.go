@@ -0,0 +1,109 @@
+package marmotd
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func quotaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrInvalidQuota):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrQuotaExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, db.ErrFound):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// ApiGetQuotas lists quotas.
+func (s *Server) ApiGetQuotas(ctx echo.Context) error {
+	quotas, err := s.Ma.Db.GetQuotas()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, quotas)
+}
+
+// ApiCreateQuota registers a quota.
+func (s *Server) ApiCreateQuota(ctx echo.Context) error {
+	var quota api.Quota
+	if err := ctx.Bind(&quota); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+
+	created, err := s.Ma.Db.CreateQuota(quota)
+	if err != nil {
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusCreated, created)
+}
+
+// ApiGetQuotaById returns one quota by ID.
+func (s *Server) ApiGetQuotaById(ctx echo.Context, id string) error {
+	quota, err := s.Ma.Db.GetQuotaById(id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, quota)
+}
+
+// ApiUpdateQuotaById replaces a quota. Resources already created over the new limits are kept.
+func (s *Server) ApiUpdateQuotaById(ctx echo.Context, id string) error {
+	var quota api.Quota
+	if err := ctx.Bind(&quota); err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+
+	updated, err := s.Ma.Db.UpdateQuotaById(id, quota)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, updated)
+}
+
+// ApiDeleteQuotaById deletes a quota.
+func (s *Server) ApiDeleteQuotaById(ctx echo.Context, id string) error {
+	if err := s.Ma.Db.DeleteQuotaById(id); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, api.Success{Id: id, Message: util.StringPtr("quota deleted")})
+}
+
+// ApiGetUserQuotaUsage returns the resource usage of a user and the quotas applied to the user.
+func (s *Server) ApiGetUserQuotaUsage(ctx echo.Context, userId string) error {
+	if _, err := s.Ma.Db.GetUserById(userId); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	quotas, err := s.Ma.Db.GetQuotasForUser(userId)
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	used, err := s.quotaUsage(userId)
+	if err != nil {
+		slog.Error("quotaUsage()", "err", err, "userId", userId)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, api.QuotaUsage{UserId: userId, Used: used.resources(), Quotas: quotas})
+}
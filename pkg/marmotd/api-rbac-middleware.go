@@ -6,17 +6,45 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
 )
 
+// 認証した利用者と API キーを後続のハンドラーと監査記録に渡すためのキー
+const (
+	authUserContextKey   = "marmot.auth.userId"
+	authApiKeyContextKey = "marmot.auth.apiKeyId"
+)
+
+// requestUserID は認証した利用者のIDを返す。認証していない場合は空文字
+func requestUserID(ctx echo.Context) string {
+	userID, _ := ctx.Get(authUserContextKey).(string)
+	return userID
+}
+
 type operationRBACRule struct {
 	Resource      string
 	Verb          string
 	AllowSelf     bool
 	SelfParam     string
 	RequiredRoles []string
+	// Projects は利用者に割り当てたロールで許可されない場合に、プロジェクトのロールとラベルの条件のある権限で認可する対象
+	Projects []projectLookup
 }
 
 func rbacOperationMiddlewares(s *Server) map[string][]echo.MiddlewareFunc {
+	// プロジェクトに属するリソースは、プロジェクトのメンバーとしてのロールでも操作できる
+	inListedProject := []projectLookup{projectFromQuery}
+	inRequestedProject := []projectLookup{projectFromBody}
+	inServerProject := []projectLookup{projectOfResource(db.ServerPrefix, "id")}
+	inServerAndVolumeProject := []projectLookup{projectOfResource(db.ServerPrefix, "id"), projectOfResource(db.VolumePrefix, "volumeId")}
+	inVolumeProject := []projectLookup{projectOfResource(db.VolumePrefix, "volumeId")}
+	inNetworkProject := []projectLookup{projectOfResource(db.NetworkPrefix, "id")}
+	inImageProject := []projectLookup{projectOfResource(db.ImagePrefix, "id")}
+	inLoadBalancerProject := []projectLookup{projectOfResource(db.LoadBalancerPrefix, "id")}
+	inNetworkLoadBalancerProject := []projectLookup{projectOfResource(db.NetworkLoadBalancerPrefix, "id")}
+	inRequestedCertificateProject := []projectLookup{projectFromFlatBody}
+	inCertificateProject := []projectLookup{projectOfResource(db.CertificatePrefix, "id")}
+
 	rules := map[string]operationRBACRule{
 		"apiAuthLogout": {Resource: "", Verb: ""},
 		"apiAuthMe":     {Resource: "", Verb: ""},
@@ -24,34 +52,76 @@ func rbacOperationMiddlewares(s *Server) map[string][]echo.MiddlewareFunc {
 
 		"apiListRoles":     {Resource: "", Verb: ""},
 		"apiGetRoleByName": {Resource: "", Verb: ""},
+		// カスタムロールで権限を広げられないよう、ロールの管理は Administrator ロールに限る
+		"apiCreateRole":       {Resource: "User", Verb: "create", RequiredRoles: []string{"Administrator"}},
+		"apiUpdateRoleByName": {Resource: "User", Verb: "update", RequiredRoles: []string{"Administrator"}},
+		"apiDeleteRoleByName": {Resource: "User", Verb: "delete", RequiredRoles: []string{"Administrator"}},
+
+		"apiGetAuditRecords": {Resource: "Cluster", Verb: "read", RequiredRoles: []string{"Administrator"}},
+
+		// クォータはクラスタの権限で管理し、利用者は自分の使用量を参照できる
+		"apiGetQuotas":         {Resource: "Cluster", Verb: "read"},
+		"apiCreateQuota":       {Resource: "Cluster", Verb: "create"},
+		"apiGetQuotaById":      {Resource: "Cluster", Verb: "read"},
+		"apiUpdateQuotaById":   {Resource: "Cluster", Verb: "update"},
+		"apiDeleteQuotaById":   {Resource: "Cluster", Verb: "delete"},
+		"apiGetUserQuotaUsage": {Resource: "User", Verb: "read", AllowSelf: true, SelfParam: "userId"},
+
+		// プロジェクトはクラスタの権限で管理し、メンバーは自分が属するプロジェクトを参照できる
+		"apiGetProjects":         {Resource: "", Verb: ""},
+		"apiCreateProject":       {Resource: "Cluster", Verb: "create"},
+		"apiGetProjectByName":    {Resource: "", Verb: ""},
+		"apiUpdateProjectByName": {Resource: "Cluster", Verb: "update"},
+		"apiDeleteProjectByName": {Resource: "Cluster", Verb: "delete"},
 
 		"apiGetMarmotStatus":  {Resource: "Cluster", Verb: "read"},
 		"apiGetMarmotCluster": {Resource: "Cluster", Verb: "read"},
+		"apiCordonNode":       {Resource: "Cluster", Verb: "update"},
+		"apiUncordonNode":     {Resource: "Cluster", Verb: "update"},
+		"apiDrainNode":        {Resource: "Cluster", Verb: "update"},
 
-		"apiGetServers":                      {Resource: "Server", Verb: "read"},
-		"apiCreateServer":                    {Resource: "Server", Verb: "create"},
-		"apiGetServerById":                   {Resource: "Server", Verb: "read"},
-		"apiUpdateServerById":                {Resource: "Server", Verb: "update"},
-		"apiDeleteServerById":                {Resource: "Server", Verb: "delete"},
-		"apiStartServerById":                 {Resource: "Server", Verb: "update"},
-		"apiStopServerById":                  {Resource: "Server", Verb: "update"},
-		"apiMakeImageEntryFromRunningVMById": {Resource: "Server", Verb: "update"},
-		"apiConsoleServerById":               {Resource: "Server", Verb: "read"},
-
-		"apiListVolumes":      {Resource: "Volume", Verb: "read"},
-		"apiCreateVolume":     {Resource: "Volume", Verb: "create"},
-		"apiShowVolumeById":   {Resource: "Volume", Verb: "read"},
-		"apiUpdateVolumeById": {Resource: "Volume", Verb: "update"},
-		"apiDeleteVolumeById": {Resource: "Volume", Verb: "delete"},
-
-		"apiGetNetworks":             {Resource: "Network", Verb: "read"},
-		"apiCreateNetwork":           {Resource: "Network", Verb: "create"},
-		"apiGetNetworkById":          {Resource: "Network", Verb: "read"},
-		"apiUpdateNetworkById":       {Resource: "Network", Verb: "update"},
-		"apiDeleteNetworkById":       {Resource: "Network", Verb: "delete"},
+		"apiGetServers":                      {Resource: "Server", Verb: "read", Projects: inListedProject},
+		"apiCreateServer":                    {Resource: "Server", Verb: "create", Projects: inRequestedProject},
+		"apiGetServerById":                   {Resource: "Server", Verb: "read", Projects: inServerProject},
+		"apiUpdateServerById":                {Resource: "Server", Verb: "update", Projects: inServerProject},
+		"apiDeleteServerById":                {Resource: "Server", Verb: "delete", Projects: inServerProject},
+		"apiStartServerById":                 {Resource: "Server", Verb: "update", Projects: inServerProject},
+		"apiStopServerById":                  {Resource: "Server", Verb: "update", Projects: inServerProject},
+		"apiMakeImageEntryFromRunningVMById": {Resource: "Server", Verb: "update", Projects: inServerProject},
+		"apiListServerSnapshots":             {Resource: "Server", Verb: "read", Projects: inServerProject},
+		"apiGetServerSnapshotById":           {Resource: "Server", Verb: "read", Projects: inServerProject},
+		"apiCreateServerSnapshot":            {Resource: "Server", Verb: "update", Projects: inServerProject},
+		"apiRevertServerSnapshotById":        {Resource: "Server", Verb: "update", Projects: inServerProject},
+		"apiDeleteServerSnapshotById":        {Resource: "Server", Verb: "update", Projects: inServerProject},
+		"apiGetServerMigration":              {Resource: "Server", Verb: "read", Projects: inServerProject},
+		"apiMigrateServer":                   {Resource: "Server", Verb: "update"},
+		"apiCloneServer":                     {Resource: "Server", Verb: "create", Projects: inServerProject},
+		"apiSetServerPassword":               {Resource: "Server", Verb: "update", Projects: inServerProject},
+		"apiConsoleServerById":               {Resource: "Server", Verb: "read", Projects: inServerProject},
+		"apiCreateServerConsoleToken":        {Resource: "Server", Verb: "update", Projects: inServerProject}, // グラフィカルコンソールはゲストを操作できるため更新の権限を求める
+		"apiAttachServerVolume":              {Resource: "Server", Verb: "update", Projects: inServerAndVolumeProject},
+		"apiDetachServerVolume":              {Resource: "Server", Verb: "update", Projects: inServerAndVolumeProject},
+
+		"apiListVolumes":      {Resource: "Volume", Verb: "read", Projects: inListedProject},
+		"apiCreateVolume":     {Resource: "Volume", Verb: "create", Projects: inRequestedProject},
+		"apiShowVolumeById":   {Resource: "Volume", Verb: "read", Projects: inVolumeProject},
+		"apiUpdateVolumeById": {Resource: "Volume", Verb: "update", Projects: inVolumeProject},
+		"apiDeleteVolumeById": {Resource: "Volume", Verb: "delete", Projects: inVolumeProject},
+
+		"apiGetNetworks":             {Resource: "Network", Verb: "read", Projects: inListedProject},
+		"apiCreateNetwork":           {Resource: "Network", Verb: "create", Projects: inRequestedProject},
+		"apiGetNetworkById":          {Resource: "Network", Verb: "read", Projects: inNetworkProject},
+		"apiUpdateNetworkById":       {Resource: "Network", Verb: "update", Projects: inNetworkProject},
+		"apiDeleteNetworkById":       {Resource: "Network", Verb: "delete", Projects: inNetworkProject},
 		"apiListIpNetworks":          {Resource: "Network", Verb: "read"},
 		"apiGetIpAddressesByNetwork": {Resource: "Network", Verb: "read"},
-		"apiGetNetworkIpNetworks":    {Resource: "Network", Verb: "read"},
+		"apiGetNetworkIpNetworks":    {Resource: "Network", Verb: "read", Projects: inNetworkProject},
+
+		// 内部DNSのカスタムレコードはネットワークの権限で管理する
+		"apiGetDnsRecords":       {Resource: "Network", Verb: "read"},
+		"apiCreateDnsRecord":     {Resource: "Network", Verb: "create"},
+		"apiGetDnsRecordById":    {Resource: "Network", Verb: "read"},
+		"apiDeleteDnsRecordById": {Resource: "Network", Verb: "delete"},
 
 		"apiGetGateways":        {Resource: "ServerGateway", Verb: "read"},
 		"apiCreateGateway":      {Resource: "ServerGateway", Verb: "create"},
@@ -73,26 +143,37 @@ func rbacOperationMiddlewares(s *Server) map[string][]echo.MiddlewareFunc {
 		"apiDeleteKubernetesEngineById":        {Resource: "KubernetesEngine", Verb: "delete"},
 		"apiGetKubernetesEngineKubeconfigById": {Resource: "KubernetesEngine", Verb: "read"},
 
-		"apiGetNetworkLoadBalancers":       {Resource: "NetworkLoadBalancer", Verb: "read"},
-		"apiCreateNetworkLoadBalancer":     {Resource: "NetworkLoadBalancer", Verb: "create"},
-		"apiGetNetworkLoadBalancerById":    {Resource: "NetworkLoadBalancer", Verb: "read"},
-		"apiUpdateNetworkLoadBalancerById": {Resource: "NetworkLoadBalancer", Verb: "update"},
-		"apiDeleteNetworkLoadBalancerById": {Resource: "NetworkLoadBalancer", Verb: "delete"},
-
-		"apiGetLoadBalancers":       {Resource: "ApplicationLoadBalancer", Verb: "read"},
-		"apiCreateLoadBalancer":     {Resource: "ApplicationLoadBalancer", Verb: "create"},
-		"apiGetLoadBalancerById":    {Resource: "ApplicationLoadBalancer", Verb: "read"},
-		"apiUpdateLoadBalancerById": {Resource: "ApplicationLoadBalancer", Verb: "update"},
-		"apiDeleteLoadBalancerById": {Resource: "ApplicationLoadBalancer", Verb: "delete"},
-
-		"apiGetImages":              {Resource: "Server", Verb: "read"},
-		"apiCreateImage":            {Resource: "Server", Verb: "create"},
-		"apiGetImageById":           {Resource: "Server", Verb: "read"},
-		"apiUpdateImageById":        {Resource: "Server", Verb: "update"},
-		"apiDeleteImageById":        {Resource: "Server", Verb: "delete"},
-		"apiDownloadImageQcow2ById": {Resource: "Server", Verb: "read"},
+		"apiGetNetworkLoadBalancers":       {Resource: "NetworkLoadBalancer", Verb: "read", Projects: inListedProject},
+		"apiCreateNetworkLoadBalancer":     {Resource: "NetworkLoadBalancer", Verb: "create", Projects: inRequestedProject},
+		"apiGetNetworkLoadBalancerById":    {Resource: "NetworkLoadBalancer", Verb: "read", Projects: inNetworkLoadBalancerProject},
+		"apiUpdateNetworkLoadBalancerById": {Resource: "NetworkLoadBalancer", Verb: "update", Projects: inNetworkLoadBalancerProject},
+		"apiDeleteNetworkLoadBalancerById": {Resource: "NetworkLoadBalancer", Verb: "delete", Projects: inNetworkLoadBalancerProject},
+
+		"apiGetLoadBalancers":       {Resource: "ApplicationLoadBalancer", Verb: "read", Projects: inListedProject},
+		"apiCreateLoadBalancer":     {Resource: "ApplicationLoadBalancer", Verb: "create", Projects: inRequestedProject},
+		"apiGetLoadBalancerById":    {Resource: "ApplicationLoadBalancer", Verb: "read", Projects: inLoadBalancerProject},
+		"apiUpdateLoadBalancerById": {Resource: "ApplicationLoadBalancer", Verb: "update", Projects: inLoadBalancerProject},
+		"apiDeleteLoadBalancerById": {Resource: "ApplicationLoadBalancer", Verb: "delete", Projects: inLoadBalancerProject},
+
+		"apiGetCertificates":       {Resource: "Certificate", Verb: "read", Projects: inListedProject},
+		"apiCreateCertificate":     {Resource: "Certificate", Verb: "create", Projects: inRequestedCertificateProject},
+		"apiGetCertificateById":    {Resource: "Certificate", Verb: "read", Projects: inCertificateProject},
+		"apiUpdateCertificateById": {Resource: "Certificate", Verb: "update", Projects: inCertificateProject},
+		"apiDeleteCertificateById": {Resource: "Certificate", Verb: "delete", Projects: inCertificateProject},
+
+		"apiGetImages":              {Resource: "Server", Verb: "read", Projects: inListedProject},
+		"apiCreateImage":            {Resource: "Server", Verb: "create", Projects: inRequestedProject},
+		"apiGetImageById":           {Resource: "Server", Verb: "read", Projects: inImageProject},
+		"apiUpdateImageById":        {Resource: "Server", Verb: "update", Projects: inImageProject},
+		"apiDeleteImageById":        {Resource: "Server", Verb: "delete", Projects: inImageProject},
+		"apiDownloadImageQcow2ById": {Resource: "Server", Verb: "read", Projects: inImageProject},
 		"apiImportImageArchive":     {Resource: "Server", Verb: "create"},
 
+		"apiGetJobs":       {Resource: "Job", Verb: "read"},
+		"apiGetJobById":    {Resource: "Job", Verb: "read"},
+		"apiCancelJobById": {Resource: "Job", Verb: "update"},
+		"apiGetJobLogById": {Resource: "Job", Verb: "read"},
+
 		"apiListUsers":          {Resource: "User", Verb: "read", RequiredRoles: []string{"Administrator"}},
 		"apiCreateUser":         {Resource: "User", Verb: "create"},
 		"apiGetUserById":        {Resource: "User", Verb: "read", AllowSelf: true, SelfParam: "userId"},
@@ -109,12 +190,17 @@ func rbacOperationMiddlewares(s *Server) map[string][]echo.MiddlewareFunc {
 		"apiDeleteUserApiKey":   {Resource: "User", Verb: "delete", AllowSelf: true, SelfParam: "userId"},
 	}
 
-	middlewares := make(map[string][]echo.MiddlewareFunc, len(rules))
+	middlewares := make(map[string][]echo.MiddlewareFunc, len(rules)+1)
+	// ログインは認証前に呼ばれるため監査記録だけを残す
+	middlewares["apiAuthLogin"] = []echo.MiddlewareFunc{s.auditMiddleware("apiAuthLogin", "")}
+	middlewares["apiAuthOidcLogin"] = []echo.MiddlewareFunc{s.auditMiddleware("apiAuthOidcLogin", "")}
+	// グラフィカルコンソールの WebSocket は発行したトークンでハンドラーが認証する
+	middlewares["apiGraphicalConsoleServerById"] = []echo.MiddlewareFunc{s.auditMiddleware("apiGraphicalConsoleServerById", "Server")}
 	for operationID, rule := range rules {
 		r := rule
-		middlewares[operationID] = []echo.MiddlewareFunc{func(next echo.HandlerFunc) echo.HandlerFunc {
+		middlewares[operationID] = []echo.MiddlewareFunc{s.auditMiddleware(operationID, r.Resource), func(next echo.HandlerFunc) echo.HandlerFunc {
 			return func(ctx echo.Context) error {
-				user, _, _, err := s.requireBearerAuth(ctx)
+				user, key, _, err := s.requireBearerAuth(ctx)
 				if err != nil {
 					return err
 				}
@@ -122,6 +208,8 @@ func rbacOperationMiddlewares(s *Server) map[string][]echo.MiddlewareFunc {
 					// requireBearerAuth already wrote an error response (e.g. 401)
 					return nil
 				}
+				ctx.Set(authUserContextKey, user.Metadata.Id)
+				ctx.Set(authApiKeyContextKey, key.Metadata.Id)
 
 				if strings.TrimSpace(r.Resource) == "" || strings.TrimSpace(r.Verb) == "" {
 					return next(ctx)
@@ -140,6 +228,9 @@ func rbacOperationMiddlewares(s *Server) map[string][]echo.MiddlewareFunc {
 				}
 
 				allowed, authErr := s.Ma.Db.Authorize(user.Metadata.Id, r.Resource, r.Verb)
+				if authErr == nil && !allowed && len(r.Projects) > 0 {
+					allowed, authErr = s.authorizeTargets(ctx, user.Metadata.Id, r)
+				}
 				if authErr != nil {
 					return mapAuthDBError(ctx, authErr)
 				}
@@ -0,0 +1,23 @@
+package marmotd
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/takara9/marmot/pkg/db"
+)
+
+// 更新APIのエラーを HTTP ステータスに変換する
+// metadata.resourceVersion が古い場合は 409 Conflict を返す
+func updateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrResourceVersionConflict):
+		return http.StatusConflict
+	case errors.Is(err, db.ErrInvalidResourceVersion):
+		return http.StatusBadRequest
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -8,6 +8,7 @@ import (
 // RegisterRoutes registers generated OpenAPI routes and project-specific extension routes.
 func RegisterRoutes(e *echo.Echo, server *Server, baseURL string) {
 	operationMiddlewares := rbacOperationMiddlewares(server)
+	wrapper := &api.ServerInterfaceWrapper{Handler: server}
 	api.RegisterHandlersWithOptions(e, server, api.RegisterHandlersOptions{
 		BaseURL:              baseURL,
 		OperationMiddlewares: operationMiddlewares,
@@ -18,7 +19,7 @@ func RegisterRoutes(e *echo.Echo, server *Server, baseURL string) {
 		return server.ApiGetGatewayCertById(ctx, ctx.Param("id"))
 	}, operationMiddlewares["apiGetGatewayCertById"]...)
 	e.POST(baseURL+"/application-load-balancer", server.ApiCreateLoadBalancer, operationMiddlewares["apiCreateLoadBalancer"]...)
-	e.GET(baseURL+"/application-load-balancer", server.ApiGetLoadBalancers, operationMiddlewares["apiGetLoadBalancers"]...)
+	e.GET(baseURL+"/application-load-balancer", wrapper.ApiGetLoadBalancers, operationMiddlewares["apiGetLoadBalancers"]...)
 	e.GET(baseURL+"/application-load-balancer/:id", func(ctx echo.Context) error {
 		return server.ApiGetLoadBalancerById(ctx, ctx.Param("id"))
 	}, operationMiddlewares["apiGetLoadBalancerById"]...)
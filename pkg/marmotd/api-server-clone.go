@@ -0,0 +1,62 @@
+package marmotd
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// サーバーの複製
+// 複製先のサーバーを PENDING で登録し、ボリュームの複製と起動はサーバーコントローラーが実施する
+func (s *Server) ApiCloneServer(ctx echo.Context, id string) error {
+	slog.Debug("===ApiCloneServer() is called===", "id", id)
+
+	var req api.ServerCloneRequest
+	if err := ctx.Bind(&req); err != nil {
+		slog.Error("ApiCloneServer()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+
+	clone, err := s.Ma.PrepareServerClone(id, req)
+	if err != nil {
+		slog.Error("PrepareServerClone()", "err", err, "id", id)
+		return ctx.JSON(serverCloneErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+
+	// クォータは認証した利用者の所有として確認する
+	clone.Metadata.Owner = requestOwner(ctx)
+	release, err := s.reserveQuota(requestUserID(ctx), db.ProjectOf(clone.Metadata), serverQuotaAmount(clone))
+	if err != nil {
+		slog.Error("reserveQuota()", "err", err)
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	defer release()
+
+	vm, err := s.Ma.Db.MakeServerEntry(clone)
+	if err != nil {
+		slog.Error("MakeServerEntry()", "err", err)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	slog.Info("server clone accepted", "sourceServerId", id, "serverId", api.ServerID(vm))
+
+	var resp api.Success
+	resp.Id = api.ServerID(vm)
+	resp.Message = util.StringPtr("Accepted the request to clone the server")
+	return ctx.JSON(http.StatusAccepted, resp)
+}
+
+// serverCloneErrorStatus はサーバーの複製のエラーを HTTP ステータスに変換する
+func serverCloneErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrServerNotClonable):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrServerCloneSourceNotReady):
+		return http.StatusConflict
+	}
+	return volumeCloneErrorStatus(err)
+}
@@ -0,0 +1,73 @@
+package marmotd
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+)
+
+const errServerMigratingMessage = "マイグレーション中のサーバーは操作できません"
+
+// isServerMigrating はサーバーがライブマイグレーション中かを返す
+func isServerMigrating(server api.Server) bool {
+	return server.Status != nil && server.Status.StatusCode == db.SERVER_MIGRATING
+}
+
+// サーバーのライブマイグレーション
+// etcd へ PENDING で登録し、実際の移行は移行元と移行先のサーバーコントローラーが実施する
+func (s *Server) ApiMigrateServer(ctx echo.Context, id string) error {
+	slog.Debug("===ApiMigrateServer() is called===", "id", id)
+
+	var req api.ServerMigration
+	if err := ctx.Bind(&req); err != nil {
+		slog.Error("ApiMigrateServer()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+
+	server, err := s.Ma.Db.GetServerById(id)
+	if err != nil {
+		slog.Error("GetServerById()", "err", err, "id", id)
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if server.Status == nil || server.Status.StatusCode != db.SERVER_RUNNING {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "マイグレーションは RUNNING のサーバーでのみ実行できます"})
+	}
+
+	created, err := s.Ma.StartServerMigration(server, req)
+	if err != nil {
+		slog.Error("StartServerMigration()", "err", err, "id", id)
+		var reqErr *migrationRequestError
+		switch {
+		case errors.Is(err, db.ErrMigrationInProgress):
+			return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "マイグレーションは既に実行中です"})
+		case errors.Is(err, ErrInsufficientCapacity), errors.Is(err, ErrPlacementUnsatisfiable):
+			return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: err.Error()})
+		case errors.As(err, &reqErr):
+			return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+
+	return ctx.JSON(http.StatusAccepted, created)
+}
+
+// サーバーの最新のマイグレーションを取得
+func (s *Server) ApiGetServerMigration(ctx echo.Context, id string) error {
+	slog.Debug("===ApiGetServerMigration() is called===", "id", id)
+
+	mig, err := s.Ma.Db.GetServerMigrationByServerId(id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "マイグレーションの記録がありません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, mig)
+}
@@ -0,0 +1,52 @@
+package marmotd
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// サーバーのユーザーのパスワード設定
+// ハッシュ化したパスワードを登録し、ゲストエージェントでの設定はサーバーのノードのコントローラーが実施する
+func (s *Server) ApiSetServerPassword(ctx echo.Context, id string) error {
+	slog.Debug("===ApiSetServerPassword() is called===", "id", id)
+
+	var body api.ServerPasswordRequest
+	if err := ctx.Bind(&body); err != nil {
+		slog.Error("ApiSetServerPassword()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+
+	server, err := s.Ma.Db.GetServerById(id)
+	if errors.Is(err, db.ErrNotFound) {
+		return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: err.Error()})
+	}
+	if err != nil {
+		slog.Error("GetServerById()", "err", err, "id", id)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if server.Status == nil || server.Status.StatusCode != db.SERVER_RUNNING {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "server is not RUNNING"})
+	}
+
+	req, err := NewGuestPasswordRequest(id, body)
+	if err != nil {
+		slog.Error("NewGuestPasswordRequest()", "err", err, "id", id)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+	if err := s.Ma.Db.PutGuestPasswordRequest(req); err != nil {
+		slog.Error("PutGuestPasswordRequest()", "err", err, "id", id)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	slog.Info("guest password request accepted", "serverId", id, "user", req.User)
+
+	var resp api.Success
+	resp.Id = id
+	resp.Message = util.StringPtr("Accepted the request to set the password")
+	return ctx.JSON(http.StatusAccepted, resp)
+}
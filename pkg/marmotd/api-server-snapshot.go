@@ -0,0 +1,163 @@
+package marmotd
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// getServerSnapshotOfServer はスナップショットを取得し、指定サーバーのものかを確認する
+func (s *Server) getServerSnapshotOfServer(id, snapshotId string) (api.ServerSnapshot, error) {
+	snap, err := s.Ma.Db.GetServerSnapshotById(snapshotId)
+	if err != nil {
+		return api.ServerSnapshot{}, err
+	}
+	if snap.Spec.ServerId == nil || strings.TrimSpace(*snap.Spec.ServerId) != id {
+		return api.ServerSnapshot{}, db.ErrNotFound
+	}
+	return snap, nil
+}
+
+// サーバーのスナップショット作成
+// etcd へ PENDING で登録し、実際の取得はサーバーコントローラーが実施する
+func (s *Server) ApiCreateServerSnapshot(ctx echo.Context, id string) error {
+	slog.Debug("===ApiCreateServerSnapshot() is called===", "id", id)
+
+	var req api.ServerSnapshot
+	if err := ctx.Bind(&req); err != nil {
+		slog.Error("ApiCreateServerSnapshot()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
+	}
+
+	server, err := s.Ma.Db.GetServerById(id)
+	if err != nil {
+		slog.Error("GetServerById()", "err", err, "id", id)
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if server.Status == nil || (server.Status.StatusCode != db.SERVER_RUNNING && server.Status.StatusCode != db.SERVER_STOPPED) {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "スナップショットは RUNNING または STOPPED のサーバーでのみ作成できます"})
+	}
+
+	snap, err := s.Ma.PrepareServerSnapshot(server, req)
+	if err != nil {
+		slog.Error("PrepareServerSnapshot()", "err", err, "id", id)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+
+	created, err := s.Ma.Db.CreateServerSnapshot(snap)
+	if err != nil {
+		slog.Error("CreateServerSnapshot()", "err", err, "id", id)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+
+	return ctx.JSON(http.StatusCreated, created)
+}
+
+// サーバーのスナップショット一覧
+func (s *Server) ApiListServerSnapshots(ctx echo.Context, id string) error {
+	slog.Debug("===ApiListServerSnapshots() is called===", "id", id)
+
+	if _, err := s.Ma.Db.GetServerById(id); err != nil {
+		slog.Error("GetServerById()", "err", err, "id", id)
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+
+	snaps, err := s.Ma.Db.GetServerSnapshotsByServerId(id)
+	if err != nil {
+		slog.Error("GetServerSnapshotsByServerId()", "err", err, "id", id)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, snaps)
+}
+
+// サーバーのスナップショット詳細
+func (s *Server) ApiGetServerSnapshotById(ctx echo.Context, id string, snapshotId string) error {
+	slog.Debug("===ApiGetServerSnapshotById() is called===", "id", id, "snapshotId", snapshotId)
+
+	snap, err := s.getServerSnapshotOfServer(id, snapshotId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, snap)
+}
+
+// サーバーのスナップショット削除
+// ステータスを DELETING に更新してサーバーコントローラーに処理を委譲する
+func (s *Server) ApiDeleteServerSnapshotById(ctx echo.Context, id string, snapshotId string) error {
+	slog.Debug("===ApiDeleteServerSnapshotById() is called===", "id", id, "snapshotId", snapshotId)
+
+	snap, err := s.getServerSnapshotOfServer(id, snapshotId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if snap.Status != nil && (snap.Status.StatusCode == db.SNAPSHOT_CREATING || snap.Status.StatusCode == db.SNAPSHOT_REVERTING) {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "スナップショットは処理中です"})
+	}
+	// スナップショットから複製中のボリュームがある間は、スナップショットを変更しない
+	clones, err := s.Ma.VolumeCloneDependents("", snapshotId)
+	if err != nil {
+		slog.Error("VolumeCloneDependents()", "err", err, "snapshotId", snapshotId)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if len(clones) > 0 {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "スナップショットから複製中のボリュームがあります: " + strings.Join(clones, ", ")})
+	}
+
+	if err := s.Ma.Db.UpdateServerSnapshotStatusWithMessage(snapshotId, db.SNAPSHOT_DELETING, ""); err != nil {
+		slog.Error("UpdateServerSnapshotStatusWithMessage()", "err", err, "snapshotId", snapshotId)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, api.Success{Id: snapshotId, Message: util.StringPtr("Delete request accepted")})
+}
+
+// サーバーをスナップショットへロールバック
+// ステータスを REVERTING に更新してサーバーコントローラーに処理を委譲する
+func (s *Server) ApiRevertServerSnapshotById(ctx echo.Context, id string, snapshotId string) error {
+	slog.Debug("===ApiRevertServerSnapshotById() is called===", "id", id, "snapshotId", snapshotId)
+
+	snap, err := s.getServerSnapshotOfServer(id, snapshotId)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if snap.Status == nil || snap.Status.StatusCode != db.SNAPSHOT_AVAILABLE {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "ロールバックできるのは AVAILABLE のスナップショットのみです"})
+	}
+	// スナップショットから複製中のボリュームがある間は、スナップショットを変更しない
+	clones, err := s.Ma.VolumeCloneDependents("", snapshotId)
+	if err != nil {
+		slog.Error("VolumeCloneDependents()", "err", err, "snapshotId", snapshotId)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if len(clones) > 0 {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "スナップショットから複製中のボリュームがあります: " + strings.Join(clones, ", ")})
+	}
+
+	if err := s.Ma.Db.UpdateServerSnapshotStatusWithMessage(snapshotId, db.SNAPSHOT_REVERTING, ""); err != nil {
+		slog.Error("UpdateServerSnapshotStatusWithMessage()", "err", err, "snapshotId", snapshotId)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+
+	return ctx.JSON(http.StatusOK, api.Success{Id: snapshotId, Message: util.StringPtr("Revert request accepted")})
+}
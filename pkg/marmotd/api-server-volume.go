@@ -0,0 +1,82 @@
+package marmotd
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// serverVolumeErrorStatus はボリュームのアタッチとデタッチのエラーを HTTP ステータスに変換する
+func serverVolumeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrNotFound), errors.Is(err, ErrVolumeNotInServerSpec):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVolumeNotAttachable):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrServerVolumeState), errors.Is(err, ErrVolumeAlreadyInSpec),
+		errors.Is(err, db.ErrVolumeInUse), errors.Is(err, db.ErrResourceVersionConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// サーバーへのボリュームのアタッチ
+// アタッチ先を記録してサーバーの storage に追加し、ドメインへの接続はサーバーコントローラーに委譲する
+func (s *Server) ApiAttachServerVolume(ctx echo.Context, id string, volumeId string) error {
+	slog.Debug("===ApiAttachServerVolume() is called ===", "id", id, "volumeId", volumeId)
+
+	server, err := s.Ma.Db.GetServerById(id)
+	if err != nil {
+		slog.Error("GetServerById()", "err", err, "id", id)
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if isServerMigrating(server) {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: errServerMigratingMessage})
+	}
+
+	if err := s.Ma.AttachServerVolume(id, volumeId); err != nil {
+		slog.Error("AttachServerVolume()", "err", err, "id", id, "volumeId", volumeId)
+		return ctx.JSON(serverVolumeErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+
+	var resp api.Success
+	resp.Id = id
+	resp.Message = util.StringPtr("Volume attach request accepted")
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+// サーバーからのボリュームのデタッチ
+// サーバーの storage から外し、ドメインからの取り外しとアタッチ先の解除はサーバーコントローラーに委譲する
+func (s *Server) ApiDetachServerVolume(ctx echo.Context, id string, volumeId string) error {
+	slog.Debug("===ApiDetachServerVolume() is called ===", "id", id, "volumeId", volumeId)
+
+	server, err := s.Ma.Db.GetServerById(id)
+	if err != nil {
+		slog.Error("GetServerById()", "err", err, "id", id)
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if isServerMigrating(server) {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: errServerMigratingMessage})
+	}
+
+	if err := s.Ma.DetachServerVolume(id, volumeId); err != nil {
+		slog.Error("DetachServerVolume()", "err", err, "id", id, "volumeId", volumeId)
+		return ctx.JSON(serverVolumeErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+
+	var resp api.Success
+	resp.Id = id
+	resp.Message = util.StringPtr("Volume detach request accepted")
+	return ctx.JSON(http.StatusOK, resp)
+}
@@ -15,8 +15,11 @@ import (
 )
 
 // サーバーのリストを取得、フィルターは、パラメータで指定するようにする
-func (s *Server) ApiGetServers(ctx echo.Context) error {
+func (s *Server) ApiGetServers(ctx echo.Context, params api.ApiGetServersParams) error {
 	slog.Debug("===", "ApiGetServers() is called", "===")
+	if isWatchRequest(params.Watch) {
+		return s.watchResources(ctx, db.ServerPrefix, params.ResourceVersion, projectVisible(ctx, params.Project))
+	}
 	var serverSpec api.Server
 	if err := ctx.Bind(&serverSpec); err != nil {
 		slog.Error("ApiGetServers()", "err", err)
@@ -30,6 +33,7 @@ func (s *Server) ApiGetServers(ctx echo.Context) error {
 	for i := range recs {
 		recs[i].NormalizeMMImageAlias()
 	}
+	recs = filterByProject(recs, func(r api.Server) api.Metadata { return r.Metadata }, projectVisible(ctx, params.Project))
 	return ctx.JSON(http.StatusOK, recs)
 }
 
@@ -46,6 +50,36 @@ func (s *Server) ApiCreateServer(ctx echo.Context) error {
 	virtualServer.NormalizeMMImageAlias()
 	slog.Debug("Recived post body", "serverSpec=", virtualServer, "cpu=", virtualServer.Spec.Cpu, "memory=", virtualServer.Spec.Memory, "mmImage", virtualServer.Spec.MmImage)
 	// nodeName の割当はスケジューラーが担当する。
+	if err := ValidateServerPlacement(virtualServer.Spec.Placement); err != nil {
+		slog.Error("ValidateServerPlacement()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+	if err := ValidateServerCloudInit(virtualServer); err != nil {
+		slog.Error("ValidateServerCloudInit()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+	if err := validateServerFirmwareSpec(virtualServer.Spec); err != nil {
+		slog.Error("validateServerFirmwareSpec()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+	if err := validateServerPerformanceSpec(virtualServer.Spec); err != nil {
+		slog.Error("validateServerPerformanceSpec()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+
+	if err := s.assignProject(&virtualServer.Metadata); err != nil {
+		slog.Error("assignProject()", "err", err)
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+
+	// クォータは認証した利用者の所有として確認する
+	virtualServer.Metadata.Owner = requestOwner(ctx)
+	release, err := s.reserveQuota(requestUserID(ctx), db.ProjectOf(virtualServer.Metadata), serverQuotaAmount(virtualServer))
+	if err != nil {
+		slog.Error("reserveQuota()", "err", err)
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	defer release()
 
 	// リクエストをetcdに登録し、正常応答を返す
 	slog.Debug("仮想マシンの使用を付与してDBへ登録、一意のIDを取得")
@@ -81,13 +115,17 @@ func (s *Server) ApiGetServerById(ctx echo.Context, id string) error {
 func (s *Server) ApiDeleteServerById(ctx echo.Context, id string) error {
 	slog.Debug("===ApiDeleteServerById() is called ===", "id", id)
 
-	if _, err := s.Ma.Db.GetServerById(id); err != nil {
+	server, err := s.Ma.Db.GetServerById(id)
+	if err != nil {
 		slog.Error("ApiDeleteServerById() GetServerById failed", "id", id, "err", err)
 		if errors.Is(err, db.ErrNotFound) {
 			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
 		}
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
+	if isServerMigrating(server) {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: errServerMigratingMessage})
+	}
 
 	if err := s.Ma.Db.SetDeleteTimestamp(id); err != nil {
 		slog.Error("SetDeleteTimestamp()", "err", err)
@@ -112,10 +150,28 @@ func (s *Server) ApiUpdateServerById(ctx echo.Context, id string) error {
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
 	serverSpec.NormalizeMMImageAlias()
+	resourceVersion := db.TakeResourceVersion(&serverSpec.Metadata)
+
+	// vCPU とメモリの増加はサーバーの所有者とプロジェクトのクォータで確認する
+	current, err := s.Ma.Db.GetServerById(id)
+	if err != nil {
+		slog.Error("GetServerById()", "err", err)
+		return ctx.JSON(updateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	if err := validateServerUpdate(current, serverSpec); err != nil {
+		slog.Error("validateServerUpdate()", "err", err)
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+	release, err := s.reserveQuota(util.OrDefault(current.Metadata.Owner, ""), db.ProjectOf(current.Metadata), serverResizeQuotaAmount(current, serverSpec))
+	if err != nil {
+		slog.Error("reserveQuota()", "err", err)
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	defer release()
 
-	if err := s.Ma.UpdateServerById(id, serverSpec); err != nil {
+	if err := s.Ma.UpdateServerById(id, serverSpec, resourceVersion); err != nil {
 		slog.Error("UpdateServerById()", "err", err)
-		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+		return ctx.JSON(updateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
 	}
 	var resp api.Success
 	resp.Id = id
@@ -123,15 +179,55 @@ func (s *Server) ApiUpdateServerById(ctx echo.Context, id string) error {
 	return ctx.JSON(http.StatusOK, resp)
 }
 
+// validateServerUpdate は更新の要求を検証する。current は変更しない
+// PatchStruct はポインターのフィールドを共有したまま書き換えるため、更新後の内容は current の複製から作る
+func validateServerUpdate(current, patch api.Server) error {
+	// ファームウェアと TPM はドメインの作成時に決まるため、作成後は変更できない
+	if err := checkServerFirmwareUnchanged(current.Spec, patch.Spec); err != nil {
+		return err
+	}
+	// spec.performance は作成時に割り当てるため変更できない
+	if err := checkServerPerformanceUnchanged(current.Spec, patch.Spec); err != nil {
+		return err
+	}
+	// spec.cloudInit は移行時の ISO の再作成で使うため、更新後の内容で検証する
+	if patch.Spec.CloudInit != nil {
+		updated, err := util.DeepCopy(current)
+		if err != nil {
+			return err
+		}
+		util.PatchStruct(&updated, patch)
+		if err := ValidateServerCloudInit(updated); err != nil {
+			return err
+		}
+	}
+	// hugepages のサーバーはメモリの変更後の値を検証する
+	if patch.Spec.Memory != nil {
+		updated, err := util.DeepCopy(current)
+		if err != nil {
+			return err
+		}
+		util.PatchStruct(&updated, patch)
+		if err := validateServerPerformanceSpec(updated.Spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // サーバーの停止
 // ステータスを STOPPING に更新してサーバーコントローラーに処理を委譲する
 func (s *Server) ApiStopServerById(ctx echo.Context, id string) error {
 	slog.Debug("===ApiStopServerById() is called ===", "id", id)
 
-	if _, err := s.Ma.Db.GetServerById(id); err != nil {
+	server, err := s.Ma.Db.GetServerById(id)
+	if err != nil {
 		slog.Error("GetServerById()", "err", err)
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
+	if isServerMigrating(server) {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: errServerMigratingMessage})
+	}
 
 	if err := s.Ma.Db.UpdateServerStatus(id, db.SERVER_STOPPING, ""); err != nil {
 		slog.Error("UpdateServerStatus()", "err", err, "id", id)
@@ -149,10 +245,14 @@ func (s *Server) ApiStopServerById(ctx echo.Context, id string) error {
 func (s *Server) ApiStartServerById(ctx echo.Context, id string) error {
 	slog.Debug("===ApiStartServerById() is called ===", "id", id)
 
-	if _, err := s.Ma.Db.GetServerById(id); err != nil {
+	server, err := s.Ma.Db.GetServerById(id)
+	if err != nil {
 		slog.Error("GetServerById()", "err", err)
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
+	if isServerMigrating(server) {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: errServerMigratingMessage})
+	}
 
 	if err := s.Ma.Db.UpdateServerStatus(id, db.SERVER_STARTING, ""); err != nil {
 		slog.Error("UpdateServerStatus()", "err", err, "id", id)
@@ -191,11 +291,22 @@ func (s *Server) ApiMakeImageEntryFromRunningVMById(ctx echo.Context, serverId s
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
 
-	// イメージ作成の登録
-	if _, err := s.Ma.Db.MakeImageEntryFromRunningVM(api.ServerID(server), imageName); err != nil {
+	// イメージ作成の登録、ボリュームのコピーはジョブで追跡する
+	jobs := s.Ma.Jobs()
+	jobId, err := jobs.EntryResourceJob("image-from-server", "", "Image")
+	if err != nil {
+		slog.Error("ApiMakeImageEntryFromRunningVMById() EntryResourceJob failed", "err", err)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	img, err := s.Ma.Db.MakeImageEntryFromRunningVMWithJob(api.ServerID(server), imageName, jobId)
+	if err != nil {
 		slog.Error("Image name is not set, it must set for new image", "err", err)
+		_ = jobs.CancelJob(jobId)
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
+	if err := jobs.SetJobResource(jobId, img.Metadata.Id, util.OrDefault(img.Metadata.NodeName, "")); err != nil {
+		slog.Warn("ApiMakeImageEntryFromRunningVMById() SetJobResource failed", "err", err, "jobId", jobId, "imageId", img.Metadata.Id)
+	}
 
-	return ctx.JSON(http.StatusOK, api.Success{Id: serverId, Message: util.StringPtr("Image created successfully from server")})
+	return ctx.JSON(http.StatusOK, api.Success{Id: serverId, JobId: util.StringPtr(jobId), Message: util.StringPtr("Image created successfully from server")})
 }
@@ -4,6 +4,7 @@ import (
 	_ "embed"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -90,15 +91,60 @@ func (s *Server) ApiCreateVolume(ctx echo.Context) error {
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
 	normalizeIncomingVolumeSpecForCompatibility(&volume)
+
+	// 複製元の検証と、複製元のノードへの割り当て
+	if volume.Spec.Source != nil {
+		if err := s.Ma.PrepareVolumeClone(&volume); err != nil {
+			slog.Error("PrepareVolumeClone()", "err", err)
+			return ctx.JSON(volumeCloneErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+		}
+	}
 	assignedNode := resolveVolumeCreationNode(s.Ma, &volume)
 	assignNodeNameIfUnset(&volume.Metadata, assignedNode)
 
+	if err := s.assignProject(&volume.Metadata); err != nil {
+		slog.Error("assignProject()", "err", err)
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+
+	// 複製の容量は PrepareVolumeClone が spec.size に設定している
+	volume.Metadata.Owner = requestOwner(ctx)
+	release, err := s.reserveQuota(requestUserID(ctx), db.ProjectOf(volume.Metadata), quotaAmount{VolumeGB: volumeSizeGB(volume, volumeDefaultSizeGB(volume))})
+	if err != nil {
+		slog.Error("reserveQuota()", "err", err)
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	defer release()
+
+	// 複製は時間がかかるため、進捗とキャンセルをジョブで追跡する
+	var jobId string
+	jobs := s.Ma.Jobs()
+	if volume.Spec.Source != nil {
+		jobId, err = jobs.EntryResourceJob("volume-clone", util.OrDefault(volume.Metadata.NodeName, ""), "Volume")
+		if err != nil {
+			slog.Error("ApiCreateVolume() EntryResourceJob failed", "err", err)
+			return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+		}
+		if volume.Status == nil {
+			volume.Status = &api.Status{}
+		}
+		volume.Status.JobId = util.StringPtr(jobId)
+	}
+
 	// etcdへの登録と状態の変更だけにして、実際のボリュームの作成はコントローラーが実施する
 	requestedVolume, err := s.Ma.Db.CreateVolumeOnDB2(volume)
 	if err != nil {
 		slog.Error("ApiCreateVolume()", "err", err)
+		if jobId != "" {
+			_ = jobs.CancelJob(jobId)
+		}
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
+	if jobId != "" {
+		if err := jobs.SetJobResource(jobId, api.VolumeID(*requestedVolume), ""); err != nil {
+			slog.Warn("ApiCreateVolume() SetJobResource failed", "err", err, "jobId", jobId, "volumeId", api.VolumeID(*requestedVolume))
+		}
+	}
 	slog.Debug("ApiCreateVolume()", "volKey", *requestedVolume.Metadata.Key)
 
 	return ctx.JSON(http.StatusCreated, requestedVolume)
@@ -109,13 +155,31 @@ func (s *Server) ApiCreateVolume(ctx echo.Context) error {
 func (s *Server) ApiDeleteVolumeById(ctx echo.Context, id string) error {
 	slog.Debug("===", "ApiDeleteVolumeById() is called", "===", "volumeId", id)
 
-	if _, err := s.Ma.Db.GetVolumeById(id); err != nil {
+	vol, err := s.Ma.Db.GetVolumeById(id)
+	if err != nil {
 		slog.Error("ApiDeleteVolumeById() GetVolumeById failed", "volumeId", id, "err", err)
 		if errors.Is(err, db.ErrNotFound) {
 			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
 		}
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
+	// サーバーにアタッチされているボリュームは削除できない
+	if serverID := db.VolumeAttachedServerId(vol); serverID != "" {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: fmt.Sprintf("ボリュームはサーバー %s にアタッチされています", serverID)})
+	}
+	// 複製中のボリュームは、ジョブのキャンセルで複製を止めてから削除する
+	if vol.Status != nil && vol.Status.StatusCode == db.VOLUME_CLONING {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: fmt.Sprintf("ボリュームは複製中です。ジョブ %s をキャンセルしてください", util.OrDefault(vol.Status.JobId, ""))})
+	}
+	// コピーオンライトのクローンと複製中のボリュームが参照している複製元は削除できない
+	clones, err := s.Ma.VolumeCloneDependents(id, "")
+	if err != nil {
+		slog.Error("VolumeCloneDependents()", "err", err, "volumeId", id)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if len(clones) > 0 {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: fmt.Sprintf("ボリュームはクローン %s の複製元です", strings.Join(clones, ", "))})
+	}
 
 	// レコードは状態だけを変更して、実際の削除はコントローラーが実施する
 	v := api.Volume{
@@ -138,8 +202,11 @@ func (s *Server) ApiDeleteVolumeById(ctx echo.Context, id string) error {
 }
 
 // ボリュームのリストを取得 implements api.ServerInterface.
-func (s *Server) ApiListVolumes(ctx echo.Context) error {
+func (s *Server) ApiListVolumes(ctx echo.Context, params api.ApiListVolumesParams) error {
 	slog.Debug("===", "ApiListVolumes() is called", "===")
+	if isWatchRequest(params.Watch) {
+		return s.watchResources(ctx, db.VolumePrefix, params.ResourceVersion, projectVisible(ctx, params.Project))
+	}
 	vols, err := s.Ma.GetDataVolumes()
 	if err != nil {
 		slog.Error("ApiListVolumes()", "err", err)
@@ -153,6 +220,7 @@ func (s *Server) ApiListVolumes(ctx echo.Context) error {
 	}
 
 	vols = append(vols, vols2...)
+	vols = filterByProject(vols, func(r api.Volume) api.Metadata { return r.Metadata }, projectVisible(ctx, params.Project))
 
 	return ctx.JSON(http.StatusOK, vols)
 }
@@ -182,13 +250,53 @@ func (s *Server) ApiUpdateVolumeById(ctx echo.Context, volumeId string) error {
 		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
 	}
 
+	// 容量の増加はボリュームの所有者とプロジェクトのクォータで確認する
+	current, err := s.Ma.Db.GetVolumeById(volumeId)
+	if err != nil {
+		slog.Error("GetVolumeById()", "err", err)
+		return ctx.JSON(updateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	if volume.Spec.Size != nil {
+		release, err := s.reserveQuota(util.OrDefault(current.Metadata.Owner, ""), db.ProjectOf(current.Metadata), quotaAmount{VolumeGB: *volume.Spec.Size - util.OrDefault(current.Spec.Size, 0)})
+		if err != nil {
+			slog.Error("reserveQuota()", "err", err)
+			return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+		}
+		defer release()
+	}
+
 	key := db.VolumePrefix + "/" + volumeId
-	if _, err := s.Ma.UpdateVolumeById(volumeId, volume); err != nil {
+	resourceVersion := db.TakeResourceVersion(&volume.Metadata)
+	if _, err := s.Ma.UpdateVolumeById(volumeId, volume, resourceVersion); err != nil {
 		slog.Error("ApiUpdateVolumeById()", "err", err)
-		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+		return ctx.JSON(volumeUpdateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
 	}
 	volume.Metadata.Key = &key
 	api.SetVolumeID(&volume, volumeId)
 
 	return ctx.JSON(http.StatusOK, volume)
 }
+
+// volumeCloneErrorStatus はボリュームの複製要求のエラーを HTTP ステータスに変換する
+func volumeCloneErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrVolumeNotClonable):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrVolumeSourceNotReady), errors.Is(err, ErrVolumeSourceInUse):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// volumeUpdateErrorStatus はボリュームの更新のエラーを HTTP ステータスに変換する
+func volumeUpdateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrVolumeNotExpandable):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrVolumeNotAvailable):
+		return http.StatusConflict
+	}
+	return updateErrorStatus(err)
+}
@@ -25,6 +25,16 @@ func (s *Server) ApiCreateVpnGateway(ctx echo.Context) error {
 	if err := normalizeVpnGatewayResource(&rec); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
 	}
+	if err := s.assignProject(&rec.Metadata); err != nil {
+		return ctx.JSON(projectErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	rec.Metadata.Owner = requestOwner(ctx)
+	release, err := s.reserveQuota(requestUserID(ctx), db.ProjectOf(rec.Metadata), quotaAmount{PublicIps: 1})
+	if err != nil {
+		return ctx.JSON(quotaErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
+	}
+	defer release()
+
 	created, err := s.Ma.Db.CreateVpnGateway(rec)
 	if err != nil {
 		if errors.Is(err, db.ErrFound) {
@@ -66,6 +76,7 @@ func (s *Server) ApiUpdateVpnGatewayById(ctx echo.Context, id string) error {
 	if err := ctx.Bind(&req); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "invalid request body"})
 	}
+	resourceVersion := db.TakeResourceVersion(&req.Metadata)
 	if err := normalizeVpnGatewaySpec(&req.Spec); err != nil {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
 	}
@@ -73,8 +84,8 @@ func (s *Server) ApiUpdateVpnGatewayById(ctx echo.Context, id string) error {
 		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "immutable fields changed: spec.bindPublicIpAddress, spec.internalVirtualNetwork"})
 	}
 	current.Spec.RemoteCIDRs = req.Spec.RemoteCIDRs
-	if err := s.Ma.Db.UpdateVpnGatewayById(id, current); err != nil {
-		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	if err := s.Ma.Db.UpdateVpnGatewayByIdWithResourceVersion(id, current, resourceVersion); err != nil {
+		return ctx.JSON(updateErrorStatus(err), api.Error{Code: 1, Message: err.Error()})
 	}
 	return ctx.JSON(http.StatusOK, api.Success{Id: id, Message: util.StringPtr("Accepted the request to update the vpn gateway")})
 }
@@ -0,0 +1,124 @@
+package marmotd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+)
+
+// 変更がない間も接続を維持するためにコメント行を送る間隔
+const watchHeartbeatInterval = 30 * time.Second
+
+// watchEventBody は Server-Sent Events の data に載せる api.WatchEvent
+type watchEventBody struct {
+	Type            string      `json:"type"`
+	ResourceVersion string      `json:"resourceVersion,omitempty"`
+	Object          interface{} `json:"object,omitempty"`
+}
+
+// isWatchRequest は一覧取得が watch=true で呼び出されたかを返す
+func isWatchRequest(watch *api.Watch) bool {
+	return watch != nil && *watch
+}
+
+// watchResources は prefix 配下のリソースの変更を Server-Sent Events で送り続ける
+// クライアントが切断するか、watch が失敗して ERROR イベントを送るまで戻らない
+// visible が false を返すリソースの変更は送らない
+func (s *Server) watchResources(ctx echo.Context, prefix string, resourceVersion *api.ResourceVersion, visible func(api.Metadata) bool) error {
+	var after int64
+	if resourceVersion != nil && strings.TrimSpace(*resourceVersion) != "" {
+		v, err := strconv.ParseInt(strings.TrimSpace(*resourceVersion), 10, 64)
+		if err != nil || v < 0 {
+			return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: "resourceVersion は 0 以上の整数で指定してください"})
+		}
+		after = v
+	}
+
+	res := ctx.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	reqCtx := ctx.Request().Context()
+	events := s.Ma.Db.WatchPrefix(reqCtx, prefix+"/", after)
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-reqCtx.Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := io.WriteString(res, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			body, ok := newWatchEventBody(prefix, ev)
+			if !ok {
+				continue
+			}
+			if meta, isResource := resourceMetadata(body.Object); isResource && visible != nil && !visible(meta) {
+				continue
+			}
+			if err := writeWatchEvent(res, body); err != nil {
+				slog.Debug("watch client disconnected", "prefix", prefix, "err", err)
+				return nil
+			}
+			res.Flush()
+			if ev.Type == db.WATCH_ERROR {
+				return nil
+			}
+		}
+	}
+}
+
+// newWatchEventBody は etcd の変更をクライアントへ送るイベントに変換する
+// リソース本体ではないキーの変更は false を返して送らない
+func newWatchEventBody(prefix string, ev db.WatchEvent) (watchEventBody, bool) {
+	if ev.Type == db.WATCH_ERROR {
+		code := http.StatusInternalServerError
+		if errors.Is(ev.Err, db.ErrResourceVersionExpired) {
+			code = http.StatusGone
+		}
+		slog.Warn("watch failed", "prefix", prefix, "err", ev.Err)
+		return watchEventBody{Type: db.WATCH_ERROR, Object: api.Error{Code: int32(code), Message: ev.Err.Error()}}, true
+	}
+	obj, ok, err := db.DecodeResource(prefix, ev.Key, ev.Value)
+	if err != nil {
+		slog.Error("DecodeResource() failed", "key", ev.Key, "err", err)
+		return watchEventBody{}, false
+	}
+	if !ok {
+		return watchEventBody{}, false
+	}
+	return watchEventBody{Type: ev.Type, ResourceVersion: strconv.FormatInt(ev.Revision, 10), Object: obj}, true
+}
+
+// writeWatchEvent はイベントを Server-Sent Events の形式で書き出す
+func writeWatchEvent(w io.Writer, body watchEventBody) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	if body.ResourceVersion != "" {
+		_, err = fmt.Fprintf(w, "event: %s\nid: %s\ndata: %s\n\n", body.Type, body.ResourceVersion, data)
+	} else {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", body.Type, data)
+	}
+	return err
+}
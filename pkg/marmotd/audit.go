@@ -0,0 +1,312 @@
+package marmotd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+/*
+API の更新系リクエスト (POST/PUT/PATCH/DELETE) を監査記録として残す
+
+	記録は etcd に保存して GET /audit で検索できるようにし、保持期間を過ぎたものは
+	API キーのメンテナンスワーカーが削除する。設定により JSON Lines のファイルと
+	Loki (job="marmotd-audit") にも同じ記録を出力する
+*/
+
+const (
+	// 作成系の応答からリソースIDを読み取るために保持する応答の大きさ
+	auditResponseCaptureLimit = 4096
+	// 保持期間を過ぎた監査記録を削除する間隔
+	auditRecordCleanupInterval = 1 * time.Hour
+	// GET /audit の件数の既定値と上限
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+// auditLogSink は etcd 以外の監査記録の出力先
+type auditLogSink struct {
+	mu   sync.Mutex
+	file *os.File
+	loki *lokiWriter
+}
+
+var auditSinkState = struct {
+	mu   sync.RWMutex
+	sink *auditLogSink
+}{}
+
+// SetupAuditLog は設定に従って監査記録のファイルと Loki の出力先を準備する。
+// 戻り値の関数で出力先を閉じる
+func SetupAuditLog(cfg *MarmotdConfig) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	if cfg == nil {
+		return noop, nil
+	}
+
+	sink := &auditLogSink{}
+	if cfg.AuditLogFile != "" {
+		f, err := os.OpenFile(cfg.AuditLogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return noop, fmt.Errorf("failed to open audit log file: %w", err)
+		}
+		sink.file = f
+	}
+	if cfg.AuditLoki {
+		if strings.TrimSpace(cfg.LokiPushURL) == "" {
+			slog.Warn("audit_loki is set but loki_push_url is empty; audit records are not sent to Loki")
+		} else {
+			writer, err := newLokiWriter(cfg, "marmotd-audit")
+			if err != nil {
+				if sink.file != nil {
+					sink.file.Close()
+				}
+				return noop, err
+			}
+			sink.loki = writer
+		}
+	}
+	if sink.file == nil && sink.loki == nil {
+		return noop, nil
+	}
+
+	auditSinkState.mu.Lock()
+	auditSinkState.sink = sink
+	auditSinkState.mu.Unlock()
+
+	return func(ctx context.Context) error {
+		auditSinkState.mu.Lock()
+		auditSinkState.sink = nil
+		auditSinkState.mu.Unlock()
+		return sink.Close(ctx)
+	}, nil
+}
+
+func (k *auditLogSink) Write(rec api.AuditRecord) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		slog.Warn("failed to encode audit record", "err", err, "id", rec.Id)
+		return
+	}
+	if k.loki != nil {
+		k.loki.Enqueue(rec.Timestamp, string(line))
+	}
+	if k.file != nil {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if _, err := k.file.Write(append(line, '\n')); err != nil {
+			slog.Warn("failed to write audit log file", "err", err, "id", rec.Id)
+		}
+	}
+}
+
+func (k *auditLogSink) Close(ctx context.Context) error {
+	var errs []error
+	if k.loki != nil {
+		errs = append(errs, k.loki.Close(ctx))
+	}
+	if k.file != nil {
+		k.mu.Lock()
+		errs = append(errs, k.file.Close())
+		k.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// isAuditedMethod は監査記録の対象となる更新系のメソッドかを返す
+func isAuditedMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// auditResult は応答のステータスコードから監査記録の結果を決める
+func auditResult(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return db.AUDIT_DENIED
+	case status < http.StatusBadRequest:
+		return db.AUDIT_SUCCESS
+	default:
+		return db.AUDIT_FAILURE
+	}
+}
+
+// auditResourceIDFromBody は作成系の応答の id または metadata.id をリソースIDとして読み取る
+func auditResourceIDFromBody(body []byte) string {
+	var resp struct {
+		Id       any `json:"id"`
+		Metadata *struct {
+			Id string `json:"id"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
+	}
+	if resp.Metadata != nil && resp.Metadata.Id != "" {
+		return resp.Metadata.Id
+	}
+	if id, ok := resp.Id.(string); ok {
+		return id
+	}
+	return ""
+}
+
+// auditResponseWriter は応答の先頭部分を保持するレスポンスライター
+type auditResponseWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if rest := auditResponseCaptureLimit - w.body.Len(); rest > 0 {
+		w.body.Write(b[:min(rest, len(b))])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap は Flush や Hijack を元のレスポンスライターに委ねるために使われる
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// auditMiddleware は更新系のリクエストの処理結果を監査記録として保存する
+func (s *Server) auditMiddleware(operationID, resource string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if !isAuditedMethod(ctx.Request().Method) {
+				return next(ctx)
+			}
+
+			res := ctx.Response()
+			writer := &auditResponseWriter{ResponseWriter: res.Writer}
+			res.Writer = writer
+			started := time.Now()
+			err := next(ctx)
+			res.Writer = writer.ResponseWriter
+
+			status := http.StatusOK
+			var httpErr *echo.HTTPError
+			switch {
+			case res.Committed:
+				status = res.Status
+			case errors.As(err, &httpErr):
+				status = httpErr.Code
+			case err != nil:
+				status = http.StatusInternalServerError
+			}
+
+			rec := api.AuditRecord{
+				Timestamp:   started,
+				OperationId: operationID,
+				Method:      ctx.Request().Method,
+				Path:        ctx.Request().URL.Path,
+				StatusCode:  status,
+				Result:      auditResult(status),
+			}
+			if resource != "" {
+				rec.Resource = util.StringPtr(resource)
+			}
+			if userID := requestUserID(ctx); userID != "" {
+				rec.UserId = util.StringPtr(userID)
+			}
+			if keyID, _ := ctx.Get(authApiKeyContextKey).(string); keyID != "" {
+				rec.ApiKeyId = util.StringPtr(keyID)
+			}
+			if ip := sourceIPFromContext(ctx); ip != "" {
+				rec.SourceIp = util.StringPtr(ip)
+			}
+			resourceID := ""
+			if values := ctx.ParamValues(); len(values) > 0 {
+				resourceID = values[0]
+			}
+			if resourceID == "" && rec.Result == db.AUDIT_SUCCESS {
+				resourceID = auditResourceIDFromBody(writer.body.Bytes())
+			}
+			if resourceID != "" {
+				rec.ResourceId = util.StringPtr(resourceID)
+			}
+
+			s.writeAuditRecord(rec)
+			return err
+		}
+	}
+}
+
+// writeAuditRecord は監査記録を保存する。保存に失敗してもリクエストは失敗させない
+func (s *Server) writeAuditRecord(rec api.AuditRecord) {
+	if s != nil && s.Ma != nil && s.Ma.Db != nil {
+		saved, err := s.Ma.Db.PutAuditRecord(rec)
+		if err != nil {
+			slog.Warn("failed to save audit record", "err", err, "operationId", rec.OperationId)
+		} else {
+			rec = saved
+		}
+	}
+
+	auditSinkState.mu.RLock()
+	sink := auditSinkState.sink
+	auditSinkState.mu.RUnlock()
+	if sink != nil {
+		sink.Write(rec)
+	}
+}
+
+// auditFilterFromParams は GET /audit の検索条件を検証して変換する
+func auditFilterFromParams(params api.ApiGetAuditRecordsParams) (db.AuditFilter, error) {
+	filter := db.AuditFilter{
+		UserID:      strings.TrimSpace(util.OrDefault(params.UserId, "")),
+		OperationID: strings.TrimSpace(util.OrDefault(params.OperationId, "")),
+		Resource:    strings.TrimSpace(util.OrDefault(params.Resource, "")),
+		ResourceID:  strings.TrimSpace(util.OrDefault(params.ResourceId, "")),
+		Result:      strings.ToLower(strings.TrimSpace(util.OrDefault(params.Result, ""))),
+		Limit:       util.OrDefault(params.Limit, auditDefaultLimit),
+	}
+	switch filter.Result {
+	case "", db.AUDIT_SUCCESS, db.AUDIT_FAILURE, db.AUDIT_DENIED:
+	default:
+		return db.AuditFilter{}, fmt.Errorf("result must be one of %s, %s, %s", db.AUDIT_SUCCESS, db.AUDIT_FAILURE, db.AUDIT_DENIED)
+	}
+	if filter.Limit < 1 || filter.Limit > auditMaxLimit {
+		return db.AuditFilter{}, fmt.Errorf("limit must be between 1 and %d", auditMaxLimit)
+	}
+	if params.Since != nil {
+		filter.Since = *params.Since
+	}
+	if params.Until != nil {
+		filter.Until = *params.Until
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return db.AuditFilter{}, fmt.Errorf("since must be before until")
+	}
+	return filter, nil
+}
+
+// 監査記録を新しい順に検索する
+func (s *Server) ApiGetAuditRecords(ctx echo.Context, params api.ApiGetAuditRecordsParams) error {
+	filter, err := auditFilterFromParams(params)
+	if err != nil {
+		return ctx.JSON(http.StatusBadRequest, api.Error{Code: 1, Message: err.Error()})
+	}
+	records, err := s.Ma.Db.GetAuditRecords(filter)
+	if err != nil {
+		slog.Error("GetAuditRecords()", "err", err)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	return ctx.JSON(http.StatusOK, records)
+}
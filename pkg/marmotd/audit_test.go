@@ -0,0 +1,157 @@
+package marmotd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestAuditResult(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusOK, db.AUDIT_SUCCESS},
+		{http.StatusCreated, db.AUDIT_SUCCESS},
+		{http.StatusNoContent, db.AUDIT_SUCCESS},
+		{http.StatusBadRequest, db.AUDIT_FAILURE},
+		{http.StatusUnauthorized, db.AUDIT_DENIED},
+		{http.StatusForbidden, db.AUDIT_DENIED},
+		{http.StatusNotFound, db.AUDIT_FAILURE},
+		{http.StatusInternalServerError, db.AUDIT_FAILURE},
+	}
+	for _, tt := range tests {
+		if got := auditResult(tt.status); got != tt.want {
+			t.Errorf("auditResult(%d) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestAuditResourceIDFromBody(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"id":"ab12c","name":"web"}`, "ab12c"},
+		{`{"id":"ignored","metadata":{"id":"cd34e"}}`, "cd34e"},
+		{`{"id":1}`, ""},
+		{`[{"id":"ab12c"}]`, ""},
+		{`{"accessToken":"tok`, ""},
+	}
+	for _, tt := range tests {
+		if got := auditResourceIDFromBody([]byte(tt.body)); got != tt.want {
+			t.Errorf("auditResourceIDFromBody(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestAuditFilterFromParams(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(24 * time.Hour)
+	filter, err := auditFilterFromParams(api.ApiGetAuditRecordsParams{
+		UserId: util.StringPtr(" alice "),
+		Result: util.StringPtr("Denied"),
+		Since:  &since,
+		Until:  &until,
+	})
+	if err != nil {
+		t.Fatalf("auditFilterFromParams() error = %v", err)
+	}
+	if filter.UserID != "alice" || filter.Result != db.AUDIT_DENIED || filter.Limit != auditDefaultLimit {
+		t.Fatalf("filter = %+v, want trimmed user, lower-case result and the default limit", filter)
+	}
+	if !filter.Since.Equal(since) || !filter.Until.Equal(until) {
+		t.Fatalf("filter period = %v - %v, want %v - %v", filter.Since, filter.Until, since, until)
+	}
+
+	zero, over := 0, auditMaxLimit+1
+	invalid := []struct {
+		name   string
+		params api.ApiGetAuditRecordsParams
+		want   string
+	}{
+		{"unknown result", api.ApiGetAuditRecordsParams{Result: util.StringPtr("ok")}, "result must be one of"},
+		{"zero limit", api.ApiGetAuditRecordsParams{Limit: &zero}, "limit must be between"},
+		{"too large limit", api.ApiGetAuditRecordsParams{Limit: &over}, "limit must be between"},
+		{"reversed period", api.ApiGetAuditRecordsParams{Since: &until, Until: &since}, "since must be before until"},
+	}
+	for _, tt := range invalid {
+		if _, err := auditFilterFromParams(tt.params); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: auditFilterFromParams() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestAuditMiddlewareWritesRecords(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "audit.log")
+	closeSink, err := SetupAuditLog(&MarmotdConfig{AuditLogFile: logFile})
+	if err != nil {
+		t.Fatalf("SetupAuditLog() error = %v", err)
+	}
+
+	s := &Server{}
+	e := echo.New()
+	e.GET("/server/:id", func(ctx echo.Context) error {
+		return ctx.JSON(http.StatusOK, map[string]string{"id": ctx.Param("id")})
+	}, s.auditMiddleware("apiGetServerById", "Server"))
+	e.POST("/server", func(ctx echo.Context) error {
+		ctx.Set(authUserContextKey, "alice")
+		ctx.Set(authApiKeyContextKey, "k1")
+		return ctx.JSON(http.StatusCreated, map[string]any{"id": "ab12c", "metadata": map[string]string{"id": "ab12c"}})
+	}, s.auditMiddleware("apiCreateServer", "Server"))
+	e.DELETE("/server/:id", func(ctx echo.Context) error {
+		return apiErrorJSON(ctx, http.StatusForbidden, "forbidden")
+	}, s.auditMiddleware("apiDeleteServerById", "Server"))
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/server/ab12c", nil),
+		httptest.NewRequest(http.MethodPost, "/server", strings.NewReader(`{}`)),
+		httptest.NewRequest(http.MethodDelete, "/server/cd34e", nil),
+	} {
+		req.RemoteAddr = "192.0.2.10:40000"
+		e.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	if err := closeSink(t.Context()); err != nil {
+		t.Fatalf("close audit sink: %v", err)
+	}
+
+	data, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("audit log has %d lines, want 2 (GET must not be recorded):\n%s", len(lines), data)
+	}
+
+	var created, deleted api.AuditRecord
+	if err := json.Unmarshal([]byte(lines[0]), &created); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &deleted); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if created.OperationId != "apiCreateServer" || created.Result != db.AUDIT_SUCCESS || created.StatusCode != http.StatusCreated {
+		t.Fatalf("created record = %+v, want a successful apiCreateServer", created)
+	}
+	if util.OrDefault(created.UserId, "") != "alice" || util.OrDefault(created.ApiKeyId, "") != "k1" {
+		t.Fatalf("created record user = %v key = %v, want alice k1", created.UserId, created.ApiKeyId)
+	}
+	if util.OrDefault(created.ResourceId, "") != "ab12c" || util.OrDefault(created.SourceIp, "") != "192.0.2.10" {
+		t.Fatalf("created record resourceId = %v sourceIp = %v, want ab12c 192.0.2.10", created.ResourceId, created.SourceIp)
+	}
+	if deleted.Result != db.AUDIT_DENIED || util.OrDefault(deleted.ResourceId, "") != "cd34e" || deleted.UserId != nil {
+		t.Fatalf("deleted record = %+v, want a denied request for cd34e without user", deleted)
+	}
+}
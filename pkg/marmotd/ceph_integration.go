@@ -15,6 +15,10 @@ import (
 type cephVolumeClient interface {
 	CreateVolume(ctx context.Context, req ceph.VolumeRequest) error
 	DeleteVolume(ctx context.Context, pool, image string) error
+	ResizeVolume(ctx context.Context, pool, image string, sizeGB int) error
+	CloneVolume(ctx context.Context, pool, image, snapshot string, dest ceph.VolumeRequest) error
+	FlattenVolume(ctx context.Context, pool, image string) error
+	RemoveSnapshot(ctx context.Context, pool, image, snapshot string) error
 	StatVolume(ctx context.Context, pool, image string) (ceph.VolumeInfo, error)
 	ListVolumes(ctx context.Context, pool string) ([]string, error)
 	Cleanup() error
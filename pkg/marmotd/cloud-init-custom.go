@@ -0,0 +1,374 @@
+package marmotd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"text/template"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+// ユーザー指定の cloud-init
+// spec.cloudInit の user-data と vendor-data を Go テンプレートとして展開し、marmot が生成する
+// ユーザー、パスワード、SSH 公開鍵の設定に合成する。ネットワークは marmot が netplan で設定するため、
+// cloud-init からの変更は受け付けない
+
+// 展開後の文書の上限。NoCloud の ISO に収める設定として十分な大きさにする
+const maxCloudInitDocumentSize = 64 * 1024
+
+var ErrInvalidCloudInit = errors.New("invalid spec.cloudInit")
+
+// cloud-init が解釈する MIME パートの種類のうち、受け付けるもの
+var allowedCloudInitPartTypes = map[string]bool{
+	"text/cloud-config":               true,
+	"text/x-shellscript":              true,
+	"text/x-shellscript-per-boot":     true,
+	"text/x-shellscript-per-instance": true,
+	"text/x-shellscript-per-once":     true,
+	"text/cloud-boothook":             true,
+}
+
+// marmot が管理するため、user-data の cloud-config で指定できないキー
+var reservedUserDataKeys = map[string]string{
+	"network":  "network settings are managed by marmot",
+	"password": "use spec.auth to set the password",
+	"chpasswd": "use spec.auth to set the password",
+}
+
+// vendor-data は user-data に上書きされるため、marmot が生成するキーも指定できない
+var reservedVendorDataKeys = map[string]string{
+	"network":             "network settings are managed by marmot",
+	"password":            "use spec.auth to set the password",
+	"chpasswd":            "use spec.auth to set the password",
+	"users":               "use spec.auth to add users",
+	"ssh_authorized_keys": "use spec.auth to add SSH keys",
+}
+
+// CloudInitDocuments は展開済みのユーザー指定の cloud-init の文書
+type CloudInitDocuments struct {
+	UserData   string
+	VendorData string
+}
+
+// cloudInitTemplateData はテンプレートから参照できる値
+type cloudInitTemplateData struct {
+	ServerName string
+	ServerId   string
+	NodeName   string
+	Project    string
+	Vars       map[string]string
+}
+
+type cloudInitPart struct {
+	contentType string
+	body        string
+}
+
+// RenderServerCloudInit は spec.cloudInit のテンプレートを展開して検証する
+// spec.cloudInit が無い場合は nil を返す
+func RenderServerCloudInit(server api.Server) (*CloudInitDocuments, error) {
+	ci := server.Spec.CloudInit
+	if ci == nil {
+		return nil, nil
+	}
+	data := cloudInitTemplateData{
+		ServerName: server.Metadata.Name,
+		ServerId:   api.ServerID(server),
+		NodeName:   util.OrDefault(server.Metadata.NodeName, ""),
+		Project:    util.OrDefault(server.Metadata.Project, ""),
+		Vars:       map[string]string{},
+	}
+	if ci.Variables != nil {
+		for k, v := range *ci.Variables {
+			if strings.TrimSpace(k) == "" {
+				return nil, fmt.Errorf("%w: variables has an empty key", ErrInvalidCloudInit)
+			}
+			data.Vars[k] = v
+		}
+	}
+
+	var docs CloudInitDocuments
+	var err error
+	if docs.UserData, err = renderCloudInitTemplate("userData", optionalTrimmedString(ci.UserData), data); err != nil {
+		return nil, err
+	}
+	if docs.VendorData, err = renderCloudInitTemplate("vendorData", optionalTrimmedString(ci.VendorData), data); err != nil {
+		return nil, err
+	}
+	if docs.UserData == "" && docs.VendorData == "" {
+		return nil, nil
+	}
+	if err := validateCloudInitDocuments(&docs); err != nil {
+		return nil, err
+	}
+	return &docs, nil
+}
+
+// ValidateServerCloudInit は API で受け付ける時点で spec.cloudInit を検証する
+// テンプレートの参照先と文書の形式に加えて、marmot が生成する設定との合成を試して衝突を確認する
+func ValidateServerCloudInit(server api.Server) error {
+	docs, err := RenderServerCloudInit(server)
+	if err != nil || docs == nil || docs.UserData == "" {
+		return err
+	}
+	// 公開鍵の取得は ISO の作成時に行うため、ここではユーザー名だけを使う
+	auth := server.Spec.Auth
+	if auth != nil {
+		a := *auth
+		a.Url = nil
+		auth = &a
+	}
+	password, _, usernames, err := cloudInitAuthInputs(auth)
+	if err != nil {
+		return err
+	}
+	base, err := buildCloudInitUserData(password, "", usernames, server.Spec.Ansible)
+	if err != nil {
+		return err
+	}
+	_, err = mergeCloudInitUserData(base, usernames, docs.UserData)
+	return err
+}
+
+func renderCloudInitTemplate(field, text string, data cloudInitTemplateData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := template.New(field).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidCloudInit, field, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrInvalidCloudInit, field, err)
+	}
+	if buf.Len() > maxCloudInitDocumentSize {
+		return "", fmt.Errorf("%w: %s exceeds %d bytes", ErrInvalidCloudInit, field, maxCloudInitDocumentSize)
+	}
+	return strings.TrimSpace(buf.String()) + "\n", nil
+}
+
+func validateCloudInitDocuments(docs *CloudInitDocuments) error {
+	for _, d := range []struct {
+		field    string
+		text     string
+		reserved map[string]string
+	}{
+		{"userData", docs.UserData, reservedUserDataKeys},
+		{"vendorData", docs.VendorData, reservedVendorDataKeys},
+	} {
+		if d.text == "" {
+			continue
+		}
+		parts, err := parseCloudInitDocument(d.field, d.text)
+		if err != nil {
+			return err
+		}
+		for _, part := range parts {
+			if part.contentType != "text/cloud-config" {
+				continue
+			}
+			cfg, err := parseCloudConfig(d.field, part.body)
+			if err != nil {
+				return err
+			}
+			for key := range cfg {
+				if reason, ok := d.reserved[key]; ok {
+					return fmt.Errorf("%w: %s cannot set %q: %s", ErrInvalidCloudInit, d.field, key, reason)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseCloudInitDocument は cloud-config、シェルスクリプト、MIME マルチパートの文書をパートに分ける
+func parseCloudInitDocument(field, text string) ([]cloudInitPart, error) {
+	switch {
+	case strings.HasPrefix(text, "#cloud-config"):
+		return []cloudInitPart{{contentType: "text/cloud-config", body: text}}, nil
+	case strings.HasPrefix(text, "#!"):
+		return []cloudInitPart{{contentType: "text/x-shellscript", body: text}}, nil
+	case strings.HasPrefix(text, "Content-Type:") || strings.HasPrefix(text, "MIME-Version:"):
+		return parseCloudInitMultipart(field, text)
+	}
+	return nil, fmt.Errorf("%w: %s must start with #cloud-config, #! or a MIME multipart header", ErrInvalidCloudInit, field)
+}
+
+func parseCloudInitMultipart(field, text string) ([]cloudInitPart, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(text))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCloudInit, field, err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return nil, fmt.Errorf("%w: %s must be a multipart/mixed document with a boundary", ErrInvalidCloudInit, field)
+	}
+
+	var parts []cloudInitPart
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCloudInit, field, err)
+		}
+		contentType, _, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		if err != nil || !allowedCloudInitPartTypes[contentType] {
+			return nil, fmt.Errorf("%w: %s has an unsupported part type %q", ErrInvalidCloudInit, field, p.Header.Get("Content-Type"))
+		}
+		body, err := io.ReadAll(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCloudInit, field, err)
+		}
+		switch strings.ToLower(strings.TrimSpace(p.Header.Get("Content-Transfer-Encoding"))) {
+		case "", "7bit", "8bit", "binary":
+		case "base64":
+			if body, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(body)), "")); err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCloudInit, field, err)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s has an unsupported transfer encoding %q", ErrInvalidCloudInit, field, p.Header.Get("Content-Transfer-Encoding"))
+		}
+		parts = append(parts, cloudInitPart{contentType: contentType, body: string(body)})
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("%w: %s has no parts", ErrInvalidCloudInit, field)
+	}
+	return parts, nil
+}
+
+func parseCloudConfig(field, body string) (map[string]interface{}, error) {
+	cfg := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(body), &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %s is not a valid cloud-config: %v", ErrInvalidCloudInit, field, err)
+	}
+	return cfg, nil
+}
+
+// mergeCloudInitUserData は marmot が生成した cloud-config にユーザー指定の user-data を合成する
+// cloud-config のパートは marmot の設定に合成し、リストは marmot の要素の後ろに追加する
+// シェルスクリプトなどのパートがある場合は、合成した cloud-config を先頭にした MIME マルチパートにする
+func mergeCloudInitUserData(base string, usernames []string, userData string) (string, error) {
+	merged, err := parseCloudConfig("user-data", base)
+	if err != nil {
+		return "", err
+	}
+	parts, err := parseCloudInitDocument("userData", userData)
+	if err != nil {
+		return "", err
+	}
+
+	var others []cloudInitPart
+	for _, part := range parts {
+		if part.contentType != "text/cloud-config" {
+			others = append(others, part)
+			continue
+		}
+		cfg, err := parseCloudConfig("userData", part.body)
+		if err != nil {
+			return "", err
+		}
+		if err := checkCloudConfigUsers(cfg, usernames); err != nil {
+			return "", err
+		}
+		if err := mergeCloudConfig(merged, cfg); err != nil {
+			return "", err
+		}
+	}
+
+	out, err := yaml.Marshal(merged)
+	if err != nil {
+		return "", err
+	}
+	cloudConfig := "#cloud-config\n" + string(out)
+	if len(others) == 0 {
+		return cloudConfig, nil
+	}
+	return buildCloudInitMultipart(append([]cloudInitPart{{contentType: "text/cloud-config", body: cloudConfig}}, others...))
+}
+
+// checkCloudConfigUsers は marmot が作成するユーザーをユーザー指定の users で再定義していないか確認する
+func checkCloudConfigUsers(cfg map[string]interface{}, usernames []string) error {
+	users, ok := cfg["users"].([]interface{})
+	if !ok {
+		return nil
+	}
+	for _, u := range users {
+		entry, ok := u.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := entry["name"].(string)
+		for _, username := range normalizeUsernames(usernames) {
+			if strings.TrimSpace(name) == username {
+				return fmt.Errorf("%w: userData redefines user %q created from spec.auth", ErrInvalidCloudInit, username)
+			}
+		}
+	}
+	return nil
+}
+
+func mergeCloudConfig(dst, src map[string]interface{}) error {
+	// users を指定すると既定のユーザーが作成されなくなるため、marmot が既定のユーザーを使う場合は残す
+	if _, ok := src["users"]; ok {
+		if _, exists := dst["users"]; !exists {
+			dst["users"] = []interface{}{"default"}
+		}
+	}
+	for key, value := range src {
+		current, exists := dst[key]
+		if !exists {
+			dst[key] = value
+			continue
+		}
+		switch cur := current.(type) {
+		case []interface{}:
+			if add, ok := value.([]interface{}); ok {
+				dst[key] = append(cur, add...)
+				continue
+			}
+		case bool:
+			if add, ok := value.(bool); ok {
+				dst[key] = cur || add
+				continue
+			}
+		}
+		return fmt.Errorf("%w: userData key %q conflicts with the settings generated by marmot", ErrInvalidCloudInit, key)
+	}
+	return nil
+}
+
+func buildCloudInitMultipart(parts []cloudInitPart) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i, part := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType+`; charset="utf-8"`)
+		header.Set("MIME-Version", "1.0")
+		header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="part-%03d"`, i+1))
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(w, part.body); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Content-Type: multipart/mixed; boundary=%q\nMIME-Version: 1.0\n\n%s", writer.Boundary(), body.String()), nil
+}
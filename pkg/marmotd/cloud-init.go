@@ -16,7 +16,26 @@ import (
 // usernames が空の場合はデフォルトユーザーに設定し、指定がある場合は各ユーザーを作成します。
 // instanceID はVM毎に一意な値を渡すこと。テンプレートqcow2から複製されたVM間で同じ値を
 // 使うと、cloud-initが「処理済みインスタンス」と誤認してuser-dataの適用をスキップする。
-func GenerateCloudInitISO(path, password, sshKey string, usernames []string, ansible *api.ServerAnsible, instanceID string) (string, error) {
+// custom は RenderServerCloudInit で展開した spec.cloudInit で、ISO の作成前に検証して合成する。
+func GenerateCloudInitISO(path, password, sshKey string, usernames []string, ansible *api.ServerAnsible, instanceID string, custom *CloudInitDocuments) (string, error) {
+	// Generate user-data
+	userData, err := buildCloudInitUserData(password, sshKey, usernames, ansible)
+	if err != nil {
+		return "", err
+	}
+	vendorData := ""
+	if custom != nil {
+		if err := validateCloudInitDocuments(custom); err != nil {
+			return "", err
+		}
+		if custom.UserData != "" {
+			if userData, err = mergeCloudInitUserData(userData, usernames, custom.UserData); err != nil {
+				return "", err
+			}
+		}
+		vendorData = custom.VendorData
+	}
+
 	// Create temporary directory for cloud-init files
 	tempDir, err := os.MkdirTemp("", "cloud-init-")
 	if err != nil {
@@ -26,12 +45,6 @@ func GenerateCloudInitISO(path, password, sshKey string, usernames []string, ans
 		_ = os.RemoveAll(tempDir)
 	}()
 
-	// Generate user-data
-	userData, err := buildCloudInitUserData(password, sshKey, usernames, ansible)
-	if err != nil {
-		return "", err
-	}
-
 	userDataPath := filepath.Join(tempDir, "user-data")
 	if err := os.WriteFile(userDataPath, []byte(userData), 0644); err != nil {
 		err := fmt.Errorf("failed to write user-data: %v", err)
@@ -39,6 +52,16 @@ func GenerateCloudInitISO(path, password, sshKey string, usernames []string, ans
 		return "", err
 	}
 
+	// Generate vendor-data: spec.cloudInit.vendorData がある場合のみ
+	if vendorData != "" {
+		vendorDataPath := filepath.Join(tempDir, "vendor-data")
+		if err := os.WriteFile(vendorDataPath, []byte(vendorData), 0644); err != nil {
+			err := fmt.Errorf("failed to write vendor-data: %v", err)
+			slog.Error("Cloud-initの作成中に、ベンダーデータの書き込みに失敗", "error", err)
+			return "", err
+		}
+	}
+
 	// Generate meta-data (minimal)
 	if strings.TrimSpace(instanceID) == "" {
 		instanceID = "iid-local01"
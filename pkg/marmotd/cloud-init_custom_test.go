@@ -0,0 +1,182 @@
+package marmotd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+	"gopkg.in/yaml.v3"
+)
+
+func newCloudInitServer(ci api.ServerCloudInit) api.Server {
+	server := api.Server{Metadata: api.Metadata{Name: "web1"}}
+	server.Spec.CloudInit = &ci
+	server.Spec.Auth = &api.Auth{User: util.StringPtr("ubuntu")}
+	api.SetServerID(&server, "s0001")
+	return server
+}
+
+func TestRenderServerCloudInitTemplate(t *testing.T) {
+	server := newCloudInitServer(api.ServerCloudInit{
+		UserData:   util.StringPtr("#cloud-config\nhostname: {{ .ServerName }}-{{ .ServerId }}\ntimezone: {{ .Vars.tz }}\n"),
+		VendorData: util.StringPtr("#!/bin/sh\necho {{ .Vars.tz }}\n"),
+		Variables:  &map[string]string{"tz": "Asia/Tokyo"},
+	})
+	docs, err := RenderServerCloudInit(server)
+	if err != nil {
+		t.Fatalf("RenderServerCloudInit() error = %v", err)
+	}
+	if !strings.Contains(docs.UserData, "hostname: web1-s0001") || !strings.Contains(docs.UserData, "timezone: Asia/Tokyo") {
+		t.Fatalf("user-data = %q, want the expanded template", docs.UserData)
+	}
+	if docs.VendorData != "#!/bin/sh\necho Asia/Tokyo\n" {
+		t.Fatalf("vendor-data = %q", docs.VendorData)
+	}
+
+	if docs, err := RenderServerCloudInit(newCloudInitServer(api.ServerCloudInit{})); err != nil || docs != nil {
+		t.Fatalf("RenderServerCloudInit() = %v, %v, want nil for an empty spec.cloudInit", docs, err)
+	}
+}
+
+func TestRenderServerCloudInitRejects(t *testing.T) {
+	tests := map[string]api.ServerCloudInit{
+		"undefined variable": {UserData: util.StringPtr("#cloud-config\ntimezone: {{ .Vars.tz }}\n")},
+		"template syntax":    {UserData: util.StringPtr("#cloud-config\ntimezone: {{ .Vars.tz\n")},
+		"unknown format":     {UserData: util.StringPtr("packages: [nginx]\n")},
+		"invalid yaml":       {UserData: util.StringPtr("#cloud-config\npackages: [nginx\n")},
+		"network":            {UserData: util.StringPtr("#cloud-config\nnetwork:\n  version: 2\n")},
+		"password":           {UserData: util.StringPtr("#cloud-config\nchpasswd:\n  expire: false\n")},
+		"vendor users":       {VendorData: util.StringPtr("#cloud-config\nusers:\n  - name: admin\n")},
+		"part type":          {UserData: util.StringPtr("Content-Type: multipart/mixed; boundary=\"b\"\nMIME-Version: 1.0\n\n--b\nContent-Type: text/x-include-url\n\nhttp://example.com\n--b--\n")},
+		"too large":          {UserData: util.StringPtr("#cloud-config\n#" + strings.Repeat("x", maxCloudInitDocumentSize))},
+	}
+	for name, ci := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := RenderServerCloudInit(newCloudInitServer(ci)); !errors.Is(err, ErrInvalidCloudInit) {
+				t.Fatalf("RenderServerCloudInit() error = %v, want %v", err, ErrInvalidCloudInit)
+			}
+		})
+	}
+}
+
+func TestMergeCloudInitUserDataCloudConfig(t *testing.T) {
+	base, err := buildCloudInitUserData("12345", "ssh-rsa AAAA", []string{"ubuntu"}, nil)
+	if err != nil {
+		t.Fatalf("buildCloudInitUserData() error = %v", err)
+	}
+	got, err := mergeCloudInitUserData(base, []string{"ubuntu"}, "#cloud-config\ntimezone: Asia/Tokyo\npackages: [nginx]\nusers:\n  - name: deploy\n")
+	if err != nil {
+		t.Fatalf("mergeCloudInitUserData() error = %v", err)
+	}
+	if !strings.HasPrefix(got, "#cloud-config\n") {
+		t.Fatalf("user-data = %q, want a cloud-config", got)
+	}
+
+	var cfg struct {
+		Timezone string   `yaml:"timezone"`
+		Packages []string `yaml:"packages"`
+		Users    []struct {
+			Name string `yaml:"name"`
+		} `yaml:"users"`
+		Chpasswd map[string]interface{} `yaml:"chpasswd"`
+	}
+	if err := yaml.Unmarshal([]byte(got), &cfg); err != nil {
+		t.Fatalf("merged user-data is not valid yaml: %v", err)
+	}
+	if cfg.Timezone != "Asia/Tokyo" || len(cfg.Packages) != 1 || cfg.Chpasswd == nil {
+		t.Fatalf("merged = %+v, want the user settings and the password of spec.auth", cfg)
+	}
+	if len(cfg.Users) != 2 || cfg.Users[0].Name != "ubuntu" || cfg.Users[1].Name != "deploy" {
+		t.Fatalf("users = %+v, want ubuntu followed by deploy", cfg.Users)
+	}
+}
+
+func TestMergeCloudInitUserDataDefaultUser(t *testing.T) {
+	base, err := buildCloudInitUserData("", "ssh-rsa AAAA", nil, nil)
+	if err != nil {
+		t.Fatalf("buildCloudInitUserData() error = %v", err)
+	}
+	got, err := mergeCloudInitUserData(base, nil, "#cloud-config\nusers:\n  - name: deploy\n")
+	if err != nil {
+		t.Fatalf("mergeCloudInitUserData() error = %v", err)
+	}
+	var cfg struct {
+		Users []interface{} `yaml:"users"`
+	}
+	if err := yaml.Unmarshal([]byte(got), &cfg); err != nil {
+		t.Fatalf("merged user-data is not valid yaml: %v", err)
+	}
+	if len(cfg.Users) != 2 || cfg.Users[0] != "default" {
+		t.Fatalf("users = %v, want the default user to be kept", cfg.Users)
+	}
+}
+
+func TestMergeCloudInitUserDataMultipart(t *testing.T) {
+	base, err := buildCloudInitUserData("", "ssh-rsa AAAA", []string{"ubuntu"}, nil)
+	if err != nil {
+		t.Fatalf("buildCloudInitUserData() error = %v", err)
+	}
+	userData := "Content-Type: multipart/mixed; boundary=\"b\"\nMIME-Version: 1.0\n\n" +
+		"--b\nContent-Type: text/cloud-config\n\n#cloud-config\nruncmd:\n  - echo hello\n" +
+		"--b\nContent-Type: text/x-shellscript\n\n#!/bin/sh\necho script\n" +
+		"--b--\n"
+	got, err := mergeCloudInitUserData(base, []string{"ubuntu"}, userData)
+	if err != nil {
+		t.Fatalf("mergeCloudInitUserData() error = %v", err)
+	}
+
+	parts, err := parseCloudInitDocument("user-data", got)
+	if err != nil {
+		t.Fatalf("merged user-data is not a valid multipart: %v", err)
+	}
+	if len(parts) != 2 || parts[0].contentType != "text/cloud-config" || parts[1].contentType != "text/x-shellscript" {
+		t.Fatalf("parts = %+v, want the merged cloud-config followed by the script", parts)
+	}
+	if !strings.Contains(parts[0].body, "name: ubuntu") || !strings.Contains(parts[0].body, "echo hello") {
+		t.Fatalf("cloud-config = %q, want the marmot user and the user runcmd", parts[0].body)
+	}
+}
+
+func TestMergeCloudInitUserDataConflicts(t *testing.T) {
+	onBoot := true
+	base, err := buildCloudInitUserData("", "ssh-rsa AAAA", []string{"ubuntu"}, &api.ServerAnsible{
+		OnBoot: &onBoot,
+		Pull:   &api.ServerAnsiblePull{Url: util.StringPtr("https://example.com/site.git")},
+	})
+	if err != nil {
+		t.Fatalf("buildCloudInitUserData() error = %v", err)
+	}
+	tests := map[string]string{
+		"redefined user": "#cloud-config\nusers:\n  - name: ubuntu\n",
+		"ansible":        "#cloud-config\nansible:\n  install_method: pip\n",
+	}
+	for name, userData := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := mergeCloudInitUserData(base, []string{"ubuntu"}, userData); !errors.Is(err, ErrInvalidCloudInit) {
+				t.Fatalf("mergeCloudInitUserData() error = %v, want %v", err, ErrInvalidCloudInit)
+			}
+		})
+	}
+
+	// packages はリストとして追加し、package_update は有効な方を残す
+	got, err := mergeCloudInitUserData(base, []string{"ubuntu"}, "#cloud-config\npackages: [nginx]\npackage_update: false\n")
+	if err != nil {
+		t.Fatalf("mergeCloudInitUserData() error = %v", err)
+	}
+	if !strings.Contains(got, "- git") || !strings.Contains(got, "- nginx") || !strings.Contains(got, "package_update: true") {
+		t.Fatalf("user-data = %q, want git and nginx with package_update", got)
+	}
+}
+
+func TestValidateServerCloudInit(t *testing.T) {
+	server := newCloudInitServer(api.ServerCloudInit{UserData: util.StringPtr("#cloud-config\nusers:\n  - name: ubuntu\n")})
+	if err := ValidateServerCloudInit(server); !errors.Is(err, ErrInvalidCloudInit) {
+		t.Fatalf("ValidateServerCloudInit() error = %v, want %v", err, ErrInvalidCloudInit)
+	}
+	server = newCloudInitServer(api.ServerCloudInit{UserData: util.StringPtr("#cloud-config\ntimezone: Asia/Tokyo\n")})
+	if err := ValidateServerCloudInit(server); err != nil {
+		t.Fatalf("ValidateServerCloudInit() error = %v", err)
+	}
+}
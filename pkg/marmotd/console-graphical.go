@@ -0,0 +1,254 @@
+package marmotd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/virt"
+	"golang.org/x/net/websocket"
+)
+
+// グラフィカルコンソール (SPICE/VNC) の WebSocket プロキシ
+// ブラウザは WebSocket に Authorization ヘッダーを付けられないため、RBAC で認可して発行した短命なトークンで接続を認証する
+
+// コンソールのトークンで接続を開ける時間。トークンは 1 回の接続で無効になり、開いた接続は切断まで維持する
+const consoleTokenLifetime = 60 * time.Second
+
+var (
+	ErrInvalidConsoleToken     = errors.New("invalid or expired console token")
+	ErrConsoleOriginNotAllowed = errors.New("origin is not allowed to open the console")
+)
+
+// ApiCreateServerConsoleToken はグラフィカルコンソールに接続するトークンを発行する
+func (s *Server) ApiCreateServerConsoleToken(ctx echo.Context, id string) error {
+	slog.Debug("===ApiCreateServerConsoleToken() is called===", "id", id)
+
+	server, err := s.Ma.Db.GetServerById(id)
+	if errors.Is(err, db.ErrNotFound) {
+		return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: err.Error()})
+	}
+	if err != nil {
+		slog.Error("GetServerById()", "err", err, "id", id)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	if server.Status == nil || server.Status.StatusCode != db.SERVER_RUNNING {
+		return ctx.JSON(http.StatusConflict, api.Error{Code: 1, Message: "server is not RUNNING"})
+	}
+
+	if n, err := s.Ma.Db.DeleteExpiredConsoleTokens(time.Now()); err != nil {
+		slog.Warn("DeleteExpiredConsoleTokens()", "err", err)
+	} else if n > 0 {
+		slog.Debug("expired console tokens deleted", "count", n)
+	}
+
+	token, err := newConsoleToken()
+	if err != nil {
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	now := time.Now()
+	rec := db.ConsoleToken{
+		ServerId:  id,
+		UserId:    requestUserID(ctx),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(consoleTokenLifetime),
+	}
+	if err := s.Ma.Db.PutConsoleToken(token, rec); err != nil {
+		slog.Error("PutConsoleToken()", "err", err, "id", id)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	slog.Info("console token issued", "serverId", id, "userId", rec.UserId, "expiresAt", rec.ExpiresAt)
+
+	resp := api.ServerConsoleToken{
+		Token:     token,
+		ExpiresAt: rec.ExpiresAt,
+		Protocol:  virt.GraphicsProtocol,
+		Path:      graphicalConsolePath(ctx.Request().URL.Path, token),
+		NodeName:  server.Metadata.NodeName,
+	}
+	return ctx.JSON(http.StatusCreated, resp)
+}
+
+// ApiGraphicalConsoleServerById はトークンを確認し、WebSocket とサーバーのグラフィカルコンソールを中継する
+// サーバーのノードの marmotd に接続する必要がある
+func (s *Server) ApiGraphicalConsoleServerById(ctx echo.Context, id string, params api.ApiGraphicalConsoleServerByIdParams) error {
+	id = strings.TrimSpace(id)
+	// 他のサイトのページからの接続を拒否する。トークンを消費する前に確認する
+	if err := checkConsoleOrigin(ctx.Request(), CurrentConfig().ConsoleAllowedOrigins); err != nil {
+		slog.Warn("checkConsoleOrigin()", "err", err, "id", id, "origin", ctx.Request().Header.Get("Origin"))
+		return apiErrorJSON(ctx, http.StatusForbidden, err.Error())
+	}
+	rec, err := s.validateConsoleToken(id, params.Token, time.Now())
+	if errors.Is(err, ErrInvalidConsoleToken) {
+		return apiErrorJSON(ctx, http.StatusUnauthorized, err.Error())
+	}
+	if err != nil {
+		slog.Error("validateConsoleToken()", "err", err, "id", id)
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	ctx.Set(authUserContextKey, rec.UserId)
+
+	server, err := s.Ma.GetServerManage(id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "IDが存在しません"})
+		}
+		return ctx.JSON(http.StatusInternalServerError, api.Error{Code: 1, Message: err.Error()})
+	}
+	endpoint, err := resolveGraphicsEndpoint(server)
+	if err != nil {
+		slog.Error("resolveGraphicsEndpoint()", "err", err, "id", id)
+		return ctx.JSON(http.StatusNotFound, api.Error{Code: 1, Message: "graphical console is not available on this node: " + err.Error()})
+	}
+
+	target := net.JoinHostPort(endpoint.Address, strconv.Itoa(endpoint.Port))
+	wsServer := websocket.Server{
+		// Origin は checkConsoleOrigin で確認済み。noVNC などが要求する binary サブプロトコルに応じる
+		Handshake: func(cfg *websocket.Config, _ *http.Request) error {
+			protocols := cfg.Protocol
+			cfg.Protocol = nil
+			for _, p := range protocols {
+				if strings.EqualFold(strings.TrimSpace(p), "binary") {
+					cfg.Protocol = []string{"binary"}
+					break
+				}
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			ws.PayloadType = websocket.BinaryFrame
+			conn, err := net.DialTimeout("tcp", target, 5*time.Second)
+			if err != nil {
+				slog.Error("ApiGraphicalConsoleServerById() dial failed", "id", id, "target", target, "err", err)
+				_ = ws.Close()
+				return
+			}
+			slog.Info("graphical console connected", "serverId", id, "userId", rec.UserId, "protocol", endpoint.Protocol)
+			if err := relayGraphicsConsole(ws, conn); err != nil {
+				slog.Debug("ApiGraphicalConsoleServerById() relay finished", "id", id, "err", err)
+			}
+		},
+	}
+	wsServer.ServeHTTP(ctx.Response(), ctx.Request())
+	return nil
+}
+
+// validateConsoleToken はトークンを消費し、対象のサーバーに発行され、有効期限内であることを確認する
+// URL の漏洩による再利用を防ぐため、トークンは確認の結果に関わらず 1 回で無効になる
+func (s *Server) validateConsoleToken(serverId, token string, now time.Time) (db.ConsoleToken, error) {
+	rec, err := s.Ma.Db.TakeConsoleToken(token)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.ConsoleToken{}, ErrInvalidConsoleToken
+	}
+	if err != nil {
+		return db.ConsoleToken{}, err
+	}
+	if err := checkConsoleToken(rec, serverId, now); err != nil {
+		return db.ConsoleToken{}, err
+	}
+	return rec, nil
+}
+
+func checkConsoleToken(rec db.ConsoleToken, serverId string, now time.Time) error {
+	if strings.TrimSpace(rec.ServerId) == "" || rec.ServerId != strings.TrimSpace(serverId) {
+		return ErrInvalidConsoleToken
+	}
+	if !now.Before(rec.ExpiresAt) {
+		return ErrInvalidConsoleToken
+	}
+	return nil
+}
+
+// checkConsoleOrigin は Origin ヘッダーがある場合に、API サーバーと同じホストか console_allowed_origins に含まれることを確認する
+// ブラウザ以外のクライアントは Origin を付けない場合がある
+func checkConsoleOrigin(r *http.Request, allowed []string) error {
+	origin := strings.TrimSpace(r.Header.Get("Origin"))
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return ErrConsoleOriginNotAllowed
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimRight(a, "/"), strings.TrimRight(origin, "/")) {
+			return nil
+		}
+	}
+	return ErrConsoleOriginNotAllowed
+}
+
+func newConsoleToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// graphicalConsolePath はトークン発行のパスから WebSocket のパスを組み立てる
+func graphicalConsolePath(tokenPath, token string) string {
+	base := strings.TrimSuffix(strings.TrimRight(tokenPath, "/"), "/token")
+	return base + "/graphical?token=" + url.QueryEscape(token)
+}
+
+// resolveGraphicsEndpoint はこのノードで稼働するドメインのグラフィカルコンソールの待ち受けアドレスを返す
+func resolveGraphicsEndpoint(server api.Server) (virt.GraphicsEndpoint, error) {
+	if server.Metadata.InstanceName == nil || strings.TrimSpace(*server.Metadata.InstanceName) == "" {
+		return virt.GraphicsEndpoint{}, errors.New("server has no instance name")
+	}
+
+	l, err := virt.NewLibVirtEp("qemu:///system")
+	if err != nil {
+		return virt.GraphicsEndpoint{}, err
+	}
+	defer l.Close()
+
+	dom, err := l.Com.LookupDomainByName(strings.TrimSpace(*server.Metadata.InstanceName))
+	if err != nil {
+		return virt.GraphicsEndpoint{}, err
+	}
+	defer func() {
+		_ = dom.Free()
+	}()
+	return virt.GetDomainGraphics(dom)
+}
+
+// relayGraphicsConsole は WebSocket とコンソールの TCP 接続を双方向に中継する
+// どちらかが終了したら両方を閉じる
+func relayGraphicsConsole(ws io.ReadWriteCloser, conn net.Conn) error {
+	defer func() {
+		_ = ws.Close()
+		_ = conn.Close()
+	}()
+
+	copyErr := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(conn, ws)
+		copyErr <- err
+	}()
+	go func() {
+		_, err := io.Copy(ws, conn)
+		copyErr <- err
+	}()
+
+	err := <-copyErr
+	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package marmotd
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/pkg/db"
+)
+
+func TestCheckConsoleToken(t *testing.T) {
+	now := time.Now()
+	rec := db.ConsoleToken{ServerId: "s0001", UserId: "u1", IssuedAt: now, ExpiresAt: now.Add(consoleTokenLifetime)}
+	if err := checkConsoleToken(rec, "s0001", now); err != nil {
+		t.Fatalf("checkConsoleToken() error = %v", err)
+	}
+	if err := checkConsoleToken(rec, "s0002", now); !errors.Is(err, ErrInvalidConsoleToken) {
+		t.Fatalf("checkConsoleToken() for another server error = %v, want %v", err, ErrInvalidConsoleToken)
+	}
+	if err := checkConsoleToken(rec, "s0001", rec.ExpiresAt); !errors.Is(err, ErrInvalidConsoleToken) {
+		t.Fatalf("checkConsoleToken() after expiry error = %v, want %v", err, ErrInvalidConsoleToken)
+	}
+}
+
+func TestCheckConsoleOrigin(t *testing.T) {
+	allowed := []string{"https://console.labo.local/"}
+	tests := []struct {
+		origin string
+		ok     bool
+	}{
+		{"", true},
+		{"https://marmot1.labo.local:8750", true},
+		{"http://MARMOT1.labo.local:8750", true},
+		{"https://console.labo.local", true},
+		{"https://evil.example.com", false},
+		{"https://marmot1.labo.local:9999", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/server/s0001/console/graphical?token=x", nil)
+		req.Host = "marmot1.labo.local:8750"
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		err := checkConsoleOrigin(req, allowed)
+		if tt.ok && err != nil {
+			t.Errorf("checkConsoleOrigin(%q) error = %v", tt.origin, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrConsoleOriginNotAllowed) {
+			t.Errorf("checkConsoleOrigin(%q) error = %v, want %v", tt.origin, err, ErrConsoleOriginNotAllowed)
+		}
+	}
+}
+
+func TestGraphicalConsolePath(t *testing.T) {
+	got := graphicalConsolePath("/api/v1/server/s0001/console/token", "a+b")
+	if want := "/api/v1/server/s0001/console/graphical?token=a%2Bb"; got != want {
+		t.Fatalf("graphicalConsolePath() = %q, want %q", got, want)
+	}
+}
+
+func TestNewConsoleTokenIsRandom(t *testing.T) {
+	a, err := newConsoleToken()
+	if err != nil {
+		t.Fatalf("newConsoleToken() error = %v", err)
+	}
+	b, _ := newConsoleToken()
+	if len(a) != 43 || a == b {
+		t.Fatalf("newConsoleToken() = %q, %q, want distinct 256-bit tokens", a, b)
+	}
+}
+
+func TestRelayGraphicsConsole(t *testing.T) {
+	client, wsSide := net.Pipe()
+	consoleSide, display := net.Pipe()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- relayGraphicsConsole(wsSide, consoleSide)
+	}()
+
+	go func() {
+		_, _ = client.Write([]byte("RFB"))
+	}()
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(display, buf); err != nil || string(buf) != "RFB" {
+		t.Fatalf("display read = %q, %v", buf, err)
+	}
+	go func() {
+		_, _ = display.Write([]byte("ok"))
+	}()
+	buf = make([]byte, 2)
+	if _, err := io.ReadFull(client, buf); err != nil || string(buf) != "ok" {
+		t.Fatalf("client read = %q, %v", buf, err)
+	}
+
+	// ブラウザ側が切断したらコンソール側も閉じる
+	_ = client.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("relayGraphicsConsole() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("relayGraphicsConsole() did not return after the client closed")
+	}
+	if _, err := display.Read(make([]byte, 1)); err == nil {
+		t.Fatal("console side is still open")
+	}
+}
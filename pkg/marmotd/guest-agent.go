@@ -0,0 +1,276 @@
+package marmotd
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+// QEMU ゲストエージェントとの連携
+// サーバーコントローラーが稼働中のサーバーのホスト名、OS、IPアドレスを status.guestInfo に記録し、
+// API が登録したパスワード設定の要求をサーバーのノードで適用する
+
+var ErrInvalidGuestPassword = errors.New("invalid password request")
+
+// guestInfoFromVirt はゲストエージェントの情報を API の形式に変換する
+func guestInfoFromVirt(gi virt.GuestInfo) *api.ServerGuestInfo {
+	info := &api.ServerGuestInfo{
+		Hostname:      optionalString(gi.Hostname),
+		OsName:        optionalString(gi.OSName),
+		OsVersion:     optionalString(gi.OSVersion),
+		KernelRelease: optionalString(gi.KernelRelease),
+	}
+	if len(gi.Interfaces) > 0 {
+		ifaces := make([]api.ServerGuestInterface, 0, len(gi.Interfaces))
+		for _, nic := range gi.Interfaces {
+			gif := api.ServerGuestInterface{
+				Name:       optionalString(nic.Name),
+				MacAddress: optionalString(nic.MacAddress),
+			}
+			if len(nic.IPAddresses) > 0 {
+				addrs := append([]string(nil), nic.IPAddresses...)
+				gif.IpAddresses = &addrs
+			}
+			ifaces = append(ifaces, gif)
+		}
+		info.Interfaces = &ifaces
+	}
+	return info
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return util.StringPtr(s)
+}
+
+// guestInfoChanged は記録済みの情報から変化があるかを返す。更新時刻は比較しない
+func guestInfoChanged(current, next *api.ServerGuestInfo) bool {
+	if current == nil || next == nil {
+		return current != next
+	}
+	a, b := *current, *next
+	a.LastUpdated, b.LastUpdated = nil, nil
+	return !reflect.DeepEqual(a, b)
+}
+
+// SyncServerGuestInfoManage はゲストエージェントから情報を取得して status.guestInfo を更新する
+// ゲストエージェントが応答しない場合は記録済みの情報を残す。コントローラーから呼び出される
+func (m *Marmot) SyncServerGuestInfoManage(id string) error {
+	sv, err := m.Db.GetServerById(id)
+	if err != nil {
+		return err
+	}
+	if sv.Metadata.InstanceName == nil || strings.TrimSpace(*sv.Metadata.InstanceName) == "" {
+		return nil
+	}
+
+	l, err := virt.NewLibVirtEp("qemu:///system")
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	gi, err := l.GuestAgentInfo(*sv.Metadata.InstanceName)
+	if err != nil {
+		slog.Debug("GuestAgentInfo()", "serverId", id, "err", err)
+		return nil
+	}
+	info := guestInfoFromVirt(gi)
+	var current *api.ServerGuestInfo
+	if sv.Status != nil {
+		current = sv.Status.GuestInfo
+	}
+	if !guestInfoChanged(current, info) {
+		return nil
+	}
+	info.LastUpdated = util.TimePtr(time.Now())
+	return m.Db.UpdateServerGuestInfo(id, info)
+}
+
+// freezeGuestFilesystems はゲストのファイルシステムを凍結し、解除する関数を返す
+// ゲストエージェントが応答しない場合は凍結せずに続行する
+func freezeGuestFilesystems(l *virt.LibVirtEp, instanceName string) func() {
+	if err := l.FreezeGuestFilesystems(instanceName); err != nil {
+		slog.Warn("FreezeGuestFilesystems() failed; continue without freezing", "instanceName", instanceName, "err", err)
+		return func() {}
+	}
+	return func() {
+		if err := l.ThawGuestFilesystems(instanceName); err != nil {
+			slog.Error("ThawGuestFilesystems()", "instanceName", instanceName, "err", err)
+		}
+	}
+}
+
+// NewGuestPasswordRequest はパスワード設定の要求を検証し、パスワードをハッシュ化した要求を返す
+func NewGuestPasswordRequest(serverId string, req api.ServerPasswordRequest) (db.GuestPasswordRequest, error) {
+	user := strings.TrimSpace(req.User)
+	if user == "" || strings.ContainsAny(user, ": \t\r\n") {
+		return db.GuestPasswordRequest{}, fmt.Errorf("%w: user must be a non-empty name without spaces or colons", ErrInvalidGuestPassword)
+	}
+	if req.Password == "" || strings.ContainsAny(req.Password, "\r\n") {
+		return db.GuestPasswordRequest{}, fmt.Errorf("%w: password must be non-empty and single-line", ErrInvalidGuestPassword)
+	}
+	salt, err := newCryptSalt()
+	if err != nil {
+		return db.GuestPasswordRequest{}, err
+	}
+	return db.GuestPasswordRequest{
+		ServerId:        serverId,
+		User:            user,
+		CryptedPassword: sha512Crypt(req.Password, salt),
+		RequestedAt:     time.Now(),
+	}, nil
+}
+
+// ApplyGuestPasswordManage は登録されたパスワード設定の要求をゲストエージェントで適用する
+// 要求は成否にかかわらず削除し、失敗した場合はサーバーのステータスのメッセージに記録する。コントローラーから呼び出される
+func (m *Marmot) ApplyGuestPasswordManage(id string) error {
+	req, err := m.Db.GetGuestPasswordRequest(id)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sv, err := m.Db.GetServerById(id)
+	if err != nil {
+		return err
+	}
+	if sv.Metadata.InstanceName == nil || strings.TrimSpace(*sv.Metadata.InstanceName) == "" {
+		return fmt.Errorf("server %s has no instance name", id)
+	}
+
+	l, err := virt.NewLibVirtEp("qemu:///system")
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	applyErr := l.SetGuestUserPassword(*sv.Metadata.InstanceName, req.User, req.CryptedPassword)
+	if err := m.Db.DeleteGuestPasswordRequest(id); err != nil {
+		return err
+	}
+	if applyErr != nil {
+		msg := fmt.Sprintf("ユーザー %s のパスワードの設定に失敗: %v", req.User, applyErr)
+		if dbErr := m.Db.UpdateServerStatus(id, db.SERVER_RUNNING, msg); dbErr != nil {
+			slog.Error("UpdateServerStatus() failed", "serverId", id, "err", dbErr)
+		}
+		return applyErr
+	}
+	slog.Info("guest password updated", "serverId", id, "user", req.User)
+	return nil
+}
+
+// SHA-512 crypt の文字セット
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func newCryptSalt() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = cryptAlphabet[int(b)%len(cryptAlphabet)]
+	}
+	return string(buf), nil
+}
+
+// sha512Crypt はパスワードを glibc の crypt(3) 互換の SHA-512 形式 ($6$) でハッシュ化する
+// ゲストエージェントの guest-set-user-password に crypted として渡す。rounds は既定の 5000
+func sha512Crypt(password, salt string) string {
+	const rounds = 5000
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+	p, s := []byte(password), []byte(salt)
+
+	alt := sha512.New()
+	alt.Write(p)
+	alt.Write(s)
+	alt.Write(p)
+	altSum := alt.Sum(nil)
+
+	a := sha512.New()
+	a.Write(p)
+	a.Write(s)
+	for n := len(p); n > 0; n -= 64 {
+		a.Write(altSum[:min(n, 64)])
+	}
+	for n := len(p); n > 0; n >>= 1 {
+		if n&1 != 0 {
+			a.Write(altSum)
+		} else {
+			a.Write(p)
+		}
+	}
+	sum := a.Sum(nil)
+
+	dp := sha512.New()
+	for range p {
+		dp.Write(p)
+	}
+	pSeq := repeatBytes(dp.Sum(nil), len(p))
+
+	ds := sha512.New()
+	for i := 0; i < 16+int(sum[0]); i++ {
+		ds.Write(s)
+	}
+	sSeq := repeatBytes(ds.Sum(nil), len(s))
+
+	for i := 0; i < rounds; i++ {
+		c := sha512.New()
+		if i%2 != 0 {
+			c.Write(pSeq)
+		} else {
+			c.Write(sum)
+		}
+		if i%3 != 0 {
+			c.Write(sSeq)
+		}
+		if i%7 != 0 {
+			c.Write(pSeq)
+		}
+		if i%2 != 0 {
+			c.Write(sum)
+		} else {
+			c.Write(pSeq)
+		}
+		sum = c.Sum(nil)
+	}
+
+	var out strings.Builder
+	out.WriteString("$6$" + salt + "$")
+	encode := func(b2, b1, b0 byte, n int) {
+		w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+		for ; n > 0; n-- {
+			out.WriteByte(cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	// crypt(3) の SHA-512 形式のバイトの並び
+	for i := 0; i < 21; i++ {
+		encode(sum[(i*22)%63], sum[(i*22+21)%63], sum[(i*22+42)%63], 4)
+	}
+	encode(0, 0, sum[63], 2)
+	return out.String()
+}
+
+func repeatBytes(src []byte, n int) []byte {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		out = append(out, src[:min(n-len(out), len(src))]...)
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package marmotd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+func TestSha512Crypt(t *testing.T) {
+	// glibc の crypt(3) の SHA-512 形式の試験値
+	tests := []struct {
+		password, salt, want string
+	}{
+		{"Hello world!", "saltstring", "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+		{"This is just a test", "toolongsaltstring", "$6$toolongsaltstrin$lQ8jolhgVRVhY4b5pZKaysCLi0QBxGoNeKQzQ3glMhwllF7oGDZxUhx1yxdYcz/e1JSbq3y6JMxxl8audkUEm0"},
+	}
+	for _, tt := range tests {
+		if got := sha512Crypt(tt.password, tt.salt); got != tt.want {
+			t.Fatalf("sha512Crypt(%q, %q) = %q, want %q", tt.password, tt.salt, got, tt.want)
+		}
+	}
+}
+
+func TestNewGuestPasswordRequest(t *testing.T) {
+	req, err := NewGuestPasswordRequest("s0001", api.ServerPasswordRequest{User: " ubuntu ", Password: "secret"})
+	if err != nil {
+		t.Fatalf("NewGuestPasswordRequest() error = %v", err)
+	}
+	if req.ServerId != "s0001" || req.User != "ubuntu" || !strings.HasPrefix(req.CryptedPassword, "$6$") {
+		t.Fatalf("request = %+v", req)
+	}
+	if strings.Contains(req.CryptedPassword, "secret") {
+		t.Fatal("the request must not keep the plain password")
+	}
+
+	for name, body := range map[string]api.ServerPasswordRequest{
+		"no user":     {Password: "secret"},
+		"colon":       {User: "a:b", Password: "secret"},
+		"no password": {User: "ubuntu"},
+		"multi-line":  {User: "ubuntu", Password: "a\nb"},
+	} {
+		if _, err := NewGuestPasswordRequest("s0001", body); !errors.Is(err, ErrInvalidGuestPassword) {
+			t.Fatalf("%s: error = %v, want %v", name, err, ErrInvalidGuestPassword)
+		}
+	}
+}
+
+func TestGuestInfoFromVirt(t *testing.T) {
+	info := guestInfoFromVirt(virt.GuestInfo{
+		Hostname: "web1",
+		OSName:   "Ubuntu 24.04.1 LTS",
+		Interfaces: []virt.GuestInterface{
+			{Name: "enp1s0", MacAddress: "52:54:00:00:00:01", IPAddresses: []string{"10.1.0.10/24"}},
+			{Name: "enp2s0", MacAddress: "52:54:00:00:00:02"},
+		},
+	})
+	if util.OrDefault(info.Hostname, "") != "web1" || info.OsVersion != nil || info.Interfaces == nil || len(*info.Interfaces) != 2 {
+		t.Fatalf("guestInfoFromVirt() = %+v", info)
+	}
+	if nic := (*info.Interfaces)[1]; nic.IpAddresses != nil {
+		t.Fatalf("interface without addresses = %+v", nic)
+	}
+
+	same := *info
+	same.LastUpdated = util.TimePtr(time.Now())
+	if guestInfoChanged(info, &same) {
+		t.Fatal("guestInfoChanged() must ignore lastUpdated")
+	}
+	changed := *info
+	changed.Hostname = util.StringPtr("web2")
+	if !guestInfoChanged(info, &changed) || !guestInfoChanged(nil, info) {
+		t.Fatal("guestInfoChanged() must report a different hostname and a new record")
+	}
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/takara9/marmot/api"
 	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/lvm"
 	"github.com/takara9/marmot/pkg/util"
 )
 
@@ -30,6 +31,24 @@ var hostIDCommandOutput = func() ([]byte, error) {
 
 var iscsiInitiatornameFile = "/etc/iscsi/initiatorname.iscsi"
 
+// qcow2 ボリュームの格納先ディレクトリ
+const qcow2VolumeDir = "/var/lib/marmot/volumes"
+
+// VG の空き容量(バイト)を取得する。テストで差し替え可能にしている
+var volumeGroupFreeBytes = func(vg string) (uint64, error) {
+	_, free, err := lvm.CheckVG(vg)
+	return free, err
+}
+
+// ディレクトリを含むファイルシステムの空き容量(バイト)を取得する
+var filesystemFreeBytes = func(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), nil
+}
+
 var systemctlRestartIscsid = func() error {
 	return exec.Command("systemctl", "restart", "iscsid").Run()
 }
@@ -116,6 +135,15 @@ func (m *Marmot) CollectHostStatus() (api.HostStatus, error) {
 		status.IscsiServer = util.BoolPtr(true)
 	}
 
+	// ノードラベルをセット（サーバー配置の nodeSelector で参照される）
+	if labels := CurrentConfig().NodeLabels; len(labels) > 0 {
+		copied := make(map[string]string, len(labels))
+		for k, v := range labels {
+			copied[k] = v
+		}
+		status.Labels = &copied
+	}
+
 	// キャパシティ情報を収集（資源搭載量＝ホストの物理ディスク等）
 	capacity, err := collectHostCapacity()
 	if err != nil {
@@ -133,6 +161,14 @@ func (m *Marmot) CollectHostStatus() (api.HostStatus, error) {
 	applyHostVolumeCapacity(allocation, nodeName, m)
 	status.Allocation = allocation
 
+	// 専有 vCPU のプールと NUMA ノードの情報を収集（スケジューラーの配置判定に使用）
+	performance, err := m.collectHostPerformance()
+	if err != nil {
+		slog.Error("collectHostPerformance()", "err", err)
+		return status, err
+	}
+	status.Performance = performance
+
 	return status, nil
 }
 
@@ -356,6 +392,25 @@ func collectHostCapacity() (*api.HostCapacity, error) {
 	capacity.DiskCount = util.IntPtrInt(diskCount)
 	capacity.DiskCapacityGB = util.IntPtrInt(diskCapacityGB)
 
+	// ボリューム作成先の空き容量を取得（スケジューラーの配置判定に使用）
+	// 取得できない場合は省略し、スケジューラーは容量制約なしとして扱う
+	cfg := CurrentConfig()
+	if free, err := volumeGroupFreeBytes(cfg.OSVolumeGroup); err != nil {
+		slog.Debug("volume group is unavailable", "vg", cfg.OSVolumeGroup, "err", err)
+	} else {
+		capacity.OsVolumeGroupFreeGB = util.IntPtrInt(int(free / (1024 * 1024 * 1024)))
+	}
+	if free, err := volumeGroupFreeBytes(cfg.DataVolumeGroup); err != nil {
+		slog.Debug("volume group is unavailable", "vg", cfg.DataVolumeGroup, "err", err)
+	} else {
+		capacity.DataVolumeGroupFreeGB = util.IntPtrInt(int(free / (1024 * 1024 * 1024)))
+	}
+	if free, err := filesystemFreeBytes(qcow2VolumeDir); err != nil {
+		slog.Debug("qcow2 volume directory is unavailable", "path", qcow2VolumeDir, "err", err)
+	} else {
+		capacity.VolumeStoreFreeGB = util.IntPtrInt(int(free / (1024 * 1024 * 1024)))
+	}
+
 	// ネットワークインターフェースを取得
 	ifaces, err := net.Interfaces()
 	if err != nil {
@@ -463,7 +518,8 @@ func (m *Marmot) collectHostAllocation() (*api.HostAllocation, error) {
 		case db.SERVER_RUNNING:
 			runningVMs++
 			// 稼働中のサーバーのみCPUとメモリを集計
-			if server.Spec.Cpu != nil {
+			// 専有 vCPU はプールから割り当てるため、共有の CPU に計上しない
+			if server.Spec.Cpu != nil && !hasDedicatedCPU(server.Spec) {
 				allocatedCPU += *server.Spec.Cpu
 			}
 			if server.Spec.Memory != nil {
@@ -22,6 +22,7 @@ import (
 	"github.com/takara9/marmot/pkg/db"
 	"github.com/takara9/marmot/pkg/lvm"
 	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
 )
 
 const (
@@ -75,6 +76,7 @@ func (m *Marmot) CreateNewImageManageWithContext(ctx context.Context, id string)
 		image.Status = &api.Status{}
 	}
 	updateImageMessage := func(message string) error {
+		JobLogf(ctx, "%s", message)
 		image.Status.Message = util.StringPtr(message)
 		if err := m.Db.UpdateImage(id, image); err != nil {
 			slog.Error("Failed to update image status in DB", "imgId", id, "err", err)
@@ -244,6 +246,9 @@ func (m *Marmot) ImportImageArchiveWithNode(src io.Reader, imageName, nodeName s
 	if archiveMeta.OsVersion != "" {
 		image.Spec.OsVersion = util.StringPtr(archiveMeta.OsVersion)
 	}
+	if fw, err := virt.NormalizeFirmware(archiveMeta.Firmware); err == nil && fw != "" {
+		image.Spec.Firmware = util.StringPtr(fw)
+	}
 
 	cleanupEntry := func() {
 		if err := m.Db.DeleteImage(image.Metadata.Id); err != nil {
@@ -319,10 +324,11 @@ func (m *Marmot) DeleteImageManage(id string) error {
 	return m.Db.DeleteImage(id)
 }
 
-// イメージの情報を更新する関数 （ラップ関数） コントローラーで使用
-func (m *Marmot) UpdateImageManage(id string, image api.Image) error {
+// イメージの情報を更新する関数 （ラップ関数）
+// resourceVersion が空でない場合は、現在のリソースバージョンと一致する時だけ更新する
+func (m *Marmot) UpdateImageManage(id string, image api.Image, resourceVersion string) error {
 	slog.Debug("Updating image", "imgId", id)
-	return m.Db.UpdateImage(id, image)
+	return m.Db.UpdateImageWithResourceVersion(id, image, resourceVersion)
 }
 
 func CheckImageBackingStore(image api.Image) error {
@@ -63,6 +63,12 @@ func (m commonImageOSModule) applyFollowerSpec(follower *api.Image, head api.Ima
 			follower.Spec.OsVersion = util.StringPtr(osVersion)
 		}
 	}
+	if head.Spec.Firmware != nil {
+		firmware := strings.TrimSpace(*head.Spec.Firmware)
+		if firmware != "" {
+			follower.Spec.Firmware = util.StringPtr(firmware)
+		}
+	}
 	if head.Spec.Size != nil {
 		follower.Spec.Size = util.IntPtrInt(*head.Spec.Size)
 	}
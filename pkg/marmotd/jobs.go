@@ -0,0 +1,63 @@
+package marmotd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/takara9/marmot/pkg/db"
+)
+
+type runningJobKey struct{}
+
+// ジョブ機能のインスタンス、Marmot のデータベース接続を使う
+func (m *Marmot) Jobs() *db.Job {
+	return db.NewJob(m.Db, "")
+}
+
+// 長時間の処理をジョブとして実行する
+// jobId が空の場合はジョブを使わずに fn を実行する
+// ジョブがキャンセルされると fn に渡す Context が終了する
+func (m *Marmot) RunWithJob(ctx context.Context, jobId string, fn func(ctx context.Context) error) error {
+	if jobId == "" {
+		return fn(ctx)
+	}
+	run, err := m.Jobs().StartJob(ctx, jobId)
+	if err != nil {
+		slog.Warn("failed to start job, running without job tracking", "jobId", jobId, "err", err)
+		return fn(ctx)
+	}
+	run.Logf("started on node %s", m.NodeName)
+	err = fn(context.WithValue(run.Context(), runningJobKey{}, run))
+	run.Finish(err)
+	return err
+}
+
+// 実行中のジョブのログに進捗を書き込む、ジョブとして実行されていない場合は何もしない
+func JobLogf(ctx context.Context, format string, args ...interface{}) {
+	if ctx == nil {
+		return
+	}
+	if run, ok := ctx.Value(runningJobKey{}).(*db.RunningJob); ok {
+		run.Logf(format, args...)
+	}
+}
+
+// 他ノードの marmotd API のベースURL
+// ポート番号は自ノードの API 待ち受けアドレスと同じとする
+func NodeAPIBaseURL(nodeIP string) string {
+	port := "8750"
+	apiListenAddr := strings.TrimSpace(CurrentConfig().APIListenAddr)
+	if _, p, err := net.SplitHostPort(apiListenAddr); err == nil {
+		if strings.TrimSpace(p) != "" {
+			port = p
+		}
+	} else if strings.HasPrefix(apiListenAddr, ":") {
+		if p := strings.TrimPrefix(apiListenAddr, ":"); strings.TrimSpace(p) != "" {
+			port = p
+		}
+	}
+	return fmt.Sprintf("http://%s/api/v1", net.JoinHostPort(nodeIP, port))
+}
@@ -57,7 +57,7 @@ func SetupDefaultLogger(cfg *MarmotdConfig) (func(context.Context) error, error)
 		return func(context.Context) error { return nil }, nil
 	}
 
-	writer, err := newLokiWriter(cfg)
+	writer, err := newLokiWriter(cfg, "marmotd")
 	if err != nil {
 		slog.SetDefault(slog.New(base))
 		return func(context.Context) error { return nil }, err
@@ -69,13 +69,14 @@ func SetupDefaultLogger(cfg *MarmotdConfig) (func(context.Context) error, error)
 	return writer.Close, nil
 }
 
-func newLokiWriter(cfg *MarmotdConfig) (*lokiWriter, error) {
+// newLokiWriter は job ラベルを付けて Loki にログを送るライターを作る
+func newLokiWriter(cfg *MarmotdConfig, job string) (*lokiWriter, error) {
 	pushURL, err := normalizeLokiPushURL(cfg.LokiPushURL)
 	if err != nil {
 		return nil, err
 	}
 
-	labels := map[string]string{"job": "marmotd"}
+	labels := map[string]string{"job": job}
 	if node := strings.TrimSpace(cfg.NodeName); node != "" {
 		labels["node"] = node
 	}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
 )
 
 const DefaultConfigPath = "/etc/marmot/marmotd.json"
@@ -73,6 +75,34 @@ type HostBridgeDefaultConfig struct {
 	Routes      []HostBridgeRouteConfig     `json:"routes"`
 }
 
+// OIDCConfig は OIDC の ID プロバイダーによるログインの設定
+type OIDCConfig struct {
+	// ID プロバイダーの issuer URL。/.well-known/openid-configuration で設定を取得する
+	// 例: "https://idp.example.com/realms/marmot"
+	Issuer string `json:"issuer"`
+
+	// mactl login --oidc が使うクライアントID (PKCE を使う public client)
+	ClientID string `json:"client_id"`
+
+	// ID トークンの aud に含まれるべき値。省略時は client_id
+	Audience string `json:"audience"`
+
+	// 認可要求のスコープ。省略時は ["openid", "profile", "email"]
+	Scopes []string `json:"scopes"`
+
+	// 利用者IDとして使うクレーム。省略時は issuer の中で一意で変わらない "sub"。
+	// "preferred_username" や "email" は ID プロバイダーで変更や再割り当てができるため、
+	// 別の利用者に同じ ID が付かないことを確認したうえで明示的に指定する
+	UsernameClaim string `json:"username_claim"`
+
+	// グループの一覧を持つクレーム。省略時は "groups"
+	GroupsClaim string `json:"groups_claim"`
+
+	// グループから marmot のロールへの対応
+	// 例: {"marmot-admins": ["Administrator"], "developers": ["Compute-Operator", "Viewer"]}
+	GroupRoles map[string][]string `json:"group_roles"`
+}
+
 type cephPoolByClassEntry struct {
 	StorageClass string `json:"storageClass"`
 	Pool         string `json:"pool"`
@@ -157,11 +187,38 @@ type MarmotdConfig struct {
 	// Ceph ボリューム作成・削除処理のタイムアウト秒数
 	CephVolumeOperationTimeoutSeconds int `json:"ceph_volume_operation_timeout_seconds"`
 
+	// スナップショット取得・ロールバック時にゲストOSのシャットダウンを待つ秒数
+	// 超過した場合は強制停止する
+	ServerShutdownTimeoutSeconds int `json:"server_shutdown_timeout_seconds"`
+
+	// スケジューラーが vCPU を割り当てる際のオーバーコミット倍率
+	// 物理コア数 × 倍率 を割当可能な vCPU 数とみなす
+	SchedulerCPUOvercommitRatio float64 `json:"scheduler_cpu_overcommit_ratio"`
+
+	// スケジューラーがメモリを割り当てる際のオーバーコミット倍率
+	// 1.0 の場合は搭載メモリ量を超えて割り当てない
+	SchedulerMemoryOvercommitRatio float64 `json:"scheduler_memory_overcommit_ratio"`
+
+	// ライブマイグレーションで移行元の libvirtd が接続する移行先の URI
+	// %s は移行先ホストの IP アドレスに置き換えられる
+	MigrationURITemplate string `json:"migration_uri_template"`
+
 	// このホストが iSCSI ターゲットサーバーを担当するかどうか
 	// true の場合、このホストの volumeコントローラーが iSCSI ターゲットを管理する。
 	// false（省略時）の場合、クラスタ内で HostId が最小のホストが自動的に担当する。
 	IscsiServer bool `json:"iscsi_server"`
 
+	// このホストに付与するノードラベル
+	// サーバーの spec.placement.nodeSelector で配置先の絞り込みに使用する
+	// 例: {"zone": "rack1", "gpu": "true"}
+	NodeLabels map[string]string `json:"node_labels"`
+
+	// 専有 vCPU (spec.performance.dedicatedCpu) に割り当てるホスト CPU のプール
+	// sysfs の cpulist と同じ形式で指定する。例: "4-15,20-31"
+	// 空の場合はこのホストに専有 vCPU のサーバーを配置しない
+	// プールの CPU はその他のサーバーの vCPU に使わない
+	PinnedCPUs string `json:"pinned_cpus"`
+
 	// 起動時に自動ダウンロード・登録する OS イメージの一覧
 	// marmotd 起動時に各イメージをチェックし、存在しなければ登録する
 	OSImages []OSImage `json:"os_images"`
@@ -170,6 +227,21 @@ type MarmotdConfig struct {
 	// 例: "http://127.0.0.1:3100/loki/api/v1/push"
 	LokiPushURL string `json:"loki_push_url"`
 
+	// API の監査記録を etcd に保持する日数
+	AuditRetentionDays int `json:"audit_retention_days"`
+
+	// 監査記録を JSON Lines 形式で追記するファイルのパス。
+	// 例: "/var/log/marmot/audit.log"
+	// 空の場合はファイルに出力しない。
+	AuditLogFile string `json:"audit_log_file"`
+
+	// true の場合、監査記録を loki_push_url の Loki に job="marmotd-audit" で送信する。
+	AuditLoki bool `json:"audit_loki"`
+
+	// OIDC の ID プロバイダーによるログインの設定。
+	// 省略した場合はローカルの利用者と API キーだけで認証する。
+	OIDC *OIDCConfig `json:"oidc"`
+
 	// API サーバーが HTTPS を使用する場合の TLS 証明書ファイルパス。
 	// 例: "/etc/marmot/certs/server.crt"
 	// 空の場合は HTTP を使用する。
@@ -180,6 +252,10 @@ type MarmotdConfig struct {
 	// 空の場合は HTTP を使用する。
 	TLSKeyFile string `json:"tls_key_file"`
 
+	// グラフィカルコンソールの WebSocket の接続を許可する Origin の一覧。例: ["https://console.labo.local"]
+	// Origin ヘッダーが API サーバーのホストと同じ場合は指定しなくても許可する
+	ConsoleAllowedOrigins []string `json:"console_allowed_origins"`
+
 	// Ceph 連携の有効/無効フラグ。
 	// true の場合、Ceph をバックエンドストレージとして利用可能にする。
 	// false（省略時）の場合、Ceph 機能は無効化される。
@@ -292,10 +368,18 @@ func defaultConfig() *MarmotdConfig {
 		ImageResizeTimeoutSeconds:         600,
 		ImageDeleteTimeoutSeconds:         120,
 		CephVolumeOperationTimeoutSeconds: 120,
+		ServerShutdownTimeoutSeconds:      120,
+		SchedulerCPUOvercommitRatio:       4.0,
+		SchedulerMemoryOvercommitRatio:    1.0,
+		MigrationURITemplate:              "qemu+ssh://%s/system",
 		LokiPushURL:                       "",
+		AuditRetentionDays:                90,
+		AuditLogFile:                      "",
+		AuditLoki:                         false,
 		TLSCertFile:                       "",
 		TLSKeyFile:                        "",
 		CephEnabled:                       false,
+		NodeLabels:                        make(map[string]string),
 		CephCrushRuleByClass:              make(map[string]string),
 		CephPoolByClass:                   make(map[string]string),
 	}
@@ -383,12 +467,38 @@ func normalizeConfig(cfg *MarmotdConfig) *MarmotdConfig {
 	if normalized.ImageDeleteTimeoutSeconds <= 0 {
 		normalized.ImageDeleteTimeoutSeconds = defaults.ImageDeleteTimeoutSeconds
 	}
+	if normalized.ServerShutdownTimeoutSeconds <= 0 {
+		normalized.ServerShutdownTimeoutSeconds = defaults.ServerShutdownTimeoutSeconds
+	}
+	if normalized.SchedulerCPUOvercommitRatio <= 0 {
+		normalized.SchedulerCPUOvercommitRatio = defaults.SchedulerCPUOvercommitRatio
+	}
+	if normalized.SchedulerMemoryOvercommitRatio <= 0 {
+		normalized.SchedulerMemoryOvercommitRatio = defaults.SchedulerMemoryOvercommitRatio
+	}
+	normalized.MigrationURITemplate = strings.TrimSpace(normalized.MigrationURITemplate)
+	normalized.PinnedCPUs = strings.TrimSpace(normalized.PinnedCPUs)
+	if !strings.Contains(normalized.MigrationURITemplate, "%s") {
+		normalized.MigrationURITemplate = defaults.MigrationURITemplate
+	}
 	if normalized.CephVolumeOperationTimeoutSeconds <= 0 {
 		normalized.CephVolumeOperationTimeoutSeconds = defaults.CephVolumeOperationTimeoutSeconds
 	}
 	normalized.LokiPushURL = strings.TrimSpace(normalized.LokiPushURL)
+	if normalized.AuditRetentionDays <= 0 {
+		normalized.AuditRetentionDays = defaults.AuditRetentionDays
+	}
+	normalized.AuditLogFile = strings.TrimSpace(normalized.AuditLogFile)
+	normalized.OIDC = normalizeOIDCConfig(normalized.OIDC)
 	normalized.TLSCertFile = strings.TrimSpace(normalized.TLSCertFile)
 	normalized.TLSKeyFile = strings.TrimSpace(normalized.TLSKeyFile)
+	normalized.ConsoleAllowedOrigins = trimNonEmptyStrings(normalized.ConsoleAllowedOrigins)
+
+	if normalized.NodeLabels == nil {
+		normalized.NodeLabels = make(map[string]string)
+	} else {
+		normalized.NodeLabels = trimMapStringString(normalized.NodeLabels)
+	}
 
 	// Ceph マップのキーと値をトリミング
 	if normalized.CephCrushRuleByClass == nil {
@@ -405,6 +515,45 @@ func normalizeConfig(cfg *MarmotdConfig) *MarmotdConfig {
 	return normalized
 }
 
+// normalizeOIDCConfig は OIDC の設定の既定値を補う。issuer か client_id がない場合は OIDC を使わない
+func normalizeOIDCConfig(cfg *OIDCConfig) *OIDCConfig {
+	if cfg == nil {
+		return nil
+	}
+	normalized := *cfg
+	normalized.Issuer = strings.TrimRight(strings.TrimSpace(normalized.Issuer), "/")
+	normalized.ClientID = strings.TrimSpace(normalized.ClientID)
+	if normalized.Issuer == "" || normalized.ClientID == "" {
+		return nil
+	}
+	normalized.Audience = strings.TrimSpace(normalized.Audience)
+	if normalized.Audience == "" {
+		normalized.Audience = normalized.ClientID
+	}
+	normalized.Scopes = trimNonEmptyStrings(slices.Clone(normalized.Scopes))
+	if len(normalized.Scopes) == 0 {
+		normalized.Scopes = []string{"openid", "profile", "email"}
+	} else if !slices.Contains(normalized.Scopes, "openid") {
+		normalized.Scopes = append([]string{"openid"}, normalized.Scopes...)
+	}
+	normalized.UsernameClaim = strings.TrimSpace(normalized.UsernameClaim)
+	if normalized.UsernameClaim == "" {
+		normalized.UsernameClaim = "sub"
+	}
+	normalized.GroupsClaim = strings.TrimSpace(normalized.GroupsClaim)
+	if normalized.GroupsClaim == "" {
+		normalized.GroupsClaim = "groups"
+	}
+	groupRoles := make(map[string][]string, len(normalized.GroupRoles))
+	for group, roles := range normalized.GroupRoles {
+		if group = strings.TrimSpace(group); group != "" {
+			groupRoles[group] = append(groupRoles[group], trimNonEmptyStrings(slices.Clone(roles))...)
+		}
+	}
+	normalized.GroupRoles = groupRoles
+	return &normalized
+}
+
 func resolveDNSListenAddrFromInterfaces() (string, bool) {
 	ifaces, err := listInterfaces()
 	if err != nil {
@@ -512,6 +661,14 @@ func (c *MarmotdConfig) CephVolumeOperationTimeout() time.Duration {
 	return time.Duration(c.CephVolumeOperationTimeoutSeconds) * time.Second
 }
 
+func (c *MarmotdConfig) ServerShutdownTimeout() time.Duration {
+	return time.Duration(c.ServerShutdownTimeoutSeconds) * time.Second
+}
+
+func (c *MarmotdConfig) AuditRetention() time.Duration {
+	return time.Duration(c.AuditRetentionDays) * 24 * time.Hour
+}
+
 func SetRuntimeConfig(cfg *MarmotdConfig) {
 	normalized := normalizeConfig(cfg)
 	sessionIdleTimeout, err := parseSessionIdleTimeout(normalized.SessionIdleTimeout)
@@ -568,6 +725,9 @@ func LoadConfig(path string) (*MarmotdConfig, error) {
 	if err := validateCephConfig(normalized); err != nil {
 		return nil, err
 	}
+	if _, err := util.ParseCPUSet(normalized.PinnedCPUs); err != nil {
+		return nil, fmt.Errorf("pinned_cpus: %w", err)
+	}
 	return normalized, nil
 }
 
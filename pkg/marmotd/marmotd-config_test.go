@@ -131,6 +131,45 @@ var _ = Describe("VolumeGroupConfig", func() {
 			Expect(cfg.IscsiServer).To(BeFalse())
 		})
 
+		It("pinned_cpus の設定値を読み込む", func() {
+			dir := GinkgoT().TempDir()
+			path := filepath.Join(dir, "marmotd.json")
+			content := []byte(`{"pinned_cpus":" 4-7,12 "}`)
+
+			err := os.WriteFile(path, content, 0o644)
+			Expect(err).NotTo(HaveOccurred())
+
+			cfg, err := marmotd.LoadConfig(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.PinnedCPUs).To(Equal("4-7,12"))
+		})
+
+		It("pinned_cpus が不正な場合はエラーになる", func() {
+			dir := GinkgoT().TempDir()
+			path := filepath.Join(dir, "marmotd.json")
+			content := []byte(`{"pinned_cpus":"7-4"}`)
+
+			err := os.WriteFile(path, content, 0o644)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = marmotd.LoadConfig(path)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("pinned_cpus"))
+		})
+
+		It("console_allowed_origins の設定値を読み込む", func() {
+			dir := GinkgoT().TempDir()
+			path := filepath.Join(dir, "marmotd.json")
+			content := []byte(`{"console_allowed_origins":[" https://console.labo.local ",""]}`)
+
+			err := os.WriteFile(path, content, 0o644)
+			Expect(err).NotTo(HaveOccurred())
+
+			cfg, err := marmotd.LoadConfig(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.ConsoleAllowedOrigins).To(Equal([]string{"https://console.labo.local"}))
+		})
+
 		It("dns_client_allow_cidrs の設定値を読み込む", func() {
 			dir := GinkgoT().TempDir()
 			path := filepath.Join(dir, "marmotd.json")
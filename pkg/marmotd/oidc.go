@@ -0,0 +1,474 @@
+package marmotd
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"golang.org/x/sync/singleflight"
+)
+
+/*
+OIDC の ID プロバイダーが発行した ID トークン (JWT) を検証する
+
+	mactl login --oidc は認可コードフローと PKCE で ID トークンを取得し、POST /auth/oidc/login で
+	ログインセッションのアクセストークンと交換する。API は Authorization: Bearer の ID トークンも受け付ける。
+	署名の鍵は issuer の /.well-known/openid-configuration の jwks_uri から取得し、
+	知らない kid のトークンを受け取ったときに取得し直す。
+	ID トークンのグループのクレームを設定の group_roles でロールに対応させ、利用者を登録する
+*/
+
+const (
+	// 知らない kid のトークンで JWKS を取得し直す最短の間隔
+	oidcJWKSRefreshInterval = 1 * time.Minute
+	// ID プロバイダーと marmotd の時刻のずれの許容範囲
+	oidcClockSkew = 1 * time.Minute
+	// ID プロバイダーへの要求のタイムアウト
+	oidcRequestTimeout = 10 * time.Second
+	// ID トークンで API を呼び出した利用者を登録し直す間隔。
+	// marmot で無効にした利用者の ID トークンは、この間隔の内に拒否される
+	oidcUserSyncTTL = 1 * time.Minute
+)
+
+var oidcSigningHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// oidcProvider は ID プロバイダーの設定と署名の鍵を保持する
+type oidcProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	jwksURI   string
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+
+	// 同時に届いた知らない kid のトークンによる JWKS の取得を 1 回にまとめる
+	refresh singleflight.Group
+
+	// 利用者ID ごとの、最後に登録した ID トークンの内容
+	usersMu sync.Mutex
+	users   map[string]oidcSyncedUser
+}
+
+// oidcSyncedUser は ID トークンの利用者を登録した結果
+type oidcSyncedUser struct {
+	ext      db.ExternalUser
+	user     api.User
+	syncedAt time.Time
+}
+
+var oidcState = struct {
+	mu       sync.RWMutex
+	provider *oidcProvider
+}{}
+
+// SetupOIDC は設定に従って OIDC によるログインを有効にする。設定がない場合は無効にする
+func SetupOIDC(cfg *MarmotdConfig) {
+	var provider *oidcProvider
+	if cfg != nil {
+		if oidc := normalizeOIDCConfig(cfg.OIDC); oidc != nil {
+			provider = &oidcProvider{cfg: *oidc, client: &http.Client{Timeout: oidcRequestTimeout}}
+		}
+	}
+	oidcState.mu.Lock()
+	oidcState.provider = provider
+	oidcState.mu.Unlock()
+}
+
+func currentOIDCProvider() *oidcProvider {
+	oidcState.mu.RLock()
+	defer oidcState.mu.RUnlock()
+	return oidcState.provider
+}
+
+// looksLikeJWT は API キーと ID トークンを区別する。API キーは '.' を含まない
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// invalidIDToken は ID トークンの検証の失敗を認証の失敗として返す
+func invalidIDToken(format string, args ...any) error {
+	return fmt.Errorf("%w: invalid ID token: %s", db.ErrInvalidCredentials, fmt.Sprintf(format, args...))
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: http status code = %d", url, resp.StatusCode)
+	}
+	return json.Unmarshal(body, v)
+}
+
+// refreshKeys は JWKS を取得し直す。ID プロバイダーへの要求は mu を保持せずに行う
+func (p *oidcProvider) refreshKeys(ctx context.Context) error {
+	p.mu.Lock()
+	jwksURI := p.jwksURI
+	recent := time.Since(p.fetchedAt) < oidcJWKSRefreshInterval
+	p.mu.Unlock()
+	// 直前に別の要求が取得し直した
+	if recent {
+		return nil
+	}
+
+	if jwksURI == "" {
+		var discovery struct {
+			Issuer  string `json:"issuer"`
+			JwksURI string `json:"jwks_uri"`
+		}
+		if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return fmt.Errorf("failed to get OIDC discovery document: %w", err)
+		}
+		if strings.TrimRight(discovery.Issuer, "/") != p.cfg.Issuer {
+			return fmt.Errorf("OIDC discovery issuer %q does not match %q", discovery.Issuer, p.cfg.Issuer)
+		}
+		if discovery.JwksURI == "" {
+			return errors.New("OIDC discovery document has no jwks_uri")
+		}
+		jwksURI = discovery.JwksURI
+	}
+
+	var jwks struct {
+		Keys []oidcJWK `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &jwks); err != nil {
+		return fmt.Errorf("failed to get OIDC JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			slog.Warn("skipped unsupported OIDC signing key", "kid", k.Kid, "kty", k.Kty, "err", err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.jwksURI = jwksURI
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	return nil
+}
+
+// cachedKey は取得済みの kid の署名の鍵を返す。stale は JWKS を取得し直してよいかを表す
+func (p *oidcProvider) cachedKey(kid string) (key crypto.PublicKey, ok bool, stale bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stale = time.Since(p.fetchedAt) >= oidcJWKSRefreshInterval
+	if key, ok := p.keys[kid]; ok {
+		return key, true, stale
+	}
+	// kid のないトークンは鍵が 1 つの場合だけ受け付ける
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true, stale
+		}
+	}
+	return nil, false, stale
+}
+
+// signingKey は kid の署名の鍵を返す。知らない kid の場合は JWKS を取得し直す
+func (p *oidcProvider) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	key, ok, stale := p.cachedKey(kid)
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, invalidIDToken("unknown signing key %q", kid)
+	}
+	// 取得は待っている要求で共有するため、最初の要求の取り消しで失敗させない
+	_, err, _ := p.refresh.Do("jwks", func() (interface{}, error) {
+		return nil, p.refreshKeys(context.WithoutCancel(ctx))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if key, ok, _ := p.cachedKey(kid); ok {
+		return key, nil
+	}
+	return nil, invalidIDToken("unknown signing key %q", kid)
+}
+
+// oidcJWK は JWKS の公開鍵 (RSA と EC)
+type oidcJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k oidcJWK) publicKey() (crypto.PublicKey, error) {
+	decode := func(v string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("invalid key parameter")
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+// verifySignature は JWT の署名を検証する
+func verifySignature(alg string, key crypto.PublicKey, signingInput, signature []byte) error {
+	hash, ok := oidcSigningHashes[alg]
+	if !ok {
+		return invalidIDToken("unsupported algorithm %q", alg)
+	}
+	h := hash.New()
+	h.Write(signingInput)
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") || rsa.VerifyPKCS1v15(pub, hash, digest, signature) != nil {
+			return invalidIDToken("signature verification failed")
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return invalidIDToken("signature verification failed")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return invalidIDToken("signature verification failed")
+		}
+	default:
+		return invalidIDToken("unsupported signing key")
+	}
+	return nil
+}
+
+// Verify は ID トークンの署名・issuer・audience・有効期限を検証してクレームを返す
+func (p *oidcProvider) Verify(ctx context.Context, token string) (map[string]interface{}, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, invalidIDToken("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, invalidIDToken("malformed header")
+	}
+	if _, ok := oidcSigningHashes[header.Alg]; !ok {
+		return nil, invalidIDToken("unsupported algorithm %q", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidIDToken("malformed signature")
+	}
+	key, err := p.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, invalidIDToken("malformed claims")
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimRight(iss, "/") != p.cfg.Issuer {
+		return nil, invalidIDToken("issuer %q is not trusted", iss)
+	}
+	if !slices.Contains(claimStrings(claims, "aud"), p.cfg.Audience) {
+		return nil, invalidIDToken("audience does not include %q", p.cfg.Audience)
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, invalidIDToken("exp is required")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(oidcClockSkew)) {
+		return nil, invalidIDToken("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(oidcClockSkew).Before(time.Unix(int64(nbf), 0)) {
+		return nil, invalidIDToken("token is not valid yet")
+	}
+	return claims, nil
+}
+
+func decodeJWTSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// claimStrings は文字列または文字列の配列のクレームを返す
+func claimStrings(claims map[string]interface{}, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// ExternalUser は ID トークンのクレームから利用者とロールを求める。ロールはグループの対応の和集合
+func (p *oidcProvider) ExternalUser(claims map[string]interface{}) db.ExternalUser {
+	user := db.ExternalUser{}
+	if id, ok := claims[p.cfg.UsernameClaim].(string); ok {
+		user.UserID = strings.TrimSpace(id)
+	}
+	user.DisplayName, _ = claims["name"].(string)
+	user.Email, _ = claims["email"].(string)
+	for _, group := range claimStrings(claims, p.cfg.GroupsClaim) {
+		for _, role := range p.cfg.GroupRoles[strings.TrimSpace(group)] {
+			if !slices.Contains(user.Roles, role) {
+				user.Roles = append(user.Roles, role)
+			}
+		}
+	}
+	sort.Strings(user.Roles)
+	return user
+}
+
+// syncedUser は同じ内容の ID トークンで oidcUserSyncTTL の内に登録した利用者を返す
+func (p *oidcProvider) syncedUser(ext db.ExternalUser) (api.User, bool) {
+	p.usersMu.Lock()
+	defer p.usersMu.Unlock()
+
+	synced, ok := p.users[ext.UserID]
+	if !ok || time.Since(synced.syncedAt) >= oidcUserSyncTTL {
+		return api.User{}, false
+	}
+	if synced.ext.DisplayName != ext.DisplayName || synced.ext.Email != ext.Email || !slices.Equal(synced.ext.Roles, ext.Roles) {
+		return api.User{}, false
+	}
+	return synced.user, true
+}
+
+// rememberUser は登録した利用者を記録し、期限の過ぎた記録を取り除く
+func (p *oidcProvider) rememberUser(ext db.ExternalUser, user api.User) {
+	p.usersMu.Lock()
+	defer p.usersMu.Unlock()
+
+	now := time.Now()
+	if p.users == nil {
+		p.users = map[string]oidcSyncedUser{}
+	}
+	for id, synced := range p.users {
+		if now.Sub(synced.syncedAt) >= oidcUserSyncTTL {
+			delete(p.users, id)
+		}
+	}
+	p.users[ext.UserID] = oidcSyncedUser{ext: ext, user: user, syncedAt: now}
+}
+
+// authenticateIDToken は ID トークンを検証し、利用者を登録してロールを合わせる。
+// 登録は初めての利用者、クレームやロールの変わった利用者、oidcUserSyncTTL を過ぎた利用者に限る。
+// refresh の場合 (ログインセッションの発行) は必ず登録し直し、無効にした利用者を拒否する
+func (s *Server) authenticateIDToken(ctx context.Context, provider *oidcProvider, token string, refresh bool) (api.User, error) {
+	claims, err := provider.Verify(ctx, token)
+	if err != nil {
+		return api.User{}, err
+	}
+	ext := provider.ExternalUser(claims)
+	if !refresh {
+		if user, ok := provider.syncedUser(ext); ok {
+			return user, nil
+		}
+	}
+	user, err := s.Ma.Db.SyncOIDCUser(ext)
+	if err != nil {
+		return api.User{}, err
+	}
+	provider.rememberUser(ext, user)
+	return user, nil
+}
@@ -0,0 +1,239 @@
+package marmotd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/oidctest"
+)
+
+func newTestOIDCProvider(idp *oidctest.Provider) *oidcProvider {
+	cfg := normalizeOIDCConfig(&OIDCConfig{
+		Issuer:   idp.Issuer,
+		ClientID: idp.ClientID,
+		GroupRoles: map[string][]string{
+			"marmot-admins": {"Administrator"},
+			"developers":    {"Compute-Operator", "Viewer"},
+			"readers":       {"Viewer"},
+		},
+	})
+	return &oidcProvider{cfg: *cfg, client: &http.Client{Timeout: oidcRequestTimeout}}
+}
+
+func TestOIDCProviderVerify(t *testing.T) {
+	idp := oidctest.NewProvider("mactl")
+	defer idp.Close()
+	provider := newTestOIDCProvider(idp)
+	now := time.Now()
+
+	valid := idp.IDToken(map[string]interface{}{"preferred_username": "alice"})
+	claims, err := provider.Verify(context.Background(), valid)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if claims["preferred_username"] != "alice" {
+		t.Fatalf("claims = %v, want preferred_username alice", claims)
+	}
+
+	parts := strings.Split(valid, ".")
+	other := strings.Split(idp.IDToken(map[string]interface{}{"preferred_username": "mallory"}), ".")
+	tampered := parts[0] + "." + other[1] + "." + parts[2]
+	tests := map[string]string{
+		"expired":         idp.IDToken(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()}),
+		"not yet valid":   idp.IDToken(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()}),
+		"other audience":  idp.IDToken(map[string]interface{}{"aud": "other-client"}),
+		"other issuer":    idp.IDToken(map[string]interface{}{"iss": "https://evil.example.com"}),
+		"tampered claims": tampered,
+		"malformed":       "not-a-jwt",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := provider.Verify(context.Background(), token); !errors.Is(err, db.ErrInvalidCredentials) {
+				t.Fatalf("Verify() error = %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestOIDCProviderAcceptsAudienceList(t *testing.T) {
+	idp := oidctest.NewProvider("mactl")
+	defer idp.Close()
+	provider := newTestOIDCProvider(idp)
+
+	token := idp.IDToken(map[string]interface{}{"aud": []string{"account", "mactl"}})
+	if _, err := provider.Verify(context.Background(), token); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+}
+
+func TestOIDCProviderFetchesKeysOnce(t *testing.T) {
+	idp := oidctest.NewProvider("mactl")
+	defer idp.Close()
+	provider := newTestOIDCProvider(idp)
+	token := idp.IDToken(map[string]interface{}{"sub": "2f1c9a7e"})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Go(func() {
+			if _, err := provider.Verify(context.Background(), token); err != nil {
+				errs <- err
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if n := idp.JWKSRequests(); n != 1 {
+		t.Fatalf("JWKS requests = %d, want 1", n)
+	}
+}
+
+// roundTripperFunc は関数を http.RoundTripper として使う
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestOIDCProviderFetchesKeysWithoutLock(t *testing.T) {
+	idp := oidctest.NewProvider("mactl")
+	defer idp.Close()
+	provider := newTestOIDCProvider(idp)
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+	provider.client.Transport = roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/jwks" {
+			close(fetching)
+			<-release
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := provider.Verify(context.Background(), idp.IDToken(map[string]interface{}{"sub": "2f1c9a7e"}))
+		done <- err
+	}()
+	<-fetching
+
+	// ID プロバイダーの応答を待つ間も、取得済みの鍵の参照は待たされない
+	looked := make(chan struct{})
+	go func() {
+		provider.cachedKey("other")
+		close(looked)
+	}()
+	select {
+	case <-looked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cachedKey() blocked while the JWKS was being fetched")
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+}
+
+func TestOIDCProviderExternalUser(t *testing.T) {
+	provider := &oidcProvider{cfg: *normalizeOIDCConfig(&OIDCConfig{
+		Issuer:   "https://idp.example.com/",
+		ClientID: "mactl",
+		GroupRoles: map[string][]string{
+			"developers": {"Compute-Operator", "Viewer"},
+			"readers":    {"Viewer"},
+		},
+	})}
+
+	claims := map[string]interface{}{
+		"sub":                "2f1c9a7e",
+		"preferred_username": "alice",
+		"name":               "Alice",
+		"email":              "alice@example.com",
+		"groups":             []interface{}{"readers", "developers", "unmapped"},
+	}
+	user := provider.ExternalUser(claims)
+	// 既定では変更や再割り当てのできる preferred_username ではなく sub を利用者IDにする
+	if user.UserID != "2f1c9a7e" || user.DisplayName != "Alice" || user.Email != "alice@example.com" {
+		t.Fatalf("user = %+v", user)
+	}
+	if got := strings.Join(user.Roles, ","); got != "Compute-Operator,Viewer" {
+		t.Fatalf("roles = %s, want Compute-Operator,Viewer", got)
+	}
+
+	provider.cfg.UsernameClaim = "preferred_username"
+	if user := provider.ExternalUser(claims); user.UserID != "alice" {
+		t.Fatalf("user with username_claim preferred_username = %+v, want alice", user)
+	}
+	if user := provider.ExternalUser(map[string]interface{}{"sub": "1234"}); user.UserID != "" || len(user.Roles) != 0 {
+		t.Fatalf("user without the username claim = %+v, want empty", user)
+	}
+}
+
+func TestOIDCProviderSyncedUser(t *testing.T) {
+	provider := &oidcProvider{}
+	ext := db.ExternalUser{UserID: "2f1c9a7e", DisplayName: "Alice", Email: "alice@example.com", Roles: []string{"Viewer"}}
+	user := api.User{Metadata: api.Metadata{Id: "2f1c9a7e", Name: "Alice"}}
+
+	if _, ok := provider.syncedUser(ext); ok {
+		t.Fatal("syncedUser() found a user that was never registered")
+	}
+	provider.rememberUser(ext, user)
+	if got, ok := provider.syncedUser(ext); !ok || got.Metadata.Id != "2f1c9a7e" {
+		t.Fatalf("syncedUser() = %+v, %v, want the registered user", got, ok)
+	}
+
+	// クレームやロールが変わった場合は登録し直す
+	changed := map[string]db.ExternalUser{
+		"roles": {UserID: ext.UserID, DisplayName: ext.DisplayName, Email: ext.Email, Roles: []string{"Administrator"}},
+		"name":  {UserID: ext.UserID, DisplayName: "Alice Smith", Email: ext.Email, Roles: ext.Roles},
+		"email": {UserID: ext.UserID, DisplayName: ext.DisplayName, Email: "alice@example.org", Roles: ext.Roles},
+		"user":  {UserID: "9b0d4c21", DisplayName: ext.DisplayName, Email: ext.Email, Roles: ext.Roles},
+	}
+	for name, other := range changed {
+		if _, ok := provider.syncedUser(other); ok {
+			t.Errorf("syncedUser() with changed %s = true, want false", name)
+		}
+	}
+
+	// 期限を過ぎた記録は使わず、次の登録で取り除く
+	provider.users[ext.UserID] = oidcSyncedUser{ext: ext, user: user, syncedAt: time.Now().Add(-oidcUserSyncTTL)}
+	if _, ok := provider.syncedUser(ext); ok {
+		t.Fatal("syncedUser() used an expired record")
+	}
+	provider.rememberUser(changed["user"], api.User{Metadata: api.Metadata{Id: "9b0d4c21"}})
+	if _, ok := provider.users[ext.UserID]; ok || len(provider.users) != 1 {
+		t.Fatalf("users = %v, want only the new record", provider.users)
+	}
+}
+
+func TestNormalizeOIDCConfig(t *testing.T) {
+	if cfg := normalizeOIDCConfig(&OIDCConfig{Issuer: "https://idp.example.com"}); cfg != nil {
+		t.Fatalf("normalizeOIDCConfig() without client_id = %+v, want nil", cfg)
+	}
+	cfg := normalizeOIDCConfig(&OIDCConfig{Issuer: " https://idp.example.com/ ", ClientID: "mactl", Scopes: []string{"groups"}})
+	if cfg.Issuer != "https://idp.example.com" || cfg.Audience != "mactl" {
+		t.Fatalf("cfg = %+v", cfg)
+	}
+	if got := strings.Join(cfg.Scopes, " "); got != "openid groups" {
+		t.Fatalf("scopes = %q, want openid groups", got)
+	}
+	if cfg.UsernameClaim != "sub" || cfg.GroupsClaim != "groups" {
+		t.Fatalf("claims = %s/%s", cfg.UsernameClaim, cfg.GroupsClaim)
+	}
+}
+
+func TestLooksLikeJWT(t *testing.T) {
+	if looksLikeJWT("0b9a3f54-2d1c-4a55-9d5c-1c0e6f0f6d2a5f0c0c1e-3e0b-4f2a-8b63-6c7d2b8f9e11") {
+		t.Fatal("an API key was treated as a JWT")
+	}
+	if !looksLikeJWT("a.b.c") {
+		t.Fatal("a JWT was not detected")
+	}
+}
@@ -0,0 +1,233 @@
+package marmotd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+/*
+プロジェクトとラベル単位の認可とリソースの絞り込み
+
+	利用者に割り当てたロールのラベルの条件のない権限で許可されない操作は、対象のリソースの metadata で認可する。
+	対象のリソースのプロジェクトのメンバーとしてのロールと、ラベルの条件のある権限を評価する。
+	対象の metadata は、作成では要求の metadata、既存のリソースへの操作ではリソースの metadata から求める。
+	一覧では、操作できる範囲 (プロジェクトとラベル) のリソースだけを返す
+*/
+
+// 一覧で参照できるリソースの範囲をハンドラーに渡すためのキー
+const authGrantsContextKey = "marmot.auth.grants"
+
+// 対象のプロジェクトを求める場所
+const (
+	projectSourceQuery    = "query"
+	projectSourceBody     = "body"
+	projectSourceFlatBody = "flatBody" // metadata を持たず、本文に name と project を持つリソース (証明書)
+	projectSourceResource = "resource"
+)
+
+// projectLookup は操作の対象のプロジェクトの求め方
+type projectLookup struct {
+	Source string
+	Prefix string // projectSourceResource の場合のリソースの種類 (db の Prefix)
+	Param  string // projectSourceResource の場合のリソースの ID のパスパラメーター
+}
+
+var (
+	projectFromQuery    = projectLookup{Source: projectSourceQuery}
+	projectFromBody     = projectLookup{Source: projectSourceBody}
+	projectFromFlatBody = projectLookup{Source: projectSourceFlatBody}
+)
+
+func projectOfResource(prefix, param string) projectLookup {
+	return projectLookup{Source: projectSourceResource, Prefix: prefix, Param: param}
+}
+
+// requestBodyMetadata は作成の要求の metadata を返す。flat の場合は本文の name と project から求める。
+// 本文は後続のハンドラーのために戻す
+func requestBodyMetadata(ctx echo.Context, flat bool) (api.Metadata, error) {
+	req := ctx.Request()
+	if req.Body == nil {
+		return api.Metadata{}, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return api.Metadata{}, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var spec struct {
+		Metadata api.Metadata `json:"metadata"`
+		Name     string       `json:"name"`
+		Project  *string      `json:"project"`
+	}
+	if err := json.Unmarshal(body, &spec); err != nil {
+		// 本文の誤りはハンドラーが応答する
+		return api.Metadata{}, nil
+	}
+	if flat {
+		return api.Metadata{Name: spec.Name, Project: spec.Project}, nil
+	}
+	return spec.Metadata, nil
+}
+
+// lookupResourceMetadata は既存のリソースの metadata を返す
+func (s *Server) lookupResourceMetadata(prefix, id string) (api.Metadata, error) {
+	var meta api.Metadata
+	switch prefix {
+	case db.ServerPrefix:
+		server, err := s.Ma.Db.GetServerById(id)
+		if err != nil {
+			return api.Metadata{}, err
+		}
+		meta = server.Metadata
+	case db.VolumePrefix:
+		vol, err := s.Ma.Db.GetVolumeById(id)
+		if err != nil {
+			return api.Metadata{}, err
+		}
+		meta = vol.Metadata
+	case db.NetworkPrefix:
+		network, err := s.Ma.Db.GetVirtualNetworkById(id)
+		if err != nil {
+			return api.Metadata{}, err
+		}
+		meta = network.Metadata
+	case db.ImagePrefix:
+		img, err := s.Ma.Db.GetImage(id)
+		if err != nil {
+			return api.Metadata{}, err
+		}
+		meta = img.Metadata
+	case db.LoadBalancerPrefix:
+		lb, err := s.Ma.Db.GetLoadBalancerById(id)
+		if err != nil {
+			return api.Metadata{}, err
+		}
+		meta = lb.Metadata
+	case db.NetworkLoadBalancerPrefix:
+		lb, err := s.Ma.Db.GetNetworkLoadBalancerById(id)
+		if err != nil {
+			return api.Metadata{}, err
+		}
+		meta = lb.Metadata
+	case db.CertificatePrefix:
+		cert, err := s.Ma.Db.GetCertificateById(id)
+		if err != nil {
+			return api.Metadata{}, err
+		}
+		meta = certificateMetadata(cert)
+	default:
+		return api.Metadata{}, fmt.Errorf("unsupported resource prefix for projects: %s", prefix)
+	}
+	return meta, nil
+}
+
+// authorizeTargets は利用者に割り当てたロールで許可されない操作を、対象のリソースの metadata で認可する。
+// 対象がすべて許可された場合に true を返す。存在しないリソースへの操作は許可しない
+func (s *Server) authorizeTargets(ctx echo.Context, userID string, r operationRBACRule) (bool, error) {
+	for _, lookup := range r.Projects {
+		var meta api.Metadata
+		switch lookup.Source {
+		case projectSourceQuery:
+			grants, err := s.Ma.Db.AccessGrants(userID, r.Resource, r.Verb)
+			if err != nil {
+				return false, err
+			}
+			project := strings.TrimSpace(ctx.QueryParam("project"))
+			grants = slices.DeleteFunc(grants, func(g db.AccessGrant) bool {
+				return project != "" && g.Project != "" && g.Project != project
+			})
+			if len(grants) == 0 {
+				return false, nil
+			}
+			ctx.Set(authGrantsContextKey, grants)
+			continue
+		case projectSourceBody, projectSourceFlatBody:
+			var err error
+			if meta, err = requestBodyMetadata(ctx, lookup.Source == projectSourceFlatBody); err != nil {
+				return false, err
+			}
+		case projectSourceResource:
+			var err error
+			meta, err = s.lookupResourceMetadata(lookup.Prefix, ctx.Param(lookup.Param))
+			if errors.Is(err, db.ErrNotFound) {
+				return false, nil
+			} else if err != nil {
+				return false, err
+			}
+		}
+		allowed, err := s.Ma.Db.AuthorizeResource(userID, r.Resource, r.Verb, meta)
+		if err != nil || !allowed {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// projectVisible は一覧で返すリソースの条件を返す。
+// project パラメーターのプロジェクトと、操作できる範囲のリソースに限る
+func projectVisible(ctx echo.Context, filter *api.ProjectFilter) func(api.Metadata) bool {
+	want := strings.TrimSpace(util.OrDefault(filter, ""))
+	grants, limited := ctx.Get(authGrantsContextKey).([]db.AccessGrant)
+	return func(meta api.Metadata) bool {
+		if want != "" && db.ProjectOf(meta) != want {
+			return false
+		}
+		if !limited {
+			return true
+		}
+		return slices.ContainsFunc(grants, func(g db.AccessGrant) bool { return g.Allows(meta) })
+	}
+}
+
+// filterByProject は条件に合うリソースだけを返す
+func filterByProject[S ~[]T, T any](items S, metadata func(T) api.Metadata, visible func(api.Metadata) bool) S {
+	filtered := make(S, 0, len(items))
+	for _, item := range items {
+		if visible(metadata(item)) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// resourceMetadata は watch で送るリソースの metadata を返す
+func resourceMetadata(obj interface{}) (api.Metadata, bool) {
+	switch o := obj.(type) {
+	case api.Server:
+		return o.Metadata, true
+	case api.Volume:
+		return o.Metadata, true
+	case api.VirtualNetwork:
+		return o.Metadata, true
+	case api.Image:
+		return o.Metadata, true
+	case api.ApplicationLoadBalancer:
+		return o.Metadata, true
+	case api.NetworkLoadBalancer:
+		return o.Metadata, true
+	}
+	return api.Metadata{}, false
+}
+
+// assignProject は作成するリソースのプロジェクトを決め、存在するかを確認する
+func (s *Server) assignProject(meta *api.Metadata) error {
+	project := db.ProjectOf(*meta)
+	if _, err := s.Ma.Db.GetProjectByName(project); errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("%w: project %q does not exist", db.ErrInvalidProject, project)
+	} else if err != nil {
+		return err
+	}
+	meta.Project = util.StringPtr(project)
+	return nil
+}
@@ -0,0 +1,104 @@
+package marmotd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func projectTestServers() api.Servers {
+	return api.Servers{
+		{Metadata: api.Metadata{Id: "s0001"}},
+		{Metadata: api.Metadata{Id: "s0002", Project: util.StringPtr("team-a")}},
+		{Metadata: api.Metadata{Id: "s0003", Project: util.StringPtr("team-b")}},
+		{Metadata: api.Metadata{Id: "s0004", Project: util.StringPtr("team-b"), Labels: &map[string]interface{}{"env": "dev"}}},
+	}
+}
+
+func projectTestServerIDs(servers api.Servers) string {
+	ids := make([]string, 0, len(servers))
+	for _, s := range servers {
+		ids = append(ids, s.Metadata.Id)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestProjectVisible(t *testing.T) {
+	e := echo.New()
+	metadata := func(r api.Server) api.Metadata { return r.Metadata }
+	dev := map[string]string{"env": "dev"}
+	tests := []struct {
+		name   string
+		filter *api.ProjectFilter
+		grants []db.AccessGrant
+		want   string
+	}{
+		{"all projects", nil, nil, "s0001,s0002,s0003,s0004"},
+		{"project filter", util.StringPtr("team-a"), nil, "s0002"},
+		{"default project", util.StringPtr(db.DefaultProjectName), nil, "s0001"},
+		{"member projects", nil, []db.AccessGrant{{Project: "team-a"}, {Project: "team-b"}}, "s0002,s0003,s0004"},
+		{"filter outside member projects", util.StringPtr(db.DefaultProjectName), []db.AccessGrant{{Project: "team-a"}}, ""},
+		{"label selector in all projects", nil, []db.AccessGrant{{MatchLabels: dev}}, "s0004"},
+		{"label selector or member project", nil, []db.AccessGrant{{Project: "team-a"}, {MatchLabels: dev}}, "s0002,s0004"},
+	}
+	for _, tt := range tests {
+		ctx := e.NewContext(httptest.NewRequest(http.MethodGet, "/server", nil), httptest.NewRecorder())
+		if tt.grants != nil {
+			ctx.Set(authGrantsContextKey, tt.grants)
+		}
+		got := filterByProject(projectTestServers(), metadata, projectVisible(ctx, tt.filter))
+		if ids := projectTestServerIDs(got); ids != tt.want {
+			t.Errorf("%s: servers = %q, want %q", tt.name, ids, tt.want)
+		}
+	}
+}
+
+func TestRequestBodyMetadata(t *testing.T) {
+	e := echo.New()
+	tests := []struct {
+		body string
+		flat bool
+		want string
+	}{
+		{`{"metadata":{"name":"web","project":"team-a"}}`, false, "team-a"},
+		{`{"metadata":{"name":"web"}}`, false, db.DefaultProjectName},
+		{`{"metadata":`, false, db.DefaultProjectName},
+		// 証明書は metadata を持たず、本文の project で作成を認可する
+		{`{"name":"web","project":"team-a","certificate":"CERT"}`, true, "team-a"},
+		{`{"name":"web","project":"team-a","certificate":"CERT"}`, false, db.DefaultProjectName},
+		{`{"name":"web","certificate":"CERT"}`, true, db.DefaultProjectName},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/server", strings.NewReader(tt.body))
+		ctx := e.NewContext(req, httptest.NewRecorder())
+		got, err := requestBodyMetadata(ctx, tt.flat)
+		if err != nil {
+			t.Fatalf("requestBodyMetadata(%s) error = %v", tt.body, err)
+		}
+		if project := db.ProjectOf(got); project != tt.want {
+			t.Errorf("requestBodyMetadata(%s) project = %q, want %q", tt.body, project, tt.want)
+		}
+		// ハンドラーが本文を読めるよう元に戻す
+		rest, _ := io.ReadAll(ctx.Request().Body)
+		if string(rest) != tt.body {
+			t.Errorf("body after requestBodyMetadata() = %q, want %q", rest, tt.body)
+		}
+	}
+}
+
+func TestResourceMetadata(t *testing.T) {
+	meta := api.Metadata{Id: "n0001", Project: util.StringPtr("team-a")}
+	if got, ok := resourceMetadata(api.VirtualNetwork{Metadata: meta}); !ok || got.Id != "n0001" {
+		t.Fatalf("resourceMetadata(VirtualNetwork) = %+v, %v", got, ok)
+	}
+	if _, ok := resourceMetadata(api.Error{Code: 500}); ok {
+		t.Fatal("resourceMetadata(Error) reported a resource")
+	}
+}
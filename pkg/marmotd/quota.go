@@ -0,0 +1,315 @@
+package marmotd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+/*
+クォータによるリソース作成の制限
+
+	利用者のクォータの使用量は metadata.owner が利用者のリソースから、プロジェクトのクォータの使用量は
+	metadata.project がプロジェクトのリソースから集計する。削除中のリソースは数えない。
+	サーバーのボリュームはコントローラーが作成するまでボリュームとして存在しないため、
+	まだ ID のないブートボリュームと新規のデータボリュームはサーバーの要求から見積もる
+*/
+
+const (
+	// サーバーとボリュームの要求で省略された値の既定値 (サーバー作成とボリューム作成の既定値に合わせる)
+	quotaDefaultServerCpu      = 2
+	quotaDefaultServerMemoryMB = 2048
+	quotaDefaultBootVolumeGB   = 16
+	quotaDefaultDataVolumeGB   = 1
+)
+
+// quotaAmount はクォータで制限するリソースの量
+type quotaAmount struct {
+	Vcpus     int
+	MemoryMB  int
+	VolumeGB  int
+	Servers   int
+	Networks  int
+	PublicIps int
+}
+
+func (a quotaAmount) add(b quotaAmount) quotaAmount {
+	return quotaAmount{
+		Vcpus:     a.Vcpus + b.Vcpus,
+		MemoryMB:  a.MemoryMB + b.MemoryMB,
+		VolumeGB:  a.VolumeGB + b.VolumeGB,
+		Servers:   a.Servers + b.Servers,
+		Networks:  a.Networks + b.Networks,
+		PublicIps: a.PublicIps + b.PublicIps,
+	}
+}
+
+func (a quotaAmount) resources() api.QuotaResources {
+	return api.QuotaResources{
+		Vcpus:     util.IntPtrInt(a.Vcpus),
+		MemoryMB:  util.IntPtrInt(a.MemoryMB),
+		VolumeGB:  util.IntPtrInt(a.VolumeGB),
+		Servers:   util.IntPtrInt(a.Servers),
+		Networks:  util.IntPtrInt(a.Networks),
+		PublicIps: util.IntPtrInt(a.PublicIps),
+	}
+}
+
+// quotaResourceSet は使用量の集計の対象とするリソース
+type quotaResourceSet struct {
+	Servers                  []api.Server
+	Volumes                  []api.Volume
+	Networks                 []api.VirtualNetwork
+	Gateways                 []api.Gateway
+	VpnGateways              []api.VpnGateway
+	ApplicationLoadBalancers []api.ApplicationLoadBalancer
+	NetworkLoadBalancers     []api.NetworkLoadBalancer
+}
+
+// quotaCounted は集計の対象で、削除中でないリソースかを返す
+func quotaCounted(meta api.Metadata, status *api.Status, match func(api.Metadata) bool) bool {
+	if !match(meta) {
+		return false
+	}
+	return status == nil || status.DeletionTimeStamp == nil
+}
+
+func volumeSizeGB(vol api.Volume, defaultGB int) int {
+	if size := util.OrDefault(vol.Spec.Size, 0); size > 0 {
+		return size
+	}
+	return defaultGB
+}
+
+// volumeDefaultSizeGB は容量を省略したボリュームの作成時の容量を返す
+func volumeDefaultSizeGB(vol api.Volume) int {
+	if volumeKindOrDefault(vol.Spec) == "os" {
+		return quotaDefaultBootVolumeGB
+	}
+	return quotaDefaultDataVolumeGB
+}
+
+// serverQuotaAmount はサーバー 1 台が使うリソースの量を返す。
+// まだ作成されていないブートボリュームとデータボリュームの容量を含む
+func serverQuotaAmount(server api.Server) quotaAmount {
+	amount := quotaAmount{
+		Servers:  1,
+		Vcpus:    util.OrDefault(server.Spec.Cpu, quotaDefaultServerCpu),
+		MemoryMB: util.OrDefault(server.Spec.Memory, quotaDefaultServerMemoryMB),
+	}
+	if server.Spec.BootVolume == nil {
+		amount.VolumeGB += quotaDefaultBootVolumeGB
+	} else if strings.TrimSpace(api.VolumeID(*server.Spec.BootVolume)) == "" {
+		amount.VolumeGB += volumeSizeGB(*server.Spec.BootVolume, quotaDefaultBootVolumeGB)
+	}
+	if server.Spec.Storage != nil {
+		for _, disk := range *server.Spec.Storage {
+			if shouldResolvePreCreatedStorageVolume(disk) {
+				continue
+			}
+			amount.VolumeGB += volumeSizeGB(disk, quotaDefaultDataVolumeGB)
+		}
+	}
+	return amount
+}
+
+// serverResizeQuotaAmount はサーバーの vCPU とメモリの変更で増える量を返す
+func serverResizeQuotaAmount(current, spec api.Server) quotaAmount {
+	var amount quotaAmount
+	if spec.Spec.Cpu != nil {
+		amount.Vcpus = *spec.Spec.Cpu - util.OrDefault(current.Spec.Cpu, quotaDefaultServerCpu)
+	}
+	if spec.Spec.Memory != nil {
+		amount.MemoryMB = *spec.Spec.Memory - util.OrDefault(current.Spec.Memory, quotaDefaultServerMemoryMB)
+	}
+	return amount
+}
+
+// quotaUsageOf は利用者が所有するリソースの使用量を集計する
+func quotaUsageOf(userID string, set quotaResourceSet) quotaAmount {
+	return quotaUsageWhere(set, func(meta api.Metadata) bool {
+		return util.OrDefault(meta.Owner, "") == userID
+	})
+}
+
+// projectQuotaUsageOf はプロジェクトに属するリソースの使用量を集計する
+func projectQuotaUsageOf(project string, set quotaResourceSet) quotaAmount {
+	return quotaUsageWhere(set, func(meta api.Metadata) bool {
+		return db.ProjectOf(meta) == project
+	})
+}
+
+// quotaUsageWhere は条件に合うリソースの使用量を集計する
+func quotaUsageWhere(set quotaResourceSet, match func(api.Metadata) bool) quotaAmount {
+	var used quotaAmount
+	for _, server := range set.Servers {
+		if quotaCounted(server.Metadata, server.Status, match) {
+			used = used.add(serverQuotaAmount(server))
+		}
+	}
+	for _, vol := range set.Volumes {
+		if quotaCounted(vol.Metadata, vol.Status, match) {
+			used.VolumeGB += util.OrDefault(vol.Spec.Size, 0)
+		}
+	}
+	for _, network := range set.Networks {
+		if quotaCounted(network.Metadata, network.Status, match) {
+			used.Networks++
+		}
+	}
+	for _, gw := range set.Gateways {
+		if quotaCounted(gw.Metadata, gw.Status, match) {
+			used.PublicIps++
+		}
+	}
+	for _, gw := range set.VpnGateways {
+		if quotaCounted(gw.Metadata, gw.Status, match) {
+			used.PublicIps++
+		}
+	}
+	for _, lb := range set.ApplicationLoadBalancers {
+		if quotaCounted(lb.Metadata, lb.Status, match) {
+			used.PublicIps++
+		}
+	}
+	for _, lb := range set.NetworkLoadBalancers {
+		if quotaCounted(lb.Metadata, lb.Status, match) {
+			used.PublicIps++
+		}
+	}
+	return used
+}
+
+// checkQuota は使用量に要求を加えた量がクォータの上限を超えないかを確認する。
+// 要求で増えないリソースは、すでに上限を超えていても確認しない
+func checkQuota(quotas []api.Quota, used, requested quotaAmount) error {
+	after := used.add(requested)
+	for _, q := range quotas {
+		dims := []struct {
+			name           string
+			limit          *int
+			request, after int
+		}{
+			{"vcpus", q.Limits.Vcpus, requested.Vcpus, after.Vcpus},
+			{"memoryMB", q.Limits.MemoryMB, requested.MemoryMB, after.MemoryMB},
+			{"volumeGB", q.Limits.VolumeGB, requested.VolumeGB, after.VolumeGB},
+			{"servers", q.Limits.Servers, requested.Servers, after.Servers},
+			{"networks", q.Limits.Networks, requested.Networks, after.Networks},
+			{"publicIps", q.Limits.PublicIps, requested.PublicIps, after.PublicIps},
+		}
+		for _, d := range dims {
+			if d.limit == nil || d.request <= 0 || d.after <= *d.limit {
+				continue
+			}
+			return fmt.Errorf("%w: %s of quota %q would be %d, the limit is %d", db.ErrQuotaExceeded, d.name, q.Name, d.after, *d.limit)
+		}
+	}
+	return nil
+}
+
+// requestOwner は作成するリソースの所有者として認証した利用者を返す。
+// 所有者はクォータの集計に使うため、クライアントが指定した値は使わない
+func requestOwner(ctx echo.Context) *string {
+	if userID := requestUserID(ctx); userID != "" {
+		return util.StringPtr(userID)
+	}
+	return nil
+}
+
+// quotaResources は使用量の集計に使うリソースを etcd から読み込む
+func (s *Server) quotaResources() (quotaResourceSet, error) {
+	var set quotaResourceSet
+	var err error
+	if set.Servers, err = s.Ma.Db.GetServers(); err != nil {
+		return set, err
+	}
+	if set.Volumes, err = s.Ma.Db.GetVolumes(); err != nil {
+		return set, err
+	}
+	if set.Networks, err = s.Ma.Db.GetVirtualNetworks(); err != nil {
+		return set, err
+	}
+	if set.Gateways, err = s.Ma.Db.GetGateways(); err != nil {
+		return set, err
+	}
+	if set.VpnGateways, err = s.Ma.Db.GetVpnGateways(); err != nil {
+		return set, err
+	}
+	if set.ApplicationLoadBalancers, err = s.Ma.Db.GetLoadBalancers(); err != nil {
+		return set, err
+	}
+	if set.NetworkLoadBalancers, err = s.Ma.Db.GetNetworkLoadBalancers(); err != nil {
+		return set, err
+	}
+	return set, nil
+}
+
+// quotaUsage は利用者の使用量を集計する
+func (s *Server) quotaUsage(userID string) (quotaAmount, error) {
+	set, err := s.quotaResources()
+	if err != nil {
+		return quotaAmount{}, err
+	}
+	return quotaUsageOf(userID, set), nil
+}
+
+// reserveQuota は利用者とプロジェクトのクォータに要求が収まるかを確認する。
+// 同じ利用者やプロジェクトの作成が同時に上限を超えないよう、リソースを登録するまで戻り値の関数を呼ばずにロックを保持する
+func (s *Server) reserveQuota(userID, project string, requested quotaAmount) (func(), error) {
+	var userQuotas, projectQuotas []api.Quota
+	var err error
+	if userID != "" {
+		if userQuotas, err = s.Ma.Db.GetQuotasForUser(userID); err != nil {
+			return func() {}, err
+		}
+	}
+	if project != "" {
+		if projectQuotas, err = s.Ma.Db.GetQuotasForProject(project); err != nil {
+			return func() {}, err
+		}
+	}
+
+	// ロックは利用者、プロジェクトの順に取得する
+	var locks []string
+	if len(userQuotas) > 0 {
+		locks = append(locks, "/lock/quota/user/"+userID)
+	}
+	if len(projectQuotas) > 0 {
+		locks = append(locks, "/lock/quota/project/"+project)
+	}
+	var mutexes []*concurrency.Mutex
+	release := func() {
+		for i := len(mutexes) - 1; i >= 0; i-- {
+			s.Ma.Db.UnlockKey(mutexes[i])
+		}
+	}
+	for _, lock := range locks {
+		mutex, err := s.Ma.Db.LockKey(lock)
+		if err != nil {
+			release()
+			return func() {}, err
+		}
+		mutexes = append(mutexes, mutex)
+	}
+	if len(locks) == 0 {
+		return release, nil
+	}
+
+	set, err := s.quotaResources()
+	if err == nil && len(userQuotas) > 0 {
+		err = checkQuota(userQuotas, quotaUsageOf(userID, set), requested)
+	}
+	if err == nil && len(projectQuotas) > 0 {
+		err = checkQuota(projectQuotas, projectQuotaUsageOf(project, set), requested)
+	}
+	if err != nil {
+		release()
+		return func() {}, err
+	}
+	return release, nil
+}
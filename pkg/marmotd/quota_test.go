@@ -0,0 +1,153 @@
+package marmotd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func quotaTestMetadata(id, owner string) api.Metadata {
+	meta := api.Metadata{Id: id}
+	if owner != "" {
+		meta.Owner = util.StringPtr(owner)
+	}
+	return meta
+}
+
+func TestServerQuotaAmount(t *testing.T) {
+	pending := api.Server{Spec: api.ServerSpec{
+		Cpu: util.IntPtrInt(4),
+		Storage: &[]api.Volume{
+			{Spec: api.VolSpec{Size: util.IntPtrInt(10)}},
+			{},
+			{Metadata: api.Metadata{Name: "shared"}, Spec: api.VolSpec{Size: util.IntPtrInt(100)}},
+		},
+	}}
+	got := serverQuotaAmount(pending)
+	want := quotaAmount{Servers: 1, Vcpus: 4, MemoryMB: quotaDefaultServerMemoryMB, VolumeGB: quotaDefaultBootVolumeGB + 10 + quotaDefaultDataVolumeGB}
+	if got != want {
+		t.Fatalf("serverQuotaAmount(pending) = %+v, want %+v", got, want)
+	}
+
+	// 作成済みのボリュームはボリュームとして数えるため、サーバーの量に含めない
+	created := api.Server{Spec: api.ServerSpec{
+		Memory:     util.IntPtrInt(1024),
+		BootVolume: &api.Volume{Metadata: quotaTestMetadata("b0001", ""), Spec: api.VolSpec{Size: util.IntPtrInt(16)}},
+		Storage:    &[]api.Volume{{Metadata: quotaTestMetadata("d0001", ""), Spec: api.VolSpec{Size: util.IntPtrInt(10)}}},
+	}}
+	got = serverQuotaAmount(created)
+	want = quotaAmount{Servers: 1, Vcpus: quotaDefaultServerCpu, MemoryMB: 1024}
+	if got != want {
+		t.Fatalf("serverQuotaAmount(created) = %+v, want %+v", got, want)
+	}
+}
+
+func TestServerResizeQuotaAmount(t *testing.T) {
+	current := api.Server{Spec: api.ServerSpec{Cpu: util.IntPtrInt(2), Memory: util.IntPtrInt(4096)}}
+	got := serverResizeQuotaAmount(current, api.Server{Spec: api.ServerSpec{Cpu: util.IntPtrInt(6), Memory: util.IntPtrInt(2048)}})
+	if got != (quotaAmount{Vcpus: 4, MemoryMB: -2048}) {
+		t.Fatalf("serverResizeQuotaAmount() = %+v, want vcpus +4 memory -2048", got)
+	}
+	if got := serverResizeQuotaAmount(current, api.Server{}); got != (quotaAmount{}) {
+		t.Fatalf("serverResizeQuotaAmount(no change) = %+v, want zero", got)
+	}
+}
+
+func TestQuotaUsageOf(t *testing.T) {
+	deleting := &api.Status{DeletionTimeStamp: util.TimePtr(time.Now())}
+	set := quotaResourceSet{
+		Servers: []api.Server{
+			{Metadata: quotaTestMetadata("s0001", "alice"), Spec: api.ServerSpec{Cpu: util.IntPtrInt(2), Memory: util.IntPtrInt(2048), BootVolume: &api.Volume{Metadata: quotaTestMetadata("b0001", "alice")}}},
+			{Metadata: quotaTestMetadata("s0002", "alice"), Spec: api.ServerSpec{Cpu: util.IntPtrInt(8)}, Status: deleting},
+			{Metadata: quotaTestMetadata("s0003", "bob"), Spec: api.ServerSpec{Cpu: util.IntPtrInt(8)}},
+			{Metadata: quotaTestMetadata("s0004", ""), Spec: api.ServerSpec{Cpu: util.IntPtrInt(8)}},
+		},
+		Volumes: []api.Volume{
+			{Metadata: quotaTestMetadata("b0001", "alice"), Spec: api.VolSpec{Size: util.IntPtrInt(16)}},
+			{Metadata: quotaTestMetadata("v0001", "alice"), Spec: api.VolSpec{Size: util.IntPtrInt(50)}},
+			{Metadata: quotaTestMetadata("v0002", "alice"), Spec: api.VolSpec{Size: util.IntPtrInt(30)}, Status: deleting},
+			{Metadata: quotaTestMetadata("v0003", "bob"), Spec: api.VolSpec{Size: util.IntPtrInt(30)}},
+		},
+		Networks:                 []api.VirtualNetwork{{Metadata: quotaTestMetadata("n0001", "alice")}, {Metadata: quotaTestMetadata("n0002", "bob")}},
+		Gateways:                 []api.Gateway{{Metadata: quotaTestMetadata("g0001", "alice")}},
+		VpnGateways:              []api.VpnGateway{{Metadata: quotaTestMetadata("p0001", "alice")}},
+		ApplicationLoadBalancers: []api.ApplicationLoadBalancer{{Metadata: quotaTestMetadata("l0001", "alice")}},
+		NetworkLoadBalancers:     []api.NetworkLoadBalancer{{Metadata: quotaTestMetadata("t0001", "bob")}},
+	}
+
+	got := quotaUsageOf("alice", set)
+	want := quotaAmount{Servers: 1, Vcpus: 2, MemoryMB: 2048, VolumeGB: 66, Networks: 1, PublicIps: 3}
+	if got != want {
+		t.Fatalf("quotaUsageOf(alice) = %+v, want %+v", got, want)
+	}
+}
+
+func TestProjectQuotaUsageOf(t *testing.T) {
+	inProject := func(id, project string) api.Metadata {
+		meta := quotaTestMetadata(id, "alice")
+		if project != "" {
+			meta.Project = util.StringPtr(project)
+		}
+		return meta
+	}
+	set := quotaResourceSet{
+		Servers: []api.Server{
+			{Metadata: inProject("s0001", "team-a"), Spec: api.ServerSpec{Cpu: util.IntPtrInt(2), Memory: util.IntPtrInt(2048), BootVolume: &api.Volume{Metadata: quotaTestMetadata("b0001", "alice")}}},
+			{Metadata: inProject("s0002", ""), Spec: api.ServerSpec{Cpu: util.IntPtrInt(8)}},
+		},
+		Volumes: []api.Volume{
+			{Metadata: inProject("b0001", "team-a"), Spec: api.VolSpec{Size: util.IntPtrInt(16)}},
+			{Metadata: inProject("v0001", db.DefaultProjectName), Spec: api.VolSpec{Size: util.IntPtrInt(50)}},
+		},
+		Networks: []api.VirtualNetwork{{Metadata: inProject("n0001", "team-a")}},
+	}
+
+	got := projectQuotaUsageOf("team-a", set)
+	want := quotaAmount{Servers: 1, Vcpus: 2, MemoryMB: 2048, VolumeGB: 16, Networks: 1}
+	if got != want {
+		t.Fatalf("projectQuotaUsageOf(team-a) = %+v, want %+v", got, want)
+	}
+	// プロジェクトのないリソースは default プロジェクトで数える
+	got = projectQuotaUsageOf(db.DefaultProjectName, set)
+	want = quotaAmount{Servers: 1, Vcpus: 8, MemoryMB: quotaDefaultServerMemoryMB, VolumeGB: quotaDefaultBootVolumeGB + 50}
+	if got != want {
+		t.Fatalf("projectQuotaUsageOf(default) = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	quotas := []api.Quota{
+		{Name: "team-a", Limits: api.QuotaResources{Vcpus: util.IntPtrInt(8), Servers: util.IntPtrInt(2)}},
+		{Name: "trial", Limits: api.QuotaResources{VolumeGB: util.IntPtrInt(100)}},
+	}
+	used := quotaAmount{Servers: 1, Vcpus: 4, VolumeGB: 120}
+
+	if err := checkQuota(quotas, used, quotaAmount{Servers: 1, Vcpus: 4}); err != nil {
+		t.Fatalf("checkQuota(within limits) error = %v", err)
+	}
+	// すでに上限を超えている容量は、容量を増やさない要求では確認しない
+	if err := checkQuota(quotas, used, quotaAmount{Vcpus: -2}); err != nil {
+		t.Fatalf("checkQuota(shrink) error = %v", err)
+	}
+
+	exceeded := []struct {
+		name      string
+		requested quotaAmount
+		want      string
+	}{
+		{"vcpus", quotaAmount{Servers: 1, Vcpus: 6}, `vcpus of quota "team-a" would be 10, the limit is 8`},
+		{"servers", quotaAmount{Servers: 2, Vcpus: 2}, `servers of quota "team-a" would be 3, the limit is 2`},
+		{"volume", quotaAmount{VolumeGB: 1}, `volumeGB of quota "trial" would be 121, the limit is 100`},
+	}
+	for _, tt := range exceeded {
+		err := checkQuota(quotas, used, tt.requested)
+		if !errors.Is(err, db.ErrQuotaExceeded) || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: checkQuota() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
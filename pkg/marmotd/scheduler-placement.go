@@ -0,0 +1,201 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+)
+
+// ErrPlacementUnsatisfiable は配置ルールを満たすノードが存在しないことを示す
+var ErrPlacementUnsatisfiable = errors.New("placement rules not satisfied")
+
+const (
+	minAffinityWeight = 1
+	maxAffinityWeight = 100
+)
+
+// ValidateServerPlacement は spec.placement の記述を検証する
+func ValidateServerPlacement(p *api.ServerPlacement) error {
+	if p == nil {
+		return nil
+	}
+	if p.NodeSelector != nil {
+		for k := range *p.NodeSelector {
+			if strings.TrimSpace(k) == "" {
+				return errors.New("placement.nodeSelector has an empty key")
+			}
+		}
+	}
+	for _, rule := range []struct {
+		name     string
+		affinity *api.ServerAffinity
+	}{
+		{"affinity", p.Affinity},
+		{"antiAffinity", p.AntiAffinity},
+	} {
+		if rule.affinity == nil {
+			continue
+		}
+		if rule.affinity.Required != nil {
+			for i, term := range *rule.affinity.Required {
+				if err := validateMatchLabels(term.MatchLabels); err != nil {
+					return fmt.Errorf("placement.%s.required[%d]: %w", rule.name, i, err)
+				}
+			}
+		}
+		if rule.affinity.Preferred != nil {
+			for i, term := range *rule.affinity.Preferred {
+				if err := validateMatchLabels(term.MatchLabels); err != nil {
+					return fmt.Errorf("placement.%s.preferred[%d]: %w", rule.name, i, err)
+				}
+				if term.Weight < minAffinityWeight || term.Weight > maxAffinityWeight {
+					return fmt.Errorf("placement.%s.preferred[%d]: weight must be between %d and %d", rule.name, i, minAffinityWeight, maxAffinityWeight)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateMatchLabels(match map[string]string) error {
+	if len(match) == 0 {
+		return errors.New("matchLabels must not be empty")
+	}
+	for k := range match {
+		if strings.TrimSpace(k) == "" {
+			return errors.New("matchLabels has an empty key")
+		}
+	}
+	return nil
+}
+
+// serverLabelsMatch はサーバーの metadata.labels が match のすべてを含むか判定する
+func serverLabelsMatch(server api.Server, match map[string]string) bool {
+	if len(match) == 0 || server.Metadata.Labels == nil {
+		return false
+	}
+	labels := *server.Metadata.Labels
+	for key, expected := range match {
+		value, ok := labels[strings.TrimSpace(key)]
+		if !ok || strings.TrimSpace(fmt.Sprint(value)) != strings.TrimSpace(expected) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatMatchLabels(match map[string]string) string {
+	pairs := make([]string, 0, len(match))
+	for k, v := range match {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func sameServer(a, b api.Server) bool {
+	return api.ServerID(a) != "" && api.ServerID(a) == api.ServerID(b)
+}
+
+func requiredTerms(a *api.ServerAffinity) []api.ServerAffinityTerm {
+	if a == nil || a.Required == nil {
+		return nil
+	}
+	return *a.Required
+}
+
+func preferredTerms(a *api.ServerAffinity) []api.ServerWeightedAffinityTerm {
+	if a == nil || a.Preferred == nil {
+		return nil
+	}
+	return *a.Preferred
+}
+
+// placementViolations は server を node に配置した場合に満たせない配置ルールを返す。
+// nodes はクラスタ全体のノードで、アフィニティ対象のサーバーが他に存在するかの判定に使う。
+func placementViolations(server api.Server, node NodeResources, nodes []NodeResources) []string {
+	var violations []string
+	var placement api.ServerPlacement
+	if server.Spec.Placement != nil {
+		placement = *server.Spec.Placement
+	}
+
+	if placement.NodeSelector != nil {
+		for key, expected := range *placement.NodeSelector {
+			if value, ok := node.Labels[strings.TrimSpace(key)]; !ok || value != strings.TrimSpace(expected) {
+				violations = append(violations, fmt.Sprintf("nodeSelector %s=%s not matched", key, expected))
+			}
+		}
+		sort.Strings(violations)
+	}
+
+	for _, term := range requiredTerms(placement.Affinity) {
+		if nodeHasMatchingServer(server, node, term.MatchLabels) {
+			continue
+		}
+		// 対象のサーバーがまだ存在せず自身が条件に一致する場合は、グループの最初の1台として配置を許可する
+		if !clusterHasMatchingServer(server, nodes, term.MatchLabels) && serverLabelsMatch(server, term.MatchLabels) {
+			continue
+		}
+		violations = append(violations, fmt.Sprintf("affinity %s: no matching server on node", formatMatchLabels(term.MatchLabels)))
+	}
+
+	for _, term := range requiredTerms(placement.AntiAffinity) {
+		if nodeHasMatchingServer(server, node, term.MatchLabels) {
+			violations = append(violations, fmt.Sprintf("anti-affinity %s: matching server on node", formatMatchLabels(term.MatchLabels)))
+		}
+	}
+
+	// 配置済みサーバー側の必須アンチアフィニティも尊重する
+	for _, other := range node.Servers {
+		if sameServer(server, other) || other.Spec.Placement == nil {
+			continue
+		}
+		for _, term := range requiredTerms(other.Spec.Placement.AntiAffinity) {
+			if serverLabelsMatch(server, term.MatchLabels) {
+				violations = append(violations, fmt.Sprintf("anti-affinity of server %s (%s)", other.Metadata.Name, formatMatchLabels(term.MatchLabels)))
+			}
+		}
+	}
+	return violations
+}
+
+func nodeHasMatchingServer(server api.Server, node NodeResources, match map[string]string) bool {
+	for _, other := range node.Servers {
+		if !sameServer(server, other) && serverLabelsMatch(other, match) {
+			return true
+		}
+	}
+	return false
+}
+
+func clusterHasMatchingServer(server api.Server, nodes []NodeResources, match map[string]string) bool {
+	for _, n := range nodes {
+		if nodeHasMatchingServer(server, n, match) {
+			return true
+		}
+	}
+	return false
+}
+
+// placementPreference は優先アフィニティの重みの合計を返す。大きいほど優先される。
+func placementPreference(server api.Server, node NodeResources) int {
+	if server.Spec.Placement == nil {
+		return 0
+	}
+	preference := 0
+	for _, term := range preferredTerms(server.Spec.Placement.Affinity) {
+		if nodeHasMatchingServer(server, node, term.MatchLabels) {
+			preference += term.Weight
+		}
+	}
+	for _, term := range preferredTerms(server.Spec.Placement.AntiAffinity) {
+		if nodeHasMatchingServer(server, node, term.MatchLabels) {
+			preference -= term.Weight
+		}
+	}
+	return preference
+}
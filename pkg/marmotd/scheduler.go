@@ -2,12 +2,16 @@ package marmotd
 
 import (
 	"errors"
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
 )
 
 const (
@@ -30,10 +34,25 @@ func filterActiveHosts(statuses []api.HostStatus) []api.HostStatus {
 	return active
 }
 
+// filterSchedulableHosts はアクティブなホストのうち cordon されていないものを返す
+func filterSchedulableHosts(statuses []api.HostStatus) []api.HostStatus {
+	var schedulable []api.HostStatus
+	for _, s := range filterActiveHosts(statuses) {
+		if !db.IsNodeUnschedulable(s) {
+			schedulable = append(schedulable, s)
+		}
+	}
+	return schedulable
+}
+
 // IsSchedulerLeader は nodeName がアクティブなホスト群の中でリーダーか判定する。
 // リーダー = hostId の値が最小のノード。同値の場合は NodeName 昇順。
+// cordon されたノードは、他にスケジュール可能なノードがある限りリーダーにならない。
 func IsSchedulerLeader(nodeName string, statuses []api.HostStatus) bool {
-	active := filterActiveHosts(statuses)
+	active := filterSchedulableHosts(statuses)
+	if len(active) == 0 {
+		active = filterActiveHosts(statuses)
+	}
 	if len(active) == 0 {
 		return false
 	}
@@ -77,38 +96,6 @@ func parseHostIDHex(v string) (uint32, bool) {
 	return uint32(id), true
 }
 
-// SelectNode はアクティブなホスト群からスコアが最良のノード名を返す。
-// スコアは割り当て済みVM数（TotalVMs, 少ないほど優先）。同点の場合は NodeName 昇順。
-func SelectNode(statuses []api.HostStatus) (string, error) {
-	active := filterActiveHosts(statuses)
-	if len(active) == 0 {
-		return "", ErrNoActiveHosts
-	}
-
-	// NodeName が設定されているホストのみ対象
-	var candidates []api.HostStatus
-	for _, s := range active {
-		if s.NodeName != nil && *s.NodeName != "" {
-			candidates = append(candidates, s)
-		}
-	}
-	if len(candidates) == 0 {
-		return "", ErrNoActiveHosts
-	}
-
-	sort.Slice(candidates, func(i, j int) bool {
-		allocatedI := allocatedVMs(candidates[i])
-		allocatedJ := allocatedVMs(candidates[j])
-		if allocatedI != allocatedJ {
-			return allocatedI < allocatedJ
-		}
-		// 同点の場合はノード名昇順（決定的な選択）
-		return *candidates[i].NodeName < *candidates[j].NodeName
-	})
-
-	return *candidates[0].NodeName, nil
-}
-
 // allocatedVMs は HostAllocation.TotalVMs の値を返す。未設定の場合は 0。
 func allocatedVMs(s api.HostStatus) int {
 	if s.Allocation != nil && s.Allocation.TotalVMs != nil {
@@ -124,8 +111,10 @@ func allocatedVMs(s api.HostStatus) int {
 //     そのホストが担当する（nodeName の IscsiServer が true であれば true を返す）。
 //  2. どのホストにも IscsiServer=true が設定されていない場合は、
 //     アクティブなホストの中で HostId が最小のホスト（= スケジューラーリーダー）が担当する。
+//
+// cordon されたホストは選出の対象外とする。
 func IsIscsiServer(nodeName string, statuses []api.HostStatus) bool {
-	active := filterActiveHosts(statuses)
+	active := filterSchedulableHosts(statuses)
 	if len(active) == 0 {
 		return false
 	}
@@ -146,3 +135,411 @@ func IsIscsiServer(nodeName string, statuses []api.HostStatus) bool {
 	// フォールバック: HostId が最小のホスト（スケジューラーリーダー）が担当
 	return IsSchedulerLeader(nodeName, statuses)
 }
+
+// ErrInsufficientCapacity はどのノードにもサーバーの要求資源が収まらないことを示す
+var ErrInsufficientCapacity = errors.New("insufficient capacity")
+
+const (
+	// CreateServerManage が適用する既定値と合わせる
+	defaultServerCpuCores = 2
+	defaultServerMemoryMB = 2048
+	// LVM の OS ボリュームはイメージの 4GB 固定スナップショットとして作成される
+	lvmBootVolumeSizeGB = 4
+
+	// 容量が取得できていないことを表す値。この場合は制約なしとして扱う
+	unknownCapacity = -1
+)
+
+// ServerResourceRequest はサーバー1台の配置に必要な資源量を表す
+type ServerResourceRequest struct {
+	CpuCores          int
+	MemoryMB          int
+	OsVolumeGroupGB   int
+	DataVolumeGroupGB int
+	VolumeStoreGB     int
+
+	// 専有 vCPU の数（分離したエミュレータースレッドの分を含む）と hugepages のページ数
+	// SingleNUMANode の場合は 1 つの NUMA ノードに収まる必要がある
+	PinnedCpus     int
+	SingleNUMANode bool
+	Hugepages2M    int
+	Hugepages1G    int
+}
+
+// ServerResourceRequestOf はサーバースペックから配置に必要な資源量を求める。
+// 作成済みボリュームや Ceph/iSCSI のボリュームはノードの容量を消費しないため計上しない。
+func ServerResourceRequestOf(server api.Server) ServerResourceRequest {
+	req := ServerResourceRequest{
+		CpuCores: defaultServerCpuCores,
+		MemoryMB: defaultServerMemoryMB,
+	}
+	if server.Spec.Cpu != nil && *server.Spec.Cpu > 0 {
+		req.CpuCores = *server.Spec.Cpu
+	}
+	if server.Spec.Memory != nil && *server.Spec.Memory > 0 {
+		req.MemoryMB = *server.Spec.Memory
+	}
+	// 専有 vCPU はプールから割り当てるため、共有の CPU を消費しない
+	if n := pinnedCPUCount(server.Spec); n > 0 {
+		req.CpuCores = 0
+		req.PinnedCpus = n
+		req.SingleNUMANode = requiresSingleNUMANode(server.Spec)
+	}
+	switch size, pages := serverHugepages(server.Spec); size {
+	case virt.HugepageSize2M:
+		req.Hugepages2M = pages
+	case virt.HugepageSize1G:
+		req.Hugepages1G = pages
+	}
+
+	bootType := "qcow2"
+	if server.Spec.BootVolume != nil && server.Spec.BootVolume.Spec.Type != nil {
+		bootType = strings.TrimSpace(*server.Spec.BootVolume.Spec.Type)
+	}
+	switch bootType {
+	case "qcow2":
+		// サイズ未指定時はイメージのコピーとなるため、事前にサイズを見積もれない
+		if server.Spec.BootVolume != nil && server.Spec.BootVolume.Spec.Size != nil && *server.Spec.BootVolume.Spec.Size > 0 {
+			req.VolumeStoreGB += *server.Spec.BootVolume.Spec.Size
+		}
+	case "lvm":
+		req.OsVolumeGroupGB += lvmBootVolumeSizeGB
+	}
+
+	if server.Spec.Storage == nil {
+		return req
+	}
+	for _, disk := range *server.Spec.Storage {
+		if shouldResolvePreCreatedStorageVolume(disk) {
+			continue
+		}
+		if disk.Spec.Size == nil || *disk.Spec.Size <= 0 {
+			continue
+		}
+		switch strings.TrimSpace(util.OrDefault(disk.Spec.Type, "qcow2")) {
+		case "", "qcow2":
+			req.VolumeStoreGB += *disk.Spec.Size
+		case "lvm":
+			if volumeKindOrDefault(disk.Spec) == "os" {
+				req.OsVolumeGroupGB += *disk.Spec.Size
+			} else {
+				req.DataVolumeGroupGB += *disk.Spec.Size
+			}
+		}
+	}
+	return req
+}
+
+// NodeResources はスケジューラーが配置判定に使うノードの資源状況を表す。
+// 各容量が unknownCapacity の場合、その資源は配置判定の制約としない。
+type NodeResources struct {
+	NodeName string
+	TotalVMs int
+
+	// cordon されたノードには新たに配置しない
+	Unschedulable bool
+
+	// ノードラベルと配置済みサーバー（配置ルールの評価に使用）
+	Labels  map[string]string
+	Servers []api.Server
+
+	// オーバーコミット倍率適用後の割当可能量と割当済み量
+	CpuCores          int
+	MemoryMB          int
+	AllocatedCpuCores int
+	AllocatedMemoryMB int
+
+	// ボリューム作成先の空き容量
+	OsVolumeGroupFreeGB   int
+	DataVolumeGroupFreeGB int
+	VolumeStoreFreeGB     int
+
+	// NUMA ノード毎の専有 vCPU と hugepages の空き。ホストが報告していなければ空
+	NUMANodes []NUMAResources
+}
+
+// NUMAResources は NUMA ノードの専有 vCPU のプールと hugepages の空きを表す
+type NUMAResources struct {
+	ID              int
+	FreePinnedCpus  int
+	FreeHugepages2M int
+	FreeHugepages1G int
+}
+
+// NewNodeResources は HostStatus のキャパシティと割当情報から NodeResources を作成する
+func NewNodeResources(status api.HostStatus, cpuOvercommitRatio, memoryOvercommitRatio float64) NodeResources {
+	n := NodeResources{
+		CpuCores:              unknownCapacity,
+		MemoryMB:              unknownCapacity,
+		OsVolumeGroupFreeGB:   unknownCapacity,
+		DataVolumeGroupFreeGB: unknownCapacity,
+		VolumeStoreFreeGB:     unknownCapacity,
+	}
+	if status.NodeName != nil {
+		n.NodeName = strings.TrimSpace(*status.NodeName)
+	}
+	n.TotalVMs = allocatedVMs(status)
+	n.Unschedulable = db.IsNodeUnschedulable(status)
+	if status.Labels != nil {
+		n.Labels = *status.Labels
+	}
+
+	if c := status.Capacity; c != nil {
+		if c.CpuCores != nil && *c.CpuCores > 0 {
+			// 専有 vCPU のプールは共有の vCPU に使わない
+			shared := *c.CpuCores
+			if p := status.Performance; p != nil && p.PinnedCpus != nil {
+				shared = max(shared-len(*p.PinnedCpus), 0)
+			}
+			n.CpuCores = int(float64(shared) * cpuOvercommitRatio)
+		}
+		if c.MemoryMB != nil && *c.MemoryMB > 0 {
+			n.MemoryMB = int(float64(*c.MemoryMB) * memoryOvercommitRatio)
+		}
+		n.OsVolumeGroupFreeGB = intOrUnknown(c.OsVolumeGroupFreeGB)
+		n.DataVolumeGroupFreeGB = intOrUnknown(c.DataVolumeGroupFreeGB)
+		n.VolumeStoreFreeGB = intOrUnknown(c.VolumeStoreFreeGB)
+	}
+	if a := status.Allocation; a != nil {
+		if a.AllocatedCpuCores != nil {
+			n.AllocatedCpuCores = *a.AllocatedCpuCores
+		}
+		if a.AllocatedMemoryMB != nil {
+			n.AllocatedMemoryMB = *a.AllocatedMemoryMB
+		}
+	}
+	n.NUMANodes = newNUMAResources(status.Performance)
+	return n
+}
+
+// newNUMAResources はホストが報告したプールと NUMA ノードから、ノード毎の空きを求める
+// NUMA ノードの情報が無い場合はプール全体を 1 つのノードとして扱う
+func newNUMAResources(p *api.HostPerformance) []NUMAResources {
+	if p == nil {
+		return nil
+	}
+	allocated := make(map[int]bool)
+	for _, c := range util.OrDefault(p.AllocatedPinnedCpus, nil) {
+		allocated[c] = true
+	}
+	freeIn := func(cpus []int, all bool) int {
+		inNode := make(map[int]bool, len(cpus))
+		for _, c := range cpus {
+			inNode[c] = true
+		}
+		free := 0
+		for _, c := range util.OrDefault(p.PinnedCpus, nil) {
+			if (all || inNode[c]) && !allocated[c] {
+				free++
+			}
+		}
+		return free
+	}
+
+	if p.NumaNodes == nil || len(*p.NumaNodes) == 0 {
+		return []NUMAResources{{FreePinnedCpus: freeIn(nil, true)}}
+	}
+	nodes := make([]NUMAResources, 0, len(*p.NumaNodes))
+	for _, nn := range *p.NumaNodes {
+		nodes = append(nodes, NUMAResources{
+			ID:              util.OrDefault(nn.Id, 0),
+			FreePinnedCpus:  freeIn(util.OrDefault(nn.Cpus, nil), false),
+			FreeHugepages2M: util.OrDefault(nn.Hugepages2MFree, 0),
+			FreeHugepages1G: util.OrDefault(nn.Hugepages1GFree, 0),
+		})
+	}
+	return nodes
+}
+
+func (r ServerResourceRequest) needsNUMAResources() bool {
+	return r.PinnedCpus > 0 || r.Hugepages2M > 0 || r.Hugepages1G > 0
+}
+
+// numaNodeFor は要求の専有 vCPU と hugepages が収まる NUMA ノードの添字を返す。無ければ -1
+func (n NodeResources) numaNodeFor(req ServerResourceRequest) int {
+	for i, nn := range n.NUMANodes {
+		if nn.FreePinnedCpus >= req.PinnedCpus && nn.FreeHugepages2M >= req.Hugepages2M && nn.FreeHugepages1G >= req.Hugepages1G {
+			return i
+		}
+	}
+	return -1
+}
+
+// numaFree はノード全体の専有 vCPU と hugepages の空きを返す
+func (n NodeResources) numaFree() (pinned, hp2M, hp1G int) {
+	for _, nn := range n.NUMANodes {
+		pinned += nn.FreePinnedCpus
+		hp2M += nn.FreeHugepages2M
+		hp1G += nn.FreeHugepages1G
+	}
+	return pinned, hp2M, hp1G
+}
+
+// numaViolations は専有 vCPU と hugepages が収まらない理由を返す
+func (n NodeResources) numaViolations(req ServerResourceRequest) []string {
+	if !req.needsNUMAResources() {
+		return nil
+	}
+	if req.SingleNUMANode {
+		if n.numaNodeFor(req) < 0 {
+			return []string{fmt.Sprintf("no NUMA node fits pinned cpus=%d hugepages 2M=%d 1G=%d", req.PinnedCpus, req.Hugepages2M, req.Hugepages1G)}
+		}
+		return nil
+	}
+	var reasons []string
+	pinned, hp2M, hp1G := n.numaFree()
+	if req.PinnedCpus > pinned {
+		reasons = append(reasons, fmt.Sprintf("pinned cpus requested=%d free=%d", req.PinnedCpus, pinned))
+	}
+	if req.Hugepages2M > hp2M {
+		reasons = append(reasons, fmt.Sprintf("hugepages 2M requested=%d free=%d", req.Hugepages2M, hp2M))
+	}
+	if req.Hugepages1G > hp1G {
+		reasons = append(reasons, fmt.Sprintf("hugepages 1G requested=%d free=%d", req.Hugepages1G, hp1G))
+	}
+	return reasons
+}
+
+// reserveNUMA は専有 vCPU と hugepages を 1 つの NUMA ノードに収まればそのノードから、収まらなければ順に差し引く
+func (n *NodeResources) reserveNUMA(req ServerResourceRequest) {
+	if i := n.numaNodeFor(req); i >= 0 {
+		n.NUMANodes[i].FreePinnedCpus -= req.PinnedCpus
+		n.NUMANodes[i].FreeHugepages2M -= req.Hugepages2M
+		n.NUMANodes[i].FreeHugepages1G -= req.Hugepages1G
+		return
+	}
+	pinned, hp2M, hp1G := req.PinnedCpus, req.Hugepages2M, req.Hugepages1G
+	for i := range n.NUMANodes {
+		nn := &n.NUMANodes[i]
+		take := func(free *int, want *int) {
+			d := min(max(*free, 0), *want)
+			*free -= d
+			*want -= d
+		}
+		take(&nn.FreePinnedCpus, &pinned)
+		take(&nn.FreeHugepages2M, &hp2M)
+		take(&nn.FreeHugepages1G, &hp1G)
+	}
+}
+
+func intOrUnknown(v *int) int {
+	if v == nil || *v < 0 {
+		return unknownCapacity
+	}
+	return *v
+}
+
+// Reserve は要求資源をノードに割当済みとして計上する
+func (n *NodeResources) Reserve(req ServerResourceRequest) {
+	n.AllocatedCpuCores += req.CpuCores
+	n.AllocatedMemoryMB += req.MemoryMB
+	if n.OsVolumeGroupFreeGB != unknownCapacity {
+		n.OsVolumeGroupFreeGB -= req.OsVolumeGroupGB
+	}
+	if n.DataVolumeGroupFreeGB != unknownCapacity {
+		n.DataVolumeGroupFreeGB -= req.DataVolumeGroupGB
+	}
+	if n.VolumeStoreFreeGB != unknownCapacity {
+		n.VolumeStoreFreeGB -= req.VolumeStoreGB
+	}
+	if req.needsNUMAResources() {
+		n.reserveNUMA(req)
+	}
+}
+
+// Fits は要求資源がノードに収まるかを判定し、収まらない場合はその理由を返す
+func (n NodeResources) Fits(req ServerResourceRequest) error {
+	var reasons []string
+	if n.CpuCores != unknownCapacity && n.AllocatedCpuCores+req.CpuCores > n.CpuCores {
+		reasons = append(reasons, fmt.Sprintf("cpu requested=%d free=%d", req.CpuCores, n.CpuCores-n.AllocatedCpuCores))
+	}
+	if n.MemoryMB != unknownCapacity && n.AllocatedMemoryMB+req.MemoryMB > n.MemoryMB {
+		reasons = append(reasons, fmt.Sprintf("memory requested=%dMB free=%dMB", req.MemoryMB, n.MemoryMB-n.AllocatedMemoryMB))
+	}
+	if req.OsVolumeGroupGB > 0 && n.OsVolumeGroupFreeGB != unknownCapacity && req.OsVolumeGroupGB > n.OsVolumeGroupFreeGB {
+		reasons = append(reasons, fmt.Sprintf("os volume group requested=%dGB free=%dGB", req.OsVolumeGroupGB, n.OsVolumeGroupFreeGB))
+	}
+	if req.DataVolumeGroupGB > 0 && n.DataVolumeGroupFreeGB != unknownCapacity && req.DataVolumeGroupGB > n.DataVolumeGroupFreeGB {
+		reasons = append(reasons, fmt.Sprintf("data volume group requested=%dGB free=%dGB", req.DataVolumeGroupGB, n.DataVolumeGroupFreeGB))
+	}
+	if req.VolumeStoreGB > 0 && n.VolumeStoreFreeGB != unknownCapacity && req.VolumeStoreGB > n.VolumeStoreFreeGB {
+		reasons = append(reasons, fmt.Sprintf("volume store requested=%dGB free=%dGB", req.VolumeStoreGB, n.VolumeStoreFreeGB))
+	}
+	reasons = append(reasons, n.numaViolations(req)...)
+	if len(reasons) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(reasons, ", "))
+}
+
+// score は配置後に残る CPU・メモリの空き率のうち小さい方を返す。
+// 値が大きいほど余裕があり、負荷が分散される。
+func (n NodeResources) score(req ServerResourceRequest) float64 {
+	score := 1.0
+	if n.CpuCores > 0 {
+		score = min(score, float64(n.CpuCores-n.AllocatedCpuCores-req.CpuCores)/float64(n.CpuCores))
+	}
+	if n.MemoryMB > 0 {
+		score = min(score, float64(n.MemoryMB-n.AllocatedMemoryMB-req.MemoryMB)/float64(n.MemoryMB))
+	}
+	return score
+}
+
+// SelectNodeForServer は配置ルールを満たし要求資源が収まるノードのうち、最適なノード名を返す。
+// 優先アフィニティの重み、配置後の空き率、TotalVMs の少なさ、NodeName 昇順の順で決定する。
+// 候補が無い場合は各ノードの不適合理由を含むエラーを返す。配置ルールに違反するノードがあれば
+// ErrPlacementUnsatisfiable、資源不足のみであれば ErrInsufficientCapacity となる。
+func SelectNodeForServer(nodes []NodeResources, server api.Server, req ServerResourceRequest) (string, error) {
+	if len(nodes) == 0 {
+		return "", ErrNoActiveHosts
+	}
+
+	type candidate struct {
+		node       NodeResources
+		preference int
+		score      float64
+	}
+	var candidates []candidate
+	var rejected []string
+	placementRejected := false
+	for _, n := range nodes {
+		if n.Unschedulable {
+			rejected = append(rejected, fmt.Sprintf("%s (node is cordoned)", n.NodeName))
+			continue
+		}
+		reasons := placementViolations(server, n, nodes)
+		if len(reasons) > 0 {
+			placementRejected = true
+		}
+		if err := n.Fits(req); err != nil {
+			reasons = append(reasons, err.Error())
+		}
+		if len(reasons) > 0 {
+			rejected = append(rejected, fmt.Sprintf("%s (%s)", n.NodeName, strings.Join(reasons, ", ")))
+			continue
+		}
+		candidates = append(candidates, candidate{node: n, preference: placementPreference(server, n), score: n.score(req)})
+	}
+	if len(candidates) == 0 {
+		sort.Strings(rejected)
+		cause := ErrInsufficientCapacity
+		if placementRejected {
+			cause = ErrPlacementUnsatisfiable
+		}
+		return "", fmt.Errorf("%w: %s", cause, strings.Join(rejected, "; "))
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].preference != candidates[j].preference {
+			return candidates[i].preference > candidates[j].preference
+		}
+		if candidates[i].score != candidates[j].score {
+			return candidates[i].score > candidates[j].score
+		}
+		if candidates[i].node.TotalVMs != candidates[j].node.TotalVMs {
+			return candidates[i].node.TotalVMs < candidates[j].node.TotalVMs
+		}
+		return candidates[i].node.NodeName < candidates[j].node.NodeName
+	})
+	return candidates[0].node.NodeName, nil
+}
@@ -0,0 +1,252 @@
+package marmotd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestServerResourceRequestOf(t *testing.T) {
+	t.Run("applies cpu and memory defaults", func(t *testing.T) {
+		req := ServerResourceRequestOf(api.Server{})
+		want := ServerResourceRequest{CpuCores: 2, MemoryMB: 2048}
+		if req != want {
+			t.Fatalf("ServerResourceRequestOf() = %+v, want %+v", req, want)
+		}
+	})
+
+	t.Run("counts boot and new storage volumes by backend", func(t *testing.T) {
+		storage := []api.Volume{
+			{Spec: api.VolSpec{Size: util.IntPtrInt(10)}},
+			{Spec: api.VolSpec{Type: util.StringPtr("lvm"), Size: util.IntPtrInt(20)}},
+			{Spec: api.VolSpec{Type: util.StringPtr("ceph"), Size: util.IntPtrInt(30)}},
+			{Metadata: api.Metadata{Name: "existing"}, Spec: api.VolSpec{Type: util.StringPtr("lvm"), Size: util.IntPtrInt(40)}},
+		}
+		server := api.Server{Spec: api.ServerSpec{
+			Cpu:        util.IntPtrInt(4),
+			Memory:     util.IntPtrInt(8192),
+			BootVolume: &api.Volume{Spec: api.VolSpec{Type: util.StringPtr("lvm")}},
+			Storage:    &storage,
+		}}
+		req := ServerResourceRequestOf(server)
+		want := ServerResourceRequest{CpuCores: 4, MemoryMB: 8192, OsVolumeGroupGB: 4, DataVolumeGroupGB: 20, VolumeStoreGB: 10}
+		if req != want {
+			t.Fatalf("ServerResourceRequestOf() = %+v, want %+v", req, want)
+		}
+	})
+
+	t.Run("counts sized qcow2 boot volume", func(t *testing.T) {
+		server := api.Server{Spec: api.ServerSpec{
+			BootVolume: &api.Volume{Spec: api.VolSpec{Type: util.StringPtr("qcow2"), Size: util.IntPtrInt(50)}},
+		}}
+		if req := ServerResourceRequestOf(server); req.VolumeStoreGB != 50 {
+			t.Fatalf("VolumeStoreGB = %d, want 50", req.VolumeStoreGB)
+		}
+	})
+}
+
+func nodeWithCapacity(name string, cpu, memoryMB, allocatedCpu, allocatedMemoryMB int) NodeResources {
+	return NewNodeResources(api.HostStatus{
+		NodeName: util.StringPtr(name),
+		Capacity: &api.HostCapacity{
+			CpuCores:              util.IntPtrInt(cpu),
+			MemoryMB:              util.IntPtrInt(memoryMB),
+			DataVolumeGroupFreeGB: util.IntPtrInt(100),
+		},
+		Allocation: &api.HostAllocation{
+			AllocatedCpuCores: util.IntPtrInt(allocatedCpu),
+			AllocatedMemoryMB: util.IntPtrInt(allocatedMemoryMB),
+		},
+	}, 1, 1)
+}
+
+func TestNodeResourcesFits(t *testing.T) {
+	node := nodeWithCapacity("hv1", 8, 16384, 6, 8192)
+
+	if err := node.Fits(ServerResourceRequest{CpuCores: 2, MemoryMB: 8192}); err != nil {
+		t.Fatalf("Fits() error = %v, want nil", err)
+	}
+	err := node.Fits(ServerResourceRequest{CpuCores: 4, MemoryMB: 2048, DataVolumeGroupGB: 200})
+	if err == nil {
+		t.Fatal("Fits() error = nil, want shortage")
+	}
+	for _, want := range []string{"cpu", "data volume group"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("Fits() error = %q, want it to mention %q", err, want)
+		}
+	}
+
+	// 容量が不明な資源は制約としない
+	unknown := NewNodeResources(api.HostStatus{NodeName: util.StringPtr("hv2")}, 1, 1)
+	if err := unknown.Fits(ServerResourceRequest{CpuCores: 64, MemoryMB: 1 << 20, VolumeStoreGB: 1000}); err != nil {
+		t.Fatalf("Fits() on unknown capacity error = %v, want nil", err)
+	}
+}
+
+func TestNewNodeResourcesAppliesOvercommit(t *testing.T) {
+	node := NewNodeResources(api.HostStatus{
+		NodeName: util.StringPtr("hv1"),
+		Capacity: &api.HostCapacity{CpuCores: util.IntPtrInt(4), MemoryMB: util.IntPtrInt(4096)},
+	}, 4, 1.5)
+	if node.CpuCores != 16 || node.MemoryMB != 6144 {
+		t.Fatalf("capacity = (%d, %d), want (16, 6144)", node.CpuCores, node.MemoryMB)
+	}
+}
+
+func TestSelectNodeForServer(t *testing.T) {
+	t.Run("prefers node with most free capacity after placement", func(t *testing.T) {
+		nodes := []NodeResources{
+			nodeWithCapacity("hv1", 8, 16384, 6, 4096),
+			nodeWithCapacity("hv2", 8, 16384, 2, 4096),
+		}
+		node, err := SelectNodeForServer(nodes, api.Server{}, ServerResourceRequest{CpuCores: 2, MemoryMB: 2048})
+		if err != nil {
+			t.Fatalf("SelectNodeForServer() error = %v", err)
+		}
+		if node != "hv2" {
+			t.Fatalf("SelectNodeForServer() = %q, want %q", node, "hv2")
+		}
+	})
+
+	t.Run("skips nodes that do not fit", func(t *testing.T) {
+		nodes := []NodeResources{
+			nodeWithCapacity("hv1", 32, 4096, 0, 0),
+			nodeWithCapacity("hv2", 4, 16384, 2, 0),
+		}
+		node, err := SelectNodeForServer(nodes, api.Server{}, ServerResourceRequest{CpuCores: 2, MemoryMB: 8192})
+		if err != nil {
+			t.Fatalf("SelectNodeForServer() error = %v", err)
+		}
+		if node != "hv2" {
+			t.Fatalf("SelectNodeForServer() = %q, want %q", node, "hv2")
+		}
+	})
+
+	t.Run("breaks ties by vm count and node name", func(t *testing.T) {
+		a := NewNodeResources(api.HostStatus{NodeName: util.StringPtr("hv-b")}, 1, 1)
+		b := NewNodeResources(api.HostStatus{NodeName: util.StringPtr("hv-a")}, 1, 1)
+		c := NewNodeResources(api.HostStatus{NodeName: util.StringPtr("hv-c")}, 1, 1)
+		c.TotalVMs = 3
+		node, err := SelectNodeForServer([]NodeResources{c, a, b}, api.Server{}, ServerResourceRequest{CpuCores: 2, MemoryMB: 2048})
+		if err != nil {
+			t.Fatalf("SelectNodeForServer() error = %v", err)
+		}
+		if node != "hv-a" {
+			t.Fatalf("SelectNodeForServer() = %q, want %q", node, "hv-a")
+		}
+	})
+
+	t.Run("reports insufficient capacity when nothing fits", func(t *testing.T) {
+		nodes := []NodeResources{nodeWithCapacity("hv1", 2, 2048, 2, 2048)}
+		_, err := SelectNodeForServer(nodes, api.Server{}, ServerResourceRequest{CpuCores: 2, MemoryMB: 2048})
+		if !errors.Is(err, ErrInsufficientCapacity) {
+			t.Fatalf("SelectNodeForServer() error = %v, want ErrInsufficientCapacity", err)
+		}
+		if !strings.Contains(err.Error(), "hv1") {
+			t.Fatalf("SelectNodeForServer() error = %q, want node name in message", err)
+		}
+	})
+
+	t.Run("skips cordoned nodes", func(t *testing.T) {
+		cordoned := nodeWithCapacity("hv1", 32, 65536, 0, 0)
+		cordoned.Unschedulable = true
+		nodes := []NodeResources{cordoned, nodeWithCapacity("hv2", 4, 8192, 2, 4096)}
+		node, err := SelectNodeForServer(nodes, api.Server{}, ServerResourceRequest{CpuCores: 2, MemoryMB: 2048})
+		if err != nil {
+			t.Fatalf("SelectNodeForServer() error = %v", err)
+		}
+		if node != "hv2" {
+			t.Fatalf("SelectNodeForServer() = %q, want %q", node, "hv2")
+		}
+
+		_, err = SelectNodeForServer([]NodeResources{cordoned}, api.Server{}, ServerResourceRequest{CpuCores: 2, MemoryMB: 2048})
+		if !errors.Is(err, ErrInsufficientCapacity) || !strings.Contains(err.Error(), "cordoned") {
+			t.Fatalf("SelectNodeForServer() error = %v, want cordoned node rejected", err)
+		}
+	})
+
+	t.Run("returns ErrNoActiveHosts without nodes", func(t *testing.T) {
+		if _, err := SelectNodeForServer(nil, api.Server{}, ServerResourceRequest{}); !errors.Is(err, ErrNoActiveHosts) {
+			t.Fatalf("SelectNodeForServer() error = %v, want ErrNoActiveHosts", err)
+		}
+	})
+
+	t.Run("reserve consumes capacity for subsequent placements", func(t *testing.T) {
+		node := nodeWithCapacity("hv1", 4, 4096, 0, 0)
+		req := ServerResourceRequest{CpuCores: 2, MemoryMB: 2048, DataVolumeGroupGB: 60}
+		node.Reserve(req)
+		if err := node.Fits(req); err == nil {
+			t.Fatal("Fits() after Reserve() error = nil, want data volume group shortage")
+		}
+	})
+}
+
+func TestServerResourceRequestOfPerformance(t *testing.T) {
+	server := api.Server{Spec: api.ServerSpec{
+		Cpu:    util.IntPtrInt(4),
+		Memory: util.IntPtrInt(8192),
+		Performance: &api.ServerPerformance{
+			DedicatedCpu:           util.BoolPtr(true),
+			Numa:                   util.BoolPtr(true),
+			IsolateEmulatorThreads: util.BoolPtr(true),
+			Hugepages:              util.StringPtr("1G"),
+		},
+	}}
+	req := ServerResourceRequestOf(server)
+	want := ServerResourceRequest{MemoryMB: 8192, PinnedCpus: 5, SingleNUMANode: true, Hugepages1G: 8}
+	if req != want {
+		t.Fatalf("ServerResourceRequestOf() = %+v, want %+v", req, want)
+	}
+}
+
+func TestNodeResourcesNUMA(t *testing.T) {
+	status := api.HostStatus{
+		NodeName: util.StringPtr("hv1"),
+		Capacity: &api.HostCapacity{CpuCores: util.IntPtrInt(16), MemoryMB: util.IntPtrInt(65536)},
+		Performance: &api.HostPerformance{
+			PinnedCpus:          &[]int{4, 5, 6, 7, 12, 13, 14, 15},
+			AllocatedPinnedCpus: &[]int{4, 5},
+			NumaNodes: &[]api.HostNumaNode{
+				numaNode(0, []int{0, 1, 2, 3, 4, 5, 6, 7}, 0, 2),
+				numaNode(1, []int{8, 9, 10, 11, 12, 13, 14, 15}, 1024, 0),
+			},
+		},
+	}
+	node := NewNodeResources(status, 1.0, 1.0)
+	if node.CpuCores != 8 {
+		t.Fatalf("CpuCores = %d, want 8 shared cores", node.CpuCores)
+	}
+	if len(node.NUMANodes) != 2 || node.NUMANodes[0].FreePinnedCpus != 2 || node.NUMANodes[1].FreePinnedCpus != 4 {
+		t.Fatalf("NUMANodes = %+v", node.NUMANodes)
+	}
+
+	single := ServerResourceRequest{MemoryMB: 2048, PinnedCpus: 3, SingleNUMANode: true, Hugepages1G: 2}
+	if err := node.Fits(single); err == nil || !strings.Contains(err.Error(), "NUMA") {
+		t.Fatalf("Fits() error = %v, want no NUMA node fits", err)
+	}
+	spanning := ServerResourceRequest{MemoryMB: 2048, PinnedCpus: 5}
+	if err := node.Fits(spanning); err != nil {
+		t.Fatalf("Fits() spanning error = %v", err)
+	}
+	node.Reserve(spanning)
+	if err := node.Fits(ServerResourceRequest{PinnedCpus: 2}); err == nil || !strings.Contains(err.Error(), "pinned cpus") {
+		t.Fatalf("Fits() after Reserve() error = %v, want pinned cpu shortage", err)
+	}
+
+	hugepages := ServerResourceRequest{MemoryMB: 2048, Hugepages2M: 1024}
+	if err := node.Fits(hugepages); err != nil {
+		t.Fatalf("Fits() hugepages error = %v", err)
+	}
+	node.Reserve(hugepages)
+	if node.NUMANodes[1].FreeHugepages2M != 0 {
+		t.Fatalf("FreeHugepages2M = %d, want 0", node.NUMANodes[1].FreeHugepages2M)
+	}
+
+	pool := NewNodeResources(api.HostStatus{Performance: &api.HostPerformance{PinnedCpus: &[]int{2, 3}}}, 1.0, 1.0)
+	if err := pool.Fits(ServerResourceRequest{PinnedCpus: 2, SingleNUMANode: true}); err != nil {
+		t.Fatalf("Fits() without NUMA info error = %v", err)
+	}
+}
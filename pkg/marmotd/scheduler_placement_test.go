@@ -0,0 +1,132 @@
+package marmotd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func labeledServer(id string, labels map[string]interface{}) api.Server {
+	return api.Server{Metadata: api.Metadata{Id: id, Name: id, Labels: &labels}}
+}
+
+func placementNode(name string, labels map[string]string, servers ...api.Server) NodeResources {
+	n := NewNodeResources(api.HostStatus{NodeName: util.StringPtr(name)}, 1, 1)
+	n.Labels = labels
+	n.Servers = servers
+	return n
+}
+
+func TestValidateServerPlacement(t *testing.T) {
+	valid := &api.ServerPlacement{
+		NodeSelector: &map[string]string{"zone": "a"},
+		AntiAffinity: &api.ServerAffinity{
+			Required:  &[]api.ServerAffinityTerm{{MatchLabels: map[string]string{"app": "etcd"}}},
+			Preferred: &[]api.ServerWeightedAffinityTerm{{Weight: 50, MatchLabels: map[string]string{"app": "db"}}},
+		},
+	}
+	if err := ValidateServerPlacement(valid); err != nil {
+		t.Fatalf("ValidateServerPlacement() error = %v, want nil", err)
+	}
+	if err := ValidateServerPlacement(nil); err != nil {
+		t.Fatalf("ValidateServerPlacement(nil) error = %v, want nil", err)
+	}
+
+	invalid := []*api.ServerPlacement{
+		{Affinity: &api.ServerAffinity{Required: &[]api.ServerAffinityTerm{{}}}},
+		{AntiAffinity: &api.ServerAffinity{Preferred: &[]api.ServerWeightedAffinityTerm{{Weight: 0, MatchLabels: map[string]string{"app": "x"}}}}},
+		{AntiAffinity: &api.ServerAffinity{Preferred: &[]api.ServerWeightedAffinityTerm{{Weight: 101, MatchLabels: map[string]string{"app": "x"}}}}},
+		{NodeSelector: &map[string]string{" ": "a"}},
+	}
+	for i, p := range invalid {
+		if err := ValidateServerPlacement(p); err == nil {
+			t.Fatalf("case %d: ValidateServerPlacement() error = nil, want error", i)
+		}
+	}
+}
+
+func TestSelectNodeForServerPlacement(t *testing.T) {
+	req := ServerResourceRequest{CpuCores: 1, MemoryMB: 1024}
+
+	t.Run("node selector filters nodes", func(t *testing.T) {
+		server := labeledServer("s1", nil)
+		server.Spec.Placement = &api.ServerPlacement{NodeSelector: &map[string]string{"gpu": "true"}}
+		nodes := []NodeResources{
+			placementNode("hv1", nil),
+			placementNode("hv2", map[string]string{"gpu": "true"}),
+		}
+		node, err := SelectNodeForServer(nodes, server, req)
+		if err != nil || node != "hv2" {
+			t.Fatalf("SelectNodeForServer() = (%q, %v), want (hv2, nil)", node, err)
+		}
+	})
+
+	t.Run("required anti-affinity spreads servers", func(t *testing.T) {
+		server := labeledServer("etcd-2", map[string]interface{}{"app": "etcd"})
+		server.Spec.Placement = &api.ServerPlacement{AntiAffinity: &api.ServerAffinity{
+			Required: &[]api.ServerAffinityTerm{{MatchLabels: map[string]string{"app": "etcd"}}},
+		}}
+		nodes := []NodeResources{
+			placementNode("hv1", nil, labeledServer("etcd-1", map[string]interface{}{"app": "etcd"})),
+			placementNode("hv2", nil, labeledServer("other", map[string]interface{}{"app": "web"}), labeledServer("other2", nil)),
+		}
+		node, err := SelectNodeForServer(nodes, server, req)
+		if err != nil || node != "hv2" {
+			t.Fatalf("SelectNodeForServer() = (%q, %v), want (hv2, nil)", node, err)
+		}
+	})
+
+	t.Run("anti-affinity of placed servers is symmetric", func(t *testing.T) {
+		placed := labeledServer("db-1", map[string]interface{}{"app": "db"})
+		placed.Spec.Placement = &api.ServerPlacement{AntiAffinity: &api.ServerAffinity{
+			Required: &[]api.ServerAffinityTerm{{MatchLabels: map[string]string{"app": "db"}}},
+		}}
+		nodes := []NodeResources{placementNode("hv1", nil, placed)}
+		_, err := SelectNodeForServer(nodes, labeledServer("db-2", map[string]interface{}{"app": "db"}), req)
+		if !errors.Is(err, ErrPlacementUnsatisfiable) {
+			t.Fatalf("SelectNodeForServer() error = %v, want ErrPlacementUnsatisfiable", err)
+		}
+		if !strings.Contains(err.Error(), "db-1") {
+			t.Fatalf("SelectNodeForServer() error = %q, want the conflicting server name", err)
+		}
+	})
+
+	t.Run("required affinity co-locates and allows the first member", func(t *testing.T) {
+		server := labeledServer("app-2", map[string]interface{}{"group": "g1"})
+		server.Spec.Placement = &api.ServerPlacement{Affinity: &api.ServerAffinity{
+			Required: &[]api.ServerAffinityTerm{{MatchLabels: map[string]string{"group": "g1"}}},
+		}}
+		nodes := []NodeResources{
+			placementNode("hv1", nil),
+			placementNode("hv2", nil, labeledServer("app-1", map[string]interface{}{"group": "g1"}), labeledServer("x", nil)),
+		}
+		node, err := SelectNodeForServer(nodes, server, req)
+		if err != nil || node != "hv2" {
+			t.Fatalf("SelectNodeForServer() = (%q, %v), want (hv2, nil)", node, err)
+		}
+
+		first := []NodeResources{placementNode("hv1", nil), placementNode("hv2", nil)}
+		if _, err := SelectNodeForServer(first, server, req); err != nil {
+			t.Fatalf("SelectNodeForServer() for first member error = %v, want nil", err)
+		}
+	})
+
+	t.Run("preferred terms outweigh load balancing", func(t *testing.T) {
+		server := labeledServer("web-2", nil)
+		server.Spec.Placement = &api.ServerPlacement{Affinity: &api.ServerAffinity{
+			Preferred: &[]api.ServerWeightedAffinityTerm{{Weight: 10, MatchLabels: map[string]string{"app": "cache"}}},
+		}}
+		nodes := []NodeResources{
+			placementNode("hv1", nil),
+			placementNode("hv2", nil, labeledServer("cache-1", map[string]interface{}{"app": "cache"})),
+		}
+		nodes[1].TotalVMs = 5
+		node, err := SelectNodeForServer(nodes, server, req)
+		if err != nil || node != "hv2" {
+			t.Fatalf("SelectNodeForServer() = (%q, %v), want (hv2, nil)", node, err)
+		}
+	})
+}
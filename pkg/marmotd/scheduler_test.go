@@ -33,6 +33,12 @@ func newHostStatusWithIscsi(nodeName, hostID string, updatedSecondsAgo int, iscs
 	return s
 }
 
+// テスト用ヘルパー: cordon された HostStatus を生成する
+func cordoned(s api.HostStatus) api.HostStatus {
+	s.Maintenance = &api.NodeMaintenance{NodeName: s.NodeName, Unschedulable: util.BoolPtr(true), State: util.StringPtr("CORDONED")}
+	return s
+}
+
 var _ = Describe("スケジューラー", func() {
 
 	Describe("IsSchedulerLeader", func() {
@@ -77,6 +83,25 @@ var _ = Describe("スケジューラー", func() {
 			})
 		})
 
+		Context("cordon されたホストがある場合", func() {
+			It("スケジュール可能なホストの中からリーダーを選ぶ", func() {
+				statuses := []api.HostStatus{
+					cordoned(newHostStatus("hv1", "00000010", 0, 5)),
+					newHostStatus("hv2", "00000020", 0, 5),
+				}
+				Expect(marmotd.IsSchedulerLeader("hv1", statuses)).To(BeFalse())
+				Expect(marmotd.IsSchedulerLeader("hv2", statuses)).To(BeTrue())
+			})
+
+			It("すべてのホストが cordon されていればアクティブなホストから選ぶ", func() {
+				statuses := []api.HostStatus{
+					cordoned(newHostStatus("hv1", "00000010", 0, 5)),
+					cordoned(newHostStatus("hv2", "00000020", 0, 5)),
+				}
+				Expect(marmotd.IsSchedulerLeader("hv1", statuses)).To(BeTrue())
+			})
+		})
+
 		Context("アクティブなホストが存在しない場合", func() {
 			It("false を返す", func() {
 				statuses := []api.HostStatus{
@@ -110,64 +135,6 @@ var _ = Describe("スケジューラー", func() {
 		})
 	})
 
-	Describe("SelectNode", func() {
-		Context("割り当て済みVM数(TotalVMs) が異なる複数のアクティブノードがある場合", func() {
-			It("TotalVMs が最小のノードを選択する", func() {
-				statuses := []api.HostStatus{
-					newHostStatus("hv1", "00000001", 5, 5),
-					newHostStatus("hv2", "00000002", 2, 5),
-					newHostStatus("hv3", "00000003", 8, 5),
-				}
-				node, err := marmotd.SelectNode(statuses)
-				Expect(err).NotTo(HaveOccurred())
-				Expect(node).To(Equal("hv2"))
-			})
-		})
-
-		Context("TotalVMs が同点の場合", func() {
-			It("NodeName 辞書順で先頭のノードを選択する（決定的）", func() {
-				statuses := []api.HostStatus{
-					newHostStatus("hv3", "00000003", 3, 5),
-					newHostStatus("hv1", "00000001", 3, 5),
-					newHostStatus("hv2", "00000002", 3, 5),
-				}
-				node, err := marmotd.SelectNode(statuses)
-				Expect(err).NotTo(HaveOccurred())
-				Expect(node).To(Equal("hv1"))
-			})
-		})
-
-		Context("Allocation が未設定のノードがある場合", func() {
-			It("TotalVMs = 0 として扱い選択する", func() {
-				t := time.Now().Add(-5 * time.Second)
-				statuses := []api.HostStatus{
-					{NodeName: util.StringPtr("hv1"), HostId: util.StringPtr("00000001"), LastUpdated: &t, Allocation: nil},
-					newHostStatus("hv2", "00000002", 3, 5),
-				}
-				node, err := marmotd.SelectNode(statuses)
-				Expect(err).NotTo(HaveOccurred())
-				Expect(node).To(Equal("hv1"))
-			})
-		})
-
-		Context("非アクティブなホストのみの場合", func() {
-			It("ErrNoActiveHosts を返す", func() {
-				statuses := []api.HostStatus{
-					newHostStatus("hv1", "00000001", 0, 60),
-				}
-				_, err := marmotd.SelectNode(statuses)
-				Expect(err).To(MatchError(marmotd.ErrNoActiveHosts))
-			})
-		})
-
-		Context("HostStatus が空の場合", func() {
-			It("ErrNoActiveHosts を返す", func() {
-				_, err := marmotd.SelectNode(nil)
-				Expect(err).To(MatchError(marmotd.ErrNoActiveHosts))
-			})
-		})
-	})
-
 	Describe("IsIscsiServer", func() {
 		Context("IscsiServer=true を持つアクティブなホストが存在する場合", func() {
 			It("IscsiServer=true のホストが true を返す", func() {
@@ -194,6 +161,19 @@ var _ = Describe("スケジューラー", func() {
 			})
 		})
 
+		Context("cordon されたホストがある場合", func() {
+			It("IscsiServer=true でも cordon されたホストは選出されずフォールバックする", func() {
+				statuses := []api.HostStatus{
+					cordoned(newHostStatusWithIscsi("hv1", "00000010", 5, true)),
+					newHostStatusWithIscsi("hv2", "00000020", 5, false),
+					newHostStatusWithIscsi("hv3", "00000030", 5, false),
+				}
+				Expect(marmotd.IsIscsiServer("hv1", statuses)).To(BeFalse())
+				Expect(marmotd.IsIscsiServer("hv2", statuses)).To(BeTrue())
+				Expect(marmotd.IsIscsiServer("hv3", statuses)).To(BeFalse())
+			})
+		})
+
 		Context("IscsiServer=true を持つホストが存在しない場合（フォールバック）", func() {
 			It("HostId が最小のホストが担当する", func() {
 				statuses := []api.HostStatus{
@@ -0,0 +1,174 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// サーバーの複製
+// API が複製元のボリュームを spec.source に持つサーバーを PENDING で登録し、サーバーコントローラーの
+// CreateServerManage がボリュームを複製して起動する。ボリュームの複製はボリュームコントローラーが実行する
+// MAC と IP アドレスは新しいサーバーとして割り当て直し、ホスト名と machine-id はブートボリュームの設定で、
+// cloud-init の instance-id はサーバーIDで新しくなる
+// ラベルと cloud-init の設定は引き継ぐ。ラベルは複製の要求で置き換えられる
+
+var (
+	ErrServerNotClonable         = errors.New("server cannot be cloned")
+	ErrServerCloneSourceNotReady = errors.New("clone source server is not RUNNING or STOPPED")
+)
+
+// cloneServerSpec は複製元のサーバーから複製先のサーバーの登録内容を作る
+// qcow2 と LVM のボリュームはノードに閉じているため、複製先は複製元と同じノードに割り当てる
+func cloneServerSpec(source api.Server, req api.ServerCloneRequest) (api.Server, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return api.Server{}, fmt.Errorf("%w: name is required", ErrServerNotClonable)
+	}
+	mode, err := normalizeVolumeCloneMode(req.Mode)
+	if err != nil {
+		return api.Server{}, err
+	}
+	if source.Spec.BootVolume == nil || strings.TrimSpace(api.VolumeID(*source.Spec.BootVolume)) == "" {
+		return api.Server{}, fmt.Errorf("%w: server %s has no boot volume", ErrServerCloneSourceNotReady, api.ServerID(source))
+	}
+	snapshotID := strings.TrimSpace(util.OrDefault(req.SnapshotId, ""))
+	volumeSource := func(vol api.Volume) *api.VolumeSource {
+		src := &api.VolumeSource{VolumeId: api.VolumeID(vol), Mode: util.StringPtr(mode)}
+		if snapshotID != "" {
+			src.SnapshotId = util.StringPtr(snapshotID)
+		}
+		return src
+	}
+
+	var clone api.Server
+	clone.ApiVersion = source.ApiVersion
+	clone.Kind = source.Kind
+	clone.Metadata.Name = name
+	clone.Metadata.Comment = req.Comment
+	clone.Metadata.NodeName = source.Metadata.NodeName
+	clone.Metadata.Project = source.Metadata.Project
+	// ロードバランサーの backendSelector に一致するラベルを引き継いだ複製先は、起動するとバックエンドに加わる。
+	// 加えない場合は、要求で別のラベルか空のラベルを指定する
+	if req.Labels != nil {
+		if len(*req.Labels) > 0 {
+			labels := make(map[string]interface{}, len(*req.Labels))
+			for key, value := range *req.Labels {
+				labels[key] = value
+			}
+			clone.Metadata.Labels = &labels
+		}
+	} else if source.Metadata.Labels != nil {
+		labels := maps.Clone(*source.Metadata.Labels)
+		clone.Metadata.Labels = &labels
+	}
+
+	clone.Spec.Cpu = source.Spec.Cpu
+	clone.Spec.Memory = source.Spec.Memory
+	clone.Spec.MmImage = source.Spec.MmImage
+	clone.Spec.OsVariant = source.Spec.OsVariant
+	clone.Spec.Auth = source.Spec.Auth
+	clone.Spec.Ansible = source.Spec.Ansible
+	// user-data と vendor-data は、新しい instance-id で複製先の初回の起動時に改めて実行される
+	clone.Spec.CloudInit = source.Spec.CloudInit
+	// NVRAM と TPM の状態は引き継がず、クローンで新しく作成する
+	clone.Spec.Firmware = source.Spec.Firmware
+	clone.Spec.Tpm = source.Spec.Tpm
+	// 専有 vCPU はクローンの作成時に新しく割り当てる
+	clone.Spec.Performance = source.Spec.Performance
+
+	boot := *source.Spec.BootVolume
+	clone.Spec.BootVolume = &api.Volume{
+		Spec: api.VolSpec{
+			Type:   boot.Spec.Type,
+			Kind:   util.StringPtr("os"),
+			Size:   boot.Spec.Size,
+			Source: volumeSource(boot),
+		},
+	}
+
+	if source.Spec.Storage != nil {
+		storage := make([]api.Volume, 0, len(*source.Spec.Storage))
+		for _, disk := range *source.Spec.Storage {
+			if strings.TrimSpace(api.VolumeID(disk)) == "" {
+				continue
+			}
+			var vol api.Volume
+			// サーバー固有のボリューム名は、複製先のサーバーIDで付け直される
+			vol.Metadata.Name = strings.TrimSuffix(strings.TrimSpace(disk.Metadata.Name), "-"+api.ServerID(source))
+			vol.Spec.Type = disk.Spec.Type
+			vol.Spec.Kind = disk.Spec.Kind
+			vol.Spec.Size = disk.Spec.Size
+			vol.Spec.StorageClass = disk.Spec.StorageClass
+			vol.Spec.Persistent = disk.Spec.Persistent
+			vol.Spec.Source = volumeSource(disk)
+			storage = append(storage, vol)
+		}
+		if len(storage) > 0 {
+			clone.Spec.Storage = &storage
+		}
+	}
+
+	// MAC と IP アドレスはプロビジョニングで新しく割り当てる
+	if source.Spec.NetworkInterface != nil {
+		nics := make([]api.NetworkInterface, 0, len(*source.Spec.NetworkInterface))
+		for _, nic := range *source.Spec.NetworkInterface {
+			nics = append(nics, api.NetworkInterface{
+				Networkname: nic.Networkname,
+				Portgroup:   nic.Portgroup,
+				Vlans:       nic.Vlans,
+				Dhcp4:       nic.Dhcp4,
+				Dhcp6:       nic.Dhcp6,
+				Routes:      nic.Routes,
+				Nameservers: nic.Nameservers,
+			})
+		}
+		clone.Spec.NetworkInterface = &nics
+	}
+	return clone, nil
+}
+
+// cloneVolumeRequests は複製先のサーバーが作成するボリュームの要求を返す
+func cloneVolumeRequests(clone api.Server) []api.Volume {
+	var vols []api.Volume
+	if clone.Spec.BootVolume != nil && clone.Spec.BootVolume.Spec.Source != nil {
+		vols = append(vols, *clone.Spec.BootVolume)
+	}
+	if clone.Spec.Storage != nil {
+		for _, disk := range *clone.Spec.Storage {
+			if disk.Spec.Source != nil {
+				vols = append(vols, disk)
+			}
+		}
+	}
+	return vols
+}
+
+// PrepareServerClone は複製元のサーバーを検証し、複製先のサーバーの登録内容を返す
+// 各ボリュームは PrepareVolumeClone で検証し、複製できないボリュームがあれば登録前にエラーにする
+// API ハンドラーから呼び出される
+func (m *Marmot) PrepareServerClone(sourceID string, req api.ServerCloneRequest) (api.Server, error) {
+	source, err := m.Db.GetServerById(sourceID)
+	if err != nil {
+		return api.Server{}, err
+	}
+	if source.Status == nil || (source.Status.StatusCode != db.SERVER_RUNNING && source.Status.StatusCode != db.SERVER_STOPPED) {
+		return api.Server{}, fmt.Errorf("%w: server %s", ErrServerCloneSourceNotReady, sourceID)
+	}
+	clone, err := cloneServerSpec(source, req)
+	if err != nil {
+		return api.Server{}, err
+	}
+	for _, vol := range cloneVolumeRequests(clone) {
+		vol.Metadata.NodeName = clone.Metadata.NodeName
+		if err := m.PrepareVolumeClone(&vol); err != nil {
+			return api.Server{}, err
+		}
+	}
+	return clone, nil
+}
@@ -0,0 +1,152 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+// サーバーのファームウェア (BIOS/UEFI/Secure Boot) と仮想 TPM
+// UEFI の NVRAM と TPM の状態はブートボリュームと同じディレクトリにサーバー毎に作成し、サーバーの削除で消す
+
+var ErrInvalidFirmware = errors.New("invalid firmware")
+
+// validateServerFirmwareSpec は spec.firmware の値を検証する
+func validateServerFirmwareSpec(spec api.ServerSpec) error {
+	if _, err := virt.NormalizeFirmware(util.OrDefault(spec.Firmware, "")); err != nil {
+		return fmt.Errorf("%w: spec.firmware: %v", ErrInvalidFirmware, err)
+	}
+	return nil
+}
+
+// validateImageFirmwareSpec は イメージの spec.firmware の値を検証する
+func validateImageFirmwareSpec(spec *api.ImageSpec) error {
+	if spec == nil {
+		return nil
+	}
+	if _, err := virt.NormalizeFirmware(util.OrDefault(spec.Firmware, "")); err != nil {
+		return fmt.Errorf("spec.firmware: %v", err)
+	}
+	return nil
+}
+
+// resolveServerFirmware はサーバーの指定とイメージが要求するファームウェアから、使うファームウェアを決める
+// サーバーの指定が無ければイメージの要求に従い、どちらも無ければ BIOS とする
+// Secure Boot を要求するイメージは uefi-secure、UEFI を要求するイメージは uefi か uefi-secure でだけ起動できる
+func resolveServerFirmware(serverFirmware, imageFirmware string) (string, error) {
+	server, err := virt.NormalizeFirmware(serverFirmware)
+	if err != nil {
+		return "", fmt.Errorf("%w: spec.firmware: %v", ErrInvalidFirmware, err)
+	}
+	image, err := virt.NormalizeFirmware(imageFirmware)
+	if err != nil {
+		return "", fmt.Errorf("%w: image firmware: %v", ErrInvalidFirmware, err)
+	}
+	if server == "" {
+		if image == "" {
+			return virt.FirmwareBIOS, nil
+		}
+		return image, nil
+	}
+
+	compatible := true
+	switch image {
+	case virt.FirmwareBIOS:
+		compatible = server == virt.FirmwareBIOS
+	case virt.FirmwareUEFI:
+		compatible = server == virt.FirmwareUEFI || server == virt.FirmwareUEFISecure
+	case virt.FirmwareUEFISecure:
+		compatible = server == virt.FirmwareUEFISecure
+	}
+	if !compatible {
+		return "", fmt.Errorf("%w: the image requires %s firmware but spec.firmware is %s", ErrInvalidFirmware, image, server)
+	}
+	return server, nil
+}
+
+// isUEFIFirmware は UEFI のファームウェアかを返す
+func isUEFIFirmware(firmware *string) bool {
+	fw, _ := virt.NormalizeFirmware(util.OrDefault(firmware, ""))
+	return fw == virt.FirmwareUEFI || fw == virt.FirmwareUEFISecure
+}
+
+// serverFirmwareStatePaths はサーバーの NVRAM と TPM の状態のパスを返す
+// qcow2 のブートボリュームは同じディレクトリに、LVM のブートボリュームは qcow2 のボリュームのディレクトリに置く
+func serverFirmwareStatePaths(bootVol api.Volume) (nvram string, tpmDir string) {
+	dir, base := qcow2VolumeDir, "boot-"+api.VolumeID(bootVol)
+	if util.OrDefault(bootVol.Spec.Type, "") == "qcow2" {
+		if path := strings.TrimSpace(util.OrDefault(bootVol.Spec.Path, "")); path != "" {
+			dir = filepath.Dir(path)
+			base = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+	}
+	return filepath.Join(dir, base+".nvram.fd"), filepath.Join(dir, base+".tpm")
+}
+
+// applyServerFirmware はファームウェアと TPM の設定を virt.ServerSpec に反映する
+// 決めたファームウェアはサーバーの spec.firmware に記録する
+func applyServerFirmware(virtSpec *virt.ServerSpec, server *api.Server, bootVol api.Volume, imageFirmware string) error {
+	firmware, err := resolveServerFirmware(util.OrDefault(server.Spec.Firmware, ""), imageFirmware)
+	if err != nil {
+		return err
+	}
+	server.Spec.Firmware = util.StringPtr(firmware)
+	virtSpec.Firmware = firmware
+
+	nvram, tpmDir := serverFirmwareStatePaths(bootVol)
+	if firmware != virt.FirmwareBIOS {
+		virtSpec.NVRAMPath = nvram
+	}
+	if server.Spec.Tpm != nil && *server.Spec.Tpm {
+		if err := os.MkdirAll(tpmDir, 0700); err != nil {
+			return err
+		}
+		virtSpec.TPM = true
+		virtSpec.TPMStateDir = tpmDir
+	}
+	return nil
+}
+
+// removeServerFirmwareState はサーバーの NVRAM と TPM の状態を削除する
+// ドメインの削除で libvirt が消している場合もあるため、存在しなければ何もしない
+func removeServerFirmwareState(bootVol *api.Volume) {
+	if bootVol == nil || strings.TrimSpace(api.VolumeID(*bootVol)) == "" {
+		return
+	}
+	nvram, tpmDir := serverFirmwareStatePaths(*bootVol)
+	if err := os.Remove(nvram); err != nil && !os.IsNotExist(err) {
+		slog.Warn("failed to remove NVRAM", "path", nvram, "err", err)
+	}
+	if err := os.RemoveAll(tpmDir); err != nil {
+		slog.Warn("failed to remove TPM state", "path", tpmDir, "err", err)
+	}
+}
+
+// checkServerFirmwareUnchanged は更新の要求が spec.firmware と spec.tpm を変更しないことを確認する
+// 指定が無い項目と、現在と同じ値の指定は許可する
+func checkServerFirmwareUnchanged(current, patch api.ServerSpec) error {
+	if patch.Firmware != nil {
+		next, err := virt.NormalizeFirmware(*patch.Firmware)
+		if err != nil {
+			return fmt.Errorf("%w: spec.firmware: %v", ErrInvalidFirmware, err)
+		}
+		cur, _ := virt.NormalizeFirmware(util.OrDefault(current.Firmware, ""))
+		if cur == "" {
+			cur = virt.FirmwareBIOS
+		}
+		if next != "" && next != cur {
+			return fmt.Errorf("%w: spec.firmware cannot be changed after the server is created", ErrInvalidFirmware)
+		}
+	}
+	if patch.Tpm != nil && *patch.Tpm != util.OrDefault(current.Tpm, false) {
+		return fmt.Errorf("%w: spec.tpm cannot be changed after the server is created", ErrInvalidFirmware)
+	}
+	return nil
+}
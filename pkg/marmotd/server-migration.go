@@ -0,0 +1,555 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/lvm"
+	"github.com/takara9/marmot/pkg/qcow"
+	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+// cloud-init ISO の配置先ディレクトリ
+const cloudInitISODir = "/var/lib/marmot/isos"
+
+// migrationDestURI は移行先ホストのアドレスから libvirtd の接続 URI を組み立てる
+func migrationDestURI(template, address string) string {
+	return fmt.Sprintf(template, address)
+}
+
+// migrationLocalVolumes はブロックマイグレーションでコピーするローカルボリュームを選ぶ
+// iSCSI と Ceph のボリュームは共有ストレージとして移行先からそのまま接続する
+func migrationLocalVolumes(vols []api.Volume) ([]api.ServerMigrationVolume, error) {
+	local := make([]api.ServerMigrationVolume, 0, len(vols))
+	for _, vol := range vols {
+		id := api.VolumeID(vol)
+		if vol.Spec.Iscsi != nil && *vol.Spec.Iscsi {
+			continue
+		}
+		volType := "qcow2"
+		if vol.Spec.Type != nil && strings.TrimSpace(*vol.Spec.Type) != "" {
+			volType = strings.TrimSpace(*vol.Spec.Type)
+		}
+		mv := api.ServerMigrationVolume{
+			VolumeId: id,
+			Type:     util.StringPtr(volType),
+			Kind:     util.StringPtr(volumeKindOrDefault(vol.Spec)),
+		}
+		switch volType {
+		case "ceph":
+			continue
+		case "qcow2":
+			if vol.Spec.Path == nil || strings.TrimSpace(*vol.Spec.Path) == "" {
+				return nil, fmt.Errorf("volume %s: qcow2 path is not set", id)
+			}
+			mv.Path = util.StringPtr(strings.TrimSpace(*vol.Spec.Path))
+		case "lvm":
+			if vol.Spec.VolumeGroup == nil || vol.Spec.LogicalVolume == nil {
+				return nil, fmt.Errorf("volume %s: volume group or logical volume is not set", id)
+			}
+			vg := strings.TrimSpace(*vol.Spec.VolumeGroup)
+			lv := strings.TrimSpace(*vol.Spec.LogicalVolume)
+			mv.VolumeGroup = util.StringPtr(vg)
+			mv.LogicalVolume = util.StringPtr(lv)
+			mv.Path = util.StringPtr(fmt.Sprintf("/dev/%s/%s", vg, lv))
+		default:
+			return nil, fmt.Errorf("volume %s: migration of %q volumes is not supported", id, volType)
+		}
+		local = append(local, mv)
+	}
+	return local, nil
+}
+
+// migrationResourceRequest は移行先ノードで必要となる資源量を返す
+// 共有ストレージは移行先の容量を消費しないため、コピーするローカルボリュームだけを計上する
+func migrationResourceRequest(server api.Server, vols []api.Volume) ServerResourceRequest {
+	base := ServerResourceRequestOf(server)
+	req := ServerResourceRequest{CpuCores: base.CpuCores, MemoryMB: base.MemoryMB}
+	local, err := migrationLocalVolumes(vols)
+	if err != nil {
+		return req
+	}
+	sizes := make(map[string]int, len(vols))
+	for _, vol := range vols {
+		if vol.Spec.Size != nil {
+			sizes[api.VolumeID(vol)] = *vol.Spec.Size
+		}
+	}
+	for _, mv := range local {
+		size := sizes[mv.VolumeId]
+		switch {
+		case *mv.Type == "qcow2":
+			req.VolumeStoreGB += size
+		case *mv.Kind == "os":
+			req.OsVolumeGroupGB += size
+		default:
+			req.DataVolumeGroupGB += size
+		}
+	}
+	return req
+}
+
+// migrationCandidateNodes は移行元を除くアクティブノードの資源状況を返す
+func migrationCandidateNodes(statuses []api.HostStatus, servers []api.Server, sourceNode string) []NodeResources {
+	cfg := CurrentConfig()
+	nodes := make([]NodeResources, 0, len(statuses))
+	for _, st := range filterActiveHosts(statuses) {
+		nr := NewNodeResources(st, cfg.SchedulerCPUOvercommitRatio, cfg.SchedulerMemoryOvercommitRatio)
+		if nr.NodeName == "" || nr.NodeName == sourceNode {
+			continue
+		}
+		for _, server := range servers {
+			if server.Metadata.NodeName == nil || strings.TrimSpace(*server.Metadata.NodeName) != nr.NodeName {
+				continue
+			}
+			if server.Status != nil && server.Status.StatusCode == db.SERVER_DELETING {
+				continue
+			}
+			nr.Servers = append(nr.Servers, server)
+		}
+		nodes = append(nodes, nr)
+	}
+	return nodes
+}
+
+// serverVolumes はサーバーのブートボリュームとデータボリュームのレコードを取得する
+func (m *Marmot) serverVolumes(server api.Server) ([]api.Volume, error) {
+	var vols []api.Volume
+	if server.Spec.BootVolume != nil && strings.TrimSpace(api.VolumeID(*server.Spec.BootVolume)) != "" {
+		vol, err := m.Db.GetVolumeById(api.VolumeID(*server.Spec.BootVolume))
+		if err != nil {
+			return nil, fmt.Errorf("boot volume %s: %w", api.VolumeID(*server.Spec.BootVolume), err)
+		}
+		vols = append(vols, vol)
+	}
+	if server.Spec.Storage != nil {
+		for _, disk := range *server.Spec.Storage {
+			if strings.TrimSpace(api.VolumeID(disk)) == "" {
+				continue
+			}
+			vol, err := m.Db.GetVolumeById(api.VolumeID(disk))
+			if err != nil {
+				return nil, fmt.Errorf("data volume %s: %w", api.VolumeID(disk), err)
+			}
+			vols = append(vols, vol)
+		}
+	}
+	return vols, nil
+}
+
+// PrepareServerMigration はマイグレーション要求を検証して移行先ノードを決め、etcd へ登録する内容を組み立てる
+// API ハンドラーから呼び出される
+func (m *Marmot) PrepareServerMigration(server api.Server, req api.ServerMigration) (api.ServerMigration, error) {
+	id := api.ServerID(server)
+	if server.Metadata.NodeName == nil || strings.TrimSpace(*server.Metadata.NodeName) == "" {
+		return api.ServerMigration{}, fmt.Errorf("server %s is not assigned to a node", id)
+	}
+	sourceNode := strings.TrimSpace(*server.Metadata.NodeName)
+
+	// NVRAM と TPM の状態は移行元ノードのファイルのため、UEFI と TPM のサーバーは移行できない
+	if isUEFIFirmware(server.Spec.Firmware) || util.OrDefault(server.Spec.Tpm, false) {
+		return api.ServerMigration{}, fmt.Errorf("server %s uses UEFI firmware or a virtual TPM; migration is not supported", id)
+	}
+	// 専有 vCPU は移行元ノードのプールから割り当てているため移行できない
+	if hasDedicatedCPU(server.Spec) {
+		return api.ServerMigration{}, fmt.Errorf("server %s uses dedicated CPUs; migration is not supported", id)
+	}
+
+	// スナップショットは移行元ノードのボリュームに残るため、移行前に削除してもらう
+	snaps, err := m.Db.GetServerSnapshotsByServerId(id)
+	if err != nil {
+		return api.ServerMigration{}, err
+	}
+	if len(snaps) > 0 {
+		return api.ServerMigration{}, fmt.Errorf("server %s has %d snapshot(s); delete them before migrating", id, len(snaps))
+	}
+
+	vols, err := m.serverVolumes(server)
+	if err != nil {
+		return api.ServerMigration{}, err
+	}
+	if _, err := migrationLocalVolumes(vols); err != nil {
+		return api.ServerMigration{}, err
+	}
+
+	statuses, err := m.Db.GetAllHostStatus()
+	if err != nil {
+		return api.ServerMigration{}, err
+	}
+	servers, err := m.Db.GetServers()
+	if err != nil {
+		return api.ServerMigration{}, err
+	}
+	nodes := migrationCandidateNodes(statuses, servers, sourceNode)
+
+	if req.Spec.TargetNode != nil && strings.TrimSpace(*req.Spec.TargetNode) != "" {
+		targetNode := strings.TrimSpace(*req.Spec.TargetNode)
+		if targetNode == sourceNode {
+			return api.ServerMigration{}, fmt.Errorf("server %s is already running on node %s", id, targetNode)
+		}
+		var selected []NodeResources
+		for _, n := range nodes {
+			if n.NodeName == targetNode {
+				selected = append(selected, n)
+			}
+		}
+		if len(selected) == 0 {
+			return api.ServerMigration{}, fmt.Errorf("target node %s is not an active node", targetNode)
+		}
+		nodes = selected
+	}
+
+	targetNode, err := SelectNodeForServer(nodes, server, migrationResourceRequest(server, vols))
+	if err != nil {
+		if errors.Is(err, ErrNoActiveHosts) {
+			return api.ServerMigration{}, errors.New("no other active node to migrate to")
+		}
+		return api.ServerMigration{}, err
+	}
+
+	req.ApiVersion = util.StringPtr("v1")
+	req.Kind = util.StringPtr("ServerMigration")
+	req.Metadata.Name = server.Metadata.Name
+	req.Metadata.NodeName = util.StringPtr(sourceNode)
+	req.Spec.ServerId = util.StringPtr(id)
+	req.Spec.SourceNode = util.StringPtr(sourceNode)
+	req.Spec.TargetNode = util.StringPtr(targetNode)
+	req.Spec.Volumes = nil
+	return req, nil
+}
+
+// migrationRequestError はマイグレーション要求の検証エラーを表す
+type migrationRequestError struct {
+	err error
+}
+
+func (e *migrationRequestError) Error() string { return e.err.Error() }
+func (e *migrationRequestError) Unwrap() error { return e.err }
+
+// StartServerMigration はマイグレーションを PENDING で登録し、サーバーを MIGRATING にする
+// API ハンドラーとノードのドレインから呼び出される
+func (m *Marmot) StartServerMigration(server api.Server, req api.ServerMigration) (api.ServerMigration, error) {
+	id := api.ServerID(server)
+	mig, err := m.PrepareServerMigration(server, req)
+	if err != nil {
+		if errors.Is(err, ErrInsufficientCapacity) || errors.Is(err, ErrPlacementUnsatisfiable) {
+			return api.ServerMigration{}, err
+		}
+		return api.ServerMigration{}, &migrationRequestError{err: err}
+	}
+
+	created, err := m.Db.CreateServerMigration(mig)
+	if err != nil {
+		return api.ServerMigration{}, err
+	}
+
+	msg := fmt.Sprintf("ノード %s へ移行中", *created.Spec.TargetNode)
+	if err := m.Db.UpdateServerStatus(id, db.SERVER_MIGRATING, msg); err != nil {
+		if dbErr := m.Db.UpdateServerMigrationStatusWithMessage(id, db.MIGRATION_FAILED, err.Error()); dbErr != nil {
+			slog.Error("UpdateServerMigrationStatusWithMessage()", "err", dbErr, "id", id)
+		}
+		return api.ServerMigration{}, err
+	}
+	return created, nil
+}
+
+// InspectServerMigrationManage は移行元ノードでコピー対象ディスクの仮想サイズを調べて記録する
+// コントローラーから呼び出される
+func (m *Marmot) InspectServerMigrationManage(serverId string) error {
+	slog.Debug("===InspectServerMigrationManage() is called===", "serverId", serverId)
+	server, err := m.Db.GetServerById(serverId)
+	if err != nil {
+		return err
+	}
+	if server.Metadata.InstanceName == nil {
+		return fmt.Errorf("server %s has no instance name", serverId)
+	}
+	vols, err := m.serverVolumes(server)
+	if err != nil {
+		return err
+	}
+	local, err := migrationLocalVolumes(vols)
+	if err != nil {
+		return err
+	}
+	for i := range local {
+		capacity, err := m.Virt.DomainDiskCapacity(*server.Metadata.InstanceName, *local[i].Path)
+		if err != nil {
+			return fmt.Errorf("volume %s: failed to get disk capacity: %w", local[i].VolumeId, err)
+		}
+		local[i].CapacityBytes = util.IntPtrInt64(int(capacity))
+	}
+
+	var update api.ServerMigration
+	update.Spec.Volumes = &local
+	return m.Db.UpdateServerMigration(serverId, update)
+}
+
+// createMigrationTargetVolume は移行先ノードにコピー先のディスクを作成する
+func createMigrationTargetVolume(mv api.ServerMigrationVolume) error {
+	if mv.CapacityBytes == nil || *mv.CapacityBytes <= 0 {
+		return fmt.Errorf("volume %s: capacity is unknown", mv.VolumeId)
+	}
+	size := uint64(*mv.CapacityBytes)
+	switch *mv.Type {
+	case "qcow2":
+		if _, err := os.Stat(*mv.Path); err == nil {
+			return fmt.Errorf("volume %s: %s already exists on the target node", mv.VolumeId, *mv.Path)
+		}
+		if err := os.MkdirAll(filepath.Dir(*mv.Path), 0755); err != nil {
+			return err
+		}
+		return qcow.CreateQcowBytes(*mv.Path, size)
+	case "lvm":
+		if err := lvm.IsExist(*mv.VolumeGroup, *mv.LogicalVolume); err == nil {
+			return fmt.Errorf("volume %s: %s already exists on the target node", mv.VolumeId, *mv.Path)
+		}
+		return lvm.CreateLV(*mv.VolumeGroup, *mv.LogicalVolume, size)
+	}
+	return fmt.Errorf("volume %s: migration of %q volumes is not supported", mv.VolumeId, *mv.Type)
+}
+
+// removeMigrationVolume はコピー先またはコピー元のディスクを削除する
+func removeMigrationVolume(mv api.ServerMigrationVolume) error {
+	switch *mv.Type {
+	case "qcow2":
+		if err := os.Remove(*mv.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	case "lvm":
+		if err := lvm.IsExist(*mv.VolumeGroup, *mv.LogicalVolume); err != nil {
+			return nil
+		}
+		return lvm.RemoveLV(*mv.VolumeGroup, *mv.LogicalVolume)
+	}
+	return nil
+}
+
+func migrationVolumes(mig api.ServerMigration) []api.ServerMigrationVolume {
+	if mig.Spec.Volumes == nil {
+		return nil
+	}
+	return *mig.Spec.Volumes
+}
+
+// PrepareServerMigrationTargetManage は移行先ノードでディスク、cloud-init ISO、ネットワーク、Ceph シークレットを準備する
+// 失敗した場合は作成したディスクを削除してからエラーを返す。コントローラーから呼び出される
+func (m *Marmot) PrepareServerMigrationTargetManage(serverId string) (err error) {
+	slog.Debug("===PrepareServerMigrationTargetManage() is called===", "serverId", serverId)
+	mig, err := m.Db.GetServerMigrationByServerId(serverId)
+	if err != nil {
+		return err
+	}
+	server, err := m.Db.GetServerById(serverId)
+	if err != nil {
+		return err
+	}
+
+	var created []api.ServerMigrationVolume
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, mv := range created {
+			if rmErr := removeMigrationVolume(mv); rmErr != nil {
+				slog.Error("removeMigrationVolume()", "volumeId", mv.VolumeId, "err", rmErr)
+			}
+		}
+	}()
+	for _, mv := range migrationVolumes(mig) {
+		if err := createMigrationTargetVolume(mv); err != nil {
+			return err
+		}
+		created = append(created, mv)
+	}
+
+	// cloud-init ISO は CD-ROM としてマウントされているため、移行先にも同じパスで用意する
+	if server.Spec.BootVolume != nil {
+		bootVol, err := m.Db.GetVolumeById(api.VolumeID(*server.Spec.BootVolume))
+		if err != nil {
+			return err
+		}
+		imageModule, err := resolveServerImageModule(m, bootVol)
+		if err != nil {
+			return err
+		}
+		password, sshKey, usernames, err := cloudInitAuthInputs(server.Spec.Auth)
+		if err != nil {
+			return err
+		}
+		customCloudInit, err := RenderServerCloudInit(server)
+		if err != nil {
+			return err
+		}
+		if _, err := imageModule.GenerateCloudInitISO(filepath.Join(cloudInitISODir, serverId), password, sshKey, usernames, server.Spec.Ansible, serverId, customCloudInit); err != nil {
+			return fmt.Errorf("failed to generate cloud-init ISO: %w", err)
+		}
+	}
+
+	if err := m.ensureServerNetworkDependencies(server); err != nil {
+		return err
+	}
+	if hasCephStorage(server.Spec.Storage) {
+		if err := prepareCephSecretForServer(m.Virt, serverId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CleanupServerMigrationTargetManage は失敗したマイグレーションのために移行先ノードで準備したディスクを削除する
+// コントローラーから呼び出される
+func (m *Marmot) CleanupServerMigrationTargetManage(serverId string) error {
+	slog.Debug("===CleanupServerMigrationTargetManage() is called===", "serverId", serverId)
+	mig, err := m.Db.GetServerMigrationByServerId(serverId)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, mv := range migrationVolumes(mig) {
+		if err := removeMigrationVolume(mv); err != nil {
+			errs = append(errs, fmt.Errorf("volume %s: %w", mv.VolumeId, err))
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(cloudInitISODir, serverId)); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+var (
+	// ErrServerMigrationInProgress はバックグラウンドのマイグレーションが実行中であることを表す
+	ErrServerMigrationInProgress = errors.New("server migration in progress")
+	// ErrServerMigrationNotRunning はこのプロセスでマイグレーションを開始していないことを表す
+	ErrServerMigrationNotRunning = errors.New("server migration is not running in this process")
+)
+
+// migrationRun はバックグラウンドで実行するマイグレーション1件の結果
+type migrationRun struct {
+	done     chan struct{}
+	migrated bool
+	err      error
+}
+
+// migrationRuns はバックグラウンドで実行中、または結果を受け取っていないマイグレーションをサーバーIDごとに保持する
+// ディスクのコピーが終わるまでコントローラーのワーカーを待たせないため、実行と完了の確認を分ける
+type migrationRuns struct {
+	mu   sync.Mutex
+	runs map[string]*migrationRun
+}
+
+// serverMigrations は marmotd のプロセス内で共有するマイグレーションの実行状態
+var serverMigrations = &migrationRuns{runs: map[string]*migrationRun{}}
+
+// start は id のマイグレーションとして run をバックグラウンドで実行する。実行中なら何もしない
+func (r *migrationRuns) start(id string, run func() (bool, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.runs[id]; ok {
+		return
+	}
+	mr := &migrationRun{done: make(chan struct{})}
+	r.runs[id] = mr
+	go func() {
+		defer close(mr.done)
+		mr.migrated, mr.err = run()
+	}()
+}
+
+// poll は id のマイグレーションの結果を返す。完了した結果は一度だけ返し、記録を消す
+func (r *migrationRuns) poll(id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mr, ok := r.runs[id]
+	if !ok {
+		return false, ErrServerMigrationNotRunning
+	}
+	select {
+	case <-mr.done:
+		delete(r.runs, id)
+		return mr.migrated, mr.err
+	default:
+		return false, ErrServerMigrationInProgress
+	}
+}
+
+// StartServerMigrationManage は移行元ノードでライブマイグレーションをバックグラウンドで開始する
+// 結果は PollServerMigrationManage で受け取る。コントローラーから呼び出される
+func (m *Marmot) StartServerMigrationManage(serverId string) {
+	slog.Debug("===StartServerMigrationManage() is called===", "serverId", serverId)
+	serverMigrations.start(serverId, func() (bool, error) {
+		return m.runServerMigration(serverId)
+	})
+}
+
+// PollServerMigrationManage はバックグラウンドのマイグレーションの結果を返す
+// 実行中は ErrServerMigrationInProgress、このプロセスで開始していなければ ErrServerMigrationNotRunning を返す
+// migrated はドメインが移行先へ移ったかを示し、true でエラーの場合は記録の更新に失敗している。
+// コントローラーから呼び出される
+func (m *Marmot) PollServerMigrationManage(serverId string) (migrated bool, err error) {
+	return serverMigrations.poll(serverId)
+}
+
+// runServerMigration は移行元ノードでライブマイグレーションを実行し、完了を etcd に記録する
+func (m *Marmot) runServerMigration(serverId string) (migrated bool, err error) {
+	slog.Debug("===runServerMigration() is called===", "serverId", serverId)
+	mig, err := m.Db.GetServerMigrationByServerId(serverId)
+	if err != nil {
+		return false, err
+	}
+	server, err := m.Db.GetServerById(serverId)
+	if err != nil {
+		return false, err
+	}
+	if server.Metadata.InstanceName == nil {
+		return false, fmt.Errorf("server %s has no instance name", serverId)
+	}
+	if mig.Spec.TargetNode == nil {
+		return false, fmt.Errorf("migration of server %s has no target node", serverId)
+	}
+	targetStatus, err := m.Db.GetHostStatus(*mig.Spec.TargetNode)
+	if err != nil {
+		return false, err
+	}
+	if targetStatus.IpAddress == nil || strings.TrimSpace(*targetStatus.IpAddress) == "" {
+		return false, fmt.Errorf("target node %s has no ip address", *mig.Spec.TargetNode)
+	}
+
+	spec := virt.MigrateSpec{
+		DestURI: migrationDestURI(CurrentConfig().MigrationURITemplate, strings.TrimSpace(*targetStatus.IpAddress)),
+	}
+	if targetStatus.InitiatorId != nil {
+		spec.ISCSIInitiator = strings.TrimSpace(*targetStatus.InitiatorId)
+	}
+	spec.SharedCPUSet = sharedCPUSetOf(targetStatus)
+	volumes := migrationVolumes(mig)
+	for _, mv := range volumes {
+		spec.CopyStoragePaths = append(spec.CopyStoragePaths, *mv.Path)
+	}
+
+	if err := m.Virt.MigrateDomain(*server.Metadata.InstanceName, spec); err != nil {
+		return false, err
+	}
+	if err := m.Db.CommitServerMigration(serverId); err != nil {
+		return true, err
+	}
+
+	// 移行元に残ったローカルディスクと cloud-init ISO を削除する
+	for _, mv := range volumes {
+		if err := removeMigrationVolume(mv); err != nil {
+			slog.Error("removeMigrationVolume()", "serverId", serverId, "volumeId", mv.VolumeId, "err", err)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(cloudInitISODir, serverId)); err != nil {
+		slog.Error("failed to remove cloud-init ISO", "serverId", serverId, "err", err)
+	}
+	return true, nil
+}
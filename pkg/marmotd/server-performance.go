@@ -0,0 +1,446 @@
+package marmotd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+// 性能を重視するサーバーの CPU のピン留め、NUMA の配置と hugepages
+// 専有 vCPU はノードの pinned_cpus のプールから、サーバーのノードで割り当てて status.performance に記録する
+
+var (
+	ErrInvalidPerformance     = errors.New("invalid performance settings")
+	ErrInsufficientPinnedCPUs = errors.New("insufficient pinned cpus")
+)
+
+// sysfs のルート。テストで置き換える
+var sysfsRoot = "/sys"
+
+// 専有 vCPU の割り当てを直列化する。割り当てはサーバーのノードの marmotd だけが行う
+var pinnedCPUAssignMu sync.Mutex
+
+// hugepageSizeKiB は spec.performance.hugepages のページサイズを KiB で返す。空は 0
+func hugepageSizeKiB(size string) (uint, error) {
+	switch strings.ToUpper(strings.TrimSpace(size)) {
+	case "":
+		return 0, nil
+	case "2M":
+		return virt.HugepageSize2M, nil
+	case "1G":
+		return virt.HugepageSize1G, nil
+	}
+	return 0, fmt.Errorf("unsupported hugepages %q: must be 2M or 1G", size)
+}
+
+func hasDedicatedCPU(spec api.ServerSpec) bool {
+	return spec.Performance != nil && util.OrDefault(spec.Performance.DedicatedCpu, false)
+}
+
+func isolatesEmulatorThreads(spec api.ServerSpec) bool {
+	return hasDedicatedCPU(spec) && util.OrDefault(spec.Performance.IsolateEmulatorThreads, false)
+}
+
+func requiresSingleNUMANode(spec api.ServerSpec) bool {
+	return hasDedicatedCPU(spec) && util.OrDefault(spec.Performance.Numa, false)
+}
+
+// pinnedCPUCount はサーバーが専有するホスト CPU の数を返す。エミュレータースレッドを分離する場合は 1 つ多い
+func pinnedCPUCount(spec api.ServerSpec) int {
+	if !hasDedicatedCPU(spec) {
+		return 0
+	}
+	n := defaultServerCpuCores
+	if spec.Cpu != nil && *spec.Cpu > 0 {
+		n = *spec.Cpu
+	}
+	if isolatesEmulatorThreads(spec) {
+		n++
+	}
+	return n
+}
+
+// serverHugepages はサーバーのメモリに使う hugepages のページサイズとページ数を返す
+func serverHugepages(spec api.ServerSpec) (uint, int) {
+	if spec.Performance == nil {
+		return 0, 0
+	}
+	size, err := hugepageSizeKiB(util.OrDefault(spec.Performance.Hugepages, ""))
+	if err != nil || size == 0 {
+		return 0, 0
+	}
+	memoryMB := defaultServerMemoryMB
+	if spec.Memory != nil && *spec.Memory > 0 {
+		memoryMB = *spec.Memory
+	}
+	return size, int(uint(memoryMB) * 1024 / size)
+}
+
+// validateServerPerformanceSpec は spec.performance の値を検証する
+func validateServerPerformanceSpec(spec api.ServerSpec) error {
+	perf := spec.Performance
+	if perf == nil {
+		return nil
+	}
+	size, err := hugepageSizeKiB(util.OrDefault(perf.Hugepages, ""))
+	if err != nil {
+		return fmt.Errorf("%w: spec.performance.hugepages: %v", ErrInvalidPerformance, err)
+	}
+	if size > 0 {
+		memoryMB := defaultServerMemoryMB
+		if spec.Memory != nil && *spec.Memory > 0 {
+			memoryMB = *spec.Memory
+		}
+		if uint(memoryMB)*1024%size != 0 {
+			return fmt.Errorf("%w: spec.memory (%dMB) must be a multiple of the hugepage size %s", ErrInvalidPerformance, memoryMB, strings.TrimSpace(*perf.Hugepages))
+		}
+	}
+	if !hasDedicatedCPU(spec) && (util.OrDefault(perf.Numa, false) || util.OrDefault(perf.IsolateEmulatorThreads, false)) {
+		return fmt.Errorf("%w: spec.performance.numa and isolateEmulatorThreads require dedicatedCpu", ErrInvalidPerformance)
+	}
+	return nil
+}
+
+type performanceSettings struct {
+	dedicatedCPU, numa, isolateEmulatorThreads bool
+	hugepages                                  string
+}
+
+func performanceSettingsOf(perf *api.ServerPerformance) performanceSettings {
+	if perf == nil {
+		return performanceSettings{}
+	}
+	return performanceSettings{
+		dedicatedCPU:           util.OrDefault(perf.DedicatedCpu, false),
+		numa:                   util.OrDefault(perf.Numa, false),
+		isolateEmulatorThreads: util.OrDefault(perf.IsolateEmulatorThreads, false),
+		hugepages:              strings.ToUpper(strings.TrimSpace(util.OrDefault(perf.Hugepages, ""))),
+	}
+}
+
+// checkServerPerformanceUnchanged は更新の要求が spec.performance と、専有 vCPU のサーバーの spec.cpu を変更しないことを確認する
+// 指定が無い項目と、現在と同じ値の指定は許可する
+func checkServerPerformanceUnchanged(current, patch api.ServerSpec) error {
+	if patch.Performance != nil && performanceSettingsOf(patch.Performance) != performanceSettingsOf(current.Performance) {
+		return fmt.Errorf("%w: spec.performance cannot be changed after the server is created", ErrInvalidPerformance)
+	}
+	if hasDedicatedCPU(current) && patch.Cpu != nil && *patch.Cpu != util.OrDefault(current.Cpu, defaultServerCpuCores) {
+		return fmt.Errorf("%w: spec.cpu of a server with dedicated CPUs cannot be changed", ErrInvalidPerformance)
+	}
+	return nil
+}
+
+// pinnedCPUPool は設定ファイルの pinned_cpus を返す
+func pinnedCPUPool() []int {
+	pool, err := util.ParseCPUSet(CurrentConfig().PinnedCPUs)
+	if err != nil {
+		slog.Warn("invalid pinned_cpus", "pinnedCpus", CurrentConfig().PinnedCPUs, "err", err)
+		return nil
+	}
+	return pool
+}
+
+// sharedCPUs はホストの CPU のうち、プールに含まれないものを返す
+func sharedCPUs(cpuCount int, pool []int) []int {
+	pinned := make(map[int]bool, len(pool))
+	for _, c := range pool {
+		pinned[c] = true
+	}
+	shared := make([]int, 0, cpuCount)
+	for c := 0; c < cpuCount; c++ {
+		if !pinned[c] {
+			shared = append(shared, c)
+		}
+	}
+	return shared
+}
+
+// sharedCPUSetOf はホストの状態からピン留めしない vCPU を動かす CPU を返す。プールが無ければ空
+func sharedCPUSetOf(status api.HostStatus) string {
+	if status.Performance == nil || status.Performance.PinnedCpus == nil || len(*status.Performance.PinnedCpus) == 0 {
+		return ""
+	}
+	if status.Capacity == nil || status.Capacity.CpuCores == nil {
+		return ""
+	}
+	return util.FormatCPUSet(sharedCPUs(*status.Capacity.CpuCores, *status.Performance.PinnedCpus))
+}
+
+// hostNUMANodes は sysfs からホストの NUMA ノードの CPU、メモリと hugepages を取得する
+func hostNUMANodes(root string) ([]api.HostNumaNode, error) {
+	dirs, err := filepath.Glob(filepath.Join(root, "devices", "system", "node", "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]api.HostNumaNode, 0, len(dirs))
+	for _, dir := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(dir), "node"))
+		if err != nil {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		cpus, err := util.ParseCPUSet(strings.TrimSpace(string(raw)))
+		if err != nil {
+			return nil, fmt.Errorf("node%d cpulist: %w", id, err)
+		}
+		hp2M := filepath.Join(dir, "hugepages", "hugepages-2048kB")
+		hp1G := filepath.Join(dir, "hugepages", "hugepages-1048576kB")
+		nodes = append(nodes, api.HostNumaNode{
+			Id:               util.IntPtrInt(id),
+			Cpus:             &cpus,
+			MemoryMB:         util.IntPtrInt(nodeMemTotalMB(filepath.Join(dir, "meminfo"))),
+			Hugepages2MTotal: util.IntPtrInt(readSysfsInt(filepath.Join(hp2M, "nr_hugepages"))),
+			Hugepages2MFree:  util.IntPtrInt(readSysfsInt(filepath.Join(hp2M, "free_hugepages"))),
+			Hugepages1GTotal: util.IntPtrInt(readSysfsInt(filepath.Join(hp1G, "nr_hugepages"))),
+			Hugepages1GFree:  util.IntPtrInt(readSysfsInt(filepath.Join(hp1G, "free_hugepages"))),
+		})
+	}
+	sort.Slice(nodes, func(i, j int) bool { return *nodes[i].Id < *nodes[j].Id })
+	return nodes, nil
+}
+
+// readSysfsInt は sysfs の数値を読む。読めない場合は 0
+func readSysfsInt(path string) int {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+// nodeMemTotalMB は NUMA ノードの meminfo の MemTotal を MB で返す
+// 形式: "Node 0 MemTotal:       16318928 kB"
+func nodeMemTotalMB(path string) int {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 4 && fields[2] == "MemTotal:" {
+			kb, err := strconv.Atoi(fields[3])
+			if err != nil {
+				return 0
+			}
+			return kb / 1024
+		}
+	}
+	return 0
+}
+
+// freeHugepages は NUMA ノードの空き hugepages の数を返す
+func freeHugepages(node api.HostNumaNode, sizeKiB uint) int {
+	switch sizeKiB {
+	case virt.HugepageSize2M:
+		return util.OrDefault(node.Hugepages2MFree, 0)
+	case virt.HugepageSize1G:
+		return util.OrDefault(node.Hugepages1GFree, 0)
+	}
+	return 0
+}
+
+// allocatedPinnedCPUs はノードのサーバーに割り当て済みのホスト CPU を返す。excludeID のサーバーは除く
+func allocatedPinnedCPUs(servers []api.Server, nodeName, excludeID string) map[int]bool {
+	used := make(map[int]bool)
+	for _, sv := range servers {
+		if sv.Status == nil || sv.Status.Performance == nil || api.ServerID(sv) == excludeID {
+			continue
+		}
+		if sv.Metadata.NodeName == nil || strings.TrimSpace(*sv.Metadata.NodeName) != nodeName {
+			continue
+		}
+		for _, c := range util.OrDefault(sv.Status.Performance.VcpuPins, nil) {
+			used[c] = true
+		}
+		for _, c := range util.OrDefault(sv.Status.Performance.EmulatorCpus, nil) {
+			used[c] = true
+		}
+	}
+	return used
+}
+
+// pinnedCPUAssignment はサーバーに割り当てたホスト CPU
+type pinnedCPUAssignment struct {
+	VCPUs    []int
+	Emulator []int
+	NUMANode *int
+}
+
+// assignPinnedCPUs はプールの空き CPU から vCPU とエミュレータースレッドの CPU を選ぶ
+// 1 つの NUMA ノードに収まる場合は、収まるノードのうち空きが最も少ないノードから選ぶ
+// singleNode の場合は 1 つのノードに収まらなければエラーとし、ノードの hugepages の空きも確認する
+func assignPinnedCPUs(pool []int, used map[int]bool, nodes []api.HostNumaNode, vcpus int, isolate, singleNode bool, hugepageKiB uint, pages int) (pinnedCPUAssignment, error) {
+	count := vcpus
+	if isolate {
+		count++
+	}
+	var free []int
+	for _, c := range pool {
+		if !used[c] {
+			free = append(free, c)
+		}
+	}
+	sort.Ints(free)
+
+	var chosen []int
+	var chosenNode *int
+	for _, n := range nodes {
+		inNode := make(map[int]bool)
+		for _, c := range util.OrDefault(n.Cpus, nil) {
+			inNode[c] = true
+		}
+		var nodeFree []int
+		for _, c := range free {
+			if inNode[c] {
+				nodeFree = append(nodeFree, c)
+			}
+		}
+		if len(nodeFree) < count {
+			continue
+		}
+		if singleNode && pages > 0 && freeHugepages(n, hugepageKiB) < pages {
+			continue
+		}
+		if chosen == nil || len(nodeFree) < len(chosen) {
+			chosen = nodeFree
+			chosenNode = util.IntPtrInt(util.OrDefault(n.Id, 0))
+		}
+	}
+
+	switch {
+	case chosen != nil:
+		chosen = chosen[:count]
+	case singleNode:
+		return pinnedCPUAssignment{}, fmt.Errorf("%w: no NUMA node has %d free pinned cpus and %d free hugepages", ErrInsufficientPinnedCPUs, count, pages)
+	case len(free) < count:
+		return pinnedCPUAssignment{}, fmt.Errorf("%w: requested=%d free=%d", ErrInsufficientPinnedCPUs, count, len(free))
+	default:
+		chosen = free[:count]
+	}
+
+	a := pinnedCPUAssignment{VCPUs: append([]int(nil), chosen[:vcpus]...)}
+	if isolate {
+		a.Emulator = append([]int(nil), chosen[vcpus:]...)
+	}
+	if singleNode {
+		a.NUMANode = chosenNode
+	}
+	return a, nil
+}
+
+// applyServerPerformance は CPU のピン留め、NUMA と hugepages の設定を virt.ServerSpec に反映する
+// 専有 vCPU はプールから割り当ててサーバーの status.performance に記録する。記録済みの場合はそれを使う
+func (m *Marmot) applyServerPerformance(virtSpec *virt.ServerSpec, server *api.Server) error {
+	pool := pinnedCPUPool()
+	shared := ""
+	if len(pool) > 0 {
+		shared = util.FormatCPUSet(sharedCPUs(runtime.NumCPU(), pool))
+	}
+	virtSpec.Performance.SharedCPUSet = shared
+	hugepageKiB, pages := serverHugepages(server.Spec)
+	virtSpec.Performance.HugepageKiB = hugepageKiB
+	if !hasDedicatedCPU(server.Spec) {
+		return nil
+	}
+
+	vcpus := int(virtSpec.CountVCPU)
+	singleNode := requiresSingleNUMANode(server.Spec)
+
+	pinnedCPUAssignMu.Lock()
+	defer pinnedCPUAssignMu.Unlock()
+
+	var st *api.ServerPerformanceStatus
+	if server.Status != nil && server.Status.Performance != nil && len(util.OrDefault(server.Status.Performance.VcpuPins, nil)) == vcpus {
+		st = server.Status.Performance
+	} else {
+		servers, err := m.Db.GetServers()
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+		nodes, err := hostNUMANodes(sysfsRoot)
+		if err != nil {
+			slog.Warn("hostNUMANodes()", "err", err)
+		}
+		id := api.ServerID(*server)
+		a, err := assignPinnedCPUs(pool, allocatedPinnedCPUs(servers, strings.TrimSpace(m.NodeName), id), nodes, vcpus, isolatesEmulatorThreads(server.Spec), singleNode, hugepageKiB, pages)
+		if err != nil {
+			return err
+		}
+		st = &api.ServerPerformanceStatus{VcpuPins: &a.VCPUs, NumaNode: a.NUMANode}
+		if len(a.Emulator) > 0 {
+			st.EmulatorCpus = &a.Emulator
+		}
+		if err := m.Db.UpdateServerPerformance(id, st); err != nil {
+			return err
+		}
+		if server.Status == nil {
+			server.Status = &api.Status{}
+		}
+		server.Status.Performance = st
+		slog.Info("pinned cpus assigned", "serverId", id, "vcpus", util.FormatCPUSet(a.VCPUs), "emulator", util.FormatCPUSet(a.Emulator))
+	}
+
+	virtSpec.Performance.VCPUPins = append([]int(nil), util.OrDefault(st.VcpuPins, nil)...)
+	virtSpec.Performance.EmulatorCPUSet = shared
+	if emulator := util.OrDefault(st.EmulatorCpus, nil); len(emulator) > 0 {
+		virtSpec.Performance.EmulatorCPUSet = util.FormatCPUSet(emulator)
+	}
+	if singleNode {
+		virtSpec.Performance.NUMANode = st.NumaNode
+	}
+	return nil
+}
+
+// collectHostPerformance は専有 vCPU のプール、割当済みの CPU と NUMA ノードの情報を返す
+func (m *Marmot) collectHostPerformance() (*api.HostPerformance, error) {
+	pool := pinnedCPUPool()
+	nodes, err := hostNUMANodes(sysfsRoot)
+	if err != nil {
+		slog.Debug("hostNUMANodes()", "err", err)
+	}
+	if len(pool) == 0 && len(nodes) == 0 {
+		return nil, nil
+	}
+
+	servers, err := m.Db.GetServers()
+	if err != nil && err != db.ErrNotFound {
+		slog.Error("GetServers()", "err", err)
+		return nil, err
+	}
+	perf := &api.HostPerformance{PinnedCpus: &pool}
+	used := allocatedPinnedCPUs(servers, strings.TrimSpace(m.NodeName), "")
+	allocated := make([]int, 0, len(used))
+	for c := range used {
+		allocated = append(allocated, c)
+	}
+	sort.Ints(allocated)
+	perf.AllocatedPinnedCpus = &allocated
+	if len(nodes) > 0 {
+		perf.NumaNodes = &nodes
+	}
+	return perf, nil
+}
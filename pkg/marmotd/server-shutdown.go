@@ -0,0 +1,80 @@
+package marmotd
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+// ErrServerShutdownInProgress はゲストOSのシャットダウンを要求済みで、停止を待っていることを表す
+var ErrServerShutdownInProgress = errors.New("server shutdown in progress")
+
+// shutdownDeadlines はシャットダウンを要求したサーバーの強制停止の期限をサーバーIDごとに保持する
+// コントローラーのワーカーを停止の完了まで待たせないため、要求と確認を別の調整で行う
+type shutdownDeadlines struct {
+	mu        sync.Mutex
+	deadlines map[string]time.Time
+}
+
+// serverShutdowns は marmotd のプロセス内で共有するシャットダウンの期限
+// marmotd が再起動した場合は、次の調整でシャットダウンを要求し直す
+var serverShutdowns = &shutdownDeadlines{deadlines: map[string]time.Time{}}
+
+// check は id のシャットダウンを要求済みか、要求済みなら now が期限を過ぎたかを返す
+func (s *shutdownDeadlines) check(id string, now time.Time) (requested, expired bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deadline, ok := s.deadlines[id]
+	if !ok {
+		return false, false
+	}
+	return true, !now.Before(deadline)
+}
+
+// start は id のシャットダウンを要求したことと、強制停止の期限を記録する
+func (s *shutdownDeadlines) start(id string, deadline time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadlines[id] = deadline
+}
+
+// clear は id の記録を消す
+func (s *shutdownDeadlines) clear(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.deadlines, id)
+}
+
+// shutdownDomainStep はサーバー id のドメインの停止を1段階進める
+// 最初の呼び出しでゲストOSにシャットダウンを要求し、停止するまで ErrServerShutdownInProgress を返す
+// 期限までに停止しない場合は、期限後の呼び出しで強制停止する。停止済みなら記録を消して nil を返す
+func shutdownDomainStep(l *virt.LibVirtEp, id, instanceName string) error {
+	active, err := l.IsDomainActive(instanceName)
+	if err != nil {
+		return err
+	}
+	if active {
+		requested, expired := serverShutdowns.check(id, time.Now())
+		switch {
+		case !requested:
+			err := l.RequestShutdownDomain(instanceName)
+			if err == nil {
+				serverShutdowns.start(id, time.Now().Add(CurrentConfig().ServerShutdownTimeout()))
+				return ErrServerShutdownInProgress
+			}
+			slog.Warn("RequestShutdownDomain() failed; destroying domain", "instanceName", instanceName, "err", err)
+		case !expired:
+			return ErrServerShutdownInProgress
+		default:
+			slog.Warn("server shutdown timed out; destroying domain", "instanceName", instanceName, "timeout", CurrentConfig().ServerShutdownTimeout())
+		}
+		if err := l.DestroyDomain(instanceName); err != nil {
+			return err
+		}
+	}
+	serverShutdowns.clear(id)
+	return nil
+}
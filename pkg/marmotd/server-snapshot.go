@@ -0,0 +1,447 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/lvm"
+	"github.com/takara9/marmot/pkg/qcow"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+const (
+	// スナップショット取得時のドメインの扱い
+	SnapshotConsistencyNone    = "none"    // ドメインはそのまま（クラッシュ整合）
+	SnapshotConsistencyQuiesce = "quiesce" // ファイルシステムを凍結し、ドメインを一時停止して取得
+	SnapshotConsistencyStop    = "stop"    // ドメインをシャットダウンして取得し、再起動する
+)
+
+// ErrSnapshotDeferred は稼働中サーバーの qcow2 スナップショット削除を停止後まで延期したことを示す
+var ErrSnapshotDeferred = errors.New("snapshot operation deferred until the server is stopped")
+
+// normalizeSnapshotConsistency は consistency の指定を検証し、未指定なら none を返す
+func normalizeSnapshotConsistency(v *string) (string, error) {
+	if v == nil || strings.TrimSpace(*v) == "" {
+		return SnapshotConsistencyNone, nil
+	}
+	c := strings.ToLower(strings.TrimSpace(*v))
+	switch c {
+	case SnapshotConsistencyNone, SnapshotConsistencyQuiesce, SnapshotConsistencyStop:
+		return c, nil
+	}
+	return "", fmt.Errorf("invalid consistency %q: must be one of none, quiesce, stop", *v)
+}
+
+// snapshotIncludesDataVolumes は includeDataVolumes の既定値(true)を解決する
+func snapshotIncludesDataVolumes(spec api.ServerSnapshotSpec) bool {
+	return spec.IncludeDataVolumes == nil || *spec.IncludeDataVolumes
+}
+
+// qcow2 内部スナップショットのタグ名
+func qcow2SnapshotName(snapshotID string) string {
+	return "marmot-" + snapshotID
+}
+
+// LVM スナップショット論理ボリューム名
+func lvmSnapshotName(logicalVolume, snapshotID string) string {
+	return logicalVolume + "-snap-" + snapshotID
+}
+
+// validateSnapshotVolumes はスナップショット対象ボリュームの種別と consistency の組み合わせを検証する
+// qcow2 内部スナップショットは qemu-img で取得するため、稼働中のドメインでは stop 以外を受け付けない
+func validateSnapshotVolumes(vols []api.Volume, consistency string, running bool) error {
+	for _, vol := range vols {
+		id := api.VolumeID(vol)
+		if vol.Spec.Iscsi != nil && *vol.Spec.Iscsi {
+			return fmt.Errorf("volume %s: snapshots of iSCSI volumes are not supported", id)
+		}
+		volType := ""
+		if vol.Spec.Type != nil {
+			volType = strings.TrimSpace(*vol.Spec.Type)
+		}
+		switch volType {
+		case "lvm":
+			if vol.Spec.VolumeGroup == nil || vol.Spec.LogicalVolume == nil {
+				return fmt.Errorf("volume %s: volume group or logical volume is not set", id)
+			}
+			// LVM の OS ボリュームとコピーオンライトのクローンはスナップショットとして作成されており、スナップショットのスナップショットは作れない
+			if isLVMSnapshotVolume(vol) {
+				return fmt.Errorf("volume %s: lvm os volumes and copy-on-write clones are snapshots and cannot be snapshotted again", id)
+			}
+		case "qcow2":
+			if vol.Spec.Path == nil || strings.TrimSpace(*vol.Spec.Path) == "" {
+				return fmt.Errorf("volume %s: qcow2 path is not set", id)
+			}
+			if running && consistency != SnapshotConsistencyStop {
+				return fmt.Errorf("volume %s: qcow2 volumes of a running server require consistency=stop", id)
+			}
+		default:
+			return fmt.Errorf("volume %s: snapshots of %q volumes are not supported", id, volType)
+		}
+	}
+	return nil
+}
+
+// snapshotTargetVolumes はサーバーのブートボリュームと（指定があれば）データボリュームのレコードを取得する
+func (m *Marmot) snapshotTargetVolumes(server api.Server, includeData bool) ([]api.Volume, error) {
+	var vols []api.Volume
+	if server.Spec.BootVolume != nil && strings.TrimSpace(api.VolumeID(*server.Spec.BootVolume)) != "" {
+		vol, err := m.Db.GetVolumeById(api.VolumeID(*server.Spec.BootVolume))
+		if err != nil {
+			return nil, fmt.Errorf("boot volume %s: %w", api.VolumeID(*server.Spec.BootVolume), err)
+		}
+		vols = append(vols, vol)
+	}
+	if includeData && server.Spec.Storage != nil {
+		for _, disk := range *server.Spec.Storage {
+			if strings.TrimSpace(api.VolumeID(disk)) == "" {
+				continue
+			}
+			vol, err := m.Db.GetVolumeById(api.VolumeID(disk))
+			if err != nil {
+				return nil, fmt.Errorf("data volume %s: %w", api.VolumeID(disk), err)
+			}
+			vols = append(vols, vol)
+		}
+	}
+	if len(vols) == 0 {
+		return nil, fmt.Errorf("server %s has no volumes to snapshot", api.ServerID(server))
+	}
+	return vols, nil
+}
+
+// PrepareServerSnapshot はスナップショット要求を検証し、etcd へ登録する内容を組み立てる
+// API ハンドラーから呼び出される
+func (m *Marmot) PrepareServerSnapshot(server api.Server, req api.ServerSnapshot) (api.ServerSnapshot, error) {
+	consistency, err := normalizeSnapshotConsistency(req.Spec.Consistency)
+	if err != nil {
+		return api.ServerSnapshot{}, err
+	}
+	running := server.Status != nil && server.Status.StatusCode == db.SERVER_RUNNING
+	vols, err := m.snapshotTargetVolumes(server, snapshotIncludesDataVolumes(req.Spec))
+	if err != nil {
+		return api.ServerSnapshot{}, err
+	}
+	if err := validateSnapshotVolumes(vols, consistency, running); err != nil {
+		return api.ServerSnapshot{}, err
+	}
+
+	req.ApiVersion = util.StringPtr("v1")
+	req.Kind = util.StringPtr("ServerSnapshot")
+	req.Spec.ServerId = util.StringPtr(api.ServerID(server))
+	req.Spec.Consistency = util.StringPtr(consistency)
+	req.Spec.IncludeDataVolumes = util.BoolPtr(snapshotIncludesDataVolumes(req.Spec))
+	req.Spec.Volumes = nil
+	req.Metadata.NodeName = server.Metadata.NodeName
+	return req, nil
+}
+
+// CreateServerSnapshotManage はスナップショットを取得する コントローラーから呼び出される
+// consistency=stop でゲストOSの停止を待つ間は ErrServerShutdownInProgress を返す
+func (m *Marmot) CreateServerSnapshotManage(snapshotId string) error {
+	slog.Debug("===CreateServerSnapshotManage() is called===", "snapshotId", snapshotId)
+	snap, err := m.Db.GetServerSnapshotById(snapshotId)
+	if err != nil {
+		return err
+	}
+	if snap.Spec.ServerId == nil {
+		return fmt.Errorf("snapshot %s has no server id", snapshotId)
+	}
+	server, err := m.Db.GetServerById(*snap.Spec.ServerId)
+	if err != nil {
+		return err
+	}
+	if server.Metadata.InstanceName == nil {
+		return fmt.Errorf("server %s has no instance name", api.ServerID(server))
+	}
+	instanceName := *server.Metadata.InstanceName
+
+	consistency, err := normalizeSnapshotConsistency(snap.Spec.Consistency)
+	if err != nil {
+		return err
+	}
+	// 前回の呼び出しでシャットダウンを要求していれば、停止後に起動し直す
+	stopRequested, _ := serverShutdowns.check(api.ServerID(server), time.Now())
+	running, err := m.Virt.IsDomainActive(instanceName)
+	if err != nil {
+		return err
+	}
+	vols, err := m.snapshotTargetVolumes(server, snapshotIncludesDataVolumes(snap.Spec))
+	if err != nil {
+		return err
+	}
+	if err := validateSnapshotVolumes(vols, consistency, running); err != nil {
+		return err
+	}
+
+	// 整合性確保のためのドメイン操作
+	switch {
+	case running && consistency == SnapshotConsistencyQuiesce:
+		// ゲストエージェントでファイルシステムを凍結してから一時停止する
+		defer freezeGuestFilesystems(m.Virt, instanceName)()
+		if err := m.Virt.SuspendDomain(instanceName); err != nil {
+			return fmt.Errorf("failed to suspend domain %s: %w", instanceName, err)
+		}
+		defer func() {
+			if err := m.Virt.ResumeDomain(instanceName); err != nil {
+				slog.Error("ResumeDomain()", "instanceName", instanceName, "err", err)
+			}
+		}()
+	case (running || stopRequested) && consistency == SnapshotConsistencyStop:
+		// 停止するまで ErrServerShutdownInProgress を返し、コントローラーが次の周期で呼び出し直す
+		if err := shutdownDomainStep(m.Virt, api.ServerID(server), instanceName); err != nil {
+			if errors.Is(err, ErrServerShutdownInProgress) {
+				return err
+			}
+			return fmt.Errorf("failed to shutdown domain %s: %w", instanceName, err)
+		}
+		defer func() {
+			if err := m.Virt.StartDomain(instanceName); err != nil {
+				slog.Error("StartDomain()", "instanceName", instanceName, "err", err)
+			}
+		}()
+	}
+
+	var taken []api.ServerSnapshotVolume
+	for _, vol := range vols {
+		sv, err := takeVolumeSnapshot(vol, snapshotId)
+		if err != nil {
+			// 途中まで取得したスナップショットは残さない
+			for _, done := range taken {
+				if rmErr := removeVolumeSnapshot(done); rmErr != nil {
+					slog.Error("removeVolumeSnapshot()", "volumeId", done.VolumeId, "err", rmErr)
+				}
+			}
+			return err
+		}
+		taken = append(taken, sv)
+	}
+
+	return m.Db.UpdateServerSnapshotById(snapshotId, api.ServerSnapshot{
+		Spec: api.ServerSnapshotSpec{Volumes: &taken},
+	})
+}
+
+// takeVolumeSnapshot は1つのボリュームのスナップショットを取得する
+func takeVolumeSnapshot(vol api.Volume, snapshotId string) (api.ServerSnapshotVolume, error) {
+	sv := api.ServerSnapshotVolume{
+		VolumeId: api.VolumeID(vol),
+		Type:     vol.Spec.Type,
+		Kind:     util.StringPtr(volumeKindOrDefault(vol.Spec)),
+		Size:     vol.Spec.Size,
+	}
+	switch *vol.Spec.Type {
+	case "qcow2":
+		sv.Path = vol.Spec.Path
+		sv.SnapshotName = util.StringPtr(qcow2SnapshotName(snapshotId))
+		if err := qcow.CreateSnapshotQcow(*sv.Path, *sv.SnapshotName); err != nil {
+			return sv, err
+		}
+	case "lvm":
+		sv.VolumeGroup = vol.Spec.VolumeGroup
+		sv.LogicalVolume = vol.Spec.LogicalVolume
+		sv.SnapshotName = util.StringPtr(lvmSnapshotName(*vol.Spec.LogicalVolume, snapshotId))
+		if err := lvm.CreateSnapshot(*sv.VolumeGroup, *sv.LogicalVolume, *sv.SnapshotName, snapshotSizeInBytes(sv)); err != nil {
+			return sv, err
+		}
+	default:
+		return sv, fmt.Errorf("volume %s: snapshots of %q volumes are not supported", sv.VolumeId, *vol.Spec.Type)
+	}
+	return sv, nil
+}
+
+// LVM スナップショットの COW 領域は元ボリュームと同じサイズを確保し、容量不足で無効化されないようにする
+func snapshotSizeInBytes(sv api.ServerSnapshotVolume) uint64 {
+	size := 1
+	if sv.Size != nil && *sv.Size > 0 {
+		size = *sv.Size
+	}
+	return uint64(size) * 1024 * 1024 * 1024
+}
+
+// removeVolumeSnapshot は1つのボリュームのスナップショットを削除する
+func removeVolumeSnapshot(sv api.ServerSnapshotVolume) error {
+	if sv.Type == nil || sv.SnapshotName == nil {
+		return nil
+	}
+	switch *sv.Type {
+	case "qcow2":
+		if sv.Path == nil {
+			return nil
+		}
+		if err := qcow.IsExist(*sv.Path); err != nil {
+			// ボリュームごと削除済み
+			return nil
+		}
+		return qcow.DeleteSnapshotQcow(*sv.Path, *sv.SnapshotName)
+	case "lvm":
+		if sv.VolumeGroup == nil {
+			return nil
+		}
+		if err := lvm.IsExist(*sv.VolumeGroup, *sv.SnapshotName); err != nil {
+			return nil
+		}
+		return lvm.RemoveLV(*sv.VolumeGroup, *sv.SnapshotName)
+	}
+	return nil
+}
+
+// snapshotHasQcow2Volume はスナップショットに qcow2 ボリュームが含まれるかを返す
+func snapshotHasQcow2Volume(snap api.ServerSnapshot) bool {
+	if snap.Spec.Volumes == nil {
+		return false
+	}
+	for _, sv := range *snap.Spec.Volumes {
+		if sv.Type != nil && *sv.Type == "qcow2" {
+			return true
+		}
+	}
+	return false
+}
+
+// RevertServerSnapshotManage はサーバーのボリュームをスナップショット取得時点へ戻す コントローラーから呼び出される
+// qcow2/LVM ともにボリュームが使用中だと戻せないため、ドメインを停止して実行し、稼働中だった場合は再起動する
+// ゲストOSの停止を待つ間は ErrServerShutdownInProgress を返す
+func (m *Marmot) RevertServerSnapshotManage(snapshotId string) error {
+	slog.Debug("===RevertServerSnapshotManage() is called===", "snapshotId", snapshotId)
+	snap, err := m.Db.GetServerSnapshotById(snapshotId)
+	if err != nil {
+		return err
+	}
+	if snap.Spec.ServerId == nil {
+		return fmt.Errorf("snapshot %s has no server id", snapshotId)
+	}
+	if snap.Spec.Volumes == nil || len(*snap.Spec.Volumes) == 0 {
+		return fmt.Errorf("snapshot %s has no volumes", snapshotId)
+	}
+	server, err := m.Db.GetServerById(*snap.Spec.ServerId)
+	if err != nil {
+		return err
+	}
+	if server.Metadata.InstanceName == nil {
+		return fmt.Errorf("server %s has no instance name", api.ServerID(server))
+	}
+	instanceName := *server.Metadata.InstanceName
+
+	// 前回の呼び出しでシャットダウンを要求していれば、停止後に起動し直す
+	stopRequested, _ := serverShutdowns.check(api.ServerID(server), time.Now())
+	running, err := m.Virt.IsDomainActive(instanceName)
+	if err != nil {
+		return err
+	}
+	running = running || stopRequested
+	if running {
+		// 停止するまで ErrServerShutdownInProgress を返し、コントローラーが次の周期で呼び出し直す
+		if err := shutdownDomainStep(m.Virt, api.ServerID(server), instanceName); err != nil {
+			if errors.Is(err, ErrServerShutdownInProgress) {
+				return err
+			}
+			return fmt.Errorf("failed to shutdown domain %s: %w", instanceName, err)
+		}
+	}
+
+	var revertErr error
+	for _, sv := range *snap.Spec.Volumes {
+		if err := revertVolumeSnapshot(sv); err != nil {
+			revertErr = fmt.Errorf("volume %s: %w", sv.VolumeId, err)
+			break
+		}
+	}
+
+	if running {
+		if err := m.Virt.StartDomain(instanceName); err != nil {
+			slog.Error("StartDomain()", "instanceName", instanceName, "err", err)
+			if revertErr == nil {
+				revertErr = fmt.Errorf("failed to start domain %s: %w", instanceName, err)
+			}
+		}
+	}
+	return revertErr
+}
+
+// revertVolumeSnapshot は1つのボリュームをスナップショットへ戻す
+func revertVolumeSnapshot(sv api.ServerSnapshotVolume) error {
+	if sv.Type == nil || sv.SnapshotName == nil {
+		return fmt.Errorf("snapshot volume record is incomplete")
+	}
+	switch *sv.Type {
+	case "qcow2":
+		if sv.Path == nil {
+			return fmt.Errorf("qcow2 path is not set")
+		}
+		return qcow.RestoreSnapshotQcow(*sv.Path, *sv.SnapshotName)
+	case "lvm":
+		if sv.VolumeGroup == nil || sv.LogicalVolume == nil {
+			return fmt.Errorf("volume group or logical volume is not set")
+		}
+		// マージでスナップショットは消費されるので、同名で取り直して再度ロールバックできるようにする
+		if err := lvm.MergeSnapshot(*sv.VolumeGroup, *sv.SnapshotName); err != nil {
+			return err
+		}
+		return lvm.CreateSnapshot(*sv.VolumeGroup, *sv.LogicalVolume, *sv.SnapshotName, snapshotSizeInBytes(sv))
+	}
+	return fmt.Errorf("snapshots of %q volumes are not supported", *sv.Type)
+}
+
+// DeleteServerSnapshotManage はスナップショットの実体を削除する コントローラーから呼び出される
+// qcow2 の内部スナップショットは稼働中に削除できないため、サーバー停止まで ErrSnapshotDeferred を返して延期する
+func (m *Marmot) DeleteServerSnapshotManage(snapshotId string) error {
+	slog.Debug("===DeleteServerSnapshotManage() is called===", "snapshotId", snapshotId)
+	snap, err := m.Db.GetServerSnapshotById(snapshotId)
+	if err != nil {
+		return err
+	}
+	if snap.Spec.Volumes == nil {
+		return nil
+	}
+
+	if snapshotHasQcow2Volume(snap) && snap.Spec.ServerId != nil {
+		server, err := m.Db.GetServerById(*snap.Spec.ServerId)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+		if err == nil && server.Metadata.InstanceName != nil {
+			running, err := m.Virt.IsDomainActive(*server.Metadata.InstanceName)
+			if err == nil && running {
+				return ErrSnapshotDeferred
+			}
+		}
+	}
+
+	for _, sv := range *snap.Spec.Volumes {
+		if err := removeVolumeSnapshot(sv); err != nil {
+			return fmt.Errorf("volume %s: %w", sv.VolumeId, err)
+		}
+	}
+	return nil
+}
+
+// DeleteServerSnapshotsByServerManage はサーバー削除時にスナップショットを片付ける
+// qcow2 の内部スナップショットはボリュームファイルと一緒に消えるので、LVM スナップショットとレコードを削除する
+func (m *Marmot) DeleteServerSnapshotsByServerManage(serverId string) error {
+	snaps, err := m.Db.GetServerSnapshotsByServerId(serverId)
+	if err != nil {
+		return err
+	}
+	for _, snap := range snaps {
+		if snap.Spec.Volumes != nil {
+			for _, sv := range *snap.Spec.Volumes {
+				if sv.Type == nil || *sv.Type != "lvm" {
+					continue
+				}
+				if err := removeVolumeSnapshot(sv); err != nil {
+					slog.Error("removeVolumeSnapshot()", "snapshotId", api.ServerSnapshotID(snap), "volumeId", sv.VolumeId, "err", err)
+				}
+			}
+		}
+		if err := m.Db.DeleteServerSnapshotById(api.ServerSnapshotID(snap)); err != nil {
+			slog.Error("DeleteServerSnapshotById()", "snapshotId", api.ServerSnapshotID(snap), "err", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+// サーバーへのデータボリュームのアタッチとデタッチ
+// API はサーバーの storage とボリュームのアタッチ先を記録し、
+// libvirt への反映はサーバーのノードのサーバーコントローラーが SyncServerVolumesManage で行う
+
+var (
+	ErrServerVolumeState     = errors.New("volumes can be attached or detached only while the server is RUNNING or STOPPED")
+	ErrVolumeNotAttachable   = errors.New("volume cannot be attached to the server")
+	ErrVolumeAlreadyInSpec   = errors.New("volume is already attached to the server")
+	ErrVolumeNotInServerSpec = errors.New("volume is not attached to the server")
+)
+
+// serverStorageIndex はサーバーの storage 内のボリュームの位置を返す。無い場合は -1
+func serverStorageIndex(server api.Server, volumeID string) int {
+	if server.Spec.Storage == nil {
+		return -1
+	}
+	for i, vol := range *server.Spec.Storage {
+		if api.VolumeID(vol) == volumeID {
+			return i
+		}
+	}
+	return -1
+}
+
+// isNodeLocalVolume はボリュームの実体が作成したノードにしか存在しないかを返す
+func isNodeLocalVolume(vol api.Volume) bool {
+	switch strings.TrimSpace(util.OrDefault(vol.Spec.Type, "qcow2")) {
+	case "qcow2":
+		return true
+	case "lvm":
+		return !util.OrDefault(vol.Spec.Iscsi, false)
+	}
+	return false
+}
+
+// canChangeServerVolumes はボリュームを付け外しできるサーバーの状態かを返す
+func canChangeServerVolumes(server api.Server) bool {
+	if server.Status == nil {
+		return false
+	}
+	return server.Status.StatusCode == db.SERVER_RUNNING || server.Status.StatusCode == db.SERVER_STOPPED
+}
+
+// validateVolumeAttach はボリュームをサーバーへアタッチできるかを検査する
+func validateVolumeAttach(server api.Server, vol api.Volume) error {
+	if !canChangeServerVolumes(server) {
+		return ErrServerVolumeState
+	}
+	if serverStorageIndex(server, api.VolumeID(vol)) >= 0 {
+		return ErrVolumeAlreadyInSpec
+	}
+	if volumeKindOrDefault(vol.Spec) != "data" {
+		return fmt.Errorf("%w: only data volumes can be attached", ErrVolumeNotAttachable)
+	}
+	if vol.Status == nil || vol.Status.StatusCode != db.VOLUME_AVAILABLE {
+		return fmt.Errorf("%w: volume is not AVAILABLE", ErrVolumeNotAttachable)
+	}
+	volType := strings.TrimSpace(util.OrDefault(vol.Spec.Type, "qcow2"))
+	switch volType {
+	case "qcow2", "lvm", "ceph":
+	default:
+		return fmt.Errorf("%w: unsupported volume type %s", ErrVolumeNotAttachable, volType)
+	}
+	if isNodeLocalVolume(vol) {
+		serverNode := strings.TrimSpace(util.OrDefault(server.Metadata.NodeName, ""))
+		volNode := strings.TrimSpace(util.OrDefault(vol.Metadata.NodeName, ""))
+		if volNode != "" && serverNode != volNode {
+			return fmt.Errorf("%w: %s volume on node %s cannot be attached to a server on node %s", ErrVolumeNotAttachable, volType, volNode, serverNode)
+		}
+	}
+	return nil
+}
+
+// AttachServerVolume はボリュームのアタッチ先を記録して、サーバーの storage に追加する
+func (m *Marmot) AttachServerVolume(serverID, volumeID string) error {
+	server, err := m.Db.GetServerById(serverID)
+	if err != nil {
+		return err
+	}
+	vol, err := m.Db.GetVolumeById(volumeID)
+	if err != nil {
+		return err
+	}
+	if err := validateVolumeAttach(server, vol); err != nil {
+		return err
+	}
+	if err := m.Db.AttachVolumeToServer(volumeID, serverID); err != nil {
+		return err
+	}
+
+	// 後からアタッチしたボリュームはサーバーの削除で消さない
+	vol.Spec.Persistent = util.BoolPtr(true)
+	vol.Metadata.ResourceVersion = nil
+	storage := []api.Volume{}
+	if server.Spec.Storage != nil {
+		storage = append(storage, *server.Spec.Storage...)
+	}
+	storage = append(storage, vol)
+	update := api.Server{Spec: api.ServerSpec{Storage: &storage}}
+	if err := m.Db.UpdateServerWithResourceVersion(serverID, update, util.OrDefault(server.Metadata.ResourceVersion, "")); err != nil {
+		if detachErr := m.Db.DetachVolumeFromServer(volumeID, serverID); detachErr != nil {
+			slog.Error("DetachVolumeFromServer()", "err", detachErr, "volumeId", volumeID, "serverId", serverID)
+		}
+		return err
+	}
+	return nil
+}
+
+// DetachServerVolume はサーバーの storage からボリュームを外す
+// ボリュームのアタッチ先は、コントローラーが libvirt から取り外した後に解除する
+func (m *Marmot) DetachServerVolume(serverID, volumeID string) error {
+	server, err := m.Db.GetServerById(serverID)
+	if err != nil {
+		return err
+	}
+	idx := serverStorageIndex(server, volumeID)
+	if idx < 0 {
+		return ErrVolumeNotInServerSpec
+	}
+	if !canChangeServerVolumes(server) {
+		return ErrServerVolumeState
+	}
+
+	current := *server.Spec.Storage
+	storage := make([]api.Volume, 0, len(current)-1)
+	storage = append(storage, current[:idx]...)
+	storage = append(storage, current[idx+1:]...)
+	update := api.Server{Spec: api.ServerSpec{Storage: &storage}}
+	return m.Db.UpdateServerWithResourceVersion(serverID, update, util.OrDefault(server.Metadata.ResourceVersion, ""))
+}
+
+// SyncServerVolumesManage はサーバーの storage とドメインのディスクを合わせる
+// storage にあるボリュームを接続して拡張後のサイズを通知し、storage から外されたボリュームを取り外してアタッチ先を解除する
+func (m *Marmot) SyncServerVolumesManage(id string) error {
+	sv, err := m.Db.GetServerById(id)
+	if err != nil {
+		slog.Error("GetServerById()", "err", err)
+		return err
+	}
+	if sv.Metadata.InstanceName == nil || strings.TrimSpace(*sv.Metadata.InstanceName) == "" {
+		return fmt.Errorf("server %s has no instance name", id)
+	}
+	instanceName := *sv.Metadata.InstanceName
+	nodeName := util.OrDefault(sv.Metadata.NodeName, m.NodeName)
+
+	vols, err := m.Db.GetVolumes()
+	if err != nil {
+		return err
+	}
+	var detached []api.Volume
+	for _, vol := range vols {
+		if db.VolumeAttachedServerId(vol) == id && serverStorageIndex(sv, api.VolumeID(vol)) < 0 {
+			detached = append(detached, vol)
+		}
+	}
+	if (sv.Spec.Storage == nil || len(*sv.Spec.Storage) == 0) && len(detached) == 0 {
+		return nil
+	}
+
+	l, err := virt.NewLibVirtEp("qemu:///system")
+	if err != nil {
+		slog.Error("NewLibVirtEp()", "err", err)
+		return err
+	}
+	defer l.Close()
+
+	cephReady := false
+	if sv.Spec.Storage != nil {
+		for i, disk := range *sv.Spec.Storage {
+			ds, err := m.dataDiskSpec(nodeName, id, disk, i)
+			if err != nil {
+				return fmt.Errorf("failed to build disk for volume %s: %w", api.VolumeID(disk), err)
+			}
+			if ds.Type == "" {
+				continue
+			}
+			if ds.Type == "rbd" && !cephReady {
+				if err := prepareCephSecretForServer(l, id); err != nil {
+					return err
+				}
+				cephReady = true
+			}
+			attached, err := l.AttachDisk(instanceName, ds)
+			if err != nil {
+				return fmt.Errorf("failed to attach volume %s: %w", api.VolumeID(disk), err)
+			}
+			if attached {
+				slog.Info("volume attached to server", "serverId", id, "volumeId", api.VolumeID(disk))
+			}
+
+			// 拡張されたボリュームのサイズを稼働中のゲストに通知する
+			if size := util.OrDefault(disk.Spec.Size, 0); size > 0 {
+				resized, err := l.ResizeDisk(instanceName, ds, uint64(size)*1024*1024*1024)
+				if err != nil {
+					return fmt.Errorf("failed to resize volume %s: %w", api.VolumeID(disk), err)
+				}
+				if resized {
+					slog.Info("volume resized on server", "serverId", id, "volumeId", api.VolumeID(disk), "sizeGB", size)
+				}
+			}
+		}
+	}
+
+	for _, vol := range detached {
+		ds, err := m.dataDiskSpec(nodeName, id, vol, 0)
+		if err != nil {
+			return fmt.Errorf("failed to build disk for volume %s: %w", api.VolumeID(vol), err)
+		}
+		if ds.Type != "" {
+			removed, err := l.DetachDisk(instanceName, ds)
+			if err != nil {
+				return fmt.Errorf("failed to detach volume %s: %w", api.VolumeID(vol), err)
+			}
+			if removed {
+				slog.Info("volume detached from server", "serverId", id, "volumeId", api.VolumeID(vol))
+			}
+		}
+		if err := m.Db.DetachVolumeFromServer(api.VolumeID(vol), id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -67,6 +67,10 @@ func shouldSkipVolumeDeletion(vol api.Volume) bool {
 }
 
 func shouldResolvePreCreatedStorageVolume(disk api.Volume) bool {
+	// 複製するボリュームは新しく作成する
+	if disk.Spec.Source != nil {
+		return false
+	}
 	if strings.TrimSpace(api.VolumeID(disk)) != "" {
 		return true
 	}
@@ -226,6 +230,8 @@ func (m *Marmot) resolveStorageBoundNodeName(storage *[]api.Volume) (string, err
 
 // ResolveAndAssignServerNodeByStorage inspects pre-created storage volumes and
 // updates server metadata.nodeName when storage constrains placement.
+// Servers without a storage constraint are left to the scheduler, and the
+// current metadata.nodeName (possibly empty) is returned unchanged.
 func (m *Marmot) ResolveAndAssignServerNodeByStorage(serverID string) (string, error) {
 	serverID = strings.TrimSpace(serverID)
 	if serverID == "" {
@@ -241,6 +247,9 @@ func (m *Marmot) ResolveAndAssignServerNodeByStorage(serverID string) (string, e
 	if err != nil {
 		return "", err
 	}
+	if strings.TrimSpace(storageNodeName) == "" {
+		return strings.TrimSpace(util.OrDefault(serverConfig.Metadata.NodeName, "")), nil
+	}
 
 	assignedNodeName, err := chooseAssignedNodeName(m.NodeName, serverConfig.Metadata.NodeName, storageNodeName)
 	if err != nil {
@@ -266,7 +275,7 @@ func (m *Marmot) ResolveAndAssignServerNodeByStorage(serverID string) (string, e
 	return assignedNodeName, nil
 }
 func resolveISCSIServerNode(statuses []api.HostStatus) string {
-	active := filterActiveHosts(statuses)
+	active := filterSchedulableHosts(statuses)
 	if len(active) == 0 {
 		return ""
 	}
@@ -330,7 +339,16 @@ func (m *Marmot) resolveISCSIDiskAttachment(nodeName string, disk api.Volume) (t
 		return "", "", "", "", err
 	}
 
-	iscsiServerNode := resolveISCSIServerNode(statuses)
+	// ターゲットはボリュームを作成したノードにあるため、そのノードを優先する（cordon 後も接続先は変わらない）
+	iscsiServerNode := ""
+	if disk.Metadata.NodeName != nil {
+		if node := strings.TrimSpace(*disk.Metadata.NodeName); node != "" && findHostStatusByNodeName(statuses, node) != nil {
+			iscsiServerNode = node
+		}
+	}
+	if iscsiServerNode == "" {
+		iscsiServerNode = resolveISCSIServerNode(statuses)
+	}
 	if iscsiServerNode == "" {
 		return "", "", "", "", errors.New("failed to resolve iscsi server node")
 	}
@@ -353,6 +371,50 @@ func (m *Marmot) resolveISCSIDiskAttachment(nodeName string, disk api.Volume) (t
 	return normalizeISCSITargetName(*disk.Spec.IscsiTargetIqn), strings.TrimSpace(*iscsiServerStatus.IpAddress), "3260", initiator, nil
 }
 
+// dataDiskSpec はデータボリュームの libvirt ディスク定義を生成する
+// index はサーバーの storage 内の順番で、デバイス名と PCI バスを決める
+// 未対応のタイプは Type が空の定義を返し、ドメインXMLには含まれない
+func (m *Marmot) dataDiskSpec(nodeName, serverID string, disk api.Volume, index int) (virt.DiskSpec, error) {
+	if disk.Spec.Kind == nil {
+		disk.Spec.Kind = util.StringPtr("data")
+	}
+	if disk.Spec.Type == nil {
+		disk.Spec.Type = util.StringPtr("qcow2")
+	}
+	dev := fmt.Sprintf("vd%c", 'b'+index)
+	bus := uint(11 + index)
+	switch *disk.Spec.Type {
+	case "qcow2":
+		if disk.Spec.Path == nil || strings.TrimSpace(*disk.Spec.Path) == "" {
+			return virt.DiskSpec{}, fmt.Errorf("storage[%d] path is required for qcow2", index)
+		}
+		return virt.DiskSpec{Dev: dev, Src: *disk.Spec.Path, Bus: bus, Type: "qcow2"}, nil
+	case "lvm":
+		ds := virt.DiskSpec{Dev: dev, Bus: bus}
+		if disk.Spec.Iscsi != nil && *disk.Spec.Iscsi {
+			targetName, host, port, initiator, err := m.resolveISCSIDiskAttachment(nodeName, disk)
+			if err != nil {
+				return virt.DiskSpec{}, err
+			}
+			ds.Type = "iscsi"
+			ds.ISCSITarget = targetName
+			ds.ISCSIHost = host
+			ds.ISCSIPort = port
+			ds.ISCSIInitiator = initiator
+			return ds, nil
+		}
+		if disk.Spec.Path == nil || strings.TrimSpace(*disk.Spec.Path) == "" {
+			return virt.DiskSpec{}, fmt.Errorf("storage[%d] path is required for lvm", index)
+		}
+		ds.Src = *disk.Spec.Path
+		ds.Type = "raw"
+		return ds, nil
+	case "ceph":
+		return buildCephDiskSpec(disk, serverID, dev, bus)
+	}
+	return virt.DiskSpec{}, nil
+}
+
 // サーバーの生成 コントローラーから呼び出される
 func (m *Marmot) CreateServerManage(id string) (string, error) {
 	slog.Debug("=====CreateServer2()=====", "", "")
@@ -394,6 +456,9 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 	bootVol.Metadata.Name = "boot-" + api.ServerID(serverConfig)
 	// サーバー割当ノードをブートボリュームのメタデータに付与する。
 	assignNodeNameIfUnset(&bootVol.Metadata, assignedNodeName)
+	// サーバーが作成するボリュームはサーバーの所有者とプロジェクトのクォータで数える
+	bootVol.Metadata.Owner = serverConfig.Metadata.Owner
+	bootVol.Metadata.Project = serverConfig.Metadata.Project
 	bootVol.Spec.Kind = util.StringPtr("os")
 	bootVol.Spec.Path = util.StringPtr("")
 	bootVol.Spec.Size = util.IntPtrInt(0)
@@ -408,6 +473,7 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 
 	if bootVol.Spec.Type != nil && *bootVol.Spec.Type != "qcow2" &&
 		serverConfig.Spec.BootVolume != nil &&
+		serverConfig.Spec.BootVolume.Spec.Source == nil &&
 		serverConfig.Spec.BootVolume.Spec.Size != nil {
 		return "", errors.New("boot_volume.size は boot_volume.type=qcow2 のときのみ指定できます")
 	}
@@ -425,6 +491,12 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 		bootVol.Spec.OsVariant = serverConfig.Spec.OsVariant
 	}
 
+	// サーバーの複製では、ブートボリュームを複製元のブートボリュームから作成する
+	if serverConfig.Spec.BootVolume != nil && serverConfig.Spec.BootVolume.Spec.Source != nil {
+		source := *serverConfig.Spec.BootVolume.Spec.Source
+		bootVol.Spec.Source = &source
+	}
+
 	slog.Debug("サーバーのネットワークインターフェースの設定")
 
 	// ネットワークの設定
@@ -821,6 +893,8 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 
 			slog.Debug("データボリュームを作成", "disk index", i, "type", util.OrDefault(disk.Spec.Type, ""))
 			assignNodeNameIfUnset(&disk.Metadata, assignedNodeName)
+			disk.Metadata.Owner = serverConfig.Metadata.Owner
+			disk.Metadata.Project = serverConfig.Metadata.Project
 			diskVol, err := m.CreateNewVolumeWithWait(disk)
 			if err != nil {
 				slog.Error("CreateNewVolumeWithWait()", "err", err)
@@ -846,6 +920,20 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 		return "", err
 	}
 
+	// データボリュームにアタッチ先を記録する。他のサーバーで使用中のボリュームはアタッチできない
+	if serverConfig.Spec.Storage != nil {
+		for _, disk := range *serverConfig.Spec.Storage {
+			volID := strings.TrimSpace(api.VolumeID(disk))
+			if volID == "" {
+				continue
+			}
+			if err := m.Db.AttachVolumeToServer(volID, api.ServerID(serverConfig)); err != nil {
+				slog.Error("AttachVolumeToServer()", "err", err, "volume id", volID)
+				return "", err
+			}
+		}
+	}
+
 	slog.Debug("ハイパーバイザーのリソース確保")
 	//var virtSpec virt.ServerSpec
 	virtSpec.UUID = *serverConfig.Metadata.Uuid
@@ -880,7 +968,7 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 	}
 	slog.Debug("ボリュームの設定が無いときはqcow2をデフォルトとする2", "boot volume ptr", bootVolDefined)
 
-	path := "/var/lib/marmot/isos/" + api.ServerID(serverConfig)
+	path := cloudInitISODir + "/" + api.ServerID(serverConfig)
 
 	password, sshKey, usernames, err := cloudInitAuthInputs(serverConfig.Spec.Auth)
 	if err != nil {
@@ -888,7 +976,13 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 		return "", err
 	}
 
-	isoPath, err := imageModule.GenerateCloudInitISO(path, password, sshKey, usernames, serverConfig.Spec.Ansible, api.ServerID(serverConfig))
+	customCloudInit, err := RenderServerCloudInit(serverConfig)
+	if err != nil {
+		slog.Error("RenderServerCloudInit()", "err", err)
+		return "", err
+	}
+
+	isoPath, err := imageModule.GenerateCloudInitISO(path, password, sshKey, usernames, serverConfig.Spec.Ansible, api.ServerID(serverConfig), customCloudInit)
 	if err != nil {
 		slog.Error("GenerateCloudInitISO()", "module", imageModule.Key(), "err", err)
 		return "", err
@@ -944,60 +1038,19 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 	// データディスクの設定
 	if serverConfig.Spec.Storage != nil {
 		for i, disk := range *serverConfig.Spec.Storage {
-			if disk.Spec.Kind == nil {
-				disk.Spec.Kind = util.StringPtr("data")
-			}
-			if disk.Spec.Type == nil {
-				disk.Spec.Type = util.StringPtr("qcow2")
+			ds, err := m.dataDiskSpec(assignedNodeName, api.ServerID(serverConfig), disk, i)
+			if err != nil {
+				return "", err
 			}
-			switch {
-			case *disk.Spec.Type == "qcow2":
-				if disk.Spec.Path == nil || strings.TrimSpace(*disk.Spec.Path) == "" {
-					return "", fmt.Errorf("storage[%d] path is required for qcow2", i)
-				}
-				ds := virt.DiskSpec{
-					Dev:  fmt.Sprintf("vd%c", 'b'+i),
-					Src:  *disk.Spec.Path,
-					Bus:  uint(11 + i),
-					Type: "qcow2",
-				}
-				virtSpec.DiskSpecs = append(virtSpec.DiskSpecs, ds)
-			case *disk.Spec.Type == "lvm":
-				ds := virt.DiskSpec{Dev: fmt.Sprintf("vd%c", 'b'+i), Bus: uint(11 + i)}
-				isISCSIDataDisk := disk.Spec.Iscsi != nil && *disk.Spec.Iscsi
-				if isISCSIDataDisk {
-					targetName, host, port, initiator, err := m.resolveISCSIDiskAttachment(assignedNodeName, disk)
-					if err != nil {
-						return "", err
-					}
-					ds.Type = "iscsi"
-					ds.ISCSITarget = targetName
-					ds.ISCSIHost = host
-					ds.ISCSIPort = port
-					ds.ISCSIInitiator = initiator
-				} else {
-					if disk.Spec.Path == nil || strings.TrimSpace(*disk.Spec.Path) == "" {
-						return "", fmt.Errorf("storage[%d] path is required for lvm", i)
-					}
-					ds.Src = *disk.Spec.Path
-					ds.Type = "raw"
-				}
-				virtSpec.DiskSpecs = append(virtSpec.DiskSpecs, ds)
-			case *disk.Spec.Type == "ceph":
-				ds, err := buildCephDiskSpec(disk, api.ServerID(serverConfig), fmt.Sprintf("vd%c", 'b'+i), uint(11+i))
-				if err != nil {
-					return "", err
-				}
+			if ds.Type != "" {
 				virtSpec.DiskSpecs = append(virtSpec.DiskSpecs, ds)
 			}
 		}
 	}
 
-	//channelFile := "org.qemu.guest_agent.0"
-	//channelPath, err := util.CreateChannelDir(virtSpec.UUID)
-
+	// ゲストエージェントのソケットは libvirt がドメイン毎のディレクトリに作成する
 	virtSpec.ChannelSpecs = []virt.ChannelSpec{
-		//{"unix", channelPath + "/" + channelFile, channelFile, "channel0", 1},
+		{Type: "unix", Path: "", Name: virt.GuestAgentChannelName, Alias: "channel0", Port: 1},
 		{Type: "spicevmc", Path: "", Name: "com.redhat.spice.0", Alias: "channel1", Port: 2},
 	}
 	virtSpec.Clocks = []virt.ClockSpec{
@@ -1006,7 +1059,8 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 		{Name: "hpet", TickPolicy: "", Present: "no"},
 	}
 
-	// イメージのOSメタデータを取得してvirtSpecに設定
+	// イメージのOSメタデータとファームウェアの要求を取得してvirtSpecに設定
+	imageFirmware := ""
 	if bootVol.Spec.OsVariant != nil {
 		img, imgErr := resolveImageTemplateByVolumeNode(m, bootVol)
 		if imgErr == nil {
@@ -1016,8 +1070,17 @@ func (m *Marmot) CreateServerManage(id string) (string, error) {
 			if img.Spec.OsVersion != nil {
 				virtSpec.OsVersion = *img.Spec.OsVersion
 			}
+			imageFirmware = util.OrDefault(img.Spec.Firmware, "")
 		}
 	}
+	if err := applyServerFirmware(&virtSpec, &serverConfig, bootVolDefined, imageFirmware); err != nil {
+		slog.Error("applyServerFirmware()", "err", err)
+		return "", err
+	}
+	if err := m.applyServerPerformance(&virtSpec, &serverConfig); err != nil {
+		slog.Error("applyServerPerformance()", "err", err)
+		return "", err
+	}
 
 	// VM 起動直前に、依存ネットワーク実体とオーバーレイブリッジを再確認する。
 	// ネットワークコントローラーとのタイミング競合や host 再起動後の実体欠落に備える。
@@ -1593,6 +1656,8 @@ func (m *Marmot) ensureOverlayBridgesForServer(serverConfig api.Server) error {
 }
 
 // サーバーの停止 コントローラーから呼び出される
+// 最初の呼び出しでゲストOSにシャットダウンを要求し、停止するまで ErrServerShutdownInProgress を返す
+// 期限までに停止しない場合は、期限後の呼び出しで強制停止する
 func (m *Marmot) StopServerManage(id string) error {
 	slog.Debug("===StopServerManage() is called===", "id", id)
 	sv, err := m.Db.GetServerById(id)
@@ -1612,8 +1677,16 @@ func (m *Marmot) StopServerManage(id string) error {
 	}
 	defer l.Close()
 
-	if err = l.StopDomain(*sv.Metadata.InstanceName); err != nil {
-		slog.Error("StopDomain()", "err", err)
+	instanceName := *sv.Metadata.InstanceName
+	if err := shutdownDomainStep(l, id, instanceName); err != nil {
+		if !errors.Is(err, ErrServerShutdownInProgress) {
+			slog.Error("shutdownDomainStep()", "err", err)
+		}
+		return err
+	}
+
+	if err = l.DisableDomainAutostart(instanceName); err != nil {
+		slog.Error("DisableDomainAutostart()", "err", err)
 		return err
 	}
 
@@ -1632,6 +1705,8 @@ func (m *Marmot) StopServerManage(id string) error {
 // サーバーの起動 コントローラーから呼び出される
 func (m *Marmot) StartServerManage(id string) error {
 	slog.Debug("===StartServerManage() is called===", "id", id)
+	// 停止を待たずに起動する場合は、以前のシャットダウンの要求を取り消す
+	serverShutdowns.clear(id)
 	sv, err := m.Db.GetServerById(id)
 	if err != nil {
 		slog.Error("GetServerById()", "err", err)
@@ -1718,6 +1793,7 @@ func (m *Marmot) DeleteServerByIdManage(id string) error {
 				slog.Warn("removeCephSecretForServer()", "err", err, "serverId", id)
 			}
 		}
+		removeServerFirmwareState(sv.Spec.BootVolume)
 	}
 
 	// ブートボリュームの削除タイムスタンプのセット
@@ -1745,6 +1821,11 @@ func (m *Marmot) DeleteServerByIdManage(id string) error {
 		}
 	}
 
+	// アタッチ先の記録を消して、残るボリュームを他のサーバーで使えるようにする
+	if err := m.Db.DetachVolumesFromServer(id); err != nil {
+		slog.Warn("DetachVolumesFromServer()", "err", err, "serverId", id)
+	}
+
 	return nil
 }
 
@@ -1772,9 +1853,10 @@ func (m *Marmot) GetServerManage(id string) (api.Server, error) {
 }
 
 // サーバーの更新
-func (m *Marmot) UpdateServerById(id string, serverSpec api.Server) error {
+// resourceVersion が空でない場合は、現在のリソースバージョンと一致する時だけ更新する
+func (m *Marmot) UpdateServerById(id string, serverSpec api.Server, resourceVersion string) error {
 	slog.Debug("===", "UpdateServerById is called", "===")
-	err := m.Db.UpdateServer(id, serverSpec)
+	err := m.Db.UpdateServerWithResourceVersion(id, serverSpec, resourceVersion)
 	if err != nil {
 		slog.Error("UpdateServer()", "err", err)
 		return err
@@ -1814,6 +1896,10 @@ func (m *Marmot) SyncServerResourcesManage(id string) error {
 	}
 
 	if err := m.StopServerManage(id); err != nil {
+		if errors.Is(err, ErrServerShutdownInProgress) {
+			// 停止を待つ間は RUNNING のまま、次の調整で反映を続ける
+			return err
+		}
 		return fmt.Errorf("failed to stop server before applying resource changes: %w", err)
 	}
 
@@ -1868,6 +1954,14 @@ func (m *Marmot) CreateNewVolumeWithWait(volReq api.Volume) (api.Volume, error)
 		}
 	}
 
+	// 複製するボリュームは、ボリュームコントローラーが PENDING のボリュームとして複製する
+	if volReq.Spec.Source != nil {
+		if err := m.PrepareVolumeClone(&volReq); err != nil {
+			slog.Error("PrepareVolumeClone()", "err", err)
+			return api.Volume{}, err
+		}
+	}
+
 	vol, err := m.Db.CreateVolumeOnDB2(volReq)
 	if err != nil {
 		slog.Error("CreateVolumeOnDB2()", "err", err)
@@ -1875,6 +1969,9 @@ func (m *Marmot) CreateNewVolumeWithWait(volReq api.Volume) (api.Volume, error)
 
 		return api.Volume{}, err
 	}
+	if volReq.Spec.Source != nil {
+		return m.waitForVolumeAvailable(api.VolumeID(*vol))
+	}
 
 	if _, err = m.CreateNewVolume(api.VolumeID(*vol)); err != nil {
 		slog.Error("CreateNewVolume()", "err", err)
@@ -1932,9 +2029,11 @@ func (m *Marmot) MakeImageEntryFromRunningVMWithContext(ctx context.Context, ser
 	//	return "", err
 	//}
 
-	// 仮想マシンの一時停止
+	// ファイルシステムを凍結して書き込みを反映してから仮想マシンを停止する
+	thaw := freezeGuestFilesystems(m.Virt, *serverSpec.Metadata.InstanceName)
 	if err := m.Virt.StopDomain(*serverSpec.Metadata.InstanceName); err != nil {
 		slog.Error("StopDomain()", "err", err)
+		thaw()
 		//return "", err
 	}
 
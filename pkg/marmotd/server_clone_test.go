@@ -0,0 +1,176 @@
+package marmotd
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func newCloneSourceServer() api.Server {
+	boot := newCloneSourceVolume("b0001", "qcow2", "os", "hv1", 20)
+	data := newCloneSourceVolume("d0001", "lvm", "data", "hv1", 10)
+	data.Metadata.Name = "logs-s0001"
+	server := api.Server{
+		Metadata: api.Metadata{
+			Name:     "web1",
+			NodeName: util.StringPtr("hv1"),
+			Project:  util.StringPtr("team-a"),
+			Labels:   &map[string]interface{}{"role": "web"},
+		},
+		Spec: api.ServerSpec{
+			Cpu:        util.IntPtrInt(4),
+			Memory:     util.IntPtrInt(8192),
+			OsVariant:  util.StringPtr("ubuntu24.04"),
+			BootVolume: &boot,
+			Storage:    &[]api.Volume{data},
+			CloudInit:  &api.ServerCloudInit{UserData: util.StringPtr("#cloud-config\npackages: [nginx]\n")},
+			NetworkInterface: &[]api.NetworkInterface{{
+				Networkname: "net1",
+				Networkid:   "n0001",
+				Address:     util.StringPtr("10.1.0.10"),
+				Netmasklen:  util.IntPtrInt(24),
+				Mac:         util.StringPtr("52:54:00:00:00:01"),
+				IpNetworkId: util.StringPtr("i0001"),
+				Vlans:       &[]uint{100},
+			}},
+		},
+		Status: &api.Status{StatusCode: db.SERVER_STOPPED},
+	}
+	api.SetServerID(&server, "s0001")
+	return server
+}
+
+func TestCloneServerSpec(t *testing.T) {
+	source := newCloneSourceServer()
+	clone, err := cloneServerSpec(source, api.ServerCloneRequest{Name: " web2 ", Mode: util.StringPtr("COW")})
+	if err != nil {
+		t.Fatalf("cloneServerSpec() error = %v", err)
+	}
+
+	if clone.Metadata.Name != "web2" || api.ServerID(clone) != "" {
+		t.Fatalf("metadata = %+v, want the new name without an id", clone.Metadata)
+	}
+	if util.OrDefault(clone.Metadata.NodeName, "") != "hv1" || util.OrDefault(clone.Metadata.Project, "") != "team-a" {
+		t.Fatalf("metadata = %+v, want the node and project of the source", clone.Metadata)
+	}
+	if clone.Metadata.Labels == nil || (*clone.Metadata.Labels)["role"] != "web" {
+		t.Fatalf("labels = %v, want those of the source", clone.Metadata.Labels)
+	}
+	(*clone.Metadata.Labels)["role"] = "db"
+	if (*source.Metadata.Labels)["role"] != "web" {
+		t.Fatal("the labels of the clone share the map of the source")
+	}
+	if clone.Spec.CloudInit == nil || util.OrDefault(clone.Spec.CloudInit.UserData, "") != "#cloud-config\npackages: [nginx]\n" {
+		t.Fatalf("cloudInit = %+v, want that of the source", clone.Spec.CloudInit)
+	}
+	if *clone.Spec.Cpu != 4 || *clone.Spec.Memory != 8192 {
+		t.Fatalf("cpu/memory = %d/%d, want those of the source", *clone.Spec.Cpu, *clone.Spec.Memory)
+	}
+
+	boot := clone.Spec.BootVolume
+	if boot == nil || boot.Spec.Source == nil || boot.Spec.Source.VolumeId != "b0001" || *boot.Spec.Source.Mode != VolumeCloneModeCow {
+		t.Fatalf("boot volume = %+v, want a cow clone of b0001", boot)
+	}
+	if api.VolumeID(*boot) != "" || boot.Spec.Path != nil {
+		t.Fatalf("boot volume = %+v, want a new volume", boot.Spec)
+	}
+
+	disks := *clone.Spec.Storage
+	if len(disks) != 1 || disks[0].Spec.Source.VolumeId != "d0001" || disks[0].Metadata.Name != "logs" {
+		t.Fatalf("storage = %+v, want a clone of d0001 named logs", disks)
+	}
+	if shouldResolvePreCreatedStorageVolume(disks[0]) {
+		t.Fatal("a cloned data volume was treated as a pre-created volume")
+	}
+
+	nic := (*clone.Spec.NetworkInterface)[0]
+	if nic.Networkname != "net1" || nic.Address != nil || nic.Mac != nil || nic.IpNetworkId != nil {
+		t.Fatalf("nic = %+v, want the network without address and MAC", nic)
+	}
+	if nic.Vlans == nil || (*nic.Vlans)[0] != 100 {
+		t.Fatalf("vlans = %v, want those of the source", nic.Vlans)
+	}
+}
+
+func TestCloneServerSpecLabels(t *testing.T) {
+	tests := map[string]struct {
+		labels *map[string]string
+		want   map[string]interface{}
+	}{
+		"copied from the source": {nil, map[string]interface{}{"role": "web"}},
+		"replaced":               {&map[string]string{"role": "canary"}, map[string]interface{}{"role": "canary"}},
+		"cleared":                {&map[string]string{}, nil},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			clone, err := cloneServerSpec(newCloneSourceServer(), api.ServerCloneRequest{Name: "web2", Labels: tt.labels})
+			if err != nil {
+				t.Fatalf("cloneServerSpec() error = %v", err)
+			}
+			var got map[string]interface{}
+			if clone.Metadata.Labels != nil {
+				got = *clone.Metadata.Labels
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Fatalf("labels = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloneServerSpecFromSnapshot(t *testing.T) {
+	clone, err := cloneServerSpec(newCloneSourceServer(), api.ServerCloneRequest{Name: "web2", SnapshotId: util.StringPtr("p0001")})
+	if err != nil {
+		t.Fatalf("cloneServerSpec() error = %v", err)
+	}
+	for _, vol := range cloneVolumeRequests(clone) {
+		if util.OrDefault(vol.Spec.Source.SnapshotId, "") != "p0001" || *vol.Spec.Source.Mode != VolumeCloneModeFull {
+			t.Fatalf("source = %+v, want a full clone from snapshot p0001", vol.Spec.Source)
+		}
+	}
+	if got := len(cloneVolumeRequests(clone)); got != 2 {
+		t.Fatalf("volumes = %d, want 2", got)
+	}
+}
+
+func TestCloneServerSpecRejects(t *testing.T) {
+	unprovisioned := newCloneSourceServer()
+	unprovisioned.Spec.BootVolume = nil
+
+	tests := map[string]struct {
+		source api.Server
+		req    api.ServerCloneRequest
+		want   error
+	}{
+		"no name":        {newCloneSourceServer(), api.ServerCloneRequest{}, ErrServerNotClonable},
+		"invalid mode":   {newCloneSourceServer(), api.ServerCloneRequest{Name: "web2", Mode: util.StringPtr("thin")}, ErrVolumeNotClonable},
+		"no boot volume": {unprovisioned, api.ServerCloneRequest{Name: "web2"}, ErrServerCloneSourceNotReady},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := cloneServerSpec(tt.source, tt.req); !errors.Is(err, tt.want) {
+				t.Fatalf("cloneServerSpec() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerCloneErrorStatus(t *testing.T) {
+	tests := map[error]int{
+		ErrServerNotClonable:         http.StatusBadRequest,
+		ErrServerCloneSourceNotReady: http.StatusConflict,
+		ErrVolumeSourceInUse:         http.StatusConflict,
+		ErrVolumeNotClonable:         http.StatusBadRequest,
+		db.ErrNotFound:               http.StatusNotFound,
+	}
+	for err, want := range tests {
+		if got := serverCloneErrorStatus(err); got != want {
+			t.Fatalf("serverCloneErrorStatus(%v) = %d, want %d", err, got, want)
+		}
+	}
+}
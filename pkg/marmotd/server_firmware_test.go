@@ -0,0 +1,129 @@
+package marmotd
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+func TestResolveServerFirmware(t *testing.T) {
+	cases := []struct {
+		name   string
+		server string
+		image  string
+		want   string
+		err    bool
+	}{
+		{name: "default bios", want: virt.FirmwareBIOS},
+		{name: "image requirement", image: "uefi", want: virt.FirmwareUEFI},
+		{name: "server choice", server: "uefi-secure", want: virt.FirmwareUEFISecure},
+		{name: "case insensitive", server: "UEFI", want: virt.FirmwareUEFI},
+		{name: "secure boot on uefi image", server: "uefi-secure", image: "uefi", want: virt.FirmwareUEFISecure},
+		{name: "bios on uefi image", server: "bios", image: "uefi", err: true},
+		{name: "uefi on secure boot image", server: "uefi", image: "uefi-secure", err: true},
+		{name: "uefi on bios image", server: "uefi", image: "bios", err: true},
+		{name: "invalid value", server: "coreboot", err: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveServerFirmware(tc.server, tc.image)
+			if tc.err {
+				if !errors.Is(err, ErrInvalidFirmware) {
+					t.Fatalf("expected ErrInvalidFirmware, got %v (%q)", err, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("firmware = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestServerFirmwareStatePaths(t *testing.T) {
+	qcow := api.Volume{
+		Metadata: api.Metadata{Id: "b0001"},
+		Spec: api.VolSpec{
+			Type: util.StringPtr("qcow2"),
+			Path: util.StringPtr("/var/lib/marmot/volumes/boot-b0001.qcow2"),
+		},
+	}
+	nvram, tpm := serverFirmwareStatePaths(qcow)
+	if nvram != "/var/lib/marmot/volumes/boot-b0001.nvram.fd" {
+		t.Fatalf("nvram = %q", nvram)
+	}
+	if tpm != "/var/lib/marmot/volumes/boot-b0001.tpm" {
+		t.Fatalf("tpm = %q", tpm)
+	}
+
+	lvm := api.Volume{
+		Metadata: api.Metadata{Id: "b0002"},
+		Spec: api.VolSpec{
+			Type: util.StringPtr("lvm"),
+			Path: util.StringPtr("/dev/vg1/boot-b0002"),
+		},
+	}
+	nvram, tpm = serverFirmwareStatePaths(lvm)
+	if nvram != filepath.Join(qcow2VolumeDir, "boot-b0002.nvram.fd") {
+		t.Fatalf("nvram = %q", nvram)
+	}
+	if tpm != filepath.Join(qcow2VolumeDir, "boot-b0002.tpm") {
+		t.Fatalf("tpm = %q", tpm)
+	}
+}
+
+func TestApplyServerFirmware(t *testing.T) {
+	dir := t.TempDir()
+	boot := api.Volume{
+		Metadata: api.Metadata{Id: "b0001"},
+		Spec: api.VolSpec{
+			Type: util.StringPtr("qcow2"),
+			Path: util.StringPtr(filepath.Join(dir, "boot-b0001.qcow2")),
+		},
+	}
+	server := api.Server{Spec: api.ServerSpec{Tpm: util.BoolPtr(true)}}
+	var vs virt.ServerSpec
+	if err := applyServerFirmware(&vs, &server, boot, "uefi-secure"); err != nil {
+		t.Fatalf("applyServerFirmware() error: %v", err)
+	}
+	if util.OrDefault(server.Spec.Firmware, "") != virt.FirmwareUEFISecure || vs.Firmware != virt.FirmwareUEFISecure {
+		t.Fatalf("firmware not recorded: spec=%v virt=%q", server.Spec.Firmware, vs.Firmware)
+	}
+	if vs.NVRAMPath != filepath.Join(dir, "boot-b0001.nvram.fd") {
+		t.Fatalf("NVRAMPath = %q", vs.NVRAMPath)
+	}
+	if !vs.TPM || vs.TPMStateDir != filepath.Join(dir, "boot-b0001.tpm") {
+		t.Fatalf("TPM = %v, TPMStateDir = %q", vs.TPM, vs.TPMStateDir)
+	}
+
+	removeServerFirmwareState(&boot)
+	if m, _ := filepath.Glob(filepath.Join(dir, "*.tpm")); len(m) != 0 {
+		t.Fatalf("TPM state remains: %v", m)
+	}
+}
+
+func TestCheckServerFirmwareUnchanged(t *testing.T) {
+	current := api.ServerSpec{Firmware: util.StringPtr("uefi"), Tpm: util.BoolPtr(true)}
+	if err := checkServerFirmwareUnchanged(current, api.ServerSpec{Cpu: util.IntPtrInt(4)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := checkServerFirmwareUnchanged(current, api.ServerSpec{Firmware: util.StringPtr("UEFI"), Tpm: util.BoolPtr(true)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := checkServerFirmwareUnchanged(current, api.ServerSpec{Firmware: util.StringPtr("bios")}); !errors.Is(err, ErrInvalidFirmware) {
+		t.Fatalf("expected firmware change to be rejected, got %v", err)
+	}
+	if err := checkServerFirmwareUnchanged(current, api.ServerSpec{Tpm: util.BoolPtr(false)}); !errors.Is(err, ErrInvalidFirmware) {
+		t.Fatalf("expected tpm change to be rejected, got %v", err)
+	}
+	if err := checkServerFirmwareUnchanged(api.ServerSpec{}, api.ServerSpec{Firmware: util.StringPtr("bios")}); err != nil {
+		t.Fatalf("unexpected error for legacy server: %v", err)
+	}
+}
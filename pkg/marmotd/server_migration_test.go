@@ -0,0 +1,101 @@
+package marmotd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestMigrationDestURI(t *testing.T) {
+	if got := migrationDestURI("qemu+ssh://%s/system", "10.0.0.2"); got != "qemu+ssh://10.0.0.2/system" {
+		t.Fatalf("migrationDestURI() = %q", got)
+	}
+}
+
+func TestMigrationLocalVolumes(t *testing.T) {
+	t.Run("skips shared storage and builds lvm paths", func(t *testing.T) {
+		vols := []api.Volume{
+			{Metadata: api.Metadata{Id: "boot"}, Spec: api.VolSpec{Kind: util.StringPtr("os"), Path: util.StringPtr("/var/lib/marmot/volumes/boot.qcow2")}},
+			{Metadata: api.Metadata{Id: "data"}, Spec: api.VolSpec{Type: util.StringPtr("lvm"), VolumeGroup: util.StringPtr("vg2"), LogicalVolume: util.StringPtr("lv01")}},
+			{Metadata: api.Metadata{Id: "shared"}, Spec: api.VolSpec{Type: util.StringPtr("lvm"), Iscsi: util.BoolPtr(true)}},
+			{Metadata: api.Metadata{Id: "rbd"}, Spec: api.VolSpec{Type: util.StringPtr("ceph")}},
+		}
+		local, err := migrationLocalVolumes(vols)
+		if err != nil {
+			t.Fatalf("migrationLocalVolumes() error = %v", err)
+		}
+		if len(local) != 2 {
+			t.Fatalf("len(local) = %d, want 2", len(local))
+		}
+		if local[0].VolumeId != "boot" || *local[0].Type != "qcow2" || *local[0].Kind != "os" || *local[0].Path != "/var/lib/marmot/volumes/boot.qcow2" {
+			t.Fatalf("unexpected qcow2 volume: %+v", local[0])
+		}
+		if local[1].VolumeId != "data" || *local[1].Path != "/dev/vg2/lv01" || *local[1].Kind != "data" {
+			t.Fatalf("unexpected lvm volume: %+v", local[1])
+		}
+	})
+
+	t.Run("rejects unsupported volume types", func(t *testing.T) {
+		vols := []api.Volume{{Metadata: api.Metadata{Id: "x"}, Spec: api.VolSpec{Type: util.StringPtr("nfs")}}}
+		if _, err := migrationLocalVolumes(vols); err == nil {
+			t.Fatal("expected error for unsupported volume type")
+		}
+	})
+
+	t.Run("rejects qcow2 volumes without path", func(t *testing.T) {
+		vols := []api.Volume{{Metadata: api.Metadata{Id: "x"}}}
+		if _, err := migrationLocalVolumes(vols); err == nil {
+			t.Fatal("expected error for qcow2 volume without path")
+		}
+	})
+}
+
+func TestMigrationResourceRequest(t *testing.T) {
+	server := api.Server{Spec: api.ServerSpec{Cpu: util.IntPtrInt(4), Memory: util.IntPtrInt(4096)}}
+	vols := []api.Volume{
+		{Metadata: api.Metadata{Id: "boot"}, Spec: api.VolSpec{Type: util.StringPtr("lvm"), Kind: util.StringPtr("os"), Size: util.IntPtrInt(16), VolumeGroup: util.StringPtr("vg1"), LogicalVolume: util.StringPtr("os")}},
+		{Metadata: api.Metadata{Id: "data"}, Spec: api.VolSpec{Type: util.StringPtr("lvm"), Size: util.IntPtrInt(20), VolumeGroup: util.StringPtr("vg2"), LogicalVolume: util.StringPtr("d")}},
+		{Metadata: api.Metadata{Id: "img"}, Spec: api.VolSpec{Size: util.IntPtrInt(8), Path: util.StringPtr("/a.qcow2")}},
+		{Metadata: api.Metadata{Id: "rbd"}, Spec: api.VolSpec{Type: util.StringPtr("ceph"), Size: util.IntPtrInt(100)}},
+	}
+	req := migrationResourceRequest(server, vols)
+	want := ServerResourceRequest{CpuCores: 4, MemoryMB: 4096, OsVolumeGroupGB: 16, DataVolumeGroupGB: 20, VolumeStoreGB: 8}
+	if req != want {
+		t.Fatalf("migrationResourceRequest() = %+v, want %+v", req, want)
+	}
+}
+
+func TestMigrationRuns(t *testing.T) {
+	r := &migrationRuns{runs: map[string]*migrationRun{}}
+	if _, err := r.poll("sv-1"); !errors.Is(err, ErrServerMigrationNotRunning) {
+		t.Fatalf("poll() before start error = %v, want ErrServerMigrationNotRunning", err)
+	}
+
+	release := make(chan struct{})
+	calls := 0
+	run := func() (bool, error) {
+		calls++
+		<-release
+		return true, errors.New("commit failed")
+	}
+	r.start("sv-1", run)
+	r.start("sv-1", run)
+	if _, err := r.poll("sv-1"); !errors.Is(err, ErrServerMigrationInProgress) {
+		t.Fatalf("poll() while running error = %v, want ErrServerMigrationInProgress", err)
+	}
+
+	close(release)
+	<-r.runs["sv-1"].done
+	migrated, err := r.poll("sv-1")
+	if !migrated || err == nil || err.Error() != "commit failed" {
+		t.Fatalf("poll() after completion = (%v, %v), want (true, commit failed)", migrated, err)
+	}
+	if calls != 1 {
+		t.Fatalf("run was called %d times, want 1", calls)
+	}
+	if _, err := r.poll("sv-1"); !errors.Is(err, ErrServerMigrationNotRunning) {
+		t.Fatalf("poll() after the result was taken error = %v, want ErrServerMigrationNotRunning", err)
+	}
+}
@@ -0,0 +1,261 @@
+package marmotd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestValidateServerPerformanceSpec(t *testing.T) {
+	cases := []struct {
+		name   string
+		memory int
+		perf   *api.ServerPerformance
+		err    bool
+	}{
+		{name: "no performance"},
+		{name: "dedicated cpu", perf: &api.ServerPerformance{DedicatedCpu: util.BoolPtr(true)}},
+		{name: "2M hugepages", memory: 4096, perf: &api.ServerPerformance{Hugepages: util.StringPtr("2M")}},
+		{name: "1G hugepages", memory: 8192, perf: &api.ServerPerformance{Hugepages: util.StringPtr("1g")}},
+		{name: "memory not multiple of 1G", memory: 1536, perf: &api.ServerPerformance{Hugepages: util.StringPtr("1G")}, err: true},
+		{name: "unsupported page size", perf: &api.ServerPerformance{Hugepages: util.StringPtr("4K")}, err: true},
+		{name: "numa without dedicated cpu", perf: &api.ServerPerformance{Numa: util.BoolPtr(true)}, err: true},
+		{name: "isolate without dedicated cpu", perf: &api.ServerPerformance{IsolateEmulatorThreads: util.BoolPtr(true)}, err: true},
+		{name: "numa with dedicated cpu", perf: &api.ServerPerformance{DedicatedCpu: util.BoolPtr(true), Numa: util.BoolPtr(true), IsolateEmulatorThreads: util.BoolPtr(true)}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := api.ServerSpec{Performance: tc.perf}
+			if tc.memory > 0 {
+				spec.Memory = util.IntPtrInt(tc.memory)
+			}
+			err := validateServerPerformanceSpec(spec)
+			if tc.err {
+				if !errors.Is(err, ErrInvalidPerformance) {
+					t.Fatalf("expected ErrInvalidPerformance, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckServerPerformanceUnchanged(t *testing.T) {
+	current := api.ServerSpec{
+		Cpu:         util.IntPtrInt(4),
+		Performance: &api.ServerPerformance{DedicatedCpu: util.BoolPtr(true), Hugepages: util.StringPtr("1G")},
+	}
+	if err := checkServerPerformanceUnchanged(current, api.ServerSpec{Memory: util.IntPtrInt(8192)}); err != nil {
+		t.Fatalf("patch without performance: %v", err)
+	}
+	same := api.ServerSpec{
+		Cpu:         util.IntPtrInt(4),
+		Performance: &api.ServerPerformance{DedicatedCpu: util.BoolPtr(true), Numa: util.BoolPtr(false), Hugepages: util.StringPtr("1g")},
+	}
+	if err := checkServerPerformanceUnchanged(current, same); err != nil {
+		t.Fatalf("patch with same values: %v", err)
+	}
+	changed := api.ServerSpec{Performance: &api.ServerPerformance{Hugepages: util.StringPtr("2M")}}
+	if err := checkServerPerformanceUnchanged(current, changed); !errors.Is(err, ErrInvalidPerformance) {
+		t.Fatalf("changing performance: expected ErrInvalidPerformance, got %v", err)
+	}
+	if err := checkServerPerformanceUnchanged(current, api.ServerSpec{Cpu: util.IntPtrInt(8)}); !errors.Is(err, ErrInvalidPerformance) {
+		t.Fatalf("changing cpu of dedicated server: expected ErrInvalidPerformance, got %v", err)
+	}
+	shared := api.ServerSpec{Cpu: util.IntPtrInt(2)}
+	if err := checkServerPerformanceUnchanged(shared, api.ServerSpec{Cpu: util.IntPtrInt(8)}); err != nil {
+		t.Fatalf("changing cpu of shared server: %v", err)
+	}
+}
+
+func numaNode(id int, cpus []int, free2M, free1G int) api.HostNumaNode {
+	return api.HostNumaNode{
+		Id:              util.IntPtrInt(id),
+		Cpus:            &cpus,
+		Hugepages2MFree: util.IntPtrInt(free2M),
+		Hugepages1GFree: util.IntPtrInt(free1G),
+	}
+}
+
+func TestAssignPinnedCPUs(t *testing.T) {
+	pool := []int{2, 3, 4, 5, 10, 11, 12, 13}
+	nodes := []api.HostNumaNode{
+		numaNode(0, []int{0, 1, 2, 3, 4, 5, 6, 7}, 0, 4),
+		numaNode(1, []int{8, 9, 10, 11, 12, 13, 14, 15}, 0, 0),
+	}
+
+	t.Run("best fit node", func(t *testing.T) {
+		used := map[int]bool{10: true}
+		a, err := assignPinnedCPUs(pool, used, nodes, 2, false, false, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a.VCPUs, []int{11, 12}) || a.Emulator != nil || a.NUMANode != nil {
+			t.Fatalf("assignment = %+v, want vcpus [11 12] on node 1 without numa node", a)
+		}
+	})
+
+	t.Run("isolated emulator thread", func(t *testing.T) {
+		a, err := assignPinnedCPUs(pool, nil, nodes, 2, true, true, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a.VCPUs, []int{2, 3}) || !reflect.DeepEqual(a.Emulator, []int{4}) {
+			t.Fatalf("assignment = %+v", a)
+		}
+		if a.NUMANode == nil || *a.NUMANode != 0 {
+			t.Fatalf("NUMANode = %v, want 0", a.NUMANode)
+		}
+	})
+
+	t.Run("node with free hugepages", func(t *testing.T) {
+		a, err := assignPinnedCPUs(pool, map[int]bool{2: true}, nodes, 2, false, true, 1048576, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.NUMANode == nil || *a.NUMANode != 0 || !reflect.DeepEqual(a.VCPUs, []int{3, 4}) {
+			t.Fatalf("assignment = %+v, want vcpus [3 4] on node 0", a)
+		}
+		if _, err := assignPinnedCPUs(pool, nil, nodes, 2, false, true, 1048576, 8); !errors.Is(err, ErrInsufficientPinnedCPUs) {
+			t.Fatalf("expected ErrInsufficientPinnedCPUs for hugepages, got %v", err)
+		}
+	})
+
+	t.Run("spans nodes without numa", func(t *testing.T) {
+		a, err := assignPinnedCPUs(pool, nil, nodes, 6, false, false, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(a.VCPUs, []int{2, 3, 4, 5, 10, 11}) {
+			t.Fatalf("VCPUs = %v", a.VCPUs)
+		}
+		if _, err := assignPinnedCPUs(pool, nil, nodes, 6, false, true, 0, 0); !errors.Is(err, ErrInsufficientPinnedCPUs) {
+			t.Fatalf("expected ErrInsufficientPinnedCPUs with numa, got %v", err)
+		}
+	})
+
+	t.Run("pool exhausted", func(t *testing.T) {
+		if _, err := assignPinnedCPUs(pool, nil, nil, 9, false, false, 0, 0); !errors.Is(err, ErrInsufficientPinnedCPUs) {
+			t.Fatalf("expected ErrInsufficientPinnedCPUs, got %v", err)
+		}
+	})
+}
+
+func TestAllocatedPinnedCPUs(t *testing.T) {
+	servers := []api.Server{
+		{Metadata: api.Metadata{Id: "a", NodeName: util.StringPtr("hv1")}, Status: &api.Status{Performance: &api.ServerPerformanceStatus{
+			VcpuPins: &[]int{4, 5}, EmulatorCpus: &[]int{6},
+		}}},
+		{Metadata: api.Metadata{Id: "b", NodeName: util.StringPtr("hv2")}, Status: &api.Status{Performance: &api.ServerPerformanceStatus{VcpuPins: &[]int{7}}}},
+		{Metadata: api.Metadata{Id: "c", NodeName: util.StringPtr("hv1")}, Status: &api.Status{Performance: &api.ServerPerformanceStatus{VcpuPins: &[]int{8}}}},
+		{Metadata: api.Metadata{Id: "d", NodeName: util.StringPtr("hv1")}},
+	}
+	got := allocatedPinnedCPUs(servers, "hv1", "c")
+	want := map[int]bool{4: true, 5: true, 6: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("allocatedPinnedCPUs() = %v, want %v", got, want)
+	}
+}
+
+func TestSharedCPUSetOf(t *testing.T) {
+	status := api.HostStatus{
+		Capacity:    &api.HostCapacity{CpuCores: util.IntPtrInt(16)},
+		Performance: &api.HostPerformance{PinnedCpus: &[]int{4, 5, 6, 7, 12, 13, 14, 15}},
+	}
+	if got := sharedCPUSetOf(status); got != "0-3,8-11" {
+		t.Fatalf("sharedCPUSetOf() = %q, want 0-3,8-11", got)
+	}
+	if got := sharedCPUSetOf(api.HostStatus{Capacity: status.Capacity}); got != "" {
+		t.Fatalf("sharedCPUSetOf() without pool = %q, want empty", got)
+	}
+}
+
+func TestHostNUMANodes(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("devices/system/node/node1/cpulist", "8-15\n")
+	write("devices/system/node/node1/meminfo", "Node 1 MemTotal:       16777216 kB\nNode 1 MemFree:        8388608 kB\n")
+	write("devices/system/node/node0/cpulist", "0-7\n")
+	write("devices/system/node/node0/meminfo", "Node 0 MemTotal:       8388608 kB\n")
+	write("devices/system/node/node0/hugepages/hugepages-2048kB/nr_hugepages", "512\n")
+	write("devices/system/node/node0/hugepages/hugepages-2048kB/free_hugepages", "256\n")
+	write("devices/system/node/node0/hugepages/hugepages-1048576kB/nr_hugepages", "4\n")
+	write("devices/system/node/node0/hugepages/hugepages-1048576kB/free_hugepages", "3\n")
+
+	nodes, err := hostNUMANodes(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || *nodes[0].Id != 0 || *nodes[1].Id != 1 {
+		t.Fatalf("nodes = %+v", nodes)
+	}
+	n0 := nodes[0]
+	if !reflect.DeepEqual(*n0.Cpus, []int{0, 1, 2, 3, 4, 5, 6, 7}) || *n0.MemoryMB != 8192 {
+		t.Fatalf("node0 = cpus %v memory %d", *n0.Cpus, *n0.MemoryMB)
+	}
+	if *n0.Hugepages2MTotal != 512 || *n0.Hugepages2MFree != 256 || *n0.Hugepages1GTotal != 4 || *n0.Hugepages1GFree != 3 {
+		t.Fatalf("node0 hugepages = %+v", n0)
+	}
+	if *nodes[1].MemoryMB != 16384 || *nodes[1].Hugepages1GFree != 0 {
+		t.Fatalf("node1 = %+v", nodes[1])
+	}
+
+	empty, err := hostNUMANodes(t.TempDir())
+	if err != nil || len(empty) != 0 {
+		t.Fatalf("hostNUMANodes(empty) = %v, %v", empty, err)
+	}
+}
+
+func TestValidateServerUpdateKeepsPerformanceImmutable(t *testing.T) {
+	current := api.Server{Spec: api.ServerSpec{
+		Cpu:         util.IntPtrInt(2),
+		Memory:      util.IntPtrInt(4096),
+		Performance: &api.ServerPerformance{DedicatedCpu: util.BoolPtr(true)},
+	}}
+	patch := api.Server{Spec: api.ServerSpec{
+		CloudInit: &api.ServerCloudInit{UserData: util.StringPtr("#cloud-config\npackages: [htop]\n")},
+		Performance: &api.ServerPerformance{
+			DedicatedCpu: util.BoolPtr(true),
+			Numa:         util.BoolPtr(true),
+			Hugepages:    util.StringPtr("2M"),
+		},
+	}}
+	if err := validateServerUpdate(current, patch); !errors.Is(err, ErrInvalidPerformance) {
+		t.Fatalf("cloudInit with performance: expected ErrInvalidPerformance, got %v", err)
+	}
+	if current.Spec.Performance.Numa != nil || current.Spec.Performance.Hugepages != nil {
+		t.Fatalf("current performance was modified: %+v", *current.Spec.Performance)
+	}
+
+	current.Spec.Performance.Hugepages = util.StringPtr("1G")
+	resize := api.Server{Spec: api.ServerSpec{
+		CloudInit: &api.ServerCloudInit{UserData: util.StringPtr("#cloud-config\n")},
+		Memory:    util.IntPtrInt(1536),
+	}}
+	if err := validateServerUpdate(current, resize); !errors.Is(err, ErrInvalidPerformance) {
+		t.Fatalf("memory not multiple of hugepage size: expected ErrInvalidPerformance, got %v", err)
+	}
+	if *current.Spec.Memory != 4096 || current.Spec.CloudInit != nil {
+		t.Fatalf("current spec was modified: memory=%d cloudInit=%v", *current.Spec.Memory, current.Spec.CloudInit)
+	}
+	if err := validateServerUpdate(current, api.Server{Spec: api.ServerSpec{Memory: util.IntPtrInt(8192)}}); err != nil {
+		t.Fatalf("valid resize: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package marmotd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShutdownDeadlines(t *testing.T) {
+	s := &shutdownDeadlines{deadlines: map[string]time.Time{}}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if requested, expired := s.check("sv-1", now); requested || expired {
+		t.Fatalf("check() before start = (%v, %v), want (false, false)", requested, expired)
+	}
+
+	s.start("sv-1", now.Add(2*time.Minute))
+	if requested, expired := s.check("sv-1", now.Add(time.Minute)); !requested || expired {
+		t.Fatalf("check() before deadline = (%v, %v), want (true, false)", requested, expired)
+	}
+	if requested, expired := s.check("sv-1", now.Add(2*time.Minute)); !requested || !expired {
+		t.Fatalf("check() at deadline = (%v, %v), want (true, true)", requested, expired)
+	}
+	if requested, _ := s.check("sv-2", now); requested {
+		t.Fatalf("check() for another server = requested, want not requested")
+	}
+
+	s.clear("sv-1")
+	if requested, _ := s.check("sv-1", now.Add(3*time.Minute)); requested {
+		t.Fatalf("check() after clear = requested, want not requested")
+	}
+}
@@ -0,0 +1,94 @@
+package marmotd
+
+import (
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestNormalizeSnapshotConsistency(t *testing.T) {
+	t.Run("defaults to none when unset", func(t *testing.T) {
+		got, err := normalizeSnapshotConsistency(nil)
+		if err != nil || got != SnapshotConsistencyNone {
+			t.Fatalf("normalizeSnapshotConsistency(nil) = %q, %v; want %q, nil", got, err, SnapshotConsistencyNone)
+		}
+	})
+
+	t.Run("accepts known values case-insensitively", func(t *testing.T) {
+		got, err := normalizeSnapshotConsistency(util.StringPtr(" Stop "))
+		if err != nil || got != SnapshotConsistencyStop {
+			t.Fatalf("normalizeSnapshotConsistency(Stop) = %q, %v; want %q, nil", got, err, SnapshotConsistencyStop)
+		}
+	})
+
+	t.Run("rejects unknown values", func(t *testing.T) {
+		if _, err := normalizeSnapshotConsistency(util.StringPtr("freeze")); err == nil {
+			t.Fatal("normalizeSnapshotConsistency(freeze) error = nil, want error")
+		}
+	})
+}
+
+func TestValidateSnapshotVolumes(t *testing.T) {
+	qcow2Vol := api.Volume{
+		Metadata: api.Metadata{Id: "q0001"},
+		Spec:     api.VolSpec{Type: util.StringPtr("qcow2"), Path: util.StringPtr("/var/lib/marmot/volumes/boot-q0001.qcow2")},
+	}
+	lvmVol := api.Volume{
+		Metadata: api.Metadata{Id: "l0001"},
+		Spec:     api.VolSpec{Type: util.StringPtr("lvm"), VolumeGroup: util.StringPtr("vg1"), LogicalVolume: util.StringPtr("datalv0001")},
+	}
+
+	t.Run("qcow2 on running server requires stop", func(t *testing.T) {
+		if err := validateSnapshotVolumes([]api.Volume{qcow2Vol}, SnapshotConsistencyQuiesce, true); err == nil {
+			t.Fatal("validateSnapshotVolumes() error = nil, want error")
+		}
+		if err := validateSnapshotVolumes([]api.Volume{qcow2Vol}, SnapshotConsistencyStop, true); err != nil {
+			t.Fatalf("validateSnapshotVolumes() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("qcow2 on stopped server accepts none", func(t *testing.T) {
+		if err := validateSnapshotVolumes([]api.Volume{qcow2Vol}, SnapshotConsistencyNone, false); err != nil {
+			t.Fatalf("validateSnapshotVolumes() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("lvm on running server accepts none", func(t *testing.T) {
+		if err := validateSnapshotVolumes([]api.Volume{lvmVol}, SnapshotConsistencyNone, true); err != nil {
+			t.Fatalf("validateSnapshotVolumes() error = %v, want nil", err)
+		}
+	})
+
+	t.Run("rejects lvm os volumes", func(t *testing.T) {
+		osVol := lvmVol
+		osVol.Spec.Kind = util.StringPtr("os")
+		if err := validateSnapshotVolumes([]api.Volume{osVol}, SnapshotConsistencyStop, false); err == nil {
+			t.Fatal("validateSnapshotVolumes(lvm os) error = nil, want error")
+		}
+	})
+
+	t.Run("rejects ceph and iscsi volumes", func(t *testing.T) {
+		ceph := api.Volume{Metadata: api.Metadata{Id: "c0001"}, Spec: api.VolSpec{Type: util.StringPtr("ceph")}}
+		if err := validateSnapshotVolumes([]api.Volume{ceph}, SnapshotConsistencyStop, false); err == nil {
+			t.Fatal("validateSnapshotVolumes(ceph) error = nil, want error")
+		}
+		iscsi := lvmVol
+		iscsi.Spec.Iscsi = util.BoolPtr(true)
+		if err := validateSnapshotVolumes([]api.Volume{iscsi}, SnapshotConsistencyStop, false); err == nil {
+			t.Fatal("validateSnapshotVolumes(iscsi) error = nil, want error")
+		}
+	})
+}
+
+func TestSnapshotNaming(t *testing.T) {
+	if got := qcow2SnapshotName("ab123"); got != "marmot-ab123" {
+		t.Fatalf("qcow2SnapshotName() = %q", got)
+	}
+	if got := lvmSnapshotName("oslv0001", "ab123"); got != "oslv0001-snap-ab123" {
+		t.Fatalf("lvmSnapshotName() = %q", got)
+	}
+	if got := snapshotSizeInBytes(api.ServerSnapshotVolume{Size: util.IntPtrInt(2)}); got != 2*1024*1024*1024 {
+		t.Fatalf("snapshotSizeInBytes() = %d", got)
+	}
+}
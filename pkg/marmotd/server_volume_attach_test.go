@@ -0,0 +1,95 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestValidateVolumeAttach(t *testing.T) {
+	newServer := func(code int, node string, storage ...api.Volume) api.Server {
+		server := api.Server{
+			Metadata: api.Metadata{NodeName: util.StringPtr(node)},
+			Status:   &api.Status{StatusCode: code},
+		}
+		if len(storage) > 0 {
+			server.Spec.Storage = &storage
+		}
+		return server
+	}
+	newVolume := func(id, volType, node string, iscsi bool) api.Volume {
+		vol := api.Volume{
+			Metadata: api.Metadata{NodeName: util.StringPtr(node)},
+			Spec: api.VolSpec{
+				Kind:  util.StringPtr("data"),
+				Type:  util.StringPtr(volType),
+				Iscsi: util.BoolPtr(iscsi),
+			},
+			Status: &api.Status{StatusCode: db.VOLUME_AVAILABLE},
+		}
+		api.SetVolumeID(&vol, id)
+		return vol
+	}
+
+	osVolume := newVolume("11111", "qcow2", "hv1", false)
+	osVolume.Spec.Kind = util.StringPtr("os")
+	provisioning := newVolume("11111", "qcow2", "hv1", false)
+	provisioning.Status.StatusCode = db.VOLUME_PROVISIONING
+
+	tests := []struct {
+		name   string
+		server api.Server
+		vol    api.Volume
+		want   error
+	}{
+		{"qcow2 on the same node", newServer(db.SERVER_RUNNING, "hv1"), newVolume("11111", "qcow2", "hv1", false), nil},
+		{"stopped server", newServer(db.SERVER_STOPPED, "hv1"), newVolume("11111", "lvm", "hv1", false), nil},
+		{"iscsi volume on another node", newServer(db.SERVER_RUNNING, "hv1"), newVolume("11111", "lvm", "hv2", true), nil},
+		{"ceph volume on another node", newServer(db.SERVER_RUNNING, "hv1"), newVolume("11111", "ceph", "hv2", false), nil},
+		{"qcow2 on another node", newServer(db.SERVER_RUNNING, "hv1"), newVolume("11111", "qcow2", "hv2", false), ErrVolumeNotAttachable},
+		{"local lvm on another node", newServer(db.SERVER_RUNNING, "hv1"), newVolume("11111", "lvm", "hv2", false), ErrVolumeNotAttachable},
+		{"os volume", newServer(db.SERVER_RUNNING, "hv1"), osVolume, ErrVolumeNotAttachable},
+		{"volume not available", newServer(db.SERVER_RUNNING, "hv1"), provisioning, ErrVolumeNotAttachable},
+		{"provisioning server", newServer(db.SERVER_PROVISIONING, "hv1"), newVolume("11111", "qcow2", "hv1", false), ErrServerVolumeState},
+		{"already in storage", newServer(db.SERVER_RUNNING, "hv1", newVolume("11111", "qcow2", "hv1", false)), newVolume("11111", "qcow2", "hv1", false), ErrVolumeAlreadyInSpec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateVolumeAttach(tt.server, tt.vol)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("validateVolumeAttach() error = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("validateVolumeAttach() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServerVolumeErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{db.ErrNotFound, http.StatusNotFound},
+		{ErrVolumeNotInServerSpec, http.StatusNotFound},
+		{fmt.Errorf("%w: os volume", ErrVolumeNotAttachable), http.StatusBadRequest},
+		{fmt.Errorf("%w: 22222", db.ErrVolumeInUse), http.StatusConflict},
+		{ErrServerVolumeState, http.StatusConflict},
+		{db.ErrResourceVersionConflict, http.StatusConflict},
+		{errors.New("etcd unavailable"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := serverVolumeErrorStatus(tt.err); got != tt.want {
+			t.Errorf("serverVolumeErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
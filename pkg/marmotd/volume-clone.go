@@ -0,0 +1,438 @@
+package marmotd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/ceph"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/lvm"
+	"github.com/takara9/marmot/pkg/qcow"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+// ボリュームの複製
+// API が spec.source を検証してジョブとともに登録し、ボリュームのノードのボリュームコントローラーが CloneVolume で実体を複製する
+// qcow2 と LVM の実体はノードに閉じているため、複製先は複製元と同じノードになる
+
+const (
+	VolumeCloneModeFull = "full" // 全データをコピーする
+	VolumeCloneModeCow  = "cow"  // 変更の無いデータを複製元と共有する
+)
+
+var (
+	ErrVolumeNotClonable      = errors.New("volume cannot be cloned")
+	ErrVolumeSourceNotReady   = errors.New("clone source is not AVAILABLE")
+	ErrVolumeSourceInUse      = errors.New("source volume is in use by a server that is not STOPPED")
+	ErrVolumeCloneInterrupted = errors.New("volume clone was interrupted")
+)
+
+// normalizeVolumeCloneMode は mode の指定を検証し、未指定なら full を返す
+func normalizeVolumeCloneMode(v *string) (string, error) {
+	if v == nil || strings.TrimSpace(*v) == "" {
+		return VolumeCloneModeFull, nil
+	}
+	mode := strings.ToLower(strings.TrimSpace(*v))
+	switch mode {
+	case VolumeCloneModeFull, VolumeCloneModeCow:
+		return mode, nil
+	}
+	return "", fmt.Errorf("%w: invalid mode %q: must be full or cow", ErrVolumeNotClonable, *v)
+}
+
+// isCowClone はボリュームがコピーオンライトのクローンかを返す
+func isCowClone(vol api.Volume) bool {
+	return vol.Spec.Source != nil && util.OrDefault(vol.Spec.Source.Mode, "") == VolumeCloneModeCow
+}
+
+// isLVMSnapshotVolume はボリュームの実体が LVM スナップショットかを返す
+// LVM の OS ボリュームはイメージの、コピーオンライトのクローンは複製元のスナップショットとして作成される
+func isLVMSnapshotVolume(vol api.Volume) bool {
+	if strings.TrimSpace(util.OrDefault(vol.Spec.Type, "qcow2")) != "lvm" {
+		return false
+	}
+	return volumeKindOrDefault(vol.Spec) == "os" || isCowClone(vol)
+}
+
+// cloneSnapshotName は Ceph のクローンの親として複製元に作成するスナップショット名
+func cloneSnapshotName(volumeID string) string {
+	return "clone-" + volumeID
+}
+
+// snapshotVolume はサーバースナップショットが取得したボリュームを返す
+func snapshotVolume(snap api.ServerSnapshot, volumeID string) (api.ServerSnapshotVolume, bool) {
+	if snap.Spec.Volumes == nil {
+		return api.ServerSnapshotVolume{}, false
+	}
+	for _, sv := range *snap.Spec.Volumes {
+		if sv.VolumeId == volumeID {
+			return sv, true
+		}
+	}
+	return api.ServerSnapshotVolume{}, false
+}
+
+// prepareVolumeClone は複製の要求を検証し、指定の無い項目を複製元から補う
+// sv はサーバースナップショットから複製する場合の、スナップショットが取得したボリューム
+func prepareVolumeClone(req *api.Volume, source api.Volume, sv *api.ServerSnapshotVolume) error {
+	mode, err := normalizeVolumeCloneMode(req.Spec.Source.Mode)
+	if err != nil {
+		return err
+	}
+	sourceID := api.VolumeID(source)
+	if source.Status == nil || source.Status.StatusCode != db.VOLUME_AVAILABLE {
+		return fmt.Errorf("%w: volume %s", ErrVolumeSourceNotReady, sourceID)
+	}
+
+	volType := strings.TrimSpace(util.OrDefault(source.Spec.Type, "qcow2"))
+	if t := strings.TrimSpace(util.OrDefault(req.Spec.Type, "")); t != "" && !strings.EqualFold(t, volType) {
+		return fmt.Errorf("%w: type %s differs from the %s source volume", ErrVolumeNotClonable, t, volType)
+	}
+	switch volType {
+	case "qcow2", "lvm", "ceph":
+	default:
+		return fmt.Errorf("%w: unsupported volume type %s", ErrVolumeNotClonable, volType)
+	}
+	kind := volumeKindOrDefault(source.Spec)
+	if k := strings.TrimSpace(util.OrDefault(req.Spec.Kind, "")); k != "" && k != kind {
+		return fmt.Errorf("%w: kind %s differs from the %s source volume", ErrVolumeNotClonable, k, kind)
+	}
+
+	sourceSize := util.OrDefault(source.Spec.Size, 0)
+	if sv != nil {
+		sourceSize = util.OrDefault(sv.Size, sourceSize)
+	}
+	size := util.OrDefault(req.Spec.Size, 0)
+	if size <= 0 {
+		size = sourceSize
+	}
+	if size < sourceSize {
+		return fmt.Errorf("%w: size %dGB is smaller than the source size %dGB", ErrVolumeNotClonable, size, sourceSize)
+	}
+
+	if mode == VolumeCloneModeCow {
+		switch {
+		case sv != nil:
+			return fmt.Errorf("%w: copy-on-write clones of server snapshots are not supported", ErrVolumeNotClonable)
+		case volType == "qcow2":
+			return fmt.Errorf("%w: copy-on-write clones are supported for lvm and ceph volumes", ErrVolumeNotClonable)
+		case volType == "lvm" && isLVMSnapshotVolume(source):
+			return fmt.Errorf("%w: volume %s is an lvm snapshot and cannot be snapshotted again", ErrVolumeNotClonable, sourceID)
+		case volType == "lvm" && size != sourceSize:
+			return fmt.Errorf("%w: copy-on-write clones of lvm volumes keep the source size %dGB", ErrVolumeNotClonable, sourceSize)
+		}
+	}
+
+	// qcow2 と LVM の実体は複製元のノードにしか無い
+	if volType != "ceph" {
+		sourceNode := strings.TrimSpace(util.OrDefault(source.Metadata.NodeName, ""))
+		node := strings.TrimSpace(util.OrDefault(req.Metadata.NodeName, ""))
+		if node != "" && sourceNode != "" && node != sourceNode {
+			return fmt.Errorf("%w: %s volume on node %s cannot be cloned to node %s", ErrVolumeNotClonable, volType, sourceNode, node)
+		}
+		if node == "" && sourceNode != "" {
+			req.Metadata.NodeName = util.StringPtr(sourceNode)
+		}
+	}
+
+	req.Spec.Type = util.StringPtr(volType)
+	req.Spec.Kind = util.StringPtr(kind)
+	req.Spec.Size = util.IntPtrInt(size)
+	if req.Spec.OsVariant == nil {
+		req.Spec.OsVariant = source.Spec.OsVariant
+	}
+	if volType == "ceph" && strings.TrimSpace(util.OrDefault(req.Spec.StorageClass, "")) == "" {
+		req.Spec.StorageClass = source.Spec.StorageClass
+	}
+	req.Spec.Source.VolumeId = sourceID
+	req.Spec.Source.Mode = util.StringPtr(mode)
+	return nil
+}
+
+// needsStoppedSource は複製中に複製元のデータが変わらないよう、使用しているサーバーの停止が必要かを返す
+// LVM と Ceph のコピーオンライトのクローン、Ceph の全コピー、スナップショットからの複製は、取得時点のデータを読み出す
+func needsStoppedSource(vol api.Volume) bool {
+	if vol.Spec.Source == nil || util.OrDefault(vol.Spec.Source.SnapshotId, "") != "" {
+		return false
+	}
+	switch strings.TrimSpace(util.OrDefault(vol.Spec.Type, "qcow2")) {
+	case "qcow2":
+		return true
+	case "lvm":
+		return !isCowClone(vol)
+	}
+	return false
+}
+
+// volumeCloneDependents は volumeID または snapshotID を複製元とするボリュームのうち、
+// 複製元を削除できなくするもののIDを返す
+// コピーオンライトのクローンは複製元のデータを参照し続け、複製中のボリュームは複製元を読み出している
+func volumeCloneDependents(vols []api.Volume, volumeID, snapshotID string) []string {
+	var ids []string
+	for _, vol := range vols {
+		src := vol.Spec.Source
+		if src == nil {
+			continue
+		}
+		inProgress := vol.Status != nil && (vol.Status.StatusCode == db.VOLUME_PENDING || vol.Status.StatusCode == db.VOLUME_CLONING)
+		switch {
+		case volumeID != "" && src.VolumeId == volumeID && (inProgress || isCowClone(vol)):
+		case snapshotID != "" && util.OrDefault(src.SnapshotId, "") == snapshotID && inProgress:
+		default:
+			continue
+		}
+		ids = append(ids, api.VolumeID(vol))
+	}
+	return ids
+}
+
+// VolumeCloneDependents は volumeID または snapshotID を複製元とし、複製元の削除を妨げるボリュームのIDを返す
+func (m *Marmot) VolumeCloneDependents(volumeID, snapshotID string) ([]string, error) {
+	vols, err := m.GetVolumes()
+	if err != nil {
+		return nil, err
+	}
+	return volumeCloneDependents(vols, volumeID, snapshotID), nil
+}
+
+// resolveVolumeCloneSource は複製元のボリュームと、サーバースナップショットから複製する場合はスナップショットが取得したボリュームを返す
+func (m *Marmot) resolveVolumeCloneSource(src api.VolumeSource) (api.Volume, *api.ServerSnapshotVolume, error) {
+	source, err := m.Db.GetVolumeById(strings.TrimSpace(src.VolumeId))
+	if err != nil {
+		return api.Volume{}, nil, fmt.Errorf("source volume %s: %w", src.VolumeId, err)
+	}
+	snapshotID := strings.TrimSpace(util.OrDefault(src.SnapshotId, ""))
+	if snapshotID == "" {
+		return source, nil, nil
+	}
+	snap, err := m.Db.GetServerSnapshotById(snapshotID)
+	if err != nil {
+		return api.Volume{}, nil, fmt.Errorf("server snapshot %s: %w", snapshotID, err)
+	}
+	if snap.Status == nil || snap.Status.StatusCode != db.SNAPSHOT_AVAILABLE {
+		return api.Volume{}, nil, fmt.Errorf("%w: server snapshot %s", ErrVolumeSourceNotReady, snapshotID)
+	}
+	sv, ok := snapshotVolume(snap, api.VolumeID(source))
+	if !ok {
+		return api.Volume{}, nil, fmt.Errorf("%w: server snapshot %s does not contain volume %s", ErrVolumeNotClonable, snapshotID, api.VolumeID(source))
+	}
+	return source, &sv, nil
+}
+
+// volumeUserServer は停止していないサーバーのうち、ボリュームをブートボリュームか storage に持つもののIDを返す
+func (m *Marmot) volumeUserServer(volumeID string) (string, error) {
+	servers, err := m.Db.GetServers()
+	if err != nil {
+		return "", err
+	}
+	for _, server := range servers {
+		if server.Status != nil && server.Status.StatusCode == db.SERVER_STOPPED {
+			continue
+		}
+		boot := server.Spec.BootVolume != nil && api.VolumeID(*server.Spec.BootVolume) == volumeID
+		if boot || serverStorageIndex(server, volumeID) >= 0 {
+			return api.ServerID(server), nil
+		}
+	}
+	return "", nil
+}
+
+// PrepareVolumeClone は spec.source を持つボリュームの作成要求を検証し、複製先の指定を補う
+// API ハンドラーから呼び出される
+func (m *Marmot) PrepareVolumeClone(req *api.Volume) error {
+	source, sv, err := m.resolveVolumeCloneSource(*req.Spec.Source)
+	if err != nil {
+		return err
+	}
+	if err := prepareVolumeClone(req, source, sv); err != nil {
+		return err
+	}
+	if needsStoppedSource(*req) {
+		serverID, err := m.volumeUserServer(api.VolumeID(source))
+		if err != nil {
+			return err
+		}
+		if serverID != "" {
+			return fmt.Errorf("%w: server %s uses volume %s; stop it, clone a server snapshot, or use a copy-on-write clone", ErrVolumeSourceInUse, serverID, api.VolumeID(source))
+		}
+	}
+	return nil
+}
+
+// CloneVolume は spec.source からボリュームの実体を複製して、ボリュームを AVAILABLE にする
+// ボリュームコントローラーからジョブとして呼び出され、ジョブのキャンセルで ctx が終了する
+func (m *Marmot) CloneVolume(ctx context.Context, id string) error {
+	vol, err := m.Db.GetVolumeById(id)
+	if err != nil {
+		return err
+	}
+	if vol.Spec.Source == nil {
+		return fmt.Errorf("volume %s has no source", id)
+	}
+	source, sv, err := m.resolveVolumeCloneSource(*vol.Spec.Source)
+	if err == nil {
+		JobLogf(ctx, "cloning volume %s into %s (%s)", api.VolumeID(source), id, util.OrDefault(vol.Spec.Source.Mode, VolumeCloneModeFull))
+		err = m.cloneVolumeBackingStore(ctx, &vol, source, sv)
+	}
+	if err != nil {
+		slog.Error("cloneVolumeBackingStore()", "err", err, "volId", id)
+		m.Db.UpdateVolumeStatusMessage(id, db.VOLUME_ERROR, fmt.Sprintf("ボリュームの複製に失敗: %v", err))
+		return err
+	}
+
+	vol.Status.Message = nil
+	vol.Status.StatusCode = db.VOLUME_AVAILABLE
+	vol.Status.Status = util.StringPtr(db.VolStatus[db.VOLUME_AVAILABLE])
+	vol.Status.LastUpdateTimeStamp = util.TimePtr(time.Now())
+	if err := m.Db.UpdateVolume(id, vol); err != nil {
+		slog.Error("UpdateVolume()", "err", err, "volId", id)
+		return err
+	}
+	slog.Info("volume cloned", "volId", id, "sourceVolId", api.VolumeID(source))
+	return nil
+}
+
+// cloneVolumeBackingStore はボリュームのタイプと複製の方法に応じて実体を複製する
+// 実体の場所が複製の方法で決まる場合は vol の spec と status を更新する
+func (m *Marmot) cloneVolumeBackingStore(ctx context.Context, vol *api.Volume, source api.Volume, sv *api.ServerSnapshotVolume) error {
+	size := util.OrDefault(vol.Spec.Size, 0)
+	sizeInBytes := uint64(size) * 1024 * 1024 * 1024
+	if vol.Status == nil {
+		vol.Status = &api.Status{}
+	}
+
+	switch strings.TrimSpace(util.OrDefault(vol.Spec.Type, "qcow2")) {
+	case "qcow2":
+		dst := strings.TrimSpace(util.OrDefault(vol.Spec.Path, ""))
+		src := strings.TrimSpace(util.OrDefault(source.Spec.Path, ""))
+		if dst == "" || src == "" {
+			return errors.New("path is required for qcow2")
+		}
+		// 複製元の内部スナップショットは複製先に持ち込まない
+		baseSize := util.OrDefault(source.Spec.Size, 0)
+		snapshotName := ""
+		if sv != nil {
+			baseSize = util.OrDefault(sv.Size, baseSize)
+			snapshotName = util.OrDefault(sv.SnapshotName, "")
+		}
+		if err := qcow.ConvertQcowWithContext(ctx, src, snapshotName, dst); err != nil {
+			return err
+		}
+		if size > baseSize {
+			return qcow.ResizeQcow(dst, size)
+		}
+		return nil
+	case "lvm":
+		vg := strings.TrimSpace(util.OrDefault(vol.Spec.VolumeGroup, ""))
+		lv := strings.TrimSpace(util.OrDefault(vol.Spec.LogicalVolume, ""))
+		srcVg := strings.TrimSpace(util.OrDefault(source.Spec.VolumeGroup, ""))
+		srcLv := strings.TrimSpace(util.OrDefault(source.Spec.LogicalVolume, ""))
+		if sv != nil {
+			srcVg = strings.TrimSpace(util.OrDefault(sv.VolumeGroup, srcVg))
+			srcLv = strings.TrimSpace(util.OrDefault(sv.SnapshotName, ""))
+		}
+		if vg == "" || lv == "" || srcVg == "" || srcLv == "" {
+			return errors.New("volumeGroup and logicalVolume are required for lvm")
+		}
+		if isCowClone(*vol) {
+			// スナップショットは複製元と同じボリュームグループに作成される
+			// COW 領域は複製元と同じサイズを確保し、容量不足で無効化されないようにする
+			vol.Spec.VolumeGroup = util.StringPtr(srcVg)
+			vol.Spec.Path = util.StringPtr(fmt.Sprintf("/dev/%s/%s", srcVg, lv))
+			if err := m.Db.UpdateVolume(api.VolumeID(*vol), api.Volume{Spec: api.VolSpec{VolumeGroup: vol.Spec.VolumeGroup, Path: vol.Spec.Path}}); err != nil {
+				return err
+			}
+			return lvm.CreateSnapshot(srcVg, srcLv, lv, sizeInBytes)
+		}
+		return lvm.CopyLogicalVoulumeWithContext(ctx, srcVg, srcLv, vg, lv, sizeInBytes)
+	case "ceph":
+		return m.cloneCephVolume(ctx, vol, source)
+	default:
+		return fmt.Errorf("unsupported volume type: %s", util.OrDefault(vol.Spec.Type, ""))
+	}
+}
+
+// cloneCephVolume は複製元のスナップショットから RBD のクローンを作成する
+// 全コピーの場合はクローンを親から切り離して、スナップショットを削除する
+func (m *Marmot) cloneCephVolume(ctx context.Context, vol *api.Volume, source api.Volume) error {
+	if !CurrentConfig().CephEnabled {
+		return errors.New("ceph is disabled")
+	}
+	cfg := runtimeCephConfig()
+	client, err := newCephVolumeClient(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cleanupErr := client.Cleanup(); cleanupErr != nil {
+			slog.Warn("ceph client cleanup failed", "err", cleanupErr, "volume", api.VolumeID(*vol))
+		}
+	}()
+
+	srcPool, srcImage, err := resolveCephDeleteTarget(source, cfg)
+	if err != nil {
+		return err
+	}
+	req, err := ceph.MapVolumeToRequest(*vol, cfg)
+	if err != nil {
+		return err
+	}
+	// 途中で失敗しても、ボリュームの削除で実体とスナップショットを片付けられるよう先に記録する
+	vol.Spec.StorageClass = util.StringPtr(req.StorageClass)
+	vol.Status.Provider = util.StringPtr("ceph")
+	vol.Status.ProviderVolumeId = util.StringPtr(req.ProviderVolumeID())
+	vol.Status.AttachProtocol = util.StringPtr("rbd")
+	if err := m.Db.UpdateVolume(api.VolumeID(*vol), api.Volume{Spec: api.VolSpec{StorageClass: vol.Spec.StorageClass}, Status: vol.Status}); err != nil {
+		return err
+	}
+
+	timeout := CurrentConfig().CephVolumeOperationTimeout()
+	snapshot := cloneSnapshotName(api.VolumeID(*vol))
+	if err := client.CloneVolume(ctx, srcPool, srcImage, snapshot, req); err != nil {
+		if removeErr := client.RemoveSnapshot(context.Background(), srcPool, srcImage, snapshot); removeErr != nil {
+			slog.Debug("RemoveSnapshot()", "err", removeErr, "pool", srcPool, "image", srcImage)
+		}
+		return wrapDeadlineExceeded(err, "Ceph ボリューム複製", timeout)
+	}
+	if req.SizeGB > util.OrDefault(source.Spec.Size, 0) {
+		if err := client.ResizeVolume(ctx, req.Pool, req.Image, req.SizeGB); err != nil {
+			return err
+		}
+	}
+	if isCowClone(*vol) {
+		return nil
+	}
+	JobLogf(ctx, "flattening %s", req.ProviderVolumeID())
+	if err := client.FlattenVolume(ctx, req.Pool, req.Image); err != nil {
+		return err
+	}
+	return client.RemoveSnapshot(ctx, srcPool, srcImage, snapshot)
+}
+
+// removeCephCloneSnapshot は Ceph のクローンの削除後に、複製元に残ったクローン用のスナップショットを削除する
+func (m *Marmot) removeCephCloneSnapshot(client cephVolumeClient, vol api.Volume, cfg ceph.Config) {
+	if vol.Spec.Source == nil {
+		return
+	}
+	source, err := m.Db.GetVolumeById(vol.Spec.Source.VolumeId)
+	if err != nil {
+		slog.Warn("clone source volume not found; skip removing the clone snapshot", "err", err, "volId", api.VolumeID(vol))
+		return
+	}
+	pool, image, err := resolveCephDeleteTarget(source, cfg)
+	if err != nil {
+		slog.Warn("resolveCephDeleteTarget()", "err", err, "volId", api.VolumeID(source))
+		return
+	}
+	ctx, cancel := newCephVolumeOperationContext()
+	defer cancel()
+	if err := client.RemoveSnapshot(ctx, pool, image, cloneSnapshotName(api.VolumeID(vol))); err != nil {
+		// 全コピーの完了後はスナップショットが削除済み
+		slog.Debug("RemoveSnapshot()", "err", err, "pool", pool, "image", image)
+	}
+}
@@ -0,0 +1,197 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/lvm"
+	"github.com/takara9/marmot/pkg/qcow"
+	"github.com/takara9/marmot/pkg/util"
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+// ボリュームのオンライン拡張
+// API が spec.size を増やして EXPANDING にし、ボリュームのノードのボリュームコントローラーが実体を拡張する
+// アタッチ先の稼働中のサーバーへの通知は、サーバーのノードのサーバーコントローラーが行う
+
+var (
+	ErrVolumeNotExpandable = errors.New("volume cannot be expanded")
+	ErrVolumeNotAvailable  = errors.New("volume is not AVAILABLE")
+)
+
+// validateVolumeExpansion は spec.size の変更が拡張の要求かを返す
+// 縮小や拡張できないボリュームの場合はエラーを返す
+func validateVolumeExpansion(current api.Volume, size *int) (bool, error) {
+	if size == nil {
+		return false, nil
+	}
+	currentSize := util.OrDefault(current.Spec.Size, 0)
+	if *size == currentSize {
+		return false, nil
+	}
+	if *size < currentSize {
+		return false, fmt.Errorf("%w: size cannot be reduced from %dGB to %dGB", ErrVolumeNotExpandable, currentSize, *size)
+	}
+
+	volType := strings.TrimSpace(util.OrDefault(current.Spec.Type, "qcow2"))
+	switch volType {
+	case "qcow2", "ceph":
+	case "lvm":
+		// OS ボリュームとコピーオンライトのクローンは LVM スナップショットのため拡張できない
+		if isLVMSnapshotVolume(current) {
+			return false, fmt.Errorf("%w: lvm os volumes and copy-on-write clones are lvm snapshots", ErrVolumeNotExpandable)
+		}
+	default:
+		return false, fmt.Errorf("%w: unsupported volume type %s", ErrVolumeNotExpandable, volType)
+	}
+	if current.Status == nil || current.Status.StatusCode != db.VOLUME_AVAILABLE {
+		return false, ErrVolumeNotAvailable
+	}
+	return true, nil
+}
+
+// markVolumeExpanding は拡張前のサイズを記録して、ボリュームを EXPANDING にする
+func markVolumeExpanding(vol *api.Volume, previousSize int) {
+	if vol.Status == nil {
+		vol.Status = &api.Status{}
+	}
+	vol.Status.Size = util.IntPtrInt(previousSize)
+	vol.Status.StatusCode = db.VOLUME_EXPANDING
+	vol.Status.Status = util.StringPtr(db.VolStatus[db.VOLUME_EXPANDING])
+	vol.Status.LastUpdateTimeStamp = util.TimePtr(time.Now())
+}
+
+// ExpandVolume はボリュームの実体を spec.size まで拡張して、結果をボリュームに記録する
+// ボリュームコントローラーから呼び出される
+func (m *Marmot) ExpandVolume(id string) error {
+	vol, err := m.Db.GetVolumeById(id)
+	if err != nil {
+		return err
+	}
+	size := util.OrDefault(vol.Spec.Size, 0)
+
+	expandErr := m.expandVolumeBackingStore(vol, size)
+	if err := m.Db.CompleteVolumeExpansion(id, expandErr); err != nil {
+		slog.Error("CompleteVolumeExpansion()", "err", err, "volId", id)
+		return err
+	}
+	if expandErr != nil {
+		return expandErr
+	}
+	slog.Info("volume expanded", "volId", id, "sizeGB", size)
+
+	// アタッチ先のサーバーの storage のサイズを更新して、サーバーコントローラーにゲストへの通知を任せる
+	if serverID := db.VolumeAttachedServerId(vol); serverID != "" {
+		if err := m.updateServerStorageVolumeSize(serverID, id, size); err != nil {
+			slog.Error("updateServerStorageVolumeSize()", "err", err, "serverId", serverID, "volId", id)
+			return err
+		}
+	}
+	return nil
+}
+
+// expandVolumeBackingStore はボリュームのタイプに応じて実体を拡張する
+func (m *Marmot) expandVolumeBackingStore(vol api.Volume, size int) error {
+	if size < 1 {
+		return fmt.Errorf("volume %s has no size", api.VolumeID(vol))
+	}
+	switch strings.TrimSpace(util.OrDefault(vol.Spec.Type, "qcow2")) {
+	case "qcow2":
+		path := strings.TrimSpace(util.OrDefault(vol.Spec.Path, ""))
+		if path == "" {
+			return errors.New("path is required for qcow2")
+		}
+		// 稼働中のドメインが使っているイメージは qemu-img で変更できないため、QEMU に拡張させる
+		resized, err := m.resizeLocalDomainDisk(vol, virt.DiskSpec{Type: "qcow2", Src: path}, size)
+		if err != nil || resized {
+			return err
+		}
+		return qcow.ResizeQcow(path, size)
+	case "lvm":
+		vg := strings.TrimSpace(util.OrDefault(vol.Spec.VolumeGroup, ""))
+		lv := strings.TrimSpace(util.OrDefault(vol.Spec.LogicalVolume, ""))
+		if vg == "" || lv == "" {
+			return errors.New("volumeGroup and logicalVolume are required for lvm")
+		}
+		return lvm.ExtendLV(vg, lv, uint64(size)*1024*1024*1024)
+	case "ceph":
+		if !CurrentConfig().CephEnabled {
+			return errors.New("ceph is disabled")
+		}
+		cfg := runtimeCephConfig()
+		client, err := newCephVolumeClient(cfg)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cleanupErr := client.Cleanup(); cleanupErr != nil {
+				slog.Warn("ceph client cleanup failed", "err", cleanupErr, "volume", api.VolumeID(vol))
+			}
+		}()
+
+		pool, image, err := resolveCephDeleteTarget(vol, cfg)
+		if err != nil {
+			return err
+		}
+		timeout := CurrentConfig().CephVolumeOperationTimeout()
+		ctx, cancel := newCephVolumeOperationContext()
+		defer cancel()
+		if err := client.ResizeVolume(ctx, pool, image, size); err != nil {
+			return wrapDeadlineExceeded(err, "Ceph ボリューム拡張", timeout)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported volume type: %s", util.OrDefault(vol.Spec.Type, ""))
+	}
+}
+
+// resizeLocalDomainDisk はこのノードで稼働中のアタッチ先のドメインでディスクを拡張する
+// アタッチされていない、または稼働していない場合は false を返す
+func (m *Marmot) resizeLocalDomainDisk(vol api.Volume, ds virt.DiskSpec, size int) (bool, error) {
+	serverID := db.VolumeAttachedServerId(vol)
+	if serverID == "" {
+		return false, nil
+	}
+	server, err := m.Db.GetServerById(serverID)
+	if err != nil {
+		return false, err
+	}
+	if util.OrDefault(server.Metadata.NodeName, "") != m.NodeName || util.OrDefault(server.Metadata.InstanceName, "") == "" {
+		return false, nil
+	}
+
+	l, err := virt.NewLibVirtEp("qemu:///system")
+	if err != nil {
+		slog.Error("NewLibVirtEp()", "err", err)
+		return false, err
+	}
+	defer l.Close()
+	return l.ResizeDisk(*server.Metadata.InstanceName, ds, uint64(size)*1024*1024*1024)
+}
+
+// updateServerStorageVolumeSize はサーバーの storage に記録したボリュームのサイズを更新する
+func (m *Marmot) updateServerStorageVolumeSize(serverID, volumeID string, size int) error {
+	for {
+		server, err := m.Db.GetServerById(serverID)
+		if err != nil {
+			return err
+		}
+		idx := serverStorageIndex(server, volumeID)
+		if idx < 0 {
+			return nil
+		}
+		storage := append([]api.Volume{}, *server.Spec.Storage...)
+		storage[idx].Spec.Size = util.IntPtrInt(size)
+		update := api.Server{Spec: api.ServerSpec{Storage: &storage}}
+		err = m.Db.UpdateServerWithResourceVersion(serverID, update, util.OrDefault(server.Metadata.ResourceVersion, ""))
+		if errors.Is(err, db.ErrResourceVersionConflict) {
+			continue
+		}
+		return err
+	}
+}
@@ -0,0 +1,203 @@
+package marmotd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func newCloneSourceVolume(id, volType, kind, node string, size int) api.Volume {
+	vol := api.Volume{
+		Metadata: api.Metadata{NodeName: util.StringPtr(node)},
+		Spec: api.VolSpec{
+			Type: util.StringPtr(volType),
+			Kind: util.StringPtr(kind),
+			Size: util.IntPtrInt(size),
+		},
+		Status: &api.Status{StatusCode: db.VOLUME_AVAILABLE},
+	}
+	api.SetVolumeID(&vol, id)
+	return vol
+}
+
+func newCloneRequest(sourceID, mode string) api.Volume {
+	req := api.Volume{Spec: api.VolSpec{Source: &api.VolumeSource{VolumeId: sourceID}}}
+	if mode != "" {
+		req.Spec.Source.Mode = util.StringPtr(mode)
+	}
+	return req
+}
+
+func TestPrepareVolumeCloneInheritsSource(t *testing.T) {
+	source := newCloneSourceVolume("11111", "lvm", "data", "hv2", 20)
+	req := newCloneRequest("11111", "")
+
+	if err := prepareVolumeClone(&req, source, nil); err != nil {
+		t.Fatalf("prepareVolumeClone() error = %v", err)
+	}
+	if *req.Spec.Type != "lvm" || *req.Spec.Kind != "data" || *req.Spec.Size != 20 {
+		t.Fatalf("spec = %+v, want the type, kind and size of the source", req.Spec)
+	}
+	if *req.Spec.Source.Mode != VolumeCloneModeFull {
+		t.Fatalf("mode = %s, want full", *req.Spec.Source.Mode)
+	}
+	if util.OrDefault(req.Metadata.NodeName, "") != "hv2" {
+		t.Fatalf("nodeName = %v, want the node of the source", req.Metadata.NodeName)
+	}
+}
+
+func TestPrepareVolumeCloneFromSnapshot(t *testing.T) {
+	source := newCloneSourceVolume("11111", "qcow2", "data", "hv1", 40)
+	sv := &api.ServerSnapshotVolume{VolumeId: "11111", Size: util.IntPtrInt(20), SnapshotName: util.StringPtr("marmot-22222")}
+
+	req := newCloneRequest("11111", "")
+	req.Spec.Size = util.IntPtrInt(30)
+	if err := prepareVolumeClone(&req, source, sv); err != nil {
+		t.Fatalf("prepareVolumeClone() error = %v", err)
+	}
+	if *req.Spec.Size != 30 {
+		t.Fatalf("size = %d, want 30 as it is larger than the snapshot", *req.Spec.Size)
+	}
+
+	req = newCloneRequest("11111", VolumeCloneModeCow)
+	if err := prepareVolumeClone(&req, source, sv); !errors.Is(err, ErrVolumeNotClonable) {
+		t.Fatalf("prepareVolumeClone() error = %v, want ErrVolumeNotClonable for a cow clone of a snapshot", err)
+	}
+}
+
+func TestPrepareVolumeCloneRejects(t *testing.T) {
+	provisioning := newCloneSourceVolume("11111", "qcow2", "data", "hv1", 20)
+	provisioning.Status.StatusCode = db.VOLUME_PROVISIONING
+	lvmOS := newCloneSourceVolume("11111", "lvm", "os", "hv1", 16)
+	lvmClone := newCloneSourceVolume("11111", "lvm", "data", "hv1", 20)
+	lvmClone.Spec.Source = &api.VolumeSource{VolumeId: "00000", Mode: util.StringPtr(VolumeCloneModeCow)}
+
+	tests := []struct {
+		name   string
+		source api.Volume
+		mutate func(req *api.Volume)
+		mode   string
+		want   error
+	}{
+		{"source not available", provisioning, nil, "", ErrVolumeSourceNotReady},
+		{"different type", newCloneSourceVolume("11111", "qcow2", "data", "hv1", 20), func(req *api.Volume) { req.Spec.Type = util.StringPtr("lvm") }, "", ErrVolumeNotClonable},
+		{"different kind", newCloneSourceVolume("11111", "qcow2", "data", "hv1", 20), func(req *api.Volume) { req.Spec.Kind = util.StringPtr("os") }, "", ErrVolumeNotClonable},
+		{"smaller than the source", newCloneSourceVolume("11111", "qcow2", "data", "hv1", 20), func(req *api.Volume) { req.Spec.Size = util.IntPtrInt(10) }, "", ErrVolumeNotClonable},
+		{"local volume to another node", newCloneSourceVolume("11111", "qcow2", "data", "hv1", 20), func(req *api.Volume) { req.Metadata.NodeName = util.StringPtr("hv2") }, "", ErrVolumeNotClonable},
+		{"unknown mode", newCloneSourceVolume("11111", "qcow2", "data", "hv1", 20), nil, "linked", ErrVolumeNotClonable},
+		{"cow clone of qcow2", newCloneSourceVolume("11111", "qcow2", "data", "hv1", 20), nil, VolumeCloneModeCow, ErrVolumeNotClonable},
+		{"cow clone of an lvm os volume", lvmOS, nil, VolumeCloneModeCow, ErrVolumeNotClonable},
+		{"cow clone of an lvm cow clone", lvmClone, nil, VolumeCloneModeCow, ErrVolumeNotClonable},
+		{"resized cow clone of lvm", newCloneSourceVolume("11111", "lvm", "data", "hv1", 20), func(req *api.Volume) { req.Spec.Size = util.IntPtrInt(40) }, VolumeCloneModeCow, ErrVolumeNotClonable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newCloneRequest("11111", tt.mode)
+			if tt.mutate != nil {
+				tt.mutate(&req)
+			}
+			if err := prepareVolumeClone(&req, tt.source, nil); !errors.Is(err, tt.want) {
+				t.Fatalf("prepareVolumeClone() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareVolumeCloneCeph(t *testing.T) {
+	source := newCloneSourceVolume("11111", "ceph", "data", "hv1", 20)
+	source.Spec.StorageClass = util.StringPtr("ssd")
+
+	req := newCloneRequest("11111", VolumeCloneModeCow)
+	req.Metadata.NodeName = util.StringPtr("hv2")
+	req.Spec.Size = util.IntPtrInt(40)
+	if err := prepareVolumeClone(&req, source, nil); err != nil {
+		t.Fatalf("prepareVolumeClone() error = %v", err)
+	}
+	if util.OrDefault(req.Spec.StorageClass, "") != "ssd" {
+		t.Fatalf("storageClass = %v, want the storage class of the source", req.Spec.StorageClass)
+	}
+	if util.OrDefault(req.Metadata.NodeName, "") != "hv2" {
+		t.Fatalf("nodeName = %v, want hv2 as ceph volumes are not node local", req.Metadata.NodeName)
+	}
+}
+
+func TestNeedsStoppedSource(t *testing.T) {
+	fromSnapshot := newCloneRequest("11111", "")
+	fromSnapshot.Spec.Type = util.StringPtr("qcow2")
+	fromSnapshot.Spec.Source.SnapshotId = util.StringPtr("22222")
+
+	tests := []struct {
+		volType string
+		mode    string
+		want    bool
+	}{
+		{"qcow2", VolumeCloneModeFull, true},
+		{"lvm", VolumeCloneModeFull, true},
+		{"lvm", VolumeCloneModeCow, false},
+		{"ceph", VolumeCloneModeFull, false},
+	}
+	for _, tt := range tests {
+		req := newCloneRequest("11111", tt.mode)
+		req.Spec.Type = util.StringPtr(tt.volType)
+		if got := needsStoppedSource(req); got != tt.want {
+			t.Errorf("needsStoppedSource(%s, %s) = %v, want %v", tt.volType, tt.mode, got, tt.want)
+		}
+	}
+	if needsStoppedSource(fromSnapshot) {
+		t.Error("needsStoppedSource() = true, want false for a clone of a server snapshot")
+	}
+}
+
+func TestVolumeCloneDependents(t *testing.T) {
+	clone := func(id, sourceID, snapshotID, mode string, code int) api.Volume {
+		vol := newCloneRequest(sourceID, mode)
+		if snapshotID != "" {
+			vol.Spec.Source.SnapshotId = util.StringPtr(snapshotID)
+		}
+		vol.Status = &api.Status{StatusCode: code}
+		api.SetVolumeID(&vol, id)
+		return vol
+	}
+	vols := []api.Volume{
+		newCloneSourceVolume("11111", "lvm", "data", "hv1", 20),
+		clone("22222", "11111", "", VolumeCloneModeCow, db.VOLUME_AVAILABLE),
+		clone("33333", "11111", "", VolumeCloneModeFull, db.VOLUME_AVAILABLE),
+		clone("44444", "11111", "", VolumeCloneModeFull, db.VOLUME_CLONING),
+		clone("55555", "11111", "snap1", VolumeCloneModeFull, db.VOLUME_PENDING),
+		clone("66666", "11111", "snap1", VolumeCloneModeFull, db.VOLUME_AVAILABLE),
+	}
+
+	if got, want := volumeCloneDependents(vols, "11111", ""), []string{"22222", "44444", "55555"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("volumeCloneDependents(volume) = %v, want %v", got, want)
+	}
+	if got, want := volumeCloneDependents(vols, "", "snap1"), []string{"55555"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("volumeCloneDependents(snapshot) = %v, want %v", got, want)
+	}
+	if got := volumeCloneDependents(vols, "22222", ""); len(got) != 0 {
+		t.Fatalf("volumeCloneDependents() = %v, want none", got)
+	}
+}
+
+func TestVolumeCloneErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{fmt.Errorf("source volume 11111: %w", db.ErrNotFound), http.StatusNotFound},
+		{fmt.Errorf("%w: unknown mode", ErrVolumeNotClonable), http.StatusBadRequest},
+		{fmt.Errorf("%w: volume 11111", ErrVolumeSourceNotReady), http.StatusConflict},
+		{fmt.Errorf("%w: server 22222", ErrVolumeSourceInUse), http.StatusConflict},
+		{errors.New("etcd unavailable"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := volumeCloneErrorStatus(tt.err); got != tt.want {
+			t.Errorf("volumeCloneErrorStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
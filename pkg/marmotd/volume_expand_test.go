@@ -0,0 +1,87 @@
+package marmotd
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/takara9/marmot/api"
+	"github.com/takara9/marmot/pkg/db"
+	"github.com/takara9/marmot/pkg/util"
+)
+
+func TestValidateVolumeExpansion(t *testing.T) {
+	newVolume := func(volType, kind string, size, status int) api.Volume {
+		return api.Volume{
+			Spec: api.VolSpec{
+				Type: util.StringPtr(volType),
+				Kind: util.StringPtr(kind),
+				Size: util.IntPtrInt(size),
+			},
+			Status: &api.Status{StatusCode: status},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		vol        api.Volume
+		size       *int
+		wantExpand bool
+		wantErr    error
+	}{
+		{"size not given", newVolume("qcow2", "data", 10, db.VOLUME_AVAILABLE), nil, false, nil},
+		{"same size", newVolume("qcow2", "data", 10, db.VOLUME_AVAILABLE), util.IntPtrInt(10), false, nil},
+		{"qcow2 data volume", newVolume("qcow2", "data", 10, db.VOLUME_AVAILABLE), util.IntPtrInt(20), true, nil},
+		{"qcow2 os volume", newVolume("qcow2", "os", 16, db.VOLUME_AVAILABLE), util.IntPtrInt(32), true, nil},
+		{"lvm data volume", newVolume("lvm", "data", 10, db.VOLUME_AVAILABLE), util.IntPtrInt(20), true, nil},
+		{"ceph volume", newVolume("ceph", "data", 10, db.VOLUME_AVAILABLE), util.IntPtrInt(20), true, nil},
+		{"shrink", newVolume("qcow2", "data", 10, db.VOLUME_AVAILABLE), util.IntPtrInt(5), false, ErrVolumeNotExpandable},
+		{"lvm os volume", newVolume("lvm", "os", 16, db.VOLUME_AVAILABLE), util.IntPtrInt(32), false, ErrVolumeNotExpandable},
+		{"raw volume", newVolume("raw", "data", 10, db.VOLUME_AVAILABLE), util.IntPtrInt(20), false, ErrVolumeNotExpandable},
+		{"expanding volume", newVolume("qcow2", "data", 20, db.VOLUME_EXPANDING), util.IntPtrInt(30), false, ErrVolumeNotAvailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expand, err := validateVolumeExpansion(tt.vol, tt.size)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("validateVolumeExpansion() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateVolumeExpansion() error = %v", err)
+			}
+			if expand != tt.wantExpand {
+				t.Fatalf("validateVolumeExpansion() = %v, want %v", expand, tt.wantExpand)
+			}
+		})
+	}
+}
+
+func TestMarkVolumeExpanding(t *testing.T) {
+	vol := api.Volume{Spec: api.VolSpec{Size: util.IntPtrInt(20)}}
+	markVolumeExpanding(&vol, 10)
+
+	if vol.Status == nil || vol.Status.StatusCode != db.VOLUME_EXPANDING {
+		t.Fatalf("status = %+v, want EXPANDING", vol.Status)
+	}
+	if vol.Status.Size == nil || *vol.Status.Size != 10 {
+		t.Fatalf("status.size = %v, want the size before the expansion", vol.Status.Size)
+	}
+	if vol.Status.Status == nil || *vol.Status.Status != "EXPANDING" {
+		t.Fatalf("status.status = %v, want EXPANDING", vol.Status.Status)
+	}
+}
+
+func TestVolumeUpdateErrorStatus(t *testing.T) {
+	if got := volumeUpdateErrorStatus(ErrVolumeNotExpandable); got != http.StatusBadRequest {
+		t.Fatalf("volumeUpdateErrorStatus(ErrVolumeNotExpandable) = %d, want 400", got)
+	}
+	if got := volumeUpdateErrorStatus(ErrVolumeNotAvailable); got != http.StatusConflict {
+		t.Fatalf("volumeUpdateErrorStatus(ErrVolumeNotAvailable) = %d, want 409", got)
+	}
+	if got := volumeUpdateErrorStatus(db.ErrResourceVersionConflict); got != http.StatusConflict {
+		t.Fatalf("volumeUpdateErrorStatus(ErrResourceVersionConflict) = %d, want 409", got)
+	}
+}
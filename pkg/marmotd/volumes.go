@@ -609,6 +609,7 @@ func (m *Marmot) RemoveVolume(id string) error {
 			slog.Error("ceph.DeleteVolume()", "err", err, "pool", pool, "image", image)
 			return err
 		}
+		m.removeCephCloneSnapshot(client, vol, cfg)
 	case "qcow2":
 		// qcow2ファイルの削除
 		if vol.Spec.Path != nil {
@@ -671,19 +672,33 @@ func (m *Marmot) GetVolumeById(id string) (*api.Volume, error) {
 	return &vol, nil
 }
 
-// IDでボリュームの情報を更新する関数,API, UpdateVolumeById(volId, volSpec)
-func (m *Marmot) UpdateVolumeById(id string, volSpec api.Volume) (*api.Volume, error) {
+// IDでボリュームの情報を更新する関数,API, UpdateVolumeById(volId, volSpec, resourceVersion)
+// resourceVersion が空でない場合は、現在のリソースバージョンと一致する時だけ更新する
+func (m *Marmot) UpdateVolumeById(id string, volSpec api.Volume, resourceVersion string) (*api.Volume, error) {
 	vol, err := m.Db.GetVolumeById(id)
 	if err != nil {
 		slog.Error("failed to get volume by key", "err", err, "volume id", id)
 		return nil, err
 	}
 
+	// 複製元は作成時にだけ使うため変更させない
+	volSpec.Spec.Source = nil
+
+	// spec.size の増加は拡張の要求として扱う
+	expand, err := validateVolumeExpansion(vol, volSpec.Spec.Size)
+	if err != nil {
+		return nil, err
+	}
+	previousSize := util.OrDefault(vol.Spec.Size, 0)
+
 	util.PatchStruct(&vol, &volSpec)
 	api.SetVolumeID(&vol, id)
+	if expand {
+		markVolumeExpanding(&vol, previousSize)
+	}
 
 	// データベースを更新
-	if err := m.Db.UpdateVolume(id, vol); err != nil {
+	if err := m.Db.UpdateVolumeWithResourceVersion(id, vol, resourceVersion); err != nil {
 		return nil, err
 	}
 
@@ -793,24 +808,3 @@ func getVolumeBackingStore(spec *api.VolSpec) (string, string) {
 		return "", ""
 	}
 }
-
-// 以下は未実装
-
-// ボリュームの拡張,API, ExpandVolume(volId, newSize)
-func ExpandVolume(volId string, newSize int) error {
-	return errors.New("not implemented")
-}
-
-// ボリュームの仮想マシンへのアタッチとデタッチ,API, AttachVol(vmId, volId), DetachVol(vmId, volId)
-func AttachVol(vmId string, volId string) error {
-	return errors.New("not implemented")
-}
-
-func DetachVol(vmId string, volId string) error {
-	return errors.New("not implemented")
-}
-
-// ボリュームの複製,API, CopyVolume(volId)
-func CopyVolume(volId string) error {
-	return errors.New("not implemented")
-}
@@ -35,6 +35,19 @@ func CreateQcow(path string, size int) error {
 	return nil
 }
 
+// QCOW2 ボリュームの作成、サイズはバイト単位
+// ライブマイグレーションの移行先ディスクのように、元ディスクと同じ仮想サイズが必要な場合に使う
+func CreateQcowBytes(path string, sizeInByte uint64) error {
+	slog.Debug("Creating QCOW2 volume", "path", path, "sizeInByte", sizeInByte)
+	cmd := exec.Command("qemu-img", "create", "-f", "qcow2", path, fmt.Sprintf("%d", sizeInByte))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		slog.Error("qemu-img create failed", "output", string(output))
+		return fmt.Errorf("failed to create QCOW2 volume at path: %s, error: %v", path, err)
+	}
+	return nil
+}
+
 // QCOW2 ボリュームの拡張、サイズはGB単位
 func ResizeQcow(path string, size int) error {
 	slog.Debug("Resizing QCOW2 volume", "path", path, "sizeGB", size)
@@ -73,6 +86,29 @@ func CopyQcowWithContext(ctx context.Context, srcPath string, destPath string) e
 	return nil
 }
 
+// QCOW2 ボリュームを、内部スナップショットを含まない新しい QCOW2 ボリュームへ書き出す
+// snapshotName を指定した場合は、その内部スナップショットの時点のデータを書き出す
+// スナップショットのデータは変更されないため、稼働中のドメインが使っているイメージからも読み出す
+func ConvertQcowWithContext(ctx context.Context, srcPath string, snapshotName string, destPath string) error {
+	slog.Debug("Converting QCOW2 volume", "srcPath", srcPath, "snapshotName", snapshotName, "destPath", destPath)
+	destDir := filepath.Dir(destPath)
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to ensure destination directory for QCOW2 convert: %s: %w", destDir, err)
+	}
+	args := []string{"convert", "-f", "qcow2", "-O", "qcow2"}
+	if snapshotName != "" {
+		args = append(args, "-U", "-l", "snapshot.name="+snapshotName)
+	}
+	args = append(args, srcPath, destPath)
+	output, err := exec.CommandContext(ctx, "qemu-img", args...).CombinedOutput()
+	if err != nil {
+		trimmed := strings.TrimSpace(string(output))
+		slog.Error("qemu-img convert failed", "output", trimmed)
+		return fmt.Errorf("failed to convert QCOW2 volume from %s to %s: %w (output: %s)", srcPath, destPath, err, trimmed)
+	}
+	return nil
+}
+
 // QCOW2ボリュームの情報取得
 func GetQcowInfo(path string) (map[string]interface{}, error) {
 	slog.Debug("Getting QCOW2 volume info", "path", path)
@@ -1,6 +1,7 @@
 package qcow_test
 
 import (
+	"context"
 	"os"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -17,6 +18,7 @@ var _ = Describe("QCOW2 Volume", Ordered, func() {
 	AfterAll(func() {
 		_ = os.Remove("testdata/test.qcow2")
 		_ = os.Remove("testdata/test_copy.qcow2")
+		_ = os.Remove("testdata/test_snapshot.qcow2")
 		_ = os.RemoveAll("testdata")
 	})
 
@@ -50,6 +52,12 @@ var _ = Describe("QCOW2 Volume", Ordered, func() {
 				}
 			})
 
+			It("Convert Snapshot of QCOW2 Volume", func() {
+				err := qcow.ConvertQcowWithContext(context.Background(), "testdata/test.qcow2", "snapshot1", "testdata/test_snapshot.qcow2")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(qcow.IsExist("testdata/test_snapshot.qcow2")).To(Succeed())
+			})
+
 			It("Delete Snapshot of QCOW2 Volume", func() {
 				err := qcow.DeleteSnapshotQcow("testdata/test.qcow2", "snapshot1")
 				Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,61 @@
+package util
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseCPUSet は "0-3,8,10-11" の形式の CPU リストを昇順で重複の無い番号の配列に変換する
+// sysfs の cpulist と libvirt の cpuset と同じ形式。空文字列は空の配列を返す
+func ParseCPUSet(s string) ([]int, error) {
+	seen := make(map[int]struct{})
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(lo))
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid cpu %q in %q", lo, s)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(strings.TrimSpace(hi))
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid cpu range %q in %q", part, s)
+			}
+		}
+		for c := start; c <= end; c++ {
+			seen[c] = struct{}{}
+		}
+	}
+	cpus := make([]int, 0, len(seen))
+	for c := range seen {
+		cpus = append(cpus, c)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// FormatCPUSet は CPU 番号の配列を連続する番号をまとめた "0-3,8" の形式に変換する
+func FormatCPUSet(cpus []int) string {
+	sorted := append([]int(nil), cpus...)
+	sort.Ints(sorted)
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[i] == sorted[j] {
+			parts = append(parts, strconv.Itoa(sorted[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUSet(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []int
+		wantErr bool
+	}{
+		{in: "", want: []int{}},
+		{in: "3", want: []int{3}},
+		{in: "0-3,8,10-11", want: []int{0, 1, 2, 3, 8, 10, 11}},
+		{in: " 4-5 , 2 ,5", want: []int{2, 4, 5}},
+		{in: "5-3", wantErr: true},
+		{in: "a-b", wantErr: true},
+		{in: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCPUSet(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseCPUSet(%q) expected error, got %v", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCPUSet(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseCPUSet(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCPUSet(t *testing.T) {
+	tests := []struct {
+		in   []int
+		want string
+	}{
+		{in: nil, want: ""},
+		{in: []int{7}, want: "7"},
+		{in: []int{11, 0, 1, 2, 3, 8, 10}, want: "0-3,8,10-11"},
+		{in: []int{4, 4, 5}, want: "4-5"},
+	}
+	for _, tt := range tests {
+		if got := FormatCPUSet(tt.in); got != tt.want {
+			t.Errorf("FormatCPUSet(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -76,4 +76,60 @@ func chardevPath(tty string, source *libvirtxml.DomainChardevSource) string {
 		return strings.TrimSpace(tty)
 	}
 	return chardevSourcePath(source)
-}
\ No newline at end of file
+}
+// GraphicsProtocol is the protocol of the display that CreateDomainXML defines.
+const GraphicsProtocol = "spice"
+
+// GraphicsEndpoint is the listen address of the graphical console of a running domain.
+type GraphicsEndpoint struct {
+	Protocol string // "spice" or "vnc"
+	Address  string
+	Port     int
+}
+
+// GetDomainGraphics reads the active libvirt XML and returns the graphical console endpoint.
+func GetDomainGraphics(dom *libvirt.Domain) (GraphicsEndpoint, error) {
+	if dom == nil {
+		return GraphicsEndpoint{}, fmt.Errorf("domain is nil")
+	}
+	xmlDesc, err := dom.GetXMLDesc(0)
+	if err != nil {
+		return GraphicsEndpoint{}, err
+	}
+	return ExtractDomainGraphics(xmlDesc)
+}
+
+// ExtractDomainGraphics parses domain XML and returns the first SPICE or VNC display with an assigned port.
+// The autoport is resolved only in the XML of a running domain.
+func ExtractDomainGraphics(xmlDesc string) (GraphicsEndpoint, error) {
+	var dom libvirtxml.Domain
+	if err := dom.Unmarshal(xmlDesc); err != nil {
+		return GraphicsEndpoint{}, err
+	}
+	if dom.Devices != nil {
+		for _, g := range dom.Devices.Graphics {
+			switch {
+			case g.Spice != nil && g.Spice.Port > 0:
+				return GraphicsEndpoint{Protocol: "spice", Address: graphicsListenAddress(g.Spice.Listen, g.Spice.Listeners), Port: g.Spice.Port}, nil
+			case g.VNC != nil && g.VNC.Port > 0:
+				return GraphicsEndpoint{Protocol: "vnc", Address: graphicsListenAddress(g.VNC.Listen, g.VNC.Listeners), Port: g.VNC.Port}, nil
+			}
+		}
+	}
+	return GraphicsEndpoint{}, fmt.Errorf("graphics port not found in domain xml")
+}
+
+func graphicsListenAddress(listen string, listeners []libvirtxml.DomainGraphicListener) string {
+	for _, l := range listeners {
+		if l.Address != nil && strings.TrimSpace(l.Address.Address) != "" {
+			listen = l.Address.Address
+			break
+		}
+	}
+	listen = strings.TrimSpace(listen)
+	// 全アドレスで待ち受けている場合もノード内からはループバックで接続する
+	if listen == "" || listen == "0.0.0.0" || listen == "::" {
+		return "127.0.0.1"
+	}
+	return listen
+}
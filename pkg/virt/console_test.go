@@ -27,4 +27,29 @@ func TestExtractDomainConsolePath(t *testing.T) {
   if path != "/dev/pts/17" {
     t.Fatalf("ExtractDomainConsolePath() = %q, want %q", path, "/dev/pts/17")
   }
-}
\ No newline at end of file
+}
+
+func TestExtractDomainGraphics(t *testing.T) {
+	xmlDesc := `
+<domain type='kvm'>
+  <name>vm-test</name>
+  <devices>
+    <graphics type='spice' port='5901' autoport='yes' listen='127.0.0.1'>
+      <listen type='address' address='127.0.0.1'/>
+    </graphics>
+  </devices>
+</domain>`
+
+	g, err := ExtractDomainGraphics(xmlDesc)
+	if err != nil {
+		t.Fatalf("ExtractDomainGraphics() error = %v", err)
+	}
+	if g.Protocol != "spice" || g.Address != "127.0.0.1" || g.Port != 5901 {
+		t.Fatalf("ExtractDomainGraphics() = %+v", g)
+	}
+
+	// 停止中のドメインは autoport のポートが決まっていない
+	if _, err := ExtractDomainGraphics(`<domain type='kvm'><devices><graphics type='vnc' port='-1' autoport='yes'/></devices></domain>`); err == nil {
+		t.Fatalf("ExtractDomainGraphics() error = nil, want an error without an assigned port")
+	}
+}
@@ -0,0 +1,212 @@
+package virt
+
+import (
+	"errors"
+	"fmt"
+
+	"libvirt.org/go/libvirt"
+	"libvirt.org/go/libvirtxml"
+)
+
+// diskSourceKey はディスクを識別するソースを返す
+// ローカルディスクはパス、ネットワークディスクはプロトコルと名前
+func diskSourceKey(disk libvirtxml.DomainDisk) string {
+	if path := diskSourcePath(disk); path != "" {
+		return path
+	}
+	if disk.Source != nil && disk.Source.Network != nil && disk.Source.Network.Name != "" {
+		return disk.Source.Network.Protocol + ":" + disk.Source.Network.Name
+	}
+	return ""
+}
+
+// findDomainDisk はドメインから同じソースのディスクを探す
+func findDomainDisk(cfg libvirtxml.Domain, disk libvirtxml.DomainDisk) *libvirtxml.DomainDisk {
+	key := diskSourceKey(disk)
+	if key == "" || cfg.Devices == nil {
+		return nil
+	}
+	for i := range cfg.Devices.Disks {
+		if diskSourceKey(cfg.Devices.Disks[i]) == key {
+			return &cfg.Devices.Disks[i]
+		}
+	}
+	return nil
+}
+
+// nextDiskTargetDev は使われていない virtio ディスクのデバイス名を返す
+// vda はブートディスクのため vdb から割り当てる
+func nextDiskTargetDev(cfg libvirtxml.Domain) (string, error) {
+	used := make(map[string]bool)
+	if cfg.Devices != nil {
+		for _, disk := range cfg.Devices.Disks {
+			if disk.Target != nil {
+				used[disk.Target.Dev] = true
+			}
+		}
+	}
+	for c := 'b'; c <= 'z'; c++ {
+		dev := fmt.Sprintf("vd%c", c)
+		if !used[dev] {
+			return dev, nil
+		}
+	}
+	return "", errors.New("no free virtio disk device name")
+}
+
+// diskAttachXML はドメインへ追加するディスクのXMLを生成する
+// 同じソースのディスクが接続済みの場合は空文字を返す
+func diskAttachXML(domainXML string, d DiskSpec) (string, error) {
+	var cfg libvirtxml.Domain
+	if err := cfg.Unmarshal(domainXML); err != nil {
+		return "", err
+	}
+	disk, ok := newDomainDisk(d)
+	if !ok || disk.Device != "disk" {
+		return "", fmt.Errorf("unsupported disk type for hot plug: %s", d.Type)
+	}
+	if findDomainDisk(cfg, disk) != nil {
+		return "", nil
+	}
+
+	// デバイス名とPCIアドレスはドメインの空きから決める
+	dev, err := nextDiskTargetDev(cfg)
+	if err != nil {
+		return "", err
+	}
+	disk.Target.Dev = dev
+	return disk.Marshal()
+}
+
+// diskDetachXML はドメインから取り外すディスクのXMLを返す
+// 同じソースのディスクが無い場合は空文字を返す
+func diskDetachXML(domainXML string, d DiskSpec) (string, error) {
+	var cfg libvirtxml.Domain
+	if err := cfg.Unmarshal(domainXML); err != nil {
+		return "", err
+	}
+	disk, ok := newDomainDisk(d)
+	if !ok {
+		return "", fmt.Errorf("unsupported disk type for hot plug: %s", d.Type)
+	}
+	attached := findDomainDisk(cfg, disk)
+	if attached == nil {
+		return "", nil
+	}
+	return attached.Marshal()
+}
+
+// attachedDiskTarget はドメインに接続済みのディスクのデバイス名を返す
+// 同じソースのディスクが無い場合は空文字を返す
+func attachedDiskTarget(domainXML string, d DiskSpec) (string, error) {
+	var cfg libvirtxml.Domain
+	if err := cfg.Unmarshal(domainXML); err != nil {
+		return "", err
+	}
+	disk, ok := newDomainDisk(d)
+	if !ok {
+		return "", fmt.Errorf("unsupported disk type for resize: %s", d.Type)
+	}
+	attached := findDomainDisk(cfg, disk)
+	if attached == nil || attached.Target == nil {
+		return "", nil
+	}
+	return attached.Target.Dev, nil
+}
+
+// diskHotplugTarget はドメインを取得して、現在のXMLとデバイス変更のフラグを返す
+// 稼働中のドメインは実行中の構成と永続定義の両方を変更する
+func (l *LibVirtEp) diskHotplugTarget(vmname string) (*libvirt.Domain, string, libvirt.DomainDeviceModifyFlags, error) {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	state, _, err := domain.GetState()
+	if err != nil {
+		_ = domain.Free()
+		return nil, "", 0, err
+	}
+	flags := libvirt.DOMAIN_DEVICE_MODIFY_CONFIG
+	if isDomainLive(state) {
+		flags |= libvirt.DOMAIN_DEVICE_MODIFY_LIVE
+	}
+	xml, err := domain.GetXMLDesc(0)
+	if err != nil {
+		_ = domain.Free()
+		return nil, "", 0, err
+	}
+	return domain, xml, flags, nil
+}
+
+// AttachDisk はドメインにディスクを接続する。稼働中のドメインにはホットプラグする
+// 接続済みの場合は何もせず false を返す
+func (l *LibVirtEp) AttachDisk(vmname string, d DiskSpec) (bool, error) {
+	domain, xml, flags, err := l.diskHotplugTarget(vmname)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = domain.Free()
+	}()
+
+	diskXML, err := diskAttachXML(xml, d)
+	if err != nil || diskXML == "" {
+		return false, err
+	}
+	if err := domain.AttachDeviceFlags(diskXML, flags); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DetachDisk はドメインからディスクを取り外す
+// 接続されていない場合は何もせず false を返す
+func (l *LibVirtEp) DetachDisk(vmname string, d DiskSpec) (bool, error) {
+	domain, xml, flags, err := l.diskHotplugTarget(vmname)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = domain.Free()
+	}()
+
+	diskXML, err := diskDetachXML(xml, d)
+	if err != nil || diskXML == "" {
+		return false, err
+	}
+	if err := domain.DetachDeviceFlags(diskXML, flags); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ResizeDisk は稼働中のドメインのディスクを sizeInBytes へ拡張し、ゲストに新しいサイズを通知する
+// qcow2 はイメージ自体も QEMU が拡張する。停止中、未接続、拡張済みの場合は何もせず false を返す
+func (l *LibVirtEp) ResizeDisk(vmname string, d DiskSpec, sizeInBytes uint64) (bool, error) {
+	domain, xml, flags, err := l.diskHotplugTarget(vmname)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = domain.Free()
+	}()
+	if flags&libvirt.DOMAIN_DEVICE_MODIFY_LIVE == 0 {
+		return false, nil
+	}
+
+	dev, err := attachedDiskTarget(xml, d)
+	if err != nil || dev == "" {
+		return false, err
+	}
+	info, err := domain.GetBlockInfo(dev, 0)
+	if err != nil {
+		return false, err
+	}
+	if info.Capacity >= sizeInBytes {
+		return false, nil
+	}
+	if err := domain.BlockResize(dev, sizeInBytes, libvirt.DOMAIN_BLOCK_RESIZE_BYTES); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,76 @@
+package virt
+
+import (
+	"testing"
+
+	"libvirt.org/go/libvirtxml"
+)
+
+func TestDiskAttachXMLAssignsFreeDevice(t *testing.T) {
+	out, err := diskAttachXML(migrationTestDomainXML, DiskSpec{Type: "qcow2", Src: "/var/lib/marmot/volumes/data-44444.qcow2"})
+	if err != nil {
+		t.Fatalf("diskAttachXML() error = %v", err)
+	}
+
+	var disk libvirtxml.DomainDisk
+	if err := disk.Unmarshal(out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if disk.Target == nil || disk.Target.Dev != "vdd" {
+		t.Fatalf("target = %+v, want vdd", disk.Target)
+	}
+	if disk.Source == nil || disk.Source.File == nil || disk.Source.File.File != "/var/lib/marmot/volumes/data-44444.qcow2" {
+		t.Fatalf("source = %+v, want the qcow2 file", disk.Source)
+	}
+	if disk.Address != nil || disk.Alias != nil {
+		t.Fatalf("address and alias must be left to libvirt: %s", out)
+	}
+}
+
+func TestDiskAttachXMLSkipsAttachedDisk(t *testing.T) {
+	out, err := diskAttachXML(migrationTestDomainXML, DiskSpec{Type: "iscsi", ISCSITarget: "iqn.2024-01.com.marmot:target-33333/0"})
+	if err != nil {
+		t.Fatalf("diskAttachXML() error = %v", err)
+	}
+	if out != "" {
+		t.Fatalf("diskAttachXML() = %s, want empty for an attached disk", out)
+	}
+
+	if _, err := diskAttachXML(migrationTestDomainXML, DiskSpec{Type: "iso", Src: "/tmp/seed.iso"}); err == nil {
+		t.Fatal("diskAttachXML() error = nil, want error for a cdrom")
+	}
+}
+
+func TestDiskDetachXML(t *testing.T) {
+	out, err := diskDetachXML(migrationTestDomainXML, DiskSpec{Type: "raw", Src: "/dev/vg2/datalv-22222"})
+	if err != nil {
+		t.Fatalf("diskDetachXML() error = %v", err)
+	}
+	var disk libvirtxml.DomainDisk
+	if err := disk.Unmarshal(out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if disk.Target == nil || disk.Target.Dev != "vdb" {
+		t.Fatalf("target = %+v, want vdb", disk.Target)
+	}
+
+	out, err = diskDetachXML(migrationTestDomainXML, DiskSpec{Type: "raw", Src: "/dev/vg2/datalv-99999"})
+	if err != nil || out != "" {
+		t.Fatalf("diskDetachXML() = %q, %v, want empty for a disk that is not attached", out, err)
+	}
+}
+
+func TestAttachedDiskTarget(t *testing.T) {
+	dev, err := attachedDiskTarget(migrationTestDomainXML, DiskSpec{Type: "iscsi", ISCSITarget: "iqn.2024-01.com.marmot:target-33333/0"})
+	if err != nil {
+		t.Fatalf("attachedDiskTarget() error = %v", err)
+	}
+	if dev != "vdc" {
+		t.Fatalf("attachedDiskTarget() = %q, want vdc", dev)
+	}
+
+	dev, err = attachedDiskTarget(migrationTestDomainXML, DiskSpec{Type: "qcow2", Src: "/var/lib/marmot/volumes/data-44444.qcow2"})
+	if err != nil || dev != "" {
+		t.Fatalf("attachedDiskTarget() = %q, %v, want empty for a disk that is not attached", dev, err)
+	}
+}
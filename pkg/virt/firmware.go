@@ -0,0 +1,64 @@
+package virt
+
+import (
+	"fmt"
+	"strings"
+
+	"libvirt.org/go/libvirtxml"
+)
+
+// サーバーのファームウェア
+const (
+	FirmwareBIOS       = "bios"
+	FirmwareUEFI       = "uefi"
+	FirmwareUEFISecure = "uefi-secure" // Secure Boot を有効にした UEFI
+)
+
+// OVMF のファイル。Ubuntu の ovmf パッケージのパス
+var (
+	OVMFCode       = "/usr/share/OVMF/OVMF_CODE_4M.fd"
+	OVMFVars       = "/usr/share/OVMF/OVMF_VARS_4M.fd"
+	OVMFSecureCode = "/usr/share/OVMF/OVMF_CODE_4M.secboot.fd"
+	OVMFSecureVars = "/usr/share/OVMF/OVMF_VARS_4M.ms.fd" // Microsoft の鍵を登録済み
+)
+
+// NormalizeFirmware はファームウェアの指定を検証して正規化する。空の場合は空を返す
+func NormalizeFirmware(firmware string) (string, error) {
+	fw := strings.ToLower(strings.TrimSpace(firmware))
+	switch fw {
+	case "", FirmwareBIOS, FirmwareUEFI, FirmwareUEFISecure:
+		return fw, nil
+	}
+	return "", fmt.Errorf("unsupported firmware %q: must be one of %s, %s or %s", firmware, FirmwareBIOS, FirmwareUEFI, FirmwareUEFISecure)
+}
+
+// applyFirmware は UEFI の場合に OVMF のローダーとドメイン毎の NVRAM を設定する
+// NVRAM は初回起動時に libvirt がテンプレートから作成する
+func applyFirmware(dom *libvirtxml.Domain, vs ServerSpec) {
+	switch vs.Firmware {
+	case FirmwareUEFI:
+		dom.OS.Loader = &libvirtxml.DomainLoader{Path: OVMFCode, Readonly: "yes", Secure: "no", Type: "pflash"}
+		dom.OS.NVRam = &libvirtxml.DomainNVRam{NVRam: vs.NVRAMPath, Template: OVMFVars}
+	case FirmwareUEFISecure:
+		// Secure Boot はファームウェアの変数を SMM で保護する。q35 が必要
+		dom.OS.Loader = &libvirtxml.DomainLoader{Path: OVMFSecureCode, Readonly: "yes", Secure: "yes", Type: "pflash"}
+		dom.OS.NVRam = &libvirtxml.DomainNVRam{NVRam: vs.NVRAMPath, Template: OVMFSecureVars}
+		dom.Features.SMM = &libvirtxml.DomainFeatureSMM{State: "on"}
+	}
+}
+
+// applyTPM は swtpm でエミュレートする TPM 2.0 を追加する
+// 状態はドメインの削除やマイグレーションで libvirt に消されないよう、指定したディレクトリに保持する
+func applyTPM(dom *libvirtxml.Domain, vs ServerSpec) {
+	if !vs.TPM {
+		return
+	}
+	emulator := &libvirtxml.DomainTPMBackendEmulator{Version: "2.0", PersistentState: "yes"}
+	if strings.TrimSpace(vs.TPMStateDir) != "" {
+		emulator.Source = &libvirtxml.DomainTPMBackendSource{Dir: &libvirtxml.DomainTPMBackendSourceDir{Path: vs.TPMStateDir}}
+	}
+	dom.Devices.TPMs = []libvirtxml.DomainTPM{{
+		Model:   "tpm-crb",
+		Backend: &libvirtxml.DomainTPMBackend{Emulator: emulator},
+	}}
+}
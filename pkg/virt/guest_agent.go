@@ -0,0 +1,149 @@
+package virt
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+
+	"libvirt.org/go/libvirt"
+)
+
+// QEMU ゲストエージェントの virtio-serial チャンネル名
+const GuestAgentChannelName = "org.qemu.guest_agent.0"
+
+// GuestInfo はゲストエージェントから取得したゲストOSの情報
+type GuestInfo struct {
+	Hostname      string
+	OSName        string
+	OSVersion     string
+	KernelRelease string
+	Interfaces    []GuestInterface
+}
+
+// GuestInterface はゲストOSから見たネットワークインターフェース
+type GuestInterface struct {
+	Name        string
+	MacAddress  string
+	IPAddresses []string // CIDR 表記
+}
+
+// GuestAgentInfo はゲストエージェントからホスト名、OS、IPアドレスを取得する
+// ゲストエージェントが応答しない場合はエラーを返す
+func (l *LibVirtEp) GuestAgentInfo(vmname string) (GuestInfo, error) {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return GuestInfo{}, err
+	}
+	defer domain.Free()
+
+	info, err := domain.GetGuestInfo(libvirt.DOMAIN_GUEST_INFO_OS|libvirt.DOMAIN_GUEST_INFO_HOSTNAME, 0)
+	if err != nil {
+		return GuestInfo{}, err
+	}
+	ifaces, err := domain.ListAllInterfaceAddresses(libvirt.DOMAIN_INTERFACE_ADDRESSES_SRC_AGENT)
+	if err != nil {
+		return GuestInfo{}, err
+	}
+	return newGuestInfo(info, ifaces), nil
+}
+
+// newGuestInfo は libvirt の応答を GuestInfo に変換する。ループバックは除く
+func newGuestInfo(info *libvirt.DomainGuestInfo, ifaces []libvirt.DomainInterface) GuestInfo {
+	var gi GuestInfo
+	if info != nil {
+		gi.Hostname = info.Hostname
+		if info.OS != nil {
+			gi.OSName = info.OS.PrettyName
+			if gi.OSName == "" {
+				gi.OSName = info.OS.Name
+			}
+			gi.OSVersion = info.OS.VersionID
+			gi.KernelRelease = info.OS.KernelRelease
+		}
+	}
+	for _, iface := range ifaces {
+		if iface.Name == "lo" {
+			continue
+		}
+		gif := GuestInterface{Name: iface.Name, MacAddress: iface.Hwaddr}
+		for _, addr := range iface.Addrs {
+			ip := net.ParseIP(addr.Addr)
+			if ip == nil || ip.IsLoopback() {
+				continue
+			}
+			gif.IPAddresses = append(gif.IPAddresses, fmt.Sprintf("%s/%d", addr.Addr, addr.Prefix))
+		}
+		gi.Interfaces = append(gi.Interfaces, gif)
+	}
+	return gi
+}
+
+// FreezeGuestFilesystems はゲストエージェントでゲストのファイルシステムを凍結する
+// 凍結したら必ず ThawGuestFilesystems で解除すること
+func (l *LibVirtEp) FreezeGuestFilesystems(vmname string) error {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return err
+	}
+	defer domain.Free()
+	return domain.FSFreeze(nil, 0)
+}
+
+// ThawGuestFilesystems はゲストのファイルシステムの凍結を解除する
+func (l *LibVirtEp) ThawGuestFilesystems(vmname string) error {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return err
+	}
+	defer domain.Free()
+	return domain.FSThaw(nil, 0)
+}
+
+// SetGuestUserPassword はゲストのユーザーのパスワードを設定する
+// cryptedPassword は crypt(3) 形式でハッシュ化したパスワード
+func (l *LibVirtEp) SetGuestUserPassword(vmname, user, cryptedPassword string) error {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return err
+	}
+	defer domain.Free()
+	return domain.SetUserPassword(user, cryptedPassword, libvirt.DOMAIN_PASSWORD_ENCRYPTED)
+}
+
+// shutdownGuest はゲストエージェントでシャットダウンを要求し、応答が無ければ ACPI で要求する
+func shutdownGuest(domain *libvirt.Domain, vmname string) error {
+	if err := domain.ShutdownFlags(libvirt.DOMAIN_SHUTDOWN_GUEST_AGENT); err != nil {
+		slog.Debug("guest agent shutdown failed; fallback to ACPI", "vmname", vmname, "err", err)
+		return domain.Shutdown()
+	}
+	return nil
+}
+
+// RequestShutdownDomain はゲストOSにシャットダウンを要求し、停止を待たずに戻る
+// 停止済みのドメインでは何もしない
+func (l *LibVirtEp) RequestShutdownDomain(vmname string) error {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return err
+	}
+	defer domain.Free()
+
+	state, _, err := domain.GetState()
+	if err != nil {
+		return err
+	}
+	if !isDomainLive(state) {
+		return nil
+	}
+	return shutdownGuest(domain, vmname)
+}
+
+// DisableDomainAutostart はドメインの autostart を無効にする
+func (l *LibVirtEp) DisableDomainAutostart(vmname string) error {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return err
+	}
+	defer domain.Free()
+	return domain.SetAutostart(false)
+}
@@ -0,0 +1,38 @@
+package virt
+
+import (
+	"testing"
+
+	"libvirt.org/go/libvirt"
+)
+
+func TestNewGuestInfo(t *testing.T) {
+	info := &libvirt.DomainGuestInfo{
+		Hostname: "web1",
+		OS: &libvirt.DomainGuestInfoOS{
+			Name:          "Ubuntu",
+			PrettyName:    "Ubuntu 24.04.1 LTS",
+			VersionID:     "24.04",
+			KernelRelease: "6.8.0-45-generic",
+		},
+	}
+	ifaces := []libvirt.DomainInterface{
+		{Name: "lo", Hwaddr: "00:00:00:00:00:00", Addrs: []libvirt.DomainIPAddress{{Addr: "127.0.0.1", Prefix: 8}}},
+		{Name: "enp1s0", Hwaddr: "52:54:00:00:00:01", Addrs: []libvirt.DomainIPAddress{
+			{Addr: "10.1.0.10", Prefix: 24},
+			{Addr: "fe80::5054:ff:fe00:1", Prefix: 64},
+		}},
+	}
+
+	got := newGuestInfo(info, ifaces)
+	if got.Hostname != "web1" || got.OSName != "Ubuntu 24.04.1 LTS" || got.OSVersion != "24.04" || got.KernelRelease != "6.8.0-45-generic" {
+		t.Fatalf("newGuestInfo() = %+v", got)
+	}
+	if len(got.Interfaces) != 1 {
+		t.Fatalf("interfaces = %+v, want enp1s0 only", got.Interfaces)
+	}
+	nic := got.Interfaces[0]
+	if nic.Name != "enp1s0" || nic.MacAddress != "52:54:00:00:00:01" || len(nic.IPAddresses) != 2 || nic.IPAddresses[0] != "10.1.0.10/24" {
+		t.Fatalf("interface = %+v", nic)
+	}
+}
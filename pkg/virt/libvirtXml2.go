@@ -76,6 +76,11 @@ type ServerSpec struct {
 	Clocks       []ClockSpec
 	OsName       string
 	OsVersion    string
+	Firmware     string // bios, uefi, uefi-secure。空は bios
+	NVRAMPath    string // UEFI の変数を保持するドメイン毎の NVRAM
+	TPM          bool
+	TPMStateDir  string // swtpm の状態を保持するディレクトリ
+	Performance  PerformanceSpec
 }
 
 type LibVirtEp struct {
@@ -99,6 +104,95 @@ func (lve *LibVirtEp) Close() {
 	_, _ = lve.Com.Close()
 }
 
+// newDomainDisk は DiskSpec からディスクの定義を生成する
+// エイリアスとアドレスは設定しない。未対応のタイプの場合は false を返す
+func newDomainDisk(d DiskSpec) (libvirtxml.DomainDisk, bool) {
+	disk := libvirtxml.DomainDisk{
+		Device: "disk",
+		Driver: &libvirtxml.DomainDiskDriver{
+			Name: "qemu", Type: d.Type, Cache: "none", IO: "native",
+		},
+		Target: &libvirtxml.DomainDiskTarget{Dev: d.Dev, Bus: "virtio"},
+	}
+
+	// ソースの割り当て（Typeに応じて切り替え）
+	switch d.Type {
+	case "raw":
+		disk.Source = &libvirtxml.DomainDiskSource{
+			Block: &libvirtxml.DomainDiskSourceBlock{Dev: d.Src},
+		}
+
+	case "qcow2":
+		disk.Source = &libvirtxml.DomainDiskSource{
+			File: &libvirtxml.DomainDiskSourceFile{File: d.Src},
+		}
+
+	case "iso":
+		disk = libvirtxml.DomainDisk{
+			Device: "cdrom",
+			Driver: &libvirtxml.DomainDiskDriver{
+				Name: "qemu", Type: "raw", Cache: "none", IO: "native",
+			},
+			Target: &libvirtxml.DomainDiskTarget{Dev: "sda", Bus: "sata"},
+			Source: &libvirtxml.DomainDiskSource{
+				File: &libvirtxml.DomainDiskSourceFile{File: d.Src},
+			},
+		}
+
+	case "iscsi":
+		// "iscsi" is an attachment source protocol, not a qemu image format.
+		// Keep driver format as raw while using network source protocol=iscsi.
+		disk.Driver.Type = "raw"
+		disk.Source = &libvirtxml.DomainDiskSource{
+			Network: &libvirtxml.DomainDiskSourceNetwork{
+				Protocol: "iscsi",
+				Name:     d.ISCSITarget,
+				Hosts: []libvirtxml.DomainDiskSourceHost{
+					{Name: d.ISCSIHost, Port: d.ISCSIPort},
+				},
+				Initiator: &libvirtxml.DomainDiskSourceNetworkInitiator{
+					IQN: &libvirtxml.DomainDiskSourceNetworkIQN{Name: d.ISCSIInitiator},
+				},
+			},
+		}
+
+	case "rbd":
+		disk.Driver.Type = "raw"
+		hosts := make([]libvirtxml.DomainDiskSourceHost, 0, len(d.CephMonitors))
+		for _, monitor := range d.CephMonitors {
+			monitor = strings.TrimSpace(monitor)
+			if monitor == "" {
+				continue
+			}
+			hostName := monitor
+			port := ""
+			if h, p, err := net.SplitHostPort(monitor); err == nil {
+				hostName = h
+				port = p
+			}
+			hosts = append(hosts, libvirtxml.DomainDiskSourceHost{Name: hostName, Port: port})
+		}
+		disk.Source = &libvirtxml.DomainDiskSource{
+			Network: &libvirtxml.DomainDiskSourceNetwork{
+				Protocol: "rbd",
+				Name:     d.Src,
+				Hosts:    hosts,
+				Auth: &libvirtxml.DomainDiskAuth{
+					Username: d.CephUser,
+					Secret: &libvirtxml.DomainDiskSecret{
+						Type: "ceph",
+						UUID: d.CephSecretUUID,
+					},
+				},
+			},
+		}
+
+	default:
+		return libvirtxml.DomainDisk{}, false
+	}
+	return disk, true
+}
+
 // libvirt XMLを生成する関数
 func CreateDomainXML(vs ServerSpec) *libvirtxml.Domain {
 	// This function is intentionally left blank.
@@ -141,99 +235,26 @@ func CreateDomainXML(vs ServerSpec) *libvirtxml.Domain {
 		},
 	}
 
+	// ファームウェアと TPM の設定
+	applyFirmware(dom, vs)
+	applyTPM(dom, vs)
+	// CPU のピン留め、NUMA、hugepages の設定
+	applyPerformance(dom, vs.Performance)
+
 	// --- ディスクの生成 ---
 	for i, d := range vs.DiskSpecs {
-		disk := libvirtxml.DomainDisk{
-			Device: "disk",
-			Driver: &libvirtxml.DomainDiskDriver{
-				Name: "qemu", Type: d.Type, Cache: "none", IO: "native",
-			},
-			Target:  &libvirtxml.DomainDiskTarget{Dev: d.Dev, Bus: "virtio"},
-			Alias:   &libvirtxml.DomainAlias{Name: fmt.Sprintf("virtio-disk%d", i)},
-			Address: pciAddr(d.Bus, 0, 0),
-		}
-
-		cdrom := libvirtxml.DomainDisk{
-			Device: "cdrom",
-			Driver: &libvirtxml.DomainDiskDriver{
-				Name: "qemu", Type: "raw", Cache: "none", IO: "native",
-			},
-			Target:  &libvirtxml.DomainDiskTarget{Dev: "sda", Bus: "sata"},
-			Alias:   &libvirtxml.DomainAlias{Name: "sata0-0-0"},
-			Address: &libvirtxml.DomainAddress{Drive: &libvirtxml.DomainAddressDrive{Controller: uintPtr(0), Bus: uintPtr(0), Target: uintPtr(0), Unit: uintPtr(0)}},
+		disk, ok := newDomainDisk(d)
+		if !ok {
+			continue
 		}
-
-		// ソースの割り当て（Typeに応じて切り替え）
-		switch d.Type {
-		case "raw":
-			disk.Source = &libvirtxml.DomainDiskSource{
-				Block: &libvirtxml.DomainDiskSourceBlock{Dev: d.Src},
-			}
-			dom.Devices.Disks = append(dom.Devices.Disks, disk)
-
-		case "qcow2":
-			disk.Source = &libvirtxml.DomainDiskSource{
-				File: &libvirtxml.DomainDiskSourceFile{File: d.Src},
-			}
-			dom.Devices.Disks = append(dom.Devices.Disks, disk)
-
-		case "iso":
-			cdrom.Source = &libvirtxml.DomainDiskSource{
-				File: &libvirtxml.DomainDiskSourceFile{File: d.Src},
-			}
-			dom.Devices.Disks = append(dom.Devices.Disks, cdrom)
-
-		case "iscsi":
-			// "iscsi" is an attachment source protocol, not a qemu image format.
-			// Keep driver format as raw while using network source protocol=iscsi.
-			disk.Driver.Type = "raw"
-			disk.Source = &libvirtxml.DomainDiskSource{
-				Network: &libvirtxml.DomainDiskSourceNetwork{
-					Protocol: "iscsi",
-					Name:     d.ISCSITarget,
-					Hosts: []libvirtxml.DomainDiskSourceHost{
-						{Name: d.ISCSIHost, Port: d.ISCSIPort},
-					},
-					Initiator: &libvirtxml.DomainDiskSourceNetworkInitiator{
-						IQN: &libvirtxml.DomainDiskSourceNetworkIQN{Name: d.ISCSIInitiator},
-					},
-				},
-			}
-			dom.Devices.Disks = append(dom.Devices.Disks, disk)
-
-		case "rbd":
-			disk.Driver.Type = "raw"
-			hosts := make([]libvirtxml.DomainDiskSourceHost, 0, len(d.CephMonitors))
-			for _, monitor := range d.CephMonitors {
-				monitor = strings.TrimSpace(monitor)
-				if monitor == "" {
-					continue
-				}
-				hostName := monitor
-				port := ""
-				if h, p, err := net.SplitHostPort(monitor); err == nil {
-					hostName = h
-					port = p
-				}
-				hosts = append(hosts, libvirtxml.DomainDiskSourceHost{Name: hostName, Port: port})
-			}
-			disk.Source = &libvirtxml.DomainDiskSource{
-				Network: &libvirtxml.DomainDiskSourceNetwork{
-					Protocol: "rbd",
-					Name:     d.Src,
-					Hosts:    hosts,
-					Auth: &libvirtxml.DomainDiskAuth{
-						Username: d.CephUser,
-						Secret: &libvirtxml.DomainDiskSecret{
-							Type: "ceph",
-							UUID: d.CephSecretUUID,
-						},
-					},
-				},
-			}
-			dom.Devices.Disks = append(dom.Devices.Disks, disk)
-
+		if disk.Device == "cdrom" {
+			disk.Alias = &libvirtxml.DomainAlias{Name: "sata0-0-0"}
+			disk.Address = &libvirtxml.DomainAddress{Drive: &libvirtxml.DomainAddressDrive{Controller: uintPtr(0), Bus: uintPtr(0), Target: uintPtr(0), Unit: uintPtr(0)}}
+		} else {
+			disk.Alias = &libvirtxml.DomainAlias{Name: fmt.Sprintf("virtio-disk%d", i)}
+			disk.Address = pciAddr(d.Bus, 0, 0)
 		}
+		dom.Devices.Disks = append(dom.Devices.Disks, disk)
 	}
 
 	// PCIコントローラーの生成
@@ -564,10 +585,10 @@ func (l *LibVirtEp) DeleteDomain(vmname string) error {
 		time.Sleep(1 * time.Second)
 	}
 
-	// ドメインの削除
-	err = domain.Undefine()
+	// ドメインの削除。UEFI の NVRAM と TPM の状態も削除する
+	err = domain.UndefineFlags(libvirt.DOMAIN_UNDEFINE_NVRAM | libvirt.DOMAIN_UNDEFINE_TPM)
 	if err != nil {
-		slog.Error("Undefine()", "err", err)
+		slog.Error("UndefineFlags()", "err", err)
 		return err
 	}
 
@@ -628,6 +649,31 @@ func (l *LibVirtEp) StopDomain(vmname string) error {
 	return nil
 }
 
+// 仮想マシンが稼働中（一時停止を含む）かを返す
+func (l *LibVirtEp) IsDomainActive(vmname string) (bool, error) {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return false, err
+	}
+	defer domain.Free()
+
+	state, _, err := domain.GetState()
+	if err != nil {
+		return false, err
+	}
+	return isDomainLive(state), nil
+}
+
+// 仮想マシンの強制停止。autostart の設定は変更しない
+func (l *LibVirtEp) DestroyDomain(vmname string) error {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return err
+	}
+	defer domain.Free()
+	return domain.Destroy()
+}
+
 // 仮想マシンの開始
 func (l *LibVirtEp) StartDomain(vmname string) error {
 	domain, err := l.Com.LookupDomainByName(vmname)
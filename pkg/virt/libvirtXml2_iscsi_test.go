@@ -44,3 +44,54 @@ func TestCreateDomainXML_ISCSIDisk(t *testing.T) {
 		t.Fatalf("iscsi initiator is missing: %s", xmlStr)
 	}
 }
+
+func TestCreateDomainXML_SecureBootAndTPM(t *testing.T) {
+	vs := virt.ServerSpec{
+		UUID:        "00000000-0000-0000-0000-000000000002",
+		Name:        "vm-uefi-test",
+		RAM:         1024 * 1024,
+		CountVCPU:   2,
+		Machine:     "pc-q35-4.2",
+		Firmware:    virt.FirmwareUEFISecure,
+		NVRAMPath:   "/var/lib/marmot/volumes/boot-s0001.nvram",
+		TPM:         true,
+		TPMStateDir: "/var/lib/marmot/volumes/boot-s0001.tpm",
+	}
+
+	xml, err := virt.CreateDomainXML(vs).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	xmlStr := string(xml)
+	for _, want := range []string{
+		`<loader readonly="yes" secure="yes" type="pflash">` + virt.OVMFSecureCode + `</loader>`,
+		`<nvram template="` + virt.OVMFSecureVars + `">/var/lib/marmot/volumes/boot-s0001.nvram</nvram>`,
+		`<smm state="on">`,
+		`<tpm model="tpm-crb">`,
+		`<backend type="emulator" version="2.0" persistent_state="yes">`,
+		`<source type="dir" path="/var/lib/marmot/volumes/boot-s0001.tpm">`,
+	} {
+		if !strings.Contains(xmlStr, want) {
+			t.Fatalf("%s is missing: %s", want, xmlStr)
+		}
+	}
+}
+
+func TestCreateDomainXML_BIOSByDefault(t *testing.T) {
+	xml, err := virt.CreateDomainXML(virt.ServerSpec{Name: "vm-bios-test", Machine: "pc-q35-4.2"}).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if strings.Contains(string(xml), "<loader") || strings.Contains(string(xml), "<tpm") {
+		t.Fatalf("BIOS domain must not have a loader or TPM: %s", xml)
+	}
+}
+
+func TestNormalizeFirmware(t *testing.T) {
+	if fw, err := virt.NormalizeFirmware(" UEFI "); err != nil || fw != virt.FirmwareUEFI {
+		t.Fatalf("NormalizeFirmware() = %q, %v", fw, err)
+	}
+	if _, err := virt.NormalizeFirmware("efi"); err == nil {
+		t.Fatal("NormalizeFirmware() error = nil, want an error for an unknown firmware")
+	}
+}
@@ -0,0 +1,156 @@
+package virt
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"libvirt.org/go/libvirt"
+	"libvirt.org/go/libvirtxml"
+)
+
+// MigrateSpec はライブマイグレーションの指定
+type MigrateSpec struct {
+	DestURI          string   // 移行先 libvirtd の接続URI (例: qemu+ssh://192.168.1.12/system)
+	CopyStoragePaths []string // ブロックマイグレーションでコピーするローカルディスクのパス
+	ISCSIInitiator   string   // 移行先ホストの iSCSI イニシエーター IQN、空なら書き換えない
+	SharedCPUSet     string   // 移行先ホストでピン留めしない vCPU を動かす CPU。空なら制限しない
+}
+
+// DomainDiskCapacity はドメインに接続されたディスクの仮想サイズをバイト単位で返す
+// path はディスクのソースパス（qcow2 ファイルまたはブロックデバイス）
+func (l *LibVirtEp) DomainDiskCapacity(vmname, path string) (uint64, error) {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = domain.Free()
+	}()
+
+	info, err := domain.GetBlockInfo(path, 0)
+	if err != nil {
+		return 0, err
+	}
+	return info.Capacity, nil
+}
+
+// diskSourcePath はローカルディスクのソースパスを返す。ネットワークディスクは空文字
+func diskSourcePath(disk libvirtxml.DomainDisk) string {
+	if disk.Source == nil {
+		return ""
+	}
+	if disk.Source.File != nil {
+		return disk.Source.File.File
+	}
+	if disk.Source.Block != nil {
+		return disk.Source.Block.Dev
+	}
+	return ""
+}
+
+// migrationDomainXML は移行先で定義するドメインXMLを組み立てる
+// コピー対象ディスクのターゲットデバイス名も返す
+func migrationDomainXML(xml string, spec MigrateSpec) (string, []string, error) {
+	var cfg libvirtxml.Domain
+	if err := cfg.Unmarshal(xml); err != nil {
+		return "", nil, err
+	}
+
+	copyPaths := make(map[string]bool, len(spec.CopyStoragePaths))
+	for _, p := range spec.CopyStoragePaths {
+		copyPaths[p] = true
+	}
+
+	var copyDevs []string
+	if cfg.Devices != nil {
+		for i := range cfg.Devices.Disks {
+			disk := &cfg.Devices.Disks[i]
+			if path := diskSourcePath(*disk); path != "" && copyPaths[path] {
+				if disk.Target == nil || disk.Target.Dev == "" {
+					return "", nil, fmt.Errorf("disk %s has no target device", path)
+				}
+				copyDevs = append(copyDevs, disk.Target.Dev)
+				delete(copyPaths, path)
+				continue
+			}
+			// iSCSI ディスクは接続元ホストのイニシエーター名で ACL が評価されるため移行先の IQN に書き換える
+			if spec.ISCSIInitiator != "" && disk.Source != nil && disk.Source.Network != nil && disk.Source.Network.Protocol == "iscsi" {
+				disk.Source.Network.Initiator = &libvirtxml.DomainDiskSourceNetworkInitiator{
+					IQN: &libvirtxml.DomainDiskSourceNetworkIQN{Name: spec.ISCSIInitiator},
+				}
+			}
+		}
+	}
+	for path := range copyPaths {
+		return "", nil, fmt.Errorf("disk %s is not attached to the domain", path)
+	}
+	// 共有の CPU はホスト毎の専有 vCPU のプールで異なるため、移行先のものに置き換える
+	if cfg.VCPU != nil && (cfg.CPUTune == nil || len(cfg.CPUTune.VCPUPin) == 0) {
+		cfg.VCPU.CPUSet = spec.SharedCPUSet
+	}
+
+	out, err := cfg.Marshal()
+	if err != nil {
+		return "", nil, err
+	}
+	return out, copyDevs, nil
+}
+
+// MigrateDomain はドメインを移行先ホストへライブマイグレーションする
+// 移行元の libvirtd が移行先へ直接接続する peer-to-peer 方式で、移行先に永続定義を作り移行元の定義は削除する
+// CopyStoragePaths のディスクは移行先に同じパスで事前作成しておく必要がある
+func (l *LibVirtEp) MigrateDomain(vmname string, spec MigrateSpec) error {
+	domain, err := l.Com.LookupDomainByName(vmname)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = domain.Free()
+	}()
+
+	xml, err := domain.GetXMLDesc(libvirt.DOMAIN_XML_MIGRATABLE)
+	if err != nil {
+		return err
+	}
+	destXML, copyDevs, err := migrationDomainXML(xml, spec)
+	if err != nil {
+		return err
+	}
+
+	params := &libvirt.DomainMigrateParameters{
+		DestXMLSet: true,
+		DestXML:    destXML,
+	}
+	flags := libvirt.MIGRATE_LIVE | libvirt.MIGRATE_PEER2PEER | libvirt.MIGRATE_PERSIST_DEST | libvirt.MIGRATE_UNDEFINE_SOURCE
+	if len(copyDevs) > 0 {
+		flags |= libvirt.MIGRATE_NON_SHARED_DISK
+		params.MigrateDisksSet = true
+		params.MigrateDisks = copyDevs
+	}
+
+	slog.Debug("MigrateDomain()", "vmname", vmname, "destURI", spec.DestURI, "copyDisks", strings.Join(copyDevs, ","))
+	if err := domain.MigrateToURI3(spec.DestURI, params, flags); err != nil {
+		return err
+	}
+
+	// 自動起動の設定は移行されないため、移行先で設定する
+	dest, err := NewLibVirtEp(spec.DestURI)
+	if err != nil {
+		slog.Warn("failed to connect to the migration destination to set autostart", "destURI", spec.DestURI, "err", err)
+		return nil
+	}
+	defer dest.Close()
+	destDomain, err := dest.Com.LookupDomainByName(vmname)
+	if err != nil {
+		slog.Warn("failed to look up the migrated domain", "vmname", vmname, "err", err)
+		return nil
+	}
+	defer func() {
+		_ = destDomain.Free()
+	}()
+	if err := destDomain.SetAutostart(true); err != nil {
+		slog.Warn("failed to set autostart on the migrated domain", "vmname", vmname, "err", err)
+	}
+	return nil
+}
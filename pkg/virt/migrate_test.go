@@ -0,0 +1,90 @@
+package virt
+
+import (
+	"strings"
+	"testing"
+
+	"libvirt.org/go/libvirtxml"
+)
+
+const migrationTestDomainXML = `<domain type="kvm">
+  <name>vm-abcde</name>
+  <devices>
+    <disk type="file" device="disk">
+      <source file="/var/lib/marmot/volumes/boot-11111.qcow2"></source>
+      <target dev="vda" bus="virtio"></target>
+    </disk>
+    <disk type="block" device="disk">
+      <source dev="/dev/vg2/datalv-22222"></source>
+      <target dev="vdb" bus="virtio"></target>
+    </disk>
+    <disk type="network" device="disk">
+      <source protocol="iscsi" name="iqn.2024-01.com.marmot:target-33333/0">
+        <host name="192.168.1.210" port="3260"></host>
+        <initiator>
+          <iqn name="iqn.2004-10.com.marmot:hv1"></iqn>
+        </initiator>
+      </source>
+      <target dev="vdc" bus="virtio"></target>
+    </disk>
+  </devices>
+</domain>`
+
+func TestMigrationDomainXML(t *testing.T) {
+	out, devs, err := migrationDomainXML(migrationTestDomainXML, MigrateSpec{
+		CopyStoragePaths: []string{"/var/lib/marmot/volumes/boot-11111.qcow2", "/dev/vg2/datalv-22222"},
+		ISCSIInitiator:   "iqn.2004-10.com.marmot:hv2",
+	})
+	if err != nil {
+		t.Fatalf("migrationDomainXML() error = %v", err)
+	}
+	if strings.Join(devs, ",") != "vda,vdb" {
+		t.Fatalf("copy devices = %v, want [vda vdb]", devs)
+	}
+
+	var cfg libvirtxml.Domain
+	if err := cfg.Unmarshal(out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	iscsi := cfg.Devices.Disks[2].Source.Network
+	if iscsi.Initiator == nil || iscsi.Initiator.IQN == nil || iscsi.Initiator.IQN.Name != "iqn.2004-10.com.marmot:hv2" {
+		t.Fatalf("iscsi initiator = %+v, want the destination IQN", iscsi.Initiator)
+	}
+}
+
+func TestMigrationDomainXMLRejectsUnknownDisk(t *testing.T) {
+	_, _, err := migrationDomainXML(migrationTestDomainXML, MigrateSpec{
+		CopyStoragePaths: []string{"/var/lib/marmot/volumes/data-99999.qcow2"},
+	})
+	if err == nil {
+		t.Fatal("migrationDomainXML() error = nil, want error for a disk that is not attached")
+	}
+}
+
+func TestMigrationDomainXMLReplacesSharedCPUSet(t *testing.T) {
+	src := strings.Replace(migrationTestDomainXML, "<name>vm-abcde</name>", `<name>vm-abcde</name>
+  <vcpu placement="static" cpuset="0-3">2</vcpu>`, 1)
+	out, _, err := migrationDomainXML(src, MigrateSpec{SharedCPUSet: "0-5"})
+	if err != nil {
+		t.Fatalf("migrationDomainXML() error = %v", err)
+	}
+	var cfg libvirtxml.Domain
+	if err := cfg.Unmarshal(out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if cfg.VCPU == nil || cfg.VCPU.CPUSet != "0-5" {
+		t.Fatalf("vcpu = %+v, want cpuset 0-5", cfg.VCPU)
+	}
+
+	out, _, err = migrationDomainXML(src, MigrateSpec{})
+	if err != nil {
+		t.Fatalf("migrationDomainXML() error = %v", err)
+	}
+	var unrestricted libvirtxml.Domain
+	if err := unrestricted.Unmarshal(out); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if unrestricted.VCPU == nil || unrestricted.VCPU.CPUSet != "" {
+		t.Fatalf("vcpu = %+v, want no restriction on a node without a pinned cpu pool", unrestricted.VCPU)
+	}
+}
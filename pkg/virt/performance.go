@@ -0,0 +1,52 @@
+package virt
+
+import (
+	"strconv"
+
+	"libvirt.org/go/libvirtxml"
+)
+
+// hugepages のページサイズ (KiB)
+const (
+	HugepageSize2M = 2 * 1024
+	HugepageSize1G = 1024 * 1024
+)
+
+// PerformanceSpec は性能を重視するドメインのホスト資源の割り当て
+type PerformanceSpec struct {
+	VCPUPins       []int  // vCPU 毎にピン留めするホスト CPU。空はピン留めしない
+	EmulatorCPUSet string // エミュレータースレッドを動かすホスト CPU
+	SharedCPUSet   string // ピン留めしない vCPU を動かすホスト CPU。空は制限しない
+	NUMANode       *int   // メモリを割り当てるホストの NUMA ノード
+	HugepageKiB    uint   // hugepages のページサイズ。0 は通常のメモリ
+}
+
+// applyPerformance は cputune、numatune と memoryBacking を設定する
+func applyPerformance(dom *libvirtxml.Domain, ps PerformanceSpec) {
+	if len(ps.VCPUPins) == 0 {
+		dom.VCPU.CPUSet = ps.SharedCPUSet
+	} else {
+		tune := &libvirtxml.DomainCPUTune{}
+		for i, cpu := range ps.VCPUPins {
+			tune.VCPUPin = append(tune.VCPUPin, libvirtxml.DomainCPUTuneVCPUPin{VCPU: uint(i), CPUSet: strconv.Itoa(cpu)})
+		}
+		if ps.EmulatorCPUSet != "" {
+			tune.EmulatorPin = &libvirtxml.DomainCPUTuneEmulatorPin{CPUSet: ps.EmulatorCPUSet}
+		}
+		dom.CPUTune = tune
+	}
+
+	if ps.NUMANode != nil {
+		dom.NUMATune = &libvirtxml.DomainNUMATune{
+			Memory: &libvirtxml.DomainNUMATuneMemory{Mode: "strict", Nodeset: strconv.Itoa(*ps.NUMANode)},
+		}
+	}
+
+	if ps.HugepageKiB > 0 {
+		dom.MemoryBacking = &libvirtxml.DomainMemoryBacking{
+			MemoryHugePages: &libvirtxml.DomainMemoryHugepages{
+				Hugepages: []libvirtxml.DomainMemoryHugepage{{Size: ps.HugepageKiB, Unit: "KiB"}},
+			},
+		}
+	}
+}
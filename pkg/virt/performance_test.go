@@ -0,0 +1,55 @@
+package virt_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/takara9/marmot/pkg/virt"
+)
+
+func TestCreateDomainXML_Performance(t *testing.T) {
+	node := 1
+	vs := virt.ServerSpec{
+		Name: "vm-perf-test", Machine: "pc-q35-4.2", CountVCPU: 2, RAM: 4 * 1024 * 1024,
+		Performance: virt.PerformanceSpec{
+			VCPUPins:       []int{8, 9},
+			EmulatorCPUSet: "10",
+			NUMANode:       &node,
+			HugepageKiB:    virt.HugepageSize1G,
+		},
+	}
+	xml, err := virt.CreateDomainXML(vs).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	for _, want := range []string{
+		`<vcpupin vcpu="0" cpuset="8"></vcpupin>`,
+		`<vcpupin vcpu="1" cpuset="9"></vcpupin>`,
+		`<emulatorpin cpuset="10"></emulatorpin>`,
+		`<memory mode="strict" nodeset="1"></memory>`,
+		`<page size="1048576" unit="KiB"></page>`,
+	} {
+		if !strings.Contains(string(xml), want) {
+			t.Fatalf("domain XML does not contain %s:\n%s", want, xml)
+		}
+	}
+}
+
+func TestCreateDomainXML_SharedCPUSet(t *testing.T) {
+	vs := virt.ServerSpec{
+		Name: "vm-shared-test", Machine: "pc-q35-4.2", CountVCPU: 2,
+		Performance: virt.PerformanceSpec{SharedCPUSet: "0-7"},
+	}
+	xml, err := virt.CreateDomainXML(vs).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(xml), `<vcpu placement="static" cpuset="0-7">2</vcpu>`) {
+		t.Fatalf("vcpu cpuset is not set:\n%s", xml)
+	}
+	for _, unwanted := range []string{"<cputune", "<numatune", "<memoryBacking"} {
+		if strings.Contains(string(xml), unwanted) {
+			t.Fatalf("domain XML must not contain %s:\n%s", unwanted, xml)
+		}
+	}
+}